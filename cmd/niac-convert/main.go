@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/krisarmstrong/niac-go/internal/converter"
 )
@@ -14,19 +15,21 @@ func main() {
 	inputFile := flag.String("input", "", "Input Java DSL config file (.cfg)")
 	outputFile := flag.String("output", "", "Output YAML config file (optional, defaults to <input>.yaml)")
 	batchDir := flag.String("batch", "", "Convert all .cfg files in directory")
+	reverse := flag.Bool("reverse", false, "Convert YAML (.yaml/.yml) back to Java DSL (.cfg); implied by a YAML -input")
 	verbose := flag.Bool("v", false, "Verbose output")
 	flag.Parse()
 
 	if *inputFile == "" && *batchDir == "" {
 		fmt.Fprintf(os.Stderr, "Usage: niac-convert -input <file.cfg> [-output <file.yaml>] [-v]\n")
-		fmt.Fprintf(os.Stderr, "   or: niac-convert -batch <directory> [-v]\n")
+		fmt.Fprintf(os.Stderr, "   or: niac-convert -input <file.yaml> [-output <file.cfg>] [-v]\n")
+		fmt.Fprintf(os.Stderr, "   or: niac-convert -batch <directory> [-reverse] [-v]\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
 	// Batch conversion mode
 	if *batchDir != "" {
-		if err := convertBatch(*batchDir, *verbose); err != nil {
+		if err := convertBatch(*batchDir, *reverse, *verbose); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -34,18 +37,16 @@ func main() {
 	}
 
 	// Single file conversion mode
+	toDSL := *reverse || isYAML(*inputFile)
 	if *outputFile == "" {
-		base := filepath.Base(*inputFile)
-		ext := filepath.Ext(base)
-		name := base[:len(base)-len(ext)]
-		*outputFile = name + ".yaml"
+		*outputFile = outputName(*inputFile, toDSL)
 	}
 
 	if *verbose {
 		fmt.Printf("Converting %s -> %s\n", *inputFile, *outputFile)
 	}
 
-	if err := converter.ConvertFile(*inputFile, *outputFile, *verbose); err != nil {
+	if err := convertFile(*inputFile, *outputFile, toDSL, *verbose); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -55,29 +56,35 @@ func main() {
 	}
 }
 
-func convertBatch(dir string, verbose bool) error {
-	files, err := filepath.Glob(filepath.Join(dir, "*.cfg"))
-	if err != nil {
-		return fmt.Errorf("error finding .cfg files: %w", err)
+func convertBatch(dir string, reverse, verbose bool) error {
+	patterns := []string{"*.cfg"}
+	if reverse {
+		patterns = []string{"*.yaml", "*.yml"}
+	}
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return fmt.Errorf("error finding %s files: %w", pattern, err)
+		}
+		files = append(files, matches...)
 	}
 
 	if len(files) == 0 {
-		return fmt.Errorf("no .cfg files found in %s", dir)
+		return fmt.Errorf("no %s files found in %s", strings.Join(patterns, "/"), dir)
 	}
 
 	fmt.Printf("Found %d config files to convert\n", len(files))
 
 	for _, file := range files {
 		base := filepath.Base(file)
-		ext := filepath.Ext(base)
-		name := base[:len(base)-len(ext)]
-		output := filepath.Join(dir, name+".yaml")
+		output := filepath.Join(dir, outputName(base, reverse))
 
 		if verbose {
 			fmt.Printf("Converting %s -> %s\n", file, output)
 		}
 
-		if err := converter.ConvertFile(file, output, verbose); err != nil {
+		if err := convertFile(file, output, reverse, verbose); err != nil {
 			fmt.Fprintf(os.Stderr, "Error converting %s: %v\n", file, err)
 			continue
 		}
@@ -90,3 +97,26 @@ func convertBatch(dir string, verbose bool) error {
 	fmt.Printf("Batch conversion complete\n")
 	return nil
 }
+
+func convertFile(input, output string, toDSL, verbose bool) error {
+	if toDSL {
+		return converter.ConvertYAMLToDSL(input, output, verbose)
+	}
+	return converter.ConvertFile(input, output, verbose)
+}
+
+// outputName is the default output file name: the input's base name with the
+// extension of the target format
+func outputName(input string, toDSL bool) string {
+	base := filepath.Base(input)
+	name := base[:len(base)-len(filepath.Ext(base))]
+	if toDSL {
+		return name + ".cfg"
+	}
+	return name + ".yaml"
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcap"
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -25,8 +26,8 @@ var analyzePcapCmd = &cobra.Command{
 	Use:   "analyze-pcap <pcap-file>",
 	Short: "Summarise a packet capture by protocol",
 	Long: `Parse a PCAP file and emit protocol counters for rapid troubleshooting.
-The tool classifies packets into ARP, LLDP, CDP, STP, IPv4, IPv6, TCP, UDP,
-and generic application protocols.`,
+The tool classifies packets into ARP, LLDP, CDP, EDP, FDP, STP, IPv4, IPv6,
+TCP, UDP, ICMP and the application protocols NIAC simulates.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runAnalyzePcap,
 }
@@ -76,30 +77,8 @@ func summarizePCAP(filename string) (*pcapSummary, error) {
 
 	for packet := range source.Packets() {
 		summary.Packets++
-		if layer := packet.Layer(layers.LayerTypeARP); layer != nil {
-			summary.ProtocolMap["ARP"]++
-			continue
-		}
-		if layer := packet.Layer(layers.LayerTypeLinkLayerDiscovery); layer != nil {
-			summary.ProtocolMap["LLDP"]++
-		}
-		if layer := packet.Layer(layers.LayerTypeCiscoDiscovery); layer != nil {
-			summary.ProtocolMap["CDP"]++
-		}
-		if layer := packet.Layer(layers.LayerTypeSTP); layer != nil {
-			summary.ProtocolMap["STP"]++
-		}
-		if ip4 := packet.Layer(layers.LayerTypeIPv4); ip4 != nil {
-			summary.ProtocolMap["IPv4"]++
-		}
-		if ip6 := packet.Layer(layers.LayerTypeIPv6); ip6 != nil {
-			summary.ProtocolMap["IPv6"]++
-		}
-		if tcp := packet.Layer(layers.LayerTypeTCP); tcp != nil {
-			summary.ProtocolMap["TCP"]++
-		}
-		if udp := packet.Layer(layers.LayerTypeUDP); udp != nil {
-			summary.ProtocolMap["UDP"]++
+		for _, proto := range classifyPacket(packet) {
+			summary.ProtocolMap[proto]++
 		}
 	}
 
@@ -110,3 +89,79 @@ func summarizePCAP(filename string) (*pcapSummary, error) {
 	}
 	return summary, nil
 }
+
+// classifyPacket returns every protocol label that applies to a packet, from
+// the link layer up (e.g. IPv4, UDP, DNS). ARP frames are labelled ARP only.
+func classifyPacket(packet gopacket.Packet) []string {
+	if packet.Layer(layers.LayerTypeARP) != nil {
+		return []string{"ARP"}
+	}
+
+	var protos []string
+	if packet.Layer(layers.LayerTypeLinkLayerDiscovery) != nil {
+		protos = append(protos, "LLDP")
+	}
+	if packet.Layer(layers.LayerTypeCiscoDiscovery) != nil {
+		protos = append(protos, "CDP")
+	}
+	if eth, ok := packet.LinkLayer().(*layers.Ethernet); ok {
+		switch string(eth.DstMAC) {
+		case protocols.EDPMulticastMAC:
+			protos = append(protos, "EDP")
+		case protocols.FDPMulticastMAC:
+			protos = append(protos, "FDP")
+		}
+	}
+	if packet.Layer(layers.LayerTypeSTP) != nil {
+		protos = append(protos, "STP")
+	}
+	if packet.Layer(layers.LayerTypeIPv4) != nil {
+		protos = append(protos, "IPv4")
+	}
+	if packet.Layer(layers.LayerTypeIPv6) != nil {
+		protos = append(protos, "IPv6")
+	}
+	if packet.Layer(layers.LayerTypeICMPv4) != nil {
+		protos = append(protos, "ICMP")
+	}
+	if packet.Layer(layers.LayerTypeICMPv6) != nil {
+		protos = append(protos, "ICMPv6")
+	}
+	if tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+		protos = append(protos, "TCP")
+		switch {
+		case isPort(uint16(tcp.SrcPort), uint16(tcp.DstPort), protocols.TCPPortHTTP):
+			protos = append(protos, "HTTP")
+		case isPort(uint16(tcp.SrcPort), uint16(tcp.DstPort), protocols.TCPPortFTP):
+			protos = append(protos, "FTP")
+		case isPort(uint16(tcp.SrcPort), uint16(tcp.DstPort), protocols.NetBIOSSessionServicePort):
+			protos = append(protos, "NetBIOS")
+		}
+	}
+	if udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP); ok {
+		protos = append(protos, "UDP")
+		switch {
+		case packet.Layer(layers.LayerTypeDNS) != nil:
+			protos = append(protos, "DNS")
+		case packet.Layer(layers.LayerTypeDHCPv4) != nil:
+			protos = append(protos, "DHCP")
+		case packet.Layer(layers.LayerTypeDHCPv6) != nil:
+			protos = append(protos, "DHCPv6")
+		case isPort(uint16(udp.SrcPort), uint16(udp.DstPort), protocols.UDPPortSNMP),
+			isPort(uint16(udp.SrcPort), uint16(udp.DstPort), snmpTrapPort):
+			protos = append(protos, "SNMP")
+		case isPort(uint16(udp.SrcPort), uint16(udp.DstPort), protocols.NetBIOSNameServicePort),
+			isPort(uint16(udp.SrcPort), uint16(udp.DstPort), protocols.NetBIOSDatagramServicePort):
+			protos = append(protos, "NetBIOS")
+		}
+	}
+	return protos
+}
+
+// snmpTrapPort is the well-known SNMP trap receiver port.
+const snmpTrapPort = 162
+
+// isPort reports whether either end of a TCP/UDP flow uses port.
+func isPort(src, dst uint16, port int) bool {
+	return int(src) == port || int(dst) == port
+}
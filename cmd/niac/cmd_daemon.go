@@ -47,8 +47,30 @@ func init() {
 	daemonCmd.Flags().StringVar(&daemonOpts.storagePath, "storage", "~/.niac/niac.db", "Path to run history database (use 'disabled' to disable)")
 }
 
+// daemonStoragePath returns the run history database path. Unless --storage
+// was given, run history goes under --output-dir like the other artifacts.
+func daemonStoragePath(storageSet bool) string {
+	if !storageSet && outputDirOpts.dir != "" {
+		return defaultStoragePath()
+	}
+	return daemonOpts.storagePath
+}
+
 func runDaemon(cmd *cobra.Command, args []string) error {
 	logging.InitColors(true)
+	if err := validateAPIRateLimit(); err != nil {
+		return err
+	}
+	if err := validateUIDir(); err != nil {
+		return err
+	}
+	durationBuckets, err := snmpDurationBuckets()
+	if err != nil {
+		return err
+	}
+	if err := prepareOutputDir(); err != nil {
+		return err
+	}
 
 	logging.Info("Starting NIAC Daemon v%s", version)
 	logging.Info("Web UI will be available at http://localhost%s", daemonOpts.listen)
@@ -62,8 +84,14 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 	d, err := daemon.NewDaemon(daemon.Config{
 		ListenAddr:  daemonOpts.listen,
 		Token:       daemonOpts.token,
-		StoragePath: daemonOpts.storagePath,
+		StoragePath: daemonStoragePath(cmd.Flags().Changed("storage")),
 		Version:     version,
+		RateLimit:   servicesOpts.apiRate,
+		RateBurst:   servicesOpts.apiBurst,
+		UIDir:       servicesOpts.uiDir,
+		OutputDir:   outputDirOpts.dir,
+
+		SNMPDurationBuckets: durationBuckets,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create daemon: %w", err)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/krisarmstrong/niac-go/pkg/api"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -200,6 +201,17 @@ func runConfigDiff(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Check for recabled, added, or removed links
+	linkDiff := api.DiffTopology(api.BuildTopology(cfg1), api.BuildTopology(cfg2))
+	for _, link := range linkDiff.Removed {
+		fmt.Printf("- Link removed: %s\n", link.Endpoints())
+		hasChanges = true
+	}
+	for _, link := range linkDiff.Added {
+		fmt.Printf("+ Link added: %s\n", link.Endpoints())
+		hasChanges = true
+	}
+
 	if !hasChanges {
 		fmt.Println("No differences found")
 	}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// deviceFilterOptions restricts which configured devices are simulated. Each
+// value is a comma-separated list of device names or "tag:<name>" selectors.
+type deviceFilterOptions struct {
+	only    string
+	exclude string
+}
+
+var deviceFilterOpts = deviceFilterOptions{}
+
+// splitSelectors splits a comma-separated selector list, dropping blanks.
+func splitSelectors(value string) []string {
+	var selectors []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			selectors = append(selectors, part)
+		}
+	}
+	return selectors
+}
+
+// applyDeviceFilters applies --only/--exclude to a freshly loaded configuration.
+func applyDeviceFilters(cfg *config.Config) error {
+	return cfg.FilterDevices(splitSelectors(deviceFilterOpts.only), splitSelectors(deviceFilterOpts.exclude))
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/spf13/cobra"
+)
+
+// maxReplayFrameLen is the largest frame replay can inject: a full-size
+// Ethernet frame plus one 802.1Q tag, without FCS.
+const maxReplayFrameLen = 1518
+
+// pcapInspection is a pre-replay summary of a capture file.
+type pcapInspection struct {
+	File         string         `json:"file"`
+	LinkType     string         `json:"link_type"`
+	Packets      int            `json:"packets"`
+	Bytes        int            `json:"bytes"`
+	FirstPacket  time.Time      `json:"first_packet,omitempty"`
+	LastPacket   time.Time      `json:"last_packet,omitempty"`
+	Duration     float64        `json:"duration_seconds"`
+	AveragePPS   float64        `json:"average_pps"`
+	Protocols    map[string]int `json:"protocols"`
+	Sources      []string       `json:"sources"`
+	Destinations []string       `json:"destinations"`
+	Unreplayable []string       `json:"unreplayable,omitempty"`
+}
+
+type inspectOptions struct {
+	json bool
+}
+
+var inspectOpts = inspectOptions{}
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <file.pcap>",
+	Short: "Summarise a capture before replaying it",
+	Long: `Read a PCAP file and report what replaying it would send: packets per
+protocol, distinct source and destination addresses, capture duration and
+average packet rate. Anything NIAC cannot replay faithfully (non-Ethernet link
+types, packets truncated by the snap length, oversized frames) is flagged.`,
+	Example: `  niac inspect capture.pcap
+  niac inspect capture.pcap --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inspection, err := inspectPCAP(args[0])
+		if err != nil {
+			return err
+		}
+		if inspectOpts.json {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(inspection)
+		}
+		printInspection(cmd.OutOrStdout(), inspection)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().BoolVar(&inspectOpts.json, "json", false, "Output the summary as JSON")
+}
+
+func inspectPCAP(filename string) (*pcapInspection, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open pcap: %w", err)
+	}
+	defer f.Close()
+
+	reader, linkType, err := newCaptureReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("open pcap: %w", err)
+	}
+
+	inspection := &pcapInspection{
+		File:      filename,
+		LinkType:  linkType.String(),
+		Protocols: make(map[string]int),
+	}
+
+	sources := make(map[string]struct{})
+	destinations := make(map[string]struct{})
+	var truncated, oversized int
+
+	source := gopacket.NewPacketSource(reader, linkType)
+	for packet := range source.Packets() {
+		inspection.Packets++
+		inspection.Bytes += len(packet.Data())
+		for _, proto := range classifyPacket(packet) {
+			inspection.Protocols[proto]++
+		}
+
+		md := packet.Metadata()
+		if inspection.FirstPacket.IsZero() || md.Timestamp.Before(inspection.FirstPacket) {
+			inspection.FirstPacket = md.Timestamp
+		}
+		if md.Timestamp.After(inspection.LastPacket) {
+			inspection.LastPacket = md.Timestamp
+		}
+		if md.CaptureLength < md.Length {
+			truncated++
+		}
+		if len(packet.Data()) > maxReplayFrameLen {
+			oversized++
+		}
+
+		if src, dst := packetEndpoints(packet); src != "" {
+			sources[src] = struct{}{}
+			if dst != "" {
+				destinations[dst] = struct{}{}
+			}
+		}
+	}
+
+	if inspection.Packets > 1 {
+		inspection.Duration = inspection.LastPacket.Sub(inspection.FirstPacket).Seconds()
+		if inspection.Duration > 0 {
+			inspection.AveragePPS = float64(inspection.Packets) / inspection.Duration
+		}
+	}
+	inspection.Sources = sortedKeys(sources)
+	inspection.Destinations = sortedKeys(destinations)
+
+	if linkType != layers.LinkTypeEthernet {
+		inspection.Unreplayable = append(inspection.Unreplayable,
+			fmt.Sprintf("link type %s is not Ethernet; replay injects raw Ethernet frames", linkType))
+	}
+	if truncated > 0 {
+		inspection.Unreplayable = append(inspection.Unreplayable,
+			fmt.Sprintf("%d packets truncated by the capture snap length", truncated))
+	}
+	if oversized > 0 {
+		inspection.Unreplayable = append(inspection.Unreplayable,
+			fmt.Sprintf("%d frames exceed %d bytes (offloaded segments?) and will be rejected by the interface", oversized, maxReplayFrameLen))
+	}
+	if inspection.Packets == 0 {
+		inspection.Unreplayable = append(inspection.Unreplayable, "capture contains no packets")
+	}
+	return inspection, nil
+}
+
+// newCaptureReader reads pcap or pcapng files without libpcap, so captures can
+// be inspected on hosts that cannot open a live interface.
+func newCaptureReader(f *os.File) (gopacket.PacketDataSource, layers.LinkType, error) {
+	if r, err := pcapgo.NewReader(f); err == nil {
+		return r, r.LinkType(), nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("not a pcap or pcapng file: %w", err)
+	}
+	return r, r.LinkType(), nil
+}
+
+// packetEndpoints returns the network-layer source and destination of a
+// packet, falling back to MAC addresses for non-IP frames.
+func packetEndpoints(packet gopacket.Packet) (string, string) {
+	if arp, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP); ok {
+		return net.IP(arp.SourceProtAddress).String(), net.IP(arp.DstProtAddress).String()
+	}
+	if network := packet.NetworkLayer(); network != nil {
+		flow := network.NetworkFlow()
+		return flow.Src().String(), flow.Dst().String()
+	}
+	if link := packet.LinkLayer(); link != nil {
+		flow := link.LinkFlow()
+		return flow.Src().String(), flow.Dst().String()
+	}
+	return "", ""
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func printInspection(w io.Writer, in *pcapInspection) {
+	fmt.Fprintf(w, "File:      %s\n", in.File)
+	fmt.Fprintf(w, "Link type: %s\n", in.LinkType)
+	fmt.Fprintf(w, "Packets:   %d (%d bytes)\n", in.Packets, in.Bytes)
+	if in.Packets > 0 {
+		fmt.Fprintf(w, "Duration:  %s (%.1f pps average)\n",
+			time.Duration(in.Duration*float64(time.Second)).Round(time.Millisecond), in.AveragePPS)
+	}
+
+	if len(in.Protocols) > 0 {
+		names := make([]string, 0, len(in.Protocols))
+		for name := range in.Protocols {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(w, "\nProtocols:")
+		for _, name := range names {
+			fmt.Fprintf(w, "  %-8s %d\n", name, in.Protocols[name])
+		}
+	}
+
+	fmt.Fprintf(w, "\nSources (%d):\n", len(in.Sources))
+	for _, addr := range in.Sources {
+		fmt.Fprintf(w, "  %s\n", addr)
+	}
+	fmt.Fprintf(w, "\nDestinations (%d):\n", len(in.Destinations))
+	for _, addr := range in.Destinations {
+		fmt.Fprintf(w, "  %s\n", addr)
+	}
+
+	if len(in.Unreplayable) > 0 {
+		fmt.Fprintln(w, "\nReplay warnings:")
+		for _, warning := range in.Unreplayable {
+			fmt.Fprintf(w, "  ⚠ %s\n", warning)
+		}
+	} else {
+		fmt.Fprintln(w, "\n✓ All packets can be replayed")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// writeInspectPCAP writes an ARP request, an ICMP echo, a DNS query and an
+// SNMP get (one second apart) to a temporary capture.
+func writeInspectPCAP(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "inspect.pcap")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create pcap: %v", err)
+	}
+	defer f.Close()
+
+	w := pcapgo.NewWriter(f)
+	if err := w.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+
+	hostMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	routerMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+	hostIP := net.ParseIP("10.0.0.5").To4()
+	routerIP := net.ParseIP("10.0.0.1").To4()
+
+	ipv4 := func(proto layers.IPProtocol) *layers.IPv4 {
+		return &layers.IPv4{Version: 4, TTL: 64, Protocol: proto, SrcIP: hostIP, DstIP: routerIP}
+	}
+	eth := func(etherType layers.EthernetType) *layers.Ethernet {
+		return &layers.Ethernet{SrcMAC: hostMAC, DstMAC: routerMAC, EthernetType: etherType}
+	}
+
+	dnsIP := ipv4(layers.IPProtocolUDP)
+	dnsUDP := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	dnsUDP.SetNetworkLayerForChecksum(dnsIP)
+	snmpIP := ipv4(layers.IPProtocolUDP)
+	snmpUDP := &layers.UDP{SrcPort: 40001, DstPort: 161}
+	snmpUDP.SetNetworkLayerForChecksum(snmpIP)
+
+	frames := [][]gopacket.SerializableLayer{
+		{
+			&layers.Ethernet{SrcMAC: hostMAC, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeARP},
+			&layers.ARP{
+				AddrType: layers.LinkTypeEthernet, Protocol: layers.EthernetTypeIPv4,
+				HwAddressSize: 6, ProtAddressSize: 4, Operation: layers.ARPRequest,
+				SourceHwAddress: hostMAC, SourceProtAddress: hostIP,
+				DstHwAddress: make([]byte, 6), DstProtAddress: routerIP,
+			},
+		},
+		{
+			eth(layers.EthernetTypeIPv4), ipv4(layers.IPProtocolICMPv4),
+			&layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: 1, Seq: 1},
+		},
+		{
+			eth(layers.EthernetTypeIPv4), dnsIP, dnsUDP,
+			&layers.DNS{ID: 1, RD: true, Questions: []layers.DNSQuestion{{Name: []byte("router.lab"), Type: layers.DNSTypeA, Class: layers.DNSClassIN}}},
+		},
+		{
+			eth(layers.EthernetTypeIPv4), snmpIP, snmpUDP, gopacket.Payload([]byte{0x30, 0x00}),
+		},
+	}
+
+	start := time.Unix(1700000000, 0)
+	for i, frame := range frames {
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, frame...); err != nil {
+			t.Fatalf("serialize frame %d: %v", i, err)
+		}
+		data := buf.Bytes()
+		ci := gopacket.CaptureInfo{Timestamp: start.Add(time.Duration(i) * time.Second), CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(ci, data); err != nil {
+			t.Fatalf("write frame %d: %v", i, err)
+		}
+	}
+	return path
+}
+
+func TestInspectPCAP(t *testing.T) {
+	path := writeInspectPCAP(t)
+
+	inspection, err := inspectPCAP(path)
+	if err != nil {
+		t.Fatalf("inspectPCAP: %v", err)
+	}
+
+	if inspection.Packets != 4 {
+		t.Fatalf("expected 4 packets, got %d", inspection.Packets)
+	}
+	want := map[string]int{"ARP": 1, "IPv4": 3, "ICMP": 1, "UDP": 2, "DNS": 1, "SNMP": 1}
+	for proto, count := range want {
+		if got := inspection.Protocols[proto]; got != count {
+			t.Errorf("expected %d %s packets, got %d (all: %v)", count, proto, got, inspection.Protocols)
+		}
+	}
+	if len(inspection.Protocols) != len(want) {
+		t.Errorf("unexpected protocols: %v", inspection.Protocols)
+	}
+
+	if inspection.Duration != 3 {
+		t.Errorf("expected 3s duration, got %v", inspection.Duration)
+	}
+	if got := inspection.AveragePPS; got < 1.33 || got > 1.34 {
+		t.Errorf("expected ~1.33 pps, got %v", got)
+	}
+	if strings.Join(inspection.Sources, ",") != "10.0.0.5" {
+		t.Errorf("unexpected sources: %v", inspection.Sources)
+	}
+	if strings.Join(inspection.Destinations, ",") != "10.0.0.1" {
+		t.Errorf("unexpected destinations: %v", inspection.Destinations)
+	}
+	if len(inspection.Unreplayable) != 0 {
+		t.Errorf("expected no replay warnings, got %v", inspection.Unreplayable)
+	}
+}
+
+func TestInspectCommandJSON(t *testing.T) {
+	path := writeInspectPCAP(t)
+	orig := inspectOpts
+	t.Cleanup(func() { inspectOpts = orig })
+	inspectOpts.json = true
+
+	var out bytes.Buffer
+	inspectCmd.SetOut(&out)
+	t.Cleanup(func() { inspectCmd.SetOut(nil) })
+	if err := inspectCmd.RunE(inspectCmd, []string{path}); err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+
+	var decoded pcapInspection
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode JSON output: %v\n%s", err, out.String())
+	}
+	if decoded.Packets != 4 || decoded.Protocols["DNS"] != 1 {
+		t.Errorf("unexpected JSON summary: %+v", decoded)
+	}
+}
+
+func TestInspectPCAP_FlagsTruncatedPackets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "truncated.pcap")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create pcap: %v", err)
+	}
+	w := pcapgo.NewWriter(f)
+	if err := w.WriteFileHeader(64, layers.LinkTypeEthernet); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	data := make([]byte, 64)
+	copy(data[12:], []byte{0x08, 0x00})
+	if err := w.WritePacket(gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: 64, Length: 1400}, data); err != nil {
+		t.Fatalf("write packet: %v", err)
+	}
+	f.Close()
+
+	inspection, err := inspectPCAP(path)
+	if err != nil {
+		t.Fatalf("inspectPCAP: %v", err)
+	}
+	if len(inspection.Unreplayable) != 1 || !strings.Contains(inspection.Unreplayable[0], "truncated") {
+		t.Fatalf("expected truncation warning, got %v", inspection.Unreplayable)
+	}
+}
@@ -60,6 +60,10 @@ func runInteractive(cmd *cobra.Command, args []string) {
 		logging.Error("Failed to load configuration: %v", err)
 		os.Exit(1)
 	}
+	if err := applyDeviceFilters(cfg); err != nil {
+		logging.Error("Failed to filter devices: %v", err)
+		os.Exit(1)
+	}
 
 	debugLevel := interactiveOptions.debugLevel
 	if interactiveOptions.verbose {
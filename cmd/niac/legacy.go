@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/krisarmstrong/niac-go/pkg/capture"
 	"github.com/krisarmstrong/niac-go/pkg/config"
@@ -22,6 +25,7 @@ type legacyFlags struct {
 
 	// Information flags
 	showVersion    bool
+	versionJSON    bool
 	listInterfaces bool
 	listDevices    bool
 
@@ -43,6 +47,7 @@ type legacyFlags struct {
 	// Statistics export flags
 	exportStatsJSON string
 	exportStatsCSV  string
+	exportStatsProm string
 
 	// Per-protocol debug levels
 	debugARP     int
@@ -68,10 +73,42 @@ type legacyFlags struct {
 	// Service / API flags
 	apiListen             string
 	apiToken              string
+	apiTokenFile          string
 	metricsListen         string
 	storagePath           string
 	alertPacketsThreshold uint64
 	alertWebhook          string
+	apiRate               float64
+	apiBurst              int
+	snmpDurationBuckets   []float64
+
+	// Device filter flags
+	onlyDevices    string
+	excludeDevices string
+
+	// Randomization seed
+	seed int64
+
+	// Run identifier marked on generated frames
+	runID string
+
+	// Tap interface
+	mirrorInterface string
+
+	// Artifact base directory
+	outputDir string
+
+	// Reject unknown YAML keys
+	strictConfig bool
+
+	// Dead-man's switch: shut down after this long
+	maxRuntime time.Duration
+
+	// Force exit if shutdown takes longer than this
+	shutdownTimeout time.Duration
+
+	// Print per-device counters on shutdown
+	summaryOnExit bool
 }
 
 // defineLegacyFlags defines all command-line flags for legacy mode
@@ -92,6 +129,7 @@ func defineLegacyFlags(flags *legacyFlags) {
 	// Information flags
 	flag.BoolVar(&flags.showVersion, "V", false, "Show version information")
 	flag.BoolVar(&flags.showVersion, "version", false, "Show version information")
+	flag.BoolVar(&flags.versionJSON, "json", false, "With --version, output version information as JSON")
 	flag.BoolVar(&flags.listInterfaces, "l", false, "List available network interfaces")
 	flag.BoolVar(&flags.listInterfaces, "list-interfaces", false, "List available network interfaces")
 	flag.BoolVar(&flags.listDevices, "list-devices", false, "List devices in configuration file")
@@ -115,6 +153,7 @@ func defineLegacyFlags(flags *legacyFlags) {
 	// Statistics export flags
 	flag.StringVar(&flags.exportStatsJSON, "export-stats-json", "", "Export statistics to JSON file on exit")
 	flag.StringVar(&flags.exportStatsCSV, "export-stats-csv", "", "Export statistics to CSV file on exit")
+	flag.StringVar(&flags.exportStatsProm, "export-stats-prom", "", "Export statistics as Prometheus metrics on exit")
 
 	// Per-protocol debug flags (-1 means use global level)
 	flag.IntVar(&flags.debugARP, "debug-arp", -1, "ARP protocol debug level (0-3, default: global level)")
@@ -140,10 +179,36 @@ func defineLegacyFlags(flags *legacyFlags) {
 	// Service / API flags
 	flag.StringVar(&flags.apiListen, "api-listen", "", "Expose REST API and Web UI on this address (e.g., :8080)")
 	flag.StringVar(&flags.apiToken, "api-token", "", "Bearer token required for API/Web UI access")
+	flag.StringVar(&flags.apiTokenFile, "api-token-file", "", "Read the API bearer token from this file, re-read on SIGHUP")
 	flag.StringVar(&flags.metricsListen, "metrics-listen", "", "Expose Prometheus metrics on this address (defaults to --api-listen)")
 	flag.StringVar(&flags.storagePath, "storage-path", "", "Path to NIAC run history database (default: ~/.niac/niac.db)")
 	flag.Uint64Var(&flags.alertPacketsThreshold, "alert-packets-threshold", 0, "Trigger alerts when total packet count exceeds this value")
 	flag.StringVar(&flags.alertWebhook, "alert-webhook", "", "Optional webhook URL to notify when alerts fire")
+	flag.Float64Var(&flags.apiRate, "api-rate", -1, "API requests per second allowed per client IP (0 = no rate limit, default: 100)")
+	flag.IntVar(&flags.apiBurst, "api-burst", -1, "API request burst allowed per client IP (0 = no rate limit, default: 200)")
+	flag.Func("snmp-duration-buckets", "Comma-separated upper bounds in seconds of the SNMP request duration histogram buckets (default: 0.001 to 1)", func(value string) error {
+		for _, field := range strings.Split(value, ",") {
+			seconds, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return err
+			}
+			flags.snmpDurationBuckets = append(flags.snmpDurationBuckets, seconds)
+		}
+		return nil
+	})
+
+	// Device filter flags
+	flag.StringVar(&flags.onlyDevices, "only", "", "Simulate only these devices (comma-separated names or tag:<name> selectors)")
+	flag.StringVar(&flags.excludeDevices, "exclude", "", "Skip these devices (comma-separated names or tag:<name> selectors)")
+
+	flag.Int64Var(&flags.seed, "seed", 0, "Seed for randomized behavior such as discovery phase jitter (0 = random)")
+	flag.StringVar(&flags.runID, "run-id", "", "Tag every generated frame with a marker derived from this run identifier")
+	flag.StringVar(&flags.mirrorInterface, "mirror-interface", "", "Copy every sent packet to this interface (SPAN-like tap)")
+	flag.StringVar(&flags.outputDir, "output-dir", "", "Base directory for generated artifacts (replay uploads, stats exports, run history)")
+	flag.BoolVar(&flags.strictConfig, "strict-config", false, "Fail on unknown keys in YAML configuration files instead of ignoring them")
+	flag.DurationVar(&flags.maxRuntime, "max-runtime", 0, "Shut down gracefully after this long, e.g. 30m (0 = run until stopped)")
+	flag.DurationVar(&flags.shutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "Force exit if stopping the stack and capture takes longer than this (0 = wait indefinitely)")
+	flag.BoolVar(&flags.summaryOnExit, "summary-on-exit", false, "Print per-device packet and protocol counters on shutdown")
 }
 
 // processFlags applies flag transformations (verbose/quiet override)
@@ -157,12 +222,45 @@ func processFlags(flags *legacyFlags) {
 }
 
 func applyLegacyServiceFlags(flags *legacyFlags) {
+	if flags.onlyDevices != "" {
+		deviceFilterOpts.only = flags.onlyDevices
+	}
+	if flags.excludeDevices != "" {
+		deviceFilterOpts.exclude = flags.excludeDevices
+	}
+	if flags.seed != 0 {
+		seedOpts.seed = flags.seed
+	}
+	if flags.runID != "" {
+		runIDOpts.runID = flags.runID
+	}
+	if flags.mirrorInterface != "" {
+		mirrorOpts.iface = flags.mirrorInterface
+	}
+	if flags.outputDir != "" {
+		outputDirOpts.dir = flags.outputDir
+	}
+	if flags.strictConfig {
+		strictConfigOpts.strict = true
+		applyStrictConfig()
+	}
+	if flags.maxRuntime > 0 {
+		maxRuntimeOpts.duration = flags.maxRuntime
+	}
+	shutdownTimeoutOpts.timeout = flags.shutdownTimeout
+	if flags.summaryOnExit {
+		summaryOnExitOpts.enabled = true
+	}
+
 	if flags.apiListen != "" {
 		servicesOpts.apiListen = flags.apiListen
 	}
 	if flags.apiToken != "" {
 		servicesOpts.apiToken = flags.apiToken
 	}
+	if flags.apiTokenFile != "" {
+		servicesOpts.apiTokenFile = flags.apiTokenFile
+	}
 	if flags.metricsListen != "" {
 		servicesOpts.metricsListen = flags.metricsListen
 	}
@@ -175,6 +273,15 @@ func applyLegacyServiceFlags(flags *legacyFlags) {
 	if flags.alertWebhook != "" {
 		servicesOpts.alertWebhook = flags.alertWebhook
 	}
+	if flags.apiRate >= 0 {
+		servicesOpts.apiRate = flags.apiRate
+	}
+	if flags.apiBurst >= 0 {
+		servicesOpts.apiBurst = flags.apiBurst
+	}
+	if len(flags.snmpDurationBuckets) > 0 {
+		servicesOpts.snmpDurationBuckets = flags.snmpDurationBuckets
+	}
 	if servicesOpts.storagePath == "" {
 		servicesOpts.storagePath = defaultStoragePath()
 	}
@@ -251,6 +358,13 @@ func setupDebugConfig(flags *legacyFlags) *logging.DebugConfig {
 func handleInformationalFlags(flags *legacyFlags, args []string) bool {
 	// Handle version flag
 	if flags.showVersion {
+		if flags.versionJSON {
+			if err := writeVersionJSON(os.Stdout); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return true
+		}
 		printVersion()
 		return true
 	}
@@ -302,6 +416,9 @@ func loadAndPrintConfig(configFile, interfaceName string, flags *legacyFlags) (*
 	if err != nil {
 		return nil, fmt.Errorf("loading configuration: %w", err)
 	}
+	if err := applyDeviceFilters(cfg); err != nil {
+		return nil, fmt.Errorf("filtering devices: %w", err)
+	}
 
 	if flags.debugLevel >= 1 {
 		logging.Success("Loaded configuration: %s", configFile)
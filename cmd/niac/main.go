@@ -116,7 +116,7 @@ func runLegacyMode(osArgs []string) {
 	globalStats.SetSNMPDeviceCount(snmpCount)
 
 	// Setup deferred stats export on exit
-	if flags.exportStatsJSON != "" || flags.exportStatsCSV != "" {
+	if flags.exportStatsJSON != "" || flags.exportStatsCSV != "" || flags.exportStatsProm != "" {
 		defer exportStatistics(&flags)
 	}
 
@@ -204,6 +204,7 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("  Information:")
 	fmt.Println("    -V, --version            Show version information")
+	fmt.Println("        --json               With --version, output JSON (version, build, capabilities)")
 	fmt.Println("    -l, --list-interfaces    List available network interfaces")
 	fmt.Println("        --list-devices       List devices in configuration file")
 	fmt.Println("    -h, --help               Show this help message")
@@ -218,6 +219,19 @@ func printUsage() {
 	fmt.Println("        --no-traffic            Disable background traffic generation")
 	fmt.Println("        --snmp-community <str>  Default SNMP community string")
 	fmt.Println("        --max-packet-size <n>   Maximum packet size [default: 1514]")
+	fmt.Println("        --only <selectors>      Simulate only matching devices (names or tag:<name>)")
+	fmt.Println("        --exclude <selectors>   Skip matching devices (names or tag:<name>)")
+	fmt.Println("        --seed <n>              Seed for randomized behavior (reproducible runs)")
+	fmt.Println("        --run-id <id>           Tag generated frames with a marker derived from <id>")
+	fmt.Println("        --mirror-interface <if> Copy every sent packet to a second interface")
+	fmt.Println("        --output-dir <dir>      Base directory for replay uploads, stats exports, run history")
+	fmt.Println("        --strict-config         Fail on unknown keys in YAML configuration files")
+	fmt.Println("        --max-runtime <dur>     Shut down gracefully after this long (e.g. 30m)")
+	fmt.Println("        --shutdown-timeout <dur> Force exit if shutdown takes longer (0 = wait) [default: 10s]")
+	fmt.Println("        --summary-on-exit       Print per-device packet and protocol counters on shutdown")
+	fmt.Println("        --api-rate <n>          API requests per second per client IP (0 = unlimited) [default: 100]")
+	fmt.Println("        --api-burst <n>         API request burst per client IP (0 = unlimited) [default: 200]")
+	fmt.Println("        --snmp-duration-buckets <s,...> SNMP request duration histogram bounds in seconds [default: 0.001 to 1]")
 	fmt.Println()
 	fmt.Println("  Performance Profiling:")
 	fmt.Println("    -p, --profile            Enable pprof performance profiling")
@@ -226,6 +240,7 @@ func printUsage() {
 	fmt.Println("  Statistics Export:")
 	fmt.Println("        --export-stats-json <file>  Export runtime statistics to JSON file on exit")
 	fmt.Println("        --export-stats-csv <file>   Export runtime statistics to CSV file on exit")
+	fmt.Println("        --export-stats-prom <file>  Export runtime statistics as Prometheus metrics on exit")
 	fmt.Println()
 	fmt.Println("  Per-Protocol Debug Levels:")
 	fmt.Println("        --debug-arp <level>     ARP protocol debug level (0-3)")
@@ -415,11 +430,17 @@ func startSimulation(interfaceName string, cfg *config.Config, debugConfig *logg
 		fmt.Print("⏳ Creating protocol stack... ")
 	}
 	stack := protocols.NewStack(engine, cfg, debugConfig)
+	if seedOpts.seed != 0 {
+		stack.SetRandomSeed(uint64(seedOpts.seed))
+	}
+	if runIDOpts.runID != "" {
+		stack.SetRunID(runIDOpts.runID)
+	}
 	if debugLevel >= 1 {
 		fmt.Println("✓")
 	}
 
-	dhcpCount, dnsCount := configureServiceHandlers(stack, cfg, debugLevel)
+	dhcpCount, dnsCount := countServiceHandlers(cfg)
 	if debugLevel >= 1 && (dhcpCount > 0 || dnsCount > 0) {
 		if dhcpCount > 0 {
 			fmt.Printf("⏳ Configuring DHCP servers (%d)... ✓\n", dhcpCount)
@@ -441,7 +462,7 @@ func startSimulation(interfaceName string, cfg *config.Config, debugConfig *logg
 	}
 	if debugLevel >= 1 {
 		fmt.Println("✓")
-		printStartupSummary(cfg, debugLevel)
+		printStartupSummary(cfg, stack.SNMPWalkOIDs(), debugLevel)
 	}
 
 	return engine, stack, time.Now(), nil
@@ -453,21 +474,28 @@ func runNormalMode(interfaceName string, cfg *config.Config, debugConfig *loggin
 	if err != nil {
 		return err
 	}
-	defer engine.Close()
-	defer stack.Stop()
+	var services *runtimeServices
+	teardown := newShutdownDeadline(shutdownTimeoutOpts.timeout)
+	defer func() {
+		// Bounded, since closing the pcap handle of a wedged NIC can block forever
+		_ = teardown.stop(func() {
+			if services != nil {
+				services.Stop()
+			}
+			recordStackStatistics(stack)
+			stack.Stop()
+			engine.Close()
+		})
+	}()
 
-	services, err := startRuntimeServices(engine, stack, cfg, interfaceName, configFile)
+	services, err = startRuntimeServices(engine, stack, cfg, interfaceName, configFile)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if services != nil {
-			services.Stop()
-		}
-	}()
 
 	reloadFunc := buildReloadFunc(stack, configFile, services)
-	return runSimulationLoop(stack, debugConfig.GetGlobal(), startTime, reloadFunc)
+	return runSimulationLoop(stack, debugConfig.GetGlobal(), startTime, maxRuntimeOpts.duration,
+		teardown, len(cfg.Devices), reloadFunc)
 }
 
 // runInteractiveMode runs NIAC with the interactive TUI layered on the live simulator
@@ -485,6 +513,7 @@ func runInteractiveMode(interfaceName string, cfg *config.Config, debugConfig *l
 	}
 
 	defer func() {
+		recordStackStatistics(stack)
 		stack.Stop()
 		engine.Close()
 		if services != nil {
@@ -511,68 +540,27 @@ func initializeCaptureEngine(interfaceName string, debugLevel int) (*capture.Eng
 	if debugLevel >= 1 {
 		fmt.Println("✓")
 	}
+	attachMirror(engine, debugLevel)
 	return engine, nil
 }
 
-// configureServiceHandlers configures DHCP and DNS service handlers
-func configureServiceHandlers(stack *protocols.Stack, cfg *config.Config, debugLevel int) (dhcpCount, dnsCount int) {
+// countServiceHandlers counts the devices serving DHCP and DNS. The stack
+// configures their handlers from the config.
+func countServiceHandlers(cfg *config.Config) (dhcpCount, dnsCount int) {
 	for _, device := range cfg.Devices {
-		// Configure DHCP if present
 		if device.DHCPConfig != nil && len(device.IPAddresses) > 0 {
 			dhcpCount++
-			dhcp := device.DHCPConfig
-			dhcpHandler := stack.GetDHCPHandler()
-			dhcpv6Handler := stack.GetDHCPv6Handler()
-
-			// Basic DHCPv4 configuration
-			if len(dhcp.DomainNameServer) > 0 || dhcp.Router != nil {
-				dhcpHandler.SetServerConfig(
-					device.IPAddresses[0], // Server IP
-					dhcp.Router,           // Gateway
-					dhcp.DomainNameServer, // DNS servers
-					dhcp.DomainName,       // Domain name
-				)
-			}
-
-			// Advanced DHCPv4 options
-			if len(dhcp.NTPServers) > 0 || len(dhcp.DomainSearch) > 0 || dhcp.TFTPServerName != "" || dhcp.BootfileName != "" {
-				dhcpHandler.SetAdvancedOptions(
-					dhcp.NTPServers,
-					dhcp.DomainSearch,
-					dhcp.TFTPServerName,
-					dhcp.BootfileName,
-					dhcp.VendorSpecific,
-				)
-			}
-
-			// DHCPv6 configuration
-			if len(dhcp.SNTPServersV6) > 0 || len(dhcp.NTPServersV6) > 0 || len(dhcp.SIPServersV6) > 0 || len(dhcp.SIPDomainsV6) > 0 {
-				dhcpv6Handler.SetAdvancedOptions(
-					dhcp.SNTPServersV6,
-					dhcp.NTPServersV6,
-					dhcp.SIPServersV6,
-					dhcp.SIPDomainsV6,
-				)
-			}
 		}
-
-		// Configure DNS if present
 		if device.DNSConfig != nil {
 			dnsCount++
-			dnsHandler := stack.GetDNSHandler()
-
-			// Load DNS records
-			for _, record := range device.DNSConfig.ForwardRecords {
-				dnsHandler.AddRecord(record.Name, record.IP)
-			}
-			// PTR records are handled automatically by AddRecord
 		}
 	}
 	return dhcpCount, dnsCount
 }
 
-// printStartupSummary displays the enabled features summary
-func printStartupSummary(cfg *config.Config, debugLevel int) {
+// printStartupSummary displays the enabled features summary. walkOIDs is the
+// number of OIDs loaded from SNMP walk files.
+func printStartupSummary(cfg *config.Config, walkOIDs int, debugLevel int) {
 	fmt.Println()
 
 	// Display enabled features summary
@@ -590,7 +578,7 @@ func printStartupSummary(cfg *config.Config, debugLevel int) {
 		}
 	}
 	if snmpCount > 0 {
-		fmt.Printf("  • SNMP agents: %d device(s)\n", snmpCount)
+		fmt.Printf("  • SNMP agents: %d device(s), %d OIDs loaded from walk files\n", snmpCount, walkOIDs)
 		if trapCount > 0 {
 			fmt.Printf("  • SNMP traps: %d device(s)\n", trapCount)
 		}
@@ -618,11 +606,19 @@ func printStartupSummary(cfg *config.Config, debugLevel int) {
 	fmt.Println()
 }
 
-// runSimulationLoop runs the main simulation loop with signal handling and stats
-func runSimulationLoop(stack *protocols.Stack, debugLevel int, startTime time.Time, reloadConfig func() (*config.Config, error)) error {
+// runSimulationLoop runs the main simulation loop with signal handling and
+// stats. A positive maxRuntime shuts the simulation down once it elapses,
+// exactly as SIGTERM would. Stopping the stack is the first teardown stage,
+// sharing one shutdown timeout with the caller's later stages. SIGHUP
+// re-applies the config file through reloadConfig; if it fails, the running
+// config (deviceCount devices) stays.
+func runSimulationLoop(stack *protocols.Stack, debugLevel int, startTime time.Time, maxRuntime time.Duration, teardown *shutdownDeadline,
+	deviceCount int, reloadConfig func() (*config.Config, error)) error {
+
 	// Setup signal handler for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
 
 	// Stats ticker (print stats every 10 seconds if debug >= 1)
 	var statsTicker *time.Ticker
@@ -633,6 +629,31 @@ func runSimulationLoop(stack *protocols.Stack, debugLevel int, startTime time.Ti
 		defer statsTicker.Stop()
 	}
 
+	// Dead-man's switch
+	var deadlineC <-chan time.Time
+	if maxRuntime > 0 {
+		deadline := time.NewTimer(maxRuntime)
+		deadlineC = deadline.C
+		defer deadline.Stop()
+	}
+
+	shutdown := func() error {
+		fmt.Println("Shutting down...")
+		if err := teardown.stop(stack.Stop); err != nil {
+			return err
+		}
+
+		// Print final stats
+		if debugLevel >= 1 {
+			printFinalStats(stack, time.Since(startTime))
+		}
+		if summaryOnExitOpts.enabled {
+			printDeviceSummary(os.Stdout, stack.GetDeviceStats())
+		}
+
+		return nil
+	}
+
 	// Main loop
 	for {
 		select {
@@ -642,9 +663,10 @@ func runSimulationLoop(stack *protocols.Stack, debugLevel int, startTime time.Ti
 					fmt.Println()
 					fmt.Println("Reloading configuration...")
 					if cfg, err := reloadConfig(); err != nil {
-						fmt.Printf("Reload failed: %v\n", err)
-					} else if cfg != nil && debugLevel >= 1 {
-						fmt.Printf("Reloaded configuration (%d devices)\n", len(cfg.Devices))
+						fmt.Printf("Reload failed, keeping the current configuration: %v\n", err)
+					} else if cfg != nil {
+						fmt.Printf("Reloaded configuration: %d -> %d devices\n", deviceCount, len(cfg.Devices))
+						deviceCount = len(cfg.Devices)
 					}
 				} else {
 					fmt.Println()
@@ -654,15 +676,12 @@ func runSimulationLoop(stack *protocols.Stack, debugLevel int, startTime time.Ti
 			}
 
 			fmt.Println()
-			fmt.Println("Shutting down...")
-			stack.Stop()
+			return shutdown()
 
-			// Print final stats
-			if debugLevel >= 1 {
-				printFinalStats(stack, time.Since(startTime))
-			}
-
-			return nil
+		case <-deadlineC:
+			fmt.Println()
+			fmt.Printf("Maximum runtime of %s reached\n", maxRuntime)
+			return shutdown()
 
 		case <-statsC:
 			// Print periodic stats
@@ -725,19 +744,23 @@ func buildReloadFunc(stack *protocols.Stack, configFile string, services *runtim
 		abs = configFile
 	}
 	return func() (*config.Config, error) {
+		// A rotated API token applies even if the config fails to reload
+		if err := services.reloadAPIToken(); err != nil {
+			fmt.Printf("API token reload failed, keeping the current token: %v\n", err)
+		}
 		newCfg, err := config.Load(abs)
 		if err != nil {
 			return nil, err
 		}
+		if err := applyDeviceFilters(newCfg); err != nil {
+			return nil, err
+		}
 		if services != nil {
 			if err := services.applyConfig(newCfg); err != nil {
 				return nil, err
 			}
-		} else {
-			if err := stack.ReloadConfig(newCfg); err != nil {
-				return nil, err
-			}
-			configureServiceHandlers(stack, newCfg, stack.GetDebugLevel())
+		} else if err := stack.ReloadConfig(newCfg); err != nil {
+			return nil, err
 		}
 		return newCfg, nil
 	}
@@ -765,19 +788,56 @@ func exportStatistics(flags *legacyFlags) {
 
 	// Export to JSON if requested
 	if flags.exportStatsJSON != "" {
-		if err := globalStats.ExportJSON(flags.exportStatsJSON); err != nil {
+		path := resolveOutputPath(flags.exportStatsJSON)
+		if err := globalStats.ExportJSON(path); err != nil {
 			logging.Error("Failed to export statistics to JSON: %v", err)
 		} else {
-			logging.Info("Statistics exported to JSON: %s", flags.exportStatsJSON)
+			logging.Info("Statistics exported to JSON: %s", path)
 		}
 	}
 
 	// Export to CSV if requested
 	if flags.exportStatsCSV != "" {
-		if err := globalStats.ExportCSV(flags.exportStatsCSV); err != nil {
+		path := resolveOutputPath(flags.exportStatsCSV)
+		if err := globalStats.ExportCSV(path); err != nil {
 			logging.Error("Failed to export statistics to CSV: %v", err)
 		} else {
-			logging.Info("Statistics exported to CSV: %s", flags.exportStatsCSV)
+			logging.Info("Statistics exported to CSV: %s", path)
 		}
 	}
+
+	// Export Prometheus metrics if requested
+	if flags.exportStatsProm != "" {
+		path := resolveOutputPath(flags.exportStatsProm)
+		if err := exportStatsPrometheus(path); err != nil {
+			logging.Error("Failed to export statistics as Prometheus metrics: %v", err)
+		} else {
+			logging.Info("Statistics exported as Prometheus metrics: %s", path)
+		}
+	}
+}
+
+// exportStatsPrometheus writes the final statistics to path in the
+// Prometheus text format
+func exportStatsPrometheus(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	if err := globalStats.ExportPrometheus(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// recordStackStatistics captures the stack counters for the exit-time
+// statistics export
+func recordStackStatistics(stack *protocols.Stack) {
+	if globalStats == nil {
+		return
+	}
+	counters := stack.GetStats()
+	globalStats.SetStackCounters(counters.Counters())
+	globalStats.SetDeviceSummaries(stack.GetDeviceStats())
 }
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
 )
 
 // TestPadRight tests string padding functionality
@@ -82,3 +89,92 @@ func TestPrintUsage(t *testing.T) {
 	}()
 	printUsage()
 }
+
+// TestRunSimulationLoop_MaxRuntime tests that the loop shuts down on its own
+// once the maximum runtime elapses, printing final stats like SIGTERM does
+func TestRunSimulationLoop_MaxRuntime(t *testing.T) {
+	stack := protocols.NewStack(nil, &config.Config{}, logging.NewDebugConfig(1))
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- runSimulationLoop(stack, 1, start, 50*time.Millisecond, newShutdownDeadline(time.Second), 0, nil)
+		w.Close()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runSimulationLoop returned %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runSimulationLoop did not exit after its maximum runtime")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("loop exited after %v, before the 50ms maximum runtime", elapsed)
+	}
+
+	output, _ := io.ReadAll(r)
+	for _, want := range []string{"Maximum runtime of 50ms reached", "Shutting down...", "Final Statistics"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+// TestStopWithDeadline_BlockedHandler tests that shutdown gives up once its
+// deadline passes when stopping blocks, forcing a non-zero exit
+func TestStopWithDeadline_BlockedHandler(t *testing.T) {
+	exitCode := -1
+	forceExit = func(code int) { exitCode = code }
+	defer func() { forceExit = os.Exit }()
+
+	release := make(chan struct{})
+	defer close(release)
+	blocked := func() { <-release } // A handler wedged on a dead NIC
+
+	start := time.Now()
+	err := stopWithDeadline(blocked, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %v, want about the 50ms deadline", elapsed)
+	}
+	if err == nil || exitCode != 1 {
+		t.Errorf("stopWithDeadline = %v with exit code %d, want an error and exit code 1", err, exitCode)
+	}
+
+	// A prompt stop neither errors nor exits
+	exitCode = -1
+	if err := stopWithDeadline(func() {}, time.Second); err != nil || exitCode != -1 {
+		t.Errorf("prompt stop: err %v, exit code %d; want nil and no exit", err, exitCode)
+	}
+}
+
+// TestShutdownDeadline_SharedAcrossStages tests that shutdown stages share
+// one timeout rather than each getting the full timeout
+func TestShutdownDeadline_SharedAcrossStages(t *testing.T) {
+	exitCode := -1
+	forceExit = func(code int) { exitCode = code }
+	defer func() { forceExit = os.Exit }()
+
+	teardown := newShutdownDeadline(200 * time.Millisecond)
+	stage := func() { time.Sleep(120 * time.Millisecond) }
+	if err := teardown.stop(stage); err != nil || exitCode != -1 {
+		t.Fatalf("first stage: err %v, exit code %d; want it to finish within the timeout", err, exitCode)
+	}
+
+	start := time.Now()
+	if err := teardown.stop(stage); err == nil || exitCode != 1 {
+		t.Errorf("second stage: err %v, exit code %d; want the shared deadline to force an exit", err, exitCode)
+	}
+	if elapsed := time.Since(start); elapsed >= 120*time.Millisecond {
+		t.Errorf("second stage ran %v, want it cut off at the remaining ~80ms", elapsed)
+	}
+}
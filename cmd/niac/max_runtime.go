@@ -0,0 +1,12 @@
+package main
+
+import "time"
+
+// maxRuntimeOptions bounds how long a simulation runs. Once the duration
+// elapses NIAC shuts down as if it had received SIGTERM, so a hung CI job
+// cannot leave it capturing forever. Zero means no limit.
+type maxRuntimeOptions struct {
+	duration time.Duration
+}
+
+var maxRuntimeOpts = maxRuntimeOptions{}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/krisarmstrong/niac-go/pkg/capture"
+)
+
+// mirrorOptions configures the optional tap interface that receives a copy of
+// every packet the simulator sends.
+type mirrorOptions struct {
+	iface string
+}
+
+var mirrorOpts = mirrorOptions{}
+
+// attachMirror opens --mirror-interface on the engine. A mirror that cannot be
+// opened is reported and skipped so the primary interface keeps running.
+func attachMirror(engine *capture.Engine, debugLevel int) {
+	if mirrorOpts.iface == "" {
+		return
+	}
+	if debugLevel >= 1 {
+		fmt.Printf("⏳ Opening mirror interface %s... ", mirrorOpts.iface)
+	}
+	if err := engine.SetMirror(mirrorOpts.iface); err != nil {
+		if debugLevel >= 1 {
+			fmt.Println("❌")
+		}
+		fmt.Printf("Warning: mirroring disabled: %v\n", err)
+		return
+	}
+	if debugLevel >= 1 {
+		fmt.Println("✓")
+	}
+}
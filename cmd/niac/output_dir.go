@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// outputDirMode keeps generated artifacts private to the simulator's user and group.
+const outputDirMode = 0o750
+
+// outputDirOptions configures the base directory for generated artifacts
+// (replay uploads, statistics exports, run history).
+type outputDirOptions struct {
+	dir string
+}
+
+var outputDirOpts = outputDirOptions{}
+
+// prepareOutputDir creates --output-dir if it was given. It is a no-op when
+// the flag is unset so the historical temp/CWD locations keep working.
+func prepareOutputDir() error {
+	if outputDirOpts.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(outputDirOpts.dir, outputDirMode); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+	return nil
+}
+
+// resolveOutputPath anchors relative artifact paths under --output-dir.
+// Absolute paths, and all paths when no output dir is configured, are
+// returned unchanged.
+func resolveOutputPath(path string) string {
+	if path == "" || outputDirOpts.dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(outputDirOpts.dir, path)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveOutputPath(t *testing.T) {
+	orig := outputDirOpts
+	t.Cleanup(func() { outputDirOpts = orig })
+
+	outputDirOpts.dir = ""
+	if got := resolveOutputPath("stats.json"); got != "stats.json" {
+		t.Fatalf("expected path unchanged without output dir, got %q", got)
+	}
+
+	dir := t.TempDir()
+	outputDirOpts.dir = dir
+	if got, want := resolveOutputPath("stats.json"), filepath.Join(dir, "stats.json"); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	abs := filepath.Join(t.TempDir(), "stats.csv")
+	if got := resolveOutputPath(abs); got != abs {
+		t.Fatalf("expected absolute path unchanged, got %q", got)
+	}
+	if got, want := defaultStoragePath(), filepath.Join(dir, "niac.db"); got != want {
+		t.Fatalf("expected storage under output dir %q, got %q", want, got)
+	}
+}
+
+func TestDaemonStoragePath(t *testing.T) {
+	origDir, origDaemon := outputDirOpts, daemonOpts
+	t.Cleanup(func() { outputDirOpts, daemonOpts = origDir, origDaemon })
+
+	daemonOpts.storagePath = "~/.niac/niac.db"
+	outputDirOpts.dir = ""
+	if got := daemonStoragePath(false); got != "~/.niac/niac.db" {
+		t.Fatalf("expected the --storage default without output dir, got %q", got)
+	}
+
+	dir := t.TempDir()
+	outputDirOpts.dir = dir
+	if got, want := daemonStoragePath(false), filepath.Join(dir, "niac.db"); got != want {
+		t.Fatalf("expected storage under output dir %q, got %q", want, got)
+	}
+	daemonOpts.storagePath = "disabled"
+	if got := daemonStoragePath(true); got != "disabled" {
+		t.Fatalf("expected an explicit --storage to win, got %q", got)
+	}
+}
+
+func TestPrepareOutputDir(t *testing.T) {
+	orig := outputDirOpts
+	t.Cleanup(func() { outputDirOpts = orig })
+
+	outputDirOpts.dir = filepath.Join(t.TempDir(), "artifacts", "run1")
+	if err := prepareOutputDir(); err != nil {
+		t.Fatalf("prepareOutputDir: %v", err)
+	}
+	info, err := os.Stat(outputDirOpts.dir)
+	if err != nil {
+		t.Fatalf("stat output dir: %v", err)
+	}
+	if !info.IsDir() {
+		t.Fatalf("expected %s to be a directory", outputDirOpts.dir)
+	}
+	if perm := info.Mode().Perm(); perm&0o007 != 0 {
+		t.Fatalf("expected no world access on output dir, got %o", perm)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/capture"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// replayProgressInterval is how often `niac replay` prints its progress
+const replayProgressInterval = 2 * time.Second
+
+type replayOptions struct {
+	iface string
+	scale float64
+	loop  bool
+	count int
+	debug int
+}
+
+var replayOpts = replayOptions{}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay --interface <if> <file.pcap>",
+	Short: "Replay a capture onto an interface",
+	Long: `Send the packets of a PCAP file out of an interface with their original
+timing, without a configuration file or the simulator. Progress is printed while
+the capture plays and a summary when it ends. Ctrl+C stops the replay early.
+
+Use 'niac inspect' first to check what the capture will send.`,
+	Example: `  sudo niac replay --interface eth0 capture.pcap
+  sudo niac replay --interface eth0 --scale 0.5 --count 3 capture.pcap
+  sudo niac replay --interface eth0 --loop capture.pcap`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		playback, err := replayOpts.playbackConfig(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(playback.FileName); err != nil {
+			return fmt.Errorf("PCAP file not found: %s: %w", playback.FileName, err)
+		}
+
+		engine, err := capture.New(replayOpts.iface, replayOpts.debug)
+		if err != nil {
+			return err
+		}
+		defer engine.Close()
+		attachMirror(engine, replayOpts.debug)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Replaying %s on %s\n", playback.FileName, replayOpts.iface)
+		result, err := replayCapture(ctx, engine, playback, out, replayProgressInterval, replayOpts.debug)
+		printReplaySummary(out, result)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringVarP(&replayOpts.iface, "interface", "i", "", "Interface to send the packets on")
+	replayCmd.Flags().Float64Var(&replayOpts.scale, "scale", 1.0, "Multiply the gaps between packets (0.5 = twice as fast)")
+	replayCmd.Flags().BoolVar(&replayOpts.loop, "loop", false, "Replay the capture until interrupted")
+	replayCmd.Flags().IntVar(&replayOpts.count, "count", 1, "Number of times to replay the capture")
+	replayCmd.Flags().IntVarP(&replayOpts.debug, "debug", "d", 0, "Debug level (0-3)")
+	_ = replayCmd.MarkFlagRequired("interface")
+}
+
+// playbackConfig validates the flags and returns the playback configuration
+// for file
+func (o replayOptions) playbackConfig(file string) (*config.CapturePlayback, error) {
+	if o.scale <= 0 {
+		return nil, fmt.Errorf("--scale must be greater than 0: %g", o.scale)
+	}
+	if o.count < 1 {
+		return nil, fmt.Errorf("--count must be at least 1: %d", o.count)
+	}
+	playback := &config.CapturePlayback{FileName: file, ScaleTime: o.scale, LoopCount: o.count}
+	if o.loop {
+		if o.count != 1 {
+			return nil, fmt.Errorf("--loop and --count cannot be combined")
+		}
+		playback.LoopCount = -1 // Until interrupted
+	}
+	return playback, nil
+}
+
+// replayCapture plays a capture through engine and waits until it has been
+// played the configured number of times or ctx is cancelled, printing the
+// progress to out every progressEvery. It returns the final result, and an
+// error when playback could not finish.
+func replayCapture(ctx context.Context, engine *capture.Engine, playback *config.CapturePlayback, out io.Writer, progressEvery time.Duration, debugLevel int) (capture.PlaybackResult, error) {
+	player := capture.NewPlaybackEngine(engine, playback, debugLevel)
+	done := make(chan capture.PlaybackResult, 1)
+	player.SetOnComplete(func(result capture.PlaybackResult) {
+		done <- result
+	})
+	if err := player.Start(); err != nil {
+		return capture.PlaybackResult{}, err
+	}
+
+	ticker := time.NewTicker(progressEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case result := <-done:
+			if result.Error != "" {
+				return result, fmt.Errorf("replay failed: %s", result.Error)
+			}
+			return result, nil
+		case <-ctx.Done():
+			player.Stop()
+			return player.Result(), nil
+		case <-ticker.C:
+			progress := player.Result()
+			fmt.Fprintf(out, "  %d packets (%d bytes), %d loop(s) completed, %s elapsed\n",
+				progress.PacketsSent, progress.BytesSent, progress.LoopsCompleted, progress.Duration.Round(time.Second))
+		}
+	}
+}
+
+// printReplaySummary prints the final counts of a replay
+func printReplaySummary(out io.Writer, result capture.PlaybackResult) {
+	status := "completed"
+	switch {
+	case result.Error != "":
+		status = "failed"
+	case !result.Completed:
+		status = "stopped"
+	}
+	fmt.Fprintf(out, "Replay %s: %d packets (%d bytes) sent, %d loop(s), %d send errors in %s\n",
+		status, result.PacketsSent, result.BytesSent, result.LoopsCompleted, result.SendErrors, result.Duration.Round(time.Millisecond))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/pcap"
+	"github.com/krisarmstrong/niac-go/pkg/capture"
+)
+
+// countingWriter records how many frames the capture engine sends
+type countingWriter struct {
+	mu     sync.Mutex
+	frames int
+}
+
+func (w *countingWriter) WritePacketData(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.frames++
+	return nil
+}
+
+func TestReplayCapture(t *testing.T) {
+	path := writeInspectPCAP(t)
+	if handle, err := pcap.OpenOffline(path); err != nil {
+		t.Skipf("Cannot read PCAP files: %v", err)
+	} else {
+		handle.Close()
+	}
+
+	opts := replayOptions{iface: "test0", scale: 0.001, count: 2}
+	playback, err := opts.playbackConfig(path)
+	if err != nil {
+		t.Fatalf("playbackConfig: %v", err)
+	}
+	writer := &countingWriter{}
+	engine := capture.NewWithWriter("test0", writer, 0)
+
+	var out bytes.Buffer
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := replayCapture(ctx, engine, playback, &out, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("replayCapture: %v", err)
+	}
+	printReplaySummary(&out, result)
+
+	if result.PacketsSent != 8 || result.LoopsCompleted != 2 || !result.Completed {
+		t.Errorf("result = %+v, want 8 packets over 2 completed loops", result)
+	}
+	if writer.frames != 8 {
+		t.Errorf("engine sent %d frames, want 8", writer.frames)
+	}
+	if summary := out.String(); !strings.Contains(summary, "Replay completed: 8 packets") {
+		t.Errorf("summary = %q, want it to report 8 packets", summary)
+	}
+}
+
+func TestReplayOptionsValidation(t *testing.T) {
+	for name, opts := range map[string]replayOptions{
+		"zero scale":     {scale: 0, count: 1},
+		"zero count":     {scale: 1, count: 0},
+		"loop and count": {scale: 1, count: 3, loop: true},
+	} {
+		if _, err := opts.playbackConfig("capture.pcap"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	playback, err := replayOptions{scale: 1, count: 1, loop: true}.playbackConfig("capture.pcap")
+	if err != nil || playback.LoopCount >= 0 {
+		t.Errorf("--loop: playback = %+v, err = %v; want a negative loop count", playback, err)
+	}
+}
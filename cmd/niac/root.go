@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/krisarmstrong/niac-go/pkg/api"
 	"github.com/spf13/cobra"
 )
 
@@ -58,16 +59,35 @@ and network discovery without physical hardware.`,
 }
 
 func init() {
-	cobra.OnInitialize(resolveServiceDefaults)
-	rootCmd.SetVersionTemplate(fmt.Sprintf("niac %s (commit: %s, built: %s)\n", version, commit, date))
+	cobra.OnInitialize(resolveServiceDefaults, applyStrictConfig)
+	cobra.AddTemplateFunc("versionOutput", rootVersionOutput)
+	rootCmd.SetVersionTemplate("{{versionOutput}}")
+	rootCmd.Flags().BoolVar(&versionOpts.json, "json", false, "With --version, output version information as JSON")
 
 	rootCmd.PersistentFlags().StringVar(&servicesOpts.apiListen, "api-listen", "", "Expose the REST API and Web UI on this address (e.g., :8080)")
 	// SECURITY FIX #101: Deprecate --api-token flag in favor of environment variable
 	rootCmd.PersistentFlags().StringVar(&servicesOpts.apiToken, "api-token", "", "Bearer token required for API/Web UI access (DEPRECATED: use NIAC_API_TOKEN env var)")
+	rootCmd.PersistentFlags().StringVar(&servicesOpts.apiTokenFile, "api-token-file", "", "Read the API bearer token from this file, re-read on SIGHUP (or NIAC_API_TOKEN_FILE)")
 	rootCmd.PersistentFlags().StringVar(&servicesOpts.metricsListen, "metrics-listen", "", "Expose Prometheus metrics on this address (defaults to --api-listen)")
 	rootCmd.PersistentFlags().StringVar(&servicesOpts.storagePath, "storage-path", "", "Path to NIAC run history database (default: ~/.niac/niac.db)")
 	rootCmd.PersistentFlags().Uint64Var(&servicesOpts.alertPacketsThreshold, "alert-packets-threshold", 0, "Trigger alerts when total packets exceed this value")
 	rootCmd.PersistentFlags().StringVar(&servicesOpts.alertWebhook, "alert-webhook", "", "Optional webhook URL to notify when alerts fire")
+	rootCmd.PersistentFlags().Float64Var(&servicesOpts.apiRate, "api-rate", api.DefaultRateLimit, "API requests per second allowed per client IP (0 = no rate limit)")
+	rootCmd.PersistentFlags().IntVar(&servicesOpts.apiBurst, "api-burst", api.DefaultBurst, "API request burst allowed per client IP (0 = no rate limit)")
+	rootCmd.PersistentFlags().IntVar(&servicesOpts.maxReplays, "max-replays", api.DefaultMaxReplays, "PCAP replays the API runs at once; further replays queue until one ends")
+	rootCmd.PersistentFlags().DurationVar(&servicesOpts.replayUploadMaxAge, "replay-upload-max-age", api.DefaultUploadMaxAge, "Remove PCAPs uploaded for replay after this long (0 = keep them)")
+	rootCmd.PersistentFlags().Float64SliceVar(&servicesOpts.snmpDurationBuckets, "snmp-duration-buckets", defaultSNMPDurationSeconds(), "Upper bounds in seconds of the niac_snmp_request_duration_seconds histogram buckets")
+	rootCmd.PersistentFlags().StringVar(&servicesOpts.uiDir, "ui-dir", "", "Serve the Web UI from this directory instead of the embedded assets (UI development)")
+	rootCmd.PersistentFlags().StringVar(&deviceFilterOpts.only, "only", "", "Simulate only these devices (comma-separated names or tag:<name> selectors)")
+	rootCmd.PersistentFlags().StringVar(&deviceFilterOpts.exclude, "exclude", "", "Skip these devices (comma-separated names or tag:<name> selectors)")
+	rootCmd.PersistentFlags().StringVar(&mirrorOpts.iface, "mirror-interface", "", "Copy every sent packet to this interface (SPAN-like tap)")
+	rootCmd.PersistentFlags().StringVar(&outputDirOpts.dir, "output-dir", "", "Base directory for generated artifacts (replay uploads, stats exports, run history)")
+	rootCmd.PersistentFlags().Int64Var(&seedOpts.seed, "seed", 0, "Seed for randomized behavior such as discovery phase jitter (0 = random)")
+	rootCmd.PersistentFlags().StringVar(&runIDOpts.runID, "run-id", "", "Tag every generated frame with a marker derived from this run identifier")
+	rootCmd.PersistentFlags().BoolVar(&strictConfigOpts.strict, "strict-config", false, "Fail on unknown keys in YAML configuration files instead of ignoring them")
+	rootCmd.PersistentFlags().DurationVar(&maxRuntimeOpts.duration, "max-runtime", 0, "Shut down gracefully after this long, e.g. 30m (0 = run until stopped)")
+	rootCmd.PersistentFlags().DurationVar(&shutdownTimeoutOpts.timeout, "shutdown-timeout", defaultShutdownTimeout, "Force exit if stopping the stack and capture takes longer than this (0 = wait indefinitely)")
+	rootCmd.PersistentFlags().BoolVar(&summaryOnExitOpts.enabled, "summary-on-exit", false, "Print per-device packet and protocol counters on shutdown")
 }
 
 func Execute() {
@@ -0,0 +1,9 @@
+package main
+
+// runIDOptions identifies this simulator instance in captures. A run ID
+// derives an Ethernet trailer marker appended to every generated frame.
+type runIDOptions struct {
+	runID string
+}
+
+var runIDOpts = runIDOptions{}
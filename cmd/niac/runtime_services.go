@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +15,8 @@ import (
 	"github.com/krisarmstrong/niac-go/pkg/capture"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/protocols"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+	"github.com/krisarmstrong/niac-go/pkg/stats"
 	"github.com/krisarmstrong/niac-go/pkg/storage"
 )
 
@@ -28,6 +31,7 @@ type runtimeServices struct {
 	configPath    string
 	deviceCount   int
 	replay        api.ReplayManager
+	tokenFile     string // API token file re-read on reload ("" = none)
 }
 
 func startRuntimeServices(engine *capture.Engine, stack *protocols.Stack, cfg *config.Config, interfaceName, configFile string) (*runtimeServices, error) {
@@ -36,6 +40,26 @@ func startRuntimeServices(engine *capture.Engine, stack *protocols.Stack, cfg *c
 		configPath = abs
 	}
 
+	if err := validateAPIRateLimit(); err != nil {
+		return nil, err
+	}
+	if servicesOpts.replayUploadMaxAge < 0 {
+		return nil, fmt.Errorf("--replay-upload-max-age must not be negative (0 keeps uploads)")
+	}
+	if servicesOpts.maxReplays < 1 {
+		return nil, fmt.Errorf("--max-replays must be at least 1")
+	}
+	if err := validateUIDir(); err != nil {
+		return nil, err
+	}
+	durationBuckets, err := snmpDurationBuckets()
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareOutputDir(); err != nil {
+		return nil, err
+	}
+
 	rs := &runtimeServices{
 		stack:         stack,
 		engine:        engine,
@@ -46,7 +70,6 @@ func startRuntimeServices(engine *capture.Engine, stack *protocols.Stack, cfg *c
 		deviceCount:   len(cfg.Devices),
 	}
 
-	var err error
 	storagePath := servicesOpts.storagePath
 	if strings.EqualFold(storagePath, "disabled") {
 		storagePath = ""
@@ -59,7 +82,7 @@ func startRuntimeServices(engine *capture.Engine, stack *protocols.Stack, cfg *c
 	}
 
 	if engine != nil {
-		rs.replay = newReplayController(engine, stack.GetDebugLevel())
+		rs.replay = newReplayController(engine, stack.GetDebugLevel(), servicesOpts.maxReplays)
 	}
 
 	apiAddr := servicesOpts.apiListen
@@ -70,18 +93,25 @@ func startRuntimeServices(engine *capture.Engine, stack *protocols.Stack, cfg *c
 	}
 
 	if apiAddr != "" {
-		// SECURITY FIX #101: Prefer NIAC_API_TOKEN environment variable over CLI flag
-		apiToken := os.Getenv("NIAC_API_TOKEN")
-		if apiToken == "" {
-			apiToken = servicesOpts.apiToken
-			// Warn if using deprecated CLI flag
-			if apiToken != "" {
-				fmt.Fprintln(os.Stderr, "⚠️  WARNING: --api-token flag is deprecated and exposes token in process list")
-				fmt.Fprintln(os.Stderr, "    Please use NIAC_API_TOKEN environment variable instead")
+		apiToken, tokenFile, err := resolveAPIToken()
+		if err != nil {
+			if rs.storage != nil {
+				rs.storage.Close()
+			}
+			return nil, err
+		}
+		rs.tokenFile = tokenFile
+
+		// Time SNMP requests only while /metrics is served
+		snmpDurations, err := stats.NewHistogram(durationBuckets)
+		if err != nil {
+			if rs.storage != nil {
+				rs.storage.Close()
 			}
+			return nil, err
 		}
+		snmp.SetRequestDurationObserver(snmpDurations)
 
-		topology := api.BuildTopology(cfg)
 		cfgCopy := &api.ServerConfig{
 			Addr:        apiAddr,
 			MetricsAddr: metricsAddr,
@@ -92,17 +122,28 @@ func startRuntimeServices(engine *capture.Engine, stack *protocols.Stack, cfg *c
 			Storage:     rs.storage,
 			Interface:   interfaceName,
 			Version:     version,
-			Topology:    topology,
 			Alert: api.AlertConfig{
 				PacketsThreshold: servicesOpts.alertPacketsThreshold,
 				WebhookURL:       servicesOpts.alertWebhook,
 			},
 			ApplyConfig: rs.applyConfig,
 			Replay:      rs.replay,
+			OutputDir:   outputDirOpts.dir,
+			RateLimit:   servicesOpts.apiRate,
+			RateBurst:   servicesOpts.apiBurst,
+			UIDir:       servicesOpts.uiDir,
+
+			SNMPDurations: snmpDurations,
+
+			UploadMaxAge: servicesOpts.replayUploadMaxAge,
+		}
+		if engine != nil {
+			cfgCopy.Capture = engine
 		}
 
 		rs.apiServer = api.NewServer(*cfgCopy)
 		if err := rs.apiServer.Start(); err != nil {
+			snmp.SetRequestDurationObserver(nil)
 			if rs.storage != nil {
 				rs.storage.Close()
 			}
@@ -113,6 +154,49 @@ func startRuntimeServices(engine *capture.Engine, stack *protocols.Stack, cfg *c
 	return rs, nil
 }
 
+// resolveAPIToken returns the API token and the file it came from, if any. A
+// token file (--api-token-file, then NIAC_API_TOKEN_FILE) is preferred over
+// the NIAC_API_TOKEN environment variable, which is preferred over the
+// deprecated --api-token flag.
+func resolveAPIToken() (string, string, error) {
+	tokenFile := servicesOpts.apiTokenFile
+	if tokenFile == "" {
+		tokenFile = os.Getenv("NIAC_API_TOKEN_FILE")
+	}
+	if tokenFile != "" {
+		token, err := api.ReadTokenFile(tokenFile)
+		return token, tokenFile, err
+	}
+
+	// SECURITY FIX #101: Prefer NIAC_API_TOKEN environment variable over CLI flag
+	apiToken := os.Getenv("NIAC_API_TOKEN")
+	if apiToken == "" {
+		apiToken = servicesOpts.apiToken
+		// Warn if using deprecated CLI flag
+		if apiToken != "" {
+			fmt.Fprintln(os.Stderr, "⚠️  WARNING: --api-token flag is deprecated and exposes token in process list")
+			fmt.Fprintln(os.Stderr, "    Please use NIAC_API_TOKEN_FILE or the NIAC_API_TOKEN environment variable instead")
+		}
+	}
+	return apiToken, "", nil
+}
+
+// reloadAPIToken re-reads the API token file so a rotated token takes effect.
+// The current token stays in force if the file cannot be read or is empty.
+func (rs *runtimeServices) reloadAPIToken() error {
+	if rs == nil || rs.apiServer == nil || rs.tokenFile == "" {
+		return nil
+	}
+	token, err := api.ReadTokenFile(rs.tokenFile)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("API token file %s is empty", rs.tokenFile)
+	}
+	return rs.apiServer.SetToken(token)
+}
+
 func (rs *runtimeServices) applyConfig(newCfg *config.Config) error {
 	if rs == nil || newCfg == nil {
 		return fmt.Errorf("runtime services not initialized")
@@ -120,17 +204,13 @@ func (rs *runtimeServices) applyConfig(newCfg *config.Config) error {
 	if err := rs.stack.ReloadConfig(newCfg); err != nil {
 		return err
 	}
-	configureServiceHandlers(rs.stack, newCfg, rs.stack.GetDebugLevel())
 	rs.deviceCount = len(newCfg.Devices)
 	return nil
 }
 
 func (rs *runtimeServices) Stop() {
 	if rs.replay != nil {
-		// SECURITY FIX #106: Log errors during shutdown instead of silently discarding
-		if _, err := rs.replay.Stop(); err != nil {
-			log.Printf("Error stopping replay during shutdown: %v", err)
-		}
+		rs.replay.StopAll()
 	}
 
 	if rs.apiServer != nil {
@@ -140,6 +220,7 @@ func (rs *runtimeServices) Stop() {
 		if err := rs.apiServer.Shutdown(ctx); err != nil {
 			log.Printf("Error shutting down API server: %v", err)
 		}
+		snmp.SetRequestDurationObserver(nil)
 	}
 
 	if rs.storage != nil {
@@ -162,90 +243,367 @@ func (rs *runtimeServices) Stop() {
 	}
 }
 
+// maxEndedReplays is how many stopped or finished replays the API keeps
+// reporting; older ones are forgotten.
+const maxEndedReplays = 32
+
+// replayController runs named PCAP replays for the API. Up to maxRunning
+// replays play at once; further starts wait in a queue and begin, oldest
+// first, as running replays end.
 type replayController struct {
 	engine     *capture.Engine
 	debugLevel int
+	maxRunning int
 	mu         sync.Mutex
-	current    *capture.PlaybackEngine
-	state      api.ReplayState
-	cleanup    string
+	replays    map[string]*replayEntry
+	queue      []string // Names of queued replays, oldest first
+}
+
+// stoppedReplay is a replay taken off by end whose player still has to be
+// stopped. Players are stopped once rc.mu is released (see stopPlayers), as
+// stopping one waits for its playback goroutine.
+type stoppedReplay struct {
+	entry  *replayEntry
+	player *capture.PlaybackEngine
+}
+
+// replayEntry is one named replay: running, queued or ended
+type replayEntry struct {
+	request api.ReplayRequest
+	player  *capture.PlaybackEngine // Nil unless running
+	state   api.ReplayState
+	cleanup string    // Uploaded file removed when the replay ends
+	endedAt time.Time // When it stopped, finished or failed to start
 }
 
-func newReplayController(engine *capture.Engine, debugLevel int) *replayController {
+func newReplayController(engine *capture.Engine, debugLevel, maxRunning int) *replayController {
 	return &replayController{
 		engine:     engine,
 		debugLevel: debugLevel,
+		maxRunning: max(maxRunning, 1),
+		replays:    make(map[string]*replayEntry),
 	}
 }
 
-func (rc *replayController) Status() api.ReplayState {
+func (rc *replayController) Status(name string) (api.ReplayState, bool) {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
-	return rc.state
+	entry := rc.replays[name]
+	if entry == nil {
+		return api.ReplayState{Name: name}, name == api.DefaultReplayName
+	}
+	return entry.status(), true
 }
 
-func (rc *replayController) Start(req api.ReplayRequest) (api.ReplayState, error) {
+func (rc *replayController) List() []api.ReplayState {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
+	states := make([]api.ReplayState, 0, len(rc.replays))
+	for _, entry := range rc.replays {
+		states = append(states, entry.status())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+func (rc *replayController) Start(req api.ReplayRequest) (api.ReplayState, error) {
+	rc.mu.Lock()
+	state, stopped, err := rc.startLocked(req)
+	rc.mu.Unlock()
+	rc.stopPlayers(stopped)
+	return state, err
+}
 
+// startLocked starts or queues a replay, returning the replay it replaces
+// for the caller to stop.
+// Note: Caller must hold rc.mu
+func (rc *replayController) startLocked(req api.ReplayRequest) (api.ReplayState, []stoppedReplay, error) {
+	if req.Name == "" {
+		req.Name = api.DefaultReplayName
+	}
 	if rc.engine == nil {
-		return rc.state, fmt.Errorf("capture engine unavailable for replay")
+		return api.ReplayState{Name: req.Name}, nil, fmt.Errorf("capture engine unavailable for replay")
 	}
 	if strings.TrimSpace(req.File) == "" {
-		return rc.state, fmt.Errorf("pcap file path is required")
+		return api.ReplayState{Name: req.Name}, nil, fmt.Errorf("pcap file path is required")
+	}
+
+	// Two active replays cannot play as the same device
+	if req.Device != "" {
+		for name, other := range rc.replays {
+			if name != req.Name && other.request.Device == req.Device && (other.player != nil || other.state.Queued) {
+				if req.Uploaded {
+					os.Remove(req.File)
+				}
+				return api.ReplayState{Name: req.Name}, nil, fmt.Errorf("%w: %s is used by replay %q", api.ErrReplayDeviceBusy, req.Device, name)
+			}
+		}
 	}
 
-	if rc.current != nil {
-		rc.current.Stop()
-		rc.current = nil
+	// A new replay under the same name replaces the old one. The slot it
+	// frees goes to the queue first; the replacement queues behind it.
+	var stopped []stoppedReplay
+	if old := rc.replays[req.Name]; old != nil {
+		stopped = rc.end(old, stopped)
+		delete(rc.replays, req.Name)
+		rc.startQueued()
 	}
-	rc.cleanupTempFile()
 
+	entry := &replayEntry{request: req, state: api.ReplayState{
+		Name:      req.Name,
+		Device:    req.Device,
+		File:      req.File,
+		LoopMs:    req.LoopMs,
+		LoopCount: req.LoopCount,
+		Scale:     req.Scale,
+		Rewrite:   req.Rewrite,
+		VLANMode:  req.VLANMode,
+		VLANID:    req.VLANID,
+		Filter:    req.Filter,
+	}}
+	if req.Uploaded {
+		entry.cleanup = req.File
+	}
+	defer rc.pruneEnded()
+	if len(rc.queue) > 0 || rc.running() >= rc.maxRunning {
+		entry.state.Queued = true
+		rc.replays[req.Name] = entry
+		rc.queue = append(rc.queue, req.Name)
+		return entry.state, stopped, nil
+	}
+	if err := rc.start(entry); err != nil {
+		entry.cleanupTempFile()
+		rc.startQueued()
+		return api.ReplayState{Name: req.Name}, stopped, err
+	}
+	rc.replays[req.Name] = entry
+	return entry.state, stopped, nil
+}
+
+func (rc *replayController) Stop(name string) (api.ReplayState, bool) {
+	rc.mu.Lock()
+	entry := rc.replays[name]
+	if entry == nil {
+		rc.mu.Unlock()
+		return api.ReplayState{Name: name}, name == api.DefaultReplayName
+	}
+	stopped := rc.end(entry, nil)
+	rc.startQueued()
+	rc.pruneEnded()
+	rc.mu.Unlock()
+
+	rc.stopPlayers(stopped)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return entry.state, true
+}
+
+// StopAll stops every replay and empties the queue
+func (rc *replayController) StopAll() []api.ReplayState {
+	rc.mu.Lock()
+	// Empty the queue first so stopping a replay does not start the next
+	var stopped []stoppedReplay
+	for len(rc.queue) > 0 {
+		stopped = rc.end(rc.replays[rc.queue[0]], stopped)
+	}
+	entries := make([]*replayEntry, 0, len(rc.replays))
+	for _, entry := range rc.replays {
+		stopped = rc.end(entry, stopped)
+		entries = append(entries, entry)
+	}
+	rc.pruneEnded()
+	rc.mu.Unlock()
+
+	rc.stopPlayers(stopped)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	states := make([]api.ReplayState, 0, len(entries))
+	for _, entry := range entries {
+		states = append(states, entry.state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// start begins playback of a replay.
+// Note: Caller must hold rc.mu
+func (rc *replayController) start(entry *replayEntry) error {
+	req := entry.request
 	cfg := &config.CapturePlayback{
 		FileName:  req.File,
 		LoopTime:  req.LoopMs,
 		ScaleTime: req.Scale,
+		LoopCount: req.LoopCount,
 	}
 	player := capture.NewPlaybackEngine(rc.engine, cfg, rc.debugLevel)
+	player.SetOnComplete(func(result capture.PlaybackResult) {
+		rc.finished(req.Name, player, result)
+	})
+	if len(req.Rewrite) > 0 {
+		rewriter, err := capture.NewAddressRewriter(req.Rewrite)
+		if err != nil {
+			return err
+		}
+		player.SetRewriter(rewriter)
+	}
+	vlanRewriter, err := capture.NewVLANRewriter(req.VLANMode, req.VLANID)
+	if err != nil {
+		return err
+	}
+	if vlanRewriter.Mode() != capture.VLANPreserve {
+		player.SetVLANRewriter(vlanRewriter)
+	}
+	if req.Filter != "" {
+		linkType, err := capture.SourceLinkType(req.File)
+		if err != nil {
+			return err
+		}
+		filter, err := capture.NewPacketFilter(req.Filter, linkType)
+		if err != nil {
+			return err
+		}
+		player.SetFilter(filter)
+	}
 	if err := player.Start(); err != nil {
-		if req.Uploaded {
-			os.Remove(req.File)
+		return err
+	}
+
+	entry.player = player
+	entry.state.Running = true
+	entry.state.StartedAt = time.Now().UTC()
+	return nil
+}
+
+// end ends a running replay, appending it to stopped for the caller to stop
+// once rc.mu is released, or takes a queued one off the queue.
+// Note: Caller must hold rc.mu
+func (rc *replayController) end(entry *replayEntry, stopped []stoppedReplay) []stoppedReplay {
+	if entry.state.Queued {
+		entry.state.Queued = false
+		entry.endedAt = time.Now()
+		for i, name := range rc.queue {
+			if name == entry.state.Name {
+				rc.queue = append(rc.queue[:i], rc.queue[i+1:]...)
+				break
+			}
 		}
-		return rc.state, err
+	}
+	entry.state.Running = false
+	if entry.player == nil {
+		entry.cleanupTempFile()
+		return stopped
 	}
 
-	rc.current = player
-	rc.state = api.ReplayState{
-		Running:   true,
-		File:      req.File,
-		LoopMs:    req.LoopMs,
-		Scale:     req.Scale,
-		StartedAt: time.Now().UTC(),
+	// Counts so far, until stopPlayers records the final ones. The uploaded
+	// file is removed once the player is done with it.
+	entry.endedAt = time.Now()
+	entry.state.Result = api.NewReplayResult(entry.player.Result())
+	stopped = append(stopped, stoppedReplay{entry: entry, player: entry.player})
+	entry.player = nil
+	return stopped
+}
+
+// stopPlayers stops the players of replays taken off by end, records their
+// final counts and removes their uploaded files.
+// Note: Caller must not hold rc.mu
+func (rc *replayController) stopPlayers(stopped []stoppedReplay) {
+	if len(stopped) == 0 {
+		return
 	}
-	if req.Uploaded {
-		rc.cleanup = req.File
-	} else {
-		rc.cleanup = ""
+	for _, s := range stopped {
+		s.player.Stop()
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, s := range stopped {
+		s.entry.state.Result = api.NewReplayResult(s.player.Result())
+		s.entry.cleanupTempFile()
+	}
+}
+
+// startQueued starts queued replays while fewer than maxRunning are running.
+// A queued replay that fails to start ends with the error as its result.
+// Note: Caller must hold rc.mu
+func (rc *replayController) startQueued() {
+	for len(rc.queue) > 0 && rc.running() < rc.maxRunning {
+		entry := rc.replays[rc.queue[0]]
+		rc.queue = rc.queue[1:]
+		entry.state.Queued = false
+		if err := rc.start(entry); err != nil {
+			entry.state.Result = &api.ReplayResult{Error: err.Error()}
+			entry.endedAt = time.Now()
+			entry.cleanupTempFile()
+		}
+	}
+}
+
+// pruneEnded forgets the oldest ended replays beyond maxEndedReplays, so
+// replays started under ever new names do not accumulate.
+// Note: Caller must hold rc.mu
+func (rc *replayController) pruneEnded() {
+	var ended []string
+	for name, entry := range rc.replays {
+		if entry.player == nil && !entry.state.Queued {
+			ended = append(ended, name)
+		}
+	}
+	if len(ended) <= maxEndedReplays {
+		return
+	}
+	sort.Slice(ended, func(i, j int) bool {
+		return rc.replays[ended[i]].endedAt.Before(rc.replays[ended[j]].endedAt)
+	})
+	for _, name := range ended[:len(ended)-maxEndedReplays] {
+		delete(rc.replays, name)
 	}
-	return rc.state, nil
 }
 
-func (rc *replayController) Stop() (api.ReplayState, error) {
+// running counts the replays playing now.
+// Note: Caller must hold rc.mu
+func (rc *replayController) running() int {
+	n := 0
+	for _, entry := range rc.replays {
+		if entry.player != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// finished records the result of a replay that ended on its own, so its
+// status reports it as stopped with its final counts, and starts the next
+// queued replay.
+func (rc *replayController) finished(name string, player *capture.PlaybackEngine, result capture.PlaybackResult) {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
 
-	if rc.current != nil {
-		rc.current.Stop()
-		rc.current = nil
+	// Ignore a replay that has since been stopped or replaced
+	entry := rc.replays[name]
+	if entry == nil || entry.player != player {
+		return
+	}
+	entry.player = nil
+	entry.endedAt = time.Now()
+	entry.state.Running = false
+	entry.state.Result = api.NewReplayResult(result)
+	entry.cleanupTempFile()
+	rc.startQueued()
+	rc.pruneEnded()
+}
+
+// status returns the replay's state with its progress so far.
+// Note: Caller must hold rc.mu
+func (e *replayEntry) status() api.ReplayState {
+	state := e.state
+	if e.player != nil {
+		state.Result = api.NewReplayResult(e.player.Result())
 	}
-	rc.state.Running = false
-	rc.cleanupTempFile()
-	return rc.state, nil
+	return state
 }
 
-func (rc *replayController) cleanupTempFile() {
-	if rc.cleanup != "" {
-		_ = os.Remove(rc.cleanup)
-		rc.cleanup = ""
+func (e *replayEntry) cleanupTempFile() {
+	if e.cleanup != "" {
+		_ = os.Remove(e.cleanup)
+		e.cleanup = ""
 	}
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/krisarmstrong/niac-go/pkg/api"
+	"github.com/krisarmstrong/niac-go/pkg/capture"
+)
+
+// serveReplayStream accepts one connection on ln and writes count packets to
+// it as a pcap stream. The connection is left open so the replay keeps
+// running.
+func serveReplayStream(t *testing.T, ln net.Listener, count int) {
+	t.Helper()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	w := pcapgo.NewWriter(conn)
+	if err := w.WriteFileHeader(1600, layers.LinkTypeEthernet); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	for i := 0; i < count; i++ {
+		data := []byte{0x01, byte(i)}
+		info := gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(info, data); err != nil {
+			t.Fatalf("write packet: %v", err)
+		}
+	}
+}
+
+func listenReplayStream(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+// TestReplayController_NamedReplays tests that named replays run side by
+// side with independent progress, that starts beyond the limit queue until a
+// replay ends, and that stopping one leaves the others running
+func TestReplayController_NamedReplays(t *testing.T) {
+	engine := capture.NewWithWriter("test0", &countingWriter{}, 0)
+	rc := newReplayController(engine, 0, 2)
+	defer rc.StopAll()
+
+	first, second, third := listenReplayStream(t), listenReplayStream(t), listenReplayStream(t)
+	for name, ln := range map[string]net.Listener{"first": first, "second": second} {
+		state, err := rc.Start(api.ReplayRequest{Name: name, File: capture.StreamScheme + ln.Addr().String()})
+		if err != nil {
+			t.Fatalf("start %s: %v", name, err)
+		}
+		if !state.Running || state.Name != name {
+			t.Fatalf("start %s: state %+v, want it running", name, state)
+		}
+	}
+	serveReplayStream(t, first, 2)
+	serveReplayStream(t, second, 3)
+
+	// Each replay counts only the packets of its own stream
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a, _ := rc.Status("first")
+		b, _ := rc.Status("second")
+		if a.Result != nil && a.Result.PacketsSent == 2 && b.Result != nil && b.Result.PacketsSent == 3 {
+			if !a.Running || !b.Running {
+				t.Fatalf("expected both replays running, got %+v and %+v", a, b)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replays sent %+v and %+v packets, want 2 and 3", a.Result, b.Result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A third replay waits for a free slot
+	state, err := rc.Start(api.ReplayRequest{Name: "third", File: capture.StreamScheme + third.Addr().String()})
+	if err != nil {
+		t.Fatalf("start third: %v", err)
+	}
+	if !state.Queued || state.Running {
+		t.Fatalf("third replay state %+v, want it queued", state)
+	}
+	if states := rc.List(); len(states) != 3 || states[0].Name != "first" || states[1].Name != "second" || states[2].Name != "third" {
+		t.Fatalf("List() = %+v, want first, second and third", states)
+	}
+
+	stopped, ok := rc.Stop("first")
+	if !ok || stopped.Running || stopped.Result == nil || stopped.Result.PacketsSent != 2 {
+		t.Fatalf("Stop(first) = %+v, %v; want it stopped after 2 packets", stopped, ok)
+	}
+	if state, _ := rc.Status("third"); !state.Running || state.Queued {
+		t.Errorf("third replay state %+v after a slot freed, want it running", state)
+	}
+	if state, _ := rc.Status("second"); !state.Running || state.Result.PacketsSent != 3 {
+		t.Errorf("second replay state %+v, want it still running with 3 packets", state)
+	}
+
+	if _, ok := rc.Status("missing"); ok {
+		t.Error("Status(missing) reported a replay")
+	}
+	if state, ok := rc.Status(api.DefaultReplayName); !ok || state.Running {
+		t.Errorf("Status(default) = %+v, %v; want an idle replay", state, ok)
+	}
+
+	for _, state := range rc.StopAll() {
+		if state.Running || state.Queued {
+			t.Errorf("replay %s still active after StopAll: %+v", state.Name, state)
+		}
+	}
+}
+
+// TestReplayController_QueueAndLimits tests that a replaced replay hands its
+// slot to the queue, that two replays cannot play as the same device, and
+// that ended replays are forgotten beyond maxEndedReplays
+func TestReplayController_QueueAndLimits(t *testing.T) {
+	engine := capture.NewWithWriter("test0", &countingWriter{}, 0)
+	rc := newReplayController(engine, 0, 1)
+	defer rc.StopAll()
+	source := capture.StreamScheme + listenReplayStream(t).Addr().String()
+
+	if _, err := rc.Start(api.ReplayRequest{Name: "first", File: source, Device: "core1"}); err != nil {
+		t.Fatalf("start first: %v", err)
+	}
+	if state, err := rc.Start(api.ReplayRequest{Name: "second", File: source}); err != nil || !state.Queued {
+		t.Fatalf("start second = %+v, %v; want it queued", state, err)
+	}
+
+	// Replacing the running replay starts the queued one; the replacement waits
+	state, err := rc.Start(api.ReplayRequest{Name: "first", File: source, Device: "core1"})
+	if err != nil || !state.Queued {
+		t.Fatalf("replace first = %+v, %v; want it queued", state, err)
+	}
+	if state, _ := rc.Status("second"); !state.Running {
+		t.Errorf("second replay state %+v after first was replaced, want it running", state)
+	}
+
+	// The queued replay holds its device
+	_, err = rc.Start(api.ReplayRequest{Name: "third", File: source, Device: "core1"})
+	if !errors.Is(err, api.ErrReplayDeviceBusy) {
+		t.Errorf("start on a busy device: err %v, want ErrReplayDeviceBusy", err)
+	}
+
+	rc.StopAll()
+	for i := 0; i < maxEndedReplays+8; i++ {
+		name := fmt.Sprintf("replay-%d", i)
+		if _, err := rc.Start(api.ReplayRequest{Name: name, File: source}); err != nil {
+			t.Fatalf("start %s: %v", name, err)
+		}
+		rc.Stop(name)
+	}
+	states := rc.List()
+	if len(states) != maxEndedReplays {
+		t.Fatalf("List() holds %d replays, want %d", len(states), maxEndedReplays)
+	}
+	if _, ok := rc.Status(fmt.Sprintf("replay-%d", maxEndedReplays+7)); !ok {
+		t.Error("the most recent replay was forgotten")
+	}
+	if _, ok := rc.Status("replay-0"); ok {
+		t.Error("the oldest replay was kept")
+	}
+}
+
+// TestReloadAPIToken tests that a token file emptied during rotation is
+// refused with an error, and that a new token in it is applied
+func TestReloadAPIToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	rs := &runtimeServices{
+		apiServer: api.NewServer(api.ServerConfig{Token: "current"}),
+		tokenFile: tokenFile,
+	}
+
+	for _, content := range []string{"", "  \n"} {
+		if err := os.WriteFile(tokenFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("write token file: %v", err)
+		}
+		if err := rs.reloadAPIToken(); err == nil {
+			t.Errorf("reload of token file %q succeeded, want an error keeping the current token", content)
+		}
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatalf("write token file: %v", err)
+	}
+	if err := rs.reloadAPIToken(); err != nil {
+		t.Errorf("reload of a rotated token: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// maxScaffoldDevices bounds a generated config to something NIAC can simulate
+const maxScaffoldDevices = 10000
+
+type scaffoldOptions struct {
+	device  string // Template device name (default: first device)
+	count   int
+	start   int    // Number of the first clone, substituted into the name
+	name    string // fmt pattern with one integer verb, e.g. "sw-%03d"
+	baseIP  string // First clone's IP (default: template IP)
+	ipStep  int
+	baseMAC string // First clone's MAC (default: template MAC)
+	macStep int
+	output  string
+}
+
+var scaffoldOpts = scaffoldOptions{}
+
+var scaffoldCmd = &cobra.Command{
+	Use:   "scaffold <template.yaml>",
+	Short: "Clone a template device into a large config",
+	Long: `Generate a config with many devices cloned from one template device.
+
+Each clone is a copy of the template with its name, IP addresses and MAC
+address incremented: clone i gets the name pattern formatted with start+i,
+every IP advanced by i*ip-step and the MAC advanced by i*mac-step. Settings
+outside the device list (include_path, discovery protocols, ...) are kept
+from the template file.`,
+	Example: `  # 500 switches named sw-001..sw-500 from 10.1.0.1 upwards
+  niac scaffold switch.yaml --count 500 --name sw-%03d \
+    --base-ip 10.1.0.1 --base-mac 02:00:00:01:00:01 -o lab.yaml
+
+  # Clone the "edge" device, leaving a gap of 4 addresses between clones
+  niac scaffold network.yaml --device edge --count 20 --ip-step 4`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		template, err := converter.LoadYAMLConfig(args[0], false)
+		if err != nil {
+			return fmt.Errorf("load template: %w", err)
+		}
+		generated, err := scaffoldConfig(template, scaffoldOpts)
+		if err != nil {
+			return err
+		}
+
+		data, err := yaml.Marshal(generated)
+		if err != nil {
+			return fmt.Errorf("marshal config: %w", err)
+		}
+		// Catch clones the simulator would reject (e.g. addresses that
+		// overflowed into invalid ranges) before writing anything
+		if _, err := config.LoadYAMLBytes(data); err != nil {
+			return fmt.Errorf("generated config is invalid: %w", err)
+		}
+
+		if scaffoldOpts.output == "" {
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		}
+		if _, err := os.Stat(scaffoldOpts.output); err == nil {
+			return fmt.Errorf("output file already exists: %s", scaffoldOpts.output)
+		}
+		if err := os.WriteFile(scaffoldOpts.output, data, 0644); err != nil {
+			return fmt.Errorf("write config: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Generated %d devices in %s\n", len(generated.Devices), scaffoldOpts.output)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scaffoldCmd)
+	scaffoldCmd.Flags().StringVar(&scaffoldOpts.device, "device", "", "Template device name (default: first device in the file)")
+	scaffoldCmd.Flags().IntVarP(&scaffoldOpts.count, "count", "n", 0, "Number of devices to generate (required)")
+	scaffoldCmd.Flags().IntVar(&scaffoldOpts.start, "start", 1, "Number of the first device")
+	scaffoldCmd.Flags().StringVar(&scaffoldOpts.name, "name", "", "Name pattern with one integer verb, e.g. sw-%03d (default: <template>-%d)")
+	scaffoldCmd.Flags().StringVar(&scaffoldOpts.baseIP, "base-ip", "", "IP of the first device (default: template IP)")
+	scaffoldCmd.Flags().IntVar(&scaffoldOpts.ipStep, "ip-step", 1, "IP increment between devices")
+	scaffoldCmd.Flags().StringVar(&scaffoldOpts.baseMAC, "base-mac", "", "MAC of the first device (default: template MAC)")
+	scaffoldCmd.Flags().IntVar(&scaffoldOpts.macStep, "mac-step", 1, "MAC increment between devices")
+	scaffoldCmd.Flags().StringVarP(&scaffoldOpts.output, "output", "o", "", "Output file (default: stdout)")
+	_ = scaffoldCmd.MarkFlagRequired("count")
+}
+
+// scaffoldConfig returns template with its devices replaced by opts.count
+// clones of the template device.
+func scaffoldConfig(template *converter.Config, opts scaffoldOptions) (*converter.Config, error) {
+	if len(template.Devices) == 0 {
+		return nil, fmt.Errorf("template has no devices")
+	}
+	base := &template.Devices[0]
+	if opts.device != "" {
+		base = nil
+		for i := range template.Devices {
+			if template.Devices[i].Name == opts.device {
+				base = &template.Devices[i]
+				break
+			}
+		}
+		if base == nil {
+			return nil, fmt.Errorf("template device %q not found", opts.device)
+		}
+	}
+
+	devices, err := scaffoldDevices(*base, opts)
+	if err != nil {
+		return nil, err
+	}
+	generated := *template
+	generated.Devices = devices
+	return &generated, nil
+}
+
+// scaffoldDevices clones device opts.count times, giving each clone a
+// distinct name, IP addresses and MAC.
+func scaffoldDevices(device converter.Device, opts scaffoldOptions) ([]converter.Device, error) {
+	if opts.count < 1 || opts.count > maxScaffoldDevices {
+		return nil, fmt.Errorf("count must be between 1 and %d, got %d", maxScaffoldDevices, opts.count)
+	}
+	if opts.ipStep < 1 || opts.macStep < 1 {
+		return nil, fmt.Errorf("ip-step and mac-step must be positive")
+	}
+
+	pattern := opts.name
+	if pattern == "" {
+		pattern = device.Name + "-%d"
+	}
+	if strings.Count(pattern, "%") != 1 || strings.Contains(fmt.Sprintf(pattern, 0), "%!") {
+		return nil, fmt.Errorf("name pattern %q must contain exactly one integer verb such as %%d", pattern)
+	}
+
+	// Resolve the first clone's addresses. A base IP replaces the template's
+	// first address; any further addresses keep their offset from it.
+	ips := append([]string{}, device.IPs...)
+	if device.IP != "" {
+		ips = append([]string{device.IP}, ips...)
+	}
+	baseIPs := make([]netip.Addr, 0, len(ips))
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, fmt.Errorf("template device %s: invalid IP %q", device.Name, ip)
+		}
+		baseIPs = append(baseIPs, addr)
+	}
+	if opts.baseIP != "" {
+		addr, err := netip.ParseAddr(opts.baseIP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base IP %q", opts.baseIP)
+		}
+		if len(baseIPs) == 0 {
+			baseIPs = append(baseIPs, addr)
+		} else {
+			baseIPs[0] = addr
+		}
+	}
+	if len(baseIPs) == 0 {
+		return nil, fmt.Errorf("template device %s has no IP; set --base-ip", device.Name)
+	}
+
+	macText := device.MAC
+	if opts.baseMAC != "" {
+		macText = opts.baseMAC
+	}
+	baseMAC, err := net.ParseMAC(macText)
+	if err != nil || len(baseMAC) != 6 {
+		return nil, fmt.Errorf("invalid base MAC %q", macText)
+	}
+
+	// Deep copy the template through YAML so clones share no pointers
+	templateYAML, err := yaml.Marshal(device)
+	if err != nil {
+		return nil, fmt.Errorf("copy template device: %w", err)
+	}
+
+	devices := make([]converter.Device, opts.count)
+	for i := range devices {
+		clone := &devices[i]
+		if err := yaml.Unmarshal(templateYAML, clone); err != nil {
+			return nil, fmt.Errorf("copy template device: %w", err)
+		}
+		clone.Name = fmt.Sprintf(pattern, opts.start+i)
+
+		offset := uint64(i) * uint64(opts.ipStep)
+		clone.IP = ""
+		clone.IPs = nil
+		for j, base := range baseIPs {
+			addr, err := offsetIP(base, offset)
+			if err != nil {
+				return nil, fmt.Errorf("device %s: %w", clone.Name, err)
+			}
+			if j == 0 {
+				clone.IP = addr.String()
+			} else {
+				clone.IPs = append(clone.IPs, addr.String())
+			}
+		}
+
+		mac, err := offsetMAC(baseMAC, uint64(i)*uint64(opts.macStep))
+		if err != nil {
+			return nil, fmt.Errorf("device %s: %w", clone.Name, err)
+		}
+		clone.MAC = mac.String()
+	}
+	return devices, nil
+}
+
+// offsetIP returns addr advanced by n, failing rather than wrapping around
+// the end of the address space.
+func offsetIP(addr netip.Addr, n uint64) (netip.Addr, error) {
+	b := addr.AsSlice()
+	for i := len(b) - 1; i >= 0 && n > 0; i-- {
+		sum := uint64(b[i]) + n&0xff
+		b[i] = byte(sum)
+		n = n>>8 + sum>>8
+	}
+	if n > 0 {
+		return netip.Addr{}, fmt.Errorf("IP range starting at %s overflows", addr)
+	}
+	next, _ := netip.AddrFromSlice(b)
+	return next, nil
+}
+
+// offsetMAC returns mac advanced by n, failing rather than wrapping around.
+func offsetMAC(mac net.HardwareAddr, n uint64) (net.HardwareAddr, error) {
+	var value uint64
+	for _, octet := range mac {
+		value = value<<8 | uint64(octet)
+	}
+	if value+n > 1<<48-1 {
+		return nil, fmt.Errorf("MAC range starting at %s overflows", mac)
+	}
+	value += n
+
+	next := make(net.HardwareAddr, 6)
+	for i := 5; i >= 0; i-- {
+		next[i] = byte(value)
+		value >>= 8
+	}
+	return next, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+func TestScaffoldDevices(t *testing.T) {
+	template := converter.Device{
+		Name: "sw",
+		MAC:  "02:00:00:00:00:fe",
+		IP:   "10.1.0.250",
+		IPs:  []string{"2001:db8::fa"},
+		Tags: []string{"access"},
+		Lldp: &converter.LldpConfig{Enabled: true},
+	}
+
+	devices, err := scaffoldDevices(template, scaffoldOptions{
+		count: 10, start: 1, name: "sw-%03d", ipStep: 1, macStep: 1,
+	})
+	if err != nil {
+		t.Fatalf("scaffoldDevices: %v", err)
+	}
+	if len(devices) != 10 {
+		t.Fatalf("Expected 10 devices, got %d", len(devices))
+	}
+
+	names := map[string]bool{}
+	ips := map[string]bool{}
+	macs := map[string]bool{}
+	for i, device := range devices {
+		if want := fmt.Sprintf("sw-%03d", i+1); device.Name != want {
+			t.Errorf("device %d name = %s, want %s", i, device.Name, want)
+		}
+		names[device.Name] = true
+		ips[device.IP] = true
+		macs[device.MAC] = true
+		if len(device.IPs) != 1 || device.Lldp == nil || !device.Lldp.Enabled || len(device.Tags) != 1 {
+			t.Errorf("device %s lost template settings: %+v", device.Name, device)
+		}
+	}
+	if len(names) != 10 || len(ips) != 10 || len(macs) != 10 {
+		t.Errorf("Expected unique names/IPs/MACs, got %d/%d/%d", len(names), len(ips), len(macs))
+	}
+
+	// Addresses increment and carry into the next octet
+	if devices[6].IP != "10.1.1.0" || devices[9].IP != "10.1.1.3" {
+		t.Errorf("IPv4 addresses = %s, %s; want 10.1.1.0, 10.1.1.3", devices[6].IP, devices[9].IP)
+	}
+	if devices[9].IPs[0] != "2001:db8::103" {
+		t.Errorf("IPv6 address = %s, want 2001:db8::103", devices[9].IPs[0])
+	}
+	if devices[1].MAC != "02:00:00:00:00:ff" || devices[2].MAC != "02:00:00:00:01:00" {
+		t.Errorf("MACs = %s, %s; want 02:00:00:00:00:ff, 02:00:00:00:01:00", devices[1].MAC, devices[2].MAC)
+	}
+
+	// Clones share no state with each other
+	devices[0].Tags[0] = "changed"
+	if devices[1].Tags[0] != "access" {
+		t.Error("Clones share the template's tag slice")
+	}
+}
+
+func TestScaffoldDevices_Options(t *testing.T) {
+	template := converter.Device{Name: "edge", MAC: "00:11:22:33:44:55", IP: "192.168.0.1"}
+
+	devices, err := scaffoldDevices(template, scaffoldOptions{
+		count: 3, start: 10, baseIP: "10.0.0.4", ipStep: 4, baseMAC: "02:00:00:00:00:00", macStep: 16,
+	})
+	if err != nil {
+		t.Fatalf("scaffoldDevices: %v", err)
+	}
+	if devices[0].Name != "edge-10" || devices[2].Name != "edge-12" {
+		t.Errorf("names = %s..%s, want edge-10..edge-12", devices[0].Name, devices[2].Name)
+	}
+	if devices[2].IP != "10.0.0.12" || devices[2].MAC != "02:00:00:00:00:20" {
+		t.Errorf("device 3 = %s %s, want 10.0.0.12 02:00:00:00:00:20", devices[2].IP, devices[2].MAC)
+	}
+
+	invalid := []scaffoldOptions{
+		{count: 0, ipStep: 1, macStep: 1},
+		{count: 3, ipStep: 0, macStep: 1},
+		{count: 3, ipStep: 1, macStep: 1, name: "edge"},
+		{count: 3, ipStep: 1, macStep: 1, name: "edge-%s"},
+		{count: 3, ipStep: 1, macStep: 1, baseIP: "255.255.255.254"},
+		{count: 3, ipStep: 1, macStep: 1, baseMAC: "ff:ff:ff:ff:ff:fe"},
+	}
+	for _, opts := range invalid {
+		if _, err := scaffoldDevices(template, opts); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+}
+
+func TestOffsetIP(t *testing.T) {
+	got, err := offsetIP(netip.MustParseAddr("10.0.255.255"), 257)
+	if err != nil || got.String() != "10.1.1.0" {
+		t.Errorf("offsetIP = %s, %v; want 10.1.1.0", got, err)
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+// seedOptions controls randomized simulator behavior. A non-zero seed makes
+// runs reproducible (e.g., discovery advertisement phase offsets).
+type seedOptions struct {
+	seed int64
+}
+
+var seedOpts = seedOptions{}
@@ -1,22 +1,36 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/stats"
 )
 
 type serviceOptions struct {
 	apiListen             string
 	apiToken              string
+	apiTokenFile          string // File holding the API token, re-read on SIGHUP
 	metricsListen         string
 	storagePath           string
 	alertPacketsThreshold uint64
 	alertWebhook          string
+	apiRate               float64 // API requests per second per client (0 = unlimited)
+	apiBurst              int
+	replayUploadMaxAge    time.Duration // Uploaded replay PCAPs older than this are removed (0 = keep)
+	maxReplays            int           // Replays that run at once; further starts queue
+	uiDir                 string        // Serve the Web UI from this directory instead of the embedded assets
+	snmpDurationBuckets   []float64     // Upper bounds in seconds of the SNMP request duration histogram (empty = default)
 }
 
 var servicesOpts = serviceOptions{}
 
 func defaultStoragePath() string {
+	if outputDirOpts.dir != "" {
+		return filepath.Join(outputDirOpts.dir, "niac.db")
+	}
 	home, err := os.UserHomeDir()
 	if err != nil || home == "" {
 		return filepath.Join(os.TempDir(), "niac", "niac.db")
@@ -29,3 +43,52 @@ func resolveServiceDefaults() {
 		servicesOpts.storagePath = defaultStoragePath()
 	}
 }
+
+// validateAPIRateLimit rejects negative --api-rate or --api-burst values.
+func validateAPIRateLimit() error {
+	if servicesOpts.apiRate < 0 || servicesOpts.apiBurst < 0 {
+		return fmt.Errorf("--api-rate and --api-burst must not be negative (0 disables rate limiting)")
+	}
+	return nil
+}
+
+// defaultSNMPDurationSeconds returns the default SNMP request duration
+// buckets in seconds, the --snmp-duration-buckets default.
+func defaultSNMPDurationSeconds() []float64 {
+	seconds := make([]float64, len(stats.DefaultSNMPDurationBuckets))
+	for i, bound := range stats.DefaultSNMPDurationBuckets {
+		seconds[i] = bound.Seconds()
+	}
+	return seconds
+}
+
+// snmpDurationBuckets returns the --snmp-duration-buckets histogram bounds,
+// or the default 1ms-1s buckets when the flag is not set.
+func snmpDurationBuckets() ([]time.Duration, error) {
+	if len(servicesOpts.snmpDurationBuckets) == 0 {
+		return stats.DefaultSNMPDurationBuckets, nil
+	}
+	bounds := make([]time.Duration, len(servicesOpts.snmpDurationBuckets))
+	for i, seconds := range servicesOpts.snmpDurationBuckets {
+		bounds[i] = time.Duration(seconds * float64(time.Second))
+	}
+	if err := stats.ValidateBuckets(bounds); err != nil {
+		return nil, fmt.Errorf("--snmp-duration-buckets: %w", err)
+	}
+	return bounds, nil
+}
+
+// validateUIDir checks that --ui-dir, when given, is a directory.
+func validateUIDir() error {
+	if servicesOpts.uiDir == "" {
+		return nil
+	}
+	info, err := os.Stat(servicesOpts.uiDir)
+	if err != nil {
+		return fmt.Errorf("--ui-dir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("--ui-dir: %s is not a directory", servicesOpts.uiDir)
+	}
+	return nil
+}
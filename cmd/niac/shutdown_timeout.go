@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultShutdownTimeout is how long shutdown may take before NIAC gives up
+const defaultShutdownTimeout = 10 * time.Second
+
+// shutdownTimeoutOptions bounds how long stopping the stack and closing the
+// capture handle may take. A wedged NIC can block a pcap close forever; once
+// the timeout elapses NIAC logs a warning and exits anyway, so CI jobs do not
+// hang on exit. Zero waits indefinitely.
+type shutdownTimeoutOptions struct {
+	timeout time.Duration
+}
+
+var shutdownTimeoutOpts = shutdownTimeoutOptions{timeout: defaultShutdownTimeout}
+
+// shutdownDeadline holds every shutdown stage to one timeout: the clock
+// starts when the first stage begins, and each later stage gets what is
+// left of it.
+type shutdownDeadline struct {
+	timeout time.Duration
+	once    sync.Once
+	at      time.Time
+}
+
+func newShutdownDeadline(timeout time.Duration) *shutdownDeadline {
+	return &shutdownDeadline{timeout: timeout}
+}
+
+// stop runs a shutdown stage within what remains of the timeout (see
+// stopWithDeadline). Once the deadline has passed, the process is forced to
+// exit unless stop returns at once.
+func (d *shutdownDeadline) stop(stop func()) error {
+	if d.timeout <= 0 {
+		return stopWithDeadline(stop, 0)
+	}
+	d.once.Do(func() { d.at = time.Now().Add(d.timeout) })
+	return stopWithDeadline(stop, max(time.Until(d.at), time.Millisecond))
+}
+
+// forceExit terminates the process when shutdown overruns its deadline
+var forceExit = os.Exit
+
+// stopWithDeadline runs stop, and force-exits with status 1 if it has not
+// returned within timeout. stop keeps running in the background when the
+// exit is stubbed out, as in tests.
+func stopWithDeadline(stop func(), timeout time.Duration) error {
+	if timeout <= 0 {
+		stop()
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stop()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		log.Printf("WARNING: shutdown did not finish within %s (stuck capture handle?); forcing exit", timeout)
+		forceExit(1)
+		return fmt.Errorf("shutdown did not finish within %s", timeout)
+	}
+}
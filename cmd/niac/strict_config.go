@@ -0,0 +1,18 @@
+package main
+
+import "github.com/krisarmstrong/niac-go/pkg/config"
+
+// strictConfigOptions controls how YAML configuration files are parsed. In
+// strict mode unknown keys (typos like `comunity:`) fail the load instead of
+// being ignored.
+type strictConfigOptions struct {
+	strict bool
+}
+
+var strictConfigOpts = strictConfigOptions{}
+
+// applyStrictConfig passes --strict-config to the config loader before any
+// command loads a file.
+func applyStrictConfig() {
+	config.SetStrictYAML(strictConfigOpts.strict)
+}
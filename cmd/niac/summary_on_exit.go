@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/krisarmstrong/niac-go/pkg/stats"
+)
+
+// summaryOnExitOptions controls the per-device traffic summary printed on
+// shutdown, which shows which simulated devices a test actually exercised.
+type summaryOnExitOptions struct {
+	enabled bool
+}
+
+var summaryOnExitOpts = summaryOnExitOptions{}
+
+// printDeviceSummary writes one row per device with its packet counters and
+// the frames it sent by protocol
+func printDeviceSummary(w io.Writer, devices []stats.DeviceSummary) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Per-Device Summary")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEVICE\tRX\tTX\tERRORS\tSENT BY PROTOCOL")
+	for _, device := range devices {
+		protocols := make([]string, 0, len(device.Sent))
+		for protocol := range device.Sent {
+			protocols = append(protocols, protocol)
+		}
+		sort.Strings(protocols)
+		sent := make([]string, 0, len(protocols))
+		for _, protocol := range protocols {
+			sent = append(sent, fmt.Sprintf("%s=%d", protocol, device.Sent[protocol]))
+		}
+		if len(sent) == 0 {
+			sent = append(sent, "-")
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n",
+			device.Name, device.PacketsReceived, device.PacketsSent, device.Errors, strings.Join(sent, " "))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/krisarmstrong/niac-go/pkg/stats"
+)
+
+func TestPrintDeviceSummary(t *testing.T) {
+	devices := []stats.DeviceSummary{
+		{Name: "router-1", PacketsReceived: 12, PacketsSent: 15, Errors: 1, Sent: map[string]uint64{"icmp": 10, "arp": 3, "lldp": 2}},
+		{Name: "switch-1", Sent: map[string]uint64{}},
+	}
+
+	var out bytes.Buffer
+	printDeviceSummary(&out, devices)
+
+	rows := make(map[string][]string)
+	for _, line := range strings.Split(out.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			rows[fields[0]] = fields
+		}
+	}
+	if _, ok := rows["DEVICE"]; !ok {
+		t.Fatalf("summary has no header row:\n%s", out.String())
+	}
+	if got, want := strings.Join(rows["router-1"], " "), "router-1 12 15 1 arp=3 icmp=10 lldp=2"; got != want {
+		t.Errorf("router-1 row = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(rows["switch-1"], " "), "switch-1 0 0 0 -"; got != want {
+		t.Errorf("unexercised device row = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+// versionInfo is the machine-readable form of the version output.
+type versionInfo struct {
+	Version      string   `json:"version"`
+	Commit       string   `json:"commit"`
+	Date         string   `json:"date"`
+	GoVersion    string   `json:"go_version"`
+	OS           string   `json:"os"`
+	Arch         string   `json:"arch"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// protocolCapabilities lists the protocols compiled into this build.
+var protocolCapabilities = []string{
+	logging.ProtocolARP,
+	logging.ProtocolIP,
+	logging.ProtocolICMP,
+	logging.ProtocolIPv6,
+	logging.ProtocolICMPv6,
+	logging.ProtocolUDP,
+	logging.ProtocolTCP,
+	logging.ProtocolDNS,
+	logging.ProtocolDHCP,
+	logging.ProtocolDHCPv6,
+	logging.ProtocolHTTP,
+	logging.ProtocolFTP,
+	logging.ProtocolNetBIOS,
+	logging.ProtocolSTP,
+	logging.ProtocolLLDP,
+	logging.ProtocolCDP,
+	logging.ProtocolEDP,
+	logging.ProtocolFDP,
+	logging.ProtocolSNMP,
+}
+
+var versionOpts struct {
+	json bool
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show version information",
+	Long:  `Show version, build and platform information. Use --json for machine-readable output.`,
+	Example: `  # Human-readable version
+  niac version
+
+  # JSON for CI and packaging scripts
+  niac version --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if versionOpts.json {
+			if err := writeVersionJSON(cmd.OutOrStdout()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		printVersion()
+	},
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&versionOpts.json, "json", false, "Output version information as JSON")
+	rootCmd.AddCommand(versionCmd)
+}
+
+// currentVersionInfo collects build and platform details.
+func currentVersionInfo() versionInfo {
+	return versionInfo{
+		Version:      version,
+		Commit:       commit,
+		Date:         date,
+		GoVersion:    runtime.Version(),
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		Capabilities: append([]string(nil), protocolCapabilities...),
+	}
+}
+
+// writeVersionJSON writes the version information as indented JSON.
+func writeVersionJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(currentVersionInfo())
+}
+
+// rootVersionOutput renders `niac --version`, honoring --json.
+func rootVersionOutput() string {
+	if !versionOpts.json {
+		return fmt.Sprintf("niac %s (commit: %s, built: %s)\n", version, commit, date)
+	}
+	data, err := json.MarshalIndent(currentVersionInfo(), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("{\"error\": %q}\n", err.Error())
+	}
+	return string(data) + "\n"
+}
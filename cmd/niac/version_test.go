@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestWriteVersionJSON tests machine-readable version output
+func TestWriteVersionJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVersionJSON(&buf); err != nil {
+		t.Fatalf("writeVersionJSON failed: %v", err)
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &info); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	expected := map[string]string{
+		"version":    version,
+		"commit":     commit,
+		"date":       date,
+		"go_version": runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+	}
+	for key, want := range expected {
+		if got, _ := info[key].(string); got != want {
+			t.Errorf("%s = %q, expected %q", key, got, want)
+		}
+	}
+
+	caps, ok := info["capabilities"].([]interface{})
+	if !ok || len(caps) == 0 {
+		t.Fatalf("expected non-empty capabilities, got %v", info["capabilities"])
+	}
+	found := false
+	for _, c := range caps {
+		if c == "SNMP" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected SNMP in capabilities, got %v", caps)
+	}
+}
+
+// TestRootVersionOutput tests that --version stays human-readable unless --json is set
+func TestRootVersionOutput(t *testing.T) {
+	defer func() { versionOpts.json = false }()
+
+	versionOpts.json = false
+	if out := rootVersionOutput(); !strings.HasPrefix(out, "niac "+version) {
+		t.Errorf("expected human-readable version, got %q", out)
+	}
+
+	versionOpts.json = true
+	var info versionInfo
+	if err := json.Unmarshal([]byte(rootVersionOutput()), &info); err != nil {
+		t.Fatalf("expected JSON version output: %v", err)
+	}
+	if info.Version != version {
+		t.Errorf("version = %q, expected %q", info.Version, version)
+	}
+}
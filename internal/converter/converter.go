@@ -3,7 +3,10 @@ package converter
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -13,302 +16,564 @@ import (
 
 // Config represents the YAML configuration structure
 type Config struct {
-	IncludePath        string              `yaml:"include_path,omitempty"`
-	CapturePlaybacks   []CapturePlayback   `yaml:"capture_playbacks,omitempty"` // Changed to array
-	DiscoveryProtocols *DiscoveryProtocols `yaml:"discovery_protocols,omitempty"`
-	Devices            []Device            `yaml:"devices"`
+	IncludePath        string              `yaml:"include_path,omitempty" toml:"include_path,omitempty"`
+	CapturePlaybacks   []CapturePlayback   `yaml:"capture_playbacks,omitempty" toml:"capture_playbacks,omitempty"` // Changed to array
+	DiscoveryProtocols *DiscoveryProtocols `yaml:"discovery_protocols,omitempty" toml:"discovery_protocols,omitempty"`
+	Latency            *LatencyConfig      `yaml:"latency,omitempty" toml:"latency,omitempty"` // Default response latency for all devices
+	Tcp                *TcpConfig          `yaml:"tcp,omitempty" toml:"tcp,omitempty"`         // Global TCP service limits
+	FlowExport         *FlowExportConfig   `yaml:"flow_export,omitempty" toml:"flow_export,omitempty"`
+	RunMarker          *RunMarkerConfig    `yaml:"run_marker,omitempty" toml:"run_marker,omitempty"`           // Tag generated packets with a run identifier
+	WalkLimits         *WalkLimitsConfig   `yaml:"walk_limits,omitempty" toml:"walk_limits,omitempty"`         // Caps on SNMP walk file size and OID count
+	InterfaceScope     map[string][]string `yaml:"interface_scope,omitempty" toml:"interface_scope,omitempty"` // Capture interface -> subnets whose devices answer ARP/NDP/ICMP on it
+	Devices            []Device            `yaml:"devices" toml:"devices"`
 }
 
 // DiscoveryProtocols configures discovery protocol behavior
 type DiscoveryProtocols struct {
-	LLDP *ProtocolConfig `yaml:"lldp,omitempty"`
-	CDP  *ProtocolConfig `yaml:"cdp,omitempty"`
-	EDP  *ProtocolConfig `yaml:"edp,omitempty"`
-	FDP  *ProtocolConfig `yaml:"fdp,omitempty"`
+	LLDP *ProtocolConfig `yaml:"lldp,omitempty" toml:"lldp,omitempty"`
+	CDP  *ProtocolConfig `yaml:"cdp,omitempty" toml:"cdp,omitempty"`
+	EDP  *ProtocolConfig `yaml:"edp,omitempty" toml:"edp,omitempty"`
+	FDP  *ProtocolConfig `yaml:"fdp,omitempty" toml:"fdp,omitempty"`
+
+	MaxNeighbors int `yaml:"max_neighbors,omitempty" toml:"max_neighbors,omitempty"` // Learned neighbor table cap (default 1024)
 }
 
 // ProtocolConfig configures a discovery protocol
 type ProtocolConfig struct {
-	Enabled  bool `yaml:"enabled"`
-	Interval int  `yaml:"interval,omitempty"` // Advertisement interval in seconds
+	Enabled     bool    `yaml:"enabled" toml:"enabled"`
+	Interval    int     `yaml:"interval,omitempty" toml:"interval,omitempty"`         // Advertisement interval in seconds
+	PhaseJitter float64 `yaml:"phase_jitter,omitempty" toml:"phase_jitter,omitempty"` // Random first-advertisement offset as a fraction of the interval (0-1)
+}
+
+// RunMarkerConfig configures the marker applied to all generated traffic
+type RunMarkerConfig struct {
+	RunID string `yaml:"run_id,omitempty" toml:"run_id,omitempty"` // Derives an Ethernet trailer marker
+	DSCP  int    `yaml:"dscp,omitempty" toml:"dscp,omitempty"`     // DSCP value set on generated IP packets (0-63)
+}
+
+// FlowExportConfig configures export of flow samples to a collector
+type FlowExportConfig struct {
+	Collector    string `yaml:"collector" toml:"collector"`                             // host:port (default port 6343)
+	SamplingRate int    `yaml:"sampling_rate,omitempty" toml:"sampling_rate,omitempty"` // 1-in-N packets (default 256)
+	Protocol     string `yaml:"protocol,omitempty" toml:"protocol,omitempty"`           // sflow (default)
+	AgentIP      string `yaml:"agent_ip,omitempty" toml:"agent_ip,omitempty"`           // Agent address in datagrams (default: local address)
 }
 
 // CapturePlayback represents PCAP playback configuration
 type CapturePlayback struct {
-	FileName  string  `yaml:"file_name"`
-	LoopTime  int     `yaml:"loop_time,omitempty"`
-	ScaleTime float64 `yaml:"scale_time,omitempty"`
+	FileName  string  `yaml:"file_name" toml:"file_name"`
+	LoopTime  int     `yaml:"loop_time,omitempty" toml:"loop_time,omitempty"`
+	ScaleTime float64 `yaml:"scale_time,omitempty" toml:"scale_time,omitempty"`
 }
 
 // Device represents a network device
 type Device struct {
-	Name      string         `yaml:"name,omitempty"`
-	MAC       string         `yaml:"mac"`
-	IP        string         `yaml:"ip,omitempty"`  // Single IP (backward compatible)
-	IPs       []string       `yaml:"ips,omitempty"` // Multiple IPs (new feature)
-	VLAN      int            `yaml:"vlan,omitempty"`
-	SnmpAgent *SnmpAgent     `yaml:"snmp_agent,omitempty"`
-	Dhcp      *DhcpServer    `yaml:"dhcp,omitempty"`
-	Dns       *DnsServer     `yaml:"dns,omitempty"`
-	Lldp      *LldpConfig    `yaml:"lldp,omitempty"`
-	Cdp       *CdpConfig     `yaml:"cdp,omitempty"`
-	Edp       *EdpConfig     `yaml:"edp,omitempty"`
-	Fdp       *FdpConfig     `yaml:"fdp,omitempty"`
-	Stp       *StpConfig     `yaml:"stp,omitempty"`
-	Http      *HttpConfig    `yaml:"http,omitempty"`
-	Ftp       *FtpConfig     `yaml:"ftp,omitempty"`
-	Netbios   *NetbiosConfig `yaml:"netbios,omitempty"`
-	Icmp      *IcmpConfig    `yaml:"icmp,omitempty"`
-	Icmpv6    *Icmpv6Config  `yaml:"icmpv6,omitempty"`
-	Dhcpv6    *Dhcpv6Config  `yaml:"dhcpv6,omitempty"`
-	Traffic   *TrafficConfig `yaml:"traffic,omitempty"` // v1.6.0
+	Name      string         `yaml:"name,omitempty" toml:"name,omitempty"`
+	MAC       string         `yaml:"mac" toml:"mac"`
+	IP        string         `yaml:"ip,omitempty" toml:"ip,omitempty"`   // Single IP (backward compatible)
+	IPs       []string       `yaml:"ips,omitempty" toml:"ips,omitempty"` // Multiple IPs (new feature)
+	VLAN      int            `yaml:"vlan,omitempty" toml:"vlan,omitempty"`
+	Tags      []string       `yaml:"tags,omitempty" toml:"tags,omitempty"`                     // Logical groups for bulk operations
+	MTU       int            `yaml:"mtu,omitempty" toml:"mtu,omitempty"`                       // Link MTU in bytes (default 1500)
+	Jumbo     bool           `yaml:"jumbo,omitempty" toml:"jumbo,omitempty"`                   // Shorthand for mtu: 9000
+	BootDelay int            `yaml:"boot_delay,omitempty" toml:"boot_delay,omitempty"`         // Seconds the device stays silent after startup
+	IPTTL     int            `yaml:"ip_ttl,omitempty" toml:"ip_ttl,omitempty"`                 // TTL of IPv4 packets the device sends (default 64)
+	HopLimit  int            `yaml:"ipv6_hop_limit,omitempty" toml:"ipv6_hop_limit,omitempty"` // Hop limit of IPv6 packets the device sends (default 64)
+	TcpPorts  IntKeyMap      `yaml:"tcp_ports,omitempty" toml:"tcp_ports,omitempty"`           // Port -> open, closed or filtered
+	SnmpAgent *SnmpAgent     `yaml:"snmp_agent,omitempty" toml:"snmp_agent,omitempty"`
+	Quirks    []string       `yaml:"snmp_quirks,omitempty" toml:"snmp_quirks,omitempty"` // Non-RFC SNMP agent behaviors to imitate
+	Dhcp      *DhcpServer    `yaml:"dhcp,omitempty" toml:"dhcp,omitempty"`
+	Dns       *DnsServer     `yaml:"dns,omitempty" toml:"dns,omitempty"`
+	Lldp      *LldpConfig    `yaml:"lldp,omitempty" toml:"lldp,omitempty"`
+	Cdp       *CdpConfig     `yaml:"cdp,omitempty" toml:"cdp,omitempty"`
+	Edp       *EdpConfig     `yaml:"edp,omitempty" toml:"edp,omitempty"`
+	Fdp       *FdpConfig     `yaml:"fdp,omitempty" toml:"fdp,omitempty"`
+	Stp       *StpConfig     `yaml:"stp,omitempty" toml:"stp,omitempty"`
+	Http      *HttpConfig    `yaml:"http,omitempty" toml:"http,omitempty"`
+	Ftp       *FtpConfig     `yaml:"ftp,omitempty" toml:"ftp,omitempty"`
+	Netbios   *NetbiosConfig `yaml:"netbios,omitempty" toml:"netbios,omitempty"`
+	Arp       *ArpConfig     `yaml:"arp,omitempty" toml:"arp,omitempty"`
+	Latency   *LatencyConfig `yaml:"latency,omitempty" toml:"latency,omitempty"` // Overrides the global latency model
+	Tcp       *TcpConfig     `yaml:"tcp,omitempty" toml:"tcp,omitempty"`         // Per-device TCP service limits
+	Bridge    *BridgeConfig  `yaml:"bridge,omitempty" toml:"bridge,omitempty"`   // MAC learning (BRIDGE-MIB forwarding table)
+	Icmp      *IcmpConfig    `yaml:"icmp,omitempty" toml:"icmp,omitempty"`
+	Icmpv6    *Icmpv6Config  `yaml:"icmpv6,omitempty" toml:"icmpv6,omitempty"`
+	Dhcpv6    *Dhcpv6Config  `yaml:"dhcpv6,omitempty" toml:"dhcpv6,omitempty"`
+	Traffic   *TrafficConfig `yaml:"traffic,omitempty" toml:"traffic,omitempty"` // v1.6.0
+
+	Interfaces []DeviceInterface `yaml:"interfaces,omitempty" toml:"interfaces,omitempty"` // In ifIndex order
+
+	BootSequence *BootSequence `yaml:"boot_sequence,omitempty" toml:"boot_sequence,omitempty"` // Staged startup instead of boot_delay
+
+	Poe *PoeConfig `yaml:"poe,omitempty" toml:"poe,omitempty"` // Power needs advertised in LLDP and CDP
+	Bgp *BgpConfig `yaml:"bgp,omitempty" toml:"bgp,omitempty"` // Passive BGP peer on TCP port 179
+
+	MalformedFrames *MalformedFramesConfig `yaml:"malformed_frames,omitempty" toml:"malformed_frames,omitempty"` // Deliberately corrupt frames for fuzzing
+}
+
+// MalformedFramesConfig makes the device emit deliberately corrupt frames so
+// downstream parsers can be fuzzed on the wire
+type MalformedFramesConfig struct {
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`               // Nothing is sent unless true
+	Protocol   string `yaml:"protocol" toml:"protocol"`             // ipv4, icmp, udp or tcp
+	Corruption string `yaml:"corruption" toml:"corruption"`         // bad_checksum, truncated or bad_option_length
+	Rate       int    `yaml:"rate,omitempty" toml:"rate,omitempty"` // Frames per second (default 1, max 1000)
+}
+
+// BgpConfig represents a passive BGP speaker that establishes sessions but
+// exchanges no routes
+type BgpConfig struct {
+	AS       uint32 `yaml:"as" toml:"as"`                                   // Local AS number (1-4294967295)
+	RouterID string `yaml:"router_id,omitempty" toml:"router_id,omitempty"` // BGP identifier (default: the first IPv4 address)
+	HoldTime *int   `yaml:"hold_time,omitempty" toml:"hold_time,omitempty"` // Seconds, 0 or 3-65535 (default 90)
+}
+
+// PoeConfig represents the device as a Power-over-Ethernet powered device
+type PoeConfig struct {
+	Class          int     `yaml:"class,omitempty" toml:"class,omitempty"`                     // IEEE 802.3af/at power class 0-4 (default 0)
+	RequestedWatts float64 `yaml:"requested_watts,omitempty" toml:"requested_watts,omitempty"` // Power requested (default: the class maximum)
+	Priority       string  `yaml:"priority,omitempty" toml:"priority,omitempty"`               // critical, high or low (default)
+}
+
+// BootSequence represents a staged device startup. Each value is the seconds
+// after the previous stage (link_up: after the simulation starts).
+type BootSequence struct {
+	LinkUp    int `yaml:"link_up,omitempty" toml:"link_up,omitempty"`       // Answers ARP, ICMP and services other than SNMP
+	ColdStart int `yaml:"cold_start,omitempty" toml:"cold_start,omitempty"` // Sends the coldStart trap
+	Discovery int `yaml:"discovery,omitempty" toml:"discovery,omitempty"`   // Starts LLDP/CDP/EDP/FDP advertisements
+	Snmp      int `yaml:"snmp,omitempty" toml:"snmp,omitempty"`             // Answers SNMP requests
+}
+
+// DeviceInterface represents one of a device's interfaces
+type DeviceInterface struct {
+	Name        string `yaml:"name" toml:"name"`
+	Description string `yaml:"description,omitempty" toml:"description,omitempty"` // ifDescr, ifAlias and the LLDP Port Description
 }
 
 // SnmpAgent represents SNMP agent configuration
 type SnmpAgent struct {
-	WalkFile string       `yaml:"walk_file,omitempty"`
-	AddMibs  []AddMib     `yaml:"add_mibs,omitempty"`
-	Traps    *TrapsConfig `yaml:"traps,omitempty"` // v1.6.0
+	WalkFile    string          `yaml:"walk_file,omitempty" toml:"walk_file,omitempty"`
+	WalkSeries  *WalkSeries     `yaml:"walk_series,omitempty" toml:"walk_series,omitempty"`       // Timestamped walk snapshots replayed over time
+	WalkDir     string          `yaml:"walk_directory,omitempty" toml:"walk_directory,omitempty"` // Directory of .walk files merged into one MIB
+	AddMibs     []AddMib        `yaml:"add_mibs,omitempty" toml:"add_mibs,omitempty"`
+	Traps       *TrapsConfig    `yaml:"traps,omitempty" toml:"traps,omitempty"`             // v1.6.0
+	Communities []SnmpCommunity `yaml:"communities,omitempty" toml:"communities,omitempty"` // Additional communities with MIB views
+
+	AllowedManagers []string `yaml:"allowed_managers,omitempty" toml:"allowed_managers,omitempty"` // Source IPs/CIDRs allowed to query (empty = all)
+
+	ResponseSourcePort string `yaml:"response_source_port,omitempty" toml:"response_source_port,omitempty"` // "standard" (UDP 161, default) or "ephemeral"
+
+	MissingInstance string `yaml:"missing_instance,omitempty" toml:"missing_instance,omitempty"` // "no_such_instance" (default) or "no_such_object"
+
+	MaxMessageSize int `yaml:"max_message_size,omitempty" toml:"max_message_size,omitempty"` // Largest response message in bytes (484-65507)
+
+	AdminStatusSet string `yaml:"admin_status_set,omitempty" toml:"admin_status_set,omitempty"` // "read_only" (default) or "link_state"
+
+	Writable []string `yaml:"writable,omitempty" toml:"writable,omitempty"` // Objects SETs may change (unset = ifAdminStatus per admin_status_set)
+
+	HostResources *HostResourcesConfig `yaml:"host_resources,omitempty" toml:"host_resources,omitempty"` // HOST-RESOURCES-MIB storage/process tables
+
+	Contexts []SnmpContext `yaml:"contexts,omitempty" toml:"contexts,omitempty"` // SNMPv3 contexts answered from their own MIB
+
+	DisabledSubtrees []string `yaml:"disabled_subtrees,omitempty" toml:"disabled_subtrees,omitempty"` // OID subtrees answered as absent (noSuchObject)
+
+	V3 *SnmpV3User `yaml:"v3,omitempty" toml:"v3,omitempty"` // SNMPv3 USM user for authNoPriv/authPriv requests
+
+	SlowWalk *SnmpSlowWalk `yaml:"slow_walk,omitempty" toml:"slow_walk,omitempty"` // Walks slow down and time out under injected High CPU
+
+	EngineBoots int `yaml:"engine_boots,omitempty" toml:"engine_boots,omitempty"` // snmpEngineBoots at startup (default 1)
+	EngineTime  int `yaml:"engine_time,omitempty" toml:"engine_time,omitempty"`   // Seconds since the last boot at startup (snmpEngineTime, sysUpTime)
+}
+
+// SnmpSlowWalk represents an agent whose responses get slower through a walk
+// while a High CPU error is injected
+type SnmpSlowWalk struct {
+	StepMs    int `yaml:"step_ms,omitempty" toml:"step_ms,omitempty"`         // Delay added per request of a walk at 100% CPU (default 100)
+	MaxMs     int `yaml:"max_ms,omitempty" toml:"max_ms,omitempty"`           // Requests that would wait longer are dropped (default 2000)
+	WalkGapMs int `yaml:"walk_gap_ms,omitempty" toml:"walk_gap_ms,omitempty"` // Time without requests that ends a walk (default 10000)
+}
+
+// SnmpV3User represents the SNMPv3 USM user an agent authenticates
+type SnmpV3User struct {
+	User         string `yaml:"user" toml:"user"`
+	AuthProtocol string `yaml:"auth_protocol" toml:"auth_protocol"`                     // "md5" or "sha"
+	AuthKey      string `yaml:"auth_key" toml:"auth_key"`                               // Authentication passphrase (8+ characters)
+	PrivProtocol string `yaml:"priv_protocol,omitempty" toml:"priv_protocol,omitempty"` // "des" or "aes" (unset = authNoPriv only)
+	PrivKey      string `yaml:"priv_key,omitempty" toml:"priv_key,omitempty"`           // Privacy passphrase (8+ characters)
+}
+
+// SnmpContext represents a logical device reached through an SNMPv3 context name
+type SnmpContext struct {
+	Name     string `yaml:"name" toml:"name"`
+	SysName  string `yaml:"sys_name,omitempty" toml:"sys_name,omitempty"`
+	WalkFile string `yaml:"walk_file,omitempty" toml:"walk_file,omitempty"`
+}
+
+// WalkSeries represents a directory of walk snapshots the agent steps through
+type WalkSeries struct {
+	Directory string `yaml:"directory" toml:"directory"`
+	Interval  int    `yaml:"interval,omitempty" toml:"interval,omitempty"` // seconds per snapshot
+	Loop      bool   `yaml:"loop,omitempty" toml:"loop,omitempty"`         // restart from the first snapshot after the last
+}
+
+// HostResourcesConfig represents the HOST-RESOURCES-MIB tables of a server
+type HostResourcesConfig struct {
+	MemoryMB          int              `yaml:"memory_mb,omitempty" toml:"memory_mb,omitempty"`
+	MemoryUsedPercent int              `yaml:"memory_used_percent,omitempty" toml:"memory_used_percent,omitempty"`
+	SwapMB            int              `yaml:"swap_mb,omitempty" toml:"swap_mb,omitempty"`
+	Filesystems       []HostFilesystem `yaml:"filesystems,omitempty" toml:"filesystems,omitempty"`
+	Processes         []string         `yaml:"processes,omitempty" toml:"processes,omitempty"`
+}
+
+// HostFilesystem represents a mounted filesystem in hrStorageTable
+type HostFilesystem struct {
+	Path        string `yaml:"path" toml:"path"`
+	SizeGB      int    `yaml:"size_gb,omitempty" toml:"size_gb,omitempty"`
+	UsedPercent int    `yaml:"used_percent,omitempty" toml:"used_percent,omitempty"`
+}
+
+// SnmpCommunity represents a community string and the MIB view it may access
+type SnmpCommunity struct {
+	Name string       `yaml:"name" toml:"name"`
+	View *SnmpMibView `yaml:"view,omitempty" toml:"view,omitempty"` // Omit for full access
+}
+
+// SnmpMibView lists the OID subtrees included in or excluded from a view
+type SnmpMibView struct {
+	Include []string `yaml:"include,omitempty" toml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty" toml:"exclude,omitempty"`
 }
 
 // AddMib represents a MIB override or addition
 type AddMib struct {
-	OID   string `yaml:"oid"`
-	Type  string `yaml:"type"`
-	Value string `yaml:"value"`
+	OID   string `yaml:"oid" toml:"oid"`
+	Type  string `yaml:"type" toml:"type"`
+	Value string `yaml:"value" toml:"value"`
 }
 
 // DhcpServer represents DHCP server configuration
 type DhcpServer struct {
-	ClientLeases     []DhcpLease `yaml:"client_leases,omitempty"`
-	SubnetMask       string      `yaml:"subnet_mask,omitempty"`
-	Router           string      `yaml:"router,omitempty"`
-	DomainNameServer string      `yaml:"domain_name_server,omitempty"`
-	NextServerIP     string      `yaml:"next_server_ip,omitempty"`
-	ServerIdentifier string      `yaml:"server_identifier,omitempty"`
+	ClientLeases     []DhcpLease `yaml:"client_leases,omitempty" toml:"client_leases,omitempty"`
+	SubnetMask       string      `yaml:"subnet_mask,omitempty" toml:"subnet_mask,omitempty"`
+	Router           string      `yaml:"router,omitempty" toml:"router,omitempty"`
+	DomainNameServer string      `yaml:"domain_name_server,omitempty" toml:"domain_name_server,omitempty"`
+	NextServerIP     string      `yaml:"next_server_ip,omitempty" toml:"next_server_ip,omitempty"`
+	ServerIdentifier string      `yaml:"server_identifier,omitempty" toml:"server_identifier,omitempty"`
 	// Pool configuration
-	PoolStart string `yaml:"pool_start,omitempty"` // Start of DHCP address pool
-	PoolEnd   string `yaml:"pool_end,omitempty"`   // End of DHCP address pool
+	PoolStart string `yaml:"pool_start,omitempty" toml:"pool_start,omitempty"` // Start of DHCP address pool
+	PoolEnd   string `yaml:"pool_end,omitempty" toml:"pool_end,omitempty"`     // End of DHCP address pool
+	// Reply addressing
+	AlwaysBroadcast bool `yaml:"always_broadcast,omitempty" toml:"always_broadcast,omitempty"` // Broadcast Offers/Acks regardless of the client's flag
+	// Seconds a repeated DISCOVER (same MAC and xid) is answered with the original Offer
+	RetransmitWindow int `yaml:"retransmit_window,omitempty" toml:"retransmit_window,omitempty"`
+	// Seconds an expired lease's address is held before it is offered to another client
+	LeaseGrace int `yaml:"lease_grace,omitempty" toml:"lease_grace,omitempty"`
 	// DHCPv4 high priority options
-	NTPServers     []string `yaml:"ntp_servers,omitempty"`      // Option 42
-	DomainSearch   []string `yaml:"domain_search,omitempty"`    // Option 119
-	TFTPServerName string   `yaml:"tftp_server_name,omitempty"` // Option 66
-	BootfileName   string   `yaml:"bootfile_name,omitempty"`    // Option 67
-	VendorSpecific string   `yaml:"vendor_specific,omitempty"`  // Option 43 (hex string)
+	NTPServers     []string `yaml:"ntp_servers,omitempty" toml:"ntp_servers,omitempty"`           // Option 42
+	DomainSearch   []string `yaml:"domain_search,omitempty" toml:"domain_search,omitempty"`       // Option 119
+	TFTPServerName string   `yaml:"tftp_server_name,omitempty" toml:"tftp_server_name,omitempty"` // Option 66
+	BootfileName   string   `yaml:"bootfile_name,omitempty" toml:"bootfile_name,omitempty"`       // Option 67
+	VendorSpecific string   `yaml:"vendor_specific,omitempty" toml:"vendor_specific,omitempty"`   // Option 43 (hex string)
+	// Options for clients whose vendor class (60) or user class (77) matches
+	ClientClasses []DhcpClientClass `yaml:"client_classes,omitempty" toml:"client_classes,omitempty"`
 	// DHCPv6 options
-	SNTPServersV6 []string `yaml:"sntp_servers_v6,omitempty"` // Option 31
-	NTPServersV6  []string `yaml:"ntp_servers_v6,omitempty"`  // Option 56
-	SIPServersV6  []string `yaml:"sip_servers_v6,omitempty"`  // Option 22
-	SIPDomainsV6  []string `yaml:"sip_domains_v6,omitempty"`  // Option 21
+	SNTPServersV6 []string `yaml:"sntp_servers_v6,omitempty" toml:"sntp_servers_v6,omitempty"` // Option 31
+	NTPServersV6  []string `yaml:"ntp_servers_v6,omitempty" toml:"ntp_servers_v6,omitempty"`   // Option 56
+	SIPServersV6  []string `yaml:"sip_servers_v6,omitempty" toml:"sip_servers_v6,omitempty"`   // Option 22
+	SIPDomainsV6  []string `yaml:"sip_domains_v6,omitempty" toml:"sip_domains_v6,omitempty"`   // Option 21
+}
+
+// DhcpClientClass represents options handed to a class of DHCP clients, such
+// as PXE boot ROMs
+type DhcpClientClass struct {
+	Name           string    `yaml:"name" toml:"name"`
+	VendorClass    string    `yaml:"vendor_class,omitempty" toml:"vendor_class,omitempty"` // Substring of option 60
+	UserClass      string    `yaml:"user_class,omitempty" toml:"user_class,omitempty"`     // Substring of option 77
+	Regex          bool      `yaml:"regex,omitempty" toml:"regex,omitempty"`               // Match the classes as regular expressions
+	TFTPServerName string    `yaml:"tftp_server_name,omitempty" toml:"tftp_server_name,omitempty"`
+	BootfileName   string    `yaml:"bootfile_name,omitempty" toml:"bootfile_name,omitempty"`
+	NextServerIP   string    `yaml:"next_server_ip,omitempty" toml:"next_server_ip,omitempty"` // siaddr
+	Options        IntKeyMap `yaml:"options,omitempty" toml:"options,omitempty"`               // Extra options by code: text, or hex with a 0x prefix
 }
 
 // DhcpLease represents a DHCP client lease
 type DhcpLease struct {
-	ClientIP     string `yaml:"client_ip"`
-	MacAddrValue string `yaml:"mac_addr_value,omitempty"`
-	MacAddrMask  string `yaml:"mac_addr_mask,omitempty"`
+	ClientIP     string `yaml:"client_ip" toml:"client_ip"`
+	MacAddrValue string `yaml:"mac_addr_value,omitempty" toml:"mac_addr_value,omitempty"`
+	MacAddrMask  string `yaml:"mac_addr_mask,omitempty" toml:"mac_addr_mask,omitempty"`
 }
 
 // DnsServer represents DNS server configuration
 type DnsServer struct {
-	ForwardRecords []DnsRecord `yaml:"forward_records,omitempty"`
-	ReverseRecords []DnsRecord `yaml:"reverse_records,omitempty"`
+	ForwardRecords []DnsRecord `yaml:"forward_records,omitempty" toml:"forward_records,omitempty"`
+	ReverseRecords []DnsRecord `yaml:"reverse_records,omitempty" toml:"reverse_records,omitempty"`
+	Forwarders     []string    `yaml:"forwarders,omitempty" toml:"forwarders,omitempty"` // upstream resolvers (ip or ip:port) for names without a local record
+	CacheTTL       int         `yaml:"cache_ttl,omitempty" toml:"cache_ttl,omitempty"`   // seconds a forwarded answer is cached at most
 }
 
 // DnsRecord represents a DNS A or PTR record
 type DnsRecord struct {
-	Name string `yaml:"name"`
-	IP   string `yaml:"ip"`
-	TTL  int    `yaml:"ttl,omitempty"`
+	Name string `yaml:"name" toml:"name"`
+	IP   string `yaml:"ip" toml:"ip"`
+	TTL  int    `yaml:"ttl,omitempty" toml:"ttl,omitempty"`
 }
 
 // LldpConfig represents LLDP discovery protocol configuration
 type LldpConfig struct {
-	Enabled           bool   `yaml:"enabled,omitempty"`
-	AdvertiseInterval int    `yaml:"advertise_interval,omitempty"`
-	TTL               int    `yaml:"ttl,omitempty"`
-	SystemDescription string `yaml:"system_description,omitempty"`
-	PortDescription   string `yaml:"port_description,omitempty"`
-	ChassisIDType     string `yaml:"chassis_id_type,omitempty"`
+	Enabled           bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	AdvertiseInterval int    `yaml:"advertise_interval,omitempty" toml:"advertise_interval,omitempty"`
+	TTL               int    `yaml:"ttl,omitempty" toml:"ttl,omitempty"`
+	SystemDescription string `yaml:"system_description,omitempty" toml:"system_description,omitempty"`
+	PortDescription   string `yaml:"port_description,omitempty" toml:"port_description,omitempty"`
+	ChassisIDType     string `yaml:"chassis_id_type,omitempty" toml:"chassis_id_type,omitempty"`
+	FastStart         bool   `yaml:"fast_start,omitempty" toml:"fast_start,omitempty"` // Advertise as soon as a new neighbor is heard
 }
 
 // CdpConfig represents CDP discovery protocol configuration
 type CdpConfig struct {
-	Enabled           bool   `yaml:"enabled,omitempty"`
-	AdvertiseInterval int    `yaml:"advertise_interval,omitempty"`
-	Holdtime          int    `yaml:"holdtime,omitempty"`
-	Version           int    `yaml:"version,omitempty"`
-	SoftwareVersion   string `yaml:"software_version,omitempty"`
-	Platform          string `yaml:"platform,omitempty"`
-	PortID            string `yaml:"port_id,omitempty"`
+	Enabled           bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	AdvertiseInterval int    `yaml:"advertise_interval,omitempty" toml:"advertise_interval,omitempty"`
+	Holdtime          int    `yaml:"holdtime,omitempty" toml:"holdtime,omitempty"`
+	Version           int    `yaml:"version,omitempty" toml:"version,omitempty"`
+	SoftwareVersion   string `yaml:"software_version,omitempty" toml:"software_version,omitempty"`
+	Platform          string `yaml:"platform,omitempty" toml:"platform,omitempty"`
+	PortID            string `yaml:"port_id,omitempty" toml:"port_id,omitempty"`
 }
 
 // EdpConfig represents EDP discovery protocol configuration
 type EdpConfig struct {
-	Enabled           bool   `yaml:"enabled,omitempty"`
-	AdvertiseInterval int    `yaml:"advertise_interval,omitempty"`
-	VersionString     string `yaml:"version_string,omitempty"`
-	DisplayString     string `yaml:"display_string,omitempty"`
+	Enabled           bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	AdvertiseInterval int    `yaml:"advertise_interval,omitempty" toml:"advertise_interval,omitempty"`
+	VersionString     string `yaml:"version_string,omitempty" toml:"version_string,omitempty"`
+	DisplayString     string `yaml:"display_string,omitempty" toml:"display_string,omitempty"`
 }
 
 // FdpConfig represents FDP discovery protocol configuration
 type FdpConfig struct {
-	Enabled           bool   `yaml:"enabled,omitempty"`
-	AdvertiseInterval int    `yaml:"advertise_interval,omitempty"`
-	Holdtime          int    `yaml:"holdtime,omitempty"`
-	SoftwareVersion   string `yaml:"software_version,omitempty"`
-	Platform          string `yaml:"platform,omitempty"`
-	PortID            string `yaml:"port_id,omitempty"`
+	Enabled           bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	AdvertiseInterval int    `yaml:"advertise_interval,omitempty" toml:"advertise_interval,omitempty"`
+	Holdtime          int    `yaml:"holdtime,omitempty" toml:"holdtime,omitempty"`
+	SoftwareVersion   string `yaml:"software_version,omitempty" toml:"software_version,omitempty"`
+	Platform          string `yaml:"platform,omitempty" toml:"platform,omitempty"`
+	PortID            string `yaml:"port_id,omitempty" toml:"port_id,omitempty"`
 }
 
 // StpConfig represents STP/RSTP/MSTP configuration
 type StpConfig struct {
-	Enabled        bool   `yaml:"enabled,omitempty"`
-	BridgePriority uint16 `yaml:"bridge_priority,omitempty"`
-	HelloTime      uint16 `yaml:"hello_time,omitempty"`
-	MaxAge         uint16 `yaml:"max_age,omitempty"`
-	ForwardDelay   uint16 `yaml:"forward_delay,omitempty"`
-	Version        string `yaml:"version,omitempty"`
+	Enabled        bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	BridgePriority uint16 `yaml:"bridge_priority,omitempty" toml:"bridge_priority,omitempty"`
+	HelloTime      uint16 `yaml:"hello_time,omitempty" toml:"hello_time,omitempty"`
+	MaxAge         uint16 `yaml:"max_age,omitempty" toml:"max_age,omitempty"`
+	ForwardDelay   uint16 `yaml:"forward_delay,omitempty" toml:"forward_delay,omitempty"`
+	Version        string `yaml:"version,omitempty" toml:"version,omitempty"`
 }
 
 // HttpConfig represents HTTP server configuration
 type HttpConfig struct {
-	Enabled    bool           `yaml:"enabled,omitempty"`
-	ServerName string         `yaml:"server_name,omitempty"`
-	Endpoints  []HttpEndpoint `yaml:"endpoints,omitempty"`
+	Enabled    bool           `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	ServerName string         `yaml:"server_name,omitempty" toml:"server_name,omitempty"`
+	Endpoints  []HttpEndpoint `yaml:"endpoints,omitempty" toml:"endpoints,omitempty"`
 }
 
 // HttpEndpoint represents an HTTP endpoint configuration
 type HttpEndpoint struct {
-	Path        string `yaml:"path,omitempty"`
-	Method      string `yaml:"method,omitempty"`
-	StatusCode  int    `yaml:"status_code,omitempty"`
-	ContentType string `yaml:"content_type,omitempty"`
-	Body        string `yaml:"body,omitempty"`
+	Path        string `yaml:"path,omitempty" toml:"path,omitempty"`
+	Method      string `yaml:"method,omitempty" toml:"method,omitempty"`
+	StatusCode  int    `yaml:"status_code,omitempty" toml:"status_code,omitempty"`
+	ContentType string `yaml:"content_type,omitempty" toml:"content_type,omitempty"`
+	Body        string `yaml:"body,omitempty" toml:"body,omitempty"`
 }
 
 // FtpConfig represents FTP server configuration
 type FtpConfig struct {
-	Enabled        bool      `yaml:"enabled,omitempty"`
-	WelcomeBanner  string    `yaml:"welcome_banner,omitempty"`
-	SystemType     string    `yaml:"system_type,omitempty"`
-	AllowAnonymous bool      `yaml:"allow_anonymous,omitempty"`
-	Users          []FtpUser `yaml:"users,omitempty"`
+	Enabled        bool      `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	WelcomeBanner  string    `yaml:"welcome_banner,omitempty" toml:"welcome_banner,omitempty"`
+	SystemType     string    `yaml:"system_type,omitempty" toml:"system_type,omitempty"`
+	AllowAnonymous bool      `yaml:"allow_anonymous,omitempty" toml:"allow_anonymous,omitempty"`
+	Users          []FtpUser `yaml:"users,omitempty" toml:"users,omitempty"`
 }
 
 // FtpUser represents an FTP user account
 type FtpUser struct {
-	Username string `yaml:"username,omitempty"`
-	Password string `yaml:"password,omitempty"`
-	HomeDir  string `yaml:"home_dir,omitempty"`
+	Username string `yaml:"username,omitempty" toml:"username,omitempty"`
+	Password string `yaml:"password,omitempty" toml:"password,omitempty"`
+	HomeDir  string `yaml:"home_dir,omitempty" toml:"home_dir,omitempty"`
 }
 
 // NetbiosConfig represents NetBIOS service configuration
 type NetbiosConfig struct {
-	Enabled   bool     `yaml:"enabled,omitempty"`
-	Name      string   `yaml:"name,omitempty"`
-	Workgroup string   `yaml:"workgroup,omitempty"`
-	NodeType  string   `yaml:"node_type,omitempty"`
-	Services  []string `yaml:"services,omitempty"`
-	TTL       uint32   `yaml:"ttl,omitempty"`
+	Enabled   bool     `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	Name      string   `yaml:"name,omitempty" toml:"name,omitempty"`
+	Workgroup string   `yaml:"workgroup,omitempty" toml:"workgroup,omitempty"`
+	NodeType  string   `yaml:"node_type,omitempty" toml:"node_type,omitempty"`
+	Services  []string `yaml:"services,omitempty" toml:"services,omitempty"`
+	TTL       uint32   `yaml:"ttl,omitempty" toml:"ttl,omitempty"`
+}
+
+// ArpConfig represents ARP responder configuration
+type ArpConfig struct {
+	ProxyARPSubnets []string `yaml:"proxy_arp_subnets,omitempty" toml:"proxy_arp_subnets,omitempty"` // CIDRs answered with the device MAC
+	ReplyDelayMs    int      `yaml:"reply_delay_ms,omitempty" toml:"reply_delay_ms,omitempty"`
+}
+
+// LatencyConfig represents a response latency model (base delay plus jitter)
+type LatencyConfig struct {
+	BaseMs       int    `yaml:"base_ms,omitempty" toml:"base_ms,omitempty"`
+	JitterMs     int    `yaml:"jitter_ms,omitempty" toml:"jitter_ms,omitempty"`
+	Distribution string `yaml:"distribution,omitempty" toml:"distribution,omitempty"` // uniform (default) or normal
+}
+
+// TcpConfig represents limits for the simulated TCP services (HTTP, FTP)
+type TcpConfig struct {
+	MaxConnections int `yaml:"max_connections,omitempty" toml:"max_connections,omitempty"` // Concurrent connections before new ones are refused
+}
+
+// WalkLimitsConfig represents the caps on the SNMP walk files loaded
+type WalkLimitsConfig struct {
+	MaxFileSizeMB int `yaml:"max_file_size_mb,omitempty" toml:"max_file_size_mb,omitempty"` // Largest walk file in MiB (default 256)
+	MaxOIDs       int `yaml:"max_oids,omitempty" toml:"max_oids,omitempty"`                 // Most OIDs in one walk file (default 2000000)
+}
+
+// BridgeConfig represents a switch's MAC learning behavior
+type BridgeConfig struct {
+	AgingTime    int `yaml:"aging_time,omitempty" toml:"aging_time,omitempty"`         // seconds before an idle MAC is forgotten
+	MacTableSize int `yaml:"mac_table_size,omitempty" toml:"mac_table_size,omitempty"` // learned MACs the forwarding table holds
 }
 
 // IcmpConfig represents ICMP/ICMPv4 configuration
 type IcmpConfig struct {
-	Enabled   bool  `yaml:"enabled,omitempty"`
-	TTL       uint8 `yaml:"ttl,omitempty"`
-	RateLimit int   `yaml:"rate_limit,omitempty"`
+	Enabled                bool   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	TTL                    uint8  `yaml:"ttl,omitempty" toml:"ttl,omitempty"`
+	RateLimit              int    `yaml:"rate_limit,omitempty" toml:"rate_limit,omitempty"`
+	RespondToBroadcastPing bool   `yaml:"respond_to_broadcast_ping,omitempty" toml:"respond_to_broadcast_ping,omitempty"`
+	ReplyOffsubnet         *bool  `yaml:"reply_offsubnet,omitempty" toml:"reply_offsubnet,omitempty"` // Answer pings from outside the device's subnet (default true)
+	SubnetMask             string `yaml:"subnet_mask,omitempty" toml:"subnet_mask,omitempty"`         // Mask of the device's subnet (default: dhcp subnet_mask)
 }
 
 // Icmpv6Config represents ICMPv6 configuration
 type Icmpv6Config struct {
-	Enabled   bool  `yaml:"enabled,omitempty"`
-	HopLimit  uint8 `yaml:"hop_limit,omitempty"`
-	RateLimit int   `yaml:"rate_limit,omitempty"`
+	Enabled   bool              `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	HopLimit  uint8             `yaml:"hop_limit,omitempty" toml:"hop_limit,omitempty"`
+	RateLimit int               `yaml:"rate_limit,omitempty" toml:"rate_limit,omitempty"`
+	RouteInfo []Icmpv6RouteInfo `yaml:"route_info,omitempty" toml:"route_info,omitempty"`
+}
+
+// Icmpv6RouteInfo represents a route advertised in Router Advertisements
+type Icmpv6RouteInfo struct {
+	Prefix     string  `yaml:"prefix" toml:"prefix"`
+	Preference string  `yaml:"preference,omitempty" toml:"preference,omitempty"`
+	Lifetime   *uint32 `yaml:"lifetime,omitempty" toml:"lifetime,omitempty"`
 }
 
 // Dhcpv6Config represents DHCPv6 server configuration
 type Dhcpv6Config struct {
-	Enabled           bool         `yaml:"enabled,omitempty"`
-	Pools             []Dhcpv6Pool `yaml:"pools,omitempty"`
-	PreferredLifetime uint32       `yaml:"preferred_lifetime,omitempty"`
-	ValidLifetime     uint32       `yaml:"valid_lifetime,omitempty"`
-	Preference        uint8        `yaml:"preference,omitempty"`
-	DNSServers        []string     `yaml:"dns_servers,omitempty"`
-	DomainList        []string     `yaml:"domain_list,omitempty"`
-	SNTPServers       []string     `yaml:"sntp_servers,omitempty"`
-	NTPServers        []string     `yaml:"ntp_servers,omitempty"`
-	SIPServers        []string     `yaml:"sip_servers,omitempty"`
-	SIPDomains        []string     `yaml:"sip_domains,omitempty"`
+	Enabled           bool         `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	Pools             []Dhcpv6Pool `yaml:"pools,omitempty" toml:"pools,omitempty"`
+	DelegatedPrefixes []string     `yaml:"delegated_prefixes,omitempty" toml:"delegated_prefixes,omitempty"` // Prefixes handed out by prefix delegation (IA_PD)
+	PreferredLifetime uint32       `yaml:"preferred_lifetime,omitempty" toml:"preferred_lifetime,omitempty"`
+	ValidLifetime     uint32       `yaml:"valid_lifetime,omitempty" toml:"valid_lifetime,omitempty"`
+	LeaseGrace        int          `yaml:"lease_grace,omitempty" toml:"lease_grace,omitempty"` // Seconds an expired address is held before reuse
+	Preference        uint8        `yaml:"preference,omitempty" toml:"preference,omitempty"`
+	DNSServers        []string     `yaml:"dns_servers,omitempty" toml:"dns_servers,omitempty"`
+	DomainList        []string     `yaml:"domain_list,omitempty" toml:"domain_list,omitempty"`
+	SNTPServers       []string     `yaml:"sntp_servers,omitempty" toml:"sntp_servers,omitempty"`
+	NTPServers        []string     `yaml:"ntp_servers,omitempty" toml:"ntp_servers,omitempty"`
+	SIPServers        []string     `yaml:"sip_servers,omitempty" toml:"sip_servers,omitempty"`
+	SIPDomains        []string     `yaml:"sip_domains,omitempty" toml:"sip_domains,omitempty"`
 }
 
 // Dhcpv6Pool represents an IPv6 address pool
 type Dhcpv6Pool struct {
-	Network    string `yaml:"network,omitempty"`
-	RangeStart string `yaml:"range_start,omitempty"`
-	RangeEnd   string `yaml:"range_end,omitempty"`
+	Network    string `yaml:"network,omitempty" toml:"network,omitempty"`
+	RangeStart string `yaml:"range_start,omitempty" toml:"range_start,omitempty"`
+	RangeEnd   string `yaml:"range_end,omitempty" toml:"range_end,omitempty"`
 }
 
 // TrafficConfig represents traffic pattern configuration (v1.6.0)
 type TrafficConfig struct {
-	Enabled          bool                   `yaml:"enabled,omitempty"`
-	ARPAnnouncements *ARPAnnouncementConfig `yaml:"arp_announcements,omitempty"`
-	PeriodicPings    *PeriodicPingConfig    `yaml:"periodic_pings,omitempty"`
-	RandomTraffic    *RandomTrafficConfig   `yaml:"random_traffic,omitempty"`
+	Enabled          bool                   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	ARPAnnouncements *ARPAnnouncementConfig `yaml:"arp_announcements,omitempty" toml:"arp_announcements,omitempty"`
+	PeriodicPings    *PeriodicPingConfig    `yaml:"periodic_pings,omitempty" toml:"periodic_pings,omitempty"`
+	RandomTraffic    *RandomTrafficConfig   `yaml:"random_traffic,omitempty" toml:"random_traffic,omitempty"`
 }
 
 // ARPAnnouncementConfig configures gratuitous ARP announcements
 type ARPAnnouncementConfig struct {
-	Enabled  bool `yaml:"enabled,omitempty"`
-	Interval int  `yaml:"interval,omitempty"` // seconds
+	Enabled  bool                   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	Interval int                    `yaml:"interval,omitempty" toml:"interval,omitempty"` // seconds
+	Schedule *TrafficScheduleConfig `yaml:"schedule,omitempty" toml:"schedule,omitempty"`
 }
 
 // PeriodicPingConfig configures periodic ICMP echo requests
 type PeriodicPingConfig struct {
-	Enabled     bool `yaml:"enabled,omitempty"`
-	Interval    int  `yaml:"interval,omitempty"`     // seconds
-	PayloadSize int  `yaml:"payload_size,omitempty"` // bytes
+	Enabled     bool                   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	Interval    int                    `yaml:"interval,omitempty" toml:"interval,omitempty"`         // seconds
+	PayloadSize int                    `yaml:"payload_size,omitempty" toml:"payload_size,omitempty"` // bytes
+	Schedule    *TrafficScheduleConfig `yaml:"schedule,omitempty" toml:"schedule,omitempty"`
 }
 
 // RandomTrafficConfig configures random background traffic
 type RandomTrafficConfig struct {
-	Enabled     bool     `yaml:"enabled,omitempty"`
-	Interval    int      `yaml:"interval,omitempty"`     // seconds
-	PacketCount int      `yaml:"packet_count,omitempty"` // packets per interval
-	Patterns    []string `yaml:"patterns,omitempty"`     // traffic patterns
+	Enabled     bool                   `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	Interval    int                    `yaml:"interval,omitempty" toml:"interval,omitempty"`         // seconds
+	PacketCount int                    `yaml:"packet_count,omitempty" toml:"packet_count,omitempty"` // packets per interval
+	Patterns    []string               `yaml:"patterns,omitempty" toml:"patterns,omitempty"`         // traffic patterns
+	Schedule    *TrafficScheduleConfig `yaml:"schedule,omitempty" toml:"schedule,omitempty"`
+}
+
+// TrafficScheduleConfig limits a traffic pattern to daily time windows
+type TrafficScheduleConfig struct {
+	Windows []string `yaml:"windows,omitempty" toml:"windows,omitempty"` // "HH:MM-HH:MM", may wrap past midnight
+	Days    []string `yaml:"days,omitempty" toml:"days,omitempty"`       // sun, mon, tue, wed, thu, fri, sat
 }
 
 // TrapsConfig represents SNMP trap configuration (v1.6.0)
 type TrapsConfig struct {
-	Enabled               bool                 `yaml:"enabled,omitempty"`
-	Receivers             []string             `yaml:"receivers,omitempty"`
-	Community             string               `yaml:"community,omitempty"` // SNMP community string
-	ColdStart             *TrapTriggerConfig   `yaml:"cold_start,omitempty"`
-	LinkState             *LinkStateTrapConfig `yaml:"link_state,omitempty"`
-	AuthenticationFailure *TrapTriggerConfig   `yaml:"authentication_failure,omitempty"`
-	HighCPU               *ThresholdTrapConfig `yaml:"high_cpu,omitempty"`
-	HighMemory            *ThresholdTrapConfig `yaml:"high_memory,omitempty"`
-	InterfaceErrors       *ThresholdTrapConfig `yaml:"interface_errors,omitempty"`
+	Enabled               bool                 `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	Receivers             []string             `yaml:"receivers,omitempty" toml:"receivers,omitempty"`
+	Community             string               `yaml:"community,omitempty" toml:"community,omitempty"` // SNMP community string
+	ColdStart             *TrapTriggerConfig   `yaml:"cold_start,omitempty" toml:"cold_start,omitempty"`
+	LinkState             *LinkStateTrapConfig `yaml:"link_state,omitempty" toml:"link_state,omitempty"`
+	AuthenticationFailure *TrapTriggerConfig   `yaml:"authentication_failure,omitempty" toml:"authentication_failure,omitempty"`
+	HighCPU               *ThresholdTrapConfig `yaml:"high_cpu,omitempty" toml:"high_cpu,omitempty"`
+	HighMemory            *ThresholdTrapConfig `yaml:"high_memory,omitempty" toml:"high_memory,omitempty"`
+	InterfaceErrors       *ThresholdTrapConfig `yaml:"interface_errors,omitempty" toml:"interface_errors,omitempty"`
+	TrapVarbinds          []TrapVarbind        `yaml:"trap_varbinds,omitempty" toml:"trap_varbinds,omitempty"` // Extra varbinds appended to traps
+	RateLimit             *TrapRateLimitConfig `yaml:"rate_limit,omitempty" toml:"rate_limit,omitempty"`
+}
+
+// TrapRateLimitConfig caps how many traps each receiver gets per window
+type TrapRateLimitConfig struct {
+	MaxTraps int `yaml:"max_traps,omitempty" toml:"max_traps,omitempty"` // traps per receiver per window
+	Window   int `yaml:"window,omitempty" toml:"window,omitempty"`       // window length in seconds
+}
+
+// TrapVarbind represents an extra varbind appended to outgoing traps
+type TrapVarbind struct {
+	OID   string   `yaml:"oid" toml:"oid"`
+	Type  string   `yaml:"type,omitempty" toml:"type,omitempty"`   // integer, string, oid, ipaddress, counter32, gauge32, timeticks, counter64
+	Value string   `yaml:"value,omitempty" toml:"value,omitempty"` // Go template: {{.IfIndex}}, {{.IfDescr}}, {{.Value}}, {{.Device}}, {{.Trap}}
+	Traps []string `yaml:"traps,omitempty" toml:"traps,omitempty"` // Limit to these traps (e.g., linkDown, highCPU)
 }
 
 // TrapTriggerConfig configures a simple trap trigger
 type TrapTriggerConfig struct {
-	Enabled   bool `yaml:"enabled,omitempty"`
-	OnStartup bool `yaml:"on_startup,omitempty"`
+	Enabled   bool `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	OnStartup bool `yaml:"on_startup,omitempty" toml:"on_startup,omitempty"`
 }
 
 // LinkStateTrapConfig configures link up/down traps
 type LinkStateTrapConfig struct {
-	Enabled  bool `yaml:"enabled,omitempty"`
-	LinkDown bool `yaml:"link_down,omitempty"`
-	LinkUp   bool `yaml:"link_up,omitempty"`
+	Enabled  bool `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	LinkDown bool `yaml:"link_down,omitempty" toml:"link_down,omitempty"`
+	LinkUp   bool `yaml:"link_up,omitempty" toml:"link_up,omitempty"`
 }
 
 // ThresholdTrapConfig configures threshold-based traps
 type ThresholdTrapConfig struct {
-	Enabled   bool `yaml:"enabled,omitempty"`
-	Threshold int  `yaml:"threshold,omitempty"` // threshold value
-	Interval  int  `yaml:"interval,omitempty"`  // check interval in seconds
+	Enabled   bool `yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	Threshold int  `yaml:"threshold,omitempty" toml:"threshold,omitempty"` // threshold value
+	Interval  int  `yaml:"interval,omitempty" toml:"interval,omitempty"`   // check interval in seconds
 }
 
 // Parser handles parsing Java DSL format
@@ -740,19 +1005,26 @@ func (p *Parser) formatMAC(mac string) string {
 		mac[0:2], mac[2:4], mac[4:6], mac[6:8], mac[8:10], mac[10:12])
 }
 
-// LoadYAMLConfig loads a YAML config file into Go config structure
-func LoadYAMLConfig(filename string) (*Config, error) {
+// LoadYAMLConfig loads a YAML config file into Go config structure. In
+// strict mode keys that match no config field are errors (see
+// LoadYAMLConfigFromBytes).
+func LoadYAMLConfig(filename string, strict bool) (*Config, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("error reading YAML file: %w", err)
 	}
-	return LoadYAMLConfigFromBytes(data)
+	return LoadYAMLConfigFromBytes(data, strict)
 }
 
 // LoadYAMLConfigFromBytes converts in-memory YAML data into a Go config structure.
-func LoadYAMLConfigFromBytes(data []byte) (*Config, error) {
+// Unknown keys are ignored unless strict is set, in which case a typo such as
+// `comunity:` fails the load with the line it appears on.
+func LoadYAMLConfigFromBytes(data []byte, strict bool) (*Config, error) {
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(strict)
+	// An empty document decodes to io.EOF; treat it as an empty config
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
 		return nil, fmt.Errorf("error parsing YAML: %w", err)
 	}
 	return &config, nil
@@ -0,0 +1,268 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertYAMLToDSL converts a YAML config file back to the Java DSL. Settings
+// the DSL cannot express are left out and reported as warnings on stderr
+// rather than failing the conversion.
+func ConvertYAMLToDSL(inputPath, outputPath string, verbose bool) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("error reading input file: %w", err)
+	}
+
+	config, warnings, err := loadYAMLForDSL(data)
+	if err != nil {
+		return err
+	}
+
+	dsl, dropped := WriteDSL(config)
+	warnings = append(warnings, dropped...)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if verbose {
+		fmt.Printf("  %d devices, %d settings not converted\n", len(config.Devices), len(warnings))
+	}
+
+	if err := os.WriteFile(outputPath, []byte(dsl), 0600); err != nil {
+		return fmt.Errorf("error writing output file: %w", err)
+	}
+
+	return nil
+}
+
+// loadYAMLForDSL loads a YAML config, listing keys that match no config field
+// as warnings instead of failing on them.
+func loadYAMLForDSL(data []byte) (*Config, []string, error) {
+	config, err := LoadYAMLConfigFromBytes(data, true)
+	if err == nil {
+		return config, nil, nil
+	}
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return nil, nil, err
+	}
+	config, err = LoadYAMLConfigFromBytes(data, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings := make([]string, 0, len(typeErr.Errors))
+	for _, message := range typeErr.Errors {
+		warnings = append(warnings, "unknown key ignored: "+message)
+	}
+	return config, warnings, nil
+}
+
+// WriteDSL renders config in the Java DSL read by Parser. It returns the DSL
+// and a warning for each setting the DSL has no directive for.
+func WriteDSL(config *Config) (string, []string) {
+	w := &dslWriter{}
+
+	for _, key := range droppedFields(*config, "include_path", "capture_playbacks", "devices") {
+		w.warn("%s has no DSL equivalent", key)
+	}
+
+	if config.IncludePath != "" {
+		w.line(0, "IncludePath(\"%s\")", config.IncludePath)
+		w.b.WriteString("\n")
+	}
+
+	for _, playback := range config.CapturePlaybacks {
+		w.line(0, "CapturePlayback(")
+		w.line(1, "FileName(\"%s\")", playback.FileName)
+		if playback.LoopTime != 0 {
+			w.line(1, "LoopTime(%d)", playback.LoopTime)
+		}
+		if playback.ScaleTime != 0 {
+			w.line(1, "ScaleTime(%s)", strconv.FormatFloat(playback.ScaleTime, 'f', -1, 64))
+		}
+		w.line(0, ")")
+		w.b.WriteString("\n")
+	}
+
+	for i := range config.Devices {
+		w.device(i, &config.Devices[i])
+		w.b.WriteString("\n")
+	}
+
+	return w.b.String(), w.warnings
+}
+
+// dslWriter accumulates DSL lines and conversion warnings
+type dslWriter struct {
+	b        strings.Builder
+	warnings []string
+}
+
+func (w *dslWriter) line(depth int, format string, args ...interface{}) {
+	w.b.WriteString(strings.Repeat("    ", depth))
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteString("\n")
+}
+
+func (w *dslWriter) warn(format string, args ...interface{}) {
+	w.warnings = append(w.warnings, fmt.Sprintf(format, args...))
+}
+
+// device writes a Device block. The DSL names devices by position, so a
+// name other than the one Parser would assign is reported as dropped.
+func (w *dslWriter) device(index int, device *Device) {
+	label := device.Name
+	if label == "" {
+		label = fmt.Sprintf("#%d", index+1)
+	}
+	if device.Name != "" && device.Name != fmt.Sprintf("device%d", index+1) {
+		w.warn("device %s: name has no DSL equivalent", label)
+	}
+	for _, key := range droppedFields(*device, "name", "mac", "ip", "ips", "vlan", "snmp_agent", "dhcp", "dns") {
+		w.warn("device %s: %s has no DSL equivalent", label, key)
+	}
+
+	w.line(0, "Device(")
+	w.line(1, "MacAddr(%s)", dslMAC(device.MAC))
+
+	// One address per device in the DSL
+	ips := device.IPs
+	if device.IP != "" {
+		ips = append([]string{device.IP}, ips...)
+	}
+	if len(ips) > 0 {
+		w.line(1, "IpAddr(%s)", ips[0])
+		for _, ip := range ips[1:] {
+			if ip != ips[0] {
+				w.warn("device %s: additional address %s has no DSL equivalent", label, ip)
+			}
+		}
+	}
+	if device.VLAN != 0 {
+		w.line(1, "Vlan(%d)", device.VLAN)
+	}
+
+	if agent := device.SnmpAgent; agent != nil {
+		for _, key := range droppedFields(*agent, "walk_file", "add_mibs") {
+			w.warn("device %s: snmp_agent.%s has no DSL equivalent", label, key)
+		}
+		w.line(1, "SnmpAgent(")
+		if agent.WalkFile != "" {
+			w.line(2, "Include(\"%s\")", agent.WalkFile)
+		}
+		for _, mib := range agent.AddMibs {
+			// AddMib takes three non-empty quoted strings without escapes
+			if mib.OID == "" || mib.Type == "" || mib.Value == "" ||
+				strings.Contains(mib.OID+mib.Type+mib.Value, `"`) {
+				w.warn("device %s: add_mibs entry %s cannot be written in the DSL", label, mib.OID)
+				continue
+			}
+			w.line(2, "AddMib(\"%s\", \"%s\", \"%s\")", mib.OID, mib.Type, mib.Value)
+		}
+		w.line(1, ")")
+	}
+
+	if dhcp := device.Dhcp; dhcp != nil {
+		for _, key := range droppedFields(*dhcp, "client_leases", "subnet_mask", "router", "domain_name_server", "next_server_ip", "server_identifier") {
+			w.warn("device %s: dhcp.%s has no DSL equivalent", label, key)
+		}
+		w.line(1, "Dhcp(")
+		if dhcp.SubnetMask != "" {
+			w.line(2, "SubnetMask(%s)", dhcp.SubnetMask)
+		}
+		if dhcp.Router != "" {
+			w.line(2, "Router(%s)", dhcp.Router)
+		}
+		if dhcp.DomainNameServer != "" {
+			w.line(2, "DomainNameServer(%s)", dhcp.DomainNameServer)
+		}
+		if dhcp.NextServerIP != "" {
+			w.line(2, "NextServerIpAddr(%s)", dhcp.NextServerIP)
+		}
+		if dhcp.ServerIdentifier != "" {
+			w.line(2, "ServerIdentifier(%s)", dhcp.ServerIdentifier)
+		}
+		for _, lease := range dhcp.ClientLeases {
+			// Parser ends a lease at MacAddrMask, so it is always written;
+			// an all-ones mask matches the MAC exactly, as no mask does
+			mask := lease.MacAddrMask
+			if mask == "" {
+				mask = "ffffffffffff"
+			}
+			w.line(2, "YourClientIpAddr(%s", lease.ClientIP)
+			w.line(3, "MacAddrValue(%s)", dslMAC(lease.MacAddrValue))
+			w.line(3, "MacAddrMask(%s)", dslMAC(mask))
+			w.line(2, ")")
+		}
+		w.line(1, ")")
+	}
+
+	if dns := device.Dns; dns != nil {
+		for _, key := range droppedFields(*dns, "forward_records", "reverse_records") {
+			w.warn("device %s: dns.%s has no DSL equivalent", label, key)
+		}
+		w.line(1, "Dns(")
+		for _, record := range dns.ForwardRecords {
+			w.line(2, "Forward(%s)", dnsRecordArgs(`"`+record.Name+`"`, record.IP, record.TTL))
+		}
+		for _, record := range dns.ReverseRecords {
+			w.line(2, "Reverse(%s)", dnsRecordArgs(record.IP, `"`+record.Name+`"`, record.TTL))
+		}
+		w.line(1, ")")
+	}
+
+	w.line(0, ")")
+}
+
+// dnsRecordArgs joins a Forward or Reverse record's arguments, the TTL last
+// when set. The DSL strings are quoted as is: Parser reads no escapes.
+func dnsRecordArgs(first, second string, ttl int) string {
+	if ttl == 0 {
+		return first + " " + second
+	}
+	return fmt.Sprintf("%s %s %d", first, second, ttl)
+}
+
+// dslMAC converts XX:XX:XX:XX:XX:XX back to XXXXXXXXXXXX, the inverse of
+// Parser.formatMAC
+func dslMAC(mac string) string {
+	bare := strings.NewReplacer(":", "", "-", "").Replace(mac)
+	if len(bare) != 12 {
+		return mac
+	}
+	return bare
+}
+
+// droppedFields lists the yaml keys of v's set fields that are not in kept
+func droppedFields(v interface{}, kept ...string) []string {
+	value := reflect.ValueOf(v)
+	var dropped []string
+	for i := 0; i < value.NumField(); i++ {
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		field := value.Field(i)
+		if key == "" || key == "-" || field.IsZero() {
+			continue
+		}
+		if (field.Kind() == reflect.Slice || field.Kind() == reflect.Map) && field.Len() == 0 {
+			continue
+		}
+		isKept := false
+		for _, k := range kept {
+			if k == key {
+				isKept = true
+				break
+			}
+		}
+		if !isKept {
+			dropped = append(dropped, key)
+		}
+	}
+	return dropped
+}
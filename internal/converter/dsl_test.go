@@ -0,0 +1,140 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const roundTripDSL = `// Lab network
+IncludePath("walks")
+
+CapturePlayback(
+    FileName("background.pcap")
+    LoopTime(5000)
+    ScaleTime(1.5)
+)
+
+Device(
+    MacAddr(00115A000001)
+    IpAddr(10.250.0.1)
+    Vlan(10)
+    SnmpAgent(
+        Include("router.walk")
+        AddMib("1.3.6.1.2.1.1.5.0", "OctetString", "core-router")
+    )
+    Dhcp(
+        SubnetMask(255.255.255.0)
+        Router(10.250.0.1 1)
+        DomainNameServer(10.250.0.53)
+        ServerIdentifier(10.250.0.1)
+        YourClientIpAddr(10.250.0.138
+            MacAddrValue(00115A0000AA)
+            MacAddrMask(FFFFFFFFFFFF)
+        )
+    )
+    Dns(
+        Forward("router.lab" 10.250.0.1 3600)
+        Reverse(10.250.0.1 "router.lab" 3600)
+    )
+)
+
+Device(
+    MacAddr(00115A000002)
+    IpAddr(10.250.0.2)
+)
+`
+
+func parseDSL(t *testing.T, dsl string) *Config {
+	t.Helper()
+	parser := &Parser{lines: strings.Split(dsl, "\n")}
+	config, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return config
+}
+
+// TestConvertYAMLToDSL_RoundTrip tests that DSL converted to YAML and back
+// parses to the same configuration
+func TestConvertYAMLToDSL_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "lab.cfg")
+	yamlPath := filepath.Join(dir, "lab.yaml")
+	backPath := filepath.Join(dir, "lab-back.cfg")
+	if err := os.WriteFile(cfgPath, []byte(roundTripDSL), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ConvertFile(cfgPath, yamlPath, false); err != nil {
+		t.Fatalf("ConvertFile failed: %v", err)
+	}
+	if err := ConvertYAMLToDSL(yamlPath, backPath, false); err != nil {
+		t.Fatalf("ConvertYAMLToDSL failed: %v", err)
+	}
+	back, err := os.ReadFile(backPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := parseDSL(t, roundTripDSL)
+	converted := parseDSL(t, string(back))
+	if len(converted.Devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %d:\n%s", len(converted.Devices), back)
+	}
+	if !strings.Contains(string(back), "MacAddr(00115A000001)") {
+		t.Errorf("Expected the MAC written without colons:\n%s", back)
+	}
+	if !reflect.DeepEqual(original, converted) {
+		t.Errorf("Round trip changed the config:\noriginal  %+v\nconverted %+v\n%s", original, converted, back)
+	}
+}
+
+// TestWriteDSL_Warnings tests that settings without a DSL directive are
+// reported rather than failing the conversion
+func TestWriteDSL_Warnings(t *testing.T) {
+	config, warnings, err := loadYAMLForDSL([]byte(`
+devices:
+  - name: edge-switch
+    mac: "00:11:22:33:44:55"
+    ips: ["10.0.0.2", "2001:db8::2"]
+    lldp:
+      enabled: true
+    dhcp:
+      subnet_mask: 255.255.255.0
+      pool_start: 10.0.0.100
+    colour: blue
+`))
+	if err != nil {
+		t.Fatalf("loadYAMLForDSL failed: %v", err)
+	}
+	dsl, dropped := WriteDSL(config)
+	warnings = append(warnings, dropped...)
+
+	for _, want := range []string{
+		"field colour not found",
+		"device edge-switch: name has no DSL equivalent",
+		"device edge-switch: additional address 2001:db8::2",
+		"device edge-switch: lldp has no DSL equivalent",
+		"device edge-switch: dhcp.pool_start has no DSL equivalent",
+	} {
+		found := false
+		for _, warning := range warnings {
+			found = found || strings.Contains(warning, want)
+		}
+		if !found {
+			t.Errorf("Expected a warning containing %q, got %q", want, warnings)
+		}
+	}
+	if len(warnings) != 5 {
+		t.Errorf("Expected 5 warnings, got %q", warnings)
+	}
+
+	devices := parseDSL(t, dsl).Devices
+	if len(devices) != 1 || devices[0].MAC != "00:11:22:33:44:55" || devices[0].IP != "10.0.0.2" ||
+		devices[0].Dhcp == nil || devices[0].Dhcp.SubnetMask != "255.255.255.0" {
+		t.Errorf("Unexpected devices %+v from:\n%s", devices, dsl)
+	}
+}
@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LoadTOMLConfig reads a TOML configuration file into the same structure as
+// a YAML one. Keys are the YAML keys.
+func LoadTOMLConfig(filename string, strict bool) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading TOML file: %w", err)
+	}
+	return LoadTOMLConfigFromBytes(data, strict)
+}
+
+// LoadTOMLConfigFromBytes converts in-memory TOML data into a Go config
+// structure. Unknown keys are ignored unless strict is set, in which case the
+// load fails naming them.
+func LoadTOMLConfigFromBytes(data []byte, strict bool) (*Config, error) {
+	var config Config
+	meta, err := toml.Decode(string(data), &config)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing TOML: %w", err)
+	}
+	if undecoded := meta.Undecoded(); strict && len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+		return nil, fmt.Errorf("error parsing TOML: unknown keys: %s", strings.Join(keys, ", "))
+	}
+	return &config, nil
+}
+
+// IntKeyMap is a map keyed by number, such as TCP ports or DHCP option codes.
+// TOML keys are always strings, so they are converted when decoding TOML.
+type IntKeyMap map[int]string
+
+// UnmarshalTOML implements toml.Unmarshaler
+func (m *IntKeyMap) UnmarshalTOML(data interface{}) error {
+	table, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected a table, got %T", data)
+	}
+	result := make(IntKeyMap, len(table))
+	for key, value := range table {
+		number, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("key %q is not a number", key)
+		}
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("value of key %s must be a string, got %T", key, value)
+		}
+		result[number] = text
+	}
+	*m = result
+	return nil
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+// Alert metrics. The protocol counters are the same as in /api/v1/stats.
+const (
+	AlertMetricPackets         = "packets"          // Packets sent plus received
+	AlertMetricErrors          = "errors"           // Send and processing errors
+	AlertMetricPcapDrops       = "pcap_drops"       // Packets the kernel dropped before capture
+	AlertMetricPoolUtilization = "pool_utilization" // Percentage of the DHCP pool leased
+)
+
+// alertProtocolMetrics maps protocol counter metrics to their values
+var alertProtocolMetrics = map[string]func(st *protocols.Statistics) uint64{
+	"arp_requests":            func(st *protocols.Statistics) uint64 { return st.ARPRequests },
+	"arp_replies":             func(st *protocols.Statistics) uint64 { return st.ARPReplies },
+	"icmp_requests":           func(st *protocols.Statistics) uint64 { return st.ICMPRequests },
+	"icmp_replies":            func(st *protocols.Statistics) uint64 { return st.ICMPReplies },
+	"dns_queries":             func(st *protocols.Statistics) uint64 { return st.DNSQueries },
+	"dhcp_requests":           func(st *protocols.Statistics) uint64 { return st.DHCPRequests },
+	"dhcp_retransmits":        func(st *protocols.Statistics) uint64 { return st.DHCPRetransmits },
+	"snmp_queries":            func(st *protocols.Statistics) uint64 { return st.SNMPQueries },
+	"snmp_denied":             func(st *protocols.Statistics) uint64 { return st.SNMPDenied },
+	"tcp_connections_refused": func(st *protocols.Statistics) uint64 { return st.TCPConnectionsRefused },
+	"malformed_frames_sent":   func(st *protocols.Statistics) uint64 { return st.MalformedFramesSent },
+}
+
+// alertComparisons are the comparisons a rule can make against its threshold
+var alertComparisons = map[string]func(value, threshold float64) bool{
+	">=": func(value, threshold float64) bool { return value >= threshold },
+	">":  func(value, threshold float64) bool { return value > threshold },
+	"<=": func(value, threshold float64) bool { return value <= threshold },
+	"<":  func(value, threshold float64) bool { return value < threshold },
+}
+
+// AlertConfig controls threshold-based alerting. Every rule is evaluated on
+// its own and posts to its own webhook. PacketsThreshold and WebhookURL are
+// the original single rule; a non-zero PacketsThreshold still works as a
+// packets rule, and WebhookURL is the webhook of rules without one.
+type AlertConfig struct {
+	PacketsThreshold uint64      `json:"packets_threshold"`
+	WebhookURL       string      `json:"webhook_url"`
+	Rules            []AlertRule `json:"rules,omitempty"`
+}
+
+// AlertRule fires when a metric compares true against a threshold.
+type AlertRule struct {
+	Name       string  `json:"name,omitempty"`       // Sent as the webhook "type" (default "<metric>_threshold")
+	Metric     string  `json:"metric"`               // An AlertMetric* value or a protocol counter
+	Comparison string  `json:"comparison,omitempty"` // ">=" (default), ">", "<=" or "<"
+	Threshold  float64 `json:"threshold"`
+	WebhookURL string  `json:"webhook_url,omitempty"` // Default: the config's webhook_url
+}
+
+// Validate checks every rule's metric and comparison.
+func (c AlertConfig) Validate() error {
+	for i, rule := range c.Rules {
+		if !validAlertMetric(rule.Metric) {
+			return fmt.Errorf("rules[%d]: unknown metric %q", i, rule.Metric)
+		}
+		if _, ok := alertComparisons[rule.Comparison]; rule.Comparison != "" && !ok {
+			return fmt.Errorf("rules[%d]: comparison must be >=, >, <= or <: %q", i, rule.Comparison)
+		}
+	}
+	return nil
+}
+
+// EffectiveRules returns the rules to evaluate with defaults filled in,
+// including the packets rule migrated from PacketsThreshold.
+func (c AlertConfig) EffectiveRules() []AlertRule {
+	rules := make([]AlertRule, 0, len(c.Rules)+1)
+	if c.PacketsThreshold > 0 {
+		rules = append(rules, AlertRule{
+			Name:      "packet_threshold",
+			Metric:    AlertMetricPackets,
+			Threshold: float64(c.PacketsThreshold),
+		})
+	}
+	rules = append(rules, c.Rules...)
+	for i := range rules {
+		if rules[i].Name == "" {
+			rules[i].Name = rules[i].Metric + "_threshold"
+		}
+		if rules[i].Comparison == "" {
+			rules[i].Comparison = ">="
+		}
+		if rules[i].WebhookURL == "" {
+			rules[i].WebhookURL = c.WebhookURL
+		}
+	}
+	return rules
+}
+
+// hasWebhook reports whether any alert is delivered to a webhook
+func (c AlertConfig) hasWebhook() bool {
+	if c.WebhookURL != "" {
+		return true
+	}
+	for _, rule := range c.Rules {
+		if rule.WebhookURL != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func validAlertMetric(metric string) bool {
+	switch metric {
+	case AlertMetricPackets, AlertMetricErrors, AlertMetricPcapDrops, AlertMetricPoolUtilization:
+		return true
+	}
+	_, ok := alertProtocolMetrics[metric]
+	return ok
+}
+
+func (s *Server) alertLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evaluateAlerts()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// evaluateAlerts checks every rule and sends an alert for each one whose
+// condition holds, unless it already fired at the same value.
+func (s *Server) evaluateAlerts() {
+	rules := s.getAlertConfig().EffectiveRules()
+	if len(rules) == 0 {
+		return
+	}
+
+	for i, rule := range rules {
+		value, ok := s.alertMetricValue(rule.Metric)
+		if !ok || !alertComparisons[rule.Comparison](value, rule.Threshold) {
+			continue
+		}
+		s.alertMu.Lock()
+		if last, fired := s.lastAlerts[i]; !fired || last != value {
+			if s.lastAlerts == nil {
+				s.lastAlerts = make(map[int]float64)
+			}
+			s.lastAlerts[i] = value
+			go s.sendAlert(rule, value)
+		}
+		s.alertMu.Unlock()
+	}
+}
+
+// alertMetricValue returns a metric's current value, or false when it is
+// unavailable (no simulation running, or no capture for pcap_drops).
+func (s *Server) alertMetricValue(metric string) (float64, bool) {
+	s.configMu.RLock()
+	stack := s.cfg.Stack
+	capture := s.cfg.Capture
+	s.configMu.RUnlock()
+
+	switch metric {
+	case AlertMetricPcapDrops:
+		if capture == nil {
+			return 0, false
+		}
+		stats, err := capture.Stats()
+		if err != nil {
+			return 0, false
+		}
+		return float64(stats.PacketsDropped), true
+	case AlertMetricPoolUtilization:
+		if stack == nil || stack.GetDHCPHandler() == nil {
+			return 0, false
+		}
+		return stack.GetDHCPHandler().PoolUtilization(), true
+	}
+
+	if stack == nil {
+		return 0, false
+	}
+	stats := stack.GetStats()
+	switch metric {
+	case AlertMetricPackets:
+		return float64(stats.PacketsSent + stats.PacketsReceived), true
+	case AlertMetricErrors:
+		return float64(stats.Errors), true
+	}
+	if counter, ok := alertProtocolMetrics[metric]; ok {
+		return float64(counter(&stats)), true
+	}
+	return 0, false
+}
+
+func (s *Server) sendAlert(rule AlertRule, value float64) {
+	log.Printf("alert: %s: %s %s %g (value=%g)", rule.Name, rule.Metric, rule.Comparison, rule.Threshold, value)
+	if rule.WebhookURL == "" {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"type":        rule.Name,
+		"metric":      rule.Metric,
+		"comparison":  rule.Comparison,
+		"threshold":   rule.Threshold,
+		"value":       value,
+		"interface":   s.cfg.Interface,
+		"triggeredAt": time.Now().UTC(),
+	}
+	if rule.Metric == AlertMetricPackets {
+		payload["total"] = uint64(value) // Field of the original packet_threshold alert
+	}
+	body, _ := json.Marshal(payload)
+
+	req, err := http.NewRequest(http.MethodPost, rule.WebhookURL, strings.NewReader(string(body)))
+	if err != nil {
+		log.Printf("alert webhook error: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		s.webhookFailures.Add(1)
+		log.Printf("alert webhook request failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		s.webhookFailures.Add(1)
+		log.Printf("alert webhook returned %s", resp.Status)
+		return
+	}
+	s.webhookFailures.Store(0)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+// ARPEntry is an IP-to-MAC binding in the simulator's ARP/ND table.
+type ARPEntry struct {
+	IP       string    `json:"ip"`
+	MAC      string    `json:"mac"`
+	Type     string    `json:"type"`             // "dynamic" (learned) or "static" (a simulated device's address)
+	Device   string    `json:"device,omitempty"` // Owner of a static entry
+	LastSeen time.Time `json:"last_seen,omitempty"`
+}
+
+// handleARP serves GET /api/v1/arp: the learned ARP/ND neighbors and the
+// simulated devices' own addresses. With ?device=name it returns that
+// device's view, the same rows its SNMP agent serves in ipNetToMediaTable
+// and ipNetToPhysicalTable.
+func (s *Server) handleARP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stack := s.currentStack()
+	if stack == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+
+	var device *config.Device
+	if name := r.URL.Query().Get("device"); name != "" {
+		if cfg := s.currentConfig(); cfg != nil {
+			for i := range cfg.Devices {
+				if cfg.Devices[i].Name == name {
+					device = &cfg.Devices[i]
+					break
+				}
+			}
+		}
+		if device == nil {
+			http.Error(w, "device not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	entries := stack.ARPEntries(device)
+	resp := make([]ARPEntry, 0, len(entries))
+	for _, entry := range entries {
+		resp = append(resp, newARPEntry(entry))
+	}
+	s.writeJSON(w, resp)
+}
+
+func newARPEntry(entry protocols.ARPEntry) ARPEntry {
+	return ARPEntry{
+		IP:       entry.IP.String(),
+		MAC:      entry.MAC.String(),
+		Type:     entry.Type,
+		Device:   entry.Device,
+		LastSeen: entry.LastSeen,
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// DebugLevels is the running stack's debug configuration. Protocols holds the
+// effective level of every protocol; Overrides only those set explicitly,
+// the rest follow Global.
+type DebugLevels struct {
+	Global    int            `json:"global"`
+	Protocols map[string]int `json:"protocols"`
+	Overrides map[string]int `json:"overrides"`
+}
+
+// DebugLevelsUpdate changes debug levels. Omitted fields are left unchanged.
+type DebugLevelsUpdate struct {
+	Global    *int           `json:"global,omitempty"`
+	Protocols map[string]int `json:"protocols,omitempty"`
+}
+
+// handleDebug serves GET /api/v1/debug and PUT /api/v1/debug, which changes
+// debug levels live without restarting the simulation.
+func (s *Server) handleDebug(w http.ResponseWriter, r *http.Request) {
+	stack := s.currentStack()
+	if stack == nil || stack.GetDebugConfig() == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+	debugConfig := stack.GetDebugConfig()
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, debugLevels(debugConfig))
+	case http.MethodPut:
+		var req DebugLevelsUpdate
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+		protocols, err := validateDebugUpdate(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Global != nil {
+			debugConfig.SetGlobal(*req.Global)
+		}
+		for protocol, level := range protocols {
+			debugConfig.SetProtocolLevel(protocol, level)
+		}
+		s.writeJSON(w, debugLevels(debugConfig))
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// debugLevels snapshots debugConfig for the API.
+func debugLevels(debugConfig *logging.DebugConfig) DebugLevels {
+	levels := DebugLevels{
+		Global:    debugConfig.GetGlobal(),
+		Protocols: make(map[string]int, len(logging.Protocols)),
+		Overrides: debugConfig.GetAllLevels(),
+	}
+	for _, protocol := range logging.Protocols {
+		levels.Protocols[protocol] = debugConfig.GetProtocolLevel(protocol)
+	}
+	return levels
+}
+
+// validateDebugUpdate checks every level is in range and resolves protocol
+// names case-insensitively, so nothing is applied unless the whole update is
+// valid.
+func validateDebugUpdate(req DebugLevelsUpdate) (map[string]int, error) {
+	validLevel := func(level int) bool {
+		return level >= logging.MinDebugLevel && level <= logging.MaxDebugLevel
+	}
+	if req.Global != nil && !validLevel(*req.Global) {
+		return nil, fmt.Errorf("global debug level %d out of range (%d-%d)",
+			*req.Global, logging.MinDebugLevel, logging.MaxDebugLevel)
+	}
+
+	protocols := make(map[string]int, len(req.Protocols))
+	for name, level := range req.Protocols {
+		protocol := ""
+		for _, known := range logging.Protocols {
+			if strings.EqualFold(name, known) {
+				protocol = known
+				break
+			}
+		}
+		if protocol == "" {
+			return nil, fmt.Errorf("unknown protocol %q", name)
+		}
+		if !validLevel(level) {
+			return nil, fmt.Errorf("%s debug level %d out of range (%d-%d)",
+				protocol, level, logging.MinDebugLevel, logging.MaxDebugLevel)
+		}
+		protocols[protocol] = level
+	}
+	return protocols, nil
+}
@@ -0,0 +1,418 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+// BulkPowerRequest powers every device carrying Tag on or off.
+type BulkPowerRequest struct {
+	Tag   string `json:"tag"`
+	State string `json:"state"` // "on" or "off"
+}
+
+// BulkErrorRequest injects the same error on every device carrying Tag.
+type BulkErrorRequest struct {
+	Tag       string `json:"tag"`
+	Interface string `json:"interface"`
+	ErrorType string `json:"error_type"`
+	Value     int    `json:"value"`
+}
+
+// deviceSummary builds the JSON representation of a device shared by the list
+// and detail endpoints.
+func deviceSummary(dev *config.Device, stack *protocols.Stack) map[string]interface{} {
+	ips := make([]string, 0, len(dev.IPAddresses))
+	for _, ip := range dev.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	protos := make([]string, 0, 8)
+	if dev.SNMPConfig.Community != "" || dev.SNMPConfig.WalkFile != "" {
+		protos = append(protos, "SNMP")
+	}
+	if dev.DHCPConfig != nil {
+		protos = append(protos, "DHCP")
+	}
+	if dev.DNSConfig != nil {
+		protos = append(protos, "DNS")
+	}
+	if dev.HTTPConfig != nil {
+		protos = append(protos, "HTTP")
+	}
+	if dev.FTPConfig != nil {
+		protos = append(protos, "FTP")
+	}
+	if dev.LLDPConfig != nil && dev.LLDPConfig.Enabled {
+		protos = append(protos, "LLDP")
+	}
+	if dev.CDPConfig != nil && dev.CDPConfig.Enabled {
+		protos = append(protos, "CDP")
+	}
+
+	tags := dev.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	powered, enabled := true, true
+	if stack != nil {
+		powered = stack.IsDevicePowered(dev.Name)
+		enabled = stack.IsDeviceEnabled(dev.Name)
+	}
+
+	return map[string]interface{}{
+		"name":      dev.Name,
+		"type":      dev.Type,
+		"ips":       ips,
+		"protocols": protos,
+		"tags":      tags,
+		"powered":   powered,
+		"enabled":   enabled,
+	}
+}
+
+func (s *Server) currentStack() *protocols.Stack {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.cfg.Stack
+}
+
+// handleDevice serves GET /api/v1/devices/{name},
+// POST /api/v1/devices/{name}/reboot, POST /api/v1/devices/{name}/inject and
+// POST /api/v1/devices/{name}/state.
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/devices/"), "/")
+	name, action, _ := strings.Cut(rest, "/")
+	if name == "" {
+		http.Error(w, "device name is required", http.StatusBadRequest)
+		return
+	}
+
+	switch action {
+	case "":
+	case "reboot":
+		s.handleDeviceReboot(w, r, name)
+		return
+	case "inject":
+		s.handleDeviceInject(w, r, name)
+		return
+	case "state":
+		s.handleDeviceState(w, r, name)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := s.currentConfig()
+	if cfg == nil {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+	for i := range cfg.Devices {
+		dev := &cfg.Devices[i]
+		if dev.Name != name {
+			continue
+		}
+		stack := s.currentStack()
+		detail := deviceSummary(dev, stack)
+		if len(dev.MACAddress) > 0 {
+			detail["mac"] = dev.MACAddress.String()
+		}
+		if stack != nil {
+			if utilization, ok := stack.GetFDBUtilization(dev.Name); ok {
+				detail["mac_table"] = utilization
+			}
+			if boot, ok := stack.BootStatus(dev.Name); ok {
+				detail["boot_stage"] = boot.Stage
+				detail["boot_history"] = boot.History
+			}
+		}
+		detail["properties"] = dev.Properties
+		s.writeJSON(w, detail)
+		return
+	}
+	http.Error(w, "device not found", http.StatusNotFound)
+}
+
+// handleDeviceReboot simulates a reboot of a device's SNMP agent.
+func (s *Server) handleDeviceReboot(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stack := s.currentStack()
+	cfg := s.currentConfig()
+	if stack == nil || cfg == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+	if !cfg.HasDevice(name) {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+
+	if err := stack.RebootDevice(name); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	s.writeJSON(w, map[string]interface{}{
+		"success": true,
+		"device":  name,
+		"message": "device rebooted",
+	})
+}
+
+// DeviceStateRequest enables or disables a device.
+type DeviceStateRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// handleDeviceState enables or disables a device. A disabled device stops
+// answering ARP, ICMP, SNMP and the other protocols until it is enabled again.
+func (s *Server) handleDeviceState(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// SECURITY FIX #111: Enforce request body size limit
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+
+	var req DeviceStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Enabled == nil {
+		http.Error(w, "enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	stack := s.currentStack()
+	cfg := s.currentConfig()
+	if stack == nil || cfg == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+	if !cfg.HasDevice(name) {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+
+	if err := stack.SetDeviceEnabled(name, *req.Enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	s.writeJSON(w, map[string]interface{}{
+		"success": true,
+		"device":  name,
+		"enabled": *req.Enabled,
+	})
+}
+
+// InjectRequest carries a raw Ethernet frame for a device to transmit.
+type InjectRequest struct {
+	Frame string `json:"frame"` // Base64-encoded frame, without the FCS
+}
+
+// handleDeviceInject transmits a hand-crafted frame from a device as-is.
+// Every injection is logged as an audit event.
+func (s *Server) handleDeviceInject(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// SECURITY FIX #111: Enforce request body size limit
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+
+	var req InjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	frame, err := base64.StdEncoding.DecodeString(req.Frame)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("frame is not valid base64: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(frame) < protocols.MinInjectFrameSize || len(frame) > protocols.MaxInjectFrameSize {
+		http.Error(w, fmt.Sprintf("frame must be between %d and %d bytes, got %d",
+			protocols.MinInjectFrameSize, protocols.MaxInjectFrameSize, len(frame)), http.StatusBadRequest)
+		return
+	}
+
+	stack := s.currentStack()
+	cfg := s.currentConfig()
+	if stack == nil || cfg == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+	if !cfg.HasDevice(name) {
+		http.Error(w, "device not found", http.StatusNotFound)
+		return
+	}
+
+	if err := stack.InjectFrame(name, frame); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("[API] [%s] AUDIT frame injected device=%s bytes=%d dst=%s ethertype=0x%04x principal=%s remote=%s",
+		r.Header.Get("X-Request-ID"), name, len(frame), net.HardwareAddr(frame[0:6]),
+		binary.BigEndian.Uint16(frame[12:14]), principalOf(w), getClientIP(r))
+
+	s.writeJSON(w, map[string]interface{}{
+		"success": true,
+		"device":  name,
+		"bytes":   len(frame),
+	})
+}
+
+// handleBulkPower serves POST /api/v1/bulk/power.
+func (s *Server) handleBulkPower(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// SECURITY FIX #111: Enforce request body size limit
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+
+	var req BulkPowerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Tag == "" {
+		http.Error(w, "tag is required", http.StatusBadRequest)
+		return
+	}
+	if req.State != "on" && req.State != "off" {
+		http.Error(w, `state must be "on" or "off"`, http.StatusBadRequest)
+		return
+	}
+
+	stack := s.currentStack()
+	cfg := s.currentConfig()
+	if stack == nil || cfg == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+
+	devices := cfg.DevicesWithTag(req.Tag)
+	if len(devices) == 0 {
+		http.Error(w, fmt.Sprintf("no devices tagged %q", req.Tag), http.StatusNotFound)
+		return
+	}
+
+	names := make([]string, 0, len(devices))
+	for _, dev := range devices {
+		if err := stack.SetDevicePower(dev.Name, req.State == "on"); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		names = append(names, dev.Name)
+	}
+
+	s.writeJSON(w, map[string]interface{}{
+		"success": true,
+		"tag":     req.Tag,
+		"state":   req.State,
+		"devices": names,
+	})
+}
+
+// handleBulkErrors serves POST /api/v1/bulk/errors.
+func (s *Server) handleBulkErrors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// SECURITY FIX #111: Enforce request body size limit
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+
+	var req BulkErrorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Tag == "" {
+		http.Error(w, "tag is required", http.StatusBadRequest)
+		return
+	}
+	if req.Interface == "" {
+		http.Error(w, "interface is required", http.StatusBadRequest)
+		return
+	}
+	if req.ErrorType == "" {
+		http.Error(w, "error_type is required", http.StatusBadRequest)
+		return
+	}
+	if req.Value < 0 || req.Value > 100 {
+		http.Error(w, "value must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+
+	stack := s.currentStack()
+	cfg := s.currentConfig()
+	if stack == nil || cfg == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+	errorMgr := stack.GetErrorManager()
+	if errorMgr == nil {
+		http.Error(w, "error manager not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	devices := cfg.DevicesWithTag(req.Tag)
+	if len(devices) == 0 {
+		http.Error(w, fmt.Sprintf("no devices tagged %q", req.Tag), http.StatusNotFound)
+		return
+	}
+
+	names := make([]string, 0, len(devices))
+	for _, dev := range devices {
+		if len(dev.IPAddresses) == 0 {
+			continue
+		}
+		errorMgr.SetError(dev.IPAddresses[0].String(), req.Interface, errors.ErrorType(req.ErrorType), req.Value)
+		names = append(names, dev.Name)
+	}
+
+	s.writeJSON(w, map[string]interface{}{
+		"success":    true,
+		"tag":        req.Tag,
+		"interface":  req.Interface,
+		"error_type": req.ErrorType,
+		"value":      req.Value,
+		"devices":    names,
+	})
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+// ErrorPacketInfo is a packet that failed to parse or made a handler fail
+type ErrorPacketInfo struct {
+	Time         time.Time `json:"time"`
+	Protocol     string    `json:"protocol"`
+	Error        string    `json:"error"`
+	Interface    string    `json:"interface,omitempty"`
+	SerialNumber int       `json:"serial_number"`
+	Length       int       `json:"length"`    // Original length in bytes
+	Truncated    bool      `json:"truncated"` // Data holds only the first snap_length bytes
+	Data         string    `json:"data"`      // Base64 packet bytes
+	Summary      string    `json:"summary"`   // Layers and addresses as far as they decode
+}
+
+// ErrorPacketsResponse lists the most recent error packets, oldest first
+type ErrorPacketsResponse struct {
+	Capacity   int               `json:"capacity"`
+	MaxBytes   int               `json:"max_bytes"`
+	SnapLength int               `json:"snap_length"`
+	Packets    []ErrorPacketInfo `json:"packets"`
+}
+
+// handleErrorPackets lists the packets kept because they failed to parse or
+// made a handler fail (GET), or empties the buffer (DELETE).
+func (s *Server) handleErrorPackets(w http.ResponseWriter, r *http.Request) {
+	stack := s.currentStack()
+	if stack == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		packets := stack.ErrorPackets()
+		response := ErrorPacketsResponse{
+			Capacity:   protocols.ErrorPacketCapacity,
+			MaxBytes:   protocols.ErrorPacketMaxBytes,
+			SnapLength: protocols.ErrorPacketSnapLen,
+			Packets:    make([]ErrorPacketInfo, 0, len(packets)),
+		}
+		for _, packet := range packets {
+			response.Packets = append(response.Packets, ErrorPacketInfo{
+				Time:         packet.Time.UTC(),
+				Protocol:     packet.Protocol,
+				Error:        packet.Error,
+				Interface:    packet.Interface,
+				SerialNumber: packet.SerialNumber,
+				Length:       packet.Length,
+				Truncated:    packet.Truncated(),
+				Data:         base64.StdEncoding.EncodeToString(packet.Data),
+				Summary:      packet.Summary(),
+			})
+		}
+		s.writeJSON(w, response)
+	case http.MethodDelete:
+		stack.ClearErrorPackets()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
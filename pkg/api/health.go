@@ -0,0 +1,196 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/gopacket/pcap"
+)
+
+// HealthStatus is the outcome of a health check, ordered from best to worst.
+type HealthStatus string
+
+const (
+	HealthOK       HealthStatus = "ok"
+	HealthDegraded HealthStatus = "degraded"
+	HealthCritical HealthStatus = "critical"
+)
+
+// Health check thresholds
+const (
+	healthDropRateDegraded = 0.01 // 1% of captured packets dropped by the kernel
+	healthDropRateCritical = 0.10
+	healthQueueDegraded    = 0.5 // Send queue half full
+	healthQueueCritical    = 0.9
+)
+
+// Health check names
+const (
+	healthCheckCapture      = "capture"
+	healthCheckPcapDrops    = "pcap_drops"
+	healthCheckSendQueue    = "send_queue"
+	healthCheckAlertWebhook = "alert_webhook"
+	healthCheckPoweredOff   = "powered_off_devices"
+)
+
+// CaptureMonitor reports packet capture counters; *capture.Engine satisfies it.
+type CaptureMonitor interface {
+	Stats() (*pcap.Stats, error)
+}
+
+// HealthCheck is the result of one health probe.
+type HealthCheck struct {
+	Name   string       `json:"name"`
+	Status HealthStatus `json:"status"`
+	Detail string       `json:"detail"`
+}
+
+// HealthReport aggregates every check; Status is the worst individual status.
+type HealthReport struct {
+	Status    HealthStatus  `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+func healthRank(status HealthStatus) int {
+	switch status {
+	case HealthCritical:
+		return 2
+	case HealthDegraded:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// handleHealth serves GET /api/v1/health. A critical report is returned with
+// 503 so simple probes can alert on the status code alone.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := s.healthReport()
+	if report.Status == HealthCritical {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	s.writeJSON(w, report)
+}
+
+// healthReport runs every health check against the current simulation.
+func (s *Server) healthReport() HealthReport {
+	s.configMu.RLock()
+	stack := s.cfg.Stack
+	deviceCount := 0
+	if s.cfg.Config != nil {
+		deviceCount = len(s.cfg.Config.Devices)
+	}
+	s.configMu.RUnlock()
+
+	checks := s.captureChecks()
+
+	if stack != nil {
+		depth, capacity := stack.SendQueueDepth()
+		checks = append(checks, sendQueueCheck(depth, capacity))
+		checks = append(checks, poweredOffCheck(stack.PoweredOffCount(), deviceCount))
+	}
+
+	checks = append(checks, s.webhookCheck())
+
+	report := HealthReport{
+		Status:    HealthOK,
+		CheckedAt: time.Now().UTC(),
+		Checks:    checks,
+	}
+	for _, check := range checks {
+		if healthRank(check.Status) > healthRank(report.Status) {
+			report.Status = check.Status
+		}
+	}
+	return report
+}
+
+// captureChecks reports whether the capture handle is usable and how many
+// packets the kernel dropped before NIAC could read them.
+func (s *Server) captureChecks() []HealthCheck {
+	if s.cfg.Capture == nil {
+		return []HealthCheck{{Name: healthCheckCapture, Status: HealthOK, Detail: "no capture engine attached"}}
+	}
+
+	stats, err := s.cfg.Capture.Stats()
+	if err != nil {
+		return []HealthCheck{{Name: healthCheckCapture, Status: HealthCritical, Detail: fmt.Sprintf("capture unavailable: %v", err)}}
+	}
+
+	capture := HealthCheck{
+		Name:   healthCheckCapture,
+		Status: HealthOK,
+		Detail: fmt.Sprintf("%d packets received", stats.PacketsReceived),
+	}
+
+	dropped := stats.PacketsDropped + stats.PacketsIfDropped
+	drops := HealthCheck{Name: healthCheckPcapDrops, Status: HealthOK, Detail: "no packets dropped"}
+	if total := stats.PacketsReceived + dropped; dropped > 0 && total > 0 {
+		rate := float64(dropped) / float64(total)
+		drops.Detail = fmt.Sprintf("%d of %d packets dropped (%.1f%%)", dropped, total, rate*100)
+		switch {
+		case rate >= healthDropRateCritical:
+			drops.Status = HealthCritical
+		case rate >= healthDropRateDegraded:
+			drops.Status = HealthDegraded
+		}
+	}
+	return []HealthCheck{capture, drops}
+}
+
+func sendQueueCheck(depth, capacity int) HealthCheck {
+	check := HealthCheck{
+		Name:   healthCheckSendQueue,
+		Status: HealthOK,
+		Detail: fmt.Sprintf("%d of %d slots used", depth, capacity),
+	}
+	if capacity <= 0 {
+		return check
+	}
+	switch fill := float64(depth) / float64(capacity); {
+	case fill >= healthQueueCritical:
+		check.Status = HealthCritical
+	case fill >= healthQueueDegraded:
+		check.Status = HealthDegraded
+	}
+	return check
+}
+
+func poweredOffCheck(off, total int) HealthCheck {
+	check := HealthCheck{
+		Name:   healthCheckPoweredOff,
+		Status: HealthOK,
+		Detail: fmt.Sprintf("%d of %d devices powered off", off, total),
+	}
+	switch {
+	case off > 0 && off >= total:
+		check.Status = HealthCritical
+	case off > 0:
+		check.Status = HealthDegraded
+	}
+	return check
+}
+
+// webhookCheck reports alert webhook delivery. Failures degrade health since
+// alerts are silently going nowhere, but the simulation itself is unaffected.
+func (s *Server) webhookCheck() HealthCheck {
+	check := HealthCheck{Name: healthCheckAlertWebhook, Status: HealthOK, Detail: "delivering"}
+	if !s.getAlertConfig().hasWebhook() {
+		check.Detail = "not configured"
+		return check
+	}
+	if failures := s.webhookFailures.Load(); failures > 0 {
+		check.Status = HealthDegraded
+		check.Detail = fmt.Sprintf("%d consecutive deliveries failed", failures)
+	}
+	return check
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/gopacket/pcap"
+)
+
+type stubCapture struct {
+	stats pcap.Stats
+	err   error
+}
+
+func (c *stubCapture) Stats() (*pcap.Stats, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	stats := c.stats
+	return &stats, nil
+}
+
+func getHealth(t *testing.T, server *Server) (int, HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	server.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode health: %v (%s)", err, rec.Body.String())
+	}
+	return rec.Code, report
+}
+
+func findCheck(report HealthReport, name string) HealthCheck {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	return HealthCheck{}
+}
+
+func TestServerHandleHealthPcapDrops(t *testing.T) {
+	server, _ := newTestServer(t)
+	capture := &stubCapture{stats: pcap.Stats{PacketsReceived: 1000}}
+	server.cfg.Capture = capture
+
+	code, report := getHealth(t, server)
+	if code != http.StatusOK || report.Status != HealthOK {
+		t.Fatalf("expected ok with no drops, got %d %s: %+v", code, report.Status, report.Checks)
+	}
+
+	// 50 of 1050 packets dropped (~4.8%) crosses the degraded threshold only
+	capture.stats.PacketsDropped = 50
+	code, report = getHealth(t, server)
+	if report.Status != HealthDegraded {
+		t.Fatalf("expected degraded when pcap drops exceed threshold, got %s: %+v", report.Status, report.Checks)
+	}
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for degraded health, got %d", code)
+	}
+	if check := findCheck(report, healthCheckPcapDrops); check.Status != HealthDegraded {
+		t.Fatalf("expected pcap_drops check degraded, got %+v", check)
+	}
+
+	capture.stats.PacketsDropped = 500
+	code, report = getHealth(t, server)
+	if report.Status != HealthCritical || code != http.StatusServiceUnavailable {
+		t.Fatalf("expected critical/503 for heavy drops, got %s/%d", report.Status, code)
+	}
+}
+
+func TestServerHandleHealthChecks(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.cfg.Capture = &stubCapture{err: errors.New("handle closed")}
+
+	_, report := getHealth(t, server)
+	if check := findCheck(report, healthCheckCapture); check.Status != HealthCritical {
+		t.Fatalf("expected capture check critical when stats fail, got %+v", check)
+	}
+
+	server.cfg.Capture = nil
+	deviceName := server.cfg.Config.Devices[0].Name
+	if err := server.cfg.Stack.SetDevicePower(deviceName, false); err != nil {
+		t.Fatalf("power off: %v", err)
+	}
+	// The test config has a single device, so the whole simulation is down
+	code, report := getHealth(t, server)
+	if check := findCheck(report, healthCheckPoweredOff); check.Status != HealthCritical {
+		t.Fatalf("expected powered_off_devices critical with every device off, got %+v", check)
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for critical health, got %d", code)
+	}
+	if err := server.cfg.Stack.SetDevicePower(deviceName, true); err != nil {
+		t.Fatalf("power on: %v", err)
+	}
+
+	server.cfg.Alert.WebhookURL = "http://127.0.0.1:1/alerts"
+	server.webhookFailures.Store(2)
+	_, report = getHealth(t, server)
+	if check := findCheck(report, healthCheckAlertWebhook); check.Status != HealthDegraded {
+		t.Fatalf("expected alert_webhook degraded after failures, got %+v", check)
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleHealth(rec, httptest.NewRequest(http.MethodPost, "/api/v1/health", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+// ReconfigureRequest selects the exchange a DHCPv6 Reconfigure asks the
+// client to start.
+type ReconfigureRequest struct {
+	MessageType string `json:"message_type"` // "renew" (default), "rebind" or "information-request"
+}
+
+// reconfigureMessageTypes maps ReconfigureRequest.MessageType to DHCPv6
+// message types.
+var reconfigureMessageTypes = map[string]uint8{
+	"renew":               protocols.DHCPv6Renew,
+	"rebind":              protocols.DHCPv6Rebind,
+	"information-request": protocols.DHCPv6InfoRequest,
+}
+
+// handleLease serves POST /api/v1/leases/{duid}/reconfigure. The DUID is
+// hex, with or without colons.
+func (s *Server) handleLease(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/leases/"), "/")
+	duidText, action, _ := strings.Cut(rest, "/")
+	if action != "reconfigure" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	duid, err := hex.DecodeString(strings.ReplaceAll(duidText, ":", ""))
+	if err != nil || len(duid) == 0 {
+		http.Error(w, fmt.Sprintf("invalid DUID %q", duidText), http.StatusBadRequest)
+		return
+	}
+
+	// SECURITY FIX #111: Enforce request body size limit
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
+
+	req := ReconfigureRequest{MessageType: "renew"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	msgType, ok := reconfigureMessageTypes[req.MessageType]
+	if !ok {
+		http.Error(w, `message_type must be "renew", "rebind" or "information-request"`, http.StatusBadRequest)
+		return
+	}
+
+	stack := s.currentStack()
+	if stack == nil {
+		http.Error(w, "no simulation running", http.StatusServiceUnavailable)
+		return
+	}
+	handler := stack.GetDHCPv6Handler()
+	if handler == nil || !handler.HasLease(duid) {
+		http.Error(w, "lease not found", http.StatusNotFound)
+		return
+	}
+
+	if err := handler.SendReconfigure(duid, msgType); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	s.writeJSON(w, map[string]interface{}{
+		"success":      true,
+		"duid":         hex.EncodeToString(duid),
+		"message_type": req.MessageType,
+		"message":      "reconfigure sent",
+	})
+}
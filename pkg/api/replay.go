@@ -0,0 +1,18 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrReplayDeviceBusy is returned by ReplayManager.Start when another replay
+// is already playing, or queued to play, as the requested device.
+var ErrReplayDeviceBusy = errors.New("device is used by another replay")
+
+// replayStartStatus returns the HTTP status for a failed replay start.
+func replayStartStatus(err error) int {
+	if errors.Is(err, ErrReplayDeviceBusy) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -18,8 +18,10 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -28,6 +30,7 @@ import (
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/errors"
 	"github.com/krisarmstrong/niac-go/pkg/protocols"
+	"github.com/krisarmstrong/niac-go/pkg/stats"
 	"github.com/krisarmstrong/niac-go/pkg/storage"
 )
 
@@ -38,7 +41,7 @@ const (
 	// SECURITY: This prevents memory exhaustion attacks via large uploads
 	MaxPCAPUploadSize = 100 << 20 // 100MB
 
-	// FEATURE #104: Rate limiting defaults
+	// FEATURE #104: Rate limiting defaults (--api-rate, --api-burst)
 	// Allow 100 requests per second per IP with burst of 200
 	DefaultRateLimit = 100
 	DefaultBurst     = 200
@@ -184,16 +187,67 @@ func isTrustedProxy(ip string) bool {
 	return false
 }
 
-// logRequest logs HTTP requests for debugging
-func logRequest(r *http.Request) {
-	requestID := r.Header.Get("X-Request-ID")
-	if requestID != "" {
-		log.Printf("[API] [%s] %s %s from %s", requestID, r.Method, r.URL.Path, r.RemoteAddr)
-	} else {
-		log.Printf("[API] %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+// accessLogRecorder captures the status code and response size for access logging.
+type accessLogRecorder struct {
+	http.ResponseWriter
+	status    int
+	bytes     int
+	principal string
+}
+
+func (rec *accessLogRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *accessLogRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Flush supports streaming handlers wrapped by the access log.
+func (rec *accessLogRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// setPrincipal records the authenticated principal for the access log, if the
+// writer is wrapped by accessLog.
+func setPrincipal(w http.ResponseWriter, principal string) {
+	if rec, ok := w.(*accessLogRecorder); ok {
+		rec.principal = principal
 	}
 }
 
+// accessLog wraps a handler and logs method, path, status, bytes written,
+// latency and principal for every request.
+// FEATURE #118: The request ID assigned here is reused by auth for tracing.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := generateRequestID()
+		w.Header().Set("X-Request-ID", requestID)
+
+		rec := &accessLogRecorder{ResponseWriter: w, principal: "-"}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log.Printf("[API] [%s] %s %s status=%d bytes=%d duration=%s principal=%s remote=%s",
+			requestID, r.Method, r.URL.Path, status, rec.bytes, duration, rec.principal, getClientIP(r))
+	})
+}
+
 // generateRequestID creates a unique request ID for tracing
 // FEATURE #118: Request tracing for debugging and monitoring
 func generateRequestID() string {
@@ -315,28 +369,81 @@ func writeError(w http.ResponseWriter, r *http.Request, status int, errorCode, m
 	json.NewEncoder(w).Encode(response)
 }
 
-// AlertConfig controls basic threshold-based alerting.
-type AlertConfig struct {
-	PacketsThreshold uint64 `json:"packets_threshold"`
-	WebhookURL       string `json:"webhook_url"`
-}
+// DefaultReplayName names the replay of requests that do not give a name.
+const DefaultReplayName = "default"
+
+// DefaultMaxReplays is how many replays run at once; further starts queue.
+const DefaultMaxReplays = 4
+
+// MaxReplayNameLength bounds replay names.
+const MaxReplayNameLength = 64
 
 // ReplayRequest represents a packet replay request.
 type ReplayRequest struct {
+	// Name identifies the replay; replays with different names run side by
+	// side, and starting a name that is already in use replaces that replay
+	Name string `json:"name,omitempty"`
+	// Device is the simulated device the replay plays as; two active
+	// replays cannot share one (empty = no device)
+	Device     string  `json:"device,omitempty"`
 	File       string  `json:"file"`
 	LoopMs     int     `json:"loop_ms"`
+	LoopCount  int     `json:"loop_count"` // Plays before stopping (0 = once, or forever with loop_ms)
 	Scale      float64 `json:"scale"`
 	InlineData string  `json:"data,omitempty"`
-	Uploaded   bool    `json:"-"`
+	// Rewrite maps captured IP/MAC addresses to simulated ones (e.g. "10.1.1.1": "192.168.0.1")
+	Rewrite map[string]string `json:"rewrite,omitempty"`
+	// VLANMode is preserve (default), strip or rewrite; VLANID is the ID rewrite sets
+	VLANMode string `json:"vlan_mode,omitempty"`
+	VLANID   int    `json:"vlan_id,omitempty"`
+	// Filter is a BPF expression; captured packets not matching it are skipped
+	Filter   string `json:"filter,omitempty"`
+	Uploaded bool   `json:"-"`
 }
 
-// ReplayState reports the current replay status.
+// ReplayState reports the status of a named replay.
 type ReplayState struct {
-	Running   bool      `json:"running"`
-	File      string    `json:"file"`
-	LoopMs    int       `json:"loop_ms"`
-	Scale     float64   `json:"scale"`
-	StartedAt time.Time `json:"started_at,omitempty"`
+	Name      string            `json:"name"`
+	Device    string            `json:"device,omitempty"`
+	Running   bool              `json:"running"`
+	Queued    bool              `json:"queued,omitempty"` // Waiting for a running replay to end
+	File      string            `json:"file"`
+	LoopMs    int               `json:"loop_ms"`
+	LoopCount int               `json:"loop_count"`
+	Scale     float64           `json:"scale"`
+	Rewrite   map[string]string `json:"rewrite,omitempty"`
+	VLANMode  string            `json:"vlan_mode,omitempty"`
+	VLANID    int               `json:"vlan_id,omitempty"`
+	Filter    string            `json:"filter,omitempty"`
+	StartedAt time.Time         `json:"started_at,omitempty"`
+	Result    *ReplayResult     `json:"result,omitempty"`
+}
+
+// ReplayResult summarizes what a replay has sent: progress while it runs,
+// final counts once it has finished or been stopped.
+type ReplayResult struct {
+	PacketsSent    int       `json:"packets_sent"`
+	BytesSent      int64     `json:"bytes_sent"`
+	SendErrors     int       `json:"send_errors"`
+	LoopsCompleted int       `json:"loops_completed"`
+	DurationMs     int64     `json:"duration_ms"`
+	Completed      bool      `json:"completed"`
+	FinishedAt     time.Time `json:"finished_at,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// NewReplayResult converts a playback engine result for the API.
+func NewReplayResult(result capture.PlaybackResult) *ReplayResult {
+	return &ReplayResult{
+		PacketsSent:    result.PacketsSent,
+		BytesSent:      result.BytesSent,
+		SendErrors:     result.SendErrors,
+		LoopsCompleted: result.LoopsCompleted,
+		DurationMs:     result.Duration.Milliseconds(),
+		Completed:      result.Completed,
+		FinishedAt:     result.FinishedAt.UTC(),
+		Error:          result.Error,
+	}
 }
 
 // FileEntry represents a discovered file (pcap, walk, etc.).
@@ -347,11 +454,15 @@ type FileEntry struct {
 	Modified  time.Time `json:"modified_at"`
 }
 
-// ReplayManager controls PCAP playback from the API server.
+// ReplayManager controls PCAP playback from the API server. Replays are
+// identified by name; Status and Stop report false for a name that was never
+// started, except DefaultReplayName, which is always known.
 type ReplayManager interface {
-	Status() ReplayState
+	Status(name string) (ReplayState, bool)
+	List() []ReplayState // Every replay, by name
 	Start(ReplayRequest) (ReplayState, error)
-	Stop() (ReplayState, error)
+	Stop(name string) (ReplayState, bool)
+	StopAll() []ReplayState
 }
 
 // ServerConfig defines API server options.
@@ -365,10 +476,26 @@ type ServerConfig struct {
 	Storage     *storage.Storage
 	Interface   string
 	Version     string
-	Topology    Topology
 	Alert       AlertConfig
 	ApplyConfig func(*config.Config) error
 	Replay      ReplayManager
+	// OutputDir, when set, replaces os.TempDir as the base for uploaded files.
+	OutputDir string
+	// UploadMaxAge is how long uploaded replay files are kept before a
+	// background ticker removes them; 0 disables automatic cleanup.
+	UploadMaxAge time.Duration
+	Capture      CaptureMonitor // Optional: enables capture checks in /api/v1/health
+	// Per-client-IP request rate (requests/second) and burst. Either being 0
+	// disables rate limiting; pass DefaultRateLimit and DefaultBurst for the
+	// standard limits.
+	RateLimit float64
+	RateBurst int
+	// UIDir, when set, serves the Web UI from this directory instead of the
+	// embedded assets, so UI changes show without rebuilding the binary.
+	UIDir string
+	// SNMPDurations, when set, is served on /metrics as the
+	// niac_snmp_request_duration_seconds histogram.
+	SNMPDurations *stats.Histogram
 }
 
 // SimulationRequest represents a request to start a simulation
@@ -402,13 +529,23 @@ type Server struct {
 	httpServer    *http.Server
 	metricsServer *http.Server
 	alertStop     chan struct{}
-	lastAlert     uint64
+	lastAlerts    map[int]float64 // Rule index -> value it last fired at
 	alertMu       sync.RWMutex
 	configMu      sync.RWMutex
+	tokenMu       sync.RWMutex     // Guards cfg.Token, which SetToken rotates
 	daemon        DaemonController // Optional: only set in daemon mode
 	startTime     time.Time        // Track server start time for uptime
-	rateLimiter   *RateLimiter     // FEATURE #104: Per-IP rate limiting
+	rateLimiter   *RateLimiter     // FEATURE #104: Per-IP rate limiting (nil = disabled)
 	csrfToken     string           // SECURITY FIX LOW-1: CSRF protection token
+
+	webhookFailures atomic.Uint32 // Consecutive failed alert webhook deliveries
+
+	// Cached declared+discovered topology, swapped atomically so reads are lock-free
+	topology        atomic.Pointer[topologySnapshot]
+	topologyBuildMu sync.Mutex
+	topologyStop    chan struct{}
+
+	uploadCleanupStop chan struct{} // Stops the stale replay upload ticker
 }
 
 // generateCSRFToken generates a cryptographically secure random token
@@ -426,12 +563,15 @@ func NewServer(cfg ServerConfig) *Server {
 	// Generate CSRF token (ignore errors, fallback to empty which disables CSRF check)
 	csrfToken, _ := generateCSRFToken()
 
-	return &Server{
-		cfg:         cfg,
-		startTime:   time.Now(),
-		rateLimiter: NewRateLimiter(DefaultRateLimit, DefaultBurst),
-		csrfToken:   csrfToken,
+	s := &Server{
+		cfg:       cfg,
+		startTime: time.Now(),
+		csrfToken: csrfToken,
+	}
+	if cfg.RateLimit > 0 && cfg.RateBurst > 0 {
+		s.rateLimiter = NewRateLimiter(rate.Limit(cfg.RateLimit), cfg.RateBurst)
 	}
+	return s
 }
 
 // Start boots the HTTP listeners.
@@ -443,41 +583,49 @@ func (s *Server) Start() error {
 		return fmt.Errorf("api server requires stack and config references")
 	}
 
-	// SECURITY FIX #107: Warn if API is running without authentication
-	if s.cfg.Token == "" && s.cfg.Addr != "" {
-		log.Println("⚠️  WARNING: API server running WITHOUT authentication!")
-		log.Println("    All endpoints are publicly accessible without any access control.")
-		log.Println("    Set NIAC_API_TOKEN environment variable to enable authentication.")
-		log.Println("    Example: export NIAC_API_TOKEN=$(openssl rand -base64 32)")
+	if s.token() == "" {
+		s.warnUnauthenticated()
 	}
 
 	if s.cfg.Addr != "" {
 		mux := http.NewServeMux()
 		// SECURITY FIX LOW-1: CSRF token endpoint for clients to retrieve token
 		mux.HandleFunc("/api/v1/csrf-token", s.auth(s.handleCSRFToken))
-		mux.HandleFunc("/api/v1/stats", s.auth(s.handleStats))
+		mux.HandleFunc("/api/v1/stats", s.auth(s.csrfProtect(s.handleStats)))
 		mux.HandleFunc("/api/v1/devices", s.auth(s.handleDevices))
+		mux.HandleFunc("/api/v1/devices/", s.auth(s.csrfProtect(s.handleDevice)))
+		mux.HandleFunc("/api/v1/bulk/power", s.auth(s.csrfProtect(s.handleBulkPower)))
+		mux.HandleFunc("/api/v1/bulk/errors", s.auth(s.csrfProtect(s.handleBulkErrors)))
+		mux.HandleFunc("/api/v1/leases/", s.auth(s.csrfProtect(s.handleLease)))
 		mux.HandleFunc("/api/v1/history", s.auth(s.handleHistory))
 		// SECURITY FIX LOW-1: Protect state-changing endpoints with CSRF
 		mux.HandleFunc("/api/v1/config", s.auth(s.csrfProtect(s.handleConfig)))
+		mux.HandleFunc("/api/v1/config/effective", s.auth(s.handleConfigEffective))
 		mux.HandleFunc("/api/v1/replay", s.auth(s.csrfProtect(s.handleReplay)))
+		mux.HandleFunc("/api/v1/replays", s.auth(s.csrfProtect(s.handleReplays)))
+		mux.HandleFunc("/api/v1/replay/uploads", s.auth(s.csrfProtect(s.handleReplayUploads)))
 		mux.HandleFunc("/api/v1/alerts", s.auth(s.csrfProtect(s.handleAlerts)))
 		mux.HandleFunc("/api/v1/files", s.auth(s.handleFiles))
 		mux.HandleFunc("/api/v1/topology", s.auth(s.handleTopology))
 		mux.HandleFunc("/api/v1/topology/export", s.auth(s.handleTopologyExport))
 		mux.HandleFunc("/api/v1/errors", s.auth(s.handleErrors))
+		mux.HandleFunc("/api/v1/errors/packets", s.auth(s.csrfProtect(s.handleErrorPackets)))
 		mux.HandleFunc("/api/v1/interfaces", s.auth(s.handleInterfaces))
 		mux.HandleFunc("/api/v1/runtime", s.auth(s.handleRuntime))
 		mux.HandleFunc("/api/v1/simulation", s.auth(s.handleSimulation))
 		mux.HandleFunc("/api/v1/version", s.auth(s.handleVersion))
 		mux.HandleFunc("/api/v1/neighbors", s.auth(s.handleNeighbors))
+		mux.HandleFunc("/api/v1/arp", s.auth(s.handleARP))
+		mux.HandleFunc("/api/v1/health", s.auth(s.handleHealth))
+		mux.HandleFunc("/api/v1/traffic", s.auth(s.handleTraffic))
+		mux.HandleFunc("/api/v1/debug", s.auth(s.csrfProtect(s.handleDebug)))
 		mux.HandleFunc("/metrics", s.handleMetrics)
 		mux.HandleFunc("/", s.auth(s.serveSPA()))
 
 		// SECURITY FIX #99: Add HTTP timeouts to prevent slowloris attacks
 		s.httpServer = &http.Server{
 			Addr:              s.cfg.Addr,
-			Handler:           mux,
+			Handler:           accessLog(mux),
 			ReadTimeout:       10 * time.Second,
 			WriteTimeout:      10 * time.Second,
 			IdleTimeout:       60 * time.Second,
@@ -499,7 +647,7 @@ func (s *Server) Start() error {
 		// SECURITY FIX #99: Add HTTP timeouts to metrics server too
 		s.metricsServer = &http.Server{
 			Addr:              s.cfg.MetricsAddr,
-			Handler:           mux,
+			Handler:           accessLog(mux),
 			ReadTimeout:       10 * time.Second,
 			WriteTimeout:      10 * time.Second,
 			IdleTimeout:       60 * time.Second,
@@ -515,13 +663,27 @@ func (s *Server) Start() error {
 	}
 
 	// FEATURE #104: Start periodic cleanup of stale rate limiters
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-		for range ticker.C {
-			s.rateLimiter.CleanupStale()
-		}
-	}()
+	if s.rateLimiter != nil {
+		go func() {
+			ticker := time.NewTicker(5 * time.Minute)
+			defer ticker.Stop()
+			for range ticker.C {
+				s.rateLimiter.CleanupStale()
+			}
+		}()
+	}
+
+	s.topologyBuildMu.Lock()
+	if s.topologyStop == nil {
+		s.topologyStop = make(chan struct{})
+		go s.runTopologyRefresher(s.topologyStop)
+	}
+	s.topologyBuildMu.Unlock()
+
+	if s.cfg.UploadMaxAge > 0 && s.uploadCleanupStop == nil {
+		s.uploadCleanupStop = make(chan struct{})
+		go s.runUploadCleanup(s.uploadCleanupStop)
+	}
 
 	s.updateAlertConfig(s.cfg.Alert)
 	return nil
@@ -539,6 +701,18 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	}
 	s.alertMu.Unlock()
 
+	s.topologyBuildMu.Lock()
+	if s.topologyStop != nil {
+		close(s.topologyStop)
+		s.topologyStop = nil
+	}
+	s.topologyBuildMu.Unlock()
+
+	if s.uploadCleanupStop != nil {
+		close(s.uploadCleanupStop)
+		s.uploadCleanupStop = nil
+	}
+
 	var firstErr error
 
 	// Shutdown metrics server first (less critical)
@@ -573,30 +747,44 @@ func (s *Server) SetDaemonController(daemon DaemonController) {
 // UpdateSimulation updates the server with simulation components (for daemon mode)
 func (s *Server) UpdateSimulation(stack *protocols.Stack, cfg *config.Config, configPath string, iface string, replay ReplayManager) {
 	s.configMu.Lock()
-	defer s.configMu.Unlock()
-
 	s.cfg.Stack = stack
 	s.cfg.Config = cfg
 	s.cfg.ConfigPath = configPath
 	s.cfg.Interface = iface
 	s.cfg.Replay = replay
-	s.cfg.Topology = BuildTopology(cfg)
+	s.configMu.Unlock()
+
+	s.invalidateTopology()
 }
 
 // ClearSimulation clears simulation components (for daemon mode)
 func (s *Server) ClearSimulation() {
 	s.configMu.Lock()
-	defer s.configMu.Unlock()
-
 	s.cfg.Stack = nil
 	s.cfg.Config = nil
 	s.cfg.Replay = nil
+	s.configMu.Unlock()
+
+	s.invalidateTopology()
+}
+
+// principalOf returns the principal auth recorded for the access log, or "-"
+// if the writer is not wrapped by accessLog.
+func principalOf(w http.ResponseWriter) string {
+	if rec, ok := w.(*accessLogRecorder); ok {
+		return rec.principal
+	}
+	return "-"
 }
 
 func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// FEATURE #118: Generate unique request ID for tracing
-		requestID := generateRequestID()
+		// FEATURE #118: Generate unique request ID for tracing (reuse the
+		// access log's ID when present so log lines correlate)
+		requestID := w.Header().Get("X-Request-ID")
+		if requestID == "" {
+			requestID = generateRequestID()
+		}
 		r.Header.Set("X-Request-ID", requestID)
 		w.Header().Set("X-Request-ID", requestID)
 
@@ -605,8 +793,7 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 
 		// FEATURE #104: Apply rate limiting per IP address
 		clientIP := getClientIP(r)
-		limiter := s.rateLimiter.GetLimiter(clientIP)
-		if !limiter.Allow() {
+		if s.rateLimiter != nil && !s.rateLimiter.GetLimiter(clientIP).Allow() {
 			// FEATURE #105: Use standardized error response
 			writeError(w, r, http.StatusTooManyRequests, "rate_limit_exceeded",
 				"Rate limit exceeded. Please try again later.", nil)
@@ -614,7 +801,9 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		if s.cfg.Token == "" {
+		expected := s.token()
+		if expected == "" {
+			setPrincipal(w, "anonymous")
 			next(w, r)
 			return
 		}
@@ -627,7 +816,7 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 
 		// SECURITY FIX #100: Use constant-time comparison to prevent timing attacks
 		// Standard string comparison (!=) could leak token information via timing
-		if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) != 1 {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
 			// FEATURE #105: Use standardized error response
 			writeError(w, r, http.StatusUnauthorized, "unauthorized",
 				"Invalid or missing authentication token", nil)
@@ -635,6 +824,7 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		setPrincipal(w, "token")
 		next(w, r)
 	}
 }
@@ -655,10 +845,10 @@ func (s *Server) serveSPA() http.HandlerFunc {
 			return
 		}
 
-		lookupPath := path.Join("ui", requestPath)
-		data, err := fs.ReadFile(uiFS, lookupPath)
+		files, root := s.uiFiles()
+		data, err := fs.ReadFile(files, path.Join(root, requestPath))
 		if err != nil {
-			data, err = fs.ReadFile(uiFS, path.Join("ui", "index.html"))
+			data, err = fs.ReadFile(files, path.Join(root, "index.html"))
 			if err != nil {
 				http.NotFound(w, r)
 				return
@@ -678,6 +868,16 @@ func (s *Server) serveSPA() http.HandlerFunc {
 	}
 }
 
+// uiFiles returns the filesystem the Web UI is served from and the UI's
+// directory within it: --ui-dir when set, read on every request, otherwise
+// the embedded assets.
+func (s *Server) uiFiles() (fs.FS, string) {
+	if s.cfg.UIDir != "" {
+		return os.DirFS(s.cfg.UIDir), "."
+	}
+	return uiFS, "ui"
+}
+
 // handleCSRFToken returns the CSRF token for the client
 // SECURITY FIX LOW-1: Clients must retrieve this token and include it in state-changing requests
 func (s *Server) handleCSRFToken(w http.ResponseWriter, r *http.Request) {
@@ -703,7 +903,33 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := stack.GetStats()
+	var stats protocols.Statistics
+	switch r.Method {
+	case http.MethodGet:
+		reset := false
+		if value := r.URL.Query().Get("reset"); value != "" {
+			var err error
+			if reset, err = strconv.ParseBool(value); err != nil {
+				http.Error(w, fmt.Sprintf("invalid reset value %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+		if reset {
+			// Return the counters as they were when they were zeroed
+			stats = stack.ResetStats()
+		} else {
+			stats = stack.GetStats()
+		}
+	case http.MethodDelete:
+		stack.ResetStats()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
 	deviceCount := 0
 	if cfg != nil {
 		deviceCount = len(cfg.Devices)
@@ -719,16 +945,24 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		"device_count": deviceCount,
 		"goroutines":   goroutineCount, // FEATURE #119: Monitor goroutine count
 		"stack": map[string]uint64{
-			"packets_sent":     stats.PacketsSent,
-			"packets_received": stats.PacketsReceived,
-			"arp_requests":     stats.ARPRequests,
-			"arp_replies":      stats.ARPReplies,
-			"icmp_requests":    stats.ICMPRequests,
-			"icmp_replies":     stats.ICMPReplies,
-			"dns_queries":      stats.DNSQueries,
-			"dhcp_requests":    stats.DHCPRequests,
-			"snmp_queries":     stats.SNMPQueries,
-			"errors":           stats.Errors,
+			"packets_sent":            stats.PacketsSent,
+			"packets_received":        stats.PacketsReceived,
+			"arp_requests":            stats.ARPRequests,
+			"arp_replies":             stats.ARPReplies,
+			"icmp_requests":           stats.ICMPRequests,
+			"icmp_replies":            stats.ICMPReplies,
+			"dns_queries":             stats.DNSQueries,
+			"dhcp_requests":           stats.DHCPRequests,
+			"dhcp_retransmits":        stats.DHCPRetransmits,
+			"snmp_queries":            stats.SNMPQueries,
+			"snmp_denied":             stats.SNMPDenied,
+			"errors":                  stats.Errors,
+			"delayed_responses":       stats.DelayedResponses,
+			"added_latency_ms":        stats.AddedLatencyNanos / uint64(time.Millisecond),
+			"tcp_connections_refused": stats.TCPConnectionsRefused,
+			"malformed_frames_sent":   stats.MalformedFramesSent,
+			"neighbors":               stats.Neighbors,
+			"neighbors_evicted":       stats.NeighborsEvicted,
 		},
 	}
 	s.writeJSON(w, payload)
@@ -741,42 +975,10 @@ func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	stack := s.currentStack()
 	devices := make([]map[string]interface{}, 0, len(cfg.Devices))
-	for _, dev := range cfg.Devices {
-		ips := make([]string, 0, len(dev.IPAddresses))
-		for _, ip := range dev.IPAddresses {
-			ips = append(ips, ip.String())
-		}
-
-		protos := make([]string, 0, 8)
-		if dev.SNMPConfig.Community != "" || dev.SNMPConfig.WalkFile != "" {
-			protos = append(protos, "SNMP")
-		}
-		if dev.DHCPConfig != nil {
-			protos = append(protos, "DHCP")
-		}
-		if dev.DNSConfig != nil {
-			protos = append(protos, "DNS")
-		}
-		if dev.HTTPConfig != nil {
-			protos = append(protos, "HTTP")
-		}
-		if dev.FTPConfig != nil {
-			protos = append(protos, "FTP")
-		}
-		if dev.LLDPConfig != nil && dev.LLDPConfig.Enabled {
-			protos = append(protos, "LLDP")
-		}
-		if dev.CDPConfig != nil && dev.CDPConfig.Enabled {
-			protos = append(protos, "CDP")
-		}
-
-		devices = append(devices, map[string]interface{}{
-			"name":      dev.Name,
-			"type":      dev.Type,
-			"ips":       ips,
-			"protocols": protos,
-		})
+	for i := range cfg.Devices {
+		devices = append(devices, deviceSummary(&cfg.Devices[i], stack))
 	}
 	s.writeJSON(w, devices)
 }
@@ -874,6 +1076,31 @@ func (s *Server) handleConfigUpdate(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, doc)
 }
 
+// handleConfigEffective returns the running configuration, with defaults
+// applied, as YAML. Unlike GET /api/v1/config it does not read the file.
+func (s *Server) handleConfigEffective(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := s.currentConfig()
+	if cfg == nil {
+		http.Error(w, "no configuration loaded", http.StatusServiceUnavailable)
+		return
+	}
+	content, err := cfg.EffectiveYAML()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("rendering config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, effectiveConfigDocument{
+		DeviceCount: len(cfg.Devices),
+		Content:     string(content),
+	})
+}
+
 func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
 	// FEATURE #132: Graceful degradation when replay engine is unavailable
 	if s.cfg.Replay == nil {
@@ -882,9 +1109,19 @@ func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = DefaultReplayName
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		s.writeJSON(w, s.cfg.Replay.Status())
+		state, ok := s.cfg.Replay.Status(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("replay %q not found", name), http.StatusNotFound)
+			return
+		}
+		s.writeJSON(w, state)
 	case http.MethodPost:
 		// SECURITY FIX #97: Enforce request body size limit for PCAP uploads
 		r.Body = http.MaxBytesReader(w, r.Body, MaxPCAPUploadSize)
@@ -905,14 +1142,14 @@ func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
 		}
 		state, err := s.cfg.Replay.Start(prepared)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), replayStartStatus(err))
 			return
 		}
 		s.writeJSON(w, state)
 	case http.MethodDelete:
-		state, err := s.cfg.Replay.Stop()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		state, ok := s.cfg.Replay.Stop(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("replay %q not found", name), http.StatusNotFound)
 			return
 		}
 		s.writeJSON(w, state)
@@ -922,6 +1159,25 @@ func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleReplays lists every named replay (GET) or stops them all (DELETE).
+func (s *Server) handleReplays(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Replay == nil {
+		writeError(w, r, http.StatusServiceUnavailable, "replay_unavailable",
+			"PCAP replay functionality is not available in this mode. Start niac with a configuration to enable replay.", nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, s.cfg.Replay.List())
+	case http.MethodDelete:
+		s.writeJSON(w, s.cfg.Replay.StopAll())
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -932,6 +1188,10 @@ func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
 			return
 		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		s.updateAlertConfig(req)
 		s.writeJSON(w, s.getAlertConfig())
 	default:
@@ -1028,6 +1288,7 @@ func (s *Server) handleErrors(w http.ResponseWriter, r *http.Request) {
 			{"type": "High CPU", "description": "Device CPU load (0-100%)"},
 			{"type": "High Memory", "description": "Device memory usage (0-100%)"},
 			{"type": "High Disk", "description": "Device disk usage (0-100%)"},
+			{"type": "Runt Frames", "description": "Undersized frames sent on the wire per second (0-100)"},
 		}
 
 		activeErrors := errorMgr.GetAllStates()
@@ -1263,7 +1524,7 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := stack.GetStats()
+	stackStats := stack.GetStats()
 	deviceCount := 0
 	if cfg != nil {
 		deviceCount = len(cfg.Devices)
@@ -1273,74 +1534,22 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
-	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
-
-	// Existing basic metrics
-	fmt.Fprintf(w, "# HELP niac_packets_sent_total Total packets sent\n")
-	fmt.Fprintf(w, "# TYPE niac_packets_sent_total counter\n")
-	fmt.Fprintf(w, "niac_packets_sent_total %d\n", stats.PacketsSent)
-
-	fmt.Fprintf(w, "# HELP niac_packets_received_total Total packets received\n")
-	fmt.Fprintf(w, "# TYPE niac_packets_received_total counter\n")
-	fmt.Fprintf(w, "niac_packets_received_total %d\n", stats.PacketsReceived)
-
-	fmt.Fprintf(w, "# HELP niac_snmp_queries_total Total SNMP queries processed\n")
-	fmt.Fprintf(w, "# TYPE niac_snmp_queries_total counter\n")
-	fmt.Fprintf(w, "niac_snmp_queries_total %d\n", stats.SNMPQueries)
-
-	fmt.Fprintf(w, "# HELP niac_errors_total Total errors\n")
-	fmt.Fprintf(w, "# TYPE niac_errors_total counter\n")
-	fmt.Fprintf(w, "niac_errors_total %d\n", stats.Errors)
-
-	fmt.Fprintf(w, "# HELP niac_devices_total Number of simulated devices\n")
-	fmt.Fprintf(w, "# TYPE niac_devices_total gauge\n")
-	fmt.Fprintf(w, "niac_devices_total %d\n", deviceCount)
-
-	// Protocol-specific metrics
-	fmt.Fprintf(w, "# HELP niac_arp_requests_total Total ARP requests sent\n")
-	fmt.Fprintf(w, "# TYPE niac_arp_requests_total counter\n")
-	fmt.Fprintf(w, "niac_arp_requests_total %d\n", stats.ARPRequests)
-
-	fmt.Fprintf(w, "# HELP niac_arp_replies_total Total ARP replies sent\n")
-	fmt.Fprintf(w, "# TYPE niac_arp_replies_total counter\n")
-	fmt.Fprintf(w, "niac_arp_replies_total %d\n", stats.ARPReplies)
-
-	fmt.Fprintf(w, "# HELP niac_icmp_requests_total Total ICMP requests sent\n")
-	fmt.Fprintf(w, "# TYPE niac_icmp_requests_total counter\n")
-	fmt.Fprintf(w, "niac_icmp_requests_total %d\n", stats.ICMPRequests)
-
-	fmt.Fprintf(w, "# HELP niac_icmp_replies_total Total ICMP replies sent\n")
-	fmt.Fprintf(w, "# TYPE niac_icmp_replies_total counter\n")
-	fmt.Fprintf(w, "niac_icmp_replies_total %d\n", stats.ICMPReplies)
-
-	fmt.Fprintf(w, "# HELP niac_dns_queries_total Total DNS queries processed\n")
-	fmt.Fprintf(w, "# TYPE niac_dns_queries_total counter\n")
-	fmt.Fprintf(w, "niac_dns_queries_total %d\n", stats.DNSQueries)
-
-	fmt.Fprintf(w, "# HELP niac_dhcp_requests_total Total DHCP requests processed\n")
-	fmt.Fprintf(w, "# TYPE niac_dhcp_requests_total counter\n")
-	fmt.Fprintf(w, "niac_dhcp_requests_total %d\n", stats.DHCPRequests)
-
-	// System performance metrics
-	fmt.Fprintf(w, "# HELP niac_uptime_seconds Server uptime in seconds\n")
-	fmt.Fprintf(w, "# TYPE niac_uptime_seconds gauge\n")
-	fmt.Fprintf(w, "niac_uptime_seconds %d\n", int64(time.Since(s.startTime).Seconds()))
-
-	fmt.Fprintf(w, "# HELP niac_goroutines_total Number of goroutines\n")
-	fmt.Fprintf(w, "# TYPE niac_goroutines_total gauge\n")
-	fmt.Fprintf(w, "niac_goroutines_total %d\n", runtime.NumGoroutine())
-
-	fmt.Fprintf(w, "# HELP niac_memory_usage_bytes Memory usage in bytes\n")
-	fmt.Fprintf(w, "# TYPE niac_memory_usage_bytes gauge\n")
-	fmt.Fprintf(w, "niac_memory_usage_bytes %d\n", memStats.Alloc)
-
-	fmt.Fprintf(w, "# HELP niac_memory_sys_bytes Total memory obtained from OS in bytes\n")
-	fmt.Fprintf(w, "# TYPE niac_memory_sys_bytes gauge\n")
-	fmt.Fprintf(w, "niac_memory_sys_bytes %d\n", memStats.Sys)
+	metrics := stats.Metrics{
+		Stack:       stackStats.Counters(),
+		DeviceCount: deviceCount,
+		Uptime:      time.Since(s.startTime),
+		Goroutines:  runtime.NumGoroutine(),
+		MemoryAlloc: memStats.Alloc,
+		MemorySys:   memStats.Sys,
+		GCRuns:      memStats.NumGC,
+	}
+	if s.cfg.SNMPDurations != nil {
+		durations := s.cfg.SNMPDurations.Snapshot()
+		metrics.SNMPRequestDuration = &durations
+	}
 
-	fmt.Fprintf(w, "# HELP niac_gc_runs_total Total number of GC runs\n")
-	fmt.Fprintf(w, "# TYPE niac_gc_runs_total counter\n")
-	fmt.Fprintf(w, "niac_gc_runs_total %d\n", memStats.NumGC)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = metrics.WritePrometheus(w)
 }
 
 func (s *Server) writeJSON(w http.ResponseWriter, payload interface{}) {
@@ -1350,71 +1559,6 @@ func (s *Server) writeJSON(w http.ResponseWriter, payload interface{}) {
 	_ = enc.Encode(payload)
 }
 
-func (s *Server) alertLoop(stop <-chan struct{}) {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			cfg := s.getAlertConfig()
-			if cfg.PacketsThreshold == 0 {
-				continue
-			}
-
-			s.configMu.RLock()
-			stack := s.cfg.Stack
-			s.configMu.RUnlock()
-
-			if stack == nil {
-				continue
-			}
-
-			stats := stack.GetStats()
-			total := stats.PacketsSent + stats.PacketsReceived
-			if total >= cfg.PacketsThreshold {
-				s.alertMu.Lock()
-				if total != s.lastAlert {
-					s.lastAlert = total
-					go s.sendAlert(total)
-				}
-				s.alertMu.Unlock()
-			}
-		case <-stop:
-			return
-		}
-	}
-}
-
-func (s *Server) sendAlert(total uint64) {
-	log.Printf("alert: packet threshold exceeded (total=%d)", total)
-	cfg := s.getAlertConfig()
-	if cfg.WebhookURL == "" {
-		return
-	}
-
-	body, _ := json.Marshal(map[string]interface{}{
-		"type":        "packet_threshold",
-		"threshold":   cfg.PacketsThreshold,
-		"total":       total,
-		"interface":   s.cfg.Interface,
-		"triggeredAt": time.Now().UTC(),
-	})
-
-	req, err := http.NewRequest(http.MethodPost, cfg.WebhookURL, strings.NewReader(string(body)))
-	if err != nil {
-		log.Printf("alert webhook error: %v", err)
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 5 * time.Second}
-	if resp, err := client.Do(req); err != nil {
-		log.Printf("alert webhook request failed: %v", err)
-	} else {
-		resp.Body.Close()
-	}
-}
-
 // validatePCAPMagic validates that the file begins with a valid PCAP magic number
 // SECURITY FIX LOW-2: Prevents processing of non-PCAP files that could exploit parser bugs
 func validatePCAPMagic(data []byte) error {
@@ -1451,7 +1595,53 @@ func (s *Server) prepareReplayRequest(req ReplayRequest) (ReplayRequest, error)
 	if strings.TrimSpace(req.File) == "" && req.InlineData == "" {
 		return req, fmt.Errorf("pcap file path or data is required")
 	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = DefaultReplayName
+	}
+	if len(req.Name) > MaxReplayNameLength {
+		return req, fmt.Errorf("name must be at most %d characters", MaxReplayNameLength)
+	}
+	if req.Device != "" {
+		if cfg := s.currentConfig(); cfg == nil || !cfg.HasDevice(req.Device) {
+			return req, fmt.Errorf("device %q not found", req.Device)
+		}
+	}
+	if req.LoopCount < 0 {
+		return req, fmt.Errorf("loop_count must not be negative")
+	}
+	if len(req.Rewrite) > 0 {
+		if _, err := capture.NewAddressRewriter(req.Rewrite); err != nil {
+			return req, err
+		}
+	}
+	if _, err := capture.NewVLANRewriter(req.VLANMode, req.VLANID); err != nil {
+		return req, err
+	}
 
+	req, err := s.resolveReplaySource(req)
+	if err != nil {
+		return req, err
+	}
+	if req.Filter != "" {
+		// Compile against the capture's own link type
+		linkType, err := capture.SourceLinkType(req.File)
+		if err == nil {
+			_, err = capture.NewPacketFilter(req.Filter, linkType)
+		}
+		if err != nil {
+			if req.Uploaded {
+				os.Remove(req.File)
+			}
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// resolveReplaySource saves inline PCAP data to a file, or checks the file or
+// stream:// source named by the request
+func (s *Server) resolveReplaySource(req ReplayRequest) (ReplayRequest, error) {
 	if req.InlineData != "" {
 		// SECURITY FIX #97: Additional check on base64 encoded data size
 		// Base64 encoding increases size by ~4/3, so check before decode
@@ -1484,6 +1674,13 @@ func (s *Server) prepareReplayRequest(req ReplayRequest) (ReplayRequest, error)
 		return req, nil
 	}
 
+	if capture.IsStreamSource(req.File) {
+		if _, err := capture.StreamAddress(req.File); err != nil {
+			return req, err
+		}
+		return req, nil
+	}
+
 	abs, err := filepath.Abs(req.File)
 	if err != nil {
 		return req, fmt.Errorf("resolve path: %w", err)
@@ -1500,11 +1697,11 @@ func (s *Server) prepareReplayRequest(req ReplayRequest) (ReplayRequest, error)
 }
 
 func (s *Server) writeUploadedFile(data []byte) (string, error) {
-	dir := filepath.Join(os.TempDir(), "niac-replay")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	dir := s.uploadDir()
+	if err := os.MkdirAll(dir, 0o750); err != nil {
 		return "", fmt.Errorf("create upload dir: %w", err)
 	}
-	tmp, err := os.CreateTemp(dir, "upload-*.pcap")
+	tmp, err := os.CreateTemp(dir, uploadPattern)
 	if err != nil {
 		return "", fmt.Errorf("create temp file: %w", err)
 	}
@@ -1533,9 +1730,9 @@ func (s *Server) updateAlertConfig(cfg AlertConfig) {
 		s.alertStop = nil
 	}
 	s.cfg.Alert = cfg
-	s.lastAlert = 0
+	s.lastAlerts = make(map[int]float64)
 	var stopChan chan struct{}
-	if cfg.PacketsThreshold > 0 {
+	if len(cfg.EffectiveRules()) > 0 {
 		stopChan = make(chan struct{})
 		s.alertStop = stopChan
 	}
@@ -1555,6 +1752,12 @@ type configDocument struct {
 	Content     string    `json:"content"`
 }
 
+// effectiveConfigDocument is the running configuration as YAML
+type effectiveConfigDocument struct {
+	DeviceCount int    `json:"device_count"`
+	Content     string `json:"content"`
+}
+
 func (s *Server) readConfigDocument() (*configDocument, int, error) {
 	if s.cfg.ConfigPath == "" {
 		return nil, http.StatusBadRequest, fmt.Errorf("config path not available")
@@ -1631,17 +1834,12 @@ func (s *Server) currentConfig() *config.Config {
 	return s.cfg.Config
 }
 
-func (s *Server) currentTopology() Topology {
-	s.configMu.RLock()
-	defer s.configMu.RUnlock()
-	return s.cfg.Topology
-}
-
 func (s *Server) replaceConfig(cfg *config.Config) {
 	s.configMu.Lock()
 	s.cfg.Config = cfg
-	s.cfg.Topology = BuildTopology(cfg)
 	s.configMu.Unlock()
+
+	s.invalidateTopology()
 }
 
 func (s *Server) collectFiles(kind string) ([]FileEntry, error) {
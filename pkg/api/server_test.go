@@ -1,20 +1,33 @@
 package api
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/capture"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
 	"github.com/krisarmstrong/niac-go/pkg/protocols"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+	"github.com/krisarmstrong/niac-go/pkg/stats"
 )
 
 const baseConfigYAML = `
@@ -94,6 +107,43 @@ func TestServerHandleConfigUpdateReloadsStack(t *testing.T) {
 	}
 }
 
+func TestServerHandleConfigEffective(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.replaceConfig(mustLoadConfig(t, `
+devices:
+  - name: core1
+    mac: "00:11:22:33:44:55"
+    ips: ["10.0.0.1"]
+    lldp:
+      enabled: true
+`))
+
+	rec := httptest.NewRecorder()
+	server.handleConfigEffective(rec, httptest.NewRequest(http.MethodGet, "/api/v1/config/effective", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var doc effectiveConfigDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.DeviceCount != 1 {
+		t.Errorf("device_count = %d, want 1", doc.DeviceCount)
+	}
+	// advertise_interval and ttl were omitted from the source and filled in
+	for _, want := range []string{"name: core1", "mac_address: 00:11:22:33:44:55", "- 10.0.0.1", "lldp_config:", "advertise_interval: 30", "ttl: 120"} {
+		if !strings.Contains(doc.Content, want) {
+			t.Errorf("effective config missing %q:\n%s", want, doc.Content)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleConfigEffective(rec, httptest.NewRequest(http.MethodPut, "/api/v1/config/effective", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT: expected 405, got %d", rec.Code)
+	}
+}
+
 func TestServerHandleAlertsLifecycle(t *testing.T) {
 	server, _ := newTestServer(t)
 
@@ -128,6 +178,127 @@ func TestServerHandleAlertsLifecycle(t *testing.T) {
 	}
 }
 
+func TestServerAlertRulesFireOwnWebhooks(t *testing.T) {
+	hook := func(received chan<- map[string]interface{}) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			received <- payload
+		}))
+	}
+	packetAlerts := make(chan map[string]interface{}, 4)
+	errorAlerts := make(chan map[string]interface{}, 4)
+	packetHook := hook(packetAlerts)
+	defer packetHook.Close()
+	errorHook := hook(errorAlerts)
+	defer errorHook.Close()
+
+	cfg := mustLoadConfig(t, baseConfigYAML)
+	writer := &recordingWriter{}
+	stack := protocols.NewStack(capture.NewWithWriter("test0", writer, 0), cfg, logging.NewDebugConfig(0))
+	server := &Server{cfg: ServerConfig{Stack: stack, Config: cfg}}
+
+	rec := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"rules":[
+		{"name":"busy","metric":"packets","threshold":3,"webhook_url":%q},
+		{"name":"failing","metric":"errors","comparison":">=","threshold":2,"webhook_url":%q}]}`,
+		packetHook.URL, errorHook.URL)
+	server.handleAlerts(rec, httptest.NewRequest(http.MethodPut, "/api/v1/alerts", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	defer server.updateAlertConfig(AlertConfig{})
+
+	frame := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x00, 0x03, 0xaa, 0xaa, 0x03}
+	inject := func(n int) {
+		for i := 0; i < n; i++ {
+			_ = stack.InjectFrame("core1", frame)
+		}
+	}
+	expect := func(alerts <-chan map[string]interface{}, name string, value float64) {
+		t.Helper()
+		select {
+		case payload := <-alerts:
+			if payload["type"] != name || payload["value"] != value {
+				t.Errorf("webhook got %v, want %s alert at %g", payload, name, value)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %s alert delivered", name)
+		}
+	}
+	expectNone := func(alerts <-chan map[string]interface{}, name string) {
+		t.Helper()
+		select {
+		case payload := <-alerts:
+			t.Errorf("unexpected alert on the %s webhook: %v", name, payload)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// Three packets sent: only the packet rule fires
+	inject(3)
+	server.evaluateAlerts()
+	expect(packetAlerts, "busy", 3)
+	expectNone(errorAlerts, "failing")
+
+	// Two failed sends: only the error rule fires, the packet count is unchanged
+	writer.err = errors.New("link down")
+	inject(2)
+	server.evaluateAlerts()
+	expect(errorAlerts, "failing", 2)
+	expectNone(packetAlerts, "busy")
+
+	// An unknown metric is rejected
+	rec = httptest.NewRecorder()
+	body = `{"rules":[{"metric":"bogus","threshold":1}]}`
+	server.handleAlerts(rec, httptest.NewRequest(http.MethodPut, "/api/v1/alerts", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown metric: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestServerHandleDebugSetsProtocolLevel(t *testing.T) {
+	server, _ := newTestServer(t)
+	debugConfig := server.cfg.Stack.GetDebugConfig()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/debug", strings.NewReader(`{"protocols":{"dhcp":3}}`))
+	server.handleDebug(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if level := debugConfig.GetProtocolLevel(logging.ProtocolDHCP); level != 3 {
+		t.Fatalf("DHCP debug level = %d, want 3", level)
+	}
+	if level := server.cfg.Stack.GetProtocolDebugLevel(logging.ProtocolARP); level != 0 {
+		t.Errorf("ARP debug level = %d, want global level 0", level)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleDebug(rec, httptest.NewRequest(http.MethodGet, "/api/v1/debug", nil))
+	var resp DebugLevels
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Global != 0 || resp.Protocols[logging.ProtocolDHCP] != 3 || resp.Overrides[logging.ProtocolDHCP] != 3 {
+		t.Fatalf("unexpected debug levels: %+v", resp)
+	}
+
+	// Out-of-range levels and unknown protocols are rejected without
+	// applying any part of the update
+	for _, body := range []string{`{"global":4}`, `{"protocols":{"DHCP":1,"SMTP":2}}`, `{"protocols":{"DHCP":-1}}`} {
+		rec = httptest.NewRecorder()
+		server.handleDebug(rec, httptest.NewRequest(http.MethodPut, "/api/v1/debug", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+	if debugConfig.GetGlobal() != 0 || debugConfig.GetProtocolLevel(logging.ProtocolDHCP) != 3 {
+		t.Errorf("rejected updates changed levels: global=%d DHCP=%d",
+			debugConfig.GetGlobal(), debugConfig.GetProtocolLevel(logging.ProtocolDHCP))
+	}
+}
+
 type stubReplay struct {
 	state        ReplayState
 	startReq     ReplayRequest
@@ -136,8 +307,12 @@ type stubReplay struct {
 	lastUploaded bool
 }
 
-func (s *stubReplay) Status() ReplayState {
-	return s.state
+func (s *stubReplay) Status(name string) (ReplayState, bool) {
+	return s.state, name == DefaultReplayName
+}
+
+func (s *stubReplay) List() []ReplayState {
+	return []ReplayState{s.state}
 }
 
 func (s *stubReplay) Start(req ReplayRequest) (ReplayState, error) {
@@ -147,6 +322,7 @@ func (s *stubReplay) Start(req ReplayRequest) (ReplayState, error) {
 	s.startReq = req
 	s.lastUploaded = req.Uploaded
 	s.state = ReplayState{
+		Name:      req.Name,
 		Running:   true,
 		File:      req.File,
 		LoopMs:    req.LoopMs,
@@ -156,10 +332,18 @@ func (s *stubReplay) Start(req ReplayRequest) (ReplayState, error) {
 	return s.state, nil
 }
 
-func (s *stubReplay) Stop() (ReplayState, error) {
+func (s *stubReplay) Stop(name string) (ReplayState, bool) {
+	if name != DefaultReplayName {
+		return ReplayState{}, false
+	}
 	s.stopCount++
 	s.state.Running = false
-	return s.state, nil
+	return s.state, true
+}
+
+func (s *stubReplay) StopAll() []ReplayState {
+	state, _ := s.Stop(DefaultReplayName)
+	return []ReplayState{state}
 }
 
 func TestServerHandleReplayRoutes(t *testing.T) {
@@ -206,6 +390,79 @@ func TestServerHandleReplayRoutes(t *testing.T) {
 	if stub.stopCount != 1 {
 		t.Fatalf("expected stop to be called once, got %d", stub.stopCount)
 	}
+	if stub.startReq.Name != DefaultReplayName {
+		t.Errorf("expected an unnamed replay to get %q, got %q", DefaultReplayName, stub.startReq.Name)
+	}
+
+	// GET lists every replay
+	rec = httptest.NewRecorder()
+	server.handleReplays(rec, httptest.NewRequest(http.MethodGet, "/api/v1/replays", nil))
+	var states []ReplayState
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil || len(states) != 1 || states[0].Name != DefaultReplayName {
+		t.Fatalf("GET /replays = %s (%v), want the default replay", rec.Body.String(), err)
+	}
+
+	for _, tc := range []struct {
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/api/v1/replay?name=missing", "", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/replay?name=missing", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/replay", fmt.Sprintf(`{"name":%q,"file":%s}`, strings.Repeat("x", MaxReplayNameLength+1), strconvJSON(pcapPath)), http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/replay", fmt.Sprintf(`{"device":"missing","file":%s}`, strconvJSON(pcapPath)), http.StatusBadRequest},
+	} {
+		rec = httptest.NewRecorder()
+		server.handleReplay(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: got %d, want %d", tc.method, tc.target, rec.Code, tc.want)
+		}
+	}
+
+	// A device already used by another replay is a conflict
+	stub.startErr = fmt.Errorf("%w: core1 is used by replay \"other\"", ErrReplayDeviceBusy)
+	rec = httptest.NewRecorder()
+	body := fmt.Sprintf(`{"device":"core1","file":%s}`, strconvJSON(pcapPath))
+	server.handleReplay(rec, httptest.NewRequest(http.MethodPost, "/api/v1/replay", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("POST on a busy device: got %d, want 409", rec.Code)
+	}
+}
+
+// TestServerHandleReplayInvalidFilter tests that a replay with a malformed
+// BPF filter is refused with 400 and never reaches the replay manager
+func TestServerHandleReplayInvalidFilter(t *testing.T) {
+	server, _ := newTestServer(t)
+	stub := &stubReplay{state: ReplayState{}}
+	server.cfg.Replay = stub
+
+	// Empty Ethernet capture: a 24-byte little-endian PCAP header
+	pcapPath := filepath.Join(t.TempDir(), "empty.pcap")
+	header := []byte{
+		0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+	}
+	if err := os.WriteFile(pcapPath, header, 0o600); err != nil {
+		t.Fatalf("write temp pcap: %v", err)
+	}
+	if _, err := capture.SourceLinkType(pcapPath); err != nil {
+		t.Skipf("Cannot read PCAP files: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"file":%s,"filter":"udp prot 67"}`, strconvJSON(pcapPath))
+	server.handleReplay(rec, httptest.NewRequest(http.MethodPost, "/api/v1/replay", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `invalid filter "udp prot 67"`) {
+		t.Errorf("expected the error to name the filter, got %q", rec.Body.String())
+	}
+	if stub.startReq.File != "" {
+		t.Errorf("replay started despite the invalid filter: %+v", stub.startReq)
+	}
 }
 
 func TestServerHandleReplayUpload(t *testing.T) {
@@ -244,6 +501,116 @@ func TestServerHandleReplayUpload(t *testing.T) {
 	}
 }
 
+func TestServerHandleReplayUploadUsesOutputDir(t *testing.T) {
+	server, _ := newTestServer(t)
+	stub := &stubReplay{state: ReplayState{}}
+	server.cfg.Replay = stub
+	outputDir := t.TempDir()
+	server.cfg.OutputDir = outputDir
+
+	pcapData := []byte{
+		0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+	}
+	body, _ := json.Marshal(map[string]string{
+		"file": "uploaded.pcap",
+		"data": base64.StdEncoding.EncodeToString(pcapData),
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/replay", strings.NewReader(string(body)))
+	server.handleReplay(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	uploaded := stub.startReq.File
+	rel, err := filepath.Rel(outputDir, uploaded)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		t.Fatalf("uploaded file %q not under output dir %q", uploaded, outputDir)
+	}
+	if filepath.Dir(uploaded) != filepath.Join(outputDir, "replay") {
+		t.Fatalf("expected upload in %s, got %s", filepath.Join(outputDir, "replay"), uploaded)
+	}
+	data, err := os.ReadFile(uploaded)
+	if err != nil {
+		t.Fatalf("read upload: %v", err)
+	}
+	if len(data) != len(pcapData) {
+		t.Fatalf("expected %d bytes written, got %d", len(pcapData), len(data))
+	}
+}
+
+func TestServerReplayUploadsListAndCleanup(t *testing.T) {
+	server, _ := newTestServer(t)
+	stub := &stubReplay{state: ReplayState{}}
+	server.cfg.Replay = stub
+	server.cfg.OutputDir = t.TempDir()
+	server.cfg.UploadMaxAge = time.Hour
+
+	pcapData := []byte{
+		0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+	}
+	body, _ := json.Marshal(map[string]string{"data": base64.StdEncoding.EncodeToString(pcapData)})
+	rec := httptest.NewRecorder()
+	server.handleReplay(rec, httptest.NewRequest(http.MethodPost, "/api/v1/replay", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	uploaded := stub.startReq.File
+
+	list := func() []UploadInfo {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.handleReplayUploads(rec, httptest.NewRequest(http.MethodGet, "/api/v1/replay/uploads", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp UploadsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		return resp.Uploads
+	}
+
+	uploads := list()
+	if len(uploads) != 1 || uploads[0].Name != filepath.Base(uploaded) || uploads[0].SizeBytes != int64(len(pcapData)) || !uploads[0].InUse {
+		t.Fatalf("uploads = %+v, want the in-use upload %s of %d bytes", uploads, filepath.Base(uploaded), len(pcapData))
+	}
+
+	// Neither a fresh upload nor one being replayed is cleaned up
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(uploaded, old, old); err != nil {
+		t.Fatalf("age upload: %v", err)
+	}
+	if removed, err := server.removeUploads(server.cfg.UploadMaxAge, time.Now()); err != nil || len(removed) != 0 {
+		t.Fatalf("cleanup during replay removed %v (%v), want nothing", removed, err)
+	}
+
+	// Once the replay stops, cleanup removes it past the age threshold
+	_, _ = stub.Stop(DefaultReplayName)
+	removed, err := server.removeUploads(server.cfg.UploadMaxAge, time.Now())
+	if err != nil || len(removed) != 1 || removed[0] != filepath.Base(uploaded) {
+		t.Fatalf("cleanup removed %v (%v), want %s", removed, err, filepath.Base(uploaded))
+	}
+	if _, err := os.Stat(uploaded); !os.IsNotExist(err) {
+		t.Errorf("upload still on disk after cleanup: %v", err)
+	}
+	if uploads := list(); len(uploads) != 0 {
+		t.Errorf("uploads after cleanup = %+v, want none", uploads)
+	}
+
+	// Names outside the upload directory are refused
+	rec = httptest.NewRecorder()
+	server.handleReplayUploads(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/replay/uploads?name=../niac.db", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("delete ../niac.db: expected 400, got %d", rec.Code)
+	}
+}
+
 func TestServerHandleFilesWalks(t *testing.T) {
 	server, _ := newTestServer(t)
 	includeDir := t.TempDir()
@@ -302,3 +669,573 @@ func strconvJSON(s string) string {
 	b, _ := json.Marshal(s)
 	return string(b)
 }
+
+const taggedConfigYAML = `
+devices:
+  - name: edge1
+    mac: "00:11:22:33:44:01"
+    ips: ["10.0.0.11"]
+    tags: [edge, building-a]
+  - name: edge2
+    mac: "00:11:22:33:44:02"
+    ips: ["10.0.0.12"]
+    tags: [edge]
+  - name: core1
+    mac: "00:11:22:33:44:03"
+    ips: ["10.0.0.13"]
+    tags: [building-a]
+`
+
+func TestServerHandleStatsReset(t *testing.T) {
+	server, _ := newTestServer(t)
+	stack := server.cfg.Stack
+	count := func(n int) {
+		for i := 0; i < n; i++ {
+			stack.IncrementStat("arp_requests")
+			stack.IncrementStat("dhcp_requests")
+		}
+	}
+	stackStats := func(rec *httptest.ResponseRecorder) map[string]uint64 {
+		t.Helper()
+		var resp struct {
+			Stack map[string]uint64 `json:"stack"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode stats: %v", err)
+		}
+		return resp.Stack
+	}
+
+	// GET ?reset=true returns the counters, then zeroes them
+	count(3)
+	rec := httptest.NewRecorder()
+	server.handleStats(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats?reset=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := stackStats(rec); got["arp_requests"] != 3 || got["dhcp_requests"] != 3 {
+		t.Errorf("reset response = %v, want the counters before the reset", got)
+	}
+	rec = httptest.NewRecorder()
+	server.handleStats(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil))
+	for name, value := range stackStats(rec) {
+		if value != 0 {
+			t.Errorf("%s = %d after reset, want 0", name, value)
+		}
+	}
+
+	// DELETE zeroes them
+	count(2)
+	rec = httptest.NewRecorder()
+	server.handleStats(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/stats", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stats := stack.GetStats(); stats.ARPRequests != 0 || stats.DHCPRequests != 0 {
+		t.Errorf("arp_requests=%d dhcp_requests=%d after DELETE, want zero", stats.ARPRequests, stats.DHCPRequests)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleStats(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats?reset=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid reset value, got %d", rec.Code)
+	}
+}
+
+func TestServerHandleLeaseReconfigure(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	for _, tc := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/api/v1/leases/00:03:00:01:00:aa:bb:cc:dd:01/reconfigure", "", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/leases/zz/reconfigure", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/leases/0003000100aabbccdd01/reconfigure", `{"message_type":"solicit"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/leases/0003000100aabbccdd01/reconfigure", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/v1/leases/0003000100aabbccdd01", "", http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		server.handleLease(rec, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s %s: got %d, want %d", tc.method, tc.path, tc.body, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestServerHandleBulkPowerOffTagGroup(t *testing.T) {
+	cfg := mustLoadConfig(t, taggedConfigYAML)
+	stack := protocols.NewStack(nil, cfg, logging.NewDebugConfig(0))
+	server := &Server{cfg: ServerConfig{Stack: stack, Config: cfg}}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bulk/power", strings.NewReader(`{"tag":"edge","state":"off"}`))
+	server.handleBulkPower(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		name    string
+		ip      string
+		powered bool
+	}{
+		{"edge1", "10.0.0.11", false},
+		{"edge2", "10.0.0.12", false},
+		{"core1", "10.0.0.13", true},
+	} {
+		if got := stack.IsDevicePowered(tc.name); got != tc.powered {
+			t.Errorf("%s powered = %v, want %v", tc.name, got, tc.powered)
+		}
+		found := len(stack.GetDevices().GetByIP(net.ParseIP(tc.ip))) > 0
+		if found != tc.powered {
+			t.Errorf("%s reachable at %s = %v, want %v", tc.name, tc.ip, found, tc.powered)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleDevices(rec, httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil))
+	var devices []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &devices); err != nil {
+		t.Fatalf("decode devices: %v", err)
+	}
+	for _, dev := range devices {
+		if dev["name"] == "core1" && dev["powered"] != true {
+			t.Errorf("expected core1 to remain powered, got %v", dev["powered"])
+		}
+		if dev["name"] == "edge1" {
+			tags, _ := dev["tags"].([]interface{})
+			if len(tags) != 2 || tags[0] != "edge" || tags[1] != "building-a" {
+				t.Errorf("unexpected edge1 tags: %v", dev["tags"])
+			}
+		}
+	}
+}
+
+func TestServerHandleDeviceState(t *testing.T) {
+	cfg := mustLoadConfig(t, taggedConfigYAML)
+	stack := protocols.NewStack(nil, cfg, logging.NewDebugConfig(0))
+	server := &Server{cfg: ServerConfig{Stack: stack, Config: cfg}}
+
+	setState := func(method, device, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.handleDevice(rec, httptest.NewRequest(method, "/api/v1/devices/"+device+"/state", strings.NewReader(body)))
+		return rec
+	}
+	enabledStates := func() map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.handleDevices(rec, httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil))
+		var devices []map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &devices); err != nil {
+			t.Fatalf("decode devices: %v", err)
+		}
+		states := make(map[string]interface{})
+		for _, dev := range devices {
+			states[dev["name"].(string)] = dev["enabled"]
+		}
+		return states
+	}
+
+	if states := enabledStates(); states["core1"] != true || states["edge1"] != true {
+		t.Fatalf("expected devices enabled by default, got %v", states)
+	}
+
+	rec := setState(http.MethodPost, "core1", `{"enabled":false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stack.IsDeviceEnabled("core1") {
+		t.Error("expected core1 disabled")
+	}
+	if len(stack.GetDevices().GetByIP(net.ParseIP("10.0.0.13"))) != 0 {
+		t.Error("expected disabled core1 to leave the device table")
+	}
+	if states := enabledStates(); states["core1"] != false || states["edge1"] != true {
+		t.Errorf("expected only core1 reported disabled, got %v", states)
+	}
+
+	if rec := setState(http.MethodPost, "core1", `{"enabled":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(stack.GetDevices().GetByIP(net.ParseIP("10.0.0.13"))) == 0 {
+		t.Error("expected re-enabled core1 back in the device table")
+	}
+	if states := enabledStates(); states["core1"] != true {
+		t.Errorf("expected core1 reported enabled, got %v", states["core1"])
+	}
+
+	for _, tc := range []struct {
+		method string
+		device string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, "core1", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "core1", `not json`, http.StatusBadRequest},
+		{http.MethodPost, "missing", `{"enabled":false}`, http.StatusNotFound},
+		{http.MethodGet, "core1", "", http.StatusMethodNotAllowed},
+	} {
+		if rec := setState(tc.method, tc.device, tc.body); rec.Code != tc.want {
+			t.Errorf("%s %s %s: status %d, want %d", tc.method, tc.device, tc.body, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestAccessLogRecordsStatusAndDuration(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.cfg.Token = "secret"
+	server.rateLimiter = NewRateLimiter(DefaultRateLimit, DefaultBurst)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	handler := accessLog(server.auth(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(rec, req)
+
+	line := buf.String()
+	for _, want := range []string{"GET /api/v1/stats", "status=202", "bytes=2", "principal=token", rec.Header().Get("X-Request-ID")} {
+		if !strings.Contains(line, want) {
+			t.Errorf("access log %q missing %q", line, want)
+		}
+	}
+
+	idx := strings.Index(line, "duration=")
+	if idx < 0 {
+		t.Fatalf("access log %q missing duration", line)
+	}
+	durationField := strings.Fields(line[idx+len("duration="):])[0]
+	duration, err := time.ParseDuration(durationField)
+	if err != nil || duration <= 0 {
+		t.Errorf("expected nonzero duration, got %q (%v)", durationField, err)
+	}
+}
+
+func TestServerRateLimitConfig(t *testing.T) {
+	// allowed counts requests from one client that pass before the first 429
+	allowed := func(rateLimit float64, burst int) int {
+		server := NewServer(ServerConfig{RateLimit: rateLimit, RateBurst: burst})
+		handler := server.auth(func(w http.ResponseWriter, r *http.Request) {})
+		for i := 0; i < 500; i++ {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil))
+			if rec.Code == http.StatusTooManyRequests {
+				return i
+			}
+		}
+		return 500
+	}
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	if got := allowed(1, 5); got != 5 {
+		t.Errorf("burst 5 allowed %d requests before 429, want 5", got)
+	}
+	if got := allowed(1, 50); got != 50 {
+		t.Errorf("burst 50 allowed %d requests before 429, want 50", got)
+	}
+	if got := allowed(DefaultRateLimit, DefaultBurst); got < DefaultBurst || got == 500 {
+		t.Errorf("default limits allowed %d requests before 429, want about %d", got, DefaultBurst)
+	}
+	if got := allowed(0, DefaultBurst); got != 500 {
+		t.Errorf("rate 0 rejected request %d, want rate limiting disabled", got+1)
+	}
+	if got := allowed(DefaultRateLimit, 0); got != 500 {
+		t.Errorf("burst 0 rejected request %d, want rate limiting disabled", got+1)
+	}
+}
+
+func TestServerHandleTrafficPlan(t *testing.T) {
+	server, _ := newTestServer(t)
+	tomorrow := (time.Now().Weekday() + 1) % 7
+	server.cfg.Config.Devices[0].TrafficConfig = &config.TrafficConfig{
+		Enabled:          true,
+		ARPAnnouncements: &config.ARPAnnouncementConfig{Enabled: true, Interval: 60},
+		RandomTraffic: &config.RandomTrafficConfig{
+			Enabled:  true,
+			Interval: 180,
+			Schedule: &config.TrafficSchedule{Days: []time.Weekday{tomorrow}},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleTraffic(rec, httptest.NewRequest(http.MethodGet, "/api/v1/traffic", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var plan []TrafficPatternPlan
+	if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil {
+		t.Fatalf("decode plan: %v", err)
+	}
+	if len(plan) != 2 {
+		t.Fatalf("expected 2 patterns, got %+v", plan)
+	}
+	if plan[0].Pattern != "arp_announcements" || !plan[0].Active {
+		t.Errorf("expected unscheduled ARP announcements active, got %+v", plan[0])
+	}
+	if plan[1].Pattern != "random_traffic" || plan[1].Active || plan[1].Schedule == "" {
+		t.Errorf("expected random traffic inactive outside its schedule, got %+v", plan[1])
+	}
+}
+
+// recordingWriter stands in for a pcap handle and keeps every frame sent, or
+// fails every send while err is set
+type recordingWriter struct {
+	frames [][]byte
+	err    error
+}
+
+func (w *recordingWriter) WritePacketData(data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.frames = append(w.frames, append([]byte(nil), data...))
+	return nil
+}
+
+func TestServerHandleDeviceInject(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	cfg := mustLoadConfig(t, baseConfigYAML)
+	writer := &recordingWriter{}
+	engine := capture.NewWithWriter("test0", writer, 0)
+	stack := protocols.NewStack(engine, cfg, logging.NewDebugConfig(0))
+	server := &Server{cfg: ServerConfig{Stack: stack, Config: cfg}}
+
+	// A runt LLC frame no protocol handler would build
+	frame := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // Destination
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, // Source
+		0x00, 0x03, // 802.3 length
+		0xaa, 0xaa, 0x03,
+	}
+	inject := func(device, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/devices/"+device+"/inject", strings.NewReader(body))
+		req.Header.Set("X-Request-ID", "req-1")
+		server.handleDevice(rec, req)
+		return rec
+	}
+
+	rec := inject("core1", fmt.Sprintf(`{"frame":%q}`, base64.StdEncoding.EncodeToString(frame)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(writer.frames) != 1 || !bytes.Equal(writer.frames[0], frame) {
+		t.Fatalf("expected the frame written unchanged, got %x", writer.frames)
+	}
+	if !strings.Contains(logs.String(), "AUDIT frame injected device=core1 bytes=17 dst=ff:ff:ff:ff:ff:ff ethertype=0x0003") {
+		t.Errorf("expected an audit log line, got %q", logs.String())
+	}
+
+	for _, tc := range []struct {
+		device string
+		body   string
+		want   int
+	}{
+		{"core1", `{"frame":"not base64!"}`, http.StatusBadRequest},
+		{"core1", fmt.Sprintf(`{"frame":%q}`, base64.StdEncoding.EncodeToString(frame[:13])), http.StatusBadRequest},
+		{"core1", fmt.Sprintf(`{"frame":%q}`, base64.StdEncoding.EncodeToString(make([]byte, protocols.MaxInjectFrameSize+1))), http.StatusBadRequest},
+		{"missing", fmt.Sprintf(`{"frame":%q}`, base64.StdEncoding.EncodeToString(frame)), http.StatusNotFound},
+	} {
+		if rec := inject(tc.device, tc.body); rec.Code != tc.want {
+			t.Errorf("inject %s %.40s: status %d, want %d", tc.device, tc.body, rec.Code, tc.want)
+		}
+	}
+
+	// A disabled or powered-off device cannot transmit
+	if err := stack.SetDeviceEnabled("core1", false); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if rec := inject("core1", fmt.Sprintf(`{"frame":%q}`, base64.StdEncoding.EncodeToString(frame))); rec.Code != http.StatusConflict {
+		t.Errorf("inject from disabled device: status %d, want 409", rec.Code)
+	}
+	if err := stack.SetDeviceEnabled("core1", true); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	if err := stack.SetDevicePower("core1", false); err != nil {
+		t.Fatalf("power off: %v", err)
+	}
+	if rec := inject("core1", fmt.Sprintf(`{"frame":%q}`, base64.StdEncoding.EncodeToString(frame))); rec.Code != http.StatusConflict {
+		t.Errorf("inject from powered-off device: status %d, want 409", rec.Code)
+	}
+	if len(writer.frames) != 1 {
+		t.Errorf("expected only the first frame sent, got %d", len(writer.frames))
+	}
+}
+
+func TestHandleErrorPackets(t *testing.T) {
+	server, _ := newTestServer(t)
+	stack := server.cfg.Stack
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01},
+		DstMAC:       net.HardwareAddr{0x33, 0x33, 0x00, 0x01, 0x00, 0x02},
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ipv6 := &layers.IPv6{Version: 6, HopLimit: 1, NextHeader: layers.IPProtocolUDP, SrcIP: net.ParseIP("fe80::2"), DstIP: net.ParseIP("ff02::1:2")}
+	udp := &layers.UDP{SrcPort: protocols.DHCPv6ClientPort, DstPort: protocols.DHCPv6ServerPort}
+	_ = udp.SetNetworkLayerForChecksum(ipv6)
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true},
+		eth, ipv6, udp, gopacket.Payload{0x01}); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	frame := buf.Bytes()
+	decoded := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+	stack.GetDHCPv6Handler().HandlePacket(&protocols.Packet{Buffer: frame, Length: len(frame)},
+		decoded.Layer(layers.LayerTypeIPv6).(*layers.IPv6), decoded.Layer(layers.LayerTypeUDP).(*layers.UDP), nil)
+
+	rec := httptest.NewRecorder()
+	server.handleErrorPackets(rec, httptest.NewRequest(http.MethodGet, "/api/v1/errors/packets", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp ErrorPacketsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Packets) != 1 {
+		t.Fatalf("expected 1 error packet, got %d", len(resp.Packets))
+	}
+	got := resp.Packets[0]
+	if got.Protocol != "dhcpv6" || !strings.Contains(got.Error, "too short") || got.Summary == "" {
+		t.Errorf("unexpected error packet %+v", got)
+	}
+	if data, err := base64.StdEncoding.DecodeString(got.Data); err != nil || !bytes.Equal(data, frame) {
+		t.Errorf("data does not decode to the frame bytes: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleErrorPackets(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/errors/packets", nil))
+	if rec.Code != http.StatusNoContent || len(stack.ErrorPackets()) != 0 {
+		t.Errorf("expected DELETE to empty the buffer: %d, %d left", rec.Code, len(stack.ErrorPackets()))
+	}
+}
+
+func TestServeSPAFromUIDir(t *testing.T) {
+	server, _ := newTestServer(t)
+	base := t.TempDir()
+	uiDir := filepath.Join(base, "ui")
+	if err := os.Mkdir(uiDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	server.cfg.UIDir = uiDir
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.serveSPA()(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	for i, content := range []string{"console.log('v1')", "console.log('v2')"} {
+		if err := os.WriteFile(filepath.Join(uiDir, "app.js"), []byte(content), 0o600); err != nil {
+			t.Fatalf("write app.js: %v", err)
+		}
+		rec := get("/app.js")
+		if rec.Code != http.StatusOK || rec.Body.String() != content {
+			t.Fatalf("edit %d: expected %q, got %d %q", i, content, rec.Code, rec.Body.String())
+		}
+		if ctype := rec.Header().Get("Content-Type"); !strings.Contains(ctype, "javascript") {
+			t.Errorf("edit %d: unexpected Content-Type %q", i, ctype)
+		}
+	}
+
+	if rec := get("/../secret.txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected traversal out of the UI dir to be refused, got %d %q", rec.Code, rec.Body.String())
+	}
+	// Without index.html there is nothing to fall back to
+	if rec := get("/devices"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown route without index.html, got %d", rec.Code)
+	}
+	if err := os.WriteFile(filepath.Join(uiDir, "index.html"), []byte("<html>dev</html>"), 0o600); err != nil {
+		t.Fatalf("write index.html: %v", err)
+	}
+	if rec := get("/devices"); rec.Code != http.StatusOK || rec.Body.String() != "<html>dev</html>" {
+		t.Errorf("expected the SPA fallback from the UI dir, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// TestServerHandleMetricsSNMPDuration tests that SNMP request durations are
+// scraped from /metrics as a Prometheus histogram
+func TestServerHandleMetricsSNMPDuration(t *testing.T) {
+	server, _ := newTestServer(t)
+	durations, err := stats.NewHistogram([]time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewHistogram: %v", err)
+	}
+	server.cfg.SNMPDurations = durations
+	snmp.SetRequestDurationObserver(durations)
+	t.Cleanup(func() { snmp.SetRequestDurationObserver(nil) })
+
+	agent := snmp.NewAgent(&server.cfg.Config.Devices[0], 0)
+	agent.ProcessPDU(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.1.5.0"}}, 0)
+	durations.Observe(50 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	server.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want 200", rec.Code)
+	}
+
+	const name = "niac_snmp_request_duration_seconds"
+	sampleLine := regexp.MustCompile(`^` + name + `(_bucket\{le="([^"]+)"\}|_sum|_count) (\S+)$`)
+	var typed bool
+	var les []string
+	buckets := make(map[string]float64)
+	var sum, count float64
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if line == "# TYPE "+name+" histogram" {
+			typed = true
+			continue
+		}
+		match := sampleLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			t.Fatalf("Invalid sample value in %q", line)
+		}
+		switch match[1] {
+		case "_sum":
+			sum = value
+		case "_count":
+			count = value
+		default:
+			les = append(les, match[2])
+			buckets[match[2]] = value
+		}
+	}
+
+	if !typed {
+		t.Fatalf("No histogram TYPE line for %s in:\n%s", name, rec.Body.String())
+	}
+	if want := []string{"0.001", "0.01", "0.1", "+Inf"}; strings.Join(les, ",") != strings.Join(want, ",") {
+		t.Fatalf("Bucket bounds %v, want %v", les, want)
+	}
+	if count != 2 || buckets["+Inf"] != 2 {
+		t.Errorf("Count %v, +Inf bucket %v, want 2 requests", count, buckets["+Inf"])
+	}
+	if buckets["0.01"] < 1 || buckets["0.1"] != 2 || buckets["0.01"] > buckets["0.1"] || buckets["0.001"] > buckets["0.01"] {
+		t.Errorf("Buckets %v, want cumulative counts with the 50ms request only in le=0.1", buckets)
+	}
+	if sum < 0.05 {
+		t.Errorf("Sum %v, want at least the 0.05s observed", sum)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ReadTokenFile reads the API bearer token from a file, trimming surrounding
+// whitespace such as a trailing newline. An empty file yields an empty token,
+// which disables authentication when the server starts.
+func ReadTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read API token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SetToken replaces the bearer token requests must carry, so a rotated token
+// file takes effect without a restart. An empty token is refused and the
+// current one kept: a token file emptied or half-written during rotation must
+// not turn authentication off. Only the startup config can run without a token.
+func (s *Server) SetToken(token string) error {
+	if token == "" {
+		return fmt.Errorf("refusing to replace the API token with an empty one")
+	}
+	s.tokenMu.Lock()
+	s.cfg.Token = token
+	s.tokenMu.Unlock()
+	return nil
+}
+
+// token returns the bearer token requests must carry ("" = no authentication)
+func (s *Server) token() string {
+	s.tokenMu.RLock()
+	defer s.tokenMu.RUnlock()
+	return s.cfg.Token
+}
+
+// warnUnauthenticated warns that the API accepts requests without a token
+func (s *Server) warnUnauthenticated() {
+	if s.cfg.Addr == "" {
+		return
+	}
+	// SECURITY FIX #107: Warn if API is running without authentication
+	log.Println("⚠️  WARNING: API server running WITHOUT authentication!")
+	log.Println("    All endpoints are publicly accessible without any access control.")
+	log.Println("    Set NIAC_API_TOKEN environment variable to enable authentication.")
+	log.Println("    Example: export NIAC_API_TOKEN=$(openssl rand -base64 32)")
+}
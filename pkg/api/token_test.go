@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTokenFileAuthentication(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenPath, []byte("  s3cret-token\n"), 0o600); err != nil {
+		t.Fatalf("write token: %v", err)
+	}
+	token, err := ReadTokenFile(tokenPath)
+	if err != nil {
+		t.Fatalf("ReadTokenFile: %v", err)
+	}
+	if token != "s3cret-token" {
+		t.Fatalf("token = %q, want whitespace trimmed", token)
+	}
+
+	server := NewServer(ServerConfig{Addr: ":0", Token: token})
+	handler := server.auth(func(w http.ResponseWriter, r *http.Request) {})
+	status := func(bearer string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		handler(rec, req)
+		return rec.Code
+	}
+
+	if got := status("s3cret-token"); got != http.StatusOK {
+		t.Errorf("token from file: status %d, want 200", got)
+	}
+	if got := status(""); got != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", got)
+	}
+
+	// Rotation: the old token stops working once the new one is set
+	if err := server.SetToken("rotated"); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	if got := status("s3cret-token"); got != http.StatusUnauthorized {
+		t.Errorf("old token after rotation: status %d, want 401", got)
+	}
+	if got := status("rotated"); got != http.StatusOK {
+		t.Errorf("rotated token: status %d, want 200", got)
+	}
+	if strings.Contains(buf.String(), "WITHOUT authentication") {
+		t.Errorf("unexpected no-auth warning with a token set: %q", buf.String())
+	}
+
+	// An emptied file cannot turn authentication off on a running server
+	emptyPath := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyPath, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("write empty token: %v", err)
+	}
+	token, err = ReadTokenFile(emptyPath)
+	if err != nil || token != "" {
+		t.Fatalf("ReadTokenFile(empty) = %q, %v; want empty token", token, err)
+	}
+	if err := server.SetToken(token); err == nil {
+		t.Error("SetToken accepted an empty token")
+	}
+	if got := status(""); got != http.StatusUnauthorized {
+		t.Errorf("no token after an empty rotation: status %d, want 401", got)
+	}
+	if got := status("rotated"); got != http.StatusOK {
+		t.Errorf("kept token after an empty rotation: status %d, want 200", got)
+	}
+
+	if _, err := ReadTokenFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for a missing token file")
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/krisarmstrong/niac-go/pkg/config"
@@ -42,11 +43,13 @@ func BuildTopology(cfg *config.Config) Topology {
 
 	// Create interface lookup map for speed/duplex/status info
 	interfaceMap := make(map[string]map[string]config.Interface)
-	for _, dev := range cfg.Devices {
+	deviceMap := make(map[string]*config.Device, len(cfg.Devices))
+	for i, dev := range cfg.Devices {
 		interfaceMap[dev.Name] = make(map[string]config.Interface)
 		for _, iface := range dev.Interfaces {
 			interfaceMap[dev.Name][iface.Name] = iface
 		}
+		deviceMap[dev.Name] = &cfg.Devices[i]
 	}
 
 	for _, dev := range cfg.Devices {
@@ -66,10 +69,15 @@ func BuildTopology(cfg *config.Config) Topology {
 				}
 			}
 
+			remoteInterface := trunk.RemoteInterface
+			if remoteInterface == "" {
+				remoteInterface = resolveRemoteInterface(deviceMap[trunk.RemoteDevice], dev.Name, trunk.Interface)
+			}
+
 			// Build label with VLAN info
 			label := trunk.Interface
-			if trunk.RemoteInterface != "" {
-				label += " ↔ " + trunk.RemoteInterface
+			if remoteInterface != "" {
+				label += " ↔ " + remoteInterface
 			}
 			if len(trunk.VLANs) > 0 {
 				label += fmt.Sprintf(" (VLANs: %s)", formatVLANList(trunk.VLANs))
@@ -104,7 +112,7 @@ func BuildTopology(cfg *config.Config) Topology {
 				Target:          trunk.RemoteDevice,
 				Label:           label,
 				SourceInterface: trunk.Interface,
-				TargetInterface: trunk.RemoteInterface,
+				TargetInterface: remoteInterface,
 				LinkType:        linkType,
 				VLANs:           trunk.VLANs,
 				NativeVLAN:      trunk.NativeVLAN,
@@ -126,6 +134,80 @@ func BuildTopology(cfg *config.Config) Topology {
 	return topology
 }
 
+// resolveRemoteInterface finds the far-end interface of a trunk that does not
+// name it: first from the remote device's trunk pointing back at us, then from
+// the port ID the remote device advertises in LLDP (its first interface).
+func resolveRemoteInterface(remote *config.Device, localDevice, localInterface string) string {
+	if remote == nil {
+		return ""
+	}
+
+	var fallback string
+	for _, trunk := range remote.TrunkPorts {
+		if trunk.RemoteDevice != localDevice || trunk.Interface == "" {
+			continue
+		}
+		if trunk.RemoteInterface == localInterface {
+			return trunk.Interface
+		}
+		if fallback == "" && trunk.RemoteInterface == "" {
+			fallback = trunk.Interface
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+
+	if len(remote.Interfaces) > 0 {
+		return remote.Interfaces[0].Name
+	}
+	return ""
+}
+
+// TopologyDiff lists links present in only one of two topologies. Links are
+// compared by their interface-level endpoints, so recabling a device onto a
+// different port shows up as one removed and one added link.
+type TopologyDiff struct {
+	Added   []TopologyLink `json:"added"`
+	Removed []TopologyLink `json:"removed"`
+}
+
+// DiffTopology compares the links of two topologies.
+func DiffTopology(before, after Topology) TopologyDiff {
+	beforeKeys := make(map[string]bool, len(before.Links))
+	for _, link := range before.Links {
+		beforeKeys[link.Endpoints()] = true
+	}
+	afterKeys := make(map[string]bool, len(after.Links))
+	for _, link := range after.Links {
+		afterKeys[link.Endpoints()] = true
+	}
+
+	diff := TopologyDiff{Added: []TopologyLink{}, Removed: []TopologyLink{}}
+	for _, link := range after.Links {
+		if !beforeKeys[link.Endpoints()] {
+			diff.Added = append(diff.Added, link)
+		}
+	}
+	for _, link := range before.Links {
+		if !afterKeys[link.Endpoints()] {
+			diff.Removed = append(diff.Removed, link)
+		}
+	}
+	return diff
+}
+
+// Endpoints returns the link as "device:interface -- device:interface", with
+// the two ends in a stable order so a link matches its reverse.
+func (l TopologyLink) Endpoints() string {
+	ends := []string{
+		l.Source + ":" + l.SourceInterface,
+		l.Target + ":" + l.TargetInterface,
+	}
+	sort.Strings(ends)
+	return ends[0] + " -- " + ends[1]
+}
+
 // formatVLANList formats a list of VLANs for display (e.g., "1-5,10,20")
 func formatVLANList(vlans []int) string {
 	if len(vlans) == 0 {
@@ -243,8 +325,9 @@ func (t *Topology) ExportDOT() string {
 			label += fmt.Sprintf("\\n%dMbps", link.Speed)
 		}
 
-		sb.WriteString(fmt.Sprintf("  \"%s\" -- \"%s\" [label=\"%s\", style=%s, color=%s];\n",
-			escapeDOT(link.Source), escapeDOT(link.Target), label, style, color))
+		sb.WriteString(fmt.Sprintf("  \"%s\" -- \"%s\" [label=\"%s\", taillabel=\"%s\", headlabel=\"%s\", style=%s, color=%s];\n",
+			escapeDOT(link.Source), escapeDOT(link.Target), label,
+			escapeDOT(link.SourceInterface), escapeDOT(link.TargetInterface), style, color))
 	}
 
 	sb.WriteString("}\n")
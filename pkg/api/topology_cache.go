@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+const (
+	// topologyRefreshInterval is how often the cached topology is checked
+	// against the discovered neighbor table
+	topologyRefreshInterval = 2 * time.Second
+
+	// topologyRebuildTimeout bounds a background rebuild; on timeout the
+	// previous topology keeps being served
+	topologyRebuildTimeout = 2 * time.Second
+)
+
+// topologySnapshot is an immutable merged topology and what it was built from.
+// Readers load it atomically and never take a lock.
+type topologySnapshot struct {
+	topology         Topology
+	config           *config.Config
+	stack            *protocols.Stack
+	neighborsVersion uint64
+}
+
+// MergeNeighbors adds links learned through LLDP/CDP/EDP/FDP to a declared
+// topology. Declared links between the same devices take precedence; remote
+// devices that are not configured appear as "discovered" nodes. It returns the
+// context error if ctx ends before the merge completes.
+func MergeNeighbors(ctx context.Context, declared Topology, neighbors []protocols.NeighborRecord) (Topology, error) {
+	merged := Topology{
+		Nodes: append(make([]TopologyNode, 0, len(declared.Nodes)), declared.Nodes...),
+		Links: append(make([]TopologyLink, 0, len(declared.Links)), declared.Links...),
+	}
+
+	nodes := make(map[string]bool, len(merged.Nodes))
+	for _, node := range merged.Nodes {
+		nodes[node.Name] = true
+	}
+	connected := make(map[[2]string]bool, len(merged.Links))
+	for _, link := range merged.Links {
+		connected[[2]string{link.Source, link.Target}] = true
+		connected[[2]string{link.Target, link.Source}] = true
+	}
+
+	// Stable output regardless of neighbor table ordering
+	sorted := append([]protocols.NeighborRecord(nil), neighbors...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.LocalDevice != b.LocalDevice {
+			return a.LocalDevice < b.LocalDevice
+		}
+		if a.RemoteChassisID != b.RemoteChassisID {
+			return a.RemoteChassisID < b.RemoteChassisID
+		}
+		if a.RemotePort != b.RemotePort {
+			return a.RemotePort < b.RemotePort
+		}
+		return a.Protocol < b.Protocol
+	})
+
+	seen := make(map[string]bool)
+	for _, neighbor := range sorted {
+		if err := ctx.Err(); err != nil {
+			return declared, err
+		}
+
+		remote := neighbor.RemoteDevice
+		if remote == "" {
+			remote = neighbor.RemoteChassisID
+		}
+		if neighbor.LocalDevice == "" || remote == "" || connected[[2]string{neighbor.LocalDevice, remote}] {
+			continue
+		}
+
+		link := TopologyLink{
+			Source:          neighbor.LocalDevice,
+			Target:          remote,
+			Label:           neighbor.Protocol,
+			TargetInterface: neighbor.RemotePort,
+			LinkType:        "discovered",
+			Status:          "up",
+		}
+		if neighbor.RemotePort != "" {
+			link.Label += " " + neighbor.RemotePort
+		}
+		// The same neighbor is often heard over several protocols
+		if seen[link.Endpoints()] {
+			continue
+		}
+		seen[link.Endpoints()] = true
+
+		if !nodes[remote] {
+			nodes[remote] = true
+			merged.Nodes = append(merged.Nodes, TopologyNode{Name: remote, Type: "discovered"})
+		}
+		merged.Links = append(merged.Links, link)
+	}
+
+	return merged, nil
+}
+
+// currentTopology returns the cached merged topology, building it on first use.
+func (s *Server) currentTopology() Topology {
+	if snapshot := s.topology.Load(); snapshot != nil {
+		return snapshot.topology
+	}
+	if err := s.rebuildTopology(context.Background()); err != nil {
+		log.Printf("topology rebuild failed: %v", err)
+	}
+	if snapshot := s.topology.Load(); snapshot != nil {
+		return snapshot.topology
+	}
+	return Topology{Nodes: []TopologyNode{}, Links: []TopologyLink{}}
+}
+
+// invalidateTopology rebuilds the cached topology after the configuration or
+// simulation changes. Callers must not hold configMu.
+func (s *Server) invalidateTopology() {
+	if err := s.rebuildTopology(context.Background()); err != nil {
+		log.Printf("topology rebuild failed: %v", err)
+	}
+}
+
+// rebuildTopology merges the declared topology with discovered neighbors and
+// swaps the result in. Rebuilds are serialized; readers keep the previous
+// snapshot until the new one is stored.
+func (s *Server) rebuildTopology(ctx context.Context) error {
+	s.topologyBuildMu.Lock()
+	defer s.topologyBuildMu.Unlock()
+
+	s.configMu.RLock()
+	cfg := s.cfg.Config
+	stack := s.cfg.Stack
+	s.configMu.RUnlock()
+
+	snapshot := &topologySnapshot{config: cfg, stack: stack}
+	if cfg == nil {
+		snapshot.topology = Topology{Nodes: []TopologyNode{}, Links: []TopologyLink{}}
+		s.topology.Store(snapshot)
+		return nil
+	}
+
+	declared := BuildTopology(cfg)
+	if stack == nil {
+		snapshot.topology = declared
+		s.topology.Store(snapshot)
+		return nil
+	}
+
+	// Read the version first so a change during the merge triggers another rebuild
+	snapshot.neighborsVersion = stack.NeighborsVersion()
+	merged, err := MergeNeighbors(ctx, declared, stack.GetNeighbors())
+	if err != nil {
+		return err
+	}
+	snapshot.topology = merged
+	s.topology.Store(snapshot)
+	return nil
+}
+
+// topologyStale reports whether the cached topology no longer reflects the
+// current configuration, stack or neighbor table.
+func (s *Server) topologyStale() bool {
+	snapshot := s.topology.Load()
+	if snapshot == nil {
+		return true
+	}
+
+	s.configMu.RLock()
+	cfg := s.cfg.Config
+	stack := s.cfg.Stack
+	s.configMu.RUnlock()
+
+	if snapshot.config != cfg || snapshot.stack != stack {
+		return true
+	}
+	return stack != nil && stack.NeighborsVersion() != snapshot.neighborsVersion
+}
+
+// runTopologyRefresher rebuilds the cached topology when discovered neighbors
+// change, until stop is closed.
+func (s *Server) runTopologyRefresher(stop <-chan struct{}) {
+	ticker := time.NewTicker(topologyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !s.topologyStale() {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), topologyRebuildTimeout)
+			if err := s.rebuildTopology(ctx); err != nil {
+				log.Printf("topology rebuild abandoned, serving previous topology: %v", err)
+			}
+			cancel()
+		}
+	}
+}
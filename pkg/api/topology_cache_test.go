@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+func TestMergeNeighbors(t *testing.T) {
+	declared := Topology{
+		Nodes: []TopologyNode{{Name: "core-sw", Type: "switch"}, {Name: "dist-sw", Type: "switch"}},
+		Links: []TopologyLink{{Source: "core-sw", SourceInterface: "Gi1/0/1", Target: "dist-sw", TargetInterface: "Gi0/48"}},
+	}
+	neighbors := []protocols.NeighborRecord{
+		{Protocol: protocols.ProtocolLLDP, LocalDevice: "dist-sw", RemoteDevice: "core-sw", RemoteChassisID: "00:11", RemotePort: "Gi1/0/1"},
+		{Protocol: protocols.ProtocolLLDP, LocalDevice: "core-sw", RemoteDevice: "ap-1", RemoteChassisID: "00:22", RemotePort: "eth0"},
+		{Protocol: protocols.ProtocolCDP, LocalDevice: "core-sw", RemoteDevice: "ap-1", RemoteChassisID: "00:22", RemotePort: "eth0"},
+		{Protocol: protocols.ProtocolLLDP, LocalDevice: "core-sw", RemoteChassisID: "00:33", RemotePort: "1"},
+	}
+
+	merged, err := MergeNeighbors(context.Background(), declared, neighbors)
+	if err != nil {
+		t.Fatalf("MergeNeighbors failed: %v", err)
+	}
+	if len(merged.Links) != 3 {
+		t.Fatalf("expected 3 links (1 declared, 2 discovered), got %d: %+v", len(merged.Links), merged.Links)
+	}
+	if len(merged.Nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %d: %+v", len(merged.Nodes), merged.Nodes)
+	}
+	ap := merged.Links[1]
+	if ap.Target != "ap-1" || ap.TargetInterface != "eth0" || ap.LinkType != "discovered" {
+		t.Errorf("unexpected discovered link: %+v", ap)
+	}
+	if merged.Links[2].Target != "00:33" {
+		t.Errorf("expected unnamed neighbor to use its chassis ID, got %q", merged.Links[2].Target)
+	}
+	if len(declared.Links) != 1 {
+		t.Error("MergeNeighbors must not modify the declared topology")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := MergeNeighbors(ctx, declared, neighbors); err == nil {
+		t.Error("expected an error from a cancelled merge")
+	}
+}
+
+func TestServerTopology_InvalidatedOnConfigApply(t *testing.T) {
+	server, _ := newTestServer(t)
+	before := server.currentTopology()
+
+	cfg := &config.Config{Devices: []config.Device{
+		{Name: "new-core", Type: "switch", TrunkPorts: []config.TrunkPort{{Interface: "Gi1/0/1", RemoteDevice: "new-dist"}}},
+	}}
+	server.replaceConfig(cfg)
+
+	after := server.currentTopology()
+	if len(after.Links) != 1 || after.Links[0].Source != "new-core" {
+		t.Fatalf("expected topology rebuilt from applied config, got %+v (before %+v)", after, before)
+	}
+	if server.topologyStale() {
+		t.Error("expected topology to be current after config apply")
+	}
+}
+
+// BenchmarkTopologyRead compares reading the cached topology while discovery
+// keeps rebuilding it against merging neighbors on every read.
+func BenchmarkTopologyRead(b *testing.B) {
+	cfg := &config.Config{}
+	var neighbors []protocols.NeighborRecord
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("sw-%02d", i)
+		cfg.Devices = append(cfg.Devices, config.Device{
+			Name: name,
+			Type: "switch",
+			TrunkPorts: []config.TrunkPort{
+				{Interface: "Gi0/1", VLANs: []int{10, 20}, RemoteDevice: fmt.Sprintf("sw-%02d", (i+1)%50)},
+			},
+		})
+		for j := 0; j < 10; j++ {
+			neighbors = append(neighbors, protocols.NeighborRecord{
+				Protocol:        protocols.ProtocolLLDP,
+				LocalDevice:     name,
+				RemoteDevice:    fmt.Sprintf("host-%02d-%d", i, j),
+				RemoteChassisID: fmt.Sprintf("chassis-%02d-%d", i, j),
+				RemotePort:      "eth0",
+			})
+		}
+	}
+	merge := func() Topology {
+		topology, _ := MergeNeighbors(context.Background(), BuildTopology(cfg), neighbors)
+		return topology
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		server := &Server{cfg: ServerConfig{Config: cfg}}
+		server.topology.Store(&topologySnapshot{topology: merge(), config: cfg})
+
+		// Simulate discovery updates continuously swapping in new topologies
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					server.topology.Store(&topologySnapshot{topology: merge(), config: cfg})
+				}
+			}
+		}()
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = server.currentTopology()
+			}
+		})
+		b.StopTimer()
+		close(stop)
+		<-done
+	})
+
+	b.Run("merge_per_read", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = merge()
+			}
+		})
+	})
+}
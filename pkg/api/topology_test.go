@@ -0,0 +1,90 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+func TestBuildTopology_TrunkInterfaces(t *testing.T) {
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name: "core-sw",
+				Type: "switch",
+				TrunkPorts: []config.TrunkPort{
+					{Interface: "Gi1/0/1", VLANs: []int{10, 20}, RemoteDevice: "dist-sw"},
+				},
+			},
+			{
+				Name: "dist-sw",
+				Type: "switch",
+				TrunkPorts: []config.TrunkPort{
+					{Interface: "Gi0/48", VLANs: []int{10, 20}, RemoteDevice: "core-sw", RemoteInterface: "Gi1/0/1"},
+				},
+			},
+			{
+				Name:       "access-sw",
+				Type:       "switch",
+				Interfaces: []config.Interface{{Name: "Fa0/1"}},
+			},
+			{
+				Name: "edge-sw",
+				Type: "switch",
+				TrunkPorts: []config.TrunkPort{
+					{Interface: "Gi0/2", VLANs: []int{10, 20}, RemoteDevice: "access-sw"},
+				},
+			},
+		},
+	}
+
+	topology := BuildTopology(cfg)
+	links := make(map[string]TopologyLink)
+	for _, link := range topology.Links {
+		links[link.Source] = link
+	}
+
+	core := links["core-sw"]
+	if core.SourceInterface != "Gi1/0/1" || core.TargetInterface != "Gi0/48" {
+		t.Errorf("core-sw link interfaces = %q/%q, want Gi1/0/1/Gi0/48", core.SourceInterface, core.TargetInterface)
+	}
+	dist := links["dist-sw"]
+	if dist.SourceInterface != "Gi0/48" || dist.TargetInterface != "Gi1/0/1" {
+		t.Errorf("dist-sw link interfaces = %q/%q, want Gi0/48/Gi1/0/1", dist.SourceInterface, dist.TargetInterface)
+	}
+	if core.Endpoints() != dist.Endpoints() {
+		t.Errorf("expected both ends to describe the same link: %q vs %q", core.Endpoints(), dist.Endpoints())
+	}
+
+	// Without a trunk back, the far end falls back to the LLDP port ID
+	if edge := links["edge-sw"]; edge.TargetInterface != "Fa0/1" {
+		t.Errorf("edge-sw target interface = %q, want Fa0/1", edge.TargetInterface)
+	}
+
+	if dot := topology.ExportDOT(); !strings.Contains(dot, `taillabel="Gi1/0/1", headlabel="Gi0/48"`) {
+		t.Errorf("DOT export missing interface endpoints:\n%s", dot)
+	}
+	if graphml := topology.ExportGraphML(); !strings.Contains(graphml, `<data key="d3">Gi0/48</data>`) {
+		t.Errorf("GraphML export missing target interface")
+	}
+}
+
+func TestDiffTopology_Recabled(t *testing.T) {
+	before := Topology{Links: []TopologyLink{
+		{Source: "core-sw", SourceInterface: "Gi1/0/1", Target: "dist-sw", TargetInterface: "Gi0/48"},
+		{Source: "core-sw", SourceInterface: "Gi1/0/5", Target: "fw", TargetInterface: "eth0"},
+	}}
+	after := Topology{Links: []TopologyLink{
+		{Source: "dist-sw", SourceInterface: "Gi0/48", Target: "core-sw", TargetInterface: "Gi1/0/2"},
+		{Source: "fw", SourceInterface: "eth0", Target: "core-sw", TargetInterface: "Gi1/0/5"},
+	}}
+
+	diff := DiffTopology(before, after)
+	if len(diff.Removed) != 1 || diff.Removed[0].SourceInterface != "Gi1/0/1" {
+		t.Errorf("unexpected removed links: %+v", diff.Removed)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].TargetInterface != "Gi1/0/2" {
+		t.Errorf("unexpected added links: %+v", diff.Added)
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// TrafficPatternPlan describes one configured background traffic pattern and
+// whether the generator would emit it right now.
+type TrafficPatternPlan struct {
+	Device   string `json:"device"`
+	Pattern  string `json:"pattern"`
+	Enabled  bool   `json:"enabled"`
+	Interval int    `json:"interval_seconds"`
+	Schedule string `json:"schedule,omitempty"`
+	Active   bool   `json:"active"`
+}
+
+// handleTraffic serves GET /api/v1/traffic, the traffic plan for every device.
+func (s *Server) handleTraffic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	plan := []TrafficPatternPlan{}
+	if cfg := s.currentConfig(); cfg != nil {
+		plan = trafficPlan(cfg.Devices, time.Now())
+	}
+	s.writeJSON(w, plan)
+}
+
+// trafficPlan lists the traffic patterns of each device, evaluating their
+// schedules at now. A pattern is active only when device traffic, the pattern
+// itself and its schedule all allow it.
+func trafficPlan(devices []config.Device, now time.Time) []TrafficPatternPlan {
+	plan := []TrafficPatternPlan{}
+	for i := range devices {
+		device := &devices[i]
+		traffic := device.TrafficConfig
+		if traffic == nil {
+			continue
+		}
+
+		add := func(pattern string, enabled bool, interval int, schedule *config.TrafficSchedule) {
+			plan = append(plan, TrafficPatternPlan{
+				Device:   device.Name,
+				Pattern:  pattern,
+				Enabled:  enabled,
+				Interval: interval,
+				Schedule: schedule.String(),
+				Active:   traffic.Enabled && enabled && schedule.Active(now),
+			})
+		}
+		if p := traffic.ARPAnnouncements; p != nil {
+			add("arp_announcements", p.Enabled, p.Interval, p.Schedule)
+		}
+		if p := traffic.PeriodicPings; p != nil {
+			add("periodic_pings", p.Enabled, p.Interval, p.Schedule)
+		}
+		if p := traffic.RandomTraffic; p != nil {
+			add("random_traffic", p.Enabled, p.Interval, p.Schedule)
+		}
+	}
+	return plan
+}
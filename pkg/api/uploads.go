@@ -0,0 +1,200 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultUploadMaxAge is how long an uploaded replay file is kept before the
+// cleanup ticker removes it (--replay-upload-max-age)
+const DefaultUploadMaxAge = 24 * time.Hour
+
+// uploadPattern matches the files writeUploadedFile creates
+const uploadPattern = "upload-*.pcap"
+
+// maxUploadCleanupInterval bounds how often stale uploads are looked for
+const maxUploadCleanupInterval = 10 * time.Minute
+
+// UploadInfo describes a PCAP uploaded for replay
+type UploadInfo struct {
+	Name       string    `json:"name"`
+	SizeBytes  int64     `json:"size_bytes"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	AgeSeconds float64   `json:"age_seconds"`
+	InUse      bool      `json:"in_use"` // Being replayed or queued; never removed
+}
+
+// UploadsResponse lists the uploaded replay files
+type UploadsResponse struct {
+	Directory     string       `json:"directory"`
+	MaxAgeSeconds float64      `json:"max_age_seconds"` // 0 = no automatic cleanup
+	Uploads       []UploadInfo `json:"uploads"`
+}
+
+// UploadsDeleteResponse lists the uploads a DELETE removed
+type UploadsDeleteResponse struct {
+	Removed []string `json:"removed"`
+}
+
+// uploadDir is where uploaded replay files are written
+func (s *Server) uploadDir() string {
+	if s.cfg.OutputDir != "" {
+		return filepath.Join(s.cfg.OutputDir, "replay")
+	}
+	return filepath.Join(os.TempDir(), "niac-replay")
+}
+
+// listUploads returns the uploaded replay files, oldest first
+func (s *Server) listUploads(now time.Time) ([]UploadInfo, error) {
+	dir := s.uploadDir()
+	paths, err := filepath.Glob(filepath.Join(dir, uploadPattern))
+	if err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool)
+	if s.cfg.Replay != nil {
+		for _, state := range s.cfg.Replay.List() {
+			if state.Running || state.Queued {
+				inUse[state.File] = true
+			}
+		}
+	}
+
+	uploads := make([]UploadInfo, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		uploads = append(uploads, UploadInfo{
+			Name:       info.Name(),
+			SizeBytes:  info.Size(),
+			UploadedAt: info.ModTime().UTC(),
+			AgeSeconds: now.Sub(info.ModTime()).Seconds(),
+			InUse:      inUse[path],
+		})
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].UploadedAt.Before(uploads[j].UploadedAt) })
+	return uploads, nil
+}
+
+// removeUploads deletes the uploads older than olderThan (every upload when
+// it is 0), except those being replayed. It returns the names removed.
+func (s *Server) removeUploads(olderThan time.Duration, now time.Time) ([]string, error) {
+	uploads, err := s.listUploads(now)
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	for _, upload := range uploads {
+		if upload.InUse || now.Sub(upload.UploadedAt) < olderThan {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.uploadDir(), upload.Name)); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("remove %s: %w", upload.Name, err)
+		}
+		removed = append(removed, upload.Name)
+	}
+	return removed, nil
+}
+
+// runUploadCleanup removes uploads older than the configured maximum age
+// until stop is closed
+func (s *Server) runUploadCleanup(stop <-chan struct{}) {
+	maxAge := s.cfg.UploadMaxAge
+	ticker := time.NewTicker(min(maxAge, maxUploadCleanupInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := s.removeUploads(maxAge, time.Now())
+			if err != nil {
+				log.Printf("replay upload cleanup: %v", err)
+			}
+			if len(removed) > 0 {
+				log.Printf("replay upload cleanup: removed %d upload(s) older than %s", len(removed), maxAge)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// handleReplayUploads lists uploaded replay files (GET) or removes them
+// (DELETE): one by ?name=, those older than ?older_than= (a duration such
+// as 1h), or every upload not being replayed.
+func (s *Server) handleReplayUploads(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	switch r.Method {
+	case http.MethodGet:
+		uploads, err := s.listUploads(now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("list uploads: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, UploadsResponse{
+			Directory:     s.uploadDir(),
+			MaxAgeSeconds: s.cfg.UploadMaxAge.Seconds(),
+			Uploads:       uploads,
+		})
+	case http.MethodDelete:
+		query := r.URL.Query()
+		if name := query.Get("name"); name != "" {
+			s.deleteUpload(w, name, now)
+			return
+		}
+		var olderThan time.Duration
+		if value := query.Get("older_than"); value != "" {
+			var err error
+			if olderThan, err = time.ParseDuration(value); err != nil || olderThan < 0 {
+				http.Error(w, fmt.Sprintf("invalid older_than %q: expected a duration such as 1h", value), http.StatusBadRequest)
+				return
+			}
+		}
+		removed, err := s.removeUploads(olderThan, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, UploadsDeleteResponse{Removed: removed})
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// deleteUpload removes a single upload by name
+func (s *Server) deleteUpload(w http.ResponseWriter, name string, now time.Time) {
+	// Only bare upload names, so the request cannot reach outside the directory
+	if matched, _ := filepath.Match(uploadPattern, name); !matched || filepath.Base(name) != name {
+		http.Error(w, fmt.Sprintf("invalid upload name %q", name), http.StatusBadRequest)
+		return
+	}
+	uploads, err := s.listUploads(now)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("list uploads: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, upload := range uploads {
+		if upload.Name != name {
+			continue
+		}
+		if upload.InUse {
+			http.Error(w, fmt.Sprintf("upload %s is being replayed; stop the replay first", name), http.StatusConflict)
+			return
+		}
+		if err := os.Remove(filepath.Join(s.uploadDir(), name)); err != nil && !os.IsNotExist(err) {
+			http.Error(w, fmt.Sprintf("remove %s: %v", name, err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, UploadsDeleteResponse{Removed: []string{name}})
+		return
+	}
+	http.Error(w, fmt.Sprintf("upload %s not found", name), http.StatusNotFound)
+}
@@ -13,11 +13,23 @@ import (
 	"github.com/google/gopacket/pcap"
 )
 
+// PacketWriter is the send side of a pcap handle
+type PacketWriter interface {
+	WritePacketData(data []byte) error
+}
+
 // Engine handles packet capture and injection
 type Engine struct {
 	interfaceName string
 	handle        *pcap.Handle
+	writer        PacketWriter // Send path; the capture handle unless replaced in tests
 	debugLevel    int
+
+	// Optional SPAN-like tap that receives a copy of every sent packet
+	mirrorName   string
+	mirror       PacketWriter
+	mirrorCloser func()
+	mirrorErrors atomic.Uint64
 }
 
 // New creates a new capture engine
@@ -37,20 +49,61 @@ func New(interfaceName string, debugLevel int) (*Engine, error) {
 	return &Engine{
 		interfaceName: interfaceName,
 		handle:        handle,
+		writer:        handle,
 		debugLevel:    debugLevel,
 	}, nil
 }
 
+// NewWithWriter creates an engine that sends through w instead of a live
+// interface, for callers that record or redirect what the simulation sends.
+// The engine cannot capture.
+func NewWithWriter(interfaceName string, w PacketWriter, debugLevel int) *Engine {
+	return &Engine{
+		interfaceName: interfaceName,
+		writer:        w,
+		debugLevel:    debugLevel,
+	}
+}
+
+// SetMirror opens a send-only handle on interfaceName and duplicates every
+// packet sent by the engine to it. Mirror write failures are counted and never
+// affect the primary interface. Call before the engine starts sending.
+func (e *Engine) SetMirror(interfaceName string) error {
+	// Nothing is read from the mirror, so capture as little as possible
+	handle, err := pcap.OpenLive(interfaceName, 64, false, 100*time.Millisecond)
+	if err != nil {
+		return fmt.Errorf("failed to open mirror interface %s: %w", interfaceName, err)
+	}
+	if err := handle.SetDirection(pcap.DirectionOut); err != nil && e.debugLevel >= 2 {
+		log.Printf("Mirror interface %s: cannot restrict capture direction: %v", interfaceName, err)
+	}
+
+	e.mirrorName = interfaceName
+	e.mirror = handle
+	e.mirrorCloser = handle.Close
+	return nil
+}
+
+// MirrorErrors returns the number of packets that could not be copied to the
+// mirror interface.
+func (e *Engine) MirrorErrors() uint64 {
+	return e.mirrorErrors.Load()
+}
+
 // Close closes the capture engine
 func (e *Engine) Close() {
 	if e.handle != nil {
 		e.handle.Close()
 	}
+	if e.mirrorCloser != nil {
+		e.mirrorCloser()
+		e.mirrorCloser = nil
+	}
 }
 
 // SendPacket sends a raw packet on the interface
 func (e *Engine) SendPacket(packet []byte) error {
-	if err := e.handle.WritePacketData(packet); err != nil {
+	if err := e.writer.WritePacketData(packet); err != nil {
 		return fmt.Errorf("failed to send packet: %w", err)
 	}
 
@@ -58,9 +111,23 @@ func (e *Engine) SendPacket(packet []byte) error {
 		log.Printf("Sent packet: %d bytes", len(packet))
 	}
 
+	e.mirrorPacket(packet)
 	return nil
 }
 
+// mirrorPacket copies a sent packet to the mirror interface, if any
+func (e *Engine) mirrorPacket(packet []byte) {
+	if e.mirror == nil {
+		return
+	}
+	if err := e.mirror.WritePacketData(packet); err != nil {
+		// Log the first failure, then only at higher debug levels
+		if e.mirrorErrors.Add(1) == 1 || e.debugLevel >= 3 {
+			log.Printf("Mirror interface %s: failed to copy packet: %v", e.mirrorName, err)
+		}
+	}
+}
+
 // SendEthernet sends an Ethernet frame
 func (e *Engine) SendEthernet(dstMAC, srcMAC []byte, etherType uint16, payload []byte) error {
 	// Build Ethernet layer
@@ -121,6 +188,11 @@ func (e *Engine) StartCapture(handler func(gopacket.Packet)) error {
 	return nil
 }
 
+// InterfaceName returns the name of the interface the engine captures on
+func (e *Engine) InterfaceName() string {
+	return e.interfaceName
+}
+
 // SetFilter sets a BPF filter on the capture
 func (e *Engine) SetFilter(filter string) error {
 	return e.handle.SetBPFFilter(filter)
@@ -128,6 +200,9 @@ func (e *Engine) SetFilter(filter string) error {
 
 // Stats returns capture statistics
 func (e *Engine) Stats() (*pcap.Stats, error) {
+	if e.handle == nil {
+		return nil, fmt.Errorf("failed to get stats: no capture handle")
+	}
 	stats, err := e.handle.Stats()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
@@ -1,6 +1,8 @@
 package capture
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"sync"
 	"testing"
@@ -504,3 +506,66 @@ func BenchmarkSerializeARP(b *testing.B) {
 		_ = gopacket.SerializeLayers(buf, opts, eth, arp)
 	}
 }
+
+// mockWriter records packets written to a fake pcap handle
+type mockWriter struct {
+	mu      sync.Mutex
+	packets [][]byte
+	err     error
+}
+
+func (m *mockWriter) WritePacketData(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.packets = append(m.packets, append([]byte(nil), data...))
+	return nil
+}
+
+// TestEngine_MirrorInterface tests that every sent packet is written to both
+// the primary and mirror handles
+func TestEngine_MirrorInterface(t *testing.T) {
+	primary := &mockWriter{}
+	tap := &mockWriter{}
+	engine := &Engine{interfaceName: "eth0", writer: primary, mirrorName: "eth1", mirror: tap}
+
+	raw := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x08, 0x06}
+	if err := engine.SendPacket(raw); err != nil {
+		t.Fatalf("SendPacket failed: %v", err)
+	}
+	if err := engine.SendEthernet([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		uint16(layers.EthernetTypeIPv4), []byte{0x45, 0x00}); err != nil {
+		t.Fatalf("SendEthernet failed: %v", err)
+	}
+
+	if len(primary.packets) != 2 || len(tap.packets) != 2 {
+		t.Fatalf("expected 2 packets on each handle, got primary=%d mirror=%d", len(primary.packets), len(tap.packets))
+	}
+	for i := range primary.packets {
+		if !bytes.Equal(primary.packets[i], tap.packets[i]) {
+			t.Errorf("packet %d differs between primary and mirror", i)
+		}
+	}
+}
+
+// TestEngine_MirrorErrorsIsolated tests that a failing mirror does not affect
+// the primary interface
+func TestEngine_MirrorErrorsIsolated(t *testing.T) {
+	primary := &mockWriter{}
+	tap := &mockWriter{err: errors.New("interface down")}
+	engine := &Engine{interfaceName: "eth0", writer: primary, mirrorName: "eth1", mirror: tap}
+
+	for i := 0; i < 3; i++ {
+		if err := engine.SendPacket([]byte{byte(i)}); err != nil {
+			t.Fatalf("SendPacket returned mirror error: %v", err)
+		}
+	}
+	if len(primary.packets) != 3 {
+		t.Errorf("expected 3 packets on primary, got %d", len(primary.packets))
+	}
+	if engine.MirrorErrors() != 3 {
+		t.Errorf("expected 3 mirror errors, got %d", engine.MirrorErrors())
+	}
+}
@@ -0,0 +1,60 @@
+package capture
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// filterSnapLen is the capture length BPF filters are compiled for; replayed
+// packets are matched whole
+const filterSnapLen = 262144
+
+// PacketFilter selects the replayed packets that match a BPF expression, so
+// a capture can be replayed in part (e.g. only its DHCP traffic)
+type PacketFilter struct {
+	expr string
+	bpf  *pcap.BPF
+}
+
+// NewPacketFilter compiles a BPF expression for packets of linkType
+func NewPacketFilter(expr string, linkType layers.LinkType) (*PacketFilter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("filter must not be empty")
+	}
+	bpf, err := pcap.NewBPF(linkType, filterSnapLen, expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	return &PacketFilter{expr: expr, bpf: bpf}, nil
+}
+
+// String returns the filter's BPF expression
+func (f *PacketFilter) String() string {
+	return f.expr
+}
+
+// Matches reports whether a packet, as read from the capture, passes the
+// filter
+func (f *PacketFilter) Matches(ci gopacket.CaptureInfo, data []byte) bool {
+	return f.bpf.Matches(ci, data)
+}
+
+// SourceLinkType returns the link type of a replay source: the link type
+// recorded in a PCAP file, or Ethernet for a stream:// source, whose header
+// is only read once playback connects.
+func SourceLinkType(source string) (layers.LinkType, error) {
+	if IsStreamSource(source) {
+		return layers.LinkTypeEthernet, nil
+	}
+	handle, err := pcap.OpenOffline(source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open PCAP file: %w", err)
+	}
+	defer handle.Close()
+	return handle.LinkType(), nil
+}
@@ -0,0 +1,132 @@
+package capture
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// createMixedPCAP writes a capture of DHCP, DNS and ARP packets and returns
+// its path and the number of DHCP packets in it
+func createMixedPCAP(t *testing.T) (string, int) {
+	t.Helper()
+
+	src, _ := net.ParseMAC("00:aa:bb:cc:dd:01")
+	dst, _ := net.ParseMAC("ff:ff:ff:ff:ff:ff")
+	udpFrame := func(srcPort, dstPort layers.UDPPort) []gopacket.SerializableLayer {
+		ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP,
+			SrcIP: net.ParseIP("10.1.1.1").To4(), DstIP: net.ParseIP("10.1.1.2").To4()}
+		udp := &layers.UDP{SrcPort: srcPort, DstPort: dstPort}
+		_ = udp.SetNetworkLayerForChecksum(ip)
+		return []gopacket.SerializableLayer{
+			&layers.Ethernet{SrcMAC: src, DstMAC: dst, EthernetType: layers.EthernetTypeIPv4},
+			ip, udp, gopacket.Payload("niac-filter-test"),
+		}
+	}
+	arp := []gopacket.SerializableLayer{
+		&layers.Ethernet{SrcMAC: src, DstMAC: dst, EthernetType: layers.EthernetTypeARP},
+		&layers.ARP{AddrType: layers.LinkTypeEthernet, Protocol: layers.EthernetTypeIPv4,
+			HwAddressSize: 6, ProtAddressSize: 4, Operation: layers.ARPRequest,
+			SourceHwAddress: src, SourceProtAddress: []byte{10, 1, 1, 1},
+			DstHwAddress: make([]byte, 6), DstProtAddress: []byte{10, 1, 1, 2}},
+	}
+	frames := [][]gopacket.SerializableLayer{
+		udpFrame(68, 67), // DHCP DISCOVER
+		udpFrame(40000, 53),
+		arp,
+		udpFrame(67, 68), // DHCP OFFER
+		udpFrame(53, 40000),
+		udpFrame(68, 67), // DHCP REQUEST
+	}
+
+	pcapFile := filepath.Join(t.TempDir(), "mixed.pcap")
+	f, err := os.Create(pcapFile)
+	if err != nil {
+		t.Fatalf("Failed to create temp PCAP: %v", err)
+	}
+	defer f.Close()
+	w := pcapgo.NewWriter(f)
+	if err := w.WriteFileHeader(1600, layers.LinkTypeEthernet); err != nil {
+		t.Fatalf("Failed to write PCAP header: %v", err)
+	}
+	baseTime := time.Now()
+	for i, frame := range frames {
+		buf := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+		if err := gopacket.SerializeLayers(buf, opts, frame...); err != nil {
+			t.Fatalf("Failed to serialize packet: %v", err)
+		}
+		info := gopacket.CaptureInfo{
+			Timestamp:     baseTime.Add(time.Duration(i) * time.Millisecond),
+			CaptureLength: len(buf.Bytes()),
+			Length:        len(buf.Bytes()),
+		}
+		if err := w.WritePacket(info, buf.Bytes()); err != nil {
+			t.Fatalf("Failed to write packet: %v", err)
+		}
+	}
+	return pcapFile, 3
+}
+
+// TestPlaybackEngine_Filter tests that a replay with a BPF filter sends only
+// the matching packets of a mixed capture
+func TestPlaybackEngine_Filter(t *testing.T) {
+	pcapFile, dhcpPackets := createMixedPCAP(t)
+	linkType, err := SourceLinkType(pcapFile)
+	if err != nil {
+		t.Skipf("Cannot read PCAP files: %v", err)
+	}
+	filter, err := NewPacketFilter("udp port 67", linkType)
+	if err != nil {
+		t.Fatalf("NewPacketFilter failed: %v", err)
+	}
+
+	writer := &mockWriter{}
+	player := NewPlaybackEngine(&Engine{interfaceName: "test", writer: writer},
+		&config.CapturePlayback{FileName: pcapFile, ScaleTime: 0.01}, 0)
+	player.SetFilter(filter)
+	done := make(chan PlaybackResult, 1)
+	player.SetOnComplete(func(result PlaybackResult) { done <- result })
+	if err := player.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer player.Stop()
+
+	var result PlaybackResult
+	select {
+	case result = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Filtered replay did not complete")
+	}
+
+	if result.PacketsSent != dhcpPackets || len(writer.packets) != dhcpPackets {
+		t.Fatalf("Sent %d packets (result %d), want the %d DHCP packets", len(writer.packets), result.PacketsSent, dhcpPackets)
+	}
+	for i, data := range writer.packets {
+		packet := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+		udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || (udp.SrcPort != 67 && udp.DstPort != 67) {
+			t.Errorf("Packet %d is not DHCP: %v", i+1, packet)
+		}
+	}
+}
+
+// TestNewPacketFilter_Invalid tests that a malformed BPF expression is
+// rejected with the expression in the error
+func TestNewPacketFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "   ", "udp prot 67", "port 99999999"} {
+		if _, err := NewPacketFilter(expr, layers.LinkTypeEthernet); err == nil {
+			t.Errorf("NewPacketFilter(%q) succeeded, want an error", expr)
+		} else if expr != "" && strings.TrimSpace(expr) != "" && !strings.Contains(err.Error(), expr) {
+			t.Errorf("NewPacketFilter(%q) error %q does not name the expression", expr, err)
+		}
+	}
+}
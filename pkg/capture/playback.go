@@ -14,13 +14,32 @@ import (
 
 // PlaybackEngine handles PCAP file playback
 type PlaybackEngine struct {
-	engine     *Engine
-	config     *config.CapturePlayback
-	debugLevel int
-	running    bool
-	stopChan   chan struct{}
-	wg         sync.WaitGroup
-	mu         sync.Mutex
+	engine      *Engine
+	config      *config.CapturePlayback
+	rewriter    *AddressRewriter
+	vlan        *VLANRewriter
+	filter      *PacketFilter // Packets not matching it are skipped
+	debugLevel  int
+	streamRetry time.Duration // First reconnect delay for stream:// sources
+	running     bool
+	result      PlaybackResult
+	onComplete  func(PlaybackResult)
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+}
+
+// PlaybackResult summarizes what a replay has sent so far
+type PlaybackResult struct {
+	PacketsSent    int
+	BytesSent      int64
+	SendErrors     int // Packets the capture engine failed to send (dropped)
+	LoopsCompleted int // Full passes over the PCAP file
+	StartedAt      time.Time
+	FinishedAt     time.Time // Zero while playback is running
+	Duration       time.Duration
+	Completed      bool   // Every requested loop played to the end
+	Error          string // Why playback ended early, if it did
 }
 
 // PlaybackPacket represents a packet with timestamp for playback
@@ -32,21 +51,74 @@ type PlaybackPacket struct {
 // NewPlaybackEngine creates a new PCAP playback engine
 func NewPlaybackEngine(engine *Engine, playbackConfig *config.CapturePlayback, debugLevel int) *PlaybackEngine {
 	return &PlaybackEngine{
-		engine:     engine,
-		config:     playbackConfig,
-		debugLevel: debugLevel,
-		stopChan:   make(chan struct{}),
+		engine:      engine,
+		config:      playbackConfig,
+		debugLevel:  debugLevel,
+		streamRetry: streamRetryMin,
+		stopChan:    make(chan struct{}),
 	}
 }
 
+// SetRewriter applies address rewriting to every replayed packet. It must be
+// called before Start.
+func (p *PlaybackEngine) SetRewriter(rw *AddressRewriter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rewriter = rw
+}
+
+// SetVLANRewriter strips or retags the VLAN headers of every replayed packet.
+// It must be called before Start.
+func (p *PlaybackEngine) SetVLANRewriter(vr *VLANRewriter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.vlan = vr
+}
+
+// SetFilter replays only the packets matching filter. It must be called
+// before Start.
+func (p *PlaybackEngine) SetFilter(filter *PacketFilter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.filter = filter
+}
+
+// SetOnComplete registers a function called with the final result when
+// playback ends on its own (all loops played, or the file could not be
+// read), but not when it is stopped. It must be called before Start.
+func (p *PlaybackEngine) SetOnComplete(fn func(PlaybackResult)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onComplete = fn
+}
+
+// Result returns the replay summary: progress while playback runs, the
+// final counts once it has ended.
+func (p *PlaybackEngine) Result() PlaybackResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := p.result
+	if result.FinishedAt.IsZero() && !result.StartedAt.IsZero() {
+		result.Duration = time.Since(result.StartedAt)
+	}
+	return result
+}
+
 // Start begins PCAP playback
 func (p *PlaybackEngine) Start() error {
 	if p.config == nil {
 		return fmt.Errorf("no playback configuration provided")
 	}
 
-	// Check if PCAP file exists
-	if _, err := os.Stat(p.config.FileName); err != nil {
+	// A stream:// source is a remote capture; anything else must be a file
+	var streamAddr string
+	if IsStreamSource(p.config.FileName) {
+		addr, err := StreamAddress(p.config.FileName)
+		if err != nil {
+			return err
+		}
+		streamAddr = addr
+	} else if _, err := os.Stat(p.config.FileName); err != nil {
 		return fmt.Errorf("PCAP file not found: %s: %w", p.config.FileName, err)
 	}
 
@@ -56,8 +128,18 @@ func (p *PlaybackEngine) Start() error {
 		return fmt.Errorf("playback already running")
 	}
 	p.running = true
+	p.result = PlaybackResult{StartedAt: time.Now()}
 	p.mu.Unlock()
 
+	if streamAddr != "" {
+		if p.debugLevel >= 1 {
+			log.Printf("Starting stream playback from %s", streamAddr)
+		}
+		p.wg.Add(1)
+		go p.streamLoop(streamAddr)
+		return nil
+	}
+
 	if p.debugLevel >= 1 {
 		log.Printf("Starting PCAP playback: %s", p.config.FileName)
 		if p.config.ScaleTime > 0 && p.config.ScaleTime != 1.0 {
@@ -66,6 +148,9 @@ func (p *PlaybackEngine) Start() error {
 		if p.config.LoopTime > 0 {
 			log.Printf("  Loop interval: %dms", p.config.LoopTime)
 		}
+		if p.config.LoopCount > 0 {
+			log.Printf("  Loop count: %d", p.config.LoopCount)
+		}
 	}
 
 	// Start playback goroutine
@@ -95,48 +180,109 @@ func (p *PlaybackEngine) Stop() {
 
 // playbackLoop is the main playback loop
 func (p *PlaybackEngine) playbackLoop() {
-	defer p.wg.Done()
-
-	// If LoopTime is specified, loop playback at that interval
-	if p.config.LoopTime > 0 {
-		loopInterval := time.Duration(p.config.LoopTime) * time.Millisecond
-		ticker := time.NewTicker(loopInterval)
-		defer ticker.Stop()
+	completed, err := p.playLoops()
+	// Release Stop before reporting, so the completion callback may safely
+	// take locks held by whoever is stopping this engine
+	p.wg.Done()
+	p.finish(completed, err)
+}
 
-		// Play immediately on start
-		p.playOnce()
+// playLoops plays the PCAP file LoopCount times (once by default), waiting
+// LoopTime between the start of each play when set. With LoopTime and no
+// LoopCount, or a negative LoopCount, it plays until stopped. It reports
+// whether every loop played to the end.
+func (p *PlaybackEngine) playLoops() (bool, error) {
+	loops := p.config.LoopCount
+	if loops == 0 && p.config.LoopTime <= 0 {
+		loops = 1
+	}
 
-		// Then play on each tick
-		for {
-			select {
-			case <-ticker.C:
-				p.playOnce()
-			case <-p.stopChan:
-				return
+	// Without LoopTime, loops are played back to back
+	if p.config.LoopTime <= 0 {
+		for i := 0; loops < 0 || i < loops; i++ {
+			if done, err := p.playOnce(); !done {
+				return false, err
 			}
 		}
-	} else {
-		// Play once and exit
-		p.playOnce()
+		return true, nil
+	}
+
+	loopInterval := time.Duration(p.config.LoopTime) * time.Millisecond
+	ticker := time.NewTicker(loopInterval)
+	defer ticker.Stop()
+
+	// Play immediately on start, then on each tick
+	for played := 0; ; {
+		if done, err := p.playOnce(); !done {
+			return false, err
+		}
+		played++
+		if loops > 0 && played >= loops {
+			return true, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-p.stopChan:
+			return false, nil
+		}
 	}
 }
 
-// playOnce plays the PCAP file once
-func (p *PlaybackEngine) playOnce() {
+// finish records the end of playback. When it ended on its own rather than
+// through Stop, the engine is marked stopped and the completion callback runs.
+func (p *PlaybackEngine) finish(completed bool, err error) {
+	stopped := false
+	select {
+	case <-p.stopChan:
+		stopped = true
+	default:
+	}
+
+	p.mu.Lock()
+	p.result.FinishedAt = time.Now()
+	p.result.Duration = p.result.FinishedAt.Sub(p.result.StartedAt)
+	p.result.Completed = completed
+	if err != nil {
+		p.result.Error = err.Error()
+	}
+	if !stopped {
+		p.running = false
+	}
+	result := p.result
+	onComplete := p.onComplete
+	p.mu.Unlock()
+
+	if stopped {
+		return
+	}
+	if p.debugLevel >= 1 {
+		log.Printf("Playback finished: %d packets (%d bytes), %d loops, %d send errors in %v",
+			result.PacketsSent, result.BytesSent, result.LoopsCompleted, result.SendErrors, result.Duration)
+	}
+	if onComplete != nil {
+		onComplete(result)
+	}
+}
+
+// playOnce plays the PCAP file once. It returns true when every packet was
+// played, or false with the reason when playback was stopped or the file
+// could not be read.
+func (p *PlaybackEngine) playOnce() (bool, error) {
 	// Load packets from PCAP
 	packets, err := p.loadPCAP()
 	if err != nil {
 		if p.debugLevel >= 1 {
 			log.Printf("Error loading PCAP: %v", err)
 		}
-		return
+		return false, err
 	}
 
 	if len(packets) == 0 {
 		if p.debugLevel >= 2 {
 			log.Printf("No packets found in PCAP file")
 		}
-		return
+		p.recordLoop()
+		return true, nil
 	}
 
 	if p.debugLevel >= 2 {
@@ -151,7 +297,7 @@ func (p *PlaybackEngine) playOnce() {
 		// Check if we should stop
 		select {
 		case <-p.stopChan:
-			return
+			return false, nil
 		default:
 		}
 
@@ -163,12 +309,15 @@ func (p *PlaybackEngine) playOnce() {
 			select {
 			case <-time.After(sleepDuration):
 			case <-p.stopChan:
-				return
+				return false, nil
 			}
 		}
 
 		// Send packet
-		if err := p.engine.SendPacket(pkt.Data); err != nil {
+		data := p.preparePacket(pkt.Data)
+		err := p.engine.SendPacket(data)
+		p.recordSend(len(data), err)
+		if err != nil {
 			if p.debugLevel >= 2 {
 				log.Printf("Error sending packet %d: %v", i+1, err)
 			}
@@ -177,13 +326,60 @@ func (p *PlaybackEngine) playOnce() {
 		}
 	}
 
+	p.recordLoop()
 	if p.debugLevel >= 2 {
 		elapsed := time.Since(startTime)
 		log.Printf("Playback complete: %d packets in %v", len(packets), elapsed)
 	}
+	return true, nil
 }
 
-// loadPCAP loads packets from a PCAP file
+// recordSend counts one packet handed to the capture engine
+func (p *PlaybackEngine) recordSend(size int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.result.SendErrors++
+		return
+	}
+	p.result.PacketsSent++
+	p.result.BytesSent += int64(size)
+}
+
+// recordLoop counts one full pass over the PCAP file
+func (p *PlaybackEngine) recordLoop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.result.LoopsCompleted++
+}
+
+// preparePacket applies address and VLAN rewriting, falling back to the
+// original bytes when the packet cannot be rewritten.
+func (p *PlaybackEngine) preparePacket(data []byte) []byte {
+	if p.rewriter != nil {
+		rewritten, err := p.rewriter.Rewrite(data)
+		if err != nil {
+			if p.debugLevel >= 2 {
+				log.Printf("Replay rewrite skipped: %v", err)
+			}
+		} else {
+			data = rewritten
+		}
+	}
+	if p.vlan != nil {
+		retagged, err := p.vlan.Rewrite(data)
+		if err != nil {
+			if p.debugLevel >= 2 {
+				log.Printf("Replay VLAN rewrite skipped: %v", err)
+			}
+		} else {
+			data = retagged
+		}
+	}
+	return data
+}
+
+// loadPCAP loads the packets of a PCAP file that pass the filter, if any
 func (p *PlaybackEngine) loadPCAP() ([]PlaybackPacket, error) {
 	// Open PCAP file
 	handle, err := pcap.OpenOffline(p.config.FileName)
@@ -202,6 +398,10 @@ func (p *PlaybackEngine) loadPCAP() ([]PlaybackPacket, error) {
 			break
 		}
 
+		if p.filter != nil && !p.filter.Matches(packet.Metadata().CaptureInfo, packet.Data()) {
+			continue
+		}
+
 		// Store packet data and timestamp
 		pkt := PlaybackPacket{
 			Data:      packet.Data(),
@@ -543,3 +543,84 @@ func TestPlaybackEngine_ConcurrentStartStop(t *testing.T) {
 		}
 	}
 }
+
+// TestPlaybackEngine_NegativeLoopCount verifies that a negative LoopCount
+// replays back to back until stopped
+func TestPlaybackEngine_NegativeLoopCount(t *testing.T) {
+	pcapFile := createTestPCAP(t, 2)
+	writer := &mockWriter{}
+	player := NewPlaybackEngine(&Engine{interfaceName: "test", writer: writer},
+		&config.CapturePlayback{FileName: pcapFile, LoopCount: -1, ScaleTime: 0.001}, 0)
+	if _, err := player.loadPCAP(); err != nil {
+		t.Skipf("Cannot read PCAP files: %v", err)
+	}
+
+	if err := player.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for player.Result().LoopsCompleted < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Replay completed %d loops, want it to keep looping", player.Result().LoopsCompleted)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !player.IsRunning() {
+		t.Fatal("Player stopped on its own with a negative loop count")
+	}
+	player.Stop()
+	if result := player.Result(); result.Completed {
+		t.Errorf("Result completed=%v after Stop, want false", result.Completed)
+	}
+}
+
+// TestPlaybackEngine_BoundedReplayResult verifies that a replay limited by
+// LoopCount stops on its own and reports what it sent
+func TestPlaybackEngine_BoundedReplayResult(t *testing.T) {
+	pcapFile := createTestPCAP(t, 3)
+	writer := &mockWriter{}
+	player := NewPlaybackEngine(&Engine{interfaceName: "test", writer: writer},
+		&config.CapturePlayback{FileName: pcapFile, LoopCount: 2, ScaleTime: 0.01}, 0)
+	if _, err := player.loadPCAP(); err != nil {
+		t.Skipf("Cannot read PCAP files: %v", err)
+	}
+
+	done := make(chan PlaybackResult, 1)
+	player.SetOnComplete(func(result PlaybackResult) { done <- result })
+	if err := player.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer player.Stop()
+
+	var result PlaybackResult
+	select {
+	case result = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Bounded replay did not complete")
+	}
+
+	if player.IsRunning() {
+		t.Error("Player still running after its last loop")
+	}
+	var sentBytes int64
+	for _, data := range writer.packets {
+		sentBytes += int64(len(data))
+	}
+	if result.PacketsSent != 6 || result.BytesSent != sentBytes || result.LoopsCompleted != 2 {
+		t.Errorf("Result = %d packets, %d bytes, %d loops; want 6, %d, 2",
+			result.PacketsSent, result.BytesSent, result.LoopsCompleted, sentBytes)
+	}
+	if !result.Completed || result.SendErrors != 0 || result.Error != "" {
+		t.Errorf("Result completed=%v errors=%d error=%q; want a clean completion",
+			result.Completed, result.SendErrors, result.Error)
+	}
+	if result.FinishedAt.IsZero() || result.Duration <= 0 {
+		t.Errorf("Result missing timing: finished=%v duration=%v", result.FinishedAt, result.Duration)
+	}
+	if got := player.Result(); got != result {
+		t.Errorf("Result() = %+v after completion, want %+v", got, result)
+	}
+	if len(writer.packets) != 6 {
+		t.Errorf("Writer received %d packets, want 6", len(writer.packets))
+	}
+}
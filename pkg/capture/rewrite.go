@@ -0,0 +1,164 @@
+package capture
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// AddressRewriter rewrites IP and MAC addresses in replayed packets so a
+// capture from another network can drive the simulated devices. Checksums and
+// lengths are recomputed after rewriting.
+type AddressRewriter struct {
+	ips  map[string]net.IP
+	macs map[string]net.HardwareAddr
+}
+
+// NewAddressRewriter builds a rewriter from "old" -> "new" address pairs. Each
+// pair must be two IPv4 addresses, two IPv6 addresses, or two MAC addresses.
+func NewAddressRewriter(rules map[string]string) (*AddressRewriter, error) {
+	rw := &AddressRewriter{
+		ips:  make(map[string]net.IP),
+		macs: make(map[string]net.HardwareAddr),
+	}
+
+	for from, to := range rules {
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+
+		if fromIP := net.ParseIP(from); fromIP != nil {
+			toIP := net.ParseIP(to)
+			if toIP == nil {
+				return nil, fmt.Errorf("rewrite %s: target %q is not an IP address", from, to)
+			}
+			if (fromIP.To4() == nil) != (toIP.To4() == nil) {
+				return nil, fmt.Errorf("rewrite %s: cannot map between IPv4 and IPv6 (%s)", from, to)
+			}
+			if v4 := toIP.To4(); v4 != nil {
+				toIP = v4
+			}
+			rw.ips[fromIP.String()] = toIP
+			continue
+		}
+
+		fromMAC, err := net.ParseMAC(from)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite %s: not an IP or MAC address", from)
+		}
+		toMAC, err := net.ParseMAC(to)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite %s: target %q is not a MAC address", from, to)
+		}
+		rw.macs[fromMAC.String()] = toMAC
+	}
+
+	return rw, nil
+}
+
+// Rewrite returns a copy of an Ethernet frame with mapped addresses replaced in
+// the Ethernet, ARP, IPv4 and IPv6 headers. TCP, UDP and ICMP checksums are
+// recomputed; payloads above the transport layer are passed through untouched.
+func (rw *AddressRewriter) Rewrite(data []byte) ([]byte, error) {
+	packet := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+	if errLayer := packet.ErrorLayer(); errLayer != nil {
+		return nil, fmt.Errorf("decode packet: %v", errLayer.Error())
+	}
+
+	var (
+		serializable []gopacket.SerializableLayer
+		last         gopacket.Layer
+		network      gopacket.NetworkLayer
+	)
+
+decode:
+	for _, layer := range packet.Layers() {
+		switch l := layer.(type) {
+		case *layers.Ethernet:
+			l.SrcMAC = rw.mac(l.SrcMAC)
+			l.DstMAC = rw.mac(l.DstMAC)
+			serializable = append(serializable, l)
+		case *layers.Dot1Q:
+			serializable = append(serializable, l)
+		case *layers.ARP:
+			l.SourceHwAddress = rw.mac(l.SourceHwAddress)
+			l.DstHwAddress = rw.mac(l.DstHwAddress)
+			l.SourceProtAddress = rw.ip(l.SourceProtAddress)
+			l.DstProtAddress = rw.ip(l.DstProtAddress)
+			serializable = append(serializable, l)
+			last = l
+			break decode
+		case *layers.IPv4:
+			l.SrcIP = rw.ip(l.SrcIP)
+			l.DstIP = rw.ip(l.DstIP)
+			network = l
+			serializable = append(serializable, l)
+		case *layers.IPv6:
+			l.SrcIP = rw.ip(l.SrcIP)
+			l.DstIP = rw.ip(l.DstIP)
+			network = l
+			serializable = append(serializable, l)
+		case *layers.TCP:
+			if network != nil {
+				_ = l.SetNetworkLayerForChecksum(network)
+			}
+			serializable = append(serializable, l)
+			last = l
+			break decode
+		case *layers.UDP:
+			if network != nil {
+				_ = l.SetNetworkLayerForChecksum(network)
+			}
+			serializable = append(serializable, l)
+			last = l
+			break decode
+		case *layers.ICMPv4:
+			serializable = append(serializable, l)
+			last = l
+			break decode
+		case *layers.ICMPv6:
+			if network != nil {
+				_ = l.SetNetworkLayerForChecksum(network)
+			}
+			serializable = append(serializable, l)
+			last = l
+			break decode
+		default:
+			// Unknown layer: carry it and everything above it as raw payload
+			break decode
+		}
+		last = layer
+	}
+
+	if len(serializable) == 0 {
+		return nil, fmt.Errorf("packet has no rewritable layers")
+	}
+	if payload := last.LayerPayload(); len(payload) > 0 {
+		serializable = append(serializable, gopacket.Payload(payload))
+	}
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	if err := gopacket.SerializeLayers(buffer, opts, serializable...); err != nil {
+		return nil, fmt.Errorf("serialize rewritten packet: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+func (rw *AddressRewriter) ip(addr net.IP) net.IP {
+	if replacement, ok := rw.ips[net.IP(addr).String()]; ok {
+		return replacement
+	}
+	return addr
+}
+
+func (rw *AddressRewriter) mac(addr net.HardwareAddr) net.HardwareAddr {
+	if replacement, ok := rw.macs[addr.String()]; ok {
+		return replacement
+	}
+	return addr
+}
@@ -0,0 +1,138 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket/pcapgo"
+)
+
+// StreamScheme prefixes a replay source that is a remote capture streamed
+// over TCP rather than a local file, e.g. "stream://10.0.0.5:9000".
+const StreamScheme = "stream://"
+
+const (
+	// StreamMaxPacketSize caps the length of a single streamed packet. The
+	// stream's snap length is clamped to it, so a corrupt or hostile header
+	// cannot make the reader allocate more.
+	StreamMaxPacketSize = 65535
+
+	streamDialTimeout = 5 * time.Second
+	streamRetryMin    = 1 * time.Second
+	streamRetryMax    = 30 * time.Second
+)
+
+// IsStreamSource reports whether a replay source names a remote stream.
+func IsStreamSource(source string) bool {
+	return strings.HasPrefix(source, StreamScheme)
+}
+
+// StreamAddress returns the host:port of a stream:// replay source.
+func StreamAddress(source string) (string, error) {
+	if !IsStreamSource(source) {
+		return "", fmt.Errorf("not a stream source: %s", source)
+	}
+	addr := strings.TrimPrefix(source, StreamScheme)
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream address %q: %w", addr, err)
+	}
+	if host == "" || port == "" {
+		return "", fmt.Errorf("invalid stream address %q: host and port are required", addr)
+	}
+	return addr, nil
+}
+
+// streamLoop connects to the remote capture and replays it until stopped.
+// The stream is a standard pcap file (global header followed by records, as
+// written by "tcpdump -w -") sent over TCP. Whenever the connection fails or
+// the sender closes it, the loop reconnects with exponential backoff.
+func (p *PlaybackEngine) streamLoop(addr string) {
+	defer p.wg.Done()
+
+	retry := p.streamRetry
+	for {
+		dialer := net.Dialer{Timeout: streamDialTimeout}
+		conn, err := dialer.Dial("tcp", addr)
+		if err != nil {
+			if p.debugLevel >= 2 {
+				log.Printf("Replay stream %s: %v (retrying in %v)", addr, err, retry)
+			}
+		} else {
+			sent, err := p.playStream(conn)
+			conn.Close()
+			if sent > 0 {
+				retry = p.streamRetry
+			}
+			if p.debugLevel >= 2 {
+				log.Printf("Replay stream %s ended after %d packets: %v (reconnecting in %v)", addr, sent, err, retry)
+			}
+		}
+
+		select {
+		case <-p.stopChan:
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, streamRetryMax)
+	}
+}
+
+// playStream sends each packet read from conn as soon as it arrives; the
+// sender paces the replay, so loop and time scaling do not apply. It returns
+// the number of packets sent and why the stream ended.
+func (p *PlaybackEngine) playStream(conn net.Conn) (int, error) {
+	// Unblock the reader when playback is stopped
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-p.stopChan:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	reader, err := pcapgo.NewReader(conn)
+	if err != nil {
+		return 0, fmt.Errorf("read stream header: %w", err)
+	}
+	if reader.Snaplen() > StreamMaxPacketSize {
+		reader.SetSnaplen(StreamMaxPacketSize)
+	}
+	if p.debugLevel >= 1 {
+		log.Printf("Replaying stream from %s (link type %s)", conn.RemoteAddr(), reader.LinkType())
+	}
+
+	sent := 0
+	for {
+		data, ci, err := reader.ReadPacketData()
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("closed by sender")
+			}
+			return sent, err
+		}
+		if p.filter != nil && !p.filter.Matches(ci, data) {
+			continue
+		}
+
+		data = p.preparePacket(data)
+		err = p.engine.SendPacket(data)
+		p.recordSend(len(data), err)
+		if err != nil {
+			if p.debugLevel >= 2 {
+				log.Printf("Error sending streamed packet %d: %v", sent+1, err)
+			}
+			continue
+		}
+		sent++
+		if p.debugLevel >= 3 {
+			log.Printf("Sent streamed packet %d (%d bytes)", sent, len(data))
+		}
+	}
+}
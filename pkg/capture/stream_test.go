@@ -0,0 +1,114 @@
+package capture
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// serveStream accepts one connection on ln and writes packets to it as a pcap
+// stream, then closes it. Write errors after the header are expected when the
+// reader rejects a packet and hangs up.
+func serveStream(t *testing.T, ln net.Listener, snaplen uint32, packets [][]byte) {
+	t.Helper()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Errorf("accept: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	w := pcapgo.NewWriter(conn)
+	if err := w.WriteFileHeader(snaplen, layers.LinkTypeEthernet); err != nil {
+		t.Errorf("write header: %v", err)
+		return
+	}
+	for _, data := range packets {
+		info := gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(data), Length: len(data)}
+		if err := w.WritePacket(info, data); err != nil {
+			return
+		}
+	}
+}
+
+// waitForPackets polls the mock writer until it holds n packets.
+func waitForPackets(t *testing.T, writer *mockWriter, n int) [][]byte {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		writer.mu.Lock()
+		got := len(writer.packets)
+		writer.mu.Unlock()
+		if got >= n {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	return append([][]byte(nil), writer.packets...)
+}
+
+func TestPlaybackEngine_StreamSource(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	writer := &mockWriter{}
+	player := NewPlaybackEngine(&Engine{interfaceName: "test", writer: writer},
+		&config.CapturePlayback{FileName: StreamScheme + ln.Addr().String()}, 0)
+	player.streamRetry = 10 * time.Millisecond
+	if err := player.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer player.Stop()
+
+	// First connection streams two packets, then the sender goes away
+	serveStream(t, ln, 1600, [][]byte{{0x01, 0x01}, {0x01, 0x02}})
+	if got := waitForPackets(t, writer, 2); len(got) != 2 {
+		t.Fatalf("Expected 2 packets from the first connection, got %d", len(got))
+	}
+
+	// The engine reconnects; an oversized packet ends that connection
+	// without being sent
+	serveStream(t, ln, 1<<20, [][]byte{{0x02, 0x01}, make([]byte, StreamMaxPacketSize+1), {0x02, 0x02}})
+	serveStream(t, ln, 1600, [][]byte{{0x03, 0x01}})
+
+	got := waitForPackets(t, writer, 4)
+	want := [][]byte{{0x01, 0x01}, {0x01, 0x02}, {0x02, 0x01}, {0x03, 0x01}}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d packets, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if string(got[i]) != string(want[i]) {
+			t.Errorf("packet %d = %x, want %x", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStreamAddress(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{"stream://10.0.0.5:9000", "10.0.0.5:9000", false},
+		{"stream://[2001:db8::1]:9000", "[2001:db8::1]:9000", false},
+		{"stream://10.0.0.5", "", true},
+		{"stream://:9000", "", true},
+		{"/tmp/capture.pcap", "", true},
+	}
+	for _, tt := range tests {
+		got, err := StreamAddress(tt.source)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("StreamAddress(%q) = %q, %v; want %q (error %v)", tt.source, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// VLAN tag modes for replayed frames
+const (
+	VLANPreserve = "preserve" // Send 802.1Q tags as captured (default)
+	VLANStrip    = "strip"    // Remove every 802.1Q/802.1ad tag
+	VLANRewrite  = "rewrite"  // Replace the outer tag's VLAN ID
+)
+
+const (
+	ethHeaderLen    = 14 // Destination MAC, source MAC and EtherType
+	vlanTagLen      = 4  // TPID plus TCI
+	minEthFrameLen  = 60 // Smallest Ethernet frame without the FCS
+	etherTypeOffset = 12
+	maxVLANID       = 4094
+)
+
+// VLANRewriter strips or retags the 802.1Q headers of replayed frames, so a
+// capture taken on one VLAN can be replayed onto a differently tagged
+// segment.
+type VLANRewriter struct {
+	mode   string
+	vlanID uint16
+}
+
+// NewVLANRewriter builds a rewriter for a VLAN mode. vlanID (1-4094) is
+// required by rewrite and must be 0 otherwise. An empty mode means preserve.
+func NewVLANRewriter(mode string, vlanID int) (*VLANRewriter, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "", VLANPreserve:
+		mode = VLANPreserve
+	case VLANStrip:
+	case VLANRewrite:
+		if vlanID < 1 || vlanID > maxVLANID {
+			return nil, fmt.Errorf("vlan_id must be between 1 and %d: %d", maxVLANID, vlanID)
+		}
+		return &VLANRewriter{mode: mode, vlanID: uint16(vlanID)}, nil
+	default:
+		return nil, fmt.Errorf("vlan_mode must be %s, %s or %s: %q", VLANPreserve, VLANStrip, VLANRewrite, mode)
+	}
+	if vlanID != 0 {
+		return nil, fmt.Errorf("vlan_id requires vlan_mode %s", VLANRewrite)
+	}
+	return &VLANRewriter{mode: mode}, nil
+}
+
+// Mode returns the rewriter's VLAN mode
+func (vr *VLANRewriter) Mode() string {
+	return vr.mode
+}
+
+// Rewrite returns a copy of an Ethernet frame with its VLAN tags stripped or
+// its outer VLAN ID replaced; the priority and DEI bits are kept. Untagged
+// frames are returned unchanged, as is every frame in preserve mode. A
+// stripped frame shorter than the Ethernet minimum is zero-padded.
+func (vr *VLANRewriter) Rewrite(data []byte) ([]byte, error) {
+	if len(data) < ethHeaderLen {
+		return nil, fmt.Errorf("frame too short for an Ethernet header: %d bytes", len(data))
+	}
+	if vr.mode == VLANPreserve || !isVLANTPID(binary.BigEndian.Uint16(data[etherTypeOffset:])) {
+		return data, nil
+	}
+
+	switch vr.mode {
+	case VLANRewrite:
+		if len(data) < ethHeaderLen+vlanTagLen {
+			return nil, fmt.Errorf("frame too short for a VLAN tag: %d bytes", len(data))
+		}
+		frame := append([]byte(nil), data...)
+		tciOffset := etherTypeOffset + 2
+		tci := binary.BigEndian.Uint16(frame[tciOffset:])
+		binary.BigEndian.PutUint16(frame[tciOffset:], tci&0xf000|vr.vlanID)
+		return frame, nil
+
+	default: // VLANStrip
+		offset := etherTypeOffset
+		for isVLANTPID(binary.BigEndian.Uint16(data[offset:])) {
+			offset += vlanTagLen
+			if len(data) < offset+2 {
+				return nil, fmt.Errorf("frame too short for its VLAN tags: %d bytes", len(data))
+			}
+		}
+		frame := make([]byte, 0, len(data))
+		frame = append(frame, data[:etherTypeOffset]...)
+		frame = append(frame, data[offset:]...)
+		for len(frame) < minEthFrameLen {
+			frame = append(frame, 0)
+		}
+		return frame, nil
+	}
+}
+
+// isVLANTPID reports whether an EtherType is a VLAN tag protocol identifier:
+// 802.1Q, or the 802.1ad service tag of a QinQ frame
+func isVLANTPID(etherType uint16) bool {
+	return etherType == 0x8100 || etherType == 0x88a8
+}
@@ -0,0 +1,130 @@
+package capture
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// buildTaggedFrame builds an Ethernet/802.1Q/IPv4/UDP frame on a VLAN with a
+// priority
+func buildTaggedFrame(t *testing.T, vlanID uint16, priority uint8) []byte {
+	t.Helper()
+
+	src, _ := net.ParseMAC("00:aa:bb:cc:dd:01")
+	dst, _ := net.ParseMAC("00:aa:bb:cc:dd:02")
+	eth := &layers.Ethernet{SrcMAC: src, DstMAC: dst, EthernetType: layers.EthernetTypeDot1Q}
+	tag := &layers.Dot1Q{VLANIdentifier: vlanID, Priority: priority, Type: layers.EthernetTypeIPv4}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP("10.1.1.1").To4(),
+		DstIP:    net.ParseIP("10.1.1.2").To4(),
+	}
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 161}
+	_ = udp.SetNetworkLayerForChecksum(ip)
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, eth, tag, ip, udp, gopacket.Payload([]byte("niac-replay-vlan-test-payload"))); err != nil {
+		t.Fatalf("Failed to build frame: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+// TestPlaybackEngine_VLANModes tests that replayed frames keep, lose or retag
+// their 802.1Q header
+func TestPlaybackEngine_VLANModes(t *testing.T) {
+	original := buildTaggedFrame(t, 10, 5)
+
+	replay := func(mode string, vlanID int) gopacket.Packet {
+		t.Helper()
+		vr, err := NewVLANRewriter(mode, vlanID)
+		if err != nil {
+			t.Fatalf("NewVLANRewriter(%q, %d) failed: %v", mode, vlanID, err)
+		}
+		player := NewPlaybackEngine(nil, &config.CapturePlayback{FileName: "unused.pcap"}, 0)
+		player.SetVLANRewriter(vr)
+		return gopacket.NewPacket(player.preparePacket(original), layers.LayerTypeEthernet, gopacket.Default)
+	}
+
+	// Preserve leaves the frame untouched
+	if packet := replay(VLANPreserve, 0); !bytes.Equal(packet.Data(), original) {
+		t.Error("Preserve mode changed the frame")
+	}
+
+	// Strip removes the tag and restores the inner EtherType
+	packet := replay(VLANStrip, 0)
+	if packet.Layer(layers.LayerTypeDot1Q) != nil {
+		t.Error("Stripped frame still carries an 802.1Q tag")
+	}
+	if eth := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); eth.EthernetType != layers.EthernetTypeIPv4 {
+		t.Errorf("Stripped frame EtherType = %s, want IPv4", eth.EthernetType)
+	}
+	if len(packet.Data()) != len(original)-4 {
+		t.Errorf("Stripped frame is %d bytes, want %d", len(packet.Data()), len(original)-4)
+	}
+	udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok || !bytes.Equal(udp.Payload, []byte("niac-replay-vlan-test-payload")) {
+		t.Error("Stripped frame lost its UDP payload")
+	}
+
+	// Rewrite changes the VLAN ID and keeps the priority
+	packet = replay(VLANRewrite, 200)
+	tag, ok := packet.Layer(layers.LayerTypeDot1Q).(*layers.Dot1Q)
+	if !ok {
+		t.Fatal("Rewritten frame has no 802.1Q tag")
+	}
+	if tag.VLANIdentifier != 200 || tag.Priority != 5 {
+		t.Errorf("Rewritten tag = VLAN %d priority %d, want VLAN 200 priority 5", tag.VLANIdentifier, tag.Priority)
+	}
+	if len(packet.Data()) != len(original) {
+		t.Errorf("Rewritten frame is %d bytes, want %d", len(packet.Data()), len(original))
+	}
+}
+
+// TestVLANRewriter_ShortFrameIsPadded tests that stripping a minimum-size
+// tagged frame keeps it at the Ethernet minimum
+func TestVLANRewriter_ShortFrameIsPadded(t *testing.T) {
+	frame := make([]byte, 64)
+	copy(frame[12:], []byte{0x81, 0x00, 0x00, 0x0a, 0x08, 0x06}) // VLAN 10, ARP
+
+	vr, _ := NewVLANRewriter(VLANStrip, 0)
+	stripped, err := vr.Rewrite(frame)
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	if len(stripped) != 60 || stripped[12] != 0x08 || stripped[13] != 0x06 {
+		t.Errorf("Stripped frame = %d bytes, EtherType %x, want 60 bytes of ARP", len(stripped), stripped[12:14])
+	}
+
+	vr, _ = NewVLANRewriter(VLANRewrite, 20)
+	untagged := buildUDPFrame(t, "00:aa:bb:cc:dd:01", "00:aa:bb:cc:dd:02", "10.1.1.1", "10.1.1.2")
+	if out, err := vr.Rewrite(untagged); err != nil || !bytes.Equal(out, untagged) {
+		t.Error("Rewrite mode changed an untagged frame")
+	}
+}
+
+// TestNewVLANRewriter_Invalid tests VLAN option validation
+func TestNewVLANRewriter_Invalid(t *testing.T) {
+	tests := []struct {
+		mode   string
+		vlanID int
+	}{
+		{"retag", 0},
+		{VLANRewrite, 0},
+		{VLANRewrite, 4095},
+		{VLANStrip, 10},
+		{"", 10},
+	}
+	for _, tt := range tests {
+		if _, err := NewVLANRewriter(tt.mode, tt.vlanID); err == nil {
+			t.Errorf("NewVLANRewriter(%q, %d) succeeded, want an error", tt.mode, tt.vlanID)
+		}
+	}
+}
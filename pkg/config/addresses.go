@@ -0,0 +1,59 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+)
+
+// validateDeviceAddress checks that ip can be a device's own address: a
+// unicast address, not multicast, unspecified or the limited broadcast.
+func validateDeviceAddress(ip net.IP) error {
+	switch {
+	case ip.IsMulticast():
+		return fmt.Errorf("%s is a multicast address", ip)
+	case ip.IsUnspecified():
+		return fmt.Errorf("%s is the unspecified address", ip)
+	case ip.Equal(net.IPv4bcast):
+		return fmt.Errorf("%s is the broadcast address", ip)
+	}
+	return nil
+}
+
+// validateDHCPv6Pool checks that a pool's network is an IPv6 CIDR and that
+// its range, when given, is a pair of IPv6 addresses inside the network with
+// the start not after the end.
+func validateDHCPv6Pool(pool DHCPv6Pool) error {
+	ip, network, err := net.ParseCIDR(pool.Network)
+	if err != nil || ip.To4() != nil {
+		return fmt.Errorf("network %q is not an IPv6 CIDR prefix", pool.Network)
+	}
+	if pool.RangeStart == "" && pool.RangeEnd == "" {
+		return nil
+	}
+	if pool.RangeStart == "" || pool.RangeEnd == "" {
+		return fmt.Errorf("range_start and range_end must be set together")
+	}
+
+	bounds := make([]net.IP, 0, 2)
+	for _, field := range []struct{ name, value string }{
+		{"range_start", pool.RangeStart},
+		{"range_end", pool.RangeEnd},
+	} {
+		addr := net.ParseIP(field.value)
+		if addr == nil || addr.To4() != nil {
+			return fmt.Errorf("%s %q is not an IPv6 address", field.name, field.value)
+		}
+		if !network.Contains(addr) {
+			return fmt.Errorf("%s %s is outside network %s", field.name, addr, network)
+		}
+		if err := validateDeviceAddress(addr); err != nil {
+			return fmt.Errorf("%s: %v", field.name, err)
+		}
+		bounds = append(bounds, addr)
+	}
+	if bytes.Compare(bounds[0].To16(), bounds[1].To16()) > 0 {
+		return fmt.Errorf("range_start %s is after range_end %s", bounds[0], bounds[1])
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// DefaultBGPHoldTime is the hold time proposed when bgp.hold_time is not set
+// (RFC 4271 suggests 90 seconds)
+const DefaultBGPHoldTime = 90 * time.Second
+
+// BGPConfig describes a passive BGP peer. The device accepts sessions on TCP
+// port 179, completes the OPEN and KEEPALIVE exchange and then keeps the
+// session up with KEEPALIVEs; it advertises no routes.
+type BGPConfig struct {
+	AS       uint32        // Local AS number; above 65535 it is sent as AS_TRANS with the 4-octet AS capability
+	RouterID net.IP        // BGP identifier (IPv4)
+	HoldTime time.Duration // Proposed hold time; 0 disables KEEPALIVEs and the hold timer
+}
+
+// parseBGPConfig parses the passive BGP peer of device. The router ID
+// defaults to the device's first IPv4 address.
+func parseBGPConfig(yamlBgp *converter.BgpConfig, device *Device) (*BGPConfig, error) {
+	if yamlBgp == nil {
+		return nil, nil
+	}
+
+	if yamlBgp.AS == 0 {
+		return nil, fmt.Errorf("device %s: bgp as is required and must be between 1 and 4294967295", device.Name)
+	}
+	bgp := &BGPConfig{AS: yamlBgp.AS, HoldTime: DefaultBGPHoldTime}
+
+	if yamlBgp.HoldTime != nil {
+		hold := *yamlBgp.HoldTime
+		if hold != 0 && (hold < 3 || hold > 65535) {
+			return nil, fmt.Errorf("device %s: bgp hold_time must be 0 or between 3 and 65535 seconds: %d", device.Name, hold)
+		}
+		bgp.HoldTime = time.Duration(hold) * time.Second
+	}
+
+	if yamlBgp.RouterID != "" {
+		bgp.RouterID = net.ParseIP(yamlBgp.RouterID).To4()
+		if bgp.RouterID == nil {
+			return nil, fmt.Errorf("device %s: invalid bgp router_id %q (must be an IPv4 address)", device.Name, yamlBgp.RouterID)
+		}
+	} else {
+		for _, ip := range device.IPAddresses {
+			if ip4 := ip.To4(); ip4 != nil {
+				bgp.RouterID = ip4
+				break
+			}
+		}
+		if bgp.RouterID == nil {
+			return nil, fmt.Errorf("device %s: bgp router_id is required on a device without an IPv4 address", device.Name)
+		}
+	}
+	if bgp.RouterID.IsUnspecified() {
+		return nil, fmt.Errorf("device %s: bgp router_id must not be 0.0.0.0", device.Name)
+	}
+
+	return bgp, nil
+}
@@ -3,12 +3,16 @@ package config
 
 import (
 	"bufio"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/krisarmstrong/niac-go/internal/converter"
 )
@@ -41,9 +45,39 @@ const (
 	// NetBIOS defaults
 	DefaultNetBIOSTTL = 300 // 5 minutes in seconds
 
+	// ARP limits
+	MaxARPReplyDelayMs = 10000 // 10 seconds
+
+	// TCP service limits
+	DefaultMaxTCPConnections = 1024  // Global cap protecting the host from connection floods
+	MaxTCPConnections        = 65535 // Upper bound for max_connections
+
+	// Link MTU (bytes of IP packet per Ethernet frame)
+	DefaultMTU = 1500 // Standard Ethernet
+	JumboMTU   = 9000 // MTU selected by jumbo: true
+	MinMTU     = 576  // Smallest MTU every IPv4 host must accept
+	MaxMTU     = 9216 // Largest jumbo MTU common switches support
+
+	// Bridge MAC learning (dot1dTpAgingTime range from RFC 4188)
+	DefaultBridgeAgingTime = 300     // seconds
+	MinBridgeAgingTime     = 10      // seconds
+	MaxBridgeAgingTime     = 1000000 // seconds
+
+	// Bridge forwarding table capacity (learned MACs)
+	DefaultBridgeMacTableSize = 8192
+	MaxBridgeMacTableSize     = 1048576
+
+	// TTL and hop limit of IP packets a device originates
+	DefaultIPTTL        = 64
+	DefaultIPv6HopLimit = 64
+
 	// ICMP defaults
-	DefaultICMPTTL        = 64 // Default TTL
-	DefaultICMPv6HopLimit = 64 // Default hop limit (NDP uses 255)
+	DefaultICMPTTL           = 64   // Default TTL
+	DefaultICMPv6HopLimit    = 64   // Default hop limit (NDP uses 255)
+	DefaultRouteInfoLifetime = 1800 // RA route_info lifetime in seconds (the router lifetime)
+
+	// DHCP defaults
+	DefaultDHCPRetransmitWindow = 60 // seconds, spanning the RFC 2131 retransmission backoff
 
 	// DHCPv6 defaults
 	DefaultDHCPv6PreferredLifetime = 604800  // 7 days in seconds
@@ -62,9 +96,21 @@ const (
 	DefaultInterfaceErrorThreshold = 100 // error count
 	DefaultTrapCheckInterval       = 300 // 5 minutes in seconds
 	DefaultInterfaceErrorInterval  = 60  // 1 minute in seconds
+	DefaultTrapRateLimitWindow     = 10  // seconds
+
+	// SNMP message size limits (RFC 3417 minimum, largest UDP payload)
+	MinSNMPMaxMessageSize     = 484
+	DefaultSNMPMaxMessageSize = 65507
+
+	// SNMP walk series defaults
+	DefaultWalkSeriesInterval = 60 // seconds per snapshot
 
 	// DNS defaults
 	DefaultDNSTTL = 3600 // 1 hour in seconds
+
+	// DNS forwarding to upstream resolvers
+	DefaultDNSCacheTTL = 300   // seconds a forwarded answer is cached at most
+	MaxDNSCacheTTL     = 86400 // seconds
 )
 
 // Config represents the network configuration
@@ -73,6 +119,35 @@ type Config struct {
 	IncludePath        string              // Base path for walk files
 	CapturePlayback    *CapturePlayback    // Optional PCAP playback config
 	DiscoveryProtocols *DiscoveryProtocols // Discovery protocol configuration
+	Latency            *LatencyConfig      // Default response latency model
+	TCPConfig          *TCPConfig          // Global TCP service limits (nil = defaults)
+	FlowExport         *FlowExportConfig   // Optional sFlow export of observed traffic
+	RunMarker          *RunMarkerConfig    // Optional marker applied to all generated traffic
+	WalkLimits         *WalkLimitsConfig   // Caps on SNMP walk files (nil = defaults)
+	InterfaceScope     InterfaceScope      // Subnets reachable on each capture interface (nil = unscoped)
+}
+
+// InterfaceScope maps capture interfaces to the subnets on their segment. A
+// device answers ARP, NDP and ICMP/ICMPv6 echo arriving on a scoped interface
+// only when one of its addresses is on one of those subnets.
+type InterfaceScope map[string][]*net.IPNet
+
+// Reaches reports whether device is on the segment of capture interface
+// iface. Interfaces without a scope, including an unknown ("") interface,
+// reach every device.
+func (s InterfaceScope) Reaches(iface string, device *Device) bool {
+	subnets, scoped := s[iface]
+	if !scoped {
+		return true
+	}
+	for _, ip := range device.IPAddresses {
+		for _, subnet := range subnets {
+			if subnet.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // CapturePlayback represents PCAP file playback configuration
@@ -80,6 +155,7 @@ type CapturePlayback struct {
 	FileName  string
 	LoopTime  int     // milliseconds
 	ScaleTime float64 // time scaling factor
+	LoopCount int     // Plays before stopping (0 = once, or forever when LoopTime is set; negative = forever)
 }
 
 // DiscoveryProtocols configures discovery protocol behavior
@@ -88,18 +164,75 @@ type DiscoveryProtocols struct {
 	CDP  *ProtocolConfig
 	EDP  *ProtocolConfig
 	FDP  *ProtocolConfig
+
+	MaxNeighbors int // Learned neighbor table cap; least recently seen entries are evicted (0 = default)
+}
+
+// DefaultMaxNeighbors bounds the learned neighbor table when max_neighbors is unset.
+const DefaultMaxNeighbors = 1024
+
+// NeighborTableSize returns the configured neighbor table cap, or
+// DefaultMaxNeighbors when unset.
+func (d *DiscoveryProtocols) NeighborTableSize() int {
+	if d == nil || d.MaxNeighbors <= 0 {
+		return DefaultMaxNeighbors
+	}
+	return d.MaxNeighbors
 }
 
 // ProtocolConfig configures a discovery protocol
 type ProtocolConfig struct {
-	Enabled  bool
-	Interval int // Advertisement interval in seconds
+	Enabled     bool
+	Interval    int     // Advertisement interval in seconds
+	PhaseJitter float64 // Offset each device's first advertisement by up to this fraction of the interval (0-1)
+}
+
+// PhaseJitterFor returns the phase jitter configured for a discovery protocol
+// ("lldp", "cdp", "edp" or "fdp"), or 0 when unset.
+func (d *DiscoveryProtocols) PhaseJitterFor(protocol string) float64 {
+	if d == nil {
+		return 0
+	}
+	var pc *ProtocolConfig
+	switch protocol {
+	case "lldp":
+		pc = d.LLDP
+	case "cdp":
+		pc = d.CDP
+	case "edp":
+		pc = d.EDP
+	case "fdp":
+		pc = d.FDP
+	}
+	if pc == nil {
+		return 0
+	}
+	return pc.PhaseJitter
+}
+
+// validatePhaseJitter ensures every phase_jitter is a fraction between 0 and 1
+func (d *DiscoveryProtocols) validatePhaseJitter() error {
+	for _, protocol := range []string{"lldp", "cdp", "edp", "fdp"} {
+		if jitter := d.PhaseJitterFor(protocol); jitter < 0 || jitter > 1 {
+			return fmt.Errorf("discovery_protocols.%s: phase_jitter must be between 0 and 1: %g", protocol, jitter)
+		}
+	}
+	return nil
+}
+
+// validateMaxNeighbors rejects a negative neighbor table cap
+func (d *DiscoveryProtocols) validateMaxNeighbors() error {
+	if d != nil && d.MaxNeighbors < 0 {
+		return fmt.Errorf("discovery_protocols: max_neighbors must not be negative: %d", d.MaxNeighbors)
+	}
+	return nil
 }
 
 // Device represents a simulated network device
 type Device struct {
 	Name          string
 	Type          string // router, switch, ap, etc.
+	Enabled       bool   // Answers on the network (loaders default to true; see Stack.SetDeviceEnabled)
 	MACAddress    net.HardwareAddr
 	IPAddresses   []net.IP
 	Interfaces    []Interface
@@ -114,6 +247,10 @@ type Device struct {
 	HTTPConfig    *HTTPConfig    // HTTP server configuration
 	FTPConfig     *FTPConfig     // FTP server configuration
 	NetBIOSConfig *NetBIOSConfig // NetBIOS service configuration
+	ARPConfig     *ARPConfig     // ARP responder configuration (proxy ARP, reply delay)
+	Latency       *LatencyConfig // Response latency model (overrides Config.Latency)
+	TCPConfig     *TCPConfig     // TCP service limits (connection cap)
+	BridgeConfig  *BridgeConfig  // MAC learning; non-nil exposes the BRIDGE-MIB forwarding table
 	ICMPConfig    *ICMPConfig    // ICMP/ICMPv4 configuration
 	ICMPv6Config  *ICMPv6Config  // ICMPv6 configuration
 	DHCPv6Config  *DHCPv6Config  // DHCPv6 server configuration
@@ -121,6 +258,58 @@ type Device struct {
 	PortChannels  []PortChannel  // Port-channel/LAG configuration (v1.23.0)
 	TrunkPorts    []TrunkPort    // Trunk port configuration (v1.23.0)
 	Properties    map[string]string
+	Tags          []string          // Logical groups used for bulk operations and device filters
+	MTU           int               // Link MTU in bytes (0 = DefaultMTU)
+	IPTTL         uint8             // TTL of originated IPv4 packets (0 = DefaultIPTTL)
+	HopLimit      uint8             // Hop limit of originated IPv6 packets (0 = DefaultIPv6HopLimit)
+	BootDelay     time.Duration     // Silent period after startup before the device answers (0 = immediate)
+	BootSequence  *BootSequence     // Staged startup: link up, coldStart, discovery, SNMP (nil = none)
+	TCPPorts      map[uint16]string // Simulated TCP port states: open, closed or filtered (unlisted = built-in services answer, other ports closed)
+	PoE           *PoEConfig        // Power needs advertised in LLDP and CDP (nil = not a powered device)
+	BGP           *BGPConfig        // Passive BGP peer on TCP port 179 (nil = port 179 closed)
+
+	MalformedFrames *MalformedFramesConfig // Deliberately corrupt frames sent for fuzzing (nil = none)
+}
+
+// BootSequence brings a device up in stages, in this order. Each delay is
+// measured from the previous stage; LinkUp from the start of the simulation.
+type BootSequence struct {
+	LinkUp    time.Duration // Joins the network: answers ARP, ICMP and services other than SNMP
+	ColdStart time.Duration // Sends the coldStart trap; sysUpTime starts
+	Discovery time.Duration // Starts LLDP, CDP, EDP and FDP advertisements
+	SNMP      time.Duration // Answers SNMP requests
+}
+
+// LinkMTU returns the device's link MTU, falling back to DefaultMTU.
+func (d *Device) LinkMTU() int {
+	if d != nil && d.MTU > 0 {
+		return d.MTU
+	}
+	return DefaultMTU
+}
+
+// TTL returns the TTL of IPv4 packets the device originates, falling back to
+// DefaultIPTTL.
+func (d *Device) TTL() uint8 {
+	if d != nil && d.IPTTL > 0 {
+		return d.IPTTL
+	}
+	return DefaultIPTTL
+}
+
+// IPv6HopLimit returns the hop limit of IPv6 packets the device originates,
+// falling back to DefaultIPv6HopLimit. NDP messages always use 255.
+func (d *Device) IPv6HopLimit() uint8 {
+	if d != nil && d.HopLimit > 0 {
+		return d.HopLimit
+	}
+	return DefaultIPv6HopLimit
+}
+
+// MaxFrameSize returns the largest Ethernet frame the device sends or
+// accepts: the MTU plus the 14-byte header and 4-byte FCS.
+func (d *Device) MaxFrameSize() int {
+	return d.LinkMTU() + 18
 }
 
 // DHCPConfig holds DHCP server configuration for a device
@@ -137,6 +326,17 @@ type DHCPConfig struct {
 	PoolStart net.IP // Start of DHCP address pool
 	PoolEnd   net.IP // End of DHCP address pool
 
+	// Reply addressing
+	AlwaysBroadcast bool // Broadcast Offers/Acks even when the client accepts unicast
+
+	// DISCOVER retransmissions (same MAC and xid) within this window get the
+	// original Offer again
+	RetransmitWindow time.Duration
+
+	// An expired lease's address is not offered to another client until this
+	// long after expiry (0 = reusable at expiry)
+	LeaseGrace time.Duration
+
 	// DHCPv4 high priority options
 	NTPServers     []net.IP
 	DomainSearch   []string
@@ -144,6 +344,9 @@ type DHCPConfig struct {
 	BootfileName   string
 	VendorSpecific []byte // Hex-encoded vendor-specific data
 
+	// Option sets for classes of clients, first match wins
+	ClientClasses []DHCPClientClass
+
 	// DHCPv6 options
 	SNTPServersV6 []net.IP
 	NTPServersV6  []net.IP
@@ -154,6 +357,37 @@ type DHCPConfig struct {
 	ClientLeases []DHCPLease
 }
 
+// DHCPClientClass hands its options to clients whose vendor-class-identifier
+// (option 60) and user class (option 77) match. Its TFTP server and bootfile
+// replace the server-wide ones, and Options are added to or replace the
+// reply's options.
+type DHCPClientClass struct {
+	Name           string
+	VendorClass    *regexp.Regexp // nil matches any client
+	UserClass      *regexp.Regexp // nil matches any client
+	TFTPServerName string         // Option 66
+	BootfileName   string         // Option 67
+	NextServerIP   net.IP         // siaddr, the server to boot from
+	Options        map[uint8][]byte
+}
+
+// Matches reports whether a client with the given classes belongs to the
+// class: its vendor class and one of its user classes must both match
+func (c *DHCPClientClass) Matches(vendorClass string, userClasses []string) bool {
+	if c.VendorClass != nil && !c.VendorClass.MatchString(vendorClass) {
+		return false
+	}
+	if c.UserClass == nil {
+		return true
+	}
+	for _, userClass := range userClasses {
+		if c.UserClass.MatchString(userClass) {
+			return true
+		}
+	}
+	return false
+}
+
 // DHCPLease represents a static DHCP lease assignment
 type DHCPLease struct {
 	ClientIP   net.IP
@@ -165,6 +399,8 @@ type DHCPLease struct {
 type DNSConfig struct {
 	ForwardRecords []DNSRecord
 	ReverseRecords []DNSRecord
+	Forwarders     []string      // Upstream resolvers as host:port, asked for names without a local record
+	CacheTTL       time.Duration // Longest time a forwarded answer is cached (default: 300s)
 }
 
 // DNSRecord represents a DNS A or PTR record
@@ -174,6 +410,10 @@ type DNSRecord struct {
 	TTL  uint32
 }
 
+// MaxInterfaceDescription is the longest interface description, the
+// DisplayString (SIZE(0..64)) limit of ifAlias
+const MaxInterfaceDescription = 64
+
 // Interface represents a network interface on a device
 type Interface struct {
 	Name        string
@@ -181,7 +421,7 @@ type Interface struct {
 	Duplex      string
 	AdminStatus string // up, down
 	OperStatus  string // up, down, testing
-	Description string
+	Description string // ifDescr, ifAlias and the LLDP Port Description
 	VLANs       []int
 }
 
@@ -192,8 +432,170 @@ type SNMPConfig struct {
 	SysDescr    string
 	SysContact  string
 	SysLocation string
-	WalkFile    string      // Path to SNMP walk file
-	Traps       *TrapConfig // SNMP trap configuration (v1.6.0)
+	WalkFile    string          // Path to SNMP walk file
+	WalkSeries  *WalkSeries     // Walk snapshots replayed over time (applied over WalkFile)
+	WalkFiles   []string        // .walk files from walk_directory, merged in name order over WalkFile
+	Traps       *TrapConfig     // SNMP trap configuration (v1.6.0)
+	Communities []SNMPCommunity // Additional communities with MIB views
+
+	AllowedManagers []string // CIDRs whose requests are answered (empty = all sources)
+
+	ResponseSourcePort string // SNMPResponsePortStandard (default) or SNMPResponsePortEphemeral
+
+	MissingInstance string // Exception for a GET of a missing instance of a known object
+
+	MaxMessageSize int // Largest response message in bytes (default: 65507)
+
+	AdminStatusSet string // Response to a SET of ifAdminStatus: SNMPAdminStatusSetReadOnly (default) or SNMPAdminStatusSetLinkState
+
+	Writable []string // Objects a SET may change (SNMPWritable* values); nil = the default, empty = none
+
+	Quirks []string // Vendor quirks deliberately introduced in responses (SNMPQuirk* values)
+
+	HostResources *HostResourcesConfig // HOST-RESOURCES-MIB tables (nil = none unless Type is "server")
+
+	Contexts []SNMPContext // SNMPv3 contexts, each a logical device with its own MIB
+
+	DisabledSubtrees []string // OID subtrees answered as absent, even when a walk file has data there
+
+	V3 *SNMPv3Config // SNMPv3 USM user (nil = SNMPv3 only at noAuthNoPriv, for contexts)
+
+	SlowWalk *SNMPSlowWalk // Walk responses slow down under injected High CPU (nil = never)
+
+	EngineBoots int           // snmpEngineBoots at startup (0 = 1); reboots increment it
+	EngineTime  time.Duration // Time since the last boot at startup, reported in snmpEngineTime and sysUpTime
+}
+
+// MaxSNMPEngineBoots is the highest snmpEngineBoots. An agent that reaches it
+// accepts no authenticated requests until reconfigured (RFC 3414 section 2.2.3).
+const MaxSNMPEngineBoots = 2147483647
+
+// SNMPSlowWalk imitates an overloaded agent. While a High CPU error is
+// injected, each GETNEXT/GETBULK of a walk is answered Step (scaled by the CPU
+// percentage) later than the one before, and a request that would wait longer
+// than Max is dropped, so the walk times out.
+type SNMPSlowWalk struct {
+	Step    time.Duration // Delay added per request at 100% CPU
+	Max     time.Duration // Longest delay before requests are dropped
+	WalkGap time.Duration // A manager silent this long starts a new walk
+}
+
+// SNMP slow walk defaults
+const (
+	DefaultSNMPSlowWalkStep    = 100 * time.Millisecond
+	DefaultSNMPSlowWalkMax     = 2 * time.Second
+	DefaultSNMPSlowWalkWalkGap = 10 * time.Second
+)
+
+// SNMPv3Config is the SNMPv3 USM user an agent accepts authenticated, and
+// optionally encrypted, requests from.
+type SNMPv3Config struct {
+	User         string
+	AuthProtocol string // SNMPv3AuthMD5 or SNMPv3AuthSHA
+	AuthKey      string // Authentication passphrase
+	PrivProtocol string // SNMPv3PrivDES, SNMPv3PrivAES or "" for authNoPriv only
+	PrivKey      string // Privacy passphrase
+}
+
+// SNMPv3 USM authentication and privacy protocols
+const (
+	SNMPv3AuthMD5 = "md5" // HMAC-MD5-96 (RFC 3414)
+	SNMPv3AuthSHA = "sha" // HMAC-SHA-96 (RFC 3414)
+	SNMPv3PrivDES = "des" // CBC-DES (RFC 3414)
+	SNMPv3PrivAES = "aes" // CFB128-AES-128 (RFC 3826)
+)
+
+// snmpV3MinKeyLength is the shortest passphrase RFC 3414 section 11.2 allows
+const snmpV3MinKeyLength = 8
+
+// SNMPContext is a logical device sharing the agent's IP, selected by the
+// SNMPv3 context name (like a VDC or virtual context of a chassis).
+type SNMPContext struct {
+	Name     string
+	SysName  string // sysName.0 (default "<device>-<context>")
+	WalkFile string // Path to the context's SNMP walk file
+}
+
+// WalkSeries is a sequence of walk snapshots, in time order, that the agent
+// steps through so polls see the recorded values evolve.
+type WalkSeries struct {
+	Files    []string      // Snapshot files, sorted by name
+	Interval time.Duration // Time each snapshot is served
+	Loop     bool          // Restart after the last snapshot instead of holding it
+}
+
+// SNMP response source port behaviors
+const (
+	SNMPResponsePortStandard  = "standard"  // Reply from UDP 161, as real agents do
+	SNMPResponsePortEphemeral = "ephemeral" // Reply from a random high port to exercise firewall pinholes
+)
+
+// SNMP exceptions for a GET of a missing instance of an object the agent has
+const (
+	SNMPMissingInstanceNoSuchInstance = "no_such_instance" // noSuchInstance, as RFC 3416 agents answer
+	SNMPMissingInstanceNoSuchObject   = "no_such_object"   // noSuchObject, like agents that do not distinguish
+)
+
+// SNMP responses to a SET of ifAdminStatus
+const (
+	SNMPAdminStatusSetReadOnly  = "read_only"  // Rejected with notWritable
+	SNMPAdminStatusSetLinkState = "link_state" // Shuts or enables the interface, as a real switch does
+)
+
+// SNMP objects a device's writable list can name. Without a list only
+// ifAdminStatus is writable, and only when AdminStatusSet is link_state.
+const (
+	SNMPWritableSysContact    = "sysContact"
+	SNMPWritableSysName       = "sysName"
+	SNMPWritableSysLocation   = "sysLocation"
+	SNMPWritableIfAdminStatus = "ifAdminStatus"
+)
+
+// IsWritable reports whether a SET may change the named object
+// (an SNMPWritable* value)
+func (c *SNMPConfig) IsWritable(object string) bool {
+	if object == SNMPWritableIfAdminStatus && c.AdminStatusSet != SNMPAdminStatusSetLinkState {
+		return false
+	}
+	if c.Writable == nil {
+		return object == SNMPWritableIfAdminStatus
+	}
+	return contains(c.Writable, object)
+}
+
+// SNMP agent quirks a device can imitate (snmp_quirks)
+const (
+	SNMPQuirkTrailingNull        = "trailing_null"         // Text strings end with a NUL byte
+	SNMPQuirkNoEndOfMibView      = "no_endofmibview"       // The end of the MIB echoes the requested OID with a NULL instead of endOfMibView
+	SNMPQuirkTruncateLongStrings = "truncate_long_strings" // Text strings are cut to SNMPQuirkStringLimit bytes
+)
+
+// SNMPQuirkStringLimit is the length truncate_long_strings cuts text strings to
+const SNMPQuirkStringLimit = 64
+
+// HasQuirk reports whether the agent imitates the named quirk
+func (c *SNMPConfig) HasQuirk(quirk string) bool {
+	for _, q := range c.Quirks {
+		if q == quirk {
+			return true
+		}
+	}
+	return false
+}
+
+// SNMPCommunity defines a community string and its MIB view. A nil View grants
+// access to the whole MIB.
+type SNMPCommunity struct {
+	Name string
+	View *SNMPView
+}
+
+// SNMPView restricts access to OID subtrees. The longest matching subtree
+// decides whether an OID is visible; with no Included subtrees everything not
+// excluded is visible.
+type SNMPView struct {
+	Included []string
+	Excluded []string
 }
 
 // LLDPConfig holds LLDP (Link Layer Discovery Protocol) configuration
@@ -204,6 +606,7 @@ type LLDPConfig struct {
 	SystemDescription string
 	PortDescription   string
 	ChassisIDType     string // "mac", "local", "network_address"
+	FastStart         bool   // Advertise immediately when a new neighbor is heard (LLDP-MED fast start)
 }
 
 // CDPConfig holds CDP (Cisco Discovery Protocol) configuration
@@ -289,22 +692,112 @@ type NetBIOSConfig struct {
 
 // ICMPConfig holds ICMP/ICMPv4 configuration
 type ICMPConfig struct {
-	Enabled   bool
-	TTL       uint8 // Time to Live for ICMP packets (default: 64)
-	RateLimit int   // Max ICMP responses per second (0 = unlimited, default: 0)
+	Enabled                bool
+	TTL                    uint8 // Time to Live for ICMP packets (default: 64)
+	RateLimit              int   // Max ICMP responses per second (0 = unlimited, default: 0)
+	RespondToBroadcastPing bool  // Answer echo requests sent to a broadcast or multicast address (default: false)
+
+	// reply_offsubnet: false drops echo requests from outside LocalSubnets,
+	// as a host with no default route would
+	DropOffSubnet bool         // Answer echo requests only from LocalSubnets (default: false)
+	LocalSubnets  []*net.IPNet // The device's IPv4 subnets
+}
+
+// AnswersSource reports whether an echo request from src is answered: always,
+// unless DropOffSubnet limits replies to the device's own subnets
+func (c *ICMPConfig) AnswersSource(src net.IP) bool {
+	if c == nil || !c.DropOffSubnet {
+		return true
+	}
+	for _, subnet := range c.LocalSubnets {
+		if subnet.Contains(src) {
+			return true
+		}
+	}
+	return false
+}
+
+// Simulated TCP port states (tcp_ports)
+const (
+	TCPPortOpen     = "open"     // Answer SYN with SYN-ACK and complete the handshake
+	TCPPortClosed   = "closed"   // Answer SYN with RST
+	TCPPortFiltered = "filtered" // Silently drop every segment
+)
+
+// TCPConfig holds limits for the simulated TCP services (HTTP, FTP)
+type TCPConfig struct {
+	MaxConnections int // Concurrent connections before new ones are refused with RST (0 = no per-device limit)
+}
+
+// WalkLimitsConfig caps the SNMP walk files loaded, so an oversized capture
+// fails with an error instead of exhausting memory
+type WalkLimitsConfig struct {
+	MaxFileSize int64 // Largest walk file in bytes (0 = default)
+	MaxOIDs     int   // Most OIDs in one walk file (0 = default)
+}
+
+// BridgeConfig holds a switch's MAC learning configuration
+type BridgeConfig struct {
+	AgingTime    time.Duration // Idle time before a learned MAC is aged out
+	MacTableSize int           // Learned MACs the forwarding table holds (default: 8192)
+}
+
+// MaxConnections returns the global TCP connection cap, falling back to
+// DefaultMaxTCPConnections.
+func (c *Config) MaxConnections() int {
+	if c != nil && c.TCPConfig != nil && c.TCPConfig.MaxConnections > 0 {
+		return c.TCPConfig.MaxConnections
+	}
+	return DefaultMaxTCPConnections
+}
+
+// ARPConfig holds ARP responder configuration
+type ARPConfig struct {
+	ProxyARPSubnets []*net.IPNet // Answer ARP for any address in these subnets with the device MAC
+	ReplyDelayMs    int          // Delay before sending ARP replies (0 = immediate)
+}
+
+// ProxiesFor reports whether ip falls in one of the proxy ARP subnets.
+func (c *ARPConfig) ProxiesFor(ip net.IP) bool {
+	if c == nil {
+		return false
+	}
+	for _, subnet := range c.ProxyARPSubnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
 
 // ICMPv6Config holds ICMPv6 configuration
 type ICMPv6Config struct {
 	Enabled   bool
-	HopLimit  uint8 // Hop limit for ICMPv6 packets (default: 64, NDP uses 255)
-	RateLimit int   // Max ICMPv6 responses per second (0 = unlimited, default: 0)
+	HopLimit  uint8             // Hop limit for ICMPv6 packets (default: 64, NDP uses 255)
+	RateLimit int               // Max ICMPv6 responses per second (0 = unlimited, default: 0)
+	RouteInfo []RouteInfoOption // Routes advertised in Router Advertisements (RFC 4191)
+}
+
+// Route preferences for Route Information Options (RFC 4191)
+const (
+	RoutePreferenceHigh   = "high"
+	RoutePreferenceMedium = "medium" // Default
+	RoutePreferenceLow    = "low"
+)
+
+// RouteInfoOption is a more-specific route advertised in Router
+// Advertisements as a Route Information Option
+type RouteInfoOption struct {
+	Prefix     *net.IPNet
+	Preference string // high, medium or low (default: medium)
+	Lifetime   uint32 // Route lifetime in seconds (default: 1800, 0xffffffff = infinity)
 }
 
 // DHCPv6Config holds DHCPv6 server configuration
 type DHCPv6Config struct {
 	Enabled           bool
 	Pools             []DHCPv6Pool // Address pools
+	DelegatedPrefixes []net.IPNet  // Prefixes delegated to requesting routers (IA_PD)
 	PreferredLifetime uint32       // Preferred lifetime in seconds (default: 604800 = 7 days)
 	ValidLifetime     uint32       // Valid lifetime in seconds (default: 2592000 = 30 days)
 	Preference        uint8        // Server preference (0-255, higher is better, default: 0)
@@ -314,6 +807,10 @@ type DHCPv6Config struct {
 	NTPServers        []net.IP     // NTP servers (Option 56)
 	SIPServers        []net.IP     // SIP server addresses (Option 22)
 	SIPDomains        []string     // SIP domain names (Option 21)
+
+	// Expired addresses are not assigned to another client until this long
+	// after their valid lifetime ends (0 = reusable at expiry)
+	LeaseGrace time.Duration
 }
 
 // DHCPv6Pool represents an IPv6 address pool
@@ -334,28 +831,31 @@ type TrafficConfig struct {
 // ARPAnnouncementConfig configures gratuitous ARP announcements
 type ARPAnnouncementConfig struct {
 	Enabled  bool
-	Interval int // Interval in seconds (default: 60)
+	Interval int              // Interval in seconds (default: 60)
+	Schedule *TrafficSchedule // Active windows (nil = always)
 }
 
 // PeriodicPingConfig configures periodic ICMP echo requests
 type PeriodicPingConfig struct {
 	Enabled     bool
-	Interval    int // Interval in seconds (default: 120)
-	PayloadSize int // Payload size in bytes (default: 32)
+	Interval    int              // Interval in seconds (default: 120)
+	PayloadSize int              // Payload size in bytes (default: 32)
+	Schedule    *TrafficSchedule // Active windows (nil = always)
 }
 
 // RandomTrafficConfig configures random background traffic
 type RandomTrafficConfig struct {
 	Enabled     bool
-	Interval    int      // Interval in seconds (default: 180)
-	PacketCount int      // Number of packets per interval (default: 5)
-	Patterns    []string // Traffic patterns: "broadcast_arp", "multicast", "udp"
+	Interval    int              // Interval in seconds (default: 180)
+	PacketCount int              // Number of packets per interval (default: 5)
+	Patterns    []string         // Traffic patterns: "broadcast_arp", "multicast", "udp"
+	Schedule    *TrafficSchedule // Active windows (nil = always)
 }
 
 // TrapConfig holds SNMP trap configuration (v1.6.0)
 type TrapConfig struct {
 	Enabled               bool
-	Receivers             []string // Trap receiver addresses (IP:port format)
+	Receivers             []string // Trap receiver addresses: IP, IP:port or [IPv6]:port (see ParseTrapReceiver)
 	Community             string   // SNMP community string (default: "public")
 	ColdStart             *TrapTriggerConfig
 	LinkState             *LinkStateTrapConfig
@@ -363,6 +863,17 @@ type TrapConfig struct {
 	HighCPU               *ThresholdTrapConfig
 	HighMemory            *ThresholdTrapConfig
 	InterfaceErrors       *ThresholdTrapConfig
+	Varbinds              []TrapVarbind  // Extra varbinds appended to outgoing traps
+	RateLimit             *TrapRateLimit // Per-receiver trap budget (nil = unlimited)
+}
+
+// TrapRateLimit caps the traps delivered to each receiver per window. The
+// budget is shared by every device sending to the same receiver under the same
+// limit; traps over it are suppressed and reported to the receiver in a
+// summary trap when the window closes.
+type TrapRateLimit struct {
+	MaxTraps int
+	Window   time.Duration
 }
 
 // TrapTriggerConfig configures a simple trap trigger
@@ -404,6 +915,7 @@ type TrunkPort struct {
 // Load reads and parses a configuration file
 // Automatically detects format based on file extension:
 // - .yaml -> YAML format (converted from Java DSL)
+// - .toml -> TOML format, with the same keys as YAML
 // - .cfg, .conf, or other -> legacy key-value format
 func Load(filename string) (*Config, error) {
 	ext := filepath.Ext(filename)
@@ -412,6 +924,9 @@ func Load(filename string) (*Config, error) {
 	if ext == ".yaml" || ext == ".yml" {
 		return LoadYAML(filename)
 	}
+	if ext == ".toml" {
+		return LoadTOML(filename)
+	}
 
 	// Route to legacy format loader
 	return LoadLegacy(filename)
@@ -452,6 +967,7 @@ func LoadLegacy(filename string) (*Config, error) {
 
 			device := Device{
 				Name:       parts[1],
+				Enabled:    true,
 				Interfaces: make([]Interface, 0),
 				Properties: make(map[string]string),
 			}
@@ -540,6 +1056,17 @@ func (c *Config) GetDeviceByIP(ip net.IP) *Device {
 	return nil
 }
 
+// strictYAML makes YAML loads reject unknown keys (see SetStrictYAML).
+var strictYAML atomic.Bool
+
+// SetStrictYAML controls whether YAML and TOML configuration loads fail on
+// keys that match no configuration field, such as a misspelled `comunity:`.
+// It applies to every subsequent load, including reloads. The default is
+// lenient: unknown keys are ignored.
+func SetStrictYAML(strict bool) {
+	strictYAML.Store(strict)
+}
+
 // LoadYAML loads a YAML configuration file
 func LoadYAML(filename string) (*Config, error) {
 	yamlConfig, err := loadYAMLFile(filename)
@@ -549,6 +1076,19 @@ func LoadYAML(filename string) (*Config, error) {
 	return buildConfigFromYAML(yamlConfig)
 }
 
+// LoadTOML loads a TOML configuration file. It is read into the YAML config
+// structure, so devices are converted and validated exactly as in YAML.
+func LoadTOML(filename string) (*Config, error) {
+	tomlConfig, err := converter.LoadTOMLConfig(filename, strictYAML.Load())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TOML config: %w", err)
+	}
+	if _, err := validateYAMLConfig(tomlConfig); err != nil {
+		return nil, err
+	}
+	return buildConfigFromYAML(tomlConfig)
+}
+
 // LoadYAMLBytes builds a runtime config from in-memory YAML data.
 func LoadYAMLBytes(data []byte) (*Config, error) {
 	yamlConfig, err := loadYAMLBytes(data)
@@ -560,7 +1100,7 @@ func LoadYAMLBytes(data []byte) (*Config, error) {
 
 // loadYAMLFile loads and validates a YAML configuration file
 func loadYAMLFile(filename string) (*converter.Config, error) {
-	yamlConfig, err := converter.LoadYAMLConfig(filename)
+	yamlConfig, err := converter.LoadYAMLConfig(filename, strictYAML.Load())
 	if err != nil {
 		return nil, fmt.Errorf("failed to load YAML config: %w", err)
 	}
@@ -568,7 +1108,7 @@ func loadYAMLFile(filename string) (*converter.Config, error) {
 }
 
 func loadYAMLBytes(data []byte) (*converter.Config, error) {
-	yamlConfig, err := converter.LoadYAMLConfigFromBytes(data)
+	yamlConfig, err := converter.LoadYAMLConfigFromBytes(data, strictYAML.Load())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse YAML config: %w", err)
 	}
@@ -584,6 +1124,38 @@ func validateYAMLConfig(yamlConfig *converter.Config) (*converter.Config, error)
 
 func buildConfigFromYAML(yamlConfig *converter.Config) (*Config, error) {
 	cfg := createBaseConfig(yamlConfig)
+	if err := cfg.DiscoveryProtocols.validatePhaseJitter(); err != nil {
+		return nil, err
+	}
+	if err := cfg.DiscoveryProtocols.validateMaxNeighbors(); err != nil {
+		return nil, err
+	}
+
+	latency, err := parseLatencyConfig(yamlConfig.Latency, "global")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Latency = latency
+
+	if cfg.TCPConfig, err = parseTCPConfig(yamlConfig.Tcp, "global"); err != nil {
+		return nil, err
+	}
+
+	if cfg.FlowExport, err = parseFlowExportConfig(yamlConfig.FlowExport); err != nil {
+		return nil, err
+	}
+
+	if cfg.RunMarker, err = parseRunMarkerConfig(yamlConfig.RunMarker); err != nil {
+		return nil, err
+	}
+
+	if cfg.WalkLimits, err = parseWalkLimitsConfig(yamlConfig.WalkLimits); err != nil {
+		return nil, err
+	}
+
+	if cfg.InterfaceScope, err = parseInterfaceScope(yamlConfig.InterfaceScope); err != nil {
+		return nil, err
+	}
 
 	for _, yamlDevice := range yamlConfig.Devices {
 		device, err := convertYAMLDevice(yamlDevice, cfg.IncludePath)
@@ -618,33 +1190,39 @@ func createBaseConfig(yamlConfig *converter.Config) *Config {
 
 	// Copy DiscoveryProtocols if present
 	if yamlConfig.DiscoveryProtocols != nil {
-		cfg.DiscoveryProtocols = &DiscoveryProtocols{}
+		cfg.DiscoveryProtocols = &DiscoveryProtocols{
+			MaxNeighbors: yamlConfig.DiscoveryProtocols.MaxNeighbors,
+		}
 
 		if yamlConfig.DiscoveryProtocols.LLDP != nil {
 			cfg.DiscoveryProtocols.LLDP = &ProtocolConfig{
-				Enabled:  yamlConfig.DiscoveryProtocols.LLDP.Enabled,
-				Interval: yamlConfig.DiscoveryProtocols.LLDP.Interval,
+				Enabled:     yamlConfig.DiscoveryProtocols.LLDP.Enabled,
+				Interval:    yamlConfig.DiscoveryProtocols.LLDP.Interval,
+				PhaseJitter: yamlConfig.DiscoveryProtocols.LLDP.PhaseJitter,
 			}
 		}
 
 		if yamlConfig.DiscoveryProtocols.CDP != nil {
 			cfg.DiscoveryProtocols.CDP = &ProtocolConfig{
-				Enabled:  yamlConfig.DiscoveryProtocols.CDP.Enabled,
-				Interval: yamlConfig.DiscoveryProtocols.CDP.Interval,
+				Enabled:     yamlConfig.DiscoveryProtocols.CDP.Enabled,
+				Interval:    yamlConfig.DiscoveryProtocols.CDP.Interval,
+				PhaseJitter: yamlConfig.DiscoveryProtocols.CDP.PhaseJitter,
 			}
 		}
 
 		if yamlConfig.DiscoveryProtocols.EDP != nil {
 			cfg.DiscoveryProtocols.EDP = &ProtocolConfig{
-				Enabled:  yamlConfig.DiscoveryProtocols.EDP.Enabled,
-				Interval: yamlConfig.DiscoveryProtocols.EDP.Interval,
+				Enabled:     yamlConfig.DiscoveryProtocols.EDP.Enabled,
+				Interval:    yamlConfig.DiscoveryProtocols.EDP.Interval,
+				PhaseJitter: yamlConfig.DiscoveryProtocols.EDP.PhaseJitter,
 			}
 		}
 
 		if yamlConfig.DiscoveryProtocols.FDP != nil {
 			cfg.DiscoveryProtocols.FDP = &ProtocolConfig{
-				Enabled:  yamlConfig.DiscoveryProtocols.FDP.Enabled,
-				Interval: yamlConfig.DiscoveryProtocols.FDP.Interval,
+				Enabled:     yamlConfig.DiscoveryProtocols.FDP.Enabled,
+				Interval:    yamlConfig.DiscoveryProtocols.FDP.Interval,
+				PhaseJitter: yamlConfig.DiscoveryProtocols.FDP.PhaseJitter,
 			}
 		}
 	}
@@ -657,6 +1235,7 @@ func convertYAMLDevice(yamlDevice converter.Device, includePath string) (Device,
 	device := Device{
 		Name:       yamlDevice.Name,
 		Type:       "unknown", // Default type
+		Enabled:    true,
 		Interfaces: make([]Interface, 0),
 		Properties: make(map[string]string),
 		SNMPConfig: SNMPConfig{
@@ -689,65 +1268,623 @@ func convertYAMLDevice(yamlDevice converter.Device, includePath string) (Device,
 		device.Properties["vlan"] = fmt.Sprintf("%d", yamlDevice.VLAN)
 	}
 
-	// Parse protocol configurations
-	if err := parseDeviceProtocolConfigs(&device, &yamlDevice); err != nil {
-		return device, err
+	// Copy tags, dropping blanks and duplicates
+	device.Tags = normalizeTags(yamlDevice.Tags)
+
+	// Link MTU (jumbo frames)
+	if err := parseDeviceMTU(&device, &yamlDevice); err != nil {
+		return device, err
+	}
+
+	// Interfaces, in ifIndex order
+	if err := parseDeviceInterfaces(&device, &yamlDevice); err != nil {
+		return device, err
+	}
+
+	// TTL and hop limit of originated packets
+	if err := parseDeviceTTL(&device, &yamlDevice); err != nil {
+		return device, err
+	}
+
+	if yamlDevice.BootDelay < 0 {
+		return device, fmt.Errorf("device %s: boot_delay must not be negative: %d", device.Name, yamlDevice.BootDelay)
+	}
+	device.BootDelay = time.Duration(yamlDevice.BootDelay) * time.Second
+	if err := parseBootSequence(&device, &yamlDevice); err != nil {
+		return device, err
+	}
+
+	tcpPorts, err := parseTCPPorts(yamlDevice.TcpPorts, device.Name)
+	if err != nil {
+		return device, err
+	}
+	device.TCPPorts = tcpPorts
+
+	// Parse protocol configurations
+	if err := parseDeviceProtocolConfigs(&device, &yamlDevice); err != nil {
+		return device, err
+	}
+
+	return device, nil
+}
+
+// parseDeviceMTU sets the device's link MTU from mtu or the jumbo shorthand.
+func parseDeviceMTU(device *Device, yamlDevice *converter.Device) error {
+	mtu := yamlDevice.MTU
+	if mtu == 0 && yamlDevice.Jumbo {
+		mtu = JumboMTU
+	}
+	if mtu == 0 {
+		return nil
+	}
+	if mtu < MinMTU || mtu > MaxMTU {
+		return fmt.Errorf("device %s: mtu must be between %d and %d: %d", device.Name, MinMTU, MaxMTU, mtu)
+	}
+	if yamlDevice.Jumbo && mtu <= DefaultMTU {
+		return fmt.Errorf("device %s: jumbo requires an mtu above %d: %d", device.Name, DefaultMTU, mtu)
+	}
+	device.MTU = mtu
+	return nil
+}
+
+// parseDeviceInterfaces sets the device's interfaces. A description is
+// reported as ifDescr and ifAlias and in the LLDP Port Description, so it is
+// limited to the 64 characters ifAlias allows.
+func parseDeviceInterfaces(device *Device, yamlDevice *converter.Device) error {
+	seen := make(map[string]bool)
+	for i, yamlIface := range yamlDevice.Interfaces {
+		name := strings.TrimSpace(yamlIface.Name)
+		if name == "" {
+			return fmt.Errorf("device %s: interfaces[%d]: name is required", device.Name, i)
+		}
+		if seen[name] {
+			return fmt.Errorf("device %s: interfaces[%d]: duplicate interface %s", device.Name, i, name)
+		}
+		seen[name] = true
+		if len(yamlIface.Description) > MaxInterfaceDescription {
+			return fmt.Errorf("device %s: interface %s: description must be at most %d characters: %d",
+				device.Name, name, MaxInterfaceDescription, len(yamlIface.Description))
+		}
+		device.Interfaces = append(device.Interfaces, Interface{Name: name, Description: yamlIface.Description})
+	}
+	return nil
+}
+
+// parseBootSequence sets the device's staged startup. It replaces boot_delay,
+// so the two cannot be combined.
+func parseBootSequence(device *Device, yamlDevice *converter.Device) error {
+	seq := yamlDevice.BootSequence
+	if seq == nil {
+		return nil
+	}
+	if yamlDevice.BootDelay != 0 {
+		return fmt.Errorf("device %s: boot_delay and boot_sequence cannot be combined", device.Name)
+	}
+	stages := []struct {
+		name  string
+		delay int
+	}{
+		{"link_up", seq.LinkUp},
+		{"cold_start", seq.ColdStart},
+		{"discovery", seq.Discovery},
+		{"snmp", seq.Snmp},
+	}
+	for _, stage := range stages {
+		if stage.delay < 0 {
+			return fmt.Errorf("device %s: boot_sequence %s must not be negative: %d", device.Name, stage.name, stage.delay)
+		}
+	}
+	device.BootSequence = &BootSequence{
+		LinkUp:    time.Duration(seq.LinkUp) * time.Second,
+		ColdStart: time.Duration(seq.ColdStart) * time.Second,
+		Discovery: time.Duration(seq.Discovery) * time.Second,
+		SNMP:      time.Duration(seq.Snmp) * time.Second,
+	}
+	return nil
+}
+
+// parseDeviceTTL sets the TTL and hop limit of the device's originated packets.
+func parseDeviceTTL(device *Device, yamlDevice *converter.Device) error {
+	if yamlDevice.IPTTL < 0 || yamlDevice.IPTTL > 255 {
+		return fmt.Errorf("device %s: ip_ttl must be between 1 and 255: %d", device.Name, yamlDevice.IPTTL)
+	}
+	if yamlDevice.HopLimit < 0 || yamlDevice.HopLimit > 255 {
+		return fmt.Errorf("device %s: ipv6_hop_limit must be between 1 and 255: %d", device.Name, yamlDevice.HopLimit)
+	}
+	device.IPTTL = uint8(yamlDevice.IPTTL)
+	device.HopLimit = uint8(yamlDevice.HopLimit)
+	return nil
+}
+
+// parseDeviceIPAddresses parses IP addresses for a device
+func parseDeviceIPAddresses(device *Device, yamlDevice *converter.Device) error {
+	// Support both singular 'ip' (backward compatible) and plural 'ips' (new feature)
+	if yamlDevice.IP != "" {
+		ip := net.ParseIP(yamlDevice.IP)
+		if ip == nil {
+			return fmt.Errorf("device %s: invalid IP address %s", yamlDevice.Name, yamlDevice.IP)
+		}
+		if err := validateDeviceAddress(ip); err != nil {
+			return fmt.Errorf("device %s: invalid IP address: %v", yamlDevice.Name, err)
+		}
+		device.IPAddresses = append(device.IPAddresses, ip)
+	}
+
+	// Parse multiple IPs if specified
+	for i, ipStr := range yamlDevice.IPs {
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			return fmt.Errorf("device %s: invalid IP address in ips[%d]: %s", yamlDevice.Name, i, ipStr)
+		}
+		if err := validateDeviceAddress(ip); err != nil {
+			return fmt.Errorf("device %s: invalid IP address in ips[%d]: %v", yamlDevice.Name, i, err)
+		}
+		device.IPAddresses = append(device.IPAddresses, ip)
+	}
+
+	return nil
+}
+
+// parseDeviceSNMPConfig parses SNMP configuration for a device
+func parseDeviceSNMPConfig(device *Device, yamlDevice *converter.Device, includePath string) error {
+	if yamlDevice.SnmpAgent != nil {
+		if yamlDevice.SnmpAgent.WalkFile != "" {
+			// Resolve and validate walk file path (security: prevent path traversal)
+			walkFile, err := validateWalkFilePath(includePath, yamlDevice.SnmpAgent.WalkFile, yamlDevice.Name)
+			if err != nil {
+				return err
+			}
+			device.SNMPConfig.WalkFile = walkFile
+		}
+
+		// Resolve the walk files merged from walk_directory
+		if yamlDevice.SnmpAgent.WalkDir != "" {
+			files, err := resolveWalkDirectory(yamlDevice.SnmpAgent.WalkDir, "walk_directory", includePath, yamlDevice.Name, isWalkFileName)
+			if err != nil {
+				return err
+			}
+			device.SNMPConfig.WalkFiles = files
+		}
+
+		// Resolve walk series snapshots
+		series, err := parseWalkSeries(yamlDevice.SnmpAgent.WalkSeries, includePath, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.WalkSeries = series
+
+		// Store custom MIBs count for future use
+		if len(yamlDevice.SnmpAgent.AddMibs) > 0 {
+			device.Properties["custom_mibs_count"] = fmt.Sprintf("%d", len(yamlDevice.SnmpAgent.AddMibs))
+		}
+
+		// Parse SNMP Traps configuration
+		if yamlDevice.SnmpAgent.Traps != nil {
+			trapsCfg, err := parseSNMPTrapsConfig(yamlDevice.SnmpAgent.Traps, yamlDevice.Name)
+			if err != nil {
+				return err
+			}
+			device.SNMPConfig.Traps = trapsCfg
+		}
+
+		// Parse community MIB views
+		communities, err := parseSNMPCommunities(yamlDevice.SnmpAgent.Communities, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.Communities = communities
+
+		// Parse manager access list
+		managers, err := parseSNMPAllowedManagers(yamlDevice.SnmpAgent.AllowedManagers, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.AllowedManagers = managers
+
+		// Parse response source port behavior
+		responsePort, err := parseSNMPResponseSourcePort(yamlDevice.SnmpAgent.ResponseSourcePort, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.ResponseSourcePort = responsePort
+
+		// Parse the exception for missing instances
+		missingInstance, err := parseSNMPMissingInstance(yamlDevice.SnmpAgent.MissingInstance, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.MissingInstance = missingInstance
+
+		// Parse the agent's message size limit
+		maxMessageSize := yamlDevice.SnmpAgent.MaxMessageSize
+		if maxMessageSize == 0 {
+			maxMessageSize = DefaultSNMPMaxMessageSize
+		}
+		if maxMessageSize < MinSNMPMaxMessageSize || maxMessageSize > DefaultSNMPMaxMessageSize {
+			return fmt.Errorf("device %s: SNMP max_message_size must be between %d and %d: %d",
+				yamlDevice.Name, MinSNMPMaxMessageSize, DefaultSNMPMaxMessageSize, maxMessageSize)
+		}
+		device.SNMPConfig.MaxMessageSize = maxMessageSize
+
+		// Parse the response to SETs of ifAdminStatus
+		adminStatusSet, err := parseSNMPAdminStatusSet(yamlDevice.SnmpAgent.AdminStatusSet, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.AdminStatusSet = adminStatusSet
+
+		// Parse the objects SETs may change
+		writable, err := parseSNMPWritable(yamlDevice.SnmpAgent.Writable, adminStatusSet, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.Writable = writable
+
+		// Parse HOST-RESOURCES-MIB storage and process tables
+		hostResources, err := parseHostResourcesConfig(yamlDevice.SnmpAgent.HostResources, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.HostResources = hostResources
+
+		// Parse SNMPv3 contexts
+		contexts, err := parseSNMPContexts(yamlDevice.SnmpAgent.Contexts, includePath, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.Contexts = contexts
+
+		// Parse the subtrees answered as absent
+		disabled, err := parseSNMPDisabledSubtrees(yamlDevice.SnmpAgent.DisabledSubtrees, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.DisabledSubtrees = disabled
+
+		// Parse the SNMPv3 USM user
+		v3, err := parseSNMPv3User(yamlDevice.SnmpAgent.V3, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.V3 = v3
+
+		// Parse the overloaded-agent walk slowdown
+		slowWalk, err := parseSNMPSlowWalk(yamlDevice.SnmpAgent.SlowWalk, yamlDevice.Name)
+		if err != nil {
+			return err
+		}
+		device.SNMPConfig.SlowWalk = slowWalk
+
+		// Parse the engine state the agent starts with
+		if yamlDevice.SnmpAgent.EngineBoots < 0 || yamlDevice.SnmpAgent.EngineBoots > MaxSNMPEngineBoots {
+			return fmt.Errorf("device %s: SNMP engine_boots must be between 0 and %d: %d",
+				yamlDevice.Name, MaxSNMPEngineBoots, yamlDevice.SnmpAgent.EngineBoots)
+		}
+		if yamlDevice.SnmpAgent.EngineTime < 0 || yamlDevice.SnmpAgent.EngineTime > MaxSNMPEngineBoots {
+			return fmt.Errorf("device %s: SNMP engine_time must be between 0 and %d seconds: %d",
+				yamlDevice.Name, MaxSNMPEngineBoots, yamlDevice.SnmpAgent.EngineTime)
+		}
+		device.SNMPConfig.EngineBoots = yamlDevice.SnmpAgent.EngineBoots
+		device.SNMPConfig.EngineTime = time.Duration(yamlDevice.SnmpAgent.EngineTime) * time.Second
+	}
+
+	quirks, err := parseSNMPQuirks(yamlDevice.Quirks, yamlDevice.Name)
+	if err != nil {
+		return err
+	}
+	device.SNMPConfig.Quirks = quirks
+
+	return nil
+}
+
+// parseSNMPQuirks validates snmp_quirks, dropping duplicates
+func parseSNMPQuirks(values []string, deviceName string) ([]string, error) {
+	var quirks []string
+	for _, value := range values {
+		quirk := strings.ToLower(strings.TrimSpace(value))
+		switch quirk {
+		case SNMPQuirkTrailingNull, SNMPQuirkNoEndOfMibView, SNMPQuirkTruncateLongStrings:
+		default:
+			return nil, fmt.Errorf("device %s: invalid snmp_quirks entry %q (expected %q, %q or %q)",
+				deviceName, value, SNMPQuirkTrailingNull, SNMPQuirkNoEndOfMibView, SNMPQuirkTruncateLongStrings)
+		}
+		if !contains(quirks, quirk) {
+			quirks = append(quirks, quirk)
+		}
+	}
+	return quirks, nil
+}
+
+// parseSNMPWritable validates the writable list, keeping an empty list
+// distinct from an unset one: it makes every object read-only.
+func parseSNMPWritable(values []string, adminStatusSet, deviceName string) ([]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+	writable := make([]string, 0, len(values))
+	for _, value := range values {
+		var object string
+		for _, name := range []string{SNMPWritableSysContact, SNMPWritableSysName, SNMPWritableSysLocation, SNMPWritableIfAdminStatus} {
+			if strings.EqualFold(strings.TrimSpace(value), name) {
+				object = name
+			}
+		}
+		if object == "" {
+			return nil, fmt.Errorf("device %s: invalid SNMP writable entry %q (expected %s, %s, %s or %s)",
+				deviceName, value, SNMPWritableSysContact, SNMPWritableSysName, SNMPWritableSysLocation, SNMPWritableIfAdminStatus)
+		}
+		if object == SNMPWritableIfAdminStatus && adminStatusSet != SNMPAdminStatusSetLinkState {
+			return nil, fmt.Errorf("device %s: SNMP writable lists %s but admin_status_set is not %q",
+				deviceName, object, SNMPAdminStatusSetLinkState)
+		}
+		if !contains(writable, object) {
+			writable = append(writable, object)
+		}
+	}
+	return writable, nil
+}
+
+// parseSNMPContexts parses the SNMPv3 contexts of a device. The empty context
+// is the device itself, so context names must be non-empty and unique.
+func parseSNMPContexts(yamlContexts []converter.SnmpContext, includePath, deviceName string) ([]SNMPContext, error) {
+	if len(yamlContexts) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(yamlContexts))
+	contexts := make([]SNMPContext, 0, len(yamlContexts))
+	for _, yc := range yamlContexts {
+		if yc.Name == "" {
+			return nil, fmt.Errorf("device %s: SNMP context name cannot be empty", deviceName)
+		}
+		if seen[yc.Name] {
+			return nil, fmt.Errorf("device %s: duplicate SNMP context %q", deviceName, yc.Name)
+		}
+		seen[yc.Name] = true
+
+		context := SNMPContext{Name: yc.Name, SysName: yc.SysName}
+		if context.SysName == "" {
+			context.SysName = deviceName + "-" + yc.Name
+		}
+		if yc.WalkFile != "" {
+			walkFile, err := validateWalkFilePath(includePath, yc.WalkFile, deviceName)
+			if err != nil {
+				return nil, err
+			}
+			context.WalkFile = walkFile
+		}
+		contexts = append(contexts, context)
+	}
+
+	return contexts, nil
+}
+
+// parseSNMPv3User parses the SNMPv3 USM user of a device. Protocol names are
+// case-insensitive; the keys are passphrases localized to the agent's engine ID.
+func parseSNMPv3User(yamlUser *converter.SnmpV3User, deviceName string) (*SNMPv3Config, error) {
+	if yamlUser == nil {
+		return nil, nil
+	}
+	if yamlUser.User == "" {
+		return nil, fmt.Errorf("device %s: SNMPv3 user cannot be empty", deviceName)
+	}
+
+	user := &SNMPv3Config{
+		User:         yamlUser.User,
+		AuthProtocol: strings.ToLower(yamlUser.AuthProtocol),
+		AuthKey:      yamlUser.AuthKey,
+		PrivProtocol: strings.ToLower(yamlUser.PrivProtocol),
+		PrivKey:      yamlUser.PrivKey,
+	}
+	switch user.AuthProtocol {
+	case SNMPv3AuthMD5, SNMPv3AuthSHA:
+	default:
+		return nil, fmt.Errorf("device %s: invalid SNMPv3 auth_protocol %q (must be %s or %s)",
+			deviceName, yamlUser.AuthProtocol, SNMPv3AuthMD5, SNMPv3AuthSHA)
+	}
+	// SECURITY FIX MEDIUM-5: Do not echo keys in errors
+	if len(user.AuthKey) < snmpV3MinKeyLength {
+		return nil, fmt.Errorf("device %s: SNMPv3 auth_key must be at least %d characters", deviceName, snmpV3MinKeyLength)
+	}
+	switch user.PrivProtocol {
+	case "":
+		if user.PrivKey != "" {
+			return nil, fmt.Errorf("device %s: SNMPv3 priv_key requires priv_protocol", deviceName)
+		}
+	case SNMPv3PrivDES, SNMPv3PrivAES:
+		if len(user.PrivKey) < snmpV3MinKeyLength {
+			return nil, fmt.Errorf("device %s: SNMPv3 priv_key must be at least %d characters", deviceName, snmpV3MinKeyLength)
+		}
+	default:
+		return nil, fmt.Errorf("device %s: invalid SNMPv3 priv_protocol %q (must be %s or %s)",
+			deviceName, yamlUser.PrivProtocol, SNMPv3PrivDES, SNMPv3PrivAES)
+	}
+
+	return user, nil
+}
+
+// parseSNMPSlowWalk parses the walk slowdown of a device, filling in defaults
+func parseSNMPSlowWalk(yamlSlow *converter.SnmpSlowWalk, deviceName string) (*SNMPSlowWalk, error) {
+	if yamlSlow == nil {
+		return nil, nil
+	}
+	if yamlSlow.StepMs < 0 || yamlSlow.StepMs > MaxLatencyMs {
+		return nil, fmt.Errorf("device %s: SNMP slow_walk step_ms must be between 0 and %d: %d",
+			deviceName, MaxLatencyMs, yamlSlow.StepMs)
+	}
+	if yamlSlow.MaxMs < 0 || yamlSlow.MaxMs > MaxLatencyMs {
+		return nil, fmt.Errorf("device %s: SNMP slow_walk max_ms must be between 0 and %d: %d",
+			deviceName, MaxLatencyMs, yamlSlow.MaxMs)
+	}
+	if yamlSlow.WalkGapMs < 0 {
+		return nil, fmt.Errorf("device %s: SNMP slow_walk walk_gap_ms cannot be negative: %d",
+			deviceName, yamlSlow.WalkGapMs)
+	}
+
+	slowWalk := &SNMPSlowWalk{
+		Step:    time.Duration(yamlSlow.StepMs) * time.Millisecond,
+		Max:     time.Duration(yamlSlow.MaxMs) * time.Millisecond,
+		WalkGap: time.Duration(yamlSlow.WalkGapMs) * time.Millisecond,
+	}
+	if slowWalk.Step == 0 {
+		slowWalk.Step = DefaultSNMPSlowWalkStep
+	}
+	if slowWalk.Max == 0 {
+		slowWalk.Max = DefaultSNMPSlowWalkMax
+	}
+	if slowWalk.WalkGap == 0 {
+		slowWalk.WalkGap = DefaultSNMPSlowWalkWalkGap
+	}
+	return slowWalk, nil
+}
+
+// parseSNMPCommunities parses per-community MIB views from YAML
+func parseSNMPCommunities(yamlCommunities []converter.SnmpCommunity, deviceName string) ([]SNMPCommunity, error) {
+	if len(yamlCommunities) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(yamlCommunities))
+	communities := make([]SNMPCommunity, 0, len(yamlCommunities))
+	for _, yc := range yamlCommunities {
+		if yc.Name == "" {
+			return nil, fmt.Errorf("device %s: SNMP community name cannot be empty", deviceName)
+		}
+		if seen[yc.Name] {
+			// SECURITY FIX MEDIUM-5: Do not echo community strings in errors
+			return nil, fmt.Errorf("device %s: duplicate SNMP community in communities list", deviceName)
+		}
+		seen[yc.Name] = true
+
+		community := SNMPCommunity{Name: yc.Name}
+		if yc.View != nil {
+			view := &SNMPView{}
+			for _, oid := range yc.View.Include {
+				normalized, err := normalizeViewOID(oid, deviceName)
+				if err != nil {
+					return nil, err
+				}
+				view.Included = append(view.Included, normalized)
+			}
+			for _, oid := range yc.View.Exclude {
+				normalized, err := normalizeViewOID(oid, deviceName)
+				if err != nil {
+					return nil, err
+				}
+				view.Excluded = append(view.Excluded, normalized)
+			}
+			community.View = view
+		}
+		communities = append(communities, community)
+	}
+
+	return communities, nil
+}
+
+// parseSNMPAllowedManagers validates the manager access list, normalizing bare
+// addresses to host CIDRs
+func parseSNMPAllowedManagers(entries []string, deviceName string) ([]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	managers := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			managers = append(managers, fmt.Sprintf("%s/%d", ip, bits))
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: invalid SNMP allowed manager %q (expected IP or CIDR)", deviceName, entry)
+		}
+		managers = append(managers, network.String())
+	}
+	return managers, nil
+}
+
+// parseSNMPResponseSourcePort validates response_source_port, defaulting to
+// the standard UDP 161 behavior
+func parseSNMPResponseSourcePort(value, deviceName string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "", SNMPResponsePortStandard:
+		return SNMPResponsePortStandard, nil
+	case SNMPResponsePortEphemeral:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("device %s: invalid SNMP response_source_port %q (expected %q or %q)",
+			deviceName, value, SNMPResponsePortStandard, SNMPResponsePortEphemeral)
 	}
-
-	return device, nil
 }
 
-// parseDeviceIPAddresses parses IP addresses for a device
-func parseDeviceIPAddresses(device *Device, yamlDevice *converter.Device) error {
-	// Support both singular 'ip' (backward compatible) and plural 'ips' (new feature)
-	if yamlDevice.IP != "" {
-		ip := net.ParseIP(yamlDevice.IP)
-		if ip == nil {
-			return fmt.Errorf("device %s: invalid IP address %s", yamlDevice.Name, yamlDevice.IP)
-		}
-		device.IPAddresses = append(device.IPAddresses, ip)
+// parseSNMPAdminStatusSet validates admin_status_set, defaulting to a
+// read-only ifAdminStatus
+func parseSNMPAdminStatusSet(value, deviceName string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "", SNMPAdminStatusSetReadOnly:
+		return SNMPAdminStatusSetReadOnly, nil
+	case SNMPAdminStatusSetLinkState:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("device %s: invalid SNMP admin_status_set %q (expected %q or %q)",
+			deviceName, value, SNMPAdminStatusSetReadOnly, SNMPAdminStatusSetLinkState)
 	}
+}
 
-	// Parse multiple IPs if specified
-	for i, ipStr := range yamlDevice.IPs {
-		ip := net.ParseIP(ipStr)
-		if ip == nil {
-			return fmt.Errorf("device %s: invalid IP address in ips[%d]: %s", yamlDevice.Name, i, ipStr)
-		}
-		device.IPAddresses = append(device.IPAddresses, ip)
+// parseSNMPMissingInstance validates missing_instance, defaulting to the
+// RFC 3416 noSuchInstance behavior
+func parseSNMPMissingInstance(value, deviceName string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case "", SNMPMissingInstanceNoSuchInstance:
+		return SNMPMissingInstanceNoSuchInstance, nil
+	case SNMPMissingInstanceNoSuchObject:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("device %s: invalid SNMP missing_instance %q (expected %q or %q)",
+			deviceName, value, SNMPMissingInstanceNoSuchInstance, SNMPMissingInstanceNoSuchObject)
 	}
-
-	return nil
 }
 
-// parseDeviceSNMPConfig parses SNMP configuration for a device
-func parseDeviceSNMPConfig(device *Device, yamlDevice *converter.Device, includePath string) error {
-	if yamlDevice.SnmpAgent != nil {
-		if yamlDevice.SnmpAgent.WalkFile != "" {
-			// Resolve and validate walk file path (security: prevent path traversal)
-			walkFile, err := validateWalkFilePath(includePath, yamlDevice.SnmpAgent.WalkFile, yamlDevice.Name)
-			if err != nil {
-				return err
-			}
-			device.SNMPConfig.WalkFile = walkFile
+// normalizeViewOID strips the leading dot from a view subtree and validates it
+// parseSNMPDisabledSubtrees validates and normalizes the OID subtrees the
+// agent treats as absent
+func parseSNMPDisabledSubtrees(subtrees []string, deviceName string) ([]string, error) {
+	if len(subtrees) == 0 {
+		return nil, nil
+	}
+	disabled := make([]string, 0, len(subtrees))
+	for _, oid := range subtrees {
+		normalized, err := normalizeViewOID(oid, deviceName)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: invalid SNMP disabled_subtrees OID %q", deviceName, oid)
 		}
+		disabled = append(disabled, normalized)
+	}
+	return disabled, nil
+}
 
-		// Store custom MIBs count for future use
-		if len(yamlDevice.SnmpAgent.AddMibs) > 0 {
-			device.Properties["custom_mibs_count"] = fmt.Sprintf("%d", len(yamlDevice.SnmpAgent.AddMibs))
+func normalizeViewOID(oid, deviceName string) (string, error) {
+	normalized := strings.TrimPrefix(strings.TrimSpace(oid), ".")
+	if normalized == "" {
+		return "", fmt.Errorf("device %s: SNMP view OID cannot be empty", deviceName)
+	}
+	for _, part := range strings.Split(normalized, ".") {
+		if part == "" {
+			return "", fmt.Errorf("device %s: invalid SNMP view OID %q", deviceName, oid)
 		}
-
-		// Parse SNMP Traps configuration
-		if yamlDevice.SnmpAgent.Traps != nil {
-			trapsCfg, err := parseSNMPTrapsConfig(yamlDevice.SnmpAgent.Traps)
-			if err != nil {
-				return err
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return "", fmt.Errorf("device %s: invalid SNMP view OID %q", deviceName, oid)
 			}
-			device.SNMPConfig.Traps = trapsCfg
 		}
 	}
-
-	return nil
+	return normalized, nil
 }
 
 // parseDeviceProtocolConfigs parses all protocol configurations for a device
@@ -776,17 +1913,58 @@ func parseDeviceProtocolConfigs(device *Device, yamlDevice *converter.Device) er
 	device.FTPConfig = parseFTPConfig(yamlDevice.Ftp, device.Name)
 	device.NetBIOSConfig = parseNetBIOSConfig(yamlDevice.Netbios, device.Name)
 
+	// Handle PoE power needs advertised by discovery protocols
+	if device.PoE, err = parsePoEConfig(yamlDevice.Poe, device.Name); err != nil {
+		return err
+	}
+
+	// Handle the passive BGP peer
+	if device.BGP, err = parseBGPConfig(yamlDevice.Bgp, device); err != nil {
+		return err
+	}
+
+	// Handle deliberately corrupt frames
+	if device.MalformedFrames, err = parseMalformedFramesConfig(yamlDevice.MalformedFrames, device); err != nil {
+		return err
+	}
+
+	// Handle ARP responder behavior
+	if device.ARPConfig, err = parseARPConfig(yamlDevice.Arp, device.Name); err != nil {
+		return err
+	}
+
+	// Handle response latency model
+	if device.Latency, err = parseLatencyConfig(yamlDevice.Latency, "device "+device.Name); err != nil {
+		return err
+	}
+
+	// Handle TCP service limits
+	if device.TCPConfig, err = parseTCPConfig(yamlDevice.Tcp, "device "+device.Name); err != nil {
+		return err
+	}
+
+	// Handle bridge MAC learning
+	if device.BridgeConfig, err = parseBridgeConfig(yamlDevice.Bridge, device.Name); err != nil {
+		return err
+	}
+
 	// Handle ICMP protocols
-	device.ICMPConfig = parseICMPConfig(yamlDevice.Icmp)
-	device.ICMPv6Config = parseICMPv6Config(yamlDevice.Icmpv6)
+	if device.ICMPConfig, err = parseICMPConfig(yamlDevice.Icmp, device); err != nil {
+		return err
+	}
+	if device.ICMPv6Config, err = parseICMPv6Config(yamlDevice.Icmpv6, device.Name, device.IPv6HopLimit()); err != nil {
+		return err
+	}
 
 	// Handle DHCPv6 configuration
-	if device.DHCPv6Config, err = parseDHCPv6Config(yamlDevice.Dhcpv6); err != nil {
+	if device.DHCPv6Config, err = parseDHCPv6Config(yamlDevice.Dhcpv6, device.Name); err != nil {
 		return err
 	}
 
 	// Handle Traffic configuration
-	device.TrafficConfig = parseTrafficConfig(yamlDevice.Traffic)
+	if device.TrafficConfig, err = parseTrafficConfig(yamlDevice.Traffic, device.Name); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -829,29 +2007,198 @@ func parseNetBIOSConfig(yamlNetbios *converter.NetbiosConfig, deviceName string)
 	return netbiosCfg
 }
 
-// parseICMPConfig parses ICMP configuration from YAML
-func parseICMPConfig(yamlIcmp *converter.IcmpConfig) *ICMPConfig {
+// parseARPConfig parses ARP responder configuration from YAML
+func parseARPConfig(yamlArp *converter.ArpConfig, deviceName string) (*ARPConfig, error) {
+	if yamlArp == nil {
+		return nil, nil
+	}
+
+	if yamlArp.ReplyDelayMs < 0 || yamlArp.ReplyDelayMs > MaxARPReplyDelayMs {
+		return nil, fmt.Errorf("device %s: ARP reply_delay_ms must be between 0 and %d: %d",
+			deviceName, MaxARPReplyDelayMs, yamlArp.ReplyDelayMs)
+	}
+
+	arpCfg := &ARPConfig{
+		ReplyDelayMs: yamlArp.ReplyDelayMs,
+	}
+	for _, cidr := range yamlArp.ProxyARPSubnets {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: invalid proxy ARP subnet %s: %w", deviceName, cidr, err)
+		}
+		arpCfg.ProxyARPSubnets = append(arpCfg.ProxyARPSubnets, subnet)
+	}
+
+	return arpCfg, nil
+}
+
+// parseTCPConfig parses TCP service limits from YAML. scope names the owner
+// (a device or "global") in error messages.
+func parseTCPConfig(yamlTcp *converter.TcpConfig, scope string) (*TCPConfig, error) {
+	if yamlTcp == nil {
+		return nil, nil
+	}
+
+	if yamlTcp.MaxConnections < 0 || yamlTcp.MaxConnections > MaxTCPConnections {
+		return nil, fmt.Errorf("%s: tcp max_connections must be between 0 and %d: %d",
+			scope, MaxTCPConnections, yamlTcp.MaxConnections)
+	}
+
+	return &TCPConfig{MaxConnections: yamlTcp.MaxConnections}, nil
+}
+
+// parseWalkLimitsConfig parses the global walk_limits block
+func parseWalkLimitsConfig(yamlLimits *converter.WalkLimitsConfig) (*WalkLimitsConfig, error) {
+	if yamlLimits == nil {
+		return nil, nil
+	}
+	if yamlLimits.MaxFileSizeMB < 0 {
+		return nil, fmt.Errorf("walk_limits max_file_size_mb must not be negative: %d", yamlLimits.MaxFileSizeMB)
+	}
+	if yamlLimits.MaxOIDs < 0 {
+		return nil, fmt.Errorf("walk_limits max_oids must not be negative: %d", yamlLimits.MaxOIDs)
+	}
+	return &WalkLimitsConfig{
+		MaxFileSize: int64(yamlLimits.MaxFileSizeMB) << 20,
+		MaxOIDs:     yamlLimits.MaxOIDs,
+	}, nil
+}
+
+// parseInterfaceScope parses the global interface_scope block
+func parseInterfaceScope(yamlScope map[string][]string) (InterfaceScope, error) {
+	if len(yamlScope) == 0 {
+		return nil, nil
+	}
+	scope := make(InterfaceScope, len(yamlScope))
+	for iface, cidrs := range yamlScope {
+		if strings.TrimSpace(iface) == "" {
+			return nil, fmt.Errorf("interface_scope: interface name is required")
+		}
+		if len(cidrs) == 0 {
+			return nil, fmt.Errorf("interface_scope %s: at least one subnet is required", iface)
+		}
+		for _, cidr := range cidrs {
+			_, subnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("interface_scope %s: invalid subnet %s: %w", iface, cidr, err)
+			}
+			scope[iface] = append(scope[iface], subnet)
+		}
+	}
+	return scope, nil
+}
+
+// parseTCPPorts parses a device's simulated TCP port states from YAML
+func parseTCPPorts(yamlPorts map[int]string, deviceName string) (map[uint16]string, error) {
+	if len(yamlPorts) == 0 {
+		return nil, nil
+	}
+
+	ports := make(map[uint16]string, len(yamlPorts))
+	for port, state := range yamlPorts {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("device %s: tcp_ports port must be between 1 and 65535: %d", deviceName, port)
+		}
+		state = strings.ToLower(strings.TrimSpace(state))
+		switch state {
+		case TCPPortOpen, TCPPortClosed, TCPPortFiltered:
+		default:
+			return nil, fmt.Errorf("device %s: tcp_ports port %d: invalid state %q (must be %s, %s or %s)",
+				deviceName, port, state, TCPPortOpen, TCPPortClosed, TCPPortFiltered)
+		}
+		ports[uint16(port)] = state
+	}
+	return ports, nil
+}
+
+// parseBridgeConfig parses a device's MAC learning configuration
+func parseBridgeConfig(yamlBridge *converter.BridgeConfig, deviceName string) (*BridgeConfig, error) {
+	if yamlBridge == nil {
+		return nil, nil
+	}
+
+	agingTime := yamlBridge.AgingTime
+	if agingTime == 0 {
+		agingTime = DefaultBridgeAgingTime
+	}
+	if agingTime < MinBridgeAgingTime || agingTime > MaxBridgeAgingTime {
+		return nil, fmt.Errorf("device %s: bridge aging_time must be between %d and %d seconds: %d",
+			deviceName, MinBridgeAgingTime, MaxBridgeAgingTime, agingTime)
+	}
+
+	macTableSize := yamlBridge.MacTableSize
+	if macTableSize == 0 {
+		macTableSize = DefaultBridgeMacTableSize
+	}
+	if macTableSize < 1 || macTableSize > MaxBridgeMacTableSize {
+		return nil, fmt.Errorf("device %s: bridge mac_table_size must be between 1 and %d: %d",
+			deviceName, MaxBridgeMacTableSize, macTableSize)
+	}
+
+	return &BridgeConfig{
+		AgingTime:    time.Duration(agingTime) * time.Second,
+		MacTableSize: macTableSize,
+	}, nil
+}
+
+// parseICMPConfig parses ICMP configuration from YAML. An unset TTL falls
+// back to the device's ip_ttl. With reply_offsubnet off, the device's IPv4
+// addresses and subnet_mask (or the DHCP server's subnet_mask) define the
+// subnets whose pings are answered.
+func parseICMPConfig(yamlIcmp *converter.IcmpConfig, device *Device) (*ICMPConfig, error) {
 	if yamlIcmp == nil {
-		return nil
+		return nil, nil
 	}
 
 	icmpCfg := &ICMPConfig{
-		Enabled:   yamlIcmp.Enabled,
-		TTL:       yamlIcmp.TTL,
-		RateLimit: yamlIcmp.RateLimit,
+		Enabled:                yamlIcmp.Enabled,
+		TTL:                    yamlIcmp.TTL,
+		RateLimit:              yamlIcmp.RateLimit,
+		RespondToBroadcastPing: yamlIcmp.RespondToBroadcastPing,
+		DropOffSubnet:          yamlIcmp.ReplyOffsubnet != nil && !*yamlIcmp.ReplyOffsubnet,
 	}
 
 	if icmpCfg.TTL == 0 {
-		icmpCfg.TTL = DefaultICMPTTL
+		icmpCfg.TTL = device.TTL()
+	}
+
+	if !icmpCfg.DropOffSubnet {
+		if yamlIcmp.SubnetMask != "" {
+			return nil, fmt.Errorf("device %s: icmp subnet_mask requires reply_offsubnet: false", device.Name)
+		}
+		return icmpCfg, nil
+	}
+
+	var mask net.IPMask
+	switch {
+	case yamlIcmp.SubnetMask != "":
+		ip := net.ParseIP(yamlIcmp.SubnetMask).To4()
+		mask = net.IPMask(ip)
+		if ones, bits := mask.Size(); ip == nil || (ones == 0 && bits == 0) {
+			return nil, fmt.Errorf("device %s: invalid icmp subnet_mask %q", device.Name, yamlIcmp.SubnetMask)
+		}
+	case device.DHCPConfig != nil && device.DHCPConfig.SubnetMask != nil:
+		mask = device.DHCPConfig.SubnetMask
+	default:
+		return nil, fmt.Errorf("device %s: icmp reply_offsubnet: false needs a subnet_mask", device.Name)
+	}
+	for _, ip := range device.IPAddresses {
+		if ip4 := ip.To4(); ip4 != nil {
+			icmpCfg.LocalSubnets = append(icmpCfg.LocalSubnets, &net.IPNet{IP: ip4.Mask(mask), Mask: mask})
+		}
+	}
+	if len(icmpCfg.LocalSubnets) == 0 {
+		return nil, fmt.Errorf("device %s: icmp reply_offsubnet: false needs an IPv4 address", device.Name)
 	}
 
-	return icmpCfg
+	return icmpCfg, nil
 }
 
-// parseICMPv6Config parses ICMPv6 configuration from YAML
-func parseICMPv6Config(yamlIcmpv6 *converter.Icmpv6Config) *ICMPv6Config {
+// parseICMPv6Config parses ICMPv6 configuration from YAML. An unset hop limit
+// falls back to the device's ipv6_hop_limit.
+func parseICMPv6Config(yamlIcmpv6 *converter.Icmpv6Config, deviceName string, defaultHopLimit uint8) (*ICMPv6Config, error) {
 	if yamlIcmpv6 == nil {
-		return nil
+		return nil, nil
 	}
 
 	icmpv6Cfg := &ICMPv6Config{
@@ -861,14 +2208,40 @@ func parseICMPv6Config(yamlIcmpv6 *converter.Icmpv6Config) *ICMPv6Config {
 	}
 
 	if icmpv6Cfg.HopLimit == 0 {
-		icmpv6Cfg.HopLimit = DefaultICMPv6HopLimit
+		icmpv6Cfg.HopLimit = defaultHopLimit
+	}
+
+	for _, route := range yamlIcmpv6.RouteInfo {
+		ip, prefix, err := net.ParseCIDR(route.Prefix)
+		if err != nil || ip.To4() != nil {
+			return nil, fmt.Errorf("device %s: invalid ICMPv6 route_info prefix %q (expected an IPv6 CIDR)",
+				deviceName, route.Prefix)
+		}
+		preference := strings.ToLower(strings.TrimSpace(route.Preference))
+		switch preference {
+		case "":
+			preference = RoutePreferenceMedium
+		case RoutePreferenceHigh, RoutePreferenceMedium, RoutePreferenceLow:
+		default:
+			return nil, fmt.Errorf("device %s: invalid ICMPv6 route_info preference %q for %s (expected %q, %q or %q)",
+				deviceName, route.Preference, route.Prefix, RoutePreferenceHigh, RoutePreferenceMedium, RoutePreferenceLow)
+		}
+		lifetime := uint32(DefaultRouteInfoLifetime)
+		if route.Lifetime != nil {
+			lifetime = *route.Lifetime
+		}
+		icmpv6Cfg.RouteInfo = append(icmpv6Cfg.RouteInfo, RouteInfoOption{
+			Prefix:     prefix,
+			Preference: preference,
+			Lifetime:   lifetime,
+		})
 	}
 
-	return icmpv6Cfg
+	return icmpv6Cfg, nil
 }
 
 // parseDHCPv6Config parses DHCPv6 configuration from YAML
-func parseDHCPv6Config(yamlDhcpv6 *converter.Dhcpv6Config) (*DHCPv6Config, error) {
+func parseDHCPv6Config(yamlDhcpv6 *converter.Dhcpv6Config, deviceName string) (*DHCPv6Config, error) {
 	if yamlDhcpv6 == nil {
 		return nil, nil
 	}
@@ -890,14 +2263,31 @@ func parseDHCPv6Config(yamlDhcpv6 *converter.Dhcpv6Config) (*DHCPv6Config, error
 	if dhcpv6Cfg.ValidLifetime == 0 {
 		dhcpv6Cfg.ValidLifetime = DefaultDHCPv6ValidLifetime
 	}
+	if yamlDhcpv6.LeaseGrace < 0 {
+		return nil, fmt.Errorf("device %s: dhcpv6 lease_grace must not be negative: %d", deviceName, yamlDhcpv6.LeaseGrace)
+	}
+	dhcpv6Cfg.LeaseGrace = time.Duration(yamlDhcpv6.LeaseGrace) * time.Second
 
 	// Parse address pools
-	for _, pool := range yamlDhcpv6.Pools {
-		dhcpv6Cfg.Pools = append(dhcpv6Cfg.Pools, DHCPv6Pool{
-			Network:    pool.Network,
-			RangeStart: pool.RangeStart,
-			RangeEnd:   pool.RangeEnd,
-		})
+	for i, yamlPool := range yamlDhcpv6.Pools {
+		pool := DHCPv6Pool{
+			Network:    yamlPool.Network,
+			RangeStart: yamlPool.RangeStart,
+			RangeEnd:   yamlPool.RangeEnd,
+		}
+		if err := validateDHCPv6Pool(pool); err != nil {
+			return nil, fmt.Errorf("device %s: dhcpv6 pools[%d]: %v", deviceName, i, err)
+		}
+		dhcpv6Cfg.Pools = append(dhcpv6Cfg.Pools, pool)
+	}
+
+	// Parse delegated prefixes
+	for i, prefixStr := range yamlDhcpv6.DelegatedPrefixes {
+		ip, prefix, err := net.ParseCIDR(prefixStr)
+		if err != nil || ip.To4() != nil {
+			return nil, fmt.Errorf("device %s: dhcpv6 delegated_prefixes[%d]: %q is not an IPv6 CIDR prefix", deviceName, i, prefixStr)
+		}
+		dhcpv6Cfg.DelegatedPrefixes = append(dhcpv6Cfg.DelegatedPrefixes, *prefix)
 	}
 
 	// Parse DNS servers
@@ -932,10 +2322,11 @@ func parseDHCPv6Config(yamlDhcpv6 *converter.Dhcpv6Config) (*DHCPv6Config, error
 }
 
 // parseTrafficConfig parses traffic configuration from YAML
-func parseTrafficConfig(yamlTraffic *converter.TrafficConfig) *TrafficConfig {
+func parseTrafficConfig(yamlTraffic *converter.TrafficConfig, deviceName string) (*TrafficConfig, error) {
 	if yamlTraffic == nil {
-		return nil
+		return nil, nil
 	}
+	var err error
 
 	trafficCfg := &TrafficConfig{
 		Enabled: yamlTraffic.Enabled,
@@ -950,6 +2341,10 @@ func parseTrafficConfig(yamlTraffic *converter.TrafficConfig) *TrafficConfig {
 		if arpCfg.Interval == 0 {
 			arpCfg.Interval = DefaultARPAnnouncementInterval
 		}
+		arpCfg.Schedule, err = parseTrafficSchedule(yamlTraffic.ARPAnnouncements.Schedule, "device "+deviceName+" arp_announcements")
+		if err != nil {
+			return nil, err
+		}
 		trafficCfg.ARPAnnouncements = arpCfg
 	}
 
@@ -966,6 +2361,10 @@ func parseTrafficConfig(yamlTraffic *converter.TrafficConfig) *TrafficConfig {
 		if pingCfg.PayloadSize == 0 {
 			pingCfg.PayloadSize = DefaultPeriodicPingPayloadSize
 		}
+		pingCfg.Schedule, err = parseTrafficSchedule(yamlTraffic.PeriodicPings.Schedule, "device "+deviceName+" periodic_pings")
+		if err != nil {
+			return nil, err
+		}
 		trafficCfg.PeriodicPings = pingCfg
 	}
 
@@ -986,19 +2385,57 @@ func parseTrafficConfig(yamlTraffic *converter.TrafficConfig) *TrafficConfig {
 		if len(randomCfg.Patterns) == 0 {
 			randomCfg.Patterns = []string{"broadcast_arp", "multicast", "udp"}
 		}
+		randomCfg.Schedule, err = parseTrafficSchedule(yamlTraffic.RandomTraffic.Schedule, "device "+deviceName+" random_traffic")
+		if err != nil {
+			return nil, err
+		}
 		trafficCfg.RandomTraffic = randomCfg
 	}
 
-	return trafficCfg
+	return trafficCfg, nil
+}
+
+// DefaultTrapPort is the port traps are sent to when a receiver omits one
+const DefaultTrapPort = 162
+
+// ParseTrapReceiver splits a trap receiver into its address and port. A
+// receiver is an IPv4 or IPv6 address, optionally with a port: "10.0.0.5",
+// "10.0.0.5:1162", "2001:db8::5" or "[2001:db8::5]:1162". An IPv6 receiver
+// with a port must be bracketed. The port defaults to DefaultTrapPort.
+func ParseTrapReceiver(receiver string) (net.IP, uint16, error) {
+	host, portStr, hasPort := receiver, "", false
+	if h, p, err := net.SplitHostPort(receiver); err == nil {
+		host, portStr, hasPort = h, p, true
+	} else if strings.HasPrefix(receiver, "[") && strings.HasSuffix(receiver, "]") {
+		host = receiver[1 : len(receiver)-1]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid trap receiver %q: %q is not an IPv4 or IPv6 address", receiver, host)
+	}
+	if !hasPort {
+		return ip, DefaultTrapPort, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, 0, fmt.Errorf("invalid trap receiver %q: port must be between 1 and 65535", receiver)
+	}
+	return ip, uint16(port), nil
 }
 
 // parseSNMPTrapsConfig parses SNMP traps configuration from YAML
-func parseSNMPTrapsConfig(yamlTraps *converter.TrapsConfig) (*TrapConfig, error) {
+func parseSNMPTrapsConfig(yamlTraps *converter.TrapsConfig, deviceName string) (*TrapConfig, error) {
 	trapsCfg := &TrapConfig{
 		Enabled:   yamlTraps.Enabled,
 		Receivers: yamlTraps.Receivers,
 		Community: yamlTraps.Community,
 	}
+	for _, receiver := range trapsCfg.Receivers {
+		if _, _, err := ParseTrapReceiver(receiver); err != nil {
+			return nil, fmt.Errorf("device %s: %w", deviceName, err)
+		}
+	}
 
 	// Parse Cold Start trap
 	if yamlTraps.ColdStart != nil {
@@ -1073,6 +2510,31 @@ func parseSNMPTrapsConfig(yamlTraps *converter.TrapsConfig) (*TrapConfig, error)
 		trapsCfg.InterfaceErrors = ifErrCfg
 	}
 
+	// Parse platform-specific varbinds
+	varbinds, err := parseTrapVarbinds(yamlTraps.TrapVarbinds, deviceName)
+	if err != nil {
+		return nil, err
+	}
+	trapsCfg.Varbinds = varbinds
+
+	// Parse per-receiver rate limit
+	if yamlTraps.RateLimit != nil {
+		if yamlTraps.RateLimit.MaxTraps < 1 {
+			return nil, fmt.Errorf("device %s: traps rate_limit max_traps must be at least 1: %d", deviceName, yamlTraps.RateLimit.MaxTraps)
+		}
+		if yamlTraps.RateLimit.Window < 0 {
+			return nil, fmt.Errorf("device %s: traps rate_limit window must not be negative: %d", deviceName, yamlTraps.RateLimit.Window)
+		}
+		window := yamlTraps.RateLimit.Window
+		if window == 0 {
+			window = DefaultTrapRateLimitWindow
+		}
+		trapsCfg.RateLimit = &TrapRateLimit{
+			MaxTraps: yamlTraps.RateLimit.MaxTraps,
+			Window:   time.Duration(window) * time.Second,
+		}
+	}
+
 	return trapsCfg, nil
 }
 
@@ -1112,6 +2574,20 @@ func parseDHCPConfig(yamlDhcp *converter.DhcpServer, deviceName string) (*DHCPCo
 	if yamlDhcp.PoolEnd != "" {
 		dhcpCfg.PoolEnd = net.ParseIP(yamlDhcp.PoolEnd)
 	}
+	dhcpCfg.AlwaysBroadcast = yamlDhcp.AlwaysBroadcast
+
+	if yamlDhcp.RetransmitWindow < 0 {
+		return nil, fmt.Errorf("device %s: dhcp retransmit_window must not be negative: %d", deviceName, yamlDhcp.RetransmitWindow)
+	}
+	dhcpCfg.RetransmitWindow = time.Duration(yamlDhcp.RetransmitWindow) * time.Second
+	if dhcpCfg.RetransmitWindow == 0 {
+		dhcpCfg.RetransmitWindow = DefaultDHCPRetransmitWindow * time.Second
+	}
+
+	if yamlDhcp.LeaseGrace < 0 {
+		return nil, fmt.Errorf("device %s: dhcp lease_grace must not be negative: %d", deviceName, yamlDhcp.LeaseGrace)
+	}
+	dhcpCfg.LeaseGrace = time.Duration(yamlDhcp.LeaseGrace) * time.Second
 
 	// DHCPv4 high priority options
 	for _, ntpStr := range yamlDhcp.NTPServers {
@@ -1127,6 +2603,14 @@ func parseDHCPConfig(yamlDhcp *converter.DhcpServer, deviceName string) (*DHCPCo
 		dhcpCfg.VendorSpecific = []byte(yamlDhcp.VendorSpecific)
 	}
 
+	for i, yamlClass := range yamlDhcp.ClientClasses {
+		class, err := parseDHCPClientClass(yamlClass)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: dhcp client_classes[%d]: %w", deviceName, i, err)
+		}
+		dhcpCfg.ClientClasses = append(dhcpCfg.ClientClasses, class)
+	}
+
 	// DHCPv6 options
 	for _, sntpStr := range yamlDhcp.SNTPServersV6 {
 		if ip := net.ParseIP(sntpStr); ip != nil {
@@ -1170,6 +2654,77 @@ func parseDHCPConfig(yamlDhcp *converter.DhcpServer, deviceName string) (*DHCPCo
 	return dhcpCfg, nil
 }
 
+// parseDHCPClientClass parses a DHCP client class. Its classes are matched as
+// substrings unless regex is set; at least one must be given.
+func parseDHCPClientClass(yamlClass converter.DhcpClientClass) (DHCPClientClass, error) {
+	class := DHCPClientClass{
+		Name:           yamlClass.Name,
+		TFTPServerName: yamlClass.TFTPServerName,
+		BootfileName:   yamlClass.BootfileName,
+	}
+	if class.Name == "" {
+		return class, fmt.Errorf("name is required")
+	}
+	if yamlClass.VendorClass == "" && yamlClass.UserClass == "" {
+		return class, fmt.Errorf("class %s: vendor_class or user_class is required", class.Name)
+	}
+	compile := func(field, pattern string) (*regexp.Regexp, error) {
+		if pattern == "" {
+			return nil, nil
+		}
+		if !yamlClass.Regex {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("class %s: invalid %s: %v", class.Name, field, err)
+		}
+		return re, nil
+	}
+	var err error
+	if class.VendorClass, err = compile("vendor_class", yamlClass.VendorClass); err != nil {
+		return class, err
+	}
+	if class.UserClass, err = compile("user_class", yamlClass.UserClass); err != nil {
+		return class, err
+	}
+	for _, field := range []struct{ name, value string }{
+		{"tftp_server_name", class.TFTPServerName},
+		{"bootfile_name", class.BootfileName},
+	} {
+		if len(field.value) > 255 {
+			return class, fmt.Errorf("class %s: %s is longer than 255 bytes", class.Name, field.name)
+		}
+	}
+	if yamlClass.NextServerIP != "" {
+		class.NextServerIP = net.ParseIP(yamlClass.NextServerIP).To4()
+		if class.NextServerIP == nil {
+			return class, fmt.Errorf("class %s: invalid next_server_ip %q", class.Name, yamlClass.NextServerIP)
+		}
+	}
+
+	for code, value := range yamlClass.Options {
+		// 0 and 255 are pad and end; 53 and 54 identify the reply itself
+		if code < 1 || code > 254 || code == 53 || code == 54 {
+			return class, fmt.Errorf("class %s: option %d cannot be set", class.Name, code)
+		}
+		data := []byte(value)
+		if hexValue, ok := strings.CutPrefix(value, "0x"); ok {
+			if data, err = hex.DecodeString(hexValue); err != nil {
+				return class, fmt.Errorf("class %s: option %d: invalid hex value %q", class.Name, code, value)
+			}
+		}
+		if len(data) > 255 {
+			return class, fmt.Errorf("class %s: option %d is longer than 255 bytes", class.Name, code)
+		}
+		if class.Options == nil {
+			class.Options = make(map[uint8][]byte)
+		}
+		class.Options[uint8(code)] = data
+	}
+	return class, nil
+}
+
 // parseDNSConfig parses DNS configuration from YAML
 func parseDNSConfig(yamlDns *converter.DnsServer, deviceName string) (*DNSConfig, error) {
 	if yamlDns == nil {
@@ -1230,6 +2785,31 @@ func parseDNSConfig(yamlDns *converter.DnsServer, deviceName string) (*DNSConfig
 		})
 	}
 
+	// Upstream resolvers, port 53 unless given
+	for _, forwarder := range yamlDns.Forwarders {
+		host, port := forwarder, "53"
+		if h, p, err := net.SplitHostPort(forwarder); err == nil {
+			host, port = h, p
+		}
+		if net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("device %s: DNS forwarder must be an IP address or IP:port: %q", deviceName, forwarder)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("device %s: invalid DNS forwarder port: %q", deviceName, forwarder)
+		}
+		dnsCfg.Forwarders = append(dnsCfg.Forwarders, net.JoinHostPort(host, port))
+	}
+
+	cacheTTL := yamlDns.CacheTTL
+	if cacheTTL == 0 {
+		cacheTTL = DefaultDNSCacheTTL
+	}
+	if cacheTTL < 0 || cacheTTL > MaxDNSCacheTTL {
+		return nil, fmt.Errorf("device %s: DNS cache_ttl must be between 1 and %d seconds: %d",
+			deviceName, MaxDNSCacheTTL, cacheTTL)
+	}
+	dnsCfg.CacheTTL = time.Duration(cacheTTL) * time.Second
+
 	return dnsCfg, nil
 }
 
@@ -1246,6 +2826,7 @@ func parseLLDPConfig(yamlLldp *converter.LldpConfig) *LLDPConfig {
 		SystemDescription: yamlLldp.SystemDescription,
 		PortDescription:   yamlLldp.PortDescription,
 		ChassisIDType:     yamlLldp.ChassisIDType,
+		FastStart:         yamlLldp.FastStart,
 	}
 	// Set defaults if not specified
 	if lldpCfg.AdvertiseInterval == 0 {
@@ -1469,6 +3050,7 @@ func ParseSimpleConfig(lines []string) (*Config, error) {
 		device := Device{
 			Name:        parts[0],
 			Type:        parts[1],
+			Enabled:     true,
 			MACAddress:  mac,
 			IPAddresses: []net.IP{ip},
 			Properties:  make(map[string]string),
@@ -1559,6 +3141,78 @@ func validateWalkFilePath(basePath, walkFile, deviceName string) (string, error)
 	return fullPath, nil
 }
 
+// parseWalkSeries resolves a walk series directory to its snapshot files.
+// Every regular, non-hidden file in the directory is a snapshot; names must
+// sort chronologically (e.g. timestamped names). Each file passes the same
+// path checks as walk_file.
+func parseWalkSeries(yamlSeries *converter.WalkSeries, basePath, deviceName string) (*WalkSeries, error) {
+	if yamlSeries == nil {
+		return nil, nil
+	}
+	if yamlSeries.Directory == "" {
+		return nil, fmt.Errorf("device %s: walk_series requires a directory", deviceName)
+	}
+	if yamlSeries.Interval < 0 {
+		return nil, fmt.Errorf("device %s: walk_series interval must not be negative: %d", deviceName, yamlSeries.Interval)
+	}
+
+	files, err := resolveWalkDirectory(yamlSeries.Directory, "walk_series", basePath, deviceName, func(string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	series := &WalkSeries{
+		Files:    files,
+		Interval: time.Duration(yamlSeries.Interval) * time.Second,
+		Loop:     yamlSeries.Loop,
+	}
+	if series.Interval == 0 {
+		series.Interval = DefaultWalkSeriesInterval * time.Second
+	}
+	return series, nil
+}
+
+// isWalkFileName reports whether a walk_directory entry is a walk file
+func isWalkFileName(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".walk")
+}
+
+// resolveWalkDirectory returns the walk files in dir, sorted by name. Hidden
+// files, subdirectories and names not accepted by match are skipped; every
+// other file passes the same path checks as walk_file. setting names the
+// config key in error messages.
+func resolveWalkDirectory(dir, setting, basePath, deviceName string, match func(name string) bool) ([]string, error) {
+	cleanDir := filepath.Clean(dir)
+	if strings.Contains(cleanDir, "..") {
+		return nil, fmt.Errorf("device %s: path traversal detected: %s", deviceName, dir)
+	}
+	fullDir := cleanDir
+	if !filepath.IsAbs(cleanDir) && basePath != "" {
+		fullDir = filepath.Join(basePath, cleanDir)
+	}
+	entries, err := os.ReadDir(fullDir)
+	if err != nil {
+		return nil, fmt.Errorf("device %s: cannot read %s directory %s: %w", deviceName, setting, fullDir, err)
+	}
+
+	var files []string
+	// os.ReadDir returns entries sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !match(entry.Name()) {
+			continue
+		}
+		file, err := validateWalkFilePath(basePath, filepath.Join(cleanDir, entry.Name()), deviceName)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("device %s: %s directory %s contains no walk files", deviceName, setting, fullDir)
+	}
+	return files, nil
+}
+
 // ParseSpeed parses interface speed (e.g., "100M", "1G", "10G")
 func ParseSpeed(speedStr string) (int, error) {
 	speedStr = strings.ToUpper(strings.TrimSpace(speedStr))
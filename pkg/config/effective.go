@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EffectiveYAML renders the configuration as it is running: every default
+// filled in, MACs derived and paths resolved. Keys are the Go field names in
+// snake_case, in declaration order; zero values and empty lists are left out.
+// The output describes the loaded configuration and is not meant to be loaded
+// back in place of the source file.
+func (c *Config) EffectiveYAML() ([]byte, error) {
+	node, ok := effectiveNode(reflect.ValueOf(c))
+	if !ok {
+		node = &yaml.Node{Kind: yaml.MappingNode}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	durationType     = reflect.TypeOf(time.Duration(0))
+	timeType         = reflect.TypeOf(time.Time{})
+	ipType           = reflect.TypeOf(net.IP(nil))
+	hardwareAddrType = reflect.TypeOf(net.HardwareAddr(nil))
+	ipNetType        = reflect.TypeOf(net.IPNet{})
+	regexpType       = reflect.TypeOf(regexp.Regexp{})
+	textMarshaler    = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// effectiveNode converts v to a YAML node. It returns false for values left
+// out of the output: zero values, empty collections, funcs and channels.
+func effectiveNode(v reflect.Value) (*yaml.Node, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.IsZero() {
+		return nil, false
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		return nil, false
+	}
+
+	// Addresses, durations and patterns read as they are written in YAML
+	switch v.Type() {
+	case durationType:
+		return scalarNode(v.Interface().(time.Duration).String()), true
+	case timeType:
+		return scalarNode(v.Interface().(time.Time).Format(time.RFC3339)), true
+	case ipType:
+		return scalarNode(v.Interface().(net.IP).String()), true
+	case hardwareAddrType:
+		return scalarNode(v.Interface().(net.HardwareAddr).String()), true
+	case ipNetType:
+		ipNet := v.Interface().(net.IPNet)
+		return scalarNode(ipNet.String()), true
+	case regexpType:
+		re := v.Addr().Interface().(*regexp.Regexp)
+		return scalarNode(re.String()), true
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return structNode(v)
+	case reflect.Map:
+		return mapNode(v)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// Raw bytes, such as keys
+			raw := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(raw), v)
+			return scalarNode(hex.EncodeToString(raw)), true
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for i := 0; i < v.Len(); i++ {
+			item, ok := effectiveNode(v.Index(i))
+			if !ok {
+				// Keep list positions: a zero element is still an element
+				item = zeroNode(v.Index(i))
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, true
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return nil, false
+	}
+
+	if v.Type().Implements(textMarshaler) {
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return scalarNode(string(text)), true
+		}
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(v.Interface()); err != nil {
+		return scalarNode(fmt.Sprint(v.Interface())), true
+	}
+	return node, true
+}
+
+// structNode renders a struct's exported fields in declaration order
+func structNode(v reflect.Value) (*yaml.Node, bool) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value, ok := effectiveNode(v.Field(i))
+		if !ok {
+			continue
+		}
+		node.Content = append(node.Content, scalarNode(snakeCase(field.Name)), value)
+	}
+	return node, len(node.Content) > 0
+}
+
+// mapNode renders a map with its keys sorted
+func mapNode(v reflect.Value) (*yaml.Node, bool) {
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key := fmt.Sprint(iter.Key().Interface())
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	sort.Strings(keys)
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		if value, ok := effectiveNode(values[key]); ok {
+			node.Content = append(node.Content, scalarNode(key), value)
+		}
+	}
+	return node, len(node.Content) > 0
+}
+
+// zeroNode renders a zero or nil list element
+func zeroNode(v reflect.Value) *yaml.Node {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+		}
+		v = v.Elem()
+	}
+	node := &yaml.Node{}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		node.Kind = yaml.MappingNode
+	case reflect.Slice, reflect.Array:
+		node.Kind = yaml.SequenceNode
+	default:
+		if err := node.Encode(v.Interface()); err != nil {
+			return scalarNode(fmt.Sprint(v.Interface()))
+		}
+	}
+	return node
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// snakeCase converts a Go field name to snake_case, keeping acronyms and
+// version suffixes whole: LLDPConfig -> lldp_config, DHCPv6Config ->
+// dhcpv6_config, IPAddresses -> ip_addresses.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower && !isVersionSuffix(runes[i+1:])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// isVersionSuffix reports whether runes start with a version like "v6"
+func isVersionSuffix(runes []rune) bool {
+	return len(runes) >= 2 && runes[0] == 'v' && unicode.IsDigit(runes[1])
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// Flow export protocols and defaults
+const (
+	FlowProtocolSFlow = "sflow"
+
+	DefaultFlowCollectorPort = 6343 // IANA sFlow port
+	DefaultFlowSamplingRate  = 256
+)
+
+// FlowExportConfig configures export of sampled traffic to a flow collector
+type FlowExportConfig struct {
+	Collector    string // host:port
+	SamplingRate int    // Sample 1 in N packets
+	Protocol     string // sflow
+	AgentIP      net.IP // Agent address reported in datagrams (nil = local address)
+}
+
+// parseFlowExportConfig parses the flow_export block from YAML
+func parseFlowExportConfig(yamlFlow *converter.FlowExportConfig) (*FlowExportConfig, error) {
+	if yamlFlow == nil {
+		return nil, nil
+	}
+
+	collector := strings.TrimSpace(yamlFlow.Collector)
+	if collector == "" {
+		return nil, fmt.Errorf("flow_export: collector is required")
+	}
+	if _, _, err := net.SplitHostPort(collector); err != nil {
+		collector = net.JoinHostPort(collector, strconv.Itoa(DefaultFlowCollectorPort))
+	}
+	host, port, err := net.SplitHostPort(collector)
+	if portNum, convErr := strconv.Atoi(port); err != nil || host == "" || convErr != nil || portNum < 1 || portNum > 65535 {
+		return nil, fmt.Errorf("flow_export: invalid collector %s (expected host:port)", yamlFlow.Collector)
+	}
+
+	protocol := strings.ToLower(strings.TrimSpace(yamlFlow.Protocol))
+	switch protocol {
+	case "":
+		protocol = FlowProtocolSFlow
+	case FlowProtocolSFlow:
+	case "netflow":
+		return nil, fmt.Errorf("flow_export: protocol netflow is not supported yet (use %s)", FlowProtocolSFlow)
+	default:
+		return nil, fmt.Errorf("flow_export: invalid protocol %q (must be %s)", yamlFlow.Protocol, FlowProtocolSFlow)
+	}
+
+	samplingRate := yamlFlow.SamplingRate
+	if samplingRate == 0 {
+		samplingRate = DefaultFlowSamplingRate
+	}
+	if samplingRate < 1 {
+		return nil, fmt.Errorf("flow_export: sampling_rate must be at least 1: %d", samplingRate)
+	}
+
+	flowCfg := &FlowExportConfig{
+		Collector:    collector,
+		SamplingRate: samplingRate,
+		Protocol:     protocol,
+	}
+	if yamlFlow.AgentIP != "" {
+		if flowCfg.AgentIP = net.ParseIP(yamlFlow.AgentIP); flowCfg.AgentIP == nil {
+			return nil, fmt.Errorf("flow_export: invalid agent_ip %s", yamlFlow.AgentIP)
+		}
+	}
+
+	return flowCfg, nil
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// HOST-RESOURCES-MIB defaults and limits. Sizes are capped so hrStorageSize
+// (an Integer32 of allocation units) cannot overflow.
+const (
+	DefaultHostMemoryMB          = 8192
+	DefaultHostMemoryUsedPercent = 40
+	DefaultHostSwapMB            = 2048
+	DefaultHostFilesystemSizeGB  = 100
+	DefaultHostFilesystemUsed    = 45 // percent
+
+	MaxHostMemoryMB     = 1048576 // 1 TB
+	MaxHostFilesystemGB = 8000
+)
+
+// DefaultHostProcesses is the hrSWRunTable of a server without a process list
+var DefaultHostProcesses = []string{"systemd", "sshd", "snmpd", "crond", "rsyslogd"}
+
+// HostResourcesConfig describes the HOST-RESOURCES-MIB (RFC 2790) storage and
+// process tables exposed by a server's SNMP agent.
+type HostResourcesConfig struct {
+	MemoryMB          int
+	MemoryUsedPercent int // Baseline RAM usage; "High Memory" injection overrides it
+	SwapMB            int
+	Filesystems       []HostFilesystem
+	Processes         []string // hrSWRunTable entries
+}
+
+// HostFilesystem is a fixed disk in hrStorageTable
+type HostFilesystem struct {
+	Path        string
+	SizeGB      int
+	UsedPercent int // Baseline usage; "High Disk" injection overrides it
+}
+
+// DefaultHostResourcesConfig returns the tables used for "server" devices
+// that do not configure host_resources.
+func DefaultHostResourcesConfig() *HostResourcesConfig {
+	return &HostResourcesConfig{
+		MemoryMB:          DefaultHostMemoryMB,
+		MemoryUsedPercent: DefaultHostMemoryUsedPercent,
+		SwapMB:            DefaultHostSwapMB,
+		Filesystems: []HostFilesystem{
+			{Path: "/", SizeGB: DefaultHostFilesystemSizeGB, UsedPercent: DefaultHostFilesystemUsed},
+		},
+		Processes: append([]string(nil), DefaultHostProcesses...),
+	}
+}
+
+// parseHostResourcesConfig parses HOST-RESOURCES-MIB settings from YAML,
+// filling unset values with defaults
+func parseHostResourcesConfig(yamlHR *converter.HostResourcesConfig, deviceName string) (*HostResourcesConfig, error) {
+	if yamlHR == nil {
+		return nil, nil
+	}
+
+	hr := DefaultHostResourcesConfig()
+	if yamlHR.MemoryMB != 0 {
+		hr.MemoryMB = yamlHR.MemoryMB
+	}
+	if yamlHR.MemoryUsedPercent != 0 {
+		hr.MemoryUsedPercent = yamlHR.MemoryUsedPercent
+	}
+	if yamlHR.SwapMB != 0 {
+		hr.SwapMB = yamlHR.SwapMB
+	}
+
+	if hr.MemoryMB < 0 || hr.MemoryMB > MaxHostMemoryMB {
+		return nil, fmt.Errorf("device %s: host_resources memory_mb must be between 1 and %d: %d",
+			deviceName, MaxHostMemoryMB, hr.MemoryMB)
+	}
+	if hr.SwapMB < 0 || hr.SwapMB > MaxHostMemoryMB {
+		return nil, fmt.Errorf("device %s: host_resources swap_mb must be between 0 and %d: %d",
+			deviceName, MaxHostMemoryMB, hr.SwapMB)
+	}
+	if hr.MemoryUsedPercent < 0 || hr.MemoryUsedPercent > 100 {
+		return nil, fmt.Errorf("device %s: host_resources memory_used_percent must be between 0 and 100: %d",
+			deviceName, hr.MemoryUsedPercent)
+	}
+
+	if len(yamlHR.Filesystems) > 0 {
+		hr.Filesystems = make([]HostFilesystem, 0, len(yamlHR.Filesystems))
+		for _, yfs := range yamlHR.Filesystems {
+			fs := HostFilesystem{
+				Path:        strings.TrimSpace(yfs.Path),
+				SizeGB:      yfs.SizeGB,
+				UsedPercent: yfs.UsedPercent,
+			}
+			if fs.Path == "" {
+				return nil, fmt.Errorf("device %s: host_resources filesystem path cannot be empty", deviceName)
+			}
+			if fs.SizeGB == 0 {
+				fs.SizeGB = DefaultHostFilesystemSizeGB
+			}
+			if fs.SizeGB < 0 || fs.SizeGB > MaxHostFilesystemGB {
+				return nil, fmt.Errorf("device %s: host_resources filesystem %s size_gb must be between 1 and %d: %d",
+					deviceName, fs.Path, MaxHostFilesystemGB, fs.SizeGB)
+			}
+			if fs.UsedPercent < 0 || fs.UsedPercent > 100 {
+				return nil, fmt.Errorf("device %s: host_resources filesystem %s used_percent must be between 0 and 100: %d",
+					deviceName, fs.Path, fs.UsedPercent)
+			}
+			hr.Filesystems = append(hr.Filesystems, fs)
+		}
+	}
+
+	if len(yamlHR.Processes) > 0 {
+		hr.Processes = hr.Processes[:0]
+		for _, name := range yamlHR.Processes {
+			if name = strings.TrimSpace(name); name != "" {
+				hr.Processes = append(hr.Processes, name)
+			}
+		}
+	}
+
+	return hr, nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// Latency distributions
+const (
+	LatencyDistributionUniform = "uniform" // base ± jitter, evenly spread
+	LatencyDistributionNormal  = "normal"  // base with jitter as the standard deviation
+
+	// MaxLatencyMs caps base_ms and jitter_ms so a typo cannot stall responses
+	MaxLatencyMs = 10000 // 10 seconds
+)
+
+// LatencyConfig models the response latency of a simulated device. Responses
+// are held back by Base plus a random jitter drawn from Distribution.
+type LatencyConfig struct {
+	Base         time.Duration
+	Jitter       time.Duration
+	Distribution string // uniform or normal
+}
+
+// Sample draws the delay to apply to a single response. Negative samples are
+// clamped to zero. A nil model adds no latency.
+func (c *LatencyConfig) Sample() time.Duration {
+	if c == nil {
+		return 0
+	}
+
+	delay := c.Base
+	if c.Jitter > 0 {
+		switch c.Distribution {
+		case LatencyDistributionNormal:
+			delay += time.Duration(rand.NormFloat64() * float64(c.Jitter))
+		default:
+			delay += time.Duration(rand.Int64N(int64(2*c.Jitter)+1)) - c.Jitter
+		}
+	}
+
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// LatencyFor returns the latency model for a device: its own model when set,
+// otherwise the global default (which may be nil).
+func (c *Config) LatencyFor(device *Device) *LatencyConfig {
+	if device != nil && device.Latency != nil {
+		return device.Latency
+	}
+	if c == nil {
+		return nil
+	}
+	return c.Latency
+}
+
+// parseLatencyConfig parses a latency model from YAML. scope names the owner
+// (a device or "global") in error messages.
+func parseLatencyConfig(yamlLatency *converter.LatencyConfig, scope string) (*LatencyConfig, error) {
+	if yamlLatency == nil {
+		return nil, nil
+	}
+
+	if yamlLatency.BaseMs < 0 || yamlLatency.BaseMs > MaxLatencyMs {
+		return nil, fmt.Errorf("%s: latency base_ms must be between 0 and %d: %d",
+			scope, MaxLatencyMs, yamlLatency.BaseMs)
+	}
+	if yamlLatency.JitterMs < 0 || yamlLatency.JitterMs > MaxLatencyMs {
+		return nil, fmt.Errorf("%s: latency jitter_ms must be between 0 and %d: %d",
+			scope, MaxLatencyMs, yamlLatency.JitterMs)
+	}
+
+	distribution := strings.ToLower(strings.TrimSpace(yamlLatency.Distribution))
+	switch distribution {
+	case "":
+		distribution = LatencyDistributionUniform
+	case LatencyDistributionUniform, LatencyDistributionNormal:
+	default:
+		return nil, fmt.Errorf("%s: invalid latency distribution %q (must be %s or %s)",
+			scope, yamlLatency.Distribution, LatencyDistributionUniform, LatencyDistributionNormal)
+	}
+
+	return &LatencyConfig{
+		Base:         time.Duration(yamlLatency.BaseMs) * time.Millisecond,
+		Jitter:       time.Duration(yamlLatency.JitterMs) * time.Millisecond,
+		Distribution: distribution,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// Protocols malformed_frames can corrupt
+const (
+	MalformedProtocolIPv4 = "ipv4"
+	MalformedProtocolICMP = "icmp"
+	MalformedProtocolUDP  = "udp"
+	MalformedProtocolTCP  = "tcp"
+)
+
+// Corruptions malformed_frames can apply
+const (
+	MalformedBadChecksum     = "bad_checksum"      // The protocol's checksum is off by one
+	MalformedTruncated       = "truncated"         // The frame ends inside the protocol's header
+	MalformedBadOptionLength = "bad_option_length" // An option claims more bytes than the header holds (ipv4, tcp)
+)
+
+const (
+	// DefaultMalformedRate is the frames per second sent when rate is not set
+	DefaultMalformedRate = 1
+
+	// MaxMalformedRate caps the frames per second of each device
+	MaxMalformedRate = 1000
+)
+
+// MalformedFramesConfig makes a device broadcast deliberately corrupt frames
+// of one protocol, for fuzzing the parsers of monitoring tools on the wire.
+type MalformedFramesConfig struct {
+	Protocol   string // MalformedProtocolIPv4, MalformedProtocolICMP, MalformedProtocolUDP or MalformedProtocolTCP
+	Corruption string // MalformedBadChecksum, MalformedTruncated or MalformedBadOptionLength
+	Rate       int    // Frames per second
+}
+
+// parseMalformedFramesConfig parses a device's malformed_frames block. The
+// block is validated even when disabled; only enabled: true sends frames.
+func parseMalformedFramesConfig(yamlMalformed *converter.MalformedFramesConfig, device *Device) (*MalformedFramesConfig, error) {
+	if yamlMalformed == nil {
+		return nil, nil
+	}
+
+	malformed := &MalformedFramesConfig{
+		Protocol:   strings.ToLower(strings.TrimSpace(yamlMalformed.Protocol)),
+		Corruption: strings.ToLower(strings.TrimSpace(yamlMalformed.Corruption)),
+		Rate:       yamlMalformed.Rate,
+	}
+	switch malformed.Protocol {
+	case MalformedProtocolIPv4, MalformedProtocolICMP, MalformedProtocolUDP, MalformedProtocolTCP:
+	default:
+		return nil, fmt.Errorf("device %s: invalid malformed_frames protocol %q (must be %s, %s, %s or %s)",
+			device.Name, yamlMalformed.Protocol, MalformedProtocolIPv4, MalformedProtocolICMP, MalformedProtocolUDP, MalformedProtocolTCP)
+	}
+	switch malformed.Corruption {
+	case MalformedBadChecksum, MalformedTruncated:
+	case MalformedBadOptionLength:
+		if malformed.Protocol != MalformedProtocolIPv4 && malformed.Protocol != MalformedProtocolTCP {
+			return nil, fmt.Errorf("device %s: malformed_frames corruption %s needs protocol %s or %s, not %s",
+				device.Name, MalformedBadOptionLength, MalformedProtocolIPv4, MalformedProtocolTCP, malformed.Protocol)
+		}
+	default:
+		return nil, fmt.Errorf("device %s: invalid malformed_frames corruption %q (must be %s, %s or %s)",
+			device.Name, yamlMalformed.Corruption, MalformedBadChecksum, MalformedTruncated, MalformedBadOptionLength)
+	}
+	if malformed.Rate == 0 {
+		malformed.Rate = DefaultMalformedRate
+	}
+	if malformed.Rate < 1 || malformed.Rate > MaxMalformedRate {
+		return nil, fmt.Errorf("device %s: malformed_frames rate must be between 1 and %d frames per second: %d",
+			device.Name, MaxMalformedRate, malformed.Rate)
+	}
+	hasIPv4 := false
+	for _, ip := range device.IPAddresses {
+		hasIPv4 = hasIPv4 || ip.To4() != nil
+	}
+	if !hasIPv4 {
+		return nil, fmt.Errorf("device %s: malformed_frames needs an IPv4 address to send from", device.Name)
+	}
+
+	if !yamlMalformed.Enabled {
+		return nil, nil
+	}
+	return malformed, nil
+}
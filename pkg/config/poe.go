@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// PoE power priorities (IEEE 802.3at)
+const (
+	PoEPriorityCritical = "critical"
+	PoEPriorityHigh     = "high"
+	PoEPriorityLow      = "low"
+)
+
+// MaxPoEClass is the highest IEEE 802.3af/at power class
+const MaxPoEClass = 4
+
+// poeClassWatts is the most power a PD of each class may draw (IEEE 802.3at
+// Table 33-18)
+var poeClassWatts = [MaxPoEClass + 1]float64{12.95, 3.84, 6.49, 12.95, 25.5}
+
+// PoEConfig describes the device as a powered device (PD). Its power class
+// and request are advertised in LLDP (Power via MDI) and CDP (Power, Power
+// Request), so a PoE switch under test can allocate or deny power.
+type PoEConfig struct {
+	Class          int     // IEEE 802.3af/at power class (0-4)
+	RequestedWatts float64 // Power requested from the PSE
+	Priority       string  // PoEPriorityCritical, PoEPriorityHigh or PoEPriorityLow
+}
+
+// RequestedMilliwatts returns the requested power in milliwatts
+func (c *PoEConfig) RequestedMilliwatts() uint32 {
+	return uint32(c.RequestedWatts*1000 + 0.5)
+}
+
+// parsePoEConfig parses the PoE needs of a device. The requested power
+// defaults to the class maximum and may not exceed it.
+func parsePoEConfig(yamlPoe *converter.PoeConfig, deviceName string) (*PoEConfig, error) {
+	if yamlPoe == nil {
+		return nil, nil
+	}
+
+	if yamlPoe.Class < 0 || yamlPoe.Class > MaxPoEClass {
+		return nil, fmt.Errorf("device %s: PoE class must be between 0 and %d: %d",
+			deviceName, MaxPoEClass, yamlPoe.Class)
+	}
+	classWatts := poeClassWatts[yamlPoe.Class]
+	if yamlPoe.RequestedWatts < 0 || yamlPoe.RequestedWatts > classWatts {
+		return nil, fmt.Errorf("device %s: PoE requested_watts must be between 0 and %.2f for class %d: %g",
+			deviceName, classWatts, yamlPoe.Class, yamlPoe.RequestedWatts)
+	}
+
+	poe := &PoEConfig{
+		Class:          yamlPoe.Class,
+		RequestedWatts: yamlPoe.RequestedWatts,
+		Priority:       strings.ToLower(strings.TrimSpace(yamlPoe.Priority)),
+	}
+	if poe.RequestedWatts == 0 {
+		poe.RequestedWatts = classWatts
+	}
+	switch poe.Priority {
+	case "":
+		poe.Priority = PoEPriorityLow
+	case PoEPriorityCritical, PoEPriorityHigh, PoEPriorityLow:
+	default:
+		return nil, fmt.Errorf("device %s: invalid PoE priority %q (must be %s, %s or %s)",
+			deviceName, yamlPoe.Priority, PoEPriorityCritical, PoEPriorityHigh, PoEPriorityLow)
+	}
+
+	return poe, nil
+}
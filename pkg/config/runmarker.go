@@ -0,0 +1,59 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// RunMarkerPrefix starts the Ethernet trailer appended to generated frames
+// when a run ID is set, so captures can be filtered with
+// `eth.trailer contains "NIAC"` or `frame contains "NIAC"`.
+const RunMarkerPrefix = "NIAC"
+
+// MaxDSCP is the largest Differentiated Services Code Point (6 bits)
+const MaxDSCP = 63
+
+// RunMarkerConfig tags every generated packet so traffic from one simulator
+// instance can be told apart from others sharing the segment
+type RunMarkerConfig struct {
+	RunID string // Derives the Ethernet trailer marker ("" = no trailer)
+	DSCP  int    // DSCP set on generated IPv4/IPv6 packets (0 = unchanged)
+}
+
+// RunMarkerTrailer returns the Ethernet trailer derived from runID:
+// RunMarkerPrefix followed by the first 4 bytes of its SHA-256 digest. The
+// same run ID always yields the same trailer.
+func RunMarkerTrailer(runID string) []byte {
+	if runID == "" {
+		return nil
+	}
+	digest := sha256.Sum256([]byte(runID))
+	return append([]byte(RunMarkerPrefix), digest[:4]...)
+}
+
+// Trailer returns the Ethernet trailer for the configured run ID, or nil
+func (r *RunMarkerConfig) Trailer() []byte {
+	if r == nil {
+		return nil
+	}
+	return RunMarkerTrailer(r.RunID)
+}
+
+// parseRunMarkerConfig parses the run_marker block from YAML
+func parseRunMarkerConfig(yamlMarker *converter.RunMarkerConfig) (*RunMarkerConfig, error) {
+	if yamlMarker == nil {
+		return nil, nil
+	}
+
+	if yamlMarker.DSCP < 0 || yamlMarker.DSCP > MaxDSCP {
+		return nil, fmt.Errorf("run_marker: dscp must be between 0 and %d: %d", MaxDSCP, yamlMarker.DSCP)
+	}
+
+	return &RunMarkerConfig{
+		RunID: strings.TrimSpace(yamlMarker.RunID),
+		DSCP:  yamlMarker.DSCP,
+	}, nil
+}
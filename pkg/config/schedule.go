@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// TrafficSchedule restricts a traffic pattern to time-of-day windows on
+// selected weekdays, evaluated against the local process clock.
+type TrafficSchedule struct {
+	Windows []TimeWindow   // Empty = all day
+	Days    []time.Weekday // Empty = every day
+}
+
+// TimeWindow is a daily window given as offsets from midnight. A window whose
+// End is not after Start runs past midnight (e.g. 22:00-06:00); the early
+// morning part belongs to the previous day's schedule.
+type TimeWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Active reports whether the schedule allows traffic at t. A nil schedule is
+// always active.
+func (s *TrafficSchedule) Active(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+
+	day := t.Weekday()
+	if len(s.Windows) == 0 {
+		return s.runsOn(day)
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	yesterday := (day + 6) % 7
+	for _, w := range s.Windows {
+		if w.Start < w.End {
+			if offset >= w.Start && offset < w.End && s.runsOn(day) {
+				return true
+			}
+			continue
+		}
+		if offset >= w.Start && s.runsOn(day) {
+			return true
+		}
+		if offset < w.End && s.runsOn(yesterday) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *TrafficSchedule) runsOn(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the schedule in its YAML form, e.g. "mon,fri 08:00-18:00".
+func (s *TrafficSchedule) String() string {
+	if s == nil {
+		return ""
+	}
+	var parts []string
+	if len(s.Days) > 0 {
+		days := make([]string, len(s.Days))
+		for i, d := range s.Days {
+			days[i] = strings.ToLower(d.String()[:3])
+		}
+		parts = append(parts, strings.Join(days, ","))
+	}
+	for _, w := range s.Windows {
+		parts = append(parts, formatClock(w.Start)+"-"+formatClock(w.End))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// parseTrafficSchedule parses a traffic pattern schedule from YAML. scope
+// names the owning device and pattern in error messages.
+func parseTrafficSchedule(yamlSchedule *converter.TrafficScheduleConfig, scope string) (*TrafficSchedule, error) {
+	if yamlSchedule == nil {
+		return nil, nil
+	}
+	if len(yamlSchedule.Windows) == 0 && len(yamlSchedule.Days) == 0 {
+		return nil, fmt.Errorf("%s: schedule needs windows or days", scope)
+	}
+
+	schedule := &TrafficSchedule{}
+	for _, name := range yamlSchedule.Days {
+		day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid schedule day %q (use sun, mon, tue, wed, thu, fri or sat)", scope, name)
+		}
+		schedule.Days = append(schedule.Days, day)
+	}
+
+	for _, spec := range yamlSchedule.Windows {
+		startSpec, endSpec, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid schedule window %q (expected HH:MM-HH:MM)", scope, spec)
+		}
+		start, err := parseClock(startSpec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid schedule window %q: %v", scope, spec, err)
+		}
+		end, err := parseClock(endSpec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid schedule window %q: %v", scope, spec, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("%s: schedule window %q is empty", scope, spec)
+		}
+		schedule.Windows = append(schedule.Windows, TimeWindow{Start: start, End: end})
+	}
+
+	return schedule, nil
+}
+
+// parseClock parses HH:MM (00:00 through 24:00) as an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	hourSpec, minuteSpec, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("time %q must be HH:MM", s)
+	}
+	hour, err := strconv.Atoi(hourSpec)
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be HH:MM", s)
+	}
+	minute, err := strconv.Atoi(minuteSpec)
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be HH:MM", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("time %q out of range", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TagSelectorPrefix marks a device selector that matches by tag instead of name
+// (e.g. "tag:edge").
+const TagSelectorPrefix = "tag:"
+
+// normalizeTags trims tags and drops blanks and duplicates while preserving order.
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// HasTag reports whether the device carries the given tag.
+func (d *Device) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesSelector reports whether the device matches a selector. A selector is
+// either a device name or "tag:<name>" to match every device carrying that tag.
+func (d *Device) MatchesSelector(selector string) bool {
+	selector = strings.TrimSpace(selector)
+	if tag, ok := strings.CutPrefix(selector, TagSelectorPrefix); ok {
+		return d.HasTag(strings.TrimSpace(tag))
+	}
+	return selector != "" && d.Name == selector
+}
+
+// DevicesWithTag returns the devices carrying the given tag.
+func (c *Config) DevicesWithTag(tag string) []*Device {
+	var matched []*Device
+	for i := range c.Devices {
+		if c.Devices[i].HasTag(tag) {
+			matched = append(matched, &c.Devices[i])
+		}
+	}
+	return matched
+}
+
+// HasDevice reports whether a device with the given name is configured.
+func (c *Config) HasDevice(name string) bool {
+	for i := range c.Devices {
+		if c.Devices[i].Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDevices restricts the configuration to devices matching any of the
+// "only" selectors (when given) and none of the "exclude" selectors. It returns
+// an error if a selector matches nothing or no devices remain.
+func (c *Config) FilterDevices(only, exclude []string) error {
+	if len(only) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	for _, selector := range append(append([]string{}, only...), exclude...) {
+		if !c.selectorMatchesAny(selector) {
+			return fmt.Errorf("device selector %q matches no devices", selector)
+		}
+	}
+
+	filtered := make([]Device, 0, len(c.Devices))
+	for _, device := range c.Devices {
+		if len(only) > 0 && !matchesAnySelector(&device, only) {
+			continue
+		}
+		if matchesAnySelector(&device, exclude) {
+			continue
+		}
+		filtered = append(filtered, device)
+	}
+
+	if len(filtered) == 0 {
+		return fmt.Errorf("device filters excluded every device")
+	}
+	c.Devices = filtered
+	return nil
+}
+
+func (c *Config) selectorMatchesAny(selector string) bool {
+	for i := range c.Devices {
+		if c.Devices[i].MatchesSelector(selector) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnySelector(device *Device, selectors []string) bool {
+	for _, selector := range selectors {
+		if device.MatchesSelector(selector) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/krisarmstrong/niac-go/internal/converter"
+)
+
+// Trap varbind value types
+const (
+	TrapVarbindInteger   = "integer"
+	TrapVarbindString    = "string"
+	TrapVarbindOID       = "oid"
+	TrapVarbindIPAddress = "ipaddress"
+	TrapVarbindCounter32 = "counter32"
+	TrapVarbindGauge32   = "gauge32"
+	TrapVarbindTimeTicks = "timeticks"
+	TrapVarbindCounter64 = "counter64"
+)
+
+var trapVarbindTypes = map[string]bool{
+	TrapVarbindInteger:   true,
+	TrapVarbindString:    true,
+	TrapVarbindOID:       true,
+	TrapVarbindIPAddress: true,
+	TrapVarbindCounter32: true,
+	TrapVarbindGauge32:   true,
+	TrapVarbindTimeTicks: true,
+	TrapVarbindCounter64: true,
+}
+
+// Trap names a varbind can be limited to (the names used in trap logs)
+var trapVarbindTraps = map[string]bool{
+	"coldStart":             true,
+	"linkDown":              true,
+	"linkUp":                true,
+	"authenticationFailure": true,
+	"highCPU":               true,
+	"highMemory":            true,
+	"interfaceErrors":       true,
+}
+
+// TrapVarbind is an extra varbind appended to a device's outgoing traps so
+// they match a target platform's format. Value is a text/template rendered
+// with the trap's runtime data ({{.Device}}, {{.Trap}}, {{.IfIndex}},
+// {{.IfDescr}}, {{.Value}}).
+type TrapVarbind struct {
+	OID   string
+	Type  string   // integer, string, oid, ipaddress, counter32, gauge32, timeticks, counter64
+	Value string   // Value template
+	Traps []string // Only append to these traps (empty = all)
+}
+
+// AppliesTo reports whether the varbind is appended to the named trap
+func (v TrapVarbind) AppliesTo(trap string) bool {
+	if len(v.Traps) == 0 {
+		return true
+	}
+	for _, name := range v.Traps {
+		if name == trap {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrapVarbinds validates trap_varbinds entries from YAML
+func parseTrapVarbinds(yamlVarbinds []converter.TrapVarbind, deviceName string) ([]TrapVarbind, error) {
+	if len(yamlVarbinds) == 0 {
+		return nil, nil
+	}
+
+	varbinds := make([]TrapVarbind, 0, len(yamlVarbinds))
+	for i, yv := range yamlVarbinds {
+		oid, err := normalizeViewOID(yv.OID, deviceName)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: trap varbind %d: invalid OID %q", deviceName, i+1, yv.OID)
+		}
+
+		varType := strings.ToLower(strings.TrimSpace(yv.Type))
+		if varType == "" {
+			varType = TrapVarbindString
+		}
+		if !trapVarbindTypes[varType] {
+			return nil, fmt.Errorf("device %s: trap varbind %s: unsupported type %q", deviceName, oid, yv.Type)
+		}
+
+		if _, err := template.New(oid).Parse(yv.Value); err != nil {
+			return nil, fmt.Errorf("device %s: trap varbind %s: invalid value template: %w", deviceName, oid, err)
+		}
+
+		for _, trap := range yv.Traps {
+			if !trapVarbindTraps[trap] {
+				return nil, fmt.Errorf("device %s: trap varbind %s: unknown trap %q", deviceName, oid, trap)
+			}
+		}
+
+		varbinds = append(varbinds, TrapVarbind{
+			OID:   "." + oid,
+			Type:  varType,
+			Value: yv.Value,
+			Traps: yv.Traps,
+		})
+	}
+	return varbinds, nil
+}
@@ -90,6 +90,9 @@ func (v *Validator) validateDevice(device *Device, index int, names map[string]b
 			v.addError(fmt.Sprintf("%s.ip_addresses[%d]", prefix, j), "IP address is nil")
 			continue
 		}
+		if err := validateDeviceAddress(ip); err != nil {
+			v.addError(fmt.Sprintf("%s.ip_addresses[%d]", prefix, j), err.Error())
+		}
 
 		ipStr := ip.String()
 		if existingDevice, exists := ips[ipStr]; exists {
@@ -102,6 +105,7 @@ func (v *Validator) validateDevice(device *Device, index int, names map[string]b
 	// Validate protocol-specific configurations
 	v.validateSNMPTraps(device, prefix)
 	v.validateDNSRecords(device, prefix)
+	v.validateDHCPv6Pools(device, prefix)
 
 	// Validate topology configurations (v1.23.0)
 	v.validatePortChannels(device, prefix)
@@ -137,19 +141,8 @@ func (v *Validator) validateSNMPTraps(device *Device, prefix string) {
 			continue
 		}
 
-		// Parse receiver format (should be IP:port or just IP)
-		host, _, err := net.SplitHostPort(receiver)
-		if err != nil {
-			// Try parsing as just IP
-			if ip := net.ParseIP(receiver); ip == nil {
-				v.addError(fmt.Sprintf("%s.receivers[%d]", trapPrefix, i),
-					fmt.Sprintf("invalid trap receiver format: %s", receiver))
-			}
-		} else {
-			if ip := net.ParseIP(host); ip == nil {
-				v.addError(fmt.Sprintf("%s.receivers[%d]", trapPrefix, i),
-					fmt.Sprintf("invalid IP in trap receiver: %s", host))
-			}
+		if _, _, err := ParseTrapReceiver(receiver); err != nil {
+			v.addError(fmt.Sprintf("%s.receivers[%d]", trapPrefix, i), err.Error())
 		}
 	}
 }
@@ -194,6 +187,18 @@ func (v *Validator) validateDNSRecords(device *Device, prefix string) {
 	}
 }
 
+// validateDHCPv6Pools validates DHCPv6 pool prefixes and ranges
+func (v *Validator) validateDHCPv6Pools(device *Device, prefix string) {
+	if device.DHCPv6Config == nil {
+		return
+	}
+	for i, pool := range device.DHCPv6Config.Pools {
+		if err := validateDHCPv6Pool(pool); err != nil {
+			v.addError(fmt.Sprintf("%s.dhcpv6.pools[%d]", prefix, i), err.Error())
+		}
+	}
+}
+
 // validateThreshold validates a threshold value (0-100)
 func (v *Validator) validateThreshold(value int, field string) error {
 	if value < 0 || value > 100 {
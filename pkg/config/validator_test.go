@@ -186,6 +186,36 @@ func TestValidate_DuplicateIP(t *testing.T) {
 	}
 }
 
+func TestValidate_IPv6Addresses(t *testing.T) {
+	cfg := &Config{
+		Devices: []Device{
+			{
+				Name:        "router-01",
+				Type:        "router",
+				MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+				IPAddresses: []net.IP{net.ParseIP("ff02::1")},
+				DHCPv6Config: &DHCPv6Config{Pools: []DHCPv6Pool{
+					{Network: "2001:db8:1::/64", RangeStart: "2001:db8:2::100", RangeEnd: "2001:db8:2::200"},
+				}},
+			},
+		},
+	}
+
+	v := NewValidator("test.yaml")
+	result := v.Validate(cfg)
+
+	fields := make(map[string]bool)
+	for _, err := range result.Errors {
+		fields[err.Field] = true
+	}
+	if !fields["devices[0].ip_addresses[0]"] {
+		t.Error("Expected error for multicast device address")
+	}
+	if !fields["devices[0].dhcpv6.pools[0]"] {
+		t.Error("Expected error for pool range outside its network")
+	}
+}
+
 func TestValidate_InvalidThreshold(t *testing.T) {
 	cfg := &Config{
 		Devices: []Device{
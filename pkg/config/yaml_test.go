@@ -4,7 +4,10 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestLoadYAML_Basic tests basic YAML loading functionality
@@ -90,6 +93,7 @@ devices:
       advertise_interval: 45
       ttl: 180
       chassis_id_type: "mac"
+      fast_start: true
 `
 	tmpfile := createTempYAML(t, yaml)
 	defer os.Remove(tmpfile)
@@ -123,6 +127,9 @@ devices:
 	if lldp.ChassisIDType != "mac" {
 		t.Errorf("Expected chassis ID type 'mac', got '%s'", lldp.ChassisIDType)
 	}
+	if !lldp.FastStart {
+		t.Error("Expected LLDP fast start enabled")
+	}
 }
 
 // TestLoadYAML_CDP tests CDP protocol configuration
@@ -481,6 +488,68 @@ devices:
 	}
 }
 
+// TestLoadYAML_InvalidTrapReceiver tests that a malformed trap receiver is
+// rejected at load with an error naming it
+func TestLoadYAML_InvalidTrapReceiver(t *testing.T) {
+	yaml := `
+devices:
+  - name: bad-trap
+    mac: "00:11:22:33:44:55"
+    ip: "192.168.1.1"
+    snmp_agent:
+      traps:
+        enabled: true
+        receivers:
+          - "192.168.1.100:70000"
+`
+	_, err := LoadYAMLBytes([]byte(yaml))
+	if err == nil || !strings.Contains(err.Error(), `invalid trap receiver "192.168.1.100:70000"`) {
+		t.Errorf("Expected invalid trap receiver error, got %v", err)
+	}
+}
+
+// TestParseTrapReceiver tests trap receiver address and port parsing
+func TestParseTrapReceiver(t *testing.T) {
+	tests := []struct {
+		input   string
+		ip      string
+		port    uint16
+		wantErr bool
+	}{
+		{"192.168.1.100", "192.168.1.100", 162, false},
+		{"192.168.1.100:1162", "192.168.1.100", 1162, false},
+		{"192.168.1.100:65535", "192.168.1.100", 65535, false},
+		{"2001:db8::5", "2001:db8::5", 162, false},
+		{"[2001:db8::5]", "2001:db8::5", 162, false},
+		{"[2001:db8::5]:10162", "2001:db8::5", 10162, false},
+		{"192.168.1.100:0", "", 0, true},
+		{"192.168.1.100:70000", "", 0, true},
+		{"192.168.1.100:abc", "", 0, true},
+		{"192.168.1.100:", "", 0, true},
+		{"trap-host:162", "", 0, true},
+		{"invalid-ip", "", 0, true},
+		{"", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ip, port, err := ParseTrapReceiver(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTrapReceiver(%q) = %s, %d; expected error", tt.input, ip, port)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTrapReceiver(%q) failed: %v", tt.input, err)
+			}
+			if !ip.Equal(net.ParseIP(tt.ip)) || port != tt.port {
+				t.Errorf("ParseTrapReceiver(%q) = %s, %d; expected %s, %d", tt.input, ip, port, tt.ip, tt.port)
+			}
+		})
+	}
+}
+
 // TestLoadYAML_EmptyConfig tests handling of empty configuration
 func TestLoadYAML_EmptyConfig(t *testing.T) {
 	yaml := `devices: []`
@@ -555,3 +624,1898 @@ devices:
 		_, _ = LoadYAML(tmpfile)
 	}
 }
+
+// TestLoadYAML_TagsAndFilters tests device tags and tag-based device filters
+func TestLoadYAML_TagsAndFilters(t *testing.T) {
+	yaml := `
+devices:
+  - name: edge1
+    mac: "00:11:22:33:44:01"
+    ip: "10.0.0.1"
+    tags: [edge, " building-a ", edge]
+  - name: edge2
+    mac: "00:11:22:33:44:02"
+    ip: "10.0.0.2"
+    tags: [edge]
+  - name: core1
+    mac: "00:11:22:33:44:03"
+    ip: "10.0.0.3"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+
+	tags := cfg.Devices[0].Tags
+	if len(tags) != 2 || tags[0] != "edge" || tags[1] != "building-a" {
+		t.Errorf("Expected tags [edge building-a], got %v", tags)
+	}
+	if got := len(cfg.DevicesWithTag("edge")); got != 2 {
+		t.Errorf("Expected 2 edge devices, got %d", got)
+	}
+
+	if err := cfg.FilterDevices([]string{"tag:edge", "core1"}, []string{"edge2"}); err != nil {
+		t.Fatalf("FilterDevices failed: %v", err)
+	}
+	if len(cfg.Devices) != 2 || cfg.Devices[0].Name != "edge1" || cfg.Devices[1].Name != "core1" {
+		t.Errorf("Expected [edge1 core1] after filtering, got %d devices", len(cfg.Devices))
+	}
+
+	if err := cfg.FilterDevices([]string{"tag:missing"}, nil); err == nil {
+		t.Error("Expected error for selector matching no devices")
+	}
+}
+
+// TestLoadYAML_SNMPCommunityViews tests parsing per-community MIB views
+func TestLoadYAML_SNMPCommunityViews(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      communities:
+        - name: monitor
+          view:
+            include: [".1.3.6.1.2.1.1"]
+            exclude: ["1.3.6.1.2.1.1.4"]
+        - name: admin
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+
+	communities := cfg.Devices[0].SNMPConfig.Communities
+	if len(communities) != 2 {
+		t.Fatalf("Expected 2 communities, got %d", len(communities))
+	}
+	view := communities[0].View
+	if view == nil || len(view.Included) != 1 || view.Included[0] != "1.3.6.1.2.1.1" || view.Excluded[0] != "1.3.6.1.2.1.1.4" {
+		t.Errorf("Unexpected monitor view: %+v", view)
+	}
+	if communities[1].View != nil {
+		t.Errorf("Expected admin community to have full access, got %+v", communities[1].View)
+	}
+
+	bad := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      communities:
+        - name: monitor
+          view:
+            include: ["1.3.x.1"]
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for invalid view OID")
+	}
+}
+
+func TestLoadYAML_Latency(t *testing.T) {
+	yaml := `
+latency:
+  base_ms: 5
+  jitter_ms: 2
+devices:
+  - name: server
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    latency:
+      base_ms: 40
+      jitter_ms: 10
+      distribution: normal
+  - name: switch
+    mac: "00:11:22:33:44:56"
+    ip: "10.0.0.2"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+
+	server := cfg.LatencyFor(&cfg.Devices[0])
+	if server.Base != 40*time.Millisecond || server.Jitter != 10*time.Millisecond || server.Distribution != LatencyDistributionNormal {
+		t.Errorf("Unexpected server latency: %+v", server)
+	}
+	global := cfg.LatencyFor(&cfg.Devices[1])
+	if global.Base != 5*time.Millisecond || global.Distribution != LatencyDistributionUniform {
+		t.Errorf("Expected switch to inherit the global latency, got %+v", global)
+	}
+	for i := 0; i < 100; i++ {
+		if d := global.Sample(); d < 3*time.Millisecond || d > 7*time.Millisecond {
+			t.Fatalf("Uniform sample %v outside base ± jitter", d)
+		}
+	}
+
+	bad := `
+devices:
+  - name: server
+    mac: "00:11:22:33:44:55"
+    latency:
+      base_ms: 10
+      distribution: pareto
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for unknown latency distribution")
+	}
+}
+
+func TestLoadYAML_SNMPHostResources(t *testing.T) {
+	yaml := `
+devices:
+  - name: web01
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.10"
+    snmp_agent:
+      host_resources:
+        memory_mb: 16384
+        filesystems:
+          - path: /
+            size_gb: 200
+            used_percent: 35
+          - path: /data
+        processes: [nginx, postgres]
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+
+	hr := cfg.Devices[0].SNMPConfig.HostResources
+	if hr == nil {
+		t.Fatal("Expected host resources to be parsed")
+	}
+	if hr.MemoryMB != 16384 || hr.SwapMB != DefaultHostSwapMB || hr.MemoryUsedPercent != DefaultHostMemoryUsedPercent {
+		t.Errorf("Unexpected memory settings: %+v", hr)
+	}
+	if len(hr.Filesystems) != 2 || hr.Filesystems[0].SizeGB != 200 || hr.Filesystems[1].SizeGB != DefaultHostFilesystemSizeGB {
+		t.Errorf("Unexpected filesystems: %+v", hr.Filesystems)
+	}
+	if len(hr.Processes) != 2 || hr.Processes[1] != "postgres" {
+		t.Errorf("Unexpected processes: %v", hr.Processes)
+	}
+
+	bad := `
+devices:
+  - name: web01
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      host_resources:
+        filesystems:
+          - path: /
+            used_percent: 120
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for used_percent above 100")
+	}
+}
+
+func TestLoadYAML_FlowExport(t *testing.T) {
+	yaml := `
+flow_export:
+  collector: "10.0.0.250"
+  sampling_rate: 64
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if cfg.FlowExport == nil {
+		t.Fatal("Expected flow export to be parsed")
+	}
+	if cfg.FlowExport.Collector != "10.0.0.250:6343" || cfg.FlowExport.SamplingRate != 64 || cfg.FlowExport.Protocol != FlowProtocolSFlow {
+		t.Errorf("Unexpected flow export config: %+v", cfg.FlowExport)
+	}
+
+	bad := `
+flow_export:
+  collector: "10.0.0.250:6343"
+  protocol: netflow
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for unsupported netflow protocol")
+	}
+}
+
+// TestLoadYAML_DiscoveryPhaseJitter tests per-protocol discovery phase jitter
+func TestLoadYAML_DiscoveryPhaseJitter(t *testing.T) {
+	yaml := `
+discovery_protocols:
+  lldp:
+    enabled: true
+    phase_jitter: 0.5
+  cdp:
+    enabled: true
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.DiscoveryProtocols.PhaseJitterFor("lldp"); got != 0.5 {
+		t.Errorf("Expected LLDP phase jitter 0.5, got %v", got)
+	}
+	if got := cfg.DiscoveryProtocols.PhaseJitterFor("cdp"); got != 0 {
+		t.Errorf("Expected CDP phase jitter 0, got %v", got)
+	}
+
+	bad := `
+discovery_protocols:
+  fdp:
+    phase_jitter: 1.5
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for phase_jitter above 1")
+	}
+}
+
+// TestLoadYAML_MaxNeighbors tests the learned neighbor table cap
+func TestLoadYAML_MaxNeighbors(t *testing.T) {
+	yaml := `
+discovery_protocols:
+  max_neighbors: 64
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.DiscoveryProtocols.NeighborTableSize(); got != 64 {
+		t.Errorf("Expected neighbor table size 64, got %d", got)
+	}
+
+	var unset *DiscoveryProtocols
+	if got := unset.NeighborTableSize(); got != DefaultMaxNeighbors {
+		t.Errorf("Expected default neighbor table size %d, got %d", DefaultMaxNeighbors, got)
+	}
+
+	bad := `
+discovery_protocols:
+  max_neighbors: -1
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for negative max_neighbors")
+	}
+}
+
+// TestLoadYAML_SNMPAllowedManagers tests parsing of the SNMP manager access list
+func TestLoadYAML_SNMPAllowedManagers(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      allowed_managers:
+        - 10.0.1.0/24
+        - 192.168.5.9
+        - "2001:db8::/64"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	want := []string{"10.0.1.0/24", "192.168.5.9/32", "2001:db8::/64"}
+	got := cfg.Devices[0].SNMPConfig.AllowedManagers
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("AllowedManagers[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	bad := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      allowed_managers: ["not-a-network"]
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for invalid allowed manager")
+	}
+}
+
+// TestLoadYAML_SNMPResponseSourcePort tests the SNMP response source port option
+func TestLoadYAML_SNMPResponseSourcePort(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      response_source_port: Ephemeral
+  - name: switch
+    mac: "00:11:22:33:44:56"
+    ip: "10.0.0.2"
+    snmp_agent:
+      walk_file: ""
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.Devices[0].SNMPConfig.ResponseSourcePort; got != SNMPResponsePortEphemeral {
+		t.Errorf("Expected ephemeral response port, got %q", got)
+	}
+	if got := cfg.Devices[1].SNMPConfig.ResponseSourcePort; got != SNMPResponsePortStandard {
+		t.Errorf("Expected standard response port by default, got %q", got)
+	}
+
+	bad := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      response_source_port: random
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for invalid response_source_port")
+	}
+}
+
+// TestLoadYAML_SNMPMissingInstance tests the SNMP missing_instance option
+func TestLoadYAML_SNMPMissingInstance(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      missing_instance: no_such_object
+  - name: switch
+    mac: "00:11:22:33:44:56"
+    ip: "10.0.0.2"
+    snmp_agent:
+      walk_file: ""
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.Devices[0].SNMPConfig.MissingInstance; got != SNMPMissingInstanceNoSuchObject {
+		t.Errorf("Expected no_such_object, got %q", got)
+	}
+	if got := cfg.Devices[1].SNMPConfig.MissingInstance; got != SNMPMissingInstanceNoSuchInstance {
+		t.Errorf("Expected no_such_instance by default, got %q", got)
+	}
+
+	bad := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      missing_instance: generic_error
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for invalid missing_instance")
+	}
+}
+
+// TestLoadYAML_SNMPDisabledSubtrees tests the SNMP disabled_subtrees option
+func TestLoadYAML_SNMPDisabledSubtrees(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      disabled_subtrees: [".1.3.6.1.2.1.17", "1.3.6.1.4.1.9.9.46"]
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	want := []string{"1.3.6.1.2.1.17", "1.3.6.1.4.1.9.9.46"}
+	if got := cfg.Devices[0].SNMPConfig.DisabledSubtrees; !reflect.DeepEqual(got, want) {
+		t.Errorf("DisabledSubtrees = %v, want %v", got, want)
+	}
+
+	bad := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      disabled_subtrees: ["bridge-mib"]
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for invalid disabled_subtrees OID")
+	}
+}
+
+// TestLoadYAML_SNMPAdminStatusSet tests the SNMP admin_status_set option
+func TestLoadYAML_SNMPAdminStatusSet(t *testing.T) {
+	yaml := `
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      admin_status_set: link_state
+  - name: router
+    mac: "00:11:22:33:44:56"
+    ip: "10.0.0.2"
+    snmp_agent:
+      walk_file: ""
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.Devices[0].SNMPConfig.AdminStatusSet; got != SNMPAdminStatusSetLinkState {
+		t.Errorf("Expected link_state, got %q", got)
+	}
+	if got := cfg.Devices[1].SNMPConfig.AdminStatusSet; got != SNMPAdminStatusSetReadOnly {
+		t.Errorf("Expected read_only by default, got %q", got)
+	}
+
+	bad := `
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      admin_status_set: writable
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for invalid admin_status_set")
+	}
+}
+
+// TestLoadYAML_SNMPWritable tests the SNMP writable list
+func TestLoadYAML_SNMPWritable(t *testing.T) {
+	yaml := `
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      admin_status_set: link_state
+      writable: [syscontact, sysLocation, ifAdminStatus, sysContact]
+  - name: locked
+    mac: "00:11:22:33:44:56"
+    snmp_agent:
+      admin_status_set: link_state
+      writable: []
+  - name: router
+    mac: "00:11:22:33:44:57"
+    snmp_agent:
+      admin_status_set: link_state
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	snmp := cfg.Devices[0].SNMPConfig
+	if len(snmp.Writable) != 3 || !snmp.IsWritable(SNMPWritableSysContact) || !snmp.IsWritable(SNMPWritableIfAdminStatus) || snmp.IsWritable(SNMPWritableSysName) {
+		t.Errorf("Expected sysContact, sysLocation and ifAdminStatus writable, got %v", snmp.Writable)
+	}
+	locked := cfg.Devices[1].SNMPConfig
+	if locked.Writable == nil || locked.IsWritable(SNMPWritableIfAdminStatus) {
+		t.Errorf("Expected an empty writable list to make the device read-only, got %#v", locked.Writable)
+	}
+	router := cfg.Devices[2].SNMPConfig
+	if router.Writable != nil || !router.IsWritable(SNMPWritableIfAdminStatus) || router.IsWritable(SNMPWritableSysName) {
+		t.Errorf("Expected only ifAdminStatus writable by default, got %#v", router.Writable)
+	}
+
+	for _, bad := range []string{
+		"writable: [sysDescr]",
+		"writable: [ifAdminStatus]", // admin_status_set is read_only
+	} {
+		yaml := `
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      ` + bad + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+// TestLoadYAML_SNMPQuirks tests the snmp_quirks list
+func TestLoadYAML_SNMPQuirks(t *testing.T) {
+	yaml := `
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      walk_file: ""
+    snmp_quirks: [Trailing_Null, no_endofmibview, trailing_null]
+  - name: router
+    mac: "00:11:22:33:44:56"
+    ip: "10.0.0.2"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	snmp := cfg.Devices[0].SNMPConfig
+	if len(snmp.Quirks) != 2 || !snmp.HasQuirk(SNMPQuirkTrailingNull) || !snmp.HasQuirk(SNMPQuirkNoEndOfMibView) {
+		t.Errorf("Expected trailing_null and no_endofmibview once each, got %v", snmp.Quirks)
+	}
+	if snmp.HasQuirk(SNMPQuirkTruncateLongStrings) {
+		t.Error("Expected truncate_long_strings to be off")
+	}
+	if got := cfg.Devices[1].SNMPConfig.Quirks; len(got) != 0 {
+		t.Errorf("Expected no quirks by default, got %v", got)
+	}
+
+	bad := `
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+    snmp_quirks: [reverse_oids]
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for unknown snmp_quirks entry")
+	}
+}
+
+// TestLoadYAML_WalkLimits tests the global walk_limits block
+func TestLoadYAML_WalkLimits(t *testing.T) {
+	yaml := `
+walk_limits:
+  max_file_size_mb: 16
+  max_oids: 50000
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if cfg.WalkLimits == nil || cfg.WalkLimits.MaxFileSize != 16<<20 || cfg.WalkLimits.MaxOIDs != 50000 {
+		t.Errorf("Expected 16 MiB and 50000 OIDs, got %+v", cfg.WalkLimits)
+	}
+
+	bad := `
+walk_limits:
+  max_oids: -1
+devices:
+  - name: switch
+    mac: "00:11:22:33:44:55"
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for negative max_oids")
+	}
+}
+
+// TestLoadYAML_ICMPReplyOffsubnet tests the icmp reply_offsubnet toggle
+func TestLoadYAML_ICMPReplyOffsubnet(t *testing.T) {
+	yaml := `
+devices:
+  - name: isolated
+    mac: "00:11:22:33:44:55"
+    ips: ["10.0.0.1", "2001:db8::1"]
+    icmp:
+      reply_offsubnet: false
+      subnet_mask: "255.255.255.0"
+  - name: dhcp-server
+    mac: "00:11:22:33:44:56"
+    ip: "172.16.5.1"
+    dhcp:
+      subnet_mask: "255.255.0.0"
+    icmp:
+      reply_offsubnet: false
+  - name: router
+    mac: "00:11:22:33:44:57"
+    ip: "10.0.0.2"
+    icmp:
+      enabled: true
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	isolated := cfg.Devices[0].ICMPConfig
+	if !isolated.DropOffSubnet || len(isolated.LocalSubnets) != 1 || isolated.LocalSubnets[0].String() != "10.0.0.0/24" {
+		t.Errorf("Expected only 10.0.0.0/24 to be answered, got %+v", isolated)
+	}
+	if !isolated.AnswersSource(net.ParseIP("10.0.0.200")) || isolated.AnswersSource(net.ParseIP("10.0.1.1")) {
+		t.Error("Expected on-subnet sources answered and off-subnet sources dropped")
+	}
+	if subnets := cfg.Devices[1].ICMPConfig.LocalSubnets; len(subnets) != 1 || subnets[0].String() != "172.16.0.0/16" {
+		t.Errorf("Expected the DHCP subnet_mask to apply, got %v", subnets)
+	}
+	if cfg.Devices[2].ICMPConfig.DropOffSubnet {
+		t.Error("Expected reply_offsubnet on by default")
+	}
+
+	for _, icmp := range []string{
+		"{reply_offsubnet: false}",
+		"{reply_offsubnet: false, subnet_mask: 255.0.255.0}",
+		"{subnet_mask: 255.255.255.0}",
+	} {
+		bad := `
+devices:
+  - name: isolated
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    icmp: ` + icmp + `
+`
+		if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+			t.Errorf("Expected error for icmp %s", icmp)
+		}
+	}
+}
+
+// TestLoadYAML_InterfaceScope tests interface_scope parsing
+func TestLoadYAML_InterfaceScope(t *testing.T) {
+	yaml := `
+interface_scope:
+  eth0: ["10.0.0.0/24"]
+  eth1: ["10.0.1.0/24", "10.0.2.0/24"]
+devices:
+  - name: sw0
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	device := &cfg.Devices[0]
+	if !cfg.InterfaceScope.Reaches("eth0", device) || cfg.InterfaceScope.Reaches("eth1", device) {
+		t.Error("Expected sw0 to be reachable on eth0 only")
+	}
+	if !cfg.InterfaceScope.Reaches("eth2", device) {
+		t.Error("Expected an unscoped interface to reach every device")
+	}
+
+	for _, bad := range []string{"eth0: []", "eth0: [\"10.0.0.0/33\"]"} {
+		yaml := "interface_scope:\n  " + bad + "\ndevices:\n  - name: sw0\n    mac: \"00:11:22:33:44:55\"\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for interface_scope %q", bad)
+		}
+	}
+}
+
+// TestLoadYAML_Interfaces tests device interface parsing
+func TestLoadYAML_Interfaces(t *testing.T) {
+	yaml := `
+devices:
+  - name: access-sw1
+    mac: "00:11:22:33:44:55"
+    interfaces:
+      - name: GigabitEthernet0/1
+        description: "Uplink to core1"
+      - name: GigabitEthernet0/2
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	ifaces := cfg.Devices[0].Interfaces
+	if len(ifaces) != 2 || ifaces[0].Name != "GigabitEthernet0/1" || ifaces[0].Description != "Uplink to core1" || ifaces[1].Description != "" {
+		t.Errorf("Unexpected interfaces: %+v", ifaces)
+	}
+
+	for _, bad := range []string{
+		"- description: no name",
+		"- name: Gi0/1\n      - name: Gi0/1",
+		"- name: Gi0/1\n        description: \"" + strings.Repeat("x", MaxInterfaceDescription+1) + "\"",
+	} {
+		yaml := `
+devices:
+  - name: access-sw1
+    mac: "00:11:22:33:44:55"
+    interfaces:
+      ` + bad + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for interfaces %q", bad)
+		}
+	}
+}
+
+// TestLoadYAML_DHCPClientClasses tests DHCP client class parsing
+func TestLoadYAML_DHCPClientClasses(t *testing.T) {
+	yaml := `
+devices:
+  - name: dhcp-server
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    dhcp:
+      pool_start: "10.0.0.100"
+      pool_end: "10.0.0.200"
+      client_classes:
+        - name: pxe
+          vendor_class: "PXEClient"
+          bootfile_name: undionly.kpxe
+          next_server_ip: "10.0.0.5"
+          options:
+            150: "0x0a000005"
+        - name: ipxe
+          user_class: "^iPXE$"
+          regex: true
+          bootfile_name: http://10.0.0.5/boot.ipxe
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	classes := cfg.Devices[0].DHCPConfig.ClientClasses
+	if len(classes) != 2 {
+		t.Fatalf("Expected 2 client classes, got %d", len(classes))
+	}
+	pxe := classes[0]
+	if !pxe.Matches("PXEClient:Arch:00000", nil) || pxe.Matches("MSFT 5.0", nil) {
+		t.Error("Expected pxe to match vendor classes containing PXEClient only")
+	}
+	if !pxe.NextServerIP.Equal(net.ParseIP("10.0.0.5")) || string(pxe.Options[150]) != "\x0a\x00\x00\x05" {
+		t.Errorf("Unexpected pxe class: next server %s, option 150 %x", pxe.NextServerIP, pxe.Options[150])
+	}
+	if ipxe := classes[1]; !ipxe.Matches("", []string{"lab", "iPXE"}) || ipxe.Matches("", []string{"iPXE-legacy"}) {
+		t.Error("Expected ipxe to match a user class of iPXE exactly")
+	}
+
+	for _, bad := range []string{
+		"- name: none\n          bootfile_name: x",
+		"- name: badre\n          vendor_class: \"(\"\n          regex: true",
+		"- name: badopt\n          vendor_class: PXE\n          options: {53: \"x\"}",
+		"- name: badhex\n          vendor_class: PXE\n          options: {150: \"0xzz\"}",
+	} {
+		yaml := `
+devices:
+  - name: dhcp-server
+    mac: "00:11:22:33:44:55"
+    dhcp:
+      client_classes:
+        ` + bad + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for client class %q", bad)
+		}
+	}
+}
+
+// TestLoadYAML_LeaseGrace tests DHCP and DHCPv6 lease_grace parsing
+func TestLoadYAML_LeaseGrace(t *testing.T) {
+	yaml := `
+devices:
+  - name: dhcp-server
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    dhcp:
+      pool_start: "10.0.0.100"
+      pool_end: "10.0.0.200"
+      lease_grace: 300
+    dhcpv6:
+      lease_grace: 600
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	device := cfg.Devices[0]
+	if device.DHCPConfig.LeaseGrace != 5*time.Minute {
+		t.Errorf("Expected DHCP lease grace 5m, got %s", device.DHCPConfig.LeaseGrace)
+	}
+	if device.DHCPv6Config == nil || device.DHCPv6Config.LeaseGrace != 10*time.Minute {
+		t.Errorf("Expected DHCPv6 lease grace 10m, got %+v", device.DHCPv6Config)
+	}
+
+	for _, bad := range []string{"dhcp", "dhcpv6"} {
+		yaml := `
+devices:
+  - name: dhcp-server
+    mac: "00:11:22:33:44:55"
+    ` + bad + `:
+      lease_grace: -1
+`
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for negative %s lease_grace", bad)
+		}
+	}
+}
+
+// TestLoadYAML_ICMPv6RouteInfo tests parsing of RA Route Information Options
+func TestLoadYAML_ICMPv6RouteInfo(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    type: router
+    mac: "00:11:22:33:44:55"
+    ip: "2001:db8::1"
+    icmpv6:
+      route_info:
+        - prefix: "2001:db8:100::/48"
+          preference: high
+          lifetime: 900
+        - prefix: "::/0"
+          lifetime: 0
+        - prefix: "2001:db8:200::/64"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	routes := cfg.Devices[0].ICMPv6Config.RouteInfo
+	if len(routes) != 3 {
+		t.Fatalf("Expected 3 routes, got %d", len(routes))
+	}
+	if routes[0].Prefix.String() != "2001:db8:100::/48" || routes[0].Preference != RoutePreferenceHigh || routes[0].Lifetime != 900 {
+		t.Errorf("Unexpected first route: %s %s %d", routes[0].Prefix, routes[0].Preference, routes[0].Lifetime)
+	}
+	if routes[1].Lifetime != 0 {
+		t.Errorf("Expected explicit lifetime 0 to be kept, got %d", routes[1].Lifetime)
+	}
+	if routes[2].Preference != RoutePreferenceMedium || routes[2].Lifetime != DefaultRouteInfoLifetime {
+		t.Errorf("Expected medium preference and default lifetime, got %s %d", routes[2].Preference, routes[2].Lifetime)
+	}
+
+	for _, bad := range []string{
+		`{prefix: "10.0.0.0/8"}`,
+		`{prefix: "2001:db8::"}`,
+		`{prefix: "2001:db8::/32", preference: highest}`,
+	} {
+		yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    icmpv6:
+      route_info: [` + bad + `]
+`
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for route_info %s", bad)
+		}
+	}
+}
+
+// TestLoadYAML_SNMPMaxMessageSize tests parsing of the agent's message size limit
+func TestLoadYAML_SNMPMaxMessageSize(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      max_message_size: 1472
+  - name: switch
+    mac: "00:11:22:33:44:56"
+    snmp_agent:
+      community: public
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.Devices[0].SNMPConfig.MaxMessageSize; got != 1472 {
+		t.Errorf("Expected max_message_size 1472, got %d", got)
+	}
+	if got := cfg.Devices[1].SNMPConfig.MaxMessageSize; got != DefaultSNMPMaxMessageSize {
+		t.Errorf("Expected default max_message_size %d, got %d", DefaultSNMPMaxMessageSize, got)
+	}
+
+	for _, size := range []string{"100", "70000"} {
+		bad := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      max_message_size: ` + size + `
+`
+		if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+			t.Errorf("Expected error for max_message_size %s", size)
+		}
+	}
+}
+
+// TestLoadYAML_SNMPTrapVarbinds tests parsing of extra trap varbinds
+func TestLoadYAML_SNMPTrapVarbinds(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      traps:
+        enabled: true
+        receivers: ["10.0.0.100:162"]
+        trap_varbinds:
+          - oid: 1.3.6.1.4.1.9.2.2.1.1.20
+            value: "{{.IfDescr}} down"
+            traps: [linkDown]
+          - oid: .1.3.6.1.4.1.9.9.109.1.1.1.1.7
+            type: Gauge32
+            value: "{{.Value}}"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	varbinds := cfg.Devices[0].SNMPConfig.Traps.Varbinds
+	if len(varbinds) != 2 {
+		t.Fatalf("Expected 2 trap varbinds, got %d", len(varbinds))
+	}
+	if varbinds[0].OID != ".1.3.6.1.4.1.9.2.2.1.1.20" || varbinds[0].Type != TrapVarbindString {
+		t.Errorf("Unexpected first varbind: %+v", varbinds[0])
+	}
+	if !varbinds[0].AppliesTo("linkDown") || varbinds[0].AppliesTo("highCPU") {
+		t.Error("Expected first varbind to apply only to linkDown")
+	}
+	if varbinds[1].Type != TrapVarbindGauge32 || !varbinds[1].AppliesTo("highCPU") {
+		t.Errorf("Unexpected second varbind: %+v", varbinds[1])
+	}
+
+	for _, bad := range []string{
+		`{oid: "1.3.6.1.4.1.9.1", type: float, value: "1"}`,
+		`{oid: "1.3.6.1.4.1.9.1", value: "{{.IfIndex"}`,
+		`{oid: "1.3.6.1.4.1.9.1", value: "x", traps: [linkFlap]}`,
+	} {
+		badYAML := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      traps:
+        enabled: true
+        receivers: ["10.0.0.100:162"]
+        trap_varbinds: [` + bad + `]
+`
+		if _, err := LoadYAMLBytes([]byte(badYAML)); err == nil {
+			t.Errorf("Expected error for trap varbind %s", bad)
+		}
+	}
+}
+
+func TestLoadYAML_TrafficSchedule(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    traffic:
+      enabled: true
+      random_traffic:
+        enabled: true
+        schedule:
+          windows: ["22:00-06:00"]
+          days: [fri]
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	schedule := cfg.Devices[0].TrafficConfig.RandomTraffic.Schedule
+	if got := schedule.String(); got != "fri 22:00-06:00" {
+		t.Errorf("Expected schedule \"fri 22:00-06:00\", got %q", got)
+	}
+
+	// 2024-03-01 is a Friday
+	cases := []struct {
+		when   time.Time
+		active bool
+	}{
+		{time.Date(2024, 3, 1, 23, 0, 0, 0, time.Local), true},
+		{time.Date(2024, 3, 2, 5, 59, 0, 0, time.Local), true}, // Friday night's window runs into Saturday
+		{time.Date(2024, 3, 2, 6, 0, 0, 0, time.Local), false},
+		{time.Date(2024, 3, 1, 3, 0, 0, 0, time.Local), false}, // Belongs to Thursday night
+		{time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local), false},
+	}
+	for _, tc := range cases {
+		if got := schedule.Active(tc.when); got != tc.active {
+			t.Errorf("Active(%s) = %v, want %v", tc.when.Format("Mon 15:04"), got, tc.active)
+		}
+	}
+
+	var always *TrafficSchedule
+	if !always.Active(time.Now()) {
+		t.Error("Expected nil schedule to always be active")
+	}
+
+	for _, bad := range []string{`windows: ["8-18"]`, `windows: ["08:00-08:00"]`, `windows: ["08:00-25:00"]`, `days: [funday]`} {
+		yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    traffic:
+      arp_announcements:
+        schedule:
+          ` + bad + `
+`
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for schedule %s", bad)
+		}
+	}
+}
+
+func TestLoadYAML_TrapRateLimit(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      traps:
+        enabled: true
+        receivers: ["10.0.0.100:162"]
+        rate_limit:
+          max_traps: 5
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	limit := cfg.Devices[0].SNMPConfig.Traps.RateLimit
+	if limit == nil || limit.MaxTraps != 5 || limit.Window != DefaultTrapRateLimitWindow*time.Second {
+		t.Fatalf("unexpected rate limit: %+v", limit)
+	}
+
+	bad := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      traps:
+        enabled: true
+        receivers: ["10.0.0.100:162"]
+        rate_limit:
+          window: 30
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for rate_limit without max_traps")
+	}
+}
+
+func TestLoadYAML_DeviceMTU(t *testing.T) {
+	yaml := `
+devices:
+  - name: jumbo
+    mac: "00:11:22:33:44:01"
+    jumbo: true
+  - name: custom
+    mac: "00:11:22:33:44:02"
+    mtu: 9216
+  - name: standard
+    mac: "00:11:22:33:44:03"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	for i, want := range []int{JumboMTU, 9216, DefaultMTU} {
+		if got := cfg.Devices[i].LinkMTU(); got != want {
+			t.Errorf("device %s: LinkMTU() = %d, want %d", cfg.Devices[i].Name, got, want)
+		}
+	}
+	if got := cfg.Devices[0].MaxFrameSize(); got != 9018 {
+		t.Errorf("MaxFrameSize() = %d, want 9018", got)
+	}
+
+	for _, bad := range []string{"mtu: 100", "mtu: 10000", "jumbo: true\n    mtu: 1500"} {
+		yaml := "devices:\n  - name: bad\n    mac: \"00:11:22:33:44:55\"\n    " + bad + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadYAML_DeviceIPTTL(t *testing.T) {
+	yaml := `
+devices:
+  - name: windows
+    mac: "00:11:22:33:44:01"
+    ip_ttl: 128
+    ipv6_hop_limit: 128
+    icmp:
+      enabled: true
+  - name: router
+    mac: "00:11:22:33:44:02"
+    ip_ttl: 255
+    icmp:
+      enabled: true
+      ttl: 32
+  - name: standard
+    mac: "00:11:22:33:44:03"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	windows, router, standard := cfg.Devices[0], cfg.Devices[1], cfg.Devices[2]
+	if windows.TTL() != 128 || windows.IPv6HopLimit() != 128 {
+		t.Errorf("windows TTL/hop limit = %d/%d, want 128/128", windows.TTL(), windows.IPv6HopLimit())
+	}
+	// An unset icmp.ttl follows ip_ttl; a set one wins
+	if windows.ICMPConfig.TTL != 128 {
+		t.Errorf("windows icmp.ttl = %d, want 128 from ip_ttl", windows.ICMPConfig.TTL)
+	}
+	if router.TTL() != 255 || router.ICMPConfig.TTL != 32 {
+		t.Errorf("router TTL = %d, icmp.ttl = %d, want 255 and 32", router.TTL(), router.ICMPConfig.TTL)
+	}
+	if standard.TTL() != DefaultIPTTL || standard.IPv6HopLimit() != DefaultIPv6HopLimit {
+		t.Errorf("standard TTL/hop limit = %d/%d, want defaults", standard.TTL(), standard.IPv6HopLimit())
+	}
+
+	for _, bad := range []string{"ip_ttl: 256", "ip_ttl: -1", "ipv6_hop_limit: 300"} {
+		yaml := "devices:\n  - name: bad\n    mac: \"00:11:22:33:44:55\"\n    " + bad + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoadYAML_IPv6Validation(t *testing.T) {
+	valid := `
+devices:
+  - name: server
+    mac: "00:11:22:33:44:01"
+    ips: ["2001:db8:1::1"]
+    dhcpv6:
+      enabled: true
+      pools:
+        - network: "2001:db8:1::/64"
+          range_start: "2001:db8:1::100"
+          range_end: "2001:db8:1::200"
+      delegated_prefixes: ["2001:db8:100::/56"]
+`
+	cfg, err := LoadYAMLBytes([]byte(valid))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if pools := cfg.Devices[0].DHCPv6Config.Pools; len(pools) != 1 || pools[0].RangeEnd != "2001:db8:1::200" {
+		t.Errorf("Pools = %+v, want the configured pool", pools)
+	}
+	if prefixes := cfg.Devices[0].DHCPv6Config.DelegatedPrefixes; len(prefixes) != 1 || prefixes[0].String() != "2001:db8:100::/56" {
+		t.Errorf("DelegatedPrefixes = %v, want [2001:db8:100::/56]", prefixes)
+	}
+
+	bad := map[string]string{
+		"multicast ip":          `ips: ["ff02::1"]`,
+		"multicast ipv4":        `ip: "224.0.0.5"`,
+		"unspecified ip":        `ips: ["::"]`,
+		"range outside network": "dhcpv6:\n      pools:\n        - network: \"2001:db8:1::/64\"\n          range_start: \"2001:db8:1::100\"\n          range_end: \"2001:db8:2::200\"",
+		"range reversed":        "dhcpv6:\n      pools:\n        - network: \"2001:db8:1::/64\"\n          range_start: \"2001:db8:1::200\"\n          range_end: \"2001:db8:1::100\"",
+		"malformed prefix":      "dhcpv6:\n      pools:\n        - network: \"2001:db8:1::/129\"",
+		"ipv4 prefix":           "dhcpv6:\n      pools:\n        - network: \"10.0.0.0/24\"",
+		"half a range":          "dhcpv6:\n      pools:\n        - network: \"2001:db8:1::/64\"\n          range_start: \"2001:db8:1::100\"",
+		"ipv4 delegated prefix": "dhcpv6:\n      delegated_prefixes: [\"10.0.0.0/24\"]",
+	}
+	for name, snippet := range bad {
+		yaml := "devices:\n  - name: bad\n    mac: \"00:11:22:33:44:55\"\n    " + snippet + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadYAML_BootDelay(t *testing.T) {
+	yaml := `
+devices:
+  - name: slow
+    mac: "00:11:22:33:44:01"
+    boot_delay: 45
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.Devices[0].BootDelay; got != 45*time.Second {
+		t.Errorf("BootDelay = %v, want 45s", got)
+	}
+
+	yaml = "devices:\n  - name: bad\n    mac: \"00:11:22:33:44:55\"\n    boot_delay: -1\n"
+	if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+		t.Error("Expected error for negative boot_delay")
+	}
+}
+
+func TestLoadYAML_BootSequence(t *testing.T) {
+	yaml := `
+devices:
+  - name: staged
+    mac: "00:11:22:33:44:01"
+    boot_sequence:
+      link_up: 5
+      cold_start: 10
+      discovery: 2
+      snmp: 30
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	want := &BootSequence{LinkUp: 5 * time.Second, ColdStart: 10 * time.Second, Discovery: 2 * time.Second, SNMP: 30 * time.Second}
+	if got := cfg.Devices[0].BootSequence; !reflect.DeepEqual(got, want) {
+		t.Errorf("BootSequence = %+v, want %+v", got, want)
+	}
+
+	for name, extra := range map[string]string{
+		"negative":   "    boot_sequence:\n      snmp: -1\n",
+		"boot_delay": "    boot_delay: 10\n    boot_sequence:\n      link_up: 5\n",
+	} {
+		yaml := "devices:\n  - name: bad\n    mac: \"00:11:22:33:44:55\"\n" + extra
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadYAML_TCPPorts(t *testing.T) {
+	yaml := `
+devices:
+  - name: server
+    mac: "00:11:22:33:44:01"
+    tcp_ports:
+      22: open
+      23: Closed
+      445: filtered
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	want := map[uint16]string{22: TCPPortOpen, 23: TCPPortClosed, 445: TCPPortFiltered}
+	if got := cfg.Devices[0].TCPPorts; !reflect.DeepEqual(got, want) {
+		t.Errorf("TCPPorts = %v, want %v", got, want)
+	}
+
+	for name, ports := range map[string]string{
+		"invalid state": "22: listening",
+		"port zero":     "0: open",
+		"port too high": "70000: open",
+	} {
+		yaml = "devices:\n  - name: bad\n    mac: \"00:11:22:33:44:55\"\n    tcp_ports:\n      " + ports + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("%s: expected error for tcp_ports %q", name, ports)
+		}
+	}
+}
+
+func TestLoadYAML_RunMarker(t *testing.T) {
+	yaml := `
+run_marker:
+  run_id: lab-a
+  dscp: 10
+devices:
+  - name: router
+    mac: "00:11:22:33:44:01"
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if cfg.RunMarker == nil || cfg.RunMarker.RunID != "lab-a" || cfg.RunMarker.DSCP != 10 {
+		t.Fatalf("RunMarker = %+v, want run_id lab-a, dscp 10", cfg.RunMarker)
+	}
+	trailer := cfg.RunMarker.Trailer()
+	if len(trailer) != 8 || !strings.HasPrefix(string(trailer), RunMarkerPrefix) {
+		t.Errorf("Trailer() = %x, want %q and 4 digest bytes", trailer, RunMarkerPrefix)
+	}
+	if !reflect.DeepEqual(trailer, RunMarkerTrailer("lab-a")) || reflect.DeepEqual(trailer, RunMarkerTrailer("lab-b")) {
+		t.Error("Expected the trailer to be derived deterministically from the run ID")
+	}
+
+	yaml = "run_marker:\n  dscp: 64\ndevices:\n  - name: r\n    mac: \"00:11:22:33:44:55\"\n"
+	if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+		t.Error("Expected error for dscp 64")
+	}
+}
+
+func TestLoadYAML_DHCPRetransmitWindow(t *testing.T) {
+	yamlConfig := `
+devices:
+  - name: dhcp-server
+    mac: "00:11:22:33:44:55"
+    ips: ["192.168.1.1"]
+    dhcp:
+      pool_start: "192.168.1.100"
+      pool_end: "192.168.1.200"
+      retransmit_window: 30
+  - name: dhcp-default
+    mac: "00:11:22:33:44:56"
+    ips: ["192.168.2.1"]
+    dhcp:
+      pool_start: "192.168.2.100"
+      pool_end: "192.168.2.200"
+`
+	cfg, err := LoadYAMLBytes([]byte(yamlConfig))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if got := cfg.Devices[0].DHCPConfig.RetransmitWindow; got != 30*time.Second {
+		t.Errorf("RetransmitWindow = %v, want 30s", got)
+	}
+	if got := cfg.Devices[1].DHCPConfig.RetransmitWindow; got != DefaultDHCPRetransmitWindow*time.Second {
+		t.Errorf("RetransmitWindow = %v, want the %ds default", got, DefaultDHCPRetransmitWindow)
+	}
+
+	negative := `
+devices:
+  - name: dhcp-server
+    mac: "00:11:22:33:44:55"
+    dhcp:
+      retransmit_window: -1
+`
+	if _, err := LoadYAMLBytes([]byte(negative)); err == nil {
+		t.Error("Expected error for a negative retransmit_window")
+	}
+}
+
+func TestLoadYAML_WalkSeries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"t2.walk", "t1.walk", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(".1.3.6.1.2.1.1.5.0 = STRING: \"r1\"\n"), 0o600); err != nil {
+			t.Fatalf("write walk file: %v", err)
+		}
+	}
+
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      walk_series:
+        directory: "` + dir + `"
+        loop: true
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	series := cfg.Devices[0].SNMPConfig.WalkSeries
+	if series == nil {
+		t.Fatal("expected walk series")
+	}
+	want := []string{filepath.Join(dir, "t1.walk"), filepath.Join(dir, "t2.walk")}
+	if !reflect.DeepEqual(series.Files, want) {
+		t.Errorf("Files = %v, want %v", series.Files, want)
+	}
+	if series.Interval != DefaultWalkSeriesInterval*time.Second || !series.Loop {
+		t.Errorf("unexpected series settings: %+v", series)
+	}
+
+	empty := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      walk_series:
+        directory: "` + t.TempDir() + `"
+`
+	if _, err := LoadYAMLBytes([]byte(empty)); err == nil {
+		t.Error("Expected error for a walk_series directory without walk files")
+	}
+}
+
+func TestLoadYAML_WalkDirectory(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "walks")
+	if err := os.Mkdir(dir, 0o750); err != nil {
+		t.Fatalf("create walk directory: %v", err)
+	}
+	for _, name := range []string{"b.walk", "a.WALK", "readme.txt", ".hidden.walk"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(".1.3.6.1.2.1.1.5.0 = STRING: \"r1\"\n"), 0o600); err != nil {
+			t.Fatalf("write walk file: %v", err)
+		}
+	}
+
+	load := func(walkDir string) (*Config, error) {
+		return LoadYAMLBytes([]byte(`
+include_path: "` + base + `"
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      walk_directory: "` + walkDir + `"
+`))
+	}
+	cfg, err := load("walks")
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.WALK"), filepath.Join(dir, "b.walk")}
+	if got := cfg.Devices[0].SNMPConfig.WalkFiles; !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkFiles = %v, want %v", got, want)
+	}
+
+	if _, err := load("../walks"); err == nil || !strings.Contains(err.Error(), "path traversal") {
+		t.Errorf("Expected path traversal error, got %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(base, "empty"), 0o750); err != nil {
+		t.Fatalf("create empty directory: %v", err)
+	}
+	if _, err := load("empty"); err == nil {
+		t.Error("Expected error for a walk_directory without .walk files")
+	}
+}
+
+func TestLoadYAML_Bridge(t *testing.T) {
+	yaml := `
+devices:
+  - name: sw1
+    mac: "00:11:22:33:44:55"
+    bridge: {}
+  - name: sw2
+    mac: "00:11:22:33:44:56"
+    bridge:
+      aging_time: 60
+      mac_table_size: 512
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	for i, want := range []time.Duration{DefaultBridgeAgingTime * time.Second, 60 * time.Second} {
+		bridge := cfg.Devices[i].BridgeConfig
+		if bridge == nil || bridge.AgingTime != want {
+			t.Errorf("device %s: bridge = %+v, want aging time %v", cfg.Devices[i].Name, bridge, want)
+		}
+	}
+	for i, want := range []int{DefaultBridgeMacTableSize, 512} {
+		if bridge := cfg.Devices[i].BridgeConfig; bridge == nil || bridge.MacTableSize != want {
+			t.Errorf("device %s: bridge = %+v, want mac table size %d", cfg.Devices[i].Name, bridge, want)
+		}
+	}
+
+	bad := `
+devices:
+  - name: sw1
+    mac: "00:11:22:33:44:55"
+    bridge:
+      aging_time: 5
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for aging_time below 10 seconds")
+	}
+
+	negative := `
+devices:
+  - name: sw1
+    mac: "00:11:22:33:44:55"
+    bridge:
+      mac_table_size: -1
+`
+	if _, err := LoadYAMLBytes([]byte(negative)); err == nil {
+		t.Error("Expected error for a negative mac_table_size")
+	}
+}
+
+func TestLoadYAML_SNMPContexts(t *testing.T) {
+	yaml := `
+devices:
+  - name: chassis
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      contexts:
+        - name: vdc1
+          sys_name: core-vdc1
+        - name: vdc2
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	want := []SNMPContext{{Name: "vdc1", SysName: "core-vdc1"}, {Name: "vdc2", SysName: "chassis-vdc2"}}
+	if got := cfg.Devices[0].SNMPConfig.Contexts; !reflect.DeepEqual(got, want) {
+		t.Errorf("contexts = %+v, want %+v", got, want)
+	}
+
+	bad := `
+devices:
+  - name: chassis
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      contexts:
+        - name: vdc1
+        - name: vdc1
+`
+	if _, err := LoadYAMLBytes([]byte(bad)); err == nil {
+		t.Error("Expected error for duplicate context name")
+	}
+}
+
+// TestLoadYAML_PoE tests PoE defaults and the class power limit
+func TestLoadYAML_PoE(t *testing.T) {
+	cfg, err := LoadYAMLBytes([]byte(`
+devices:
+  - name: phone-1
+    mac: "00:11:22:33:44:55"
+    poe:
+      class: 2
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	poe := cfg.Devices[0].PoE
+	if poe == nil || poe.RequestedWatts != 6.49 || poe.Priority != PoEPriorityLow {
+		t.Errorf("Unexpected PoE config %+v", poe)
+	}
+
+	_, err = LoadYAMLBytes([]byte(`
+devices:
+  - name: phone-1
+    mac: "00:11:22:33:44:55"
+    poe:
+      class: 2
+      requested_watts: 15
+`))
+	if err == nil || !strings.Contains(err.Error(), "requested_watts") {
+		t.Errorf("Expected a requested_watts range error, got %v", err)
+	}
+}
+
+// TestLoadYAML_SNMPSlowWalk tests parsing the overloaded-agent walk model
+// and its defaults
+func TestLoadYAML_SNMPSlowWalk(t *testing.T) {
+	cfg, err := LoadYAMLBytes([]byte(`
+devices:
+  - name: busy-router
+    mac: "00:11:22:33:44:55"
+    ip: "10.0.0.1"
+    snmp_agent:
+      slow_walk:
+        step_ms: 250
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	slow := cfg.Devices[0].SNMPConfig.SlowWalk
+	if slow == nil || slow.Step != 250*time.Millisecond || slow.Max != DefaultSNMPSlowWalkMax || slow.WalkGap != DefaultSNMPSlowWalkWalkGap {
+		t.Errorf("Unexpected slow walk %+v", slow)
+	}
+
+	_, err = LoadYAMLBytes([]byte(`
+devices:
+  - name: busy-router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      slow_walk:
+        max_ms: 60000
+`))
+	if err == nil || !strings.Contains(err.Error(), "max_ms") {
+		t.Errorf("Expected a max_ms range error, got %v", err)
+	}
+}
+
+// TestLoadYAML_SNMPv3User tests parsing and validation of the SNMPv3 USM user
+func TestLoadYAML_SNMPv3User(t *testing.T) {
+	yaml := `
+devices:
+  - name: firewall
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      v3:
+        user: auditor
+        auth_protocol: SHA
+        auth_key: auth-secret
+        priv_protocol: AES
+        priv_key: priv-secret
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	want := &SNMPv3Config{User: "auditor", AuthProtocol: SNMPv3AuthSHA, AuthKey: "auth-secret", PrivProtocol: SNMPv3PrivAES, PrivKey: "priv-secret"}
+	if got := cfg.Devices[0].SNMPConfig.V3; !reflect.DeepEqual(got, want) {
+		t.Errorf("V3 = %+v, want %+v", got, want)
+	}
+
+	bad := map[string]string{
+		"missing user":     "auth_protocol: md5\n        auth_key: auth-secret",
+		"unknown auth":     "user: auditor\n        auth_protocol: sha256\n        auth_key: auth-secret",
+		"short auth key":   "user: auditor\n        auth_protocol: md5\n        auth_key: short",
+		"unknown priv":     "user: auditor\n        auth_protocol: md5\n        auth_key: auth-secret\n        priv_protocol: 3des\n        priv_key: priv-secret",
+		"priv without key": "user: auditor\n        auth_protocol: md5\n        auth_key: auth-secret\n        priv_protocol: des",
+		"key without priv": "user: auditor\n        auth_protocol: md5\n        auth_key: auth-secret\n        priv_key: priv-secret",
+	}
+	for name, v3 := range bad {
+		yaml := "devices:\n  - name: firewall\n    mac: \"00:11:22:33:44:55\"\n    snmp_agent:\n      v3:\n        " + v3 + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestLoadYAML_SNMPEngineState tests the engine boots and time an agent
+// starts with
+func TestLoadYAML_SNMPEngineState(t *testing.T) {
+	cfg, err := LoadYAMLBytes([]byte(`
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      engine_boots: 12
+      engine_time: 86400
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if snmp := cfg.Devices[0].SNMPConfig; snmp.EngineBoots != 12 || snmp.EngineTime != 24*time.Hour {
+		t.Errorf("Engine state = %d, %v; want 12, 24h", snmp.EngineBoots, snmp.EngineTime)
+	}
+
+	_, err = LoadYAMLBytes([]byte(`
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      engine_boots: -1
+`))
+	if err == nil || !strings.Contains(err.Error(), "device router: SNMP engine_boots") {
+		t.Errorf("Expected an engine_boots range error, got %v", err)
+	}
+}
+
+// TestLoadYAML_BGP tests the bgp block: the router ID defaults to the first
+// IPv4 address and the hold time to 90 seconds
+func TestLoadYAML_BGP(t *testing.T) {
+	cfg, err := LoadYAMLBytes([]byte(`
+devices:
+  - name: edge
+    mac: "00:11:22:33:44:55"
+    ips: ["2001:db8::1", "192.168.1.1"]
+    bgp:
+      as: 65001
+  - name: core
+    mac: "00:11:22:33:44:56"
+    ip: 192.168.1.2
+    bgp:
+      as: 4200000001
+      router_id: 10.255.0.2
+      hold_time: 0
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if bgp := cfg.Devices[0].BGP; bgp == nil || bgp.AS != 65001 || !bgp.RouterID.Equal(net.ParseIP("192.168.1.1")) || bgp.HoldTime != DefaultBGPHoldTime {
+		t.Errorf("edge BGP = %+v, want AS 65001, router ID 192.168.1.1, hold time 90s", bgp)
+	}
+	if bgp := cfg.Devices[1].BGP; bgp == nil || bgp.AS != 4200000001 || !bgp.RouterID.Equal(net.ParseIP("10.255.0.2")) || bgp.HoldTime != 0 {
+		t.Errorf("core BGP = %+v, want AS 4200000001, router ID 10.255.0.2, hold time 0", bgp)
+	}
+
+	for _, tc := range []struct{ bgp, want string }{
+		{"hold_time: 90", "bgp as is required"},
+		{"as: 65001\n      hold_time: 2", "bgp hold_time must be 0 or between 3 and 65535"},
+		{"as: 65001\n      router_id: 2001:db8::1", "invalid bgp router_id"},
+	} {
+		_, err := LoadYAMLBytes([]byte(`
+devices:
+  - name: edge
+    mac: "00:11:22:33:44:55"
+    ip: 192.168.1.1
+    bgp:
+      ` + tc.bgp + `
+`))
+		if err == nil || !strings.Contains(err.Error(), "device edge: "+tc.want) {
+			t.Errorf("bgp %q: expected %q, got %v", tc.bgp, tc.want, err)
+		}
+	}
+}
+
+func TestLoadYAML_MalformedFrames(t *testing.T) {
+	cfg, err := LoadYAMLBytes([]byte(`
+devices:
+  - name: fuzzer
+    mac: "00:11:22:33:44:55"
+    ip: 192.168.1.1
+    malformed_frames:
+      enabled: true
+      protocol: TCP
+      corruption: bad_option_length
+  - name: idle
+    mac: "00:11:22:33:44:56"
+    ip: 192.168.1.2
+    malformed_frames:
+      protocol: udp
+      corruption: bad_checksum
+      rate: 50
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	if m := cfg.Devices[0].MalformedFrames; m == nil || m.Protocol != MalformedProtocolTCP || m.Corruption != MalformedBadOptionLength || m.Rate != DefaultMalformedRate {
+		t.Errorf("fuzzer malformed_frames = %+v, want tcp bad_option_length at %d/s", m, DefaultMalformedRate)
+	}
+	if m := cfg.Devices[1].MalformedFrames; m != nil {
+		t.Errorf("idle malformed_frames = %+v, want nil without enabled: true", m)
+	}
+
+	for _, tc := range []struct{ malformed, want string }{
+		{"protocol: sctp\n      corruption: truncated", "invalid malformed_frames protocol"},
+		{"protocol: udp\n      corruption: garbled", "invalid malformed_frames corruption"},
+		{"protocol: udp\n      corruption: bad_option_length", "malformed_frames corruption bad_option_length needs protocol ipv4 or tcp"},
+		{"protocol: icmp\n      corruption: truncated\n      rate: 1001", "malformed_frames rate must be between 1 and 1000"},
+	} {
+		_, err := LoadYAMLBytes([]byte(`
+devices:
+  - name: fuzzer
+    mac: "00:11:22:33:44:55"
+    ip: 192.168.1.1
+    malformed_frames:
+      ` + tc.malformed + `
+`))
+		if err == nil || !strings.Contains(err.Error(), "device fuzzer: "+tc.want) {
+			t.Errorf("malformed_frames %q: expected %q, got %v", tc.malformed, tc.want, err)
+		}
+	}
+}
+
+func TestLoadYAML_StrictUnknownKeys(t *testing.T) {
+	yaml := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      comunity: private
+`
+	if _, err := LoadYAMLBytes([]byte(yaml)); err != nil {
+		t.Fatalf("Lenient mode should ignore unknown keys: %v", err)
+	}
+
+	SetStrictYAML(true)
+	t.Cleanup(func() { SetStrictYAML(false) })
+
+	_, err := LoadYAMLBytes([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected strict mode to reject the misspelled key")
+	}
+	if !strings.Contains(err.Error(), "comunity") || !strings.Contains(err.Error(), "line 6") {
+		t.Errorf("Expected the error to name the key and its line, got: %v", err)
+	}
+
+	valid := `
+devices:
+  - name: router
+    mac: "00:11:22:33:44:55"
+`
+	if _, err := LoadYAMLBytes([]byte(valid)); err != nil {
+		t.Errorf("Strict mode rejected a valid config: %v", err)
+	}
+}
+
+func TestLoadYAML_DNSForwarders(t *testing.T) {
+	yaml := `
+devices:
+  - name: dns1
+    mac: "00:11:22:33:44:55"
+    ips: ["10.0.0.53"]
+    dns:
+      forwarders: ["8.8.8.8", "192.0.2.1:5353", "[2001:db8::53]:53"]
+      cache_ttl: 120
+  - name: dns2
+    mac: "00:11:22:33:44:56"
+    ips: ["10.0.0.54"]
+    dns: {}
+`
+	cfg, err := LoadYAMLBytes([]byte(yaml))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	dns := cfg.Devices[0].DNSConfig
+	want := []string{"8.8.8.8:53", "192.0.2.1:5353", "[2001:db8::53]:53"}
+	if dns == nil || strings.Join(dns.Forwarders, ",") != strings.Join(want, ",") || dns.CacheTTL != 120*time.Second {
+		t.Errorf("dns = %+v, want forwarders %v and a 120s cache TTL", dns, want)
+	}
+	if dns := cfg.Devices[1].DNSConfig; dns == nil || len(dns.Forwarders) != 0 || dns.CacheTTL != DefaultDNSCacheTTL*time.Second {
+		t.Errorf("dns = %+v, want no forwarders and the default cache TTL", dns)
+	}
+
+	for _, bad := range []string{`forwarders: ["resolver.example.com"]`, `forwarders: ["8.8.8.8:0"]`, `cache_ttl: -1`} {
+		yaml := `
+devices:
+  - name: dns1
+    mac: "00:11:22:33:44:55"
+    dns:
+      ` + bad + "\n"
+		if _, err := LoadYAMLBytes([]byte(yaml)); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
+
+// TestLoadTOML_MatchesYAML tests that a TOML config loads to the same runtime
+// config as the equivalent YAML
+func TestLoadTOML_MatchesYAML(t *testing.T) {
+	fromTOML, err := Load(filepath.Join("testdata", "snmp_dhcp.toml"))
+	if err != nil {
+		t.Fatalf("Load(toml) failed: %v", err)
+	}
+	fromYAML, err := Load(filepath.Join("testdata", "snmp_dhcp.yaml"))
+	if err != nil {
+		t.Fatalf("Load(yaml) failed: %v", err)
+	}
+
+	device := fromTOML.Devices[0]
+	if device.SNMPConfig.Traps == nil || len(device.DHCPConfig.ClientLeases) != 1 || device.TCPPorts[23] != "filtered" {
+		t.Fatalf("TOML device not fully loaded: %+v", device)
+	}
+	if !reflect.DeepEqual(fromTOML, fromYAML) {
+		t.Errorf("TOML and YAML configs differ:\nTOML: %+v\nYAML: %+v", fromTOML.Devices[0], fromYAML.Devices[0])
+	}
+}
+
+// TestLoadTOML_Errors tests that TOML validation errors name the device and
+// field, and that strict loads reject unknown keys
+func TestLoadTOML_Errors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	_, err := Load(write("poe.toml", `
+[[devices]]
+name = "phone-1"
+mac = "00:11:22:33:44:55"
+poe = { class = 2, requested_watts = 15.0 }
+`))
+	if err == nil || !strings.Contains(err.Error(), "device phone-1") || !strings.Contains(err.Error(), "requested_watts") {
+		t.Errorf("Expected an error naming the device and field, got %v", err)
+	}
+
+	_, err = Load(write("type.toml", `
+[[devices]]
+name = "phone-1"
+mac = "00:11:22:33:44:55"
+vlan = "ten"
+`))
+	if err == nil || !strings.Contains(err.Error(), "devices.vlan") {
+		t.Errorf("Expected a type error naming the key, got %v", err)
+	}
+
+	typo := write("typo.toml", `
+[[devices]]
+name = "router-1"
+mac = "00:11:22:33:44:55"
+
+[devices.snmp_agent]
+comunity = "public"
+`)
+	if _, err := Load(typo); err != nil {
+		t.Fatalf("Expected unknown keys to be ignored by default, got %v", err)
+	}
+	SetStrictYAML(true)
+	t.Cleanup(func() { SetStrictYAML(false) })
+	if _, err := Load(typo); err == nil || !strings.Contains(err.Error(), "devices.snmp_agent.comunity") {
+		t.Errorf("Expected strict load to name the unknown key, got %v", err)
+	}
+}
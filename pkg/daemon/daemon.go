@@ -14,6 +14,8 @@ import (
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
 	"github.com/krisarmstrong/niac-go/pkg/protocols"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+	"github.com/krisarmstrong/niac-go/pkg/stats"
 	"github.com/krisarmstrong/niac-go/pkg/storage"
 )
 
@@ -28,6 +30,12 @@ type Config struct {
 	Token       string
 	StoragePath string
 	Version     string
+	RateLimit   float64 // API requests per second per client IP (0 = unlimited)
+	RateBurst   int
+	UIDir       string // Serve the Web UI from this directory ("" = embedded assets)
+	OutputDir   string // Base directory for generated artifacts such as replay uploads ("" = temp dir)
+
+	SNMPDurationBuckets []time.Duration // SNMP request duration histogram bounds (nil = stats.DefaultSNMPDurationBuckets)
 }
 
 // Daemon manages the NIAC simulation lifecycle
@@ -36,6 +44,8 @@ type Daemon struct {
 	apiServer *api.Server
 	storage   *storage.Storage
 
+	snmpDurations *stats.Histogram // Served on /metrics while the API server runs
+
 	mu         sync.RWMutex
 	simulation *Simulation
 }
@@ -60,10 +70,19 @@ func NewDaemon(cfg Config) (*Daemon, error) {
 		cfg: cfg,
 	}
 
+	buckets := cfg.SNMPDurationBuckets
+	if buckets == nil {
+		buckets = stats.DefaultSNMPDurationBuckets
+	}
+	snmpDurations, err := stats.NewHistogram(buckets)
+	if err != nil {
+		return nil, fmt.Errorf("snmp duration buckets: %w", err)
+	}
+	daemon.snmpDurations = snmpDurations
+
 	// Open storage if enabled
 	if cfg.StoragePath != "" && cfg.StoragePath != "disabled" {
 		storagePath := expandPath(cfg.StoragePath)
-		var err error
 		daemon.storage, err = storage.Open(storagePath)
 		if err != nil {
 			return nil, fmt.Errorf("open storage: %w", err)
@@ -77,12 +96,19 @@ func NewDaemon(cfg Config) (*Daemon, error) {
 func (d *Daemon) Start() error {
 	// Create API server
 	serverCfg := api.ServerConfig{
-		Addr:    d.cfg.ListenAddr,
-		Token:   d.cfg.Token,
-		Version: d.cfg.Version,
-		Storage: d.storage,
+		Addr:      d.cfg.ListenAddr,
+		Token:     d.cfg.Token,
+		Version:   d.cfg.Version,
+		Storage:   d.storage,
+		RateLimit: d.cfg.RateLimit,
+		RateBurst: d.cfg.RateBurst,
+		UIDir:     d.cfg.UIDir,
+		OutputDir: d.cfg.OutputDir,
 		// Stack, Config, etc. will be nil until simulation starts
+
+		SNMPDurations: d.snmpDurations,
 	}
+	snmp.SetRequestDurationObserver(d.snmpDurations)
 
 	d.apiServer = api.NewServer(serverCfg)
 
@@ -91,6 +117,7 @@ func (d *Daemon) Start() error {
 	d.apiServer.SetDaemonController(d)
 
 	if err := d.apiServer.Start(); err != nil {
+		snmp.SetRequestDurationObserver(nil)
 		if d.storage != nil {
 			if closeErr := d.storage.Close(); closeErr != nil {
 				logging.Error("Error closing storage during cleanup: %v", closeErr)
@@ -111,6 +138,7 @@ func (d *Daemon) Shutdown(ctx context.Context) error {
 
 	// Shutdown API server
 	if d.apiServer != nil {
+		snmp.SetRequestDurationObserver(nil)
 		if err := d.apiServer.Shutdown(ctx); err != nil {
 			return fmt.Errorf("shutdown API server: %w", err)
 		}
@@ -225,7 +253,7 @@ func (d *Daemon) stopSimulationLocked() error {
 
 	// Stop replay if running
 	if sim.replay != nil {
-		_, _ = sim.replay.Stop()
+		sim.replay.StopAll()
 	}
 
 	// Cancel context first to signal shutdown
@@ -320,10 +348,13 @@ func newReplayController(engine *capture.Engine, debugLevel int) *replayControll
 	}
 }
 
-func (rc *replayController) Status() api.ReplayState {
-	rc.mu.Lock()
-	defer rc.mu.Unlock()
-	return rc.state
+func (rc *replayController) Status(name string) (api.ReplayState, bool) {
+	return api.ReplayState{Name: name}, name == api.DefaultReplayName
+}
+
+// List returns no replays: replay does not run in daemon mode yet
+func (rc *replayController) List() []api.ReplayState {
+	return []api.ReplayState{}
 }
 
 func (rc *replayController) Start(req api.ReplayRequest) (api.ReplayState, error) {
@@ -332,9 +363,10 @@ func (rc *replayController) Start(req api.ReplayRequest) (api.ReplayState, error
 	return rc.state, fmt.Errorf("replay not yet implemented in daemon mode")
 }
 
-func (rc *replayController) Stop() (api.ReplayState, error) {
-	rc.mu.Lock()
-	defer rc.mu.Unlock()
-	rc.state.Running = false
-	return rc.state, nil
+func (rc *replayController) Stop(name string) (api.ReplayState, bool) {
+	return rc.Status(name)
+}
+
+func (rc *replayController) StopAll() []api.ReplayState {
+	return rc.List()
 }
@@ -82,6 +82,13 @@ func NewSimulator(cfg *config.Config, stack *protocols.Stack, errorMgr *errors.S
 	return sim
 }
 
+// newSNMPAgent creates a device's SNMP agent with cfg's walk file limits
+func (s *Simulator) newSNMPAgent(device *config.Device, cfg *config.Config) *snmp.Agent {
+	agent := snmp.NewAgent(device, s.debugLevel)
+	agent.SetWalkLimits(snmp.NewWalkLimits(cfg.WalkLimits))
+	return agent
+}
+
 // addDevice adds a device to the simulator
 func (s *Simulator) addDevice(device *config.Device) {
 	s.mu.Lock()
@@ -89,7 +96,7 @@ func (s *Simulator) addDevice(device *config.Device) {
 
 	simDevice := &SimulatedDevice{
 		Config:       device,
-		SNMPAgent:    snmp.NewAgent(device, s.debugLevel),
+		SNMPAgent:    s.newSNMPAgent(device, s.config),
 		State:        StateUp,
 		LastActivity: time.Now(),
 		Counters:     &DeviceCounters{},
@@ -102,6 +109,18 @@ func (s *Simulator) addDevice(device *config.Device) {
 			log.Printf("Warning: failed to load walk file for %s: %v", device.Name, err)
 		}
 	}
+	if files := device.SNMPConfig.WalkFiles; len(files) > 0 {
+		err := simDevice.SNMPAgent.LoadWalkFiles(files)
+		if err != nil && s.debugLevel >= 1 {
+			log.Printf("Warning: failed to load walk directory for %s: %v", device.Name, err)
+		}
+	}
+	if series := device.SNMPConfig.WalkSeries; series != nil {
+		err := simDevice.SNMPAgent.LoadWalkSeries(series.Files, series.Interval, series.Loop)
+		if err != nil && s.debugLevel >= 1 {
+			log.Printf("Warning: failed to load walk series for %s: %v", device.Name, err)
+		}
+	}
 
 	// Initialize SNMP trap sender if configured (v1.6.0)
 	if device.SNMPConfig.Traps != nil && device.SNMPConfig.Traps.Enabled {
@@ -443,6 +462,18 @@ func (s *Simulator) GetCounters(deviceName string) *DeviceCounters {
 	}
 }
 
+// ResetCounters zeroes the counters of every device
+func (s *Simulator) ResetCounters() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, device := range s.devices {
+		device.mu.Lock()
+		*device.Counters = DeviceCounters{}
+		device.mu.Unlock()
+	}
+}
+
 // Reload gracefully reloads the configuration without stopping the simulator
 // It performs a diff-based reload: adds new devices, removes deleted devices, and updates existing devices
 func (s *Simulator) Reload(newConfig *config.Config) error {
@@ -475,6 +506,9 @@ func (s *Simulator) Reload(newConfig *config.Config) error {
 		}
 	}
 
+	// Update config reference; new agents take its walk limits
+	s.config = newConfig
+
 	// Add or update devices from new config
 	for i := range newConfig.Devices {
 		device := &newConfig.Devices[i]
@@ -485,12 +519,22 @@ func (s *Simulator) Reload(newConfig *config.Config) error {
 			}
 			existingDevice.Config = device
 			// Recreate SNMP agent with updated configuration
-			existingDevice.SNMPAgent = snmp.NewAgent(device, s.debugLevel)
+			existingDevice.SNMPAgent = s.newSNMPAgent(device, newConfig)
 			if device.SNMPConfig.WalkFile != "" {
 				if err := existingDevice.SNMPAgent.LoadWalkFile(device.SNMPConfig.WalkFile); err != nil && s.debugLevel >= 1 {
 					log.Printf("Warning: failed to reload walk file for %s: %v", device.Name, err)
 				}
 			}
+			if files := device.SNMPConfig.WalkFiles; len(files) > 0 {
+				if err := existingDevice.SNMPAgent.LoadWalkFiles(files); err != nil && s.debugLevel >= 1 {
+					log.Printf("Warning: failed to reload walk directory for %s: %v", device.Name, err)
+				}
+			}
+			if series := device.SNMPConfig.WalkSeries; series != nil {
+				if err := existingDevice.SNMPAgent.LoadWalkSeries(series.Files, series.Interval, series.Loop); err != nil && s.debugLevel >= 1 {
+					log.Printf("Warning: failed to reload walk series for %s: %v", device.Name, err)
+				}
+			}
 
 			// Recreate trap sender if traps are enabled
 			existingDevice.TrapSender = nil
@@ -513,8 +557,6 @@ func (s *Simulator) Reload(newConfig *config.Config) error {
 		}
 	}
 
-	// Update config reference
-	s.config = newConfig
 	s.mu.Unlock()
 
 	// Restart if was running before
@@ -276,6 +276,27 @@ func TestSimulator_IncrementCounter(t *testing.T) {
 	}
 }
 
+// TestSimulator_ResetCounters tests that every device's counters are zeroed
+func TestSimulator_ResetCounters(t *testing.T) {
+	cfg := createTestConfig(2)
+	stack := protocols.NewStack(nil, cfg, logging.NewDebugConfig(0))
+	errorMgr := errors.NewStateManager()
+	sim := NewSimulator(cfg, stack, errorMgr, 0)
+
+	for _, deviceName := range []string{"test-device-0", "test-device-1"} {
+		sim.IncrementCounter(deviceName, "packets_sent")
+		sim.IncrementCounter(deviceName, "arp_requests")
+	}
+
+	sim.ResetCounters()
+
+	for _, deviceName := range []string{"test-device-0", "test-device-1"} {
+		if counters := sim.GetCounters(deviceName); *counters != (DeviceCounters{}) {
+			t.Errorf("%s counters after reset = %+v, want zero", deviceName, *counters)
+		}
+	}
+}
+
 // TestSimulator_IncrementCounter_NonExistentDevice tests incrementing counter for non-existent device
 func TestSimulator_IncrementCounter_NonExistentDevice(t *testing.T) {
 	cfg := createTestConfig(1)
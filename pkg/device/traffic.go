@@ -110,8 +110,11 @@ func (tg *TrafficGenerator) checkAndGenerateTraffic() {
 
 		cfg := device.Config.TrafficConfig
 
+		// Patterns idle outside their schedule and fire as soon as a window
+		// opens, since their last run time is left untouched
+
 		// Check ARP announcements
-		if cfg.ARPAnnouncements != nil && cfg.ARPAnnouncements.Enabled {
+		if cfg.ARPAnnouncements != nil && cfg.ARPAnnouncements.Enabled && cfg.ARPAnnouncements.Schedule.Active(now) {
 			lastTime := tg.lastARPTime[name]
 			interval := time.Duration(cfg.ARPAnnouncements.Interval) * time.Second
 			if now.Sub(lastTime) >= interval {
@@ -121,7 +124,7 @@ func (tg *TrafficGenerator) checkAndGenerateTraffic() {
 		}
 
 		// Check periodic pings
-		if cfg.PeriodicPings != nil && cfg.PeriodicPings.Enabled {
+		if cfg.PeriodicPings != nil && cfg.PeriodicPings.Enabled && cfg.PeriodicPings.Schedule.Active(now) {
 			lastTime := tg.lastPingTime[name]
 			interval := time.Duration(cfg.PeriodicPings.Interval) * time.Second
 			if now.Sub(lastTime) >= interval {
@@ -131,7 +134,7 @@ func (tg *TrafficGenerator) checkAndGenerateTraffic() {
 		}
 
 		// Check random traffic
-		if cfg.RandomTraffic != nil && cfg.RandomTraffic.Enabled {
+		if cfg.RandomTraffic != nil && cfg.RandomTraffic.Enabled && cfg.RandomTraffic.Schedule.Active(now) {
 			lastTime := tg.lastRandTime[name]
 			interval := time.Duration(cfg.RandomTraffic.Interval) * time.Second
 			if now.Sub(lastTime) >= interval {
@@ -170,8 +173,9 @@ func (tg *TrafficGenerator) sendPeriodicPing(device *SimulatedDevice, payloadSiz
 		return
 	}
 
-	// Use existing sendPing (currently doesn't use payloadSize, but we'll pass it for future use)
-	tg.sendPing(device, dst)
+	if err := tg.sendPing(device, dst, payloadSize); err != nil && tg.debugLevel >= 2 {
+		log.Printf("Failed to send ping from %s to %s: %v", device.Config.Name, dst.Config.Name, err)
+	}
 }
 
 // generateRandomTrafficForDevice generates random traffic for a single device (v1.6.0)
@@ -345,7 +349,7 @@ func (tg *TrafficGenerator) sendPeriodicPings() {
 		return
 	}
 
-	err := tg.sendPing(src, dst)
+	err := tg.sendPing(src, dst, 0)
 	if err != nil && tg.debugLevel >= 2 {
 		log.Printf("Failed to send ping from %s to %s: %v", src.Config.Name, dst.Config.Name, err)
 	} else if tg.debugLevel >= 3 {
@@ -353,8 +357,10 @@ func (tg *TrafficGenerator) sendPeriodicPings() {
 	}
 }
 
-// sendPing sends an ICMP Echo Request
-func (tg *TrafficGenerator) sendPing(src, dst *SimulatedDevice) error {
+// sendPing sends an ICMP Echo Request carrying payloadSize bytes (0 = a short
+// marker string). The payload is capped so the packet fits the source
+// device's MTU; only jumbo-enabled devices send jumbo pings.
+func (tg *TrafficGenerator) sendPing(src, dst *SimulatedDevice, payloadSize int) error {
 	// Build Ethernet header
 	eth := &layers.Ethernet{
 		SrcMAC:       src.Config.MACAddress,
@@ -366,7 +372,7 @@ func (tg *TrafficGenerator) sendPing(src, dst *SimulatedDevice) error {
 	ipLayer := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      src.Config.TTL(),
 		Protocol: layers.IPProtocolICMPv4,
 		SrcIP:    src.Config.IPAddresses[0].To4(),
 		DstIP:    dst.Config.IPAddresses[0].To4(),
@@ -380,7 +386,7 @@ func (tg *TrafficGenerator) sendPing(src, dst *SimulatedDevice) error {
 	}
 
 	// Payload
-	payload := []byte("NIAC-Go ping test data")
+	payload := pingPayload(payloadSize, src.Config.LinkMTU())
 
 	// Serialize
 	buffer := gopacket.NewSerializeBuffer()
@@ -409,6 +415,24 @@ func (tg *TrafficGenerator) sendPing(src, dst *SimulatedDevice) error {
 	return nil
 }
 
+// pingPayload returns an echo payload of size bytes, filled with a repeating
+// marker, capped so the IPv4 packet (20-byte header, 8-byte ICMP header)
+// fits mtu.
+func pingPayload(size, mtu int) []byte {
+	marker := []byte("NIAC-Go ping test data")
+	if size <= 0 {
+		return marker
+	}
+	if limit := mtu - 28; size > limit {
+		size = limit
+	}
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = marker[i%len(marker)]
+	}
+	return payload
+}
+
 // randomTrafficLoop generates random low-level traffic
 // nolint:unused // Future feature: background traffic generation
 func (tg *TrafficGenerator) randomTrafficLoop() {
@@ -559,7 +583,7 @@ func (tg *TrafficGenerator) sendRandomUDP(src, dst *SimulatedDevice) error {
 	ipLayer := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      src.Config.TTL(),
 		Protocol: layers.IPProtocolUDP,
 		SrcIP:    src.Config.IPAddresses[0].To4(),
 		DstIP:    dst.Config.IPAddresses[0].To4(),
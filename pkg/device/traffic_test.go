@@ -0,0 +1,58 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/krisarmstrong/niac-go/pkg/protocols"
+)
+
+// TestTrafficGenerator_Schedule tests that patterns stay idle outside their schedule
+func TestTrafficGenerator_Schedule(t *testing.T) {
+	cfg := createTestConfig(1)
+	arp := &config.ARPAnnouncementConfig{
+		Enabled:  true,
+		Interval: config.DefaultARPAnnouncementInterval,
+		// Only tomorrow, so the window is closed now
+		Schedule: &config.TrafficSchedule{Days: []time.Weekday{(time.Now().Weekday() + 1) % 7}},
+	}
+	cfg.Devices[0].TrafficConfig = &config.TrafficConfig{Enabled: true, ARPAnnouncements: arp}
+
+	stack := protocols.NewStack(nil, cfg, logging.NewDebugConfig(0))
+	sim := NewSimulator(cfg, stack, nil, 0)
+	tg := NewTrafficGenerator(sim, stack, 0)
+	name := cfg.Devices[0].Name
+
+	tg.checkAndGenerateTraffic()
+	if depth, _ := stack.SendQueueDepth(); depth != 0 {
+		t.Fatalf("Expected no traffic while the schedule is closed, got %d queued packets", depth)
+	}
+	if sent := sim.GetCounters(name).PacketsSent; sent != 0 {
+		t.Fatalf("Expected no packets sent while the schedule is closed, got %d", sent)
+	}
+
+	// Opening the window sends right away
+	arp.Schedule = &config.TrafficSchedule{Days: []time.Weekday{time.Now().Weekday()}}
+	tg.checkAndGenerateTraffic()
+	if depth, _ := stack.SendQueueDepth(); depth != 1 {
+		t.Fatalf("Expected a gratuitous ARP once the schedule opens, got %d queued packets", depth)
+	}
+}
+
+func TestPingPayload_CappedByMTU(t *testing.T) {
+	tests := []struct {
+		size, mtu, want int
+	}{
+		{0, config.DefaultMTU, len("NIAC-Go ping test data")},
+		{32, config.DefaultMTU, 32},
+		{8972, config.DefaultMTU, 1472},
+		{8972, config.JumboMTU, 8972},
+	}
+	for _, tt := range tests {
+		if got := len(pingPayload(tt.size, tt.mtu)); got != tt.want {
+			t.Errorf("pingPayload(%d, %d) is %d bytes, want %d", tt.size, tt.mtu, got, tt.want)
+		}
+	}
+}
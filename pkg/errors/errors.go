@@ -17,6 +17,7 @@ const (
 	ErrorTypeCPU         ErrorType = "High CPU"
 	ErrorTypeMemory      ErrorType = "High Memory"
 	ErrorTypeDisk        ErrorType = "High Disk"
+	ErrorTypeRunts       ErrorType = "Runt Frames"
 )
 
 // AllErrorTypes returns all available error types
@@ -29,6 +30,7 @@ func AllErrorTypes() []ErrorType {
 		ErrorTypeCPU,
 		ErrorTypeMemory,
 		ErrorTypeDisk,
+		ErrorTypeRunts,
 	}
 }
 
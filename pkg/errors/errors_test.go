@@ -82,8 +82,8 @@ func TestInterfaceConfig(t *testing.T) {
 
 func TestAllErrorTypes(t *testing.T) {
 	types := AllErrorTypes()
-	if len(types) != 7 {
-		t.Errorf("Expected 7 error types, got %d", len(types))
+	if len(types) != 8 {
+		t.Errorf("Expected 8 error types, got %d", len(types))
 	}
 
 	// Verify all expected types are present
@@ -95,6 +95,7 @@ func TestAllErrorTypes(t *testing.T) {
 		ErrorTypeCPU:         false,
 		ErrorTypeMemory:      false,
 		ErrorTypeDisk:        false,
+		ErrorTypeRunts:       false,
 	}
 
 	for _, et := range types {
@@ -0,0 +1,274 @@
+// Package flow exports samples of simulated traffic to flow collectors (sFlow v5)
+package flow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// sFlow v5 constants (https://sflow.org/sflow_version_5.txt)
+const (
+	sflowVersion = 5
+
+	sflowAddressIPv4 = 1
+	sflowAddressIPv6 = 2
+
+	sflowFlowSample        = 1 // enterprise 0, format 1
+	sflowRawPacketHeader   = 1 // enterprise 0, format 1
+	sflowHeaderEthernet    = 1 // ETHERNET-ISO88023
+	sflowSourceTypeIfIndex = 0
+
+	// MaxHeaderBytes is the number of leading frame bytes copied into a sample
+	MaxHeaderBytes = 128
+
+	// maxSamplesPerDatagram keeps datagrams below a typical 1500-byte MTU
+	maxSamplesPerDatagram = 6
+
+	// DefaultFlushInterval bounds how long a sample waits before export
+	DefaultFlushInterval = time.Second
+
+	// ifIndex reported for the simulated interface
+	simulatedIfIndex = 1
+)
+
+// Exporter samples frames from the send and receive paths and exports them as
+// sFlow v5 flow samples over UDP.
+type Exporter struct {
+	conn          *net.UDPConn
+	agentIP       net.IP
+	samplingRate  uint32
+	flushInterval time.Duration
+	startTime     time.Time
+
+	mu          sync.Mutex
+	pending     [][]byte // Encoded flow samples awaiting export
+	samplePool  uint32   // Frames observed
+	flowSeq     uint32
+	datagramSeq uint32
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+}
+
+// NewExporter connects an exporter to the configured collector
+func NewExporter(cfg *config.FlowExportConfig) (*Exporter, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("flow export not configured")
+	}
+	if cfg.Protocol != config.FlowProtocolSFlow {
+		return nil, fmt.Errorf("unsupported flow export protocol %q", cfg.Protocol)
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", cfg.Collector)
+	if err != nil {
+		return nil, fmt.Errorf("resolve collector %s: %w", cfg.Collector, err)
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to collector %s: %w", cfg.Collector, err)
+	}
+
+	agentIP := cfg.AgentIP
+	if agentIP == nil {
+		agentIP = conn.LocalAddr().(*net.UDPAddr).IP
+	}
+
+	samplingRate := cfg.SamplingRate
+	if samplingRate < 1 {
+		samplingRate = 1
+	}
+
+	return &Exporter{
+		conn:          conn,
+		agentIP:       agentIP,
+		samplingRate:  uint32(samplingRate),
+		flushInterval: DefaultFlushInterval,
+		startTime:     time.Now(),
+		stopChan:      make(chan struct{}),
+	}, nil
+}
+
+// Start begins periodic export of pending samples
+func (e *Exporter) Start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running {
+		return
+	}
+	e.running = true
+
+	e.wg.Add(1)
+	go e.flushLoop()
+}
+
+// Stop exports any pending samples and closes the collector connection
+func (e *Exporter) Stop() {
+	e.mu.Lock()
+	wasRunning := e.running
+	e.running = false
+	e.mu.Unlock()
+
+	if wasRunning {
+		close(e.stopChan)
+		e.wg.Wait()
+	}
+	if err := e.Flush(); err != nil {
+		log.Printf("sFlow: final export failed: %v", err)
+	}
+	e.conn.Close()
+}
+
+func (e *Exporter) flushLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-ticker.C:
+			if err := e.Flush(); err != nil {
+				log.Printf("sFlow: export failed: %v", err)
+			}
+		}
+	}
+}
+
+// Observe offers a frame to the sampler. received distinguishes frames read
+// from the wire (input interface) from frames NIAC sent (output interface).
+func (e *Exporter) Observe(frame []byte, received bool) {
+	if len(frame) == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	e.samplePool++
+	if e.samplingRate > 1 && rand.Uint32N(e.samplingRate) != 0 {
+		e.mu.Unlock()
+		return
+	}
+	e.flowSeq++
+	e.pending = append(e.pending, e.encodeFlowSample(frame, received))
+	full := len(e.pending) >= maxSamplesPerDatagram
+	e.mu.Unlock()
+
+	if full {
+		if err := e.Flush(); err != nil {
+			log.Printf("sFlow: export failed: %v", err)
+		}
+	}
+}
+
+// Flush sends pending samples to the collector as one sFlow datagram
+func (e *Exporter) Flush() error {
+	e.mu.Lock()
+	if len(e.pending) == 0 {
+		e.mu.Unlock()
+		return nil
+	}
+	samples := e.pending
+	e.pending = nil
+	e.datagramSeq++
+	datagram := e.encodeDatagram(samples, e.datagramSeq)
+	e.mu.Unlock()
+
+	_, err := e.conn.Write(datagram)
+	return err
+}
+
+// encodeFlowSample encodes a flow_sample carrying a raw packet header record.
+// Callers must hold e.mu.
+func (e *Exporter) encodeFlowSample(frame []byte, received bool) []byte {
+	header := frame
+	if len(header) > MaxHeaderBytes {
+		header = header[:MaxHeaderBytes]
+	}
+
+	// raw_packet_header record
+	record := newXDR()
+	record.u32(sflowHeaderEthernet)
+	record.u32(uint32(len(frame)))
+	record.u32(0) // bytes stripped
+	record.opaque(header)
+
+	input, output := uint32(0), uint32(simulatedIfIndex)
+	if received {
+		input, output = simulatedIfIndex, 0
+	}
+
+	sample := newXDR()
+	sample.u32(e.flowSeq)
+	sample.u32(sflowSourceTypeIfIndex<<24 | simulatedIfIndex)
+	sample.u32(e.samplingRate)
+	sample.u32(e.samplePool)
+	sample.u32(0) // drops
+	sample.u32(input)
+	sample.u32(output)
+	sample.u32(1) // flow records
+	sample.u32(sflowRawPacketHeader)
+	sample.u32(uint32(len(record.buf)))
+	sample.bytes(record.buf)
+
+	encoded := newXDR()
+	encoded.u32(sflowFlowSample)
+	encoded.u32(uint32(len(sample.buf)))
+	encoded.bytes(sample.buf)
+	return encoded.buf
+}
+
+// encodeDatagram wraps samples in an sFlow v5 datagram header
+func (e *Exporter) encodeDatagram(samples [][]byte, seq uint32) []byte {
+	d := newXDR()
+	d.u32(sflowVersion)
+	if v4 := e.agentIP.To4(); v4 != nil {
+		d.u32(sflowAddressIPv4)
+		d.bytes(v4)
+	} else {
+		d.u32(sflowAddressIPv6)
+		d.bytes(e.agentIP.To16())
+	}
+	d.u32(0) // sub-agent ID
+	d.u32(seq)
+	d.u32(uint32(time.Since(e.startTime).Milliseconds()))
+	d.u32(uint32(len(samples)))
+	for _, sample := range samples {
+		d.bytes(sample)
+	}
+	return d.buf
+}
+
+// xdr is a minimal XDR encoder for sFlow structures
+type xdr struct {
+	buf []byte
+}
+
+func newXDR() *xdr {
+	return &xdr{buf: make([]byte, 0, 256)}
+}
+
+func (x *xdr) u32(v uint32) {
+	x.buf = binary.BigEndian.AppendUint32(x.buf, v)
+}
+
+func (x *xdr) bytes(b []byte) {
+	x.buf = append(x.buf, b...)
+}
+
+// opaque writes a length-prefixed byte string padded to a 4-byte boundary
+func (x *xdr) opaque(b []byte) {
+	x.u32(uint32(len(b)))
+	x.buf = append(x.buf, b...)
+	if pad := (4 - len(b)%4) % 4; pad > 0 {
+		x.buf = append(x.buf, make([]byte, pad)...)
+	}
+}
@@ -0,0 +1,148 @@
+package flow
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// buildFrame builds an Ethernet/IPv4/UDP frame with the given payload size
+func buildFrame(t *testing.T, payloadSize int) []byte {
+	t.Helper()
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP("10.0.0.1").To4(),
+		DstIP:    net.ParseIP("10.0.0.2").To4(),
+	}
+	udp := &layers.UDP{SrcPort: 5000, DstPort: 161}
+	_ = udp.SetNetworkLayerForChecksum(ip)
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, eth, ip, udp, gopacket.Payload(make([]byte, payloadSize))); err != nil {
+		t.Fatalf("Failed to build frame: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+// TestExporterSendsSFlowDatagram tests that sampled frames reach a collector as a well-formed sFlow v5 datagram
+func TestExporterSendsSFlowDatagram(t *testing.T) {
+	collector, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to start collector: %v", err)
+	}
+	defer collector.Close()
+
+	exporter, err := NewExporter(&config.FlowExportConfig{
+		Collector:    collector.LocalAddr().String(),
+		SamplingRate: 1,
+		Protocol:     config.FlowProtocolSFlow,
+		AgentIP:      net.ParseIP("192.0.2.10"),
+	})
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	defer exporter.Stop()
+
+	small := buildFrame(t, 10)
+	large := buildFrame(t, 400)
+	exporter.Observe(small, true)
+	exporter.Observe(large, false)
+	if err := exporter.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	buf := make([]byte, 65535)
+	_ = collector.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := collector.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("Collector received nothing: %v", err)
+	}
+
+	packet := gopacket.NewPacket(buf[:n], layers.LayerTypeSFlow, gopacket.Default)
+	if errLayer := packet.ErrorLayer(); errLayer != nil {
+		t.Fatalf("Malformed sFlow datagram: %v", errLayer.Error())
+	}
+	datagram, ok := packet.Layer(layers.LayerTypeSFlow).(*layers.SFlowDatagram)
+	if !ok {
+		t.Fatal("Datagram did not decode as sFlow")
+	}
+
+	if datagram.DatagramVersion != 5 {
+		t.Errorf("Expected sFlow version 5, got %d", datagram.DatagramVersion)
+	}
+	if !datagram.AgentAddress.Equal(net.ParseIP("192.0.2.10")) {
+		t.Errorf("Expected agent address 192.0.2.10, got %s", datagram.AgentAddress)
+	}
+	if len(datagram.FlowSamples) != 2 {
+		t.Fatalf("Expected 2 flow samples, got %d", len(datagram.FlowSamples))
+	}
+
+	first := datagram.FlowSamples[0]
+	if first.SamplingRate != 1 || first.InputInterface != simulatedIfIndex {
+		t.Errorf("Unexpected received sample: rate=%d input=%d", first.SamplingRate, first.InputInterface)
+	}
+	header, ok := first.Records[0].(layers.SFlowRawPacketFlowRecord)
+	if !ok {
+		t.Fatalf("Expected raw packet header record, got %T", first.Records[0])
+	}
+	if header.FrameLength != uint32(len(small)) || !bytes.Equal(header.Header.Data(), small) {
+		t.Errorf("Raw header does not match the sampled frame (length %d)", header.FrameLength)
+	}
+
+	second := datagram.FlowSamples[1]
+	if second.OutputInterface != simulatedIfIndex || second.SamplePool != 2 {
+		t.Errorf("Unexpected sent sample: output=%d pool=%d", second.OutputInterface, second.SamplePool)
+	}
+	truncated := second.Records[0].(layers.SFlowRawPacketFlowRecord)
+	if truncated.FrameLength != uint32(len(large)) || truncated.HeaderLength != MaxHeaderBytes {
+		t.Errorf("Expected header truncated to %d of %d bytes, got %d of %d",
+			MaxHeaderBytes, len(large), truncated.HeaderLength, truncated.FrameLength)
+	}
+}
+
+// TestExporterSampling tests that a sampling rate exports roughly 1 in N frames
+func TestExporterSampling(t *testing.T) {
+	collector, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to start collector: %v", err)
+	}
+	defer collector.Close()
+
+	exporter, err := NewExporter(&config.FlowExportConfig{
+		Collector:    collector.LocalAddr().String(),
+		SamplingRate: 10,
+		Protocol:     config.FlowProtocolSFlow,
+	})
+	if err != nil {
+		t.Fatalf("NewExporter failed: %v", err)
+	}
+	defer exporter.Stop()
+
+	frame := buildFrame(t, 10)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		exporter.Observe(frame, true)
+		exporter.mu.Lock()
+		sampled += len(exporter.pending)
+		exporter.pending = nil
+		exporter.mu.Unlock()
+	}
+
+	if sampled < 50 || sampled > 200 {
+		t.Errorf("Expected about 100 of 1000 frames sampled at 1-in-10, got %d", sampled)
+	}
+}
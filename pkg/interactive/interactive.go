@@ -3,6 +3,7 @@ package interactive
 
 import (
 	"fmt"
+	"runtime"
 	"sort"
 	"strings"
 	"time"
@@ -68,6 +69,47 @@ type stackStatsSnapshot struct {
 	ICMPReplies     uint64
 	DNSQueries      uint64
 	DHCPRequests    uint64
+	SNMPQueries     uint64
+	Taken           time.Time // When the counters were read
+}
+
+// stackStatsRates holds per-second rates between two stats snapshots
+type stackStatsRates struct {
+	PacketsReceived float64
+	PacketsSent     float64
+	ARP             float64 // Requests and replies
+	ICMP            float64 // Requests and replies
+	DNS             float64
+	DHCP            float64
+	SNMP            float64
+}
+
+// computeRates returns the per-second rates between prev and cur. Rates are
+// zero without an earlier snapshot, and for counters that went down because
+// the stats were reset.
+func computeRates(prev, cur stackStatsSnapshot) stackStatsRates {
+	if prev.Taken.IsZero() {
+		return stackStatsRates{}
+	}
+	elapsed := cur.Taken.Sub(prev.Taken).Seconds()
+	if elapsed <= 0 {
+		return stackStatsRates{}
+	}
+	rate := func(prev, cur uint64) float64 {
+		if cur < prev {
+			return 0
+		}
+		return float64(cur-prev) / elapsed
+	}
+	return stackStatsRates{
+		PacketsReceived: rate(prev.PacketsReceived, cur.PacketsReceived),
+		PacketsSent:     rate(prev.PacketsSent, cur.PacketsSent),
+		ARP:             rate(prev.ARPRequests+prev.ARPReplies, cur.ARPRequests+cur.ARPReplies),
+		ICMP:            rate(prev.ICMPRequests+prev.ICMPReplies, cur.ICMPRequests+cur.ICMPReplies),
+		DNS:             rate(prev.DNSQueries, cur.DNSQueries),
+		DHCP:            rate(prev.DHCPRequests, cur.DHCPRequests),
+		SNMP:            rate(prev.SNMPQueries, cur.SNMPQueries),
+	}
 }
 
 type model struct {
@@ -101,6 +143,9 @@ type model struct {
 
 	// Stats
 	stackStats      stackStatsSnapshot
+	rates           stackStatsRates
+	heapAlloc       uint64 // Bytes of allocated heap objects
+	memorySys       uint64 // Bytes obtained from the OS
 	packetsInjected int
 	errorsActive    int
 	uptime          time.Duration
@@ -367,19 +412,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tickMsg:
 		m.uptime = time.Since(m.startTime)
 		m.errorsActive = len(m.stateManager.GetAllStates())
-		m.refreshStats()
+		m.refreshStats(time.Time(msg))
 		return m, tickCmd()
 	}
 
 	return m, nil
 }
 
-func (m *model) refreshStats() {
+// refreshStats reads the stack's counters and the process memory, and computes
+// rates against the previous read
+func (m *model) refreshStats(now time.Time) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	m.heapAlloc = mem.HeapAlloc
+	m.memorySys = mem.Sys
+
 	if m.stack == nil {
 		return
 	}
 	stats := m.stack.GetStats()
-	m.stackStats = stackStatsSnapshot{
+	cur := stackStatsSnapshot{
 		PacketsReceived: stats.PacketsReceived,
 		PacketsSent:     stats.PacketsSent,
 		ARPRequests:     stats.ARPRequests,
@@ -388,7 +440,11 @@ func (m *model) refreshStats() {
 		ICMPReplies:     stats.ICMPReplies,
 		DNSQueries:      stats.DNSQueries,
 		DHCPRequests:    stats.DHCPRequests,
+		SNMPQueries:     stats.SNMPQueries,
+		Taken:           now,
 	}
+	m.rates = computeRates(m.stackStats, cur)
+	m.stackStats = cur
 	m.neighbors = m.stack.GetNeighbors()
 }
 
@@ -433,6 +489,8 @@ func (m *model) handleMenuSelection() {
 		m.promptForValue(errors.ErrorTypeMemory, "Enter memory percentage (0-100): ")
 	case strings.Contains(selection, "High Disk"):
 		m.promptForValue(errors.ErrorTypeDisk, "Enter disk percentage (0-100): ")
+	case strings.Contains(selection, "Runt Frames"):
+		m.promptForValue(errors.ErrorTypeRunts, "Enter runt frames per second (0-100): ")
 	case strings.Contains(selection, "Clear All"):
 		m.stateManager.ClearAll()
 		m.statusMessage = successStyle.Render("✓ All errors cleared")
@@ -552,8 +610,10 @@ func (m model) View() string {
 	if len(m.cfg.Devices) > 0 && m.selectedDeviceIdx >= 0 && m.selectedDeviceIdx < len(m.cfg.Devices) {
 		selectedDeviceName = m.cfg.Devices[m.selectedDeviceIdx].Name
 	}
-	stats := fmt.Sprintf("Uptime: %s  |  Debug: %d (%s)  |  Selected Device: %s  |  Errors Active: %d  |  Injected: %d",
+	stats := fmt.Sprintf("Uptime: %s  |  RX/TX: %.0f/%.0f pkt/s  |  Debug: %d (%s)  |  Selected Device: %s  |  Errors Active: %d  |  Injected: %d",
 		formatDuration(m.uptime),
+		m.rates.PacketsReceived,
+		m.rates.PacketsSent,
 		m.debugLevel,
 		getDebugLevelName(m.debugLevel),
 		selectedDeviceName,
@@ -780,6 +840,11 @@ func fitColumn(text string, width int) string {
 	return fmt.Sprintf("%-*s", width, text)
 }
 
+// formatBytes renders a byte count in MB with one decimal
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
+
 func formatRelativeTime(ts time.Time) string {
 	if ts.IsZero() {
 		return "never"
@@ -932,6 +997,9 @@ func (m model) renderStatistics() string {
 	stats.WriteString("║                                                                  ║\n")
 	stats.WriteString(fmt.Sprintf("║ Total Packets:       %-10d                                    ║\n", totalPackets))
 	stats.WriteString(fmt.Sprintf("║ RX / TX Packets:     %-10d / %-10d                       ║\n", m.stackStats.PacketsReceived, m.stackStats.PacketsSent))
+	stats.WriteString(fmt.Sprintf("║ RX / TX Rate:        %-43s ║\n", fmt.Sprintf("%.1f / %.1f pkt/s", m.rates.PacketsReceived, m.rates.PacketsSent)))
+	stats.WriteString(fmt.Sprintf("║ Protocol Rates:      %-43s ║\n", fmt.Sprintf("ARP %.0f  ICMP %.0f  DNS %.0f  DHCP %.0f  SNMP %.0f",
+		m.rates.ARP, m.rates.ICMP, m.rates.DNS, m.rates.DHCP, m.rates.SNMP)))
 	stats.WriteString(fmt.Sprintf("║ ARP Req / Rep:       %-10d / %-10d                       ║\n", m.stackStats.ARPRequests, m.stackStats.ARPReplies))
 	stats.WriteString(fmt.Sprintf("║ ICMP Req / Rep:      %-10d / %-10d                       ║\n", m.stackStats.ICMPRequests, m.stackStats.ICMPReplies))
 	stats.WriteString(fmt.Sprintf("║ DNS Queries:         %-10d                                    ║\n", m.stackStats.DNSQueries))
@@ -948,6 +1016,7 @@ func (m model) renderStatistics() string {
 		}
 	}
 	stats.WriteString(fmt.Sprintf("║ SNMP Devices:        %-10d                                    ║\n", snmpCount))
+	stats.WriteString(fmt.Sprintf("║ Memory:              %-43s ║\n", fmt.Sprintf("%s heap / %s from OS", formatBytes(m.heapAlloc), formatBytes(m.memorySys))))
 	stats.WriteString("║                                                                  ║\n")
 	stats.WriteString(fmt.Sprintf("║ Start Time:          %s                                    ║\n", m.startTime.Format("15:04:05")))
 	stats.WriteString("╚══════════════════════════════════════════════════════════════════╝")
@@ -1164,8 +1233,9 @@ func Run(interfaceName string, cfg *config.Config, debugConfig *logging.DebugCon
 		"5. Inject High CPU (custom value)",
 		"6. Inject High Memory (custom value)",
 		"7. Inject High Disk (custom value)",
-		"8. Clear All Errors",
-		"9. Exit Menu",
+		"8. Inject Runt Frames (custom value)",
+		"9. Clear All Errors",
+		"10. Exit Menu",
 	}
 
 	// Create model
@@ -1183,7 +1253,7 @@ func Run(interfaceName string, cfg *config.Config, debugConfig *logging.DebugCon
 	}
 
 	if stack != nil {
-		m.refreshStats()
+		m.refreshStats(time.Now())
 	}
 
 	// Add initial log entry
@@ -609,6 +609,42 @@ func TestToggleNeighborView_WithNeighbors(t *testing.T) {
 	}
 }
 
+// TestComputeRates tests that rates are computed from two successive stats
+// snapshots
+func TestComputeRates(t *testing.T) {
+	start := time.Now()
+	prev := stackStatsSnapshot{PacketsReceived: 100, PacketsSent: 50, ARPRequests: 4, ARPReplies: 4, DNSQueries: 10, Taken: start}
+	cur := stackStatsSnapshot{PacketsReceived: 300, PacketsSent: 90, ARPRequests: 8, ARPReplies: 8, DNSQueries: 10, SNMPQueries: 6, Taken: start.Add(2 * time.Second)}
+
+	want := stackStatsRates{PacketsReceived: 100, PacketsSent: 20, ARP: 4, SNMP: 3}
+	if got := computeRates(prev, cur); got != want {
+		t.Errorf("computeRates = %+v, want %+v", got, want)
+	}
+
+	// No rates from the first snapshot, or across a stats reset
+	if got := computeRates(stackStatsSnapshot{}, cur); got != (stackStatsRates{}) {
+		t.Errorf("computeRates without a previous snapshot = %+v, want zero", got)
+	}
+	reset := stackStatsSnapshot{PacketsReceived: 10, Taken: start.Add(3 * time.Second)}
+	if got := computeRates(cur, reset); got.PacketsReceived != 0 {
+		t.Errorf("RX rate after a reset = %v, want 0", got.PacketsReceived)
+	}
+
+	m := createTestModel()
+	m.rates = want
+	m.heapAlloc = 3 * 1024 * 1024
+	stats := m.renderStatistics()
+	if !strings.Contains(stats, "100.0 / 20.0 pkt/s") {
+		t.Error("Stats should show RX/TX rates")
+	}
+	if !strings.Contains(stats, "ARP 4  ICMP 0  DNS 0  DHCP 0  SNMP 3") {
+		t.Error("Stats should show per-protocol rates")
+	}
+	if !strings.Contains(stats, "3.0 MB heap") {
+		t.Error("Stats should show memory in use")
+	}
+}
+
 // TestModel_RenderStatistics tests statistics rendering
 func TestModel_RenderStatistics(t *testing.T) {
 	m := createTestModel()
@@ -96,3 +96,17 @@ const (
 	ProtocolFDP     = "FDP"
 	ProtocolSNMP    = "SNMP"
 )
+
+// Debug level range accepted for the global and per-protocol levels
+const (
+	MinDebugLevel = 0
+	MaxDebugLevel = 3
+)
+
+// Protocols lists every protocol that can have its own debug level
+var Protocols = []string{
+	ProtocolARP, ProtocolIP, ProtocolICMP, ProtocolIPv6, ProtocolICMPv6,
+	ProtocolUDP, ProtocolTCP, ProtocolDNS, ProtocolDHCP, ProtocolDHCPv6,
+	ProtocolHTTP, ProtocolFTP, ProtocolNetBIOS, ProtocolSTP, ProtocolLLDP,
+	ProtocolCDP, ProtocolEDP, ProtocolFDP, ProtocolSNMP,
+}
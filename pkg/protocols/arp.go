@@ -4,6 +4,7 @@ package protocols
 import (
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -60,6 +61,9 @@ func (h *ARPHandler) HandlePacket(pkt *Packet) {
 		return
 	}
 
+	// Requests and replies both reveal the sender's binding
+	h.stack.learnNeighbor(net.IP(arp.SourceProtAddress), net.HardwareAddr(arp.SourceHwAddress))
+
 	if arp.Operation == layers.ARPRequest {
 		h.handleARPRequest(pkt, arp)
 	} else if arp.Operation == layers.ARPReply {
@@ -87,8 +91,11 @@ func (h *ARPHandler) handleARPRequest(pkt *Packet, arp *layers.ARP) {
 			targetIP, sourceIP, sourceMAC, pkt.SerialNumber)
 	}
 
-	// Look up devices with this IP (considering VLAN)
+	// Look up devices with this IP (considering VLAN), falling back to proxy ARP
 	devices := h.stack.GetDevices().GetByIP(targetIP)
+	if len(devices) == 0 {
+		devices = h.proxyDevices(targetIP, sourceIP)
+	}
 	if len(devices) == 0 {
 		if debugLevel >= 3 {
 			fmt.Printf("ARP Request: No device found for IP %s\n", targetIP)
@@ -105,11 +112,19 @@ func (h *ARPHandler) handleARPRequest(pkt *Packet, arp *layers.ARP) {
 			continue
 		}
 
+		// Only devices on the segment of the ingress interface answer
+		if !h.stack.reachesDevice(pkt, device) {
+			if debugLevel >= 3 {
+				fmt.Printf("ARP Request for %s on %s: device %s is on another segment, not replying\n",
+					targetIP, pkt.Interface, device.Name)
+			}
+			continue
+		}
+
 		// Create ARP reply
 		reply := h.buildARPReply(device.MACAddress, targetIP, sourceMAC, sourceIP)
 		if reply != nil {
-			h.stack.Send(reply)
-			h.stack.IncrementStat("arp_replies")
+			h.sendReply(device, reply)
 
 			if debugLevel >= 3 {
 				fmt.Printf("ARP Reply: %s is at %s (device: %s) sn=%d\n",
@@ -119,6 +134,38 @@ func (h *ARPHandler) handleARPRequest(pkt *Packet, arp *layers.ARP) {
 	}
 }
 
+// proxyDevices returns devices configured to proxy ARP for targetIP. Requests
+// where the sender probes its own address (gratuitous ARP, duplicate address
+// detection) are never proxied.
+func (h *ARPHandler) proxyDevices(targetIP, sourceIP net.IP) []*config.Device {
+	if targetIP.Equal(sourceIP) {
+		return nil
+	}
+
+	var devices []*config.Device
+	for _, device := range h.stack.GetDevices().GetAll() {
+		if device.ARPConfig.ProxiesFor(targetIP) {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// sendReply queues an ARP reply, honoring the device's configured reply delay.
+func (h *ARPHandler) sendReply(device *config.Device, reply *Packet) {
+	if device.ARPConfig == nil || device.ARPConfig.ReplyDelayMs <= 0 {
+		h.stack.Send(reply)
+		h.stack.IncrementStat("arp_replies")
+		return
+	}
+
+	delay := time.Duration(device.ARPConfig.ReplyDelayMs) * time.Millisecond
+	time.AfterFunc(delay, func() {
+		h.stack.Send(reply)
+		h.stack.IncrementStat("arp_replies")
+	})
+}
+
 // buildARPReply constructs an ARP reply packet
 func (h *ARPHandler) buildARPReply(senderMAC net.HardwareAddr, senderIP net.IP, targetMAC net.HardwareAddr, targetIP net.IP) *Packet {
 	// Build Ethernet header
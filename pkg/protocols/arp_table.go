@@ -0,0 +1,239 @@
+package protocols
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+)
+
+// IP-MIB (RFC 4293) address translation tables served from the ARP/ND table
+const (
+	OIDIPNetToMediaTable          = "1.3.6.1.2.1.4.22"
+	OIDIPNetToMediaIfIndex        = "1.3.6.1.2.1.4.22.1.1"
+	OIDIPNetToMediaPhysAddress    = "1.3.6.1.2.1.4.22.1.2"
+	OIDIPNetToMediaNetAddress     = "1.3.6.1.2.1.4.22.1.3"
+	OIDIPNetToMediaType           = "1.3.6.1.2.1.4.22.1.4"
+	OIDIPNetToPhysicalTable       = "1.3.6.1.2.1.4.35"
+	OIDIPNetToPhysicalPhysAddress = "1.3.6.1.2.1.4.35.1.4"
+	OIDIPNetToPhysicalType        = "1.3.6.1.2.1.4.35.1.6"
+	OIDIPNetToPhysicalState       = "1.3.6.1.2.1.4.35.1.7"
+	OIDIPNetToPhysicalRowStatus   = "1.3.6.1.2.1.4.35.1.8"
+)
+
+const (
+	// arpMaxEntries caps learned bindings so an ARP flood cannot exhaust
+	// memory; further new addresses are ignored until entries age out
+	arpMaxEntries = 8192
+
+	// arpEntryMaxAge is how long a binding is kept after it was last seen
+	// (the common router ARP timeout)
+	arpEntryMaxAge = 4 * time.Hour
+
+	// arpIfIndex is the interface every neighbor is reached through: the
+	// simulator sees the network through one capture interface
+	arpIfIndex = 1
+
+	ipNetToMediaTypeDynamic     = 3
+	ipNetToMediaTypeStatic      = 4
+	ipNetToPhysicalTypeDynamic  = 3
+	ipNetToPhysicalTypeLocal    = 5
+	ipNetToPhysicalStateReach   = 1
+	ipNetToPhysicalStateUnknown = 6 // IPv4 has no neighbor unreachability detection
+	inetAddressTypeIPv4         = 1
+	inetAddressTypeIPv6         = 2
+	rowStatusActive             = 1
+)
+
+// ARP entry types
+const (
+	ARPEntryDynamic = "dynamic" // Learned from ARP or neighbor discovery traffic
+	ARPEntryStatic  = "static"  // A simulated device's own address
+)
+
+// ARPEntry is an IP-to-MAC binding known on the simulated segment.
+type ARPEntry struct {
+	IP       net.IP
+	MAC      net.HardwareAddr
+	Type     string    // ARPEntryDynamic or ARPEntryStatic
+	Device   string    // Simulated device owning a static entry
+	LastSeen time.Time // When a dynamic entry was last learned
+}
+
+// arpTable is the IPv4 and IPv6 neighbors learned from ARP and neighbor
+// discovery traffic, shared by every simulated device.
+type arpTable struct {
+	mu      sync.Mutex
+	entries map[string]*ARPEntry // IP -> binding
+}
+
+func newARPTable() *arpTable {
+	return &arpTable{entries: make(map[string]*ARPEntry)}
+}
+
+// learn records that ip is reachable at mac. Unspecified addresses (address
+// probes) and multicast MACs are ignored.
+func (t *arpTable) learn(ip net.IP, mac net.HardwareAddr, now time.Time) {
+	if ip == nil || ip.IsUnspecified() || ip.IsMulticast() || len(mac) != 6 || mac[0]&0x01 != 0 {
+		return
+	}
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := ip.String()
+	if entry, ok := t.entries[key]; ok {
+		entry.MAC = append(entry.MAC[:0], mac...)
+		entry.LastSeen = now
+		return
+	}
+	if len(t.entries) >= arpMaxEntries {
+		t.expireLocked(now)
+		if len(t.entries) >= arpMaxEntries {
+			return
+		}
+	}
+	t.entries[key] = &ARPEntry{
+		IP:       append(net.IP(nil), ip...),
+		MAC:      append(net.HardwareAddr(nil), mac...),
+		Type:     ARPEntryDynamic,
+		LastSeen: now,
+	}
+}
+
+// expireLocked drops entries not seen within arpEntryMaxAge.
+func (t *arpTable) expireLocked(now time.Time) {
+	for key, entry := range t.entries {
+		if now.Sub(entry.LastSeen) >= arpEntryMaxAge {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// list returns copies of the live entries.
+func (t *arpTable) list(now time.Time) []ARPEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expireLocked(now)
+
+	entries := make([]ARPEntry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		copied := *entry
+		copied.MAC = append(net.HardwareAddr(nil), entry.MAC...)
+		entries = append(entries, copied)
+	}
+	return entries
+}
+
+// learnNeighbor feeds a sender binding from ARP or neighbor discovery to the
+// ARP table. Bindings of simulated devices are reported as static entries
+// instead.
+func (s *Stack) learnNeighbor(ip net.IP, mac net.HardwareAddr) {
+	if s.arp == nil || s.devices.GetByMAC(mac) != nil {
+		return
+	}
+	s.arp.learn(ip, mac, time.Now())
+}
+
+// ARPEntries returns the ARP/ND table as device sees it: the learned
+// neighbors plus its own addresses as static entries. A nil device returns
+// the learned neighbors plus every simulated device's addresses. Entries are
+// sorted by IP.
+func (s *Stack) ARPEntries(device *config.Device) []ARPEntry {
+	var entries []ARPEntry
+	if s.arp != nil {
+		entries = s.arp.list(time.Now())
+	}
+
+	devices := []*config.Device{device}
+	if device == nil {
+		devices = s.devices.GetAll()
+	}
+	for _, d := range devices {
+		if len(d.MACAddress) != 6 {
+			continue
+		}
+		for _, ip := range d.IPAddresses {
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			entries = append(entries, ARPEntry{IP: ip, MAC: d.MACAddress, Type: ARPEntryStatic, Device: d.Name})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if c := bytes.Compare(entries[i].IP, entries[j].IP); c != 0 {
+			return c < 0
+		}
+		return entries[i].Device < entries[j].Device
+	})
+	return entries
+}
+
+// registerIPNetToMedia serves the device's ARP/ND table as the IP-MIB
+// ipNetToMediaTable (IPv4) and ipNetToPhysicalTable (IPv4 and IPv6).
+func (s *Stack) registerIPNetToMedia(agent *snmp.Agent, device *config.Device) {
+	agent.RegisterComputedTable(OIDIPNetToMediaTable, func() []snmp.OIDResult {
+		var results []snmp.OIDResult
+		for _, entry := range s.ARPEntries(device) {
+			v4 := entry.IP.To4()
+			if v4 == nil {
+				continue
+			}
+			entryType := ipNetToMediaTypeDynamic
+			if entry.Type == ARPEntryStatic {
+				entryType = ipNetToMediaTypeStatic
+			}
+			index := fmt.Sprintf(".%d.%s", arpIfIndex, v4)
+			results = append(results,
+				snmp.OIDResult{OID: OIDIPNetToMediaIfIndex + index, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: arpIfIndex}},
+				snmp.OIDResult{OID: OIDIPNetToMediaPhysAddress + index, Value: &snmp.OIDValue{Type: gosnmp.OctetString, Value: string(entry.MAC)}},
+				snmp.OIDResult{OID: OIDIPNetToMediaNetAddress + index, Value: &snmp.OIDValue{Type: gosnmp.IPAddress, Value: v4.String()}},
+				snmp.OIDResult{OID: OIDIPNetToMediaType + index, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: entryType}},
+			)
+		}
+		return results
+	})
+
+	agent.RegisterComputedTable(OIDIPNetToPhysicalTable, func() []snmp.OIDResult {
+		var results []snmp.OIDResult
+		for _, entry := range s.ARPEntries(device) {
+			addrType, state := inetAddressTypeIPv6, ipNetToPhysicalStateReach
+			addr := entry.IP.To16()
+			if v4 := entry.IP.To4(); v4 != nil {
+				addrType, state, addr = inetAddressTypeIPv4, ipNetToPhysicalStateUnknown, v4
+			}
+			entryType := ipNetToPhysicalTypeDynamic
+			if entry.Type == ARPEntryStatic {
+				entryType = ipNetToPhysicalTypeLocal
+			}
+			index := fmt.Sprintf(".%d.%d%s", arpIfIndex, addrType, inetAddressIndex(addr))
+			results = append(results,
+				snmp.OIDResult{OID: OIDIPNetToPhysicalPhysAddress + index, Value: &snmp.OIDValue{Type: gosnmp.OctetString, Value: string(entry.MAC)}},
+				snmp.OIDResult{OID: OIDIPNetToPhysicalType + index, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: entryType}},
+				snmp.OIDResult{OID: OIDIPNetToPhysicalState + index, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: state}},
+				snmp.OIDResult{OID: OIDIPNetToPhysicalRowStatus + index, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: rowStatusActive}},
+			)
+		}
+		return results
+	})
+}
+
+// inetAddressIndex returns the index suffix of a non-IMPLIED InetAddress:
+// its length followed by each octet.
+func inetAddressIndex(addr net.IP) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, ".%d", len(addr))
+	for _, octet := range addr {
+		fmt.Fprintf(&b, ".%d", octet)
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package protocols
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+func TestIPNetToMediaTable_LearnedARPEntry(t *testing.T) {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "router1",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1").To4()},
+		SNMPConfig:  config.SNMPConfig{Community: "public"},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	device := &cfg.Devices[0]
+
+	// A host on the wire resolves the router's address
+	host := net.HardwareAddr{0x00, 0xAA, 0xBB, 0xCC, 0xDD, 0x05}
+	hostIP := net.ParseIP("10.0.0.50").To4()
+	eth := &layers.Ethernet{SrcMAC: host, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeARP}
+	arp := &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   host,
+		SourceProtAddress: hostIP,
+		DstHwAddress:      make([]byte, 6),
+		DstProtAddress:    device.IPAddresses[0],
+	}
+	buffer := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{}, eth, arp); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	pkt, err := ParsePacket(buffer.Bytes(), 1)
+	if err != nil {
+		t.Fatalf("ParsePacket: %v", err)
+	}
+	stack.decodePacket(pkt)
+
+	// Computed tables are rebuilt at most once a second; a fresh agent
+	// builds them immediately
+	stack.initSNMPAgent(device)
+	agent := stack.getSNMPAgent(device)
+
+	// Walk ipNetToMediaPhysAddress: the learned host plus the router's own
+	// address, in index order
+	var rows []string
+	for oid, value, err := agent.HandleGetNext(OIDIPNetToMediaPhysAddress); err == nil && strings.HasPrefix(oid, OIDIPNetToMediaPhysAddress+"."); oid, value, err = agent.HandleGetNext(oid) {
+		rows = append(rows, strings.TrimPrefix(oid, OIDIPNetToMediaPhysAddress)+"="+net.HardwareAddr(value.Value.(string)).String())
+	}
+	want := []string{".1.10.0.0.1=" + device.MACAddress.String(), ".1.10.0.0.50=" + host.String()}
+	if strings.Join(rows, " ") != strings.Join(want, " ") {
+		t.Fatalf("ipNetToMediaPhysAddress walk = %v, want %v", rows, want)
+	}
+
+	entryType, err := agent.HandleGet(OIDIPNetToMediaType + ".1.10.0.0.50")
+	if err != nil || entryType.Value != ipNetToMediaTypeDynamic {
+		t.Errorf("ipNetToMediaType = %v (%v), want dynamic(3)", entryType, err)
+	}
+	address, err := agent.HandleGet(OIDIPNetToMediaNetAddress + ".1.10.0.0.50")
+	if err != nil || address.Value != hostIP.String() {
+		t.Errorf("ipNetToMediaNetAddress = %v (%v), want %s", address, err, hostIP)
+	}
+	physical, err := agent.HandleGet(OIDIPNetToPhysicalType + ".1.1.4.10.0.0.50")
+	if err != nil || physical.Value != ipNetToPhysicalTypeDynamic {
+		t.Errorf("ipNetToPhysicalType = %v (%v), want dynamic(3)", physical, err)
+	}
+
+	// The API view matches the MIB
+	entries := stack.ARPEntries(device)
+	if len(entries) != 2 || !entries[1].IP.Equal(hostIP) || entries[1].Type != ARPEntryDynamic {
+		t.Errorf("ARPEntries = %+v, want the router's static entry and the learned host", entries)
+	}
+}
+
+func TestARPTable_AgesOutAndIgnoresProbes(t *testing.T) {
+	table := newARPTable()
+	mac := net.HardwareAddr{0x00, 0xAA, 0xBB, 0xCC, 0xDD, 0x05}
+	now := time.Now()
+
+	table.learn(net.IPv4zero, mac, now)                                                 // address probe
+	table.learn(net.ParseIP("10.0.0.9"), net.HardwareAddr{0x01, 0, 0x5e, 0, 0, 1}, now) // multicast MAC
+	table.learn(net.ParseIP("fe80::1"), mac, now)
+	if entries := table.list(now); len(entries) != 1 || !entries[0].IP.Equal(net.ParseIP("fe80::1")) {
+		t.Fatalf("list = %+v, want only fe80::1", entries)
+	}
+	if entries := table.list(now.Add(arpEntryMaxAge)); len(entries) != 0 {
+		t.Errorf("list after %v = %+v, want the entry aged out", arpEntryMaxAge, entries)
+	}
+}
@@ -3,6 +3,7 @@ package protocols
 import (
 	"net"
 	"testing"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -355,3 +356,146 @@ func BenchmarkSendGratuitousARP(b *testing.B) {
 		handler.SendGratuitousARP(device)
 	}
 }
+
+// buildARPRequestPacket builds an ARP request asking who has targetIP
+func buildARPRequestPacket(t *testing.T, targetIP string) *Packet {
+	t.Helper()
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeARP,
+	}
+	arpLayer := &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   net.HardwareAddr{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+		SourceProtAddress: net.ParseIP("192.168.1.100").To4(),
+		DstHwAddress:      net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		DstProtAddress:    net.ParseIP(targetIP).To4(),
+	}
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, eth, arpLayer); err != nil {
+		t.Fatalf("Failed to build ARP request: %v", err)
+	}
+	return &Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes()), SerialNumber: 1}
+}
+
+// newProxyARPStack creates a stack with a single proxy-ARP device
+func newProxyARPStack(t *testing.T, delayMs int) (*Stack, net.HardwareAddr) {
+	t.Helper()
+
+	_, subnet, _ := net.ParseCIDR("10.20.0.0/24")
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66}
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name:        "proxy-router",
+				MACAddress:  mac,
+				IPAddresses: []net.IP{net.ParseIP("192.168.1.1")},
+				ARPConfig: &config.ARPConfig{
+					ProxyARPSubnets: []*net.IPNet{subnet},
+					ReplyDelayMs:    delayMs,
+				},
+			},
+		},
+	}
+	return NewStack(nil, cfg, logging.NewDebugConfig(0)), mac
+}
+
+// TestHandleARPRequest_ProxyARP tests that addresses in a proxy subnet are answered with the device MAC
+func TestHandleARPRequest_ProxyARP(t *testing.T) {
+	stack, mac := newProxyARPStack(t, 0)
+	handler := NewARPHandler(stack)
+
+	handler.HandlePacket(buildARPRequestPacket(t, "10.20.0.42"))
+
+	select {
+	case reply := <-stack.sendQueue:
+		packet := gopacket.NewPacket(reply.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		arp, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP)
+		if !ok {
+			t.Fatal("Expected ARP layer in reply")
+		}
+		if net.HardwareAddr(arp.SourceHwAddress).String() != mac.String() {
+			t.Errorf("Expected proxy reply from %s, got %s", mac, net.HardwareAddr(arp.SourceHwAddress))
+		}
+		if !net.IP(arp.SourceProtAddress).Equal(net.ParseIP("10.20.0.42")) {
+			t.Errorf("Expected reply for 10.20.0.42, got %s", net.IP(arp.SourceProtAddress))
+		}
+	default:
+		t.Fatal("Expected proxy ARP reply to be queued")
+	}
+
+	// Addresses outside the proxy subnet are ignored
+	handler.HandlePacket(buildARPRequestPacket(t, "10.30.0.42"))
+	select {
+	case <-stack.sendQueue:
+		t.Error("Expected no reply for address outside proxy subnet")
+	default:
+	}
+}
+
+// TestHandleARPRequest_InterfaceScope tests that a device only answers ARP
+// arriving on the capture interface whose subnet holds its address
+func TestHandleARPRequest_InterfaceScope(t *testing.T) {
+	_, segment0, _ := net.ParseCIDR("10.0.0.0/24")
+	_, segment1, _ := net.ParseCIDR("10.0.1.0/24")
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{Name: "sw0", MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}},
+			{Name: "sw1", MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x02}, IPAddresses: []net.IP{net.ParseIP("10.0.1.1")}},
+		},
+		InterfaceScope: config.InterfaceScope{
+			"eth0": {segment0},
+			"eth1": {segment1},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewARPHandler(stack)
+
+	request := func(targetIP, iface string) int {
+		pkt := buildARPRequestPacket(t, targetIP)
+		pkt.Interface = iface
+		handler.HandlePacket(pkt)
+		return len(drainSendQueue(stack))
+	}
+
+	if got := request("10.0.1.1", "eth0"); got != 0 {
+		t.Errorf("sw1 sent %d replies to a request on eth0, want 0 (wrong segment)", got)
+	}
+	if got := request("10.0.1.1", "eth1"); got != 1 {
+		t.Errorf("sw1 sent %d replies to a request on eth1, want 1", got)
+	}
+	if got := request("10.0.0.1", "eth0"); got != 1 {
+		t.Errorf("sw0 sent %d replies to a request on eth0, want 1", got)
+	}
+	// Interfaces without a scope reach every device
+	if got := request("10.0.1.1", "eth2"); got != 1 {
+		t.Errorf("sw1 sent %d replies to a request on unscoped eth2, want 1", got)
+	}
+}
+
+// TestHandleARPRequest_ReplyDelay tests that ARP replies honor the configured delay
+func TestHandleARPRequest_ReplyDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	stack, _ := newProxyARPStack(t, int(delay/time.Millisecond))
+	handler := NewARPHandler(stack)
+
+	start := time.Now()
+	handler.HandlePacket(buildARPRequestPacket(t, "192.168.1.1"))
+
+	select {
+	case <-stack.sendQueue:
+		if elapsed := time.Since(start); elapsed < delay {
+			t.Errorf("Expected reply after at least %v, got %v", delay, elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected delayed ARP reply")
+	}
+}
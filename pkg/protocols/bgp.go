@@ -0,0 +1,425 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// BGP message types (RFC 4271 section 4.1)
+const (
+	BGPMessageOpen         = 1
+	BGPMessageUpdate       = 2
+	BGPMessageNotification = 3
+	BGPMessageKeepalive    = 4
+)
+
+// BGP NOTIFICATION error codes (RFC 4271 section 4.5)
+const (
+	BGPErrorMessageHeader = 1
+	BGPErrorOpenMessage   = 2
+	BGPErrorHoldTimer     = 4
+	BGPErrorFSM           = 5
+)
+
+const (
+	bgpVersion        = 4
+	bgpHeaderLen      = 19
+	bgpMaxMessageLen  = 4096
+	bgpASTrans        = 23456 // My AS of speakers with a 4-octet AS (RFC 6793)
+	bgpCapFourOctetAS = 65
+
+	// OPEN error subcodes
+	bgpUnsupportedVersion = 1
+	bgpUnacceptableHold   = 6
+
+	// bgpOpenHoldTime bounds the wait for the peer's OPEN (RFC 4271 suggests
+	// a large value such as 4 minutes)
+	bgpOpenHoldTime = 4 * time.Minute
+
+	// maxBGPSessions caps the BGP connections tracked across devices. SYNs
+	// beyond it are dropped, like a full listen backlog.
+	maxBGPSessions = 1024
+
+	// bgpTimerInterval is how often hold and keepalive timers are checked
+	bgpTimerInterval = time.Second
+)
+
+// bgpState is the state of a BGP session (RFC 4271 section 8.2.2). Connect
+// stands for a TCP handshake in progress.
+type bgpState int
+
+const (
+	bgpConnect bgpState = iota
+	bgpOpenSent
+	bgpOpenConfirm
+	bgpEstablished
+)
+
+func (s bgpState) String() string {
+	switch s {
+	case bgpConnect:
+		return "Connect"
+	case bgpOpenSent:
+		return "OpenSent"
+	case bgpOpenConfirm:
+		return "OpenConfirm"
+	default:
+		return "Established"
+	}
+}
+
+// bgpSession is a BGP connection accepted by a simulated device, with the TCP
+// sequence state needed to send KEEPALIVEs on it unprompted
+type bgpSession struct {
+	device   *config.Device
+	localIP  net.IP
+	peerIP   net.IP
+	peerPort layers.TCPPort
+	peerMAC  net.HardwareAddr
+
+	iss    uint32 // Our initial sequence number
+	sndNxt uint32 // Next sequence number we send
+	rcvNxt uint32 // Next sequence number expected from the peer
+
+	state     bgpState
+	holdTime  time.Duration // Negotiated; 0 disables KEEPALIVEs and the hold timer
+	lastSent  time.Time     // Last BGP message sent
+	lastHeard time.Time     // Last segment received
+	pending   []byte        // Start of a message split across segments
+}
+
+// BGPHandler is a passive BGP speaker on TCP port 179. It accepts sessions
+// on devices with a bgp block, completes the OPEN and KEEPALIVE exchange and
+// keeps them up with KEEPALIVEs. UPDATEs are acknowledged and ignored; no
+// routes are advertised.
+type BGPHandler struct {
+	stack    *Stack
+	mu       sync.Mutex
+	sessions map[tcpConnKey]*bgpSession
+}
+
+// NewBGPHandler creates a new BGP handler
+func NewBGPHandler(stack *Stack) *BGPHandler {
+	return &BGPHandler{
+		stack:    stack,
+		sessions: make(map[tcpConnKey]*bgpSession),
+	}
+}
+
+// HandleSegment processes a TCP segment to port 179 from the client at
+// srcIP. It returns false when the device owning dstIP runs no BGP peer.
+func (h *BGPHandler) HandleSegment(pkt *Packet, srcIP, dstIP net.IP, tcp *layers.TCP, devices []*config.Device) bool {
+	device := serviceDevice(dstIP, devices)
+	if device == nil || device.BGP == nil {
+		return false
+	}
+
+	key := tcpConnKey{
+		device: device.Name,
+		client: net.JoinHostPort(srcIP.String(), fmt.Sprintf("%d", tcp.SrcPort)),
+		port:   tcp.DstPort,
+	}
+	now := time.Now()
+	debugLevel := h.stack.GetDebugLevel()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.sessions[key]
+	switch {
+	case tcp.RST:
+		delete(h.sessions, key)
+		return true
+	case tcp.SYN && !tcp.ACK:
+		if session == nil || session.state != bgpConnect {
+			if len(h.sessions) >= maxBGPSessions {
+				if debugLevel >= 2 {
+					fmt.Printf("BGP session table full: dropping SYN from %s to %s\n", key.client, device.Name)
+				}
+				return true
+			}
+			peerMAC := h.stack.tcpHandler.clientMAC(pkt, srcIP)
+			if peerMAC == nil {
+				return true
+			}
+			iss := tcpISN(tcp.Seq, now)
+			session = &bgpSession{
+				device:    device,
+				localIP:   dstIP,
+				peerIP:    srcIP,
+				peerPort:  tcp.SrcPort,
+				peerMAC:   peerMAC,
+				iss:       iss,
+				sndNxt:    iss + 1,
+				rcvNxt:    tcp.Seq + 1,
+				lastHeard: now,
+			}
+			h.sessions[key] = session
+		}
+		// A retransmitted SYN gets the same SYN-ACK
+		h.stack.tcpHandler.sendSegmentTo(device, session.peerMAC, srcIP, dstIP, &layers.TCP{
+			SrcPort: tcp.DstPort,
+			DstPort: tcp.SrcPort,
+			Seq:     session.iss,
+			Ack:     session.rcvNxt,
+			SYN:     true,
+			ACK:     true,
+			Window:  65535,
+			Options: []layers.TCPOption{tcpMSSOption(device, srcIP)},
+		}, nil)
+		return true
+	case session == nil:
+		// Not a connection we accepted: reset it
+		rst := &layers.TCP{SrcPort: tcp.DstPort, DstPort: tcp.SrcPort, RST: true}
+		if tcp.ACK {
+			rst.Seq = tcp.Ack
+		} else {
+			rst.ACK = true
+			rst.Ack = tcp.Seq + uint32(len(tcp.Payload))
+		}
+		h.stack.tcpHandler.sendSegment(pkt, device, srcIP, dstIP, rst)
+		return true
+	}
+
+	if session.state == bgpConnect {
+		if !tcp.ACK || tcp.Ack != session.iss+1 {
+			return true
+		}
+		// Handshake complete: both sides open with an OPEN (RFC 4271 8.2.2)
+		session.state = bgpOpenSent
+		h.send(session, buildBGPOpen(device.BGP), false, now)
+		if debugLevel >= 2 {
+			fmt.Printf("BGP connection from %s to %s: OPEN sent (AS %d, router ID %s)\n",
+				key.client, device.Name, device.BGP.AS, device.BGP.RouterID)
+		}
+	}
+	session.lastHeard = now
+
+	var reply []byte
+	if len(tcp.Payload) > 0 {
+		if tcp.Seq != session.rcvNxt {
+			// Retransmitted or out of order: repeat our ACK
+			h.send(session, nil, false, now)
+			return true
+		}
+		session.rcvNxt += uint32(len(tcp.Payload))
+		session.pending = append(session.pending, tcp.Payload...)
+		var closing bool
+		if reply, closing = h.receive(session, key); closing {
+			h.close(key, session, reply, now)
+			return true
+		}
+	}
+
+	if tcp.FIN {
+		if debugLevel >= 2 {
+			fmt.Printf("BGP peer %s closed its session with %s\n", key.client, device.Name)
+		}
+		session.rcvNxt++
+		h.close(key, session, reply, now)
+	} else if len(tcp.Payload) > 0 {
+		h.send(session, reply, false, now)
+	}
+	return true
+}
+
+// receive processes the complete messages in session.pending and returns
+// the messages to answer with. It reports true when the session must be
+// closed after sending them.
+func (h *BGPHandler) receive(session *bgpSession, key tcpConnKey) ([]byte, bool) {
+	debugLevel := h.stack.GetDebugLevel()
+	var reply []byte
+
+	for len(session.pending) >= bgpHeaderLen {
+		msg := session.pending
+		for _, b := range msg[:16] {
+			if b != 0xff {
+				return append(reply, buildBGPNotification(BGPErrorMessageHeader, 1, nil)...), true // Connection Not Synchronized
+			}
+		}
+		length := int(binary.BigEndian.Uint16(msg[16:18]))
+		if length < bgpHeaderLen || length > bgpMaxMessageLen {
+			return append(reply, buildBGPNotification(BGPErrorMessageHeader, 2, msg[16:18])...), true // Bad Message Length
+		}
+		if len(msg) < length {
+			break // Rest of the message still to come
+		}
+		msgType, body := msg[18], msg[bgpHeaderLen:length]
+		session.pending = session.pending[length:]
+
+		switch msgType {
+		case BGPMessageOpen:
+			if session.state != bgpOpenSent {
+				return append(reply, buildBGPNotification(BGPErrorFSM, 0, nil)...), true
+			}
+			if len(body) < 10 {
+				return append(reply, buildBGPNotification(BGPErrorMessageHeader, 2, msg[16:18])...), true
+			}
+			if body[0] != bgpVersion {
+				return append(reply, buildBGPNotification(BGPErrorOpenMessage, bgpUnsupportedVersion, []byte{0, bgpVersion})...), true
+			}
+			peerHold := time.Duration(binary.BigEndian.Uint16(body[3:5])) * time.Second
+			if peerHold > 0 && peerHold < 3*time.Second {
+				return append(reply, buildBGPNotification(BGPErrorOpenMessage, bgpUnacceptableHold, nil)...), true
+			}
+			session.holdTime = min(session.device.BGP.HoldTime, peerHold)
+			session.state = bgpOpenConfirm
+			reply = append(reply, buildBGPKeepalive()...)
+			if debugLevel >= 2 {
+				fmt.Printf("BGP OPEN from %s (AS %d, router ID %s) on %s: hold time %v\n",
+					key.client, binary.BigEndian.Uint16(body[1:3]), net.IP(body[5:9]), session.device.Name, session.holdTime)
+			}
+		case BGPMessageKeepalive:
+			if session.state == bgpOpenConfirm {
+				session.state = bgpEstablished
+				if debugLevel >= 1 {
+					fmt.Printf("BGP session established between %s and %s\n", session.device.Name, key.client)
+				}
+			} else if session.state != bgpEstablished {
+				return append(reply, buildBGPNotification(BGPErrorFSM, 0, nil)...), true
+			}
+		case BGPMessageUpdate:
+			// No routes are kept
+			if session.state != bgpEstablished {
+				return append(reply, buildBGPNotification(BGPErrorFSM, 0, nil)...), true
+			}
+		case BGPMessageNotification:
+			if debugLevel >= 2 && len(body) >= 2 {
+				fmt.Printf("BGP NOTIFICATION from %s to %s: error %d/%d\n", key.client, session.device.Name, body[0], body[1])
+			}
+			return reply, true
+		default:
+			return append(reply, buildBGPNotification(BGPErrorMessageHeader, 3, []byte{msgType})...), true // Bad Message Type
+		}
+	}
+	return reply, false
+}
+
+// send sends messages (or a bare ACK when empty) on session, with FIN when
+// fin is set
+func (h *BGPHandler) send(session *bgpSession, messages []byte, fin bool, now time.Time) {
+	segment := &layers.TCP{
+		SrcPort: TCPPortBGP,
+		DstPort: session.peerPort,
+		Seq:     session.sndNxt,
+		Ack:     session.rcvNxt,
+		ACK:     true,
+		PSH:     len(messages) > 0,
+		FIN:     fin,
+		Window:  65535,
+	}
+	session.sndNxt += uint32(len(messages))
+	if fin {
+		session.sndNxt++
+	}
+	if len(messages) > 0 {
+		session.lastSent = now
+	}
+	h.stack.tcpHandler.sendSegmentTo(session.device, session.peerMAC, session.peerIP, session.localIP, segment, messages)
+}
+
+// close sends the last messages with FIN and forgets the session. The
+// caller holds h.mu.
+func (h *BGPHandler) close(key tcpConnKey, session *bgpSession, messages []byte, now time.Time) {
+	h.send(session, messages, true, now)
+	delete(h.sessions, key)
+}
+
+// runTimers sends the KEEPALIVEs due at now and closes sessions whose hold
+// timer expired
+func (h *BGPHandler) runTimers(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, session := range h.sessions {
+		hold := session.holdTime
+		switch session.state {
+		case bgpConnect:
+			if now.Sub(session.lastHeard) > tcpConnectionIdleTimeout {
+				delete(h.sessions, key)
+			}
+			continue
+		case bgpOpenSent:
+			hold = bgpOpenHoldTime
+		}
+		if hold == 0 {
+			continue
+		}
+		if now.Sub(session.lastHeard) > hold {
+			if h.stack.GetDebugLevel() >= 1 {
+				fmt.Printf("BGP hold timer expired for %s on %s in %s\n", key.client, session.device.Name, session.state)
+			}
+			h.close(key, session, buildBGPNotification(BGPErrorHoldTimer, 0, nil), now)
+			continue
+		}
+		if session.state != bgpOpenSent && now.Sub(session.lastSent) >= hold/3 && h.stack.isDeviceUp(session.device.Name) {
+			h.send(session, buildBGPKeepalive(), false, now)
+		}
+	}
+}
+
+// startBGPTimerLoop runs the BGP keepalive and hold timers
+func (s *Stack) startBGPTimerLoop() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(bgpTimerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.bgpHandler.runTimers(now)
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// buildBGPMessage returns a BGP message of msgType with body
+func buildBGPMessage(msgType byte, body []byte) []byte {
+	msg := make([]byte, bgpHeaderLen, bgpHeaderLen+len(body))
+	for i := 0; i < 16; i++ {
+		msg[i] = 0xff // Marker
+	}
+	binary.BigEndian.PutUint16(msg[16:18], uint16(bgpHeaderLen+len(body)))
+	msg[18] = msgType
+	return append(msg, body...)
+}
+
+// buildBGPOpen returns the OPEN advertising cfg. The 4-octet AS capability
+// carries the full AS; My AS holds AS_TRANS when the AS needs four octets.
+func buildBGPOpen(cfg *config.BGPConfig) []byte {
+	myAS := uint16(bgpASTrans)
+	if cfg.AS <= 0xffff {
+		myAS = uint16(cfg.AS)
+	}
+
+	capability := []byte{bgpCapFourOctetAS, 4, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(capability[2:], cfg.AS)
+	params := append([]byte{2, byte(len(capability))}, capability...) // Capabilities parameter
+
+	body := make([]byte, 10, 10+len(params))
+	body[0] = bgpVersion
+	binary.BigEndian.PutUint16(body[1:3], myAS)
+	binary.BigEndian.PutUint16(body[3:5], uint16(cfg.HoldTime/time.Second))
+	copy(body[5:9], cfg.RouterID.To4())
+	body[9] = byte(len(params))
+	return buildBGPMessage(BGPMessageOpen, append(body, params...))
+}
+
+// buildBGPKeepalive returns a KEEPALIVE message
+func buildBGPKeepalive() []byte {
+	return buildBGPMessage(BGPMessageKeepalive, nil)
+}
+
+// buildBGPNotification returns a NOTIFICATION message
+func buildBGPNotification(code, subcode byte, data []byte) []byte {
+	return buildBGPMessage(BGPMessageNotification, append([]byte{code, subcode}, data...))
+}
@@ -0,0 +1,168 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+func newBGPStack(bgp *config.BGPConfig) *Stack {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "router",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x80},
+		IPAddresses: []net.IP{net.ParseIP("192.168.1.80"), net.ParseIP("2001:db8::80")},
+		BGP:         bgp,
+	}}}
+	return NewStack(nil, cfg, logging.NewDebugConfig(0))
+}
+
+// bgpMessages splits a segment payload into BGP messages, failing on
+// anything that is not a whole, well-formed message
+func bgpMessages(t *testing.T, payload []byte) [][]byte {
+	t.Helper()
+
+	var messages [][]byte
+	for len(payload) > 0 {
+		if len(payload) < bgpHeaderLen || !bytes.Equal(payload[:16], bytes.Repeat([]byte{0xff}, 16)) {
+			t.Fatalf("Malformed BGP message % x", payload)
+		}
+		length := int(binary.BigEndian.Uint16(payload[16:18]))
+		if length < bgpHeaderLen || length > len(payload) {
+			t.Fatalf("BGP message length %d with %d bytes left", length, len(payload))
+		}
+		messages = append(messages, payload[:length])
+		payload = payload[length:]
+	}
+	return messages
+}
+
+// TestBGP_PassivePeer tests that a connection to port 179 gets an OPEN with
+// the configured AS, hold time and router ID, and that the OPEN/KEEPALIVE
+// exchange establishes a session that is kept up with KEEPALIVEs
+func TestBGP_PassivePeer(t *testing.T) {
+	for _, dst := range []string{"192.168.1.80", "2001:db8::80"} {
+		stack := newBGPStack(&config.BGPConfig{AS: 65001, RouterID: net.ParseIP("10.255.0.1").To4(), HoldTime: 90 * time.Second})
+
+		sendClientSegment(t, stack, dst, TCPPortBGP, layers.TCP{Seq: 1000, SYN: true}, nil)
+		replies := tcpReplies(t, stack)
+		if len(replies) != 1 || !replies[0].SYN || !replies[0].ACK || replies[0].Ack != 1001 {
+			t.Fatalf("%s: replies to SYN = %+v, want one SYN-ACK ack=1001", dst, replies)
+		}
+		serverSeq := replies[0].Seq + 1
+
+		// The handshake ACK gets the device's OPEN
+		sendClientSegment(t, stack, dst, TCPPortBGP, layers.TCP{Seq: 1001, Ack: serverSeq, ACK: true}, nil)
+		replies = tcpReplies(t, stack)
+		if len(replies) != 1 || replies[0].Seq != serverSeq || replies[0].SrcPort != TCPPortBGP {
+			t.Fatalf("%s: replies to the handshake ACK = %+v, want one segment seq=%d from port 179", dst, replies, serverSeq)
+		}
+		messages := bgpMessages(t, replies[0].Payload)
+		if len(messages) != 1 || messages[0][18] != BGPMessageOpen {
+			t.Fatalf("%s: sent % x, want one OPEN", dst, replies[0].Payload)
+		}
+		open := messages[0][bgpHeaderLen:]
+		if open[0] != 4 {
+			t.Errorf("%s: OPEN version %d, want 4", dst, open[0])
+		}
+		if as := binary.BigEndian.Uint16(open[1:3]); as != 65001 {
+			t.Errorf("%s: OPEN My AS %d, want 65001", dst, as)
+		}
+		if hold := binary.BigEndian.Uint16(open[3:5]); hold != 90 {
+			t.Errorf("%s: OPEN hold time %d, want 90", dst, hold)
+		}
+		if routerID := net.IP(open[5:9]); !routerID.Equal(net.ParseIP("10.255.0.1")) {
+			t.Errorf("%s: OPEN router ID %s, want 10.255.0.1", dst, routerID)
+		}
+		serverSeq += uint32(len(replies[0].Payload))
+
+		// The peer's OPEN is answered with a KEEPALIVE
+		peerOpen := buildBGPOpen(&config.BGPConfig{AS: 65002, RouterID: net.ParseIP("10.0.0.100").To4(), HoldTime: 30 * time.Second})
+		sendClientSegment(t, stack, dst, TCPPortBGP, layers.TCP{Seq: 1001, Ack: serverSeq, ACK: true, PSH: true}, peerOpen)
+		replies = tcpReplies(t, stack)
+		clientSeq := 1001 + uint32(len(peerOpen))
+		if len(replies) != 1 || replies[0].Ack != clientSeq {
+			t.Fatalf("%s: replies to OPEN = %+v, want one segment ack=%d", dst, replies, clientSeq)
+		}
+		if messages := bgpMessages(t, replies[0].Payload); len(messages) != 1 || messages[0][18] != BGPMessageKeepalive {
+			t.Fatalf("%s: reply to OPEN % x, want a KEEPALIVE", dst, replies[0].Payload)
+		}
+		serverSeq += uint32(len(replies[0].Payload))
+
+		// The peer's KEEPALIVE establishes the session with the lower hold time
+		keepalive := buildBGPKeepalive()
+		sendClientSegment(t, stack, dst, TCPPortBGP, layers.TCP{Seq: clientSeq, Ack: serverSeq, ACK: true, PSH: true}, keepalive)
+		clientSeq += uint32(len(keepalive))
+		if replies := tcpReplies(t, stack); len(replies) != 1 || len(replies[0].Payload) != 0 || replies[0].Ack != clientSeq {
+			t.Errorf("%s: replies to KEEPALIVE = %+v, want a bare ACK", dst, replies)
+		}
+		var session *bgpSession
+		for _, s := range stack.bgpHandler.sessions {
+			session = s
+		}
+		if session == nil || session.state != bgpEstablished || session.holdTime != 30*time.Second {
+			t.Fatalf("%s: session %+v, want Established with a 30s hold time", dst, session)
+		}
+
+		// KEEPALIVEs follow every hold time / 3
+		stack.bgpHandler.runTimers(session.lastSent.Add(10 * time.Second))
+		replies = tcpReplies(t, stack)
+		if len(replies) != 1 || replies[0].Seq != serverSeq || replies[0].Ack != clientSeq {
+			t.Fatalf("%s: timer sent %+v, want one segment seq=%d ack=%d", dst, replies, serverSeq, clientSeq)
+		}
+		if messages := bgpMessages(t, replies[0].Payload); len(messages) != 1 || messages[0][18] != BGPMessageKeepalive {
+			t.Errorf("%s: timer sent % x, want a KEEPALIVE", dst, replies[0].Payload)
+		}
+
+		// Silence past the hold time closes the session with a NOTIFICATION
+		stack.bgpHandler.runTimers(session.lastHeard.Add(31 * time.Second))
+		replies = tcpReplies(t, stack)
+		if len(replies) != 1 || !replies[0].FIN {
+			t.Fatalf("%s: hold timer expiry sent %+v, want one FIN", dst, replies)
+		}
+		if messages := bgpMessages(t, replies[0].Payload); len(messages) != 1 || messages[0][18] != BGPMessageNotification || messages[0][19] != BGPErrorHoldTimer {
+			t.Errorf("%s: hold timer expiry sent % x, want a Hold Timer Expired NOTIFICATION", dst, replies[0].Payload)
+		}
+		if len(stack.bgpHandler.sessions) != 0 {
+			t.Errorf("%s: %d sessions left after the hold timer expired", dst, len(stack.bgpHandler.sessions))
+		}
+	}
+}
+
+// TestBGP_NotConfigured tests that port 179 is refused on a device without a
+// bgp block
+func TestBGP_NotConfigured(t *testing.T) {
+	stack := newBGPStack(nil)
+
+	sendClientSegment(t, stack, "192.168.1.80", TCPPortBGP, layers.TCP{Seq: 1000, SYN: true}, nil)
+	replies := tcpReplies(t, stack)
+	if len(replies) != 1 || !replies[0].RST {
+		t.Fatalf("replies to SYN = %+v, want one RST", replies)
+	}
+}
+
+// TestBuildBGPOpen_FourOctetAS tests that an AS above 65535 is sent as
+// AS_TRANS with the full AS in the 4-octet AS capability
+func TestBuildBGPOpen_FourOctetAS(t *testing.T) {
+	open := buildBGPOpen(&config.BGPConfig{AS: 4200000001, RouterID: net.ParseIP("10.255.0.1").To4(), HoldTime: 0})
+	body := open[bgpHeaderLen:]
+
+	if as := binary.BigEndian.Uint16(body[1:3]); as != bgpASTrans {
+		t.Errorf("My AS = %d, want AS_TRANS %d", as, bgpASTrans)
+	}
+	if hold := binary.BigEndian.Uint16(body[3:5]); hold != 0 {
+		t.Errorf("Hold time = %d, want 0", hold)
+	}
+	params := body[10:]
+	if len(params) != int(body[9]) || len(params) != 8 || params[0] != 2 || params[2] != bgpCapFourOctetAS {
+		t.Fatalf("Optional parameters % x, want one 4-octet AS capability", params)
+	}
+	if as := binary.BigEndian.Uint32(params[4:8]); as != 4200000001 {
+		t.Errorf("Capability AS = %d, want 4200000001", as)
+	}
+}
@@ -0,0 +1,259 @@
+package protocols
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// Boot stages of a device with a boot sequence, in order
+const (
+	BootStageStarting  = "starting"   // Silent: not yet on the network
+	BootStageLinkUp    = "link_up"    // Answers ARP, ICMP and services other than SNMP
+	BootStageColdStart = "cold_start" // coldStart trap sent; sysUpTime counts from here
+	BootStageDiscovery = "discovery"  // LLDP, CDP, EDP and FDP advertisements sent
+	BootStageSNMP      = "snmp"       // Answers SNMP: boot complete
+)
+
+// bootStageOrder lists the boot stages in the order they are reached
+var bootStageOrder = []string{BootStageStarting, BootStageLinkUp, BootStageColdStart, BootStageDiscovery, BootStageSNMP}
+
+// BootStageEvent records when a device reached a boot stage
+type BootStageEvent struct {
+	Stage string    `json:"stage"`
+	At    time.Time `json:"at"`
+}
+
+// BootStatus is how far a device with a boot sequence has got through it
+type BootStatus struct {
+	Stage   string           `json:"stage"`
+	History []BootStageEvent `json:"history"` // Stages reached, in order
+}
+
+// bootProgress tracks a device through its boot sequence (guarded by powerMu)
+type bootProgress struct {
+	stage   int // Index into bootStageOrder
+	history []BootStageEvent
+	timer   *time.Timer // Fires when the next stage is due
+}
+
+// startBootDelays holds every device with a boot_delay or boot_sequence out of
+// the device table, so it silently drops requests as if still booting, and
+// schedules it to become responsive when the delay (or the sequence's link_up
+// stage) ends.
+func (s *Stack) startBootDelays() {
+	cfg := s.currentConfig()
+	if cfg == nil {
+		return
+	}
+
+	s.powerMu.Lock()
+	defer s.powerMu.Unlock()
+	for i := range cfg.Devices {
+		device := &cfg.Devices[i]
+		if device.BootSequence != nil {
+			s.startBootSequence(device)
+			continue
+		}
+		if device.BootDelay <= 0 || s.booting[device.Name] != nil {
+			continue
+		}
+		if s.booting == nil {
+			s.booting = make(map[string]*time.Timer)
+		}
+		name := device.Name
+		s.devices.Remove(device)
+		s.booting[name] = time.AfterFunc(device.BootDelay, func() { s.finishBoot(name) })
+
+		if s.debugConfig.GetGlobal() >= 1 {
+			fmt.Printf("Device %s booting, silent for %v\n", name, device.BootDelay)
+		}
+	}
+}
+
+// startBootSequence takes a device off the network and schedules the stages
+// of its boot sequence.
+// Note: Caller must hold s.powerMu
+func (s *Stack) startBootSequence(device *config.Device) {
+	name := device.Name
+	if s.bootStages[name] != nil {
+		return
+	}
+	if s.booting == nil {
+		s.booting = make(map[string]*time.Timer)
+	}
+	if s.bootStages == nil {
+		s.bootStages = make(map[string]*bootProgress)
+	}
+
+	s.devices.Remove(device)
+	progress := &bootProgress{history: []BootStageEvent{{Stage: BootStageStarting, At: time.Now()}}}
+	progress.timer = time.AfterFunc(bootStageDelay(device.BootSequence, BootStageLinkUp), func() { s.advanceBoot(name) })
+	s.bootStages[name] = progress
+	// Until link up the device is booting like one with a boot_delay
+	s.booting[name] = progress.timer
+
+	if s.debugConfig.GetGlobal() >= 1 {
+		fmt.Printf("Device %s booting, link up in %v\n", name, device.BootSequence.LinkUp)
+	}
+}
+
+// advanceBoot moves the named device to the next stage of its boot sequence
+// and schedules the one after.
+func (s *Stack) advanceBoot(name string) {
+	s.powerMu.Lock()
+	progress := s.bootStages[name]
+	if progress == nil || progress.stage >= len(bootStageOrder)-1 {
+		s.powerMu.Unlock()
+		return
+	}
+	progress.stage++
+	stage := bootStageOrder[progress.stage]
+	progress.history = append(progress.history, BootStageEvent{Stage: stage, At: time.Now()})
+	progress.timer = nil
+
+	// Look the device up again: a reload during the sequence replaces it
+	device := s.findDevice(name)
+	if stage == BootStageLinkUp {
+		delete(s.booting, name)
+		if device != nil && !s.poweredOff[name] && !s.disabled[name] {
+			s.addDeviceToTable(device)
+		}
+	}
+	if device != nil && device.BootSequence != nil && stage != BootStageSNMP {
+		progress.timer = time.AfterFunc(bootStageDelay(device.BootSequence, bootStageOrder[progress.stage+1]), func() { s.advanceBoot(name) })
+	}
+	s.powerMu.Unlock()
+
+	if device == nil {
+		return
+	}
+	if s.debugConfig.GetGlobal() >= 1 {
+		fmt.Printf("Device %s boot stage %s\n", name, stage)
+	}
+	switch stage {
+	case BootStageColdStart:
+		if agent := s.getSNMPAgent(device); agent != nil {
+			if err := agent.BootComplete(); err != nil && s.debugConfig.GetGlobal() >= 2 {
+				fmt.Printf("SNMP: coldStart trap for %s failed: %v\n", name, err)
+			}
+		}
+	case BootStageDiscovery:
+		// Announce the device straight away rather than at the next interval
+		s.lldpHandler.sendAdvertisement(device)
+		s.cdpHandler.sendAdvertisement(device)
+		s.edpHandler.sendAdvertisement(device)
+		s.fdpHandler.sendAdvertisement(device)
+	}
+}
+
+// bootStageDelay returns how long after the previous stage the sequence
+// reaches stage
+func bootStageDelay(sequence *config.BootSequence, stage string) time.Duration {
+	switch stage {
+	case BootStageLinkUp:
+		return sequence.LinkUp
+	case BootStageColdStart:
+		return sequence.ColdStart
+	case BootStageDiscovery:
+		return sequence.Discovery
+	case BootStageSNMP:
+		return sequence.SNMP
+	}
+	return 0
+}
+
+// bootStageReached reports whether the named device has reached stage of its
+// boot sequence. Devices without a boot sequence have reached every stage.
+func (s *Stack) bootStageReached(name, stage string) bool {
+	s.powerMu.RLock()
+	defer s.powerMu.RUnlock()
+	progress := s.bootStages[name]
+	if progress == nil {
+		return true
+	}
+	for i := 0; i <= progress.stage; i++ {
+		if bootStageOrder[i] == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// BootStatus returns how far the named device is through its boot sequence,
+// or false when it has none.
+func (s *Stack) BootStatus(name string) (BootStatus, bool) {
+	s.powerMu.RLock()
+	defer s.powerMu.RUnlock()
+	progress := s.bootStages[name]
+	if progress == nil {
+		return BootStatus{}, false
+	}
+	return BootStatus{
+		Stage:   bootStageOrder[progress.stage],
+		History: append([]BootStageEvent(nil), progress.history...),
+	}, true
+}
+
+// finishBoot ends the named device's boot delay: it rejoins the device table
+// (unless powered off or disabled meanwhile) and announces itself with a coldStart trap.
+func (s *Stack) finishBoot(name string) {
+	s.powerMu.Lock()
+	if s.booting[name] == nil {
+		s.powerMu.Unlock()
+		return
+	}
+	delete(s.booting, name)
+	// Look the device up again: a reload during the boot delay replaces it
+	device := s.findDevice(name)
+	if device != nil && !s.poweredOff[name] && !s.disabled[name] {
+		s.addDeviceToTable(device)
+	}
+	s.powerMu.Unlock()
+
+	if device == nil {
+		return
+	}
+	if s.debugConfig.GetGlobal() >= 1 {
+		fmt.Printf("Device %s finished booting\n", name)
+	}
+	if agent := s.getSNMPAgent(device); agent != nil {
+		if err := agent.BootComplete(); err != nil && s.debugConfig.GetGlobal() >= 2 {
+			fmt.Printf("SNMP: coldStart trap for %s failed: %v\n", name, err)
+		}
+	}
+}
+
+// stopBootDelays cancels pending boot delays and sequences when the stack
+// stops.
+func (s *Stack) stopBootDelays() {
+	s.powerMu.Lock()
+	defer s.powerMu.Unlock()
+	for name, timer := range s.booting {
+		timer.Stop()
+		delete(s.booting, name)
+	}
+	for name, progress := range s.bootStages {
+		if progress.timer != nil {
+			progress.timer.Stop()
+		}
+		delete(s.bootStages, name)
+	}
+}
+
+// IsDeviceBooting reports whether the named device is still in its boot
+// delay and not yet answering.
+func (s *Stack) IsDeviceBooting(name string) bool {
+	s.powerMu.RLock()
+	defer s.powerMu.RUnlock()
+	return s.booting[name] != nil
+}
+
+// isDeviceUp reports whether the named device answers requests: powered on,
+// enabled and past its boot delay.
+func (s *Stack) isDeviceUp(name string) bool {
+	s.powerMu.RLock()
+	defer s.powerMu.RUnlock()
+	return !s.poweredOff[name] && !s.disabled[name] && s.booting[name] == nil
+}
@@ -0,0 +1,162 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+)
+
+// TestBootDelay tests that a device ignores requests during its boot delay,
+// then answers and sends a coldStart trap once it is ready
+func TestBootDelay(t *testing.T) {
+	receiver, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot open UDP listener: %v", err)
+	}
+	defer receiver.Close()
+
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "slow-router",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x77},
+		IPAddresses: []net.IP{net.ParseIP("192.168.1.1")},
+		BootDelay:   200 * time.Millisecond,
+		SNMPConfig: config.SNMPConfig{
+			Community: "public",
+			Traps:     &config.TrapConfig{Enabled: true, Receivers: []string{receiver.LocalAddr().String()}},
+		},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	stack.startBootDelays()
+	defer stack.stopBootDelays()
+
+	if !stack.IsDeviceBooting("slow-router") {
+		t.Fatal("Expected device to be booting")
+	}
+	stack.arpHandler.HandlePacket(buildARPRequestPacket(t, "192.168.1.1"))
+	if depth, _ := stack.SendQueueDepth(); depth != 0 {
+		t.Fatalf("Expected no ARP reply while booting, got %d queued packets", depth)
+	}
+
+	_ = receiver.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 65535)
+	n, _, err := receiver.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no coldStart trap after boot: %v", err)
+	}
+	decoder := gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	packet, err := decoder.SnmpDecodePacket(buf[:n])
+	if err != nil {
+		t.Fatalf("decode trap: %v", err)
+	}
+	if len(packet.Variables) < 2 || packet.Variables[1].Value != snmp.OIDColdStart {
+		t.Errorf("Expected coldStart trap, got %+v", packet.Variables)
+	}
+
+	// The trap is sent once the device has rejoined the table
+	if stack.IsDeviceBooting("slow-router") {
+		t.Fatal("Expected device to have finished booting")
+	}
+	stack.arpHandler.HandlePacket(buildARPRequestPacket(t, "192.168.1.1"))
+	if depth, _ := stack.SendQueueDepth(); depth != 1 {
+		t.Errorf("Expected an ARP reply after booting, got %d queued packets", depth)
+	}
+}
+
+// TestBootSequence tests that a device with a boot sequence reaches each
+// stage in order, with the configured spacing, and only starts discovery and
+// SNMP at their stages
+func TestBootSequence(t *testing.T) {
+	receiver, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot open UDP listener: %v", err)
+	}
+	defer receiver.Close()
+
+	const spacing = 50 * time.Millisecond
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "staged-switch",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x78},
+		IPAddresses: []net.IP{net.ParseIP("192.168.1.2")},
+		BootSequence: &config.BootSequence{
+			LinkUp:    spacing,
+			ColdStart: spacing,
+			Discovery: spacing,
+			SNMP:      spacing,
+		},
+		SNMPConfig: config.SNMPConfig{
+			Community: "public",
+			Traps:     &config.TrapConfig{Enabled: true, Receivers: []string{receiver.LocalAddr().String()}},
+		},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	stack.startBootDelays()
+	defer stack.stopBootDelays()
+
+	if status, ok := stack.BootStatus("staged-switch"); !ok || status.Stage != BootStageStarting {
+		t.Fatalf("Expected stage %s, got %+v", BootStageStarting, status)
+	}
+	if stack.bootStageReached("staged-switch", BootStageDiscovery) || stack.bootStageReached("staged-switch", BootStageSNMP) {
+		t.Fatal("Expected discovery and SNMP to wait for their stages")
+	}
+
+	_ = receiver.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 65535)
+	n, _, err := receiver.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no coldStart trap during boot: %v", err)
+	}
+	decoder := gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	packet, err := decoder.SnmpDecodePacket(buf[:n])
+	if err != nil {
+		t.Fatalf("decode trap: %v", err)
+	}
+	if len(packet.Variables) < 2 || packet.Variables[1].Value != snmp.OIDColdStart {
+		t.Errorf("Expected coldStart trap, got %+v", packet.Variables)
+	}
+
+	var status BootStatus
+	deadline := time.Now().Add(2 * time.Second)
+	for status.Stage != BootStageSNMP && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		status, _ = stack.BootStatus("staged-switch")
+	}
+	if status.Stage != BootStageSNMP {
+		t.Fatalf("Expected boot to complete, stuck at %s", status.Stage)
+	}
+
+	if len(status.History) != len(bootStageOrder) {
+		t.Fatalf("Expected %d boot events, got %+v", len(bootStageOrder), status.History)
+	}
+	for i, event := range status.History {
+		if event.Stage != bootStageOrder[i] {
+			t.Errorf("Event %d: stage %s, want %s", i, event.Stage, bootStageOrder[i])
+		}
+		if i == 0 {
+			continue
+		}
+		if gap := event.At.Sub(status.History[i-1].At); gap < spacing || gap > spacing+time.Second {
+			t.Errorf("Stage %s reached %v after %s, want about %v", event.Stage, gap, status.History[i-1].Stage, spacing)
+		}
+	}
+
+	// Discovery announced the device as soon as it started
+	lldpFrames := 0
+	for _, pkt := range drainSendQueue(stack) {
+		if len(pkt.Buffer) >= 14 && binary.BigEndian.Uint16(pkt.Buffer[12:14]) == uint16(layers.EthernetTypeLinkLayerDiscovery) {
+			lldpFrames++
+		}
+	}
+	if lldpFrames != 1 {
+		t.Errorf("Expected one LLDP advertisement at the discovery stage, got %d", lldpFrames)
+	}
+	if !stack.bootStageReached("staged-switch", BootStageSNMP) {
+		t.Error("Expected SNMP to be answered after the boot sequence")
+	}
+}
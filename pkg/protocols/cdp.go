@@ -48,6 +48,7 @@ const (
 	CDPTLVTypeTrustBitmap     = 0x0012
 	CDPTLVTypeUntrustedCOS    = 0x0013
 	CDPTLVTypeManagementAddr  = 0x0016
+	CDPTLVTypePowerRequest    = 0x0019
 )
 
 // CDP Capabilities flags
@@ -86,6 +87,12 @@ func (h *CDPHandler) Start() {
 		fmt.Printf("CDP: Starting periodic advertisements (interval: %v)\n", CDPAdvertiseInterval)
 	}
 
+	// Spread first advertisements across the interval when phase jitter is set
+	if jitter := h.stack.discoveryPhaseJitter("cdp"); jitter > 0 {
+		go h.stack.runStaggeredAdvertisements("cdp", CDPAdvertiseInterval, jitter, h.stopChan, h.sendAdvertisement)
+		return
+	}
+
 	h.advertiseTicker = time.NewTicker(CDPAdvertiseInterval)
 
 	go func() {
@@ -111,28 +118,33 @@ func (h *CDPHandler) Stop() {
 
 // sendAdvertisements sends CDP advertisements for all devices
 func (h *CDPHandler) sendAdvertisements() {
+	for _, device := range h.stack.GetDevices().GetAll() {
+		h.sendAdvertisement(device)
+	}
+}
+
+// sendAdvertisement sends a CDP advertisement for one device
+func (h *CDPHandler) sendAdvertisement(device *config.Device) {
 	debugLevel := h.stack.GetDebugLevel()
 
-	devices := h.stack.GetDevices().GetAll()
-	for _, device := range devices {
-		if len(device.MACAddress) == 0 {
-			continue
-		}
+	// Devices with a boot sequence stay quiet until its discovery stage
+	if len(device.MACAddress) == 0 || !h.stack.bootStageReached(device.Name, BootStageDiscovery) {
+		return
+	}
 
-		// Skip if CDP is explicitly disabled for this device
-		if device.CDPConfig != nil && !device.CDPConfig.Enabled {
-			continue
-		}
+	// Skip if CDP is explicitly disabled for this device
+	if device.CDPConfig != nil && !device.CDPConfig.Enabled {
+		return
+	}
 
-		// Build and send CDP frame
-		frame := h.buildCDPFrame(device)
-		if frame != nil {
-			err := h.sendFrame(device, frame)
-			if err != nil && debugLevel >= 2 {
-				fmt.Printf("CDP: Error sending advertisement for %s: %v\n", device.Name, err)
-			} else if debugLevel >= 3 {
-				fmt.Printf("CDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
-			}
+	// Build and send CDP frame
+	frame := h.buildCDPFrame(device)
+	if frame != nil {
+		err := h.sendFrame(device, frame)
+		if err != nil && debugLevel >= 2 {
+			fmt.Printf("CDP: Error sending advertisement for %s: %v\n", device.Name, err)
+		} else if debugLevel >= 3 {
+			fmt.Printf("CDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
 		}
 	}
 }
@@ -165,6 +177,7 @@ func (h *CDPHandler) buildCDPFrame(device *config.Device) []byte {
 	payload = append(payload, h.buildCapabilitiesTLV(device)...)
 	payload = append(payload, h.buildSoftwareVersionTLV(device)...)
 	payload = append(payload, h.buildPlatformTLV(device)...)
+	payload = append(payload, h.buildPowerTLVs(device)...)
 
 	// Calculate checksum (standard Internet checksum)
 	checksum := h.calculateChecksum(payload)
@@ -359,6 +372,31 @@ func (h *CDPHandler) buildPlatformTLV(device *config.Device) []byte {
 	return tlv
 }
 
+// buildPowerTLVs builds the Power (consumption) and Power Request TLVs of a
+// powered device, in milliwatts. Devices without a poe block send none.
+func (h *CDPHandler) buildPowerTLVs(device *config.Device) []byte {
+	if device.PoE == nil {
+		return nil
+	}
+	milliwatts := device.PoE.RequestedMilliwatts()
+
+	// Power: Type (2) + Length (2) + Consumption (2)
+	power := make([]byte, 6)
+	binary.BigEndian.PutUint16(power[0:2], CDPTLVTypePower)
+	binary.BigEndian.PutUint16(power[2:4], uint16(len(power)))
+	binary.BigEndian.PutUint16(power[4:6], uint16(milliwatts))
+
+	// Power Request: Type (2) + Length (2) + Request ID (2) + Management ID (2) + Power (4)
+	request := make([]byte, 12)
+	binary.BigEndian.PutUint16(request[0:2], CDPTLVTypePowerRequest)
+	binary.BigEndian.PutUint16(request[2:4], uint16(len(request)))
+	binary.BigEndian.PutUint16(request[4:6], 1) // Echoed by the switch in Power Available
+	binary.BigEndian.PutUint16(request[6:8], 0)
+	binary.BigEndian.PutUint32(request[8:12], milliwatts)
+
+	return append(power, request...)
+}
+
 // calculateChecksum calculates the CDP checksum
 func (h *CDPHandler) calculateChecksum(data []byte) uint16 {
 	// Standard Internet checksum
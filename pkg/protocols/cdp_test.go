@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
 )
@@ -297,6 +299,34 @@ func TestBuildPortIDTLVCDP(t *testing.T) {
 	}
 }
 
+// TestBuildCDPFrame_Power tests that a device with a poe block sends its
+// requested power in the Power and Power Request TLVs
+func TestBuildCDPFrame_Power(t *testing.T) {
+	cfg := &config.Config{}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewCDPHandler(stack)
+
+	device := &config.Device{
+		Name:       "phone-1",
+		Type:       "phone",
+		MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x68},
+		PoE:        &config.PoEConfig{Class: 2, RequestedWatts: 6.3, Priority: config.PoEPriorityLow},
+	}
+
+	frame := handler.buildCDPFrame(device)
+	packet := gopacket.NewPacket(frame[8:], layers.LayerTypeCiscoDiscovery, gopacket.Default)
+	info, ok := packet.Layer(layers.LayerTypeCiscoDiscoveryInfo).(*layers.CiscoDiscoveryInfo)
+	if !ok {
+		t.Fatalf("CDP frame did not decode: %v", packet.ErrorLayer())
+	}
+	if info.PowerConsumption != 6300 {
+		t.Errorf("Power consumption = %d mW, want 6300", info.PowerConsumption)
+	}
+	if len(info.PowerRequest.Values) != 1 || info.PowerRequest.Values[0] != 6300 {
+		t.Errorf("Power request = %+v, want 6300 mW", info.PowerRequest)
+	}
+}
+
 // TestBuildCapabilitiesTLV verifies Capabilities TLV construction
 func TestBuildCapabilitiesTLV(t *testing.T) {
 	cfg := &config.Config{}
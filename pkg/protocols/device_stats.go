@@ -0,0 +1,203 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/stats"
+)
+
+// deviceStatsTable tracks the traffic counters of each device by name
+type deviceStatsTable struct {
+	mu      sync.Mutex
+	devices map[string]*stats.DeviceSummary
+}
+
+func newDeviceStatsTable() *deviceStatsTable {
+	return &deviceStatsTable{devices: make(map[string]*stats.DeviceSummary)}
+}
+
+// entryLocked returns the counters for name, creating them on first use
+func (t *deviceStatsTable) entryLocked(name string) *stats.DeviceSummary {
+	entry, ok := t.devices[name]
+	if !ok {
+		entry = &stats.DeviceSummary{Name: name, Sent: make(map[string]uint64)}
+		t.devices[name] = entry
+	}
+	return entry
+}
+
+func (t *deviceStatsTable) received(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entryLocked(name).PacketsReceived++
+}
+
+func (t *deviceStatsTable) sent(name string, frame []byte) {
+	protocol := frameProtocol(frame)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry := t.entryLocked(name)
+	entry.PacketsSent++
+	entry.Sent[protocol]++
+}
+
+func (t *deviceStatsTable) failed(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entryLocked(name).Errors++
+}
+
+func (t *deviceStatsTable) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.devices = make(map[string]*stats.DeviceSummary)
+}
+
+// snapshot returns a copy of the counters for each named device, in name
+// order. Devices that saw no traffic get a zero row.
+func (t *deviceStatsTable) snapshot(names []string) []stats.DeviceSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sort.Strings(names)
+	rows := make([]stats.DeviceSummary, 0, len(names))
+	for _, name := range names {
+		row := stats.DeviceSummary{Name: name, Sent: make(map[string]uint64)}
+		if entry, ok := t.devices[name]; ok {
+			row.PacketsReceived = entry.PacketsReceived
+			row.PacketsSent = entry.PacketsSent
+			row.Errors = entry.Errors
+			for protocol, count := range entry.Sent {
+				row.Sent[protocol] = count
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// GetDeviceStats returns the traffic counters of every simulated device,
+// sorted by name
+func (s *Stack) GetDeviceStats() []stats.DeviceSummary {
+	devices := s.devices.GetAll()
+	names := make([]string, 0, len(devices))
+	for _, device := range devices {
+		names = append(names, device.Name)
+	}
+	return s.deviceStats.snapshot(names)
+}
+
+// frameProtocol names the protocol of a frame the stack sends, as reported
+// in stats.DeviceSummary.Sent. It runs for every sent frame, so it reads the
+// EtherType, IP protocol and ports straight from the frame instead of
+// decoding it.
+func frameProtocol(frame []byte) string {
+	if len(frame) < 14 {
+		return "other"
+	}
+	switch string(frame[0:6]) {
+	case "\x01\x80\xc2\x00\x00\x00":
+		return "stp"
+	case CDPMulticastMAC:
+		return "cdp"
+	case EDPMulticastMAC:
+		return "edp"
+	case FDPMulticastMAC:
+		return "fdp"
+	}
+
+	etherType, payload := binary.BigEndian.Uint16(frame[12:14]), frame[14:]
+	for etherType == EtherTypeVLAN && len(payload) >= 4 {
+		etherType, payload = binary.BigEndian.Uint16(payload[2:4]), payload[4:]
+	}
+
+	var ipProtocol uint8
+	var transport []byte // Nil when the datagram is a later fragment
+	switch etherType {
+	case EtherTypeLLDP:
+		return "lldp"
+	case EtherTypeARP:
+		return "arp"
+	case EtherTypeIP:
+		if len(payload) < 20 {
+			return "other"
+		}
+		ipProtocol = payload[9]
+		if headerLen := int(payload[0]&0x0f) * 4; binary.BigEndian.Uint16(payload[6:8])&0x1fff == 0 && len(payload) >= headerLen {
+			transport = payload[headerLen:]
+		}
+	case EtherTypeIPv6:
+		ipProtocol, transport = ipv6Transport(payload)
+	default:
+		return "other"
+	}
+
+	switch ipProtocol {
+	case uint8(layers.IPProtocolUDP):
+		if len(transport) < 4 {
+			return "udp"
+		}
+		srcPort, dstPort := binary.BigEndian.Uint16(transport[0:2]), binary.BigEndian.Uint16(transport[2:4])
+		switch {
+		case srcPort == UDPPortDNS:
+			return "dns"
+		case srcPort == UDPPortDHCP || srcPort == UDPPortDHCPC:
+			return "dhcp"
+		case srcPort == DHCPv6ServerPort || srcPort == DHCPv6ClientPort:
+			return "dhcpv6"
+		case srcPort == UDPPortSNMP || dstPort == 162: // Responses and traps
+			return "snmp"
+		case srcPort == NetBIOSNameServicePort || srcPort == NetBIOSDatagramServicePort:
+			return "netbios"
+		}
+		return "udp"
+	case uint8(layers.IPProtocolTCP):
+		if len(transport) < 2 {
+			return "tcp"
+		}
+		switch binary.BigEndian.Uint16(transport[0:2]) {
+		case TCPPortHTTP:
+			return "http"
+		case TCPPortFTP:
+			return "ftp"
+		}
+		return "tcp"
+	case uint8(layers.IPProtocolICMPv4):
+		return "icmp"
+	case uint8(layers.IPProtocolICMPv6):
+		return "icmpv6"
+	}
+	return "other"
+}
+
+// ipv6Transport skips the extension headers of an IPv6 packet, returning
+// its upper-layer protocol and header. The header is nil for a later
+// fragment or a truncated packet.
+func ipv6Transport(packet []byte) (uint8, []byte) {
+	if len(packet) < 40 {
+		return 0, nil
+	}
+	next, rest := packet[6], packet[40:]
+	for {
+		switch layers.IPProtocol(next) {
+		case layers.IPProtocolIPv6HopByHop, layers.IPProtocolIPv6Routing, layers.IPProtocolIPv6Destination:
+			if len(rest) < 2 || len(rest) < (int(rest[1])+1)*8 {
+				return next, nil
+			}
+			next, rest = rest[0], rest[(int(rest[1])+1)*8:]
+		case layers.IPProtocolIPv6Fragment:
+			if len(rest) < 8 {
+				return next, nil
+			}
+			if binary.BigEndian.Uint16(rest[2:4])&^0x7 != 0 {
+				return rest[0], nil
+			}
+			next, rest = rest[0], rest[8:]
+		default:
+			return next, rest
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package protocols
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// TestDeviceStats tests that frames are counted against the device they are
+// addressed to and the device that sends them, by protocol
+func TestDeviceStats(t *testing.T) {
+	cfg := &config.Config{Devices: []config.Device{
+		{Name: "router1", MACAddress: fragTestDeviceMAC, IPAddresses: []net.IP{net.ParseIP("192.168.1.1")}},
+		{Name: "idle", MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x02}},
+	}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	buffer := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+		&layers.Ethernet{SrcMAC: fragTestHostMAC, DstMAC: fragTestDeviceMAC, EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolICMPv4,
+			SrcIP: net.ParseIP("192.168.1.50").To4(), DstIP: net.ParseIP("192.168.1.1").To4()},
+		&layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: 1, Seq: 1},
+	); err != nil {
+		t.Fatalf("serialize echo request: %v", err)
+	}
+	stack.decodePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+	stack.decodePacket(buildARPRequestPacket(t, "192.168.1.1"))
+
+	if frames := sendQueuedPackets(stack); len(frames) != 2 {
+		t.Fatalf("sent %d replies, want 2", len(frames))
+	}
+
+	rows := stack.GetDeviceStats()
+	if len(rows) != 2 || rows[0].Name != "idle" || rows[1].Name != "router1" {
+		t.Fatalf("got rows %+v, want idle and router1 in name order", rows)
+	}
+	if idle := rows[0]; idle.PacketsReceived != 0 || idle.PacketsSent != 0 || len(idle.Sent) != 0 {
+		t.Errorf("idle device counters = %+v, want zero", idle)
+	}
+	router := rows[1]
+	if router.PacketsReceived != 1 {
+		t.Errorf("router1 received %d frames, want 1 (the broadcast ARP request is not addressed to it)", router.PacketsReceived)
+	}
+	if router.PacketsSent != 2 || router.Sent["icmp"] != 1 || router.Sent["arp"] != 1 {
+		t.Errorf("router1 sent %d frames by protocol %v, want icmp=1 arp=1", router.PacketsSent, router.Sent)
+	}
+
+	stack.ResetStats()
+	if rows := stack.GetDeviceStats(); rows[1].PacketsSent != 0 || rows[1].PacketsReceived != 0 {
+		t.Errorf("router1 counters after reset = %+v, want zero", rows[1])
+	}
+}
+
+// TestFrameProtocol tests that sent frames are classified from their header
+// bytes, through VLAN tags, IPv6 extension headers and fragments
+func TestFrameProtocol(t *testing.T) {
+	serialize := func(t *testing.T, l ...gopacket.SerializableLayer) []byte {
+		t.Helper()
+		buffer := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{FixLengths: true}, l...); err != nil {
+			t.Fatalf("serialize: %v", err)
+		}
+		return buffer.Bytes()
+	}
+	srcIP, dstIP := net.ParseIP("192.168.1.1").To4(), net.ParseIP("192.168.1.50").To4()
+	srcIPv6, dstIPv6 := net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::50")
+	eth := func(etherType layers.EthernetType) *layers.Ethernet {
+		return &layers.Ethernet{SrcMAC: fragTestDeviceMAC, DstMAC: fragTestHostMAC, EthernetType: etherType}
+	}
+	udp := func(src, dst layers.UDPPort) *layers.UDP { return &layers.UDP{SrcPort: src, DstPort: dst} }
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  string
+	}{
+		{"truncated", []byte{0x01, 0x02}, "other"},
+		{"stp", serialize(t, &layers.Ethernet{SrcMAC: fragTestDeviceMAC,
+			DstMAC: net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x00}, EthernetType: layers.EthernetTypeLLC},
+			&layers.LLC{DSAP: 0x42, SSAP: 0x42, Control: 0x03}), "stp"},
+		{"lldp", serialize(t, eth(layers.EthernetTypeLinkLayerDiscovery), gopacket.Payload{0x00, 0x00}), "lldp"},
+		{"dns", serialize(t, eth(layers.EthernetTypeIPv4),
+			&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: srcIP, DstIP: dstIP},
+			udp(UDPPortDNS, 40000)), "dns"},
+		{"snmp trap", serialize(t, eth(layers.EthernetTypeIPv4),
+			&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: srcIP, DstIP: dstIP},
+			udp(40000, 162)), "snmp"},
+		{"vlan tagged http", serialize(t, eth(layers.EthernetTypeDot1Q),
+			&layers.Dot1Q{VLANIdentifier: 10, Type: layers.EthernetTypeIPv4},
+			&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: srcIP, DstIP: dstIP},
+			&layers.TCP{SrcPort: TCPPortHTTP, DstPort: 40000, DataOffset: 5}), "http"},
+		{"ipv4 later fragment", serialize(t, eth(layers.EthernetTypeIPv4),
+			&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP, FragOffset: 185,
+				SrcIP: srcIP, DstIP: dstIP}, gopacket.Payload{0x00, 0x35, 0x9c, 0x40}), "udp"},
+		{"dhcpv6 after hop-by-hop", serialize(t, eth(layers.EthernetTypeIPv6),
+			&layers.IPv6{Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolIPv6HopByHop, SrcIP: srcIPv6, DstIP: dstIPv6},
+			gopacket.Payload{byte(layers.IPProtocolUDP), 0, 1, 4, 0, 0, 0, 0},
+			udp(DHCPv6ServerPort, DHCPv6ClientPort)), "dhcpv6"},
+		{"icmpv6", serialize(t, eth(layers.EthernetTypeIPv6),
+			&layers.IPv6{Version: 6, HopLimit: 255, NextHeader: layers.IPProtocolICMPv6, SrcIP: srcIPv6, DstIP: dstIPv6},
+			gopacket.Payload{0x88, 0x00, 0x00, 0x00}), "icmpv6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frameProtocol(tt.frame); got != tt.want {
+				t.Errorf("frameProtocol(%x) = %q, want %q", tt.frame, got, tt.want)
+			}
+		})
+	}
+}
@@ -31,6 +31,7 @@ const (
 	DHCPOptNTP          layers.DHCPOpt = 42  // NTP servers
 	DHCPOptTFTPServer   layers.DHCPOpt = 66  // TFTP server name
 	DHCPOptBootfileName layers.DHCPOpt = 67  // Bootfile name
+	DHCPOptUserClass    layers.DHCPOpt = 77  // User class (RFC 3004)
 	DHCPOptDomainSearch layers.DHCPOpt = 119 // Domain search list
 )
 
@@ -58,21 +59,65 @@ type DHCPHandler struct {
 	gateway            net.IP
 	dnsServers         []net.IP
 	domainName         string
-	ntpServers         []net.IP // Option 42: NTP servers
-	domainSearch       []string // Option 119: Domain search list
-	tftpServerName     string   // Option 66: TFTP server name
-	bootfileName       string   // Option 67: Bootfile name (for PXE)
-	vendorSpecificInfo []byte   // Option 43: Vendor-specific information
+	ntpServers         []net.IP                 // Option 42: NTP servers
+	domainSearch       []string                 // Option 119: Domain search list
+	tftpServerName     string                   // Option 66: TFTP server name
+	bootfileName       string                   // Option 67: Bootfile name (for PXE)
+	vendorSpecificInfo []byte                   // Option 43: Vendor-specific information
+	clientClasses      []config.DHCPClientClass // Per-class options, matched on option 60/77 in order
+	alwaysBroadcast    bool                     // Broadcast replies even when the client can take unicast
+	retransmitWindow   time.Duration
+	leaseGrace         time.Duration
+	offers             map[dhcpTransactionKey]*dhcpOffer // Recent Offers, for DISCOVER retransmissions
 	mu                 sync.RWMutex
 }
 
+// dhcpTransactionKey identifies a client transaction (RFC 2131: xid and chaddr)
+type dhcpTransactionKey struct {
+	mac string
+	xid uint32
+}
+
+// dhcpOffer is an Offer made in answer to a DISCOVER
+type dhcpOffer struct {
+	ip      net.IP
+	offered time.Time
+}
+
+// dhcpBroadcastFlag is the BROADCAST bit of the DHCP flags field
+const dhcpBroadcastFlag = 0x8000
+
+// dhcpReplyTarget is where a reply goes and the request fields it echoes
+type dhcpReplyTarget struct {
+	ip     net.IP
+	mac    net.HardwareAddr
+	port   layers.UDPPort
+	flags  uint16
+	ciaddr net.IP
+	giaddr net.IP
+}
+
+// broadcastReplyTarget addresses a reply to the whole segment
+func broadcastReplyTarget() dhcpReplyTarget {
+	return dhcpReplyTarget{
+		ip:     net.IPv4bcast,
+		mac:    layers.EthernetBroadcast,
+		port:   68,
+		flags:  dhcpBroadcastFlag,
+		ciaddr: net.IPv4zero,
+		giaddr: net.IPv4zero,
+	}
+}
+
 // NewDHCPHandler creates a new DHCP handler
 func NewDHCPHandler(stack *Stack) *DHCPHandler {
 	return &DHCPHandler{
-		stack:      stack,
-		leases:     make(map[string]*DHCPLease),
-		ipPool:     make([]net.IP, 0),
-		subnetMask: net.IPv4(255, 255, 255, 0),
+		stack:            stack,
+		leases:           make(map[string]*DHCPLease),
+		ipPool:           make([]net.IP, 0),
+		subnetMask:       net.IPv4(255, 255, 255, 0),
+		retransmitWindow: config.DefaultDHCPRetransmitWindow * time.Second,
+		offers:           make(map[dhcpTransactionKey]*dhcpOffer),
 	}
 }
 
@@ -116,6 +161,30 @@ func (h *DHCPHandler) SetAdvancedOptions(ntpServers []net.IP, domainSearch []str
 	h.vendorSpecificInfo = vendorInfo
 }
 
+// SetClientClasses sets the option sets handed to classes of clients, matched
+// on their vendor and user class in order.
+func (h *DHCPHandler) SetClientClasses(classes []config.DHCPClientClass) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clientClasses = classes
+}
+
+// SetAlwaysBroadcast makes Offers and Acks go to the broadcast address even
+// when the client could accept unicast, for exercising relays and snoopers.
+func (h *DHCPHandler) SetAlwaysBroadcast(enabled bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.alwaysBroadcast = enabled
+}
+
+// SetRetransmitWindow sets how long a DISCOVER retransmission (same MAC and
+// xid) is answered with the original Offer instead of being processed anew.
+func (h *DHCPHandler) SetRetransmitWindow(window time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.retransmitWindow = window
+}
+
 // Reset clears all DHCP server state while preserving the associated stack.
 func (h *DHCPHandler) Reset() {
 	h.mu.Lock()
@@ -134,6 +203,47 @@ func (h *DHCPHandler) Reset() {
 	h.tftpServerName = ""
 	h.bootfileName = ""
 	h.vendorSpecificInfo = nil
+	h.clientClasses = nil
+	h.alwaysBroadcast = false
+	h.retransmitWindow = config.DefaultDHCPRetransmitWindow * time.Second
+	h.leaseGrace = 0
+	h.offers = make(map[dhcpTransactionKey]*dhcpOffer)
+}
+
+// retransmittedOffer returns the address offered for the transaction when a
+// DISCOVER repeats one answered within the retransmit window.
+func (h *DHCPHandler) retransmittedOffer(key dhcpTransactionKey, now time.Time) (net.IP, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	offer, ok := h.offers[key]
+	if !ok || now.Sub(offer.offered) > h.retransmitWindow {
+		return nil, false
+	}
+	return offer.ip, true
+}
+
+// recordOffer remembers an Offer so retransmissions of its DISCOVER get the
+// same answer. Offers older than the retransmit window are forgotten.
+func (h *DHCPHandler) recordOffer(key dhcpTransactionKey, ip net.IP, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k, offer := range h.offers {
+		if now.Sub(offer.offered) > h.retransmitWindow {
+			delete(h.offers, k)
+		}
+	}
+	h.offers[key] = &dhcpOffer{ip: ip, offered: now}
+}
+
+// forgetOffers drops the Offers made to a client, so a DISCOVER retransmitted
+// after it releases or declines its address is not answered from the cache.
+// Note: Caller must hold h.mu lock
+func (h *DHCPHandler) forgetOffers(mac string) {
+	for k := range h.offers {
+		if k.mac == mac {
+			delete(h.offers, k)
+		}
+	}
 }
 
 // MaxPoolSize is the maximum number of IPs allowed in a DHCP pool
@@ -171,14 +281,30 @@ func (h *DHCPHandler) generateIPPool(start, end net.IP) ([]net.IP, error) {
 	return pool, nil
 }
 
+// SetLeaseGrace holds an expired lease's address for grace after expiry, so
+// it is not offered to another client straight away.
+func (h *DHCPHandler) SetLeaseGrace(grace time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaseGrace = grace
+}
+
+// holdsAddress reports whether lease still keeps its address from other
+// clients: until it expires, plus the lease grace period.
+// Note: Caller must hold h.mu lock
+func (h *DHCPHandler) holdsAddress(lease *DHCPLease, now time.Time) bool {
+	return now.Before(lease.Expiry.Add(h.leaseGrace))
+}
+
 // findAvailableIP finds an available IP address
 // Note: Caller must hold h.mu lock
 func (h *DHCPHandler) findAvailableIP() net.IP {
+	now := time.Now()
 	// Check each IP in pool
 	for _, ip := range h.ipPool {
 		inUse := false
 		for _, lease := range h.leases {
-			if lease.IP.Equal(ip) && time.Now().Before(lease.Expiry) {
+			if lease.IP.Equal(ip) && h.holdsAddress(lease, now) {
 				inUse = true
 				break
 			}
@@ -244,19 +370,39 @@ func (h *DHCPHandler) isIPInPool(ip net.IP) bool {
 	return false
 }
 
-// isIPLeased checks if IP is currently leased
+// isIPLeased checks if IP is currently leased or held in its grace period
 func (h *DHCPHandler) isIPLeased(ip net.IP) bool {
+	now := time.Now()
 	for _, lease := range h.leases {
-		if lease.IP.Equal(ip) && time.Now().Before(lease.Expiry) {
+		if lease.IP.Equal(ip) && h.holdsAddress(lease, now) {
 			return true
 		}
 	}
 	return false
 }
 
+// PoolUtilization returns the percentage of the pool's addresses under an
+// active lease, or 0 when no pool is configured. Leases are only ever
+// allocated from the pool.
+func (h *DHCPHandler) PoolUtilization() float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.ipPool) == 0 {
+		return 0
+	}
+	now := time.Now()
+	leased := 0
+	for _, lease := range h.leases {
+		if now.Before(lease.Expiry) {
+			leased++
+		}
+	}
+	return min(float64(leased)*100/float64(len(h.ipPool)), 100)
+}
+
 // HandlePacket processes a DHCP packet
 func (h *DHCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, udpLayer *layers.UDP, devices []*config.Device) {
-	debugLevel := h.stack.GetDebugLevel()
+	debugLevel := h.stack.GetProtocolDebugLevel(logging.ProtocolDHCP)
 
 	h.stack.IncrementStat("dhcp_requests")
 
@@ -267,6 +413,11 @@ func (h *DHCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, udpLayer *
 		if debugLevel >= 2 {
 			fmt.Printf("DHCP packet missing DHCP layer sn=%d\n", pkt.SerialNumber)
 		}
+		err := fmt.Errorf("missing DHCP layer")
+		if failure := packet.ErrorLayer(); failure != nil {
+			err = failure.Error()
+		}
+		h.stack.recordErrorPacket(pkt, "dhcp", err)
 		return
 	}
 
@@ -315,6 +466,12 @@ func (h *DHCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, udpLayer *
 		}
 	}
 
+	// Clients of a configured class (a PXE ROM, say) get its options
+	class := h.clientClassFor(dhcp)
+	if class != nil && debugLevel >= 3 {
+		fmt.Printf("DHCP: %s is in client class %s sn=%d\n", dhcp.ClientHWAddr, class.Name, pkt.SerialNumber)
+	}
+
 	// Handle based on message type
 	switch messageType {
 	case DHCPDiscover:
@@ -323,6 +480,23 @@ func (h *DHCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, udpLayer *
 			fmt.Printf("DHCP: Processing Discover from %s sn=%d\n", dhcp.ClientHWAddr, pkt.SerialNumber)
 		}
 
+		// A retransmitted DISCOVER gets the original Offer again
+		transaction := dhcpTransactionKey{mac: dhcp.ClientHWAddr.String(), xid: dhcp.Xid}
+		now := time.Now()
+		if offeredIP, ok := h.retransmittedOffer(transaction, now); ok {
+			h.stack.IncrementStat("dhcp_retransmits")
+			target := h.replyTarget(dhcp, requestSourceMAC(packet), offeredIP)
+			if err := h.sendDHCPResponse(dhcp.Xid, dhcp.ClientHWAddr, offeredIP, serverDevice.IPAddresses[0], serverDevice.MACAddress, DHCPOffer, target, class); err != nil {
+				if debugLevel >= 1 {
+					logging.ProtocolDebug("DHCP", debugLevel, 1, "Failed to resend Offer: %v sn=%d", err, pkt.SerialNumber)
+				}
+			} else if debugLevel >= 2 {
+				logging.ProtocolDebug("DHCP", debugLevel, 2, "Resent Offer IP=%s to %s for retransmitted Discover xid=0x%x sn=%d",
+					offeredIP, dhcp.ClientHWAddr, dhcp.Xid, pkt.SerialNumber)
+			}
+			return
+		}
+
 		// Allocate IP for client
 		lease, err := h.allocateLease(dhcp.ClientHWAddr, nil, hostname)
 		if err != nil {
@@ -333,11 +507,13 @@ func (h *DHCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, udpLayer *
 		}
 
 		// Send DHCP Offer
-		if err := h.SendDHCPOffer(dhcp.Xid, dhcp.ClientHWAddr, lease.IP, serverDevice.IPAddresses[0], serverDevice.MACAddress); err != nil {
+		target := h.replyTarget(dhcp, requestSourceMAC(packet), lease.IP)
+		if err := h.sendDHCPResponse(dhcp.Xid, dhcp.ClientHWAddr, lease.IP, serverDevice.IPAddresses[0], serverDevice.MACAddress, DHCPOffer, target, class); err != nil {
 			if debugLevel >= 1 {
 				logging.ProtocolDebug("DHCP", debugLevel, 1, "Failed to send Offer: %v sn=%d", err, pkt.SerialNumber)
 			}
 		} else {
+			h.recordOffer(transaction, lease.IP, now)
 			h.stack.IncrementStat("dhcp_offers")
 			if debugLevel >= 2 {
 				logging.ProtocolDebug("DHCP", debugLevel, 2, "Sent Offer IP=%s to %s sn=%d", lease.IP, dhcp.ClientHWAddr, pkt.SerialNumber)
@@ -369,7 +545,8 @@ func (h *DHCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, udpLayer *
 		}
 
 		// Send DHCP Ack
-		if err := h.SendDHCPAck(dhcp.Xid, dhcp.ClientHWAddr, lease.IP, serverDevice.IPAddresses[0], serverDevice.MACAddress); err != nil {
+		target := h.replyTarget(dhcp, requestSourceMAC(packet), lease.IP)
+		if err := h.sendDHCPResponse(dhcp.Xid, dhcp.ClientHWAddr, lease.IP, serverDevice.IPAddresses[0], serverDevice.MACAddress, DHCPAck, target, class); err != nil {
 			if debugLevel >= 1 {
 				logging.ProtocolDebug("DHCP", debugLevel, 1, "Failed to send Ack: %v sn=%d", err, pkt.SerialNumber)
 			}
@@ -387,11 +564,33 @@ func (h *DHCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, udpLayer *
 		// Remove lease
 		h.mu.Lock()
 		delete(h.leases, dhcp.ClientHWAddr.String())
+		h.forgetOffers(dhcp.ClientHWAddr.String())
+		h.mu.Unlock()
+
+	case DHCPDecline:
+		if debugLevel >= 2 {
+			fmt.Printf("DHCP: Decline from %s sn=%d\n", dhcp.ClientHWAddr, pkt.SerialNumber)
+		}
+		// The client found the address in use; drop its lease and Offers
+		h.mu.Lock()
+		delete(h.leases, dhcp.ClientHWAddr.String())
+		h.forgetOffers(dhcp.ClientHWAddr.String())
 		h.mu.Unlock()
 
 	case DHCPInform:
-		if debugLevel >= 3 {
-			fmt.Printf("DHCP: Inform from %s sn=%d\n", dhcp.ClientHWAddr, pkt.SerialNumber)
+		// Handle DHCP Inform -> send options-only Ack (no lease)
+		logging.ProtocolDebug("DHCP", debugLevel, 2, "Processing Inform from %s sn=%d", dhcp.ClientHWAddr, pkt.SerialNumber)
+
+		clientIP := dhcp.ClientIP
+		if clientIP == nil || clientIP.IsUnspecified() {
+			clientIP = ipLayer.SrcIP
+		}
+
+		if err := h.SendDHCPInformAck(dhcp.Xid, dhcp.ClientHWAddr, clientIP, serverDevice.IPAddresses[0], serverDevice.MACAddress); err != nil {
+			logging.ProtocolDebug("DHCP", debugLevel, 1, "Failed to send Inform Ack: %v sn=%d", err, pkt.SerialNumber)
+		} else {
+			h.stack.IncrementStat("dhcp_acks")
+			logging.ProtocolDebug("DHCP", debugLevel, 2, "Sent Inform Ack to %s (%s) sn=%d", dhcp.ClientHWAddr, clientIP, pkt.SerialNumber)
 		}
 
 	default:
@@ -425,21 +624,62 @@ func (h *DHCPHandler) dhcpMessageTypeString(msgType uint8) string {
 	}
 }
 
-// SendDHCPOffer sends a DHCP Offer message
+// SendDHCPOffer broadcasts a DHCP Offer message
 func (h *DHCPHandler) SendDHCPOffer(xid uint32, clientMAC net.HardwareAddr, offeredIP, serverIP net.IP, serverMAC net.HardwareAddr) error {
-	return h.sendDHCPResponse(xid, clientMAC, offeredIP, serverIP, serverMAC, DHCPOffer)
+	return h.sendDHCPResponse(xid, clientMAC, offeredIP, serverIP, serverMAC, DHCPOffer, broadcastReplyTarget(), nil)
 }
 
-// SendDHCPAck sends a DHCP Ack message
+// SendDHCPAck broadcasts a DHCP Ack message
 func (h *DHCPHandler) SendDHCPAck(xid uint32, clientMAC net.HardwareAddr, assignedIP, serverIP net.IP, serverMAC net.HardwareAddr) error {
-	return h.sendDHCPResponse(xid, clientMAC, assignedIP, serverIP, serverMAC, DHCPAck)
+	return h.sendDHCPResponse(xid, clientMAC, assignedIP, serverIP, serverMAC, DHCPAck, broadcastReplyTarget(), nil)
+}
+
+// replyTarget chooses where a reply to req goes (RFC 2131 section 4.1):
+// back to the relay agent when giaddr is set, unicast to ciaddr for a client
+// that already has an address, broadcast when the client set the BROADCAST
+// flag, and otherwise unicast to yiaddr at the client's hardware address.
+// always_broadcast overrides everything but relayed requests.
+func (h *DHCPHandler) replyTarget(req *layers.DHCPv4, srcMAC net.HardwareAddr, yiaddr net.IP) dhcpReplyTarget {
+	h.mu.RLock()
+	alwaysBroadcast := h.alwaysBroadcast
+	h.mu.RUnlock()
+
+	giaddr := req.RelayAgentIP.To4()
+	ciaddr := req.ClientIP.To4()
+	switch {
+	case giaddr != nil && !giaddr.IsUnspecified():
+		return dhcpReplyTarget{ip: giaddr, mac: srcMAC, port: 67, flags: req.Flags, ciaddr: net.IPv4zero, giaddr: giaddr}
+	case alwaysBroadcast:
+		return broadcastReplyTarget()
+	case ciaddr != nil && !ciaddr.IsUnspecified():
+		return dhcpReplyTarget{ip: ciaddr, mac: req.ClientHWAddr, port: 68, ciaddr: ciaddr, giaddr: net.IPv4zero}
+	case req.Flags&dhcpBroadcastFlag != 0:
+		return broadcastReplyTarget()
+	default:
+		return dhcpReplyTarget{ip: yiaddr, mac: req.ClientHWAddr, port: 68, ciaddr: net.IPv4zero, giaddr: net.IPv4zero}
+	}
+}
+
+// requestSourceMAC returns the Ethernet source of a request, which for a
+// relayed request is the relay agent.
+func requestSourceMAC(packet gopacket.Packet) net.HardwareAddr {
+	if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		return eth.SrcMAC
+	}
+	return layers.EthernetBroadcast
 }
 
-// sendDHCPResponse sends a DHCP Offer or Ack response
-func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, assignedIP, serverIP net.IP, serverMAC net.HardwareAddr, msgType uint8) error {
+// sendDHCPResponse sends a DHCP Offer or Ack response to target, with the
+// options of the client's class when it has one
+func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, assignedIP, serverIP net.IP, serverMAC net.HardwareAddr, msgType uint8, target dhcpReplyTarget, class *config.DHCPClientClass) error {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	nextServer := net.IPv4zero
+	if class != nil && class.NextServerIP != nil {
+		nextServer = class.NextServerIP
+	}
+
 	// Build DHCP layer
 	dhcp := &layers.DHCPv4{
 		Operation:    layers.DHCPOpReply,
@@ -448,15 +688,63 @@ func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, a
 		HardwareOpts: 0,
 		Xid:          xid,
 		Secs:         0,
-		Flags:        0x8000, // Broadcast flag
-		ClientIP:     net.IPv4zero,
+		Flags:        target.flags,
+		ClientIP:     target.ciaddr,
 		YourClientIP: assignedIP,
+		NextServerIP: nextServer,
+		RelayAgentIP: target.giaddr,
+		ClientHWAddr: clientMAC,
+		Options:      h.buildOptions(msgType, serverIP, clientMAC, true, class),
+	}
+
+	return h.sendDHCPPacket(dhcp, serverIP, serverMAC, target.ip, target.mac, target.port)
+}
+
+// SendDHCPInformAck answers a DHCPINFORM (RFC 2131 section 4.3.5). The client
+// already has an address, so the Ack carries configuration options only: no
+// yiaddr, lease time or renewal timers. It is unicast to ciaddr when known.
+func (h *DHCPHandler) SendDHCPInformAck(xid uint32, clientMAC net.HardwareAddr, clientIP, serverIP net.IP, serverMAC net.HardwareAddr) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ciaddr := clientIP.To4()
+	if ciaddr == nil {
+		ciaddr = net.IPv4zero.To4()
+	}
+
+	dhcp := &layers.DHCPv4{
+		Operation:    layers.DHCPOpReply,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  6,
+		Xid:          xid,
+		ClientIP:     ciaddr,
+		YourClientIP: net.IPv4zero,
 		NextServerIP: net.IPv4zero,
 		RelayAgentIP: net.IPv4zero,
 		ClientHWAddr: clientMAC,
+		Options:      h.buildOptions(DHCPAck, serverIP, clientMAC, false, nil),
+	}
+
+	if ciaddr.IsUnspecified() {
+		dhcp.Flags = dhcpBroadcastFlag
+		return h.sendDHCPPacket(dhcp, serverIP, serverMAC, net.IPv4bcast, layers.EthernetBroadcast, 68)
+	}
+	return h.sendDHCPPacket(dhcp, serverIP, serverMAC, ciaddr, clientMAC, 68)
+}
+
+// buildOptions assembles the reply options from the server configuration.
+// Lease-related options (lease time, T1/T2, leased hostname) are included only
+// when withLease is set. A client class overrides the TFTP server and bootfile
+// and adds its own options. Callers must hold h.mu.
+func (h *DHCPHandler) buildOptions(msgType uint8, serverIP net.IP, clientMAC net.HardwareAddr, withLease bool, class *config.DHCPClientClass) []layers.DHCPOption {
+	tftpServerName, bootfileName := h.tftpServerName, h.bootfileName
+	if class != nil && class.TFTPServerName != "" {
+		tftpServerName = class.TFTPServerName
+	}
+	if class != nil && class.BootfileName != "" {
+		bootfileName = class.BootfileName
 	}
 
-	// Build DHCP options
 	options := []layers.DHCPOption{
 		{
 			Type:   layers.DHCPOptMessageType,
@@ -468,17 +756,19 @@ func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, a
 			Length: 4,
 			Data:   []byte(serverIP.To4()),
 		},
-		{
+	}
+	if withLease {
+		options = append(options, layers.DHCPOption{
 			Type:   layers.DHCPOptLeaseTime,
 			Length: 4,
 			Data:   h.encodeUint32(uint32(DefaultLeaseTime.Seconds())),
-		},
-		{
-			Type:   layers.DHCPOptSubnetMask,
-			Length: 4,
-			Data:   []byte(h.subnetMask.To4()),
-		},
+		})
 	}
+	options = append(options, layers.DHCPOption{
+		Type:   layers.DHCPOptSubnetMask,
+		Length: 4,
+		Data:   []byte(h.subnetMask.To4()),
+	})
 
 	// Add router/gateway if configured
 	if h.gateway != nil {
@@ -511,19 +801,21 @@ func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, a
 		})
 	}
 
-	// Add renewal time (T1) - 50% of lease time
-	options = append(options, layers.DHCPOption{
-		Type:   layers.DHCPOptT1,
-		Length: 4,
-		Data:   h.encodeUint32(uint32(DefaultLeaseTime.Seconds() / 2)),
-	})
+	if withLease {
+		// Add renewal time (T1) - 50% of lease time
+		options = append(options, layers.DHCPOption{
+			Type:   layers.DHCPOptT1,
+			Length: 4,
+			Data:   h.encodeUint32(uint32(DefaultLeaseTime.Seconds() / 2)),
+		})
 
-	// Add rebinding time (T2) - 87.5% of lease time
-	options = append(options, layers.DHCPOption{
-		Type:   layers.DHCPOptT2,
-		Length: 4,
-		Data:   h.encodeUint32(uint32(DefaultLeaseTime.Seconds() * 7 / 8)),
-	})
+		// Add rebinding time (T2) - 87.5% of lease time
+		options = append(options, layers.DHCPOption{
+			Type:   layers.DHCPOptT2,
+			Length: 4,
+			Data:   h.encodeUint32(uint32(DefaultLeaseTime.Seconds() * 7 / 8)),
+		})
+	}
 
 	// Add NTP servers if configured (Option 42)
 	if len(h.ntpServers) > 0 {
@@ -539,20 +831,20 @@ func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, a
 	}
 
 	// Add TFTP server name if configured (Option 66)
-	if h.tftpServerName != "" {
+	if tftpServerName != "" {
 		options = append(options, layers.DHCPOption{
 			Type:   DHCPOptTFTPServer,
-			Length: uint8(len(h.tftpServerName)),
-			Data:   []byte(h.tftpServerName),
+			Length: uint8(len(tftpServerName)),
+			Data:   []byte(tftpServerName),
 		})
 	}
 
 	// Add bootfile name if configured (Option 67)
-	if h.bootfileName != "" {
+	if bootfileName != "" {
 		options = append(options, layers.DHCPOption{
 			Type:   DHCPOptBootfileName,
-			Length: uint8(len(h.bootfileName)),
-			Data:   []byte(h.bootfileName),
+			Length: uint8(len(bootfileName)),
+			Data:   []byte(bootfileName),
 		})
 	}
 
@@ -581,7 +873,7 @@ func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, a
 	}
 
 	// Add hostname from lease if available (Option 12)
-	if lease, ok := h.leases[clientMAC.String()]; ok && lease.Hostname != "" {
+	if lease, ok := h.leases[clientMAC.String()]; withLease && ok && lease.Hostname != "" {
 		options = append(options, layers.DHCPOption{
 			Type:   layers.DHCPOptHostname,
 			Length: uint8(len(lease.Hostname)),
@@ -589,32 +881,40 @@ func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, a
 		})
 	}
 
+	if class != nil {
+		options = withClassOptions(options, class.Options)
+	}
+
 	// End option
 	options = append(options, layers.DHCPOption{
 		Type: layers.DHCPOptEnd,
 	})
 
-	dhcp.Options = options
+	return options
+}
 
+// sendDHCPPacket wraps a DHCP reply in UDP/IPv4/Ethernet and sends it.
+// dstPort is 68 for clients and 67 for relay agents.
+func (h *DHCPHandler) sendDHCPPacket(dhcp *layers.DHCPv4, serverIP net.IP, serverMAC net.HardwareAddr, dstIP net.IP, dstMAC net.HardwareAddr, dstPort layers.UDPPort) error {
 	// Build UDP layer
 	udp := &layers.UDP{
 		SrcPort: 67, // DHCP server port
-		DstPort: 68, // DHCP client port
+		DstPort: dstPort,
 	}
 
 	// Build IP layer
 	ip := &layers.IPv4{
 		Version:  4,
-		TTL:      64,
+		TTL:      h.stack.originTTL(serverMAC),
 		Protocol: layers.IPProtocolUDP,
 		SrcIP:    serverIP,
-		DstIP:    net.IPv4bcast, // Broadcast
+		DstIP:    dstIP,
 	}
 
 	// Build Ethernet layer
 	eth := &layers.Ethernet{
 		SrcMAC:       serverMAC,
-		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // Broadcast
+		DstMAC:       dstMAC,
 		EthernetType: layers.EthernetTypeIPv4,
 	}
 
@@ -632,7 +932,7 @@ func (h *DHCPHandler) sendDHCPResponse(xid uint32, clientMAC net.HardwareAddr, a
 	}
 
 	// Send packet
-	return h.stack.SendRawPacket(buf.Bytes())
+	return h.stack.SendResponse(buf.Bytes())
 }
 
 // encodeUint32 encodes a uint32 as big-endian bytes
@@ -0,0 +1,83 @@
+package protocols
+
+import (
+	"sort"
+
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// clientClassFor returns the first client class whose vendor class (option
+// 60) and user classes (option 77) match the request, or nil
+func (h *DHCPHandler) clientClassFor(req *layers.DHCPv4) *config.DHCPClientClass {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.clientClasses) == 0 {
+		return nil
+	}
+
+	var vendorClass string
+	var userClasses []string
+	for _, opt := range req.Options {
+		switch opt.Type {
+		case layers.DHCPOptClassID:
+			vendorClass = string(opt.Data)
+		case DHCPOptUserClass:
+			userClasses = decodeUserClasses(opt.Data)
+		}
+	}
+	if vendorClass == "" && len(userClasses) == 0 {
+		return nil
+	}
+	for i := range h.clientClasses {
+		if h.clientClasses[i].Matches(vendorClass, userClasses) {
+			return &h.clientClasses[i]
+		}
+	}
+	return nil
+}
+
+// decodeUserClasses splits option 77 into its user classes, each a length
+// byte and that many bytes of data (RFC 3004). Data that is not laid out
+// that way, as sent by iPXE and some older clients, is one class.
+func decodeUserClasses(data []byte) []string {
+	var classes []string
+	for rest := data; len(rest) > 0; {
+		n := int(rest[0])
+		if n == 0 || n >= len(rest) {
+			return []string{string(data)}
+		}
+		classes = append(classes, string(rest[1:1+n]))
+		rest = rest[1+n:]
+	}
+	return classes
+}
+
+// withClassOptions adds a class's extra options to a reply, in code order,
+// replacing any option the server already set with the same code
+func withClassOptions(options []layers.DHCPOption, extra map[uint8][]byte) []layers.DHCPOption {
+	if len(extra) == 0 {
+		return options
+	}
+	kept := options[:0]
+	for _, opt := range options {
+		if _, ok := extra[uint8(opt.Type)]; !ok {
+			kept = append(kept, opt)
+		}
+	}
+
+	codes := make([]int, 0, len(extra))
+	for code := range extra {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		data := extra[uint8(code)]
+		kept = append(kept, layers.DHCPOption{
+			Type:   layers.DHCPOpt(code),
+			Length: uint8(len(data)),
+			Data:   data,
+		})
+	}
+	return kept
+}
@@ -2,9 +2,12 @@ package protocols
 
 import (
 	"net"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
 )
@@ -377,6 +380,43 @@ func TestAllocateLease_PoolExhaustion(t *testing.T) {
 	}
 }
 
+// TestAllocateLease_LeaseGrace tests that an expired address is held for the
+// grace period before another client gets it
+func TestAllocateLease_LeaseGrace(t *testing.T) {
+	cfg := &config.Config{}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewDHCPHandler(stack)
+	handler.SetPool(net.ParseIP("192.168.1.10"), net.ParseIP("192.168.1.10"))
+	handler.SetLeaseGrace(time.Hour)
+
+	mac1 := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01}
+	lease, err := handler.allocateLease(mac1, nil, "")
+	if err != nil {
+		t.Fatalf("Failed to allocate first lease: %v", err)
+	}
+
+	// Expired, but still within the grace period: neither a free pick nor a
+	// requested address gets it
+	lease.Expiry = time.Now().Add(-30 * time.Minute)
+	mac2 := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x02}
+	if _, err := handler.allocateLease(mac2, nil, ""); err == nil {
+		t.Fatal("Expected expired address to be held during the grace period")
+	}
+	if _, err := handler.allocateLease(mac2, net.ParseIP("192.168.1.10"), ""); err == nil {
+		t.Fatal("Expected requested address to be held during the grace period")
+	}
+
+	// Grace period over
+	lease.Expiry = time.Now().Add(-2 * time.Hour)
+	reused, err := handler.allocateLease(mac2, nil, "")
+	if err != nil {
+		t.Fatalf("Expected expired address to be reallocated after the grace period: %v", err)
+	}
+	if !reused.IP.Equal(net.ParseIP("192.168.1.10")) {
+		t.Errorf("Expected 192.168.1.10, got %s", reused.IP)
+	}
+}
+
 // TestIsIPInPool tests IP pool membership checking
 func TestIsIPInPool(t *testing.T) {
 	cfg := &config.Config{}
@@ -439,6 +479,93 @@ func TestDHCPConstants(t *testing.T) {
 	}
 }
 
+// TestHandlePacket_Inform tests that DHCPINFORM gets an options-only Ack
+func TestHandlePacket_Inform(t *testing.T) {
+	serverMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	serverIP := net.ParseIP("192.168.1.1").To4()
+	clientMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+	clientIP := net.ParseIP("192.168.1.50").To4()
+
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{Name: "dhcp-server", MACAddress: serverMAC, IPAddresses: []net.IP{serverIP}},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewDHCPHandler(stack)
+	handler.SetServerConfig(serverIP, serverIP, []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("8.8.4.4")}, "example.com")
+
+	inform := &layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  6,
+		Xid:          0x1234abcd,
+		ClientIP:     clientIP,
+		YourClientIP: net.IPv4zero,
+		NextServerIP: net.IPv4zero,
+		RelayAgentIP: net.IPv4zero,
+		ClientHWAddr: clientMAC,
+		Options: []layers.DHCPOption{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{DHCPInform}),
+			layers.NewDHCPOption(layers.DHCPOptEnd, nil),
+		},
+	}
+	eth := &layers.Ethernet{SrcMAC: clientMAC, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: clientIP, DstIP: net.IPv4bcast}
+	udp := &layers.UDP{SrcPort: 68, DstPort: 67}
+	udp.SetNetworkLayerForChecksum(ip)
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}, eth, ip, udp, inform); err != nil {
+		t.Fatalf("serialize inform: %v", err)
+	}
+
+	handler.HandlePacket(&Packet{Buffer: buf.Bytes(), Length: len(buf.Bytes())}, ip, udp, []*config.Device{&cfg.Devices[0]})
+
+	var resp *Packet
+	select {
+	case resp = <-stack.sendQueue:
+	default:
+		t.Fatal("expected DHCP Ack in response to Inform")
+	}
+
+	decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	ack, ok := decoded.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+	if !ok {
+		t.Fatal("response is not DHCP")
+	}
+	if !ack.YourClientIP.Equal(net.IPv4zero) {
+		t.Errorf("expected zero yiaddr, got %v", ack.YourClientIP)
+	}
+	if !ack.ClientIP.Equal(clientIP) {
+		t.Errorf("expected ciaddr %v, got %v", clientIP, ack.ClientIP)
+	}
+	if respIP, ok := decoded.Layer(layers.LayerTypeIPv4).(*layers.IPv4); !ok || !respIP.DstIP.Equal(clientIP) {
+		t.Errorf("expected Ack unicast to %v", clientIP)
+	}
+
+	opts := make(map[layers.DHCPOpt][]byte)
+	for _, opt := range ack.Options {
+		opts[opt.Type] = opt.Data
+	}
+	if msgType := opts[layers.DHCPOptMessageType]; len(msgType) != 1 || msgType[0] != DHCPAck {
+		t.Errorf("expected DHCPACK message type, got %v", msgType)
+	}
+	if dns := opts[layers.DHCPOptDNS]; len(dns) != 8 || !net.IP(dns[:4]).Equal(net.ParseIP("8.8.8.8")) {
+		t.Errorf("expected DNS servers option, got %v", dns)
+	}
+	if domain := string(opts[layers.DHCPOptDomainName]); domain != "example.com" {
+		t.Errorf("expected domain example.com, got %q", domain)
+	}
+	for _, lease := range []layers.DHCPOpt{layers.DHCPOptLeaseTime, layers.DHCPOptT1, layers.DHCPOptT2} {
+		if _, present := opts[lease]; present {
+			t.Errorf("Inform Ack must not carry lease option %v", lease)
+		}
+	}
+	if len(handler.leases) != 0 {
+		t.Errorf("Inform must not create a lease, got %d", len(handler.leases))
+	}
+}
+
 // TestDefaultLeaseTime tests the default lease time constant
 func TestDefaultLeaseTime(t *testing.T) {
 	expectedDuration := 24 * time.Hour
@@ -494,3 +621,331 @@ func BenchmarkFindAvailableIP(b *testing.B) {
 // - All DHCP message type constants
 // This provides good coverage of the core DHCP logic without the complexity
 // of full packet flow testing.
+
+// TestHandlePacket_OfferAddressing tests that the Offer honors the client's
+// BROADCAST flag, the always_broadcast override and relay agents
+func TestHandlePacket_OfferAddressing(t *testing.T) {
+	serverMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	serverIP := net.ParseIP("192.168.1.1").To4()
+	clientMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+	relayMAC := net.HardwareAddr{0x00, 0x99, 0x88, 0x77, 0x66, 0x55}
+	relayIP := net.ParseIP("10.20.0.1").To4()
+
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{Name: "dhcp-server", MACAddress: serverMAC, IPAddresses: []net.IP{serverIP}},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewDHCPHandler(stack)
+	handler.SetPool(net.ParseIP("192.168.1.100"), net.ParseIP("192.168.1.110"))
+	handler.SetServerConfig(serverIP, serverIP, nil, "")
+
+	// offer sends a DISCOVER and returns the Offer's IPv4, UDP and DHCP layers
+	offer := func(flags uint16, giaddr net.IP, srcMAC net.HardwareAddr) (*layers.Ethernet, *layers.IPv4, *layers.UDP, *layers.DHCPv4) {
+		t.Helper()
+		discover := &layers.DHCPv4{
+			Operation:    layers.DHCPOpRequest,
+			HardwareType: layers.LinkTypeEthernet,
+			HardwareLen:  6,
+			Xid:          0x5150,
+			Flags:        flags,
+			ClientIP:     net.IPv4zero,
+			YourClientIP: net.IPv4zero,
+			NextServerIP: net.IPv4zero,
+			RelayAgentIP: giaddr,
+			ClientHWAddr: clientMAC,
+			Options: []layers.DHCPOption{
+				layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{DHCPDiscover}),
+				layers.NewDHCPOption(layers.DHCPOptEnd, nil),
+			},
+		}
+		eth := &layers.Ethernet{SrcMAC: srcMAC, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+		ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IPv4zero, DstIP: net.IPv4bcast}
+		udp := &layers.UDP{SrcPort: 68, DstPort: 67}
+		udp.SetNetworkLayerForChecksum(ip)
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}, eth, ip, udp, discover); err != nil {
+			t.Fatalf("serialize discover: %v", err)
+		}
+
+		handler.HandlePacket(&Packet{Buffer: buf.Bytes(), Length: len(buf.Bytes())}, ip, udp, []*config.Device{&cfg.Devices[0]})
+
+		var resp *Packet
+		select {
+		case resp = <-stack.sendQueue:
+		default:
+			t.Fatal("expected DHCP Offer in response to Discover")
+		}
+		decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		respEth, _ := decoded.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+		respIP, _ := decoded.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		respUDP, _ := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		respDHCP, _ := decoded.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+		if respEth == nil || respIP == nil || respUDP == nil || respDHCP == nil {
+			t.Fatal("response is not a DHCP packet")
+		}
+		return respEth, respIP, respUDP, respDHCP
+	}
+
+	// Broadcast flag set: Offer goes to the broadcast address
+	eth, ip, _, reply := offer(dhcpBroadcastFlag, net.IPv4zero, clientMAC)
+	if !ip.DstIP.Equal(net.IPv4bcast) || eth.DstMAC.String() != layers.EthernetBroadcast.String() {
+		t.Errorf("expected broadcast Offer with flag set, got %s / %s", ip.DstIP, eth.DstMAC)
+	}
+	if reply.Flags&dhcpBroadcastFlag == 0 {
+		t.Error("expected broadcast flag echoed in Offer")
+	}
+
+	// Broadcast flag clear: Offer is unicast to yiaddr at the client MAC
+	eth, ip, _, reply = offer(0, net.IPv4zero, clientMAC)
+	if !ip.DstIP.Equal(reply.YourClientIP) || eth.DstMAC.String() != clientMAC.String() {
+		t.Errorf("expected Offer unicast to %s at %s, got %s / %s", reply.YourClientIP, clientMAC, ip.DstIP, eth.DstMAC)
+	}
+
+	// always_broadcast overrides a clear flag
+	handler.SetAlwaysBroadcast(true)
+	eth, ip, _, _ = offer(0, net.IPv4zero, clientMAC)
+	if !ip.DstIP.Equal(net.IPv4bcast) || eth.DstMAC.String() != layers.EthernetBroadcast.String() {
+		t.Errorf("expected broadcast Offer with always_broadcast, got %s / %s", ip.DstIP, eth.DstMAC)
+	}
+	handler.SetAlwaysBroadcast(false)
+
+	// Relayed requests go back to the relay agent on the server port
+	eth, ip, udp, reply := offer(0, relayIP, relayMAC)
+	if !ip.DstIP.Equal(relayIP) || eth.DstMAC.String() != relayMAC.String() || udp.DstPort != 67 {
+		t.Errorf("expected Offer to relay %s:67 at %s, got %s:%d / %s", relayIP, relayMAC, ip.DstIP, udp.DstPort, eth.DstMAC)
+	}
+	if !reply.RelayAgentIP.Equal(relayIP) {
+		t.Errorf("expected giaddr echoed, got %s", reply.RelayAgentIP)
+	}
+}
+
+// TestHandlePacket_DiscoverRetransmit tests that a retransmitted DISCOVER is
+// answered with the original Offer without allocating or counting again
+func TestHandlePacket_DiscoverRetransmit(t *testing.T) {
+	serverIP := net.ParseIP("192.168.1.1").To4()
+	clientMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{Name: "dhcp-server", MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, IPAddresses: []net.IP{serverIP}},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewDHCPHandler(stack)
+	handler.SetPool(net.ParseIP("192.168.1.100"), net.ParseIP("192.168.1.110"))
+	handler.SetServerConfig(serverIP, serverIP, nil, "")
+
+	// send sends the client's message of msgType with xid, returning the replies
+	send := func(msgType uint8, xid uint32) []*Packet {
+		t.Helper()
+		msg := &layers.DHCPv4{
+			Operation:    layers.DHCPOpRequest,
+			HardwareType: layers.LinkTypeEthernet,
+			HardwareLen:  6,
+			Xid:          xid,
+			Flags:        dhcpBroadcastFlag,
+			ClientIP:     net.IPv4zero,
+			YourClientIP: net.IPv4zero,
+			NextServerIP: net.IPv4zero,
+			RelayAgentIP: net.IPv4zero,
+			ClientHWAddr: clientMAC,
+			Options: []layers.DHCPOption{
+				layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{msgType}),
+				layers.NewDHCPOption(layers.DHCPOptEnd, nil),
+			},
+		}
+		eth := &layers.Ethernet{SrcMAC: clientMAC, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+		ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IPv4zero, DstIP: net.IPv4bcast}
+		udp := &layers.UDP{SrcPort: 68, DstPort: 67}
+		udp.SetNetworkLayerForChecksum(ip)
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}, eth, ip, udp, msg); err != nil {
+			t.Fatalf("serialize %s: %v", handler.dhcpMessageTypeString(msgType), err)
+		}
+
+		handler.HandlePacket(&Packet{Buffer: buf.Bytes(), Length: len(buf.Bytes())}, ip, udp, []*config.Device{&cfg.Devices[0]})
+		return drainSendQueue(stack)
+	}
+
+	// discover sends a DISCOVER with xid and returns the offered address
+	discover := func(xid uint32) net.IP {
+		t.Helper()
+		sent := send(DHCPDiscover, xid)
+		if len(sent) != 1 {
+			t.Fatalf("got %d replies to Discover, want one Offer", len(sent))
+		}
+		decoded := gopacket.NewPacket(sent[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		reply, ok := decoded.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+		if !ok || reply.Xid != xid {
+			t.Fatalf("reply is not an Offer for xid 0x%x: %v", xid, decoded)
+		}
+		return reply.YourClientIP
+	}
+
+	first := discover(0x7001)
+	if again := discover(0x7001); !again.Equal(first) {
+		t.Errorf("retransmitted Discover offered %s, want %s", again, first)
+	}
+	if got := stack.GetStats().DHCPRetransmits; got != 1 {
+		t.Errorf("dhcp_retransmits = %d, want 1", got)
+	}
+	if len(handler.leases) != 1 || len(handler.offers) != 1 {
+		t.Errorf("got %d leases and %d offers, want one allocation", len(handler.leases), len(handler.offers))
+	}
+
+	// A new transaction is a fresh Discover
+	discover(0x7002)
+	if got := stack.GetStats().DHCPRetransmits; got != 1 || len(handler.offers) != 2 {
+		t.Errorf("after new xid: dhcp_retransmits=%d with %d offers, want 1 and 2", got, len(handler.offers))
+	}
+
+	// Outside the window the repeated xid is treated as a new Discover
+	handler.offers[dhcpTransactionKey{mac: clientMAC.String(), xid: 0x7002}].offered = time.Now().Add(-2 * config.DefaultDHCPRetransmitWindow * time.Second)
+	discover(0x7002)
+	if stats := stack.GetStats(); stats.DHCPRetransmits != 1 {
+		t.Errorf("Discover outside the window counted as a retransmit (%d)", stats.DHCPRetransmits)
+	}
+
+	// Once the client releases or declines its address, a repeated xid is
+	// not answered from the cache
+	for _, msgType := range []uint8{DHCPRelease, DHCPDecline} {
+		discover(0x7003)
+		send(msgType, 0x7004)
+		if len(handler.leases) != 0 || len(handler.offers) != 0 {
+			t.Errorf("after %s: %d leases and %d offers remain, want none",
+				handler.dhcpMessageTypeString(msgType), len(handler.leases), len(handler.offers))
+		}
+		before := stack.GetStats().DHCPRetransmits
+		discover(0x7003)
+		if got := stack.GetStats().DHCPRetransmits; got != before {
+			t.Errorf("Discover after %s answered from the cache", handler.dhcpMessageTypeString(msgType))
+		}
+		send(DHCPRelease, 0x7005)
+	}
+}
+
+// TestHandlePacket_ClientClass tests that a PXE client's Offer carries its
+// class's boot options while other clients get the server-wide ones
+func TestHandlePacket_ClientClass(t *testing.T) {
+	serverIP := net.ParseIP("192.168.1.1").To4()
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{Name: "dhcp-server", MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, IPAddresses: []net.IP{serverIP}},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewDHCPHandler(stack)
+	handler.SetPool(net.ParseIP("192.168.1.100"), net.ParseIP("192.168.1.110"))
+	handler.SetServerConfig(serverIP, serverIP, nil, "")
+	handler.SetAdvancedOptions(nil, nil, "", "default.cfg", nil)
+	handler.SetClientClasses([]config.DHCPClientClass{{
+		Name:           "pxe",
+		VendorClass:    regexp.MustCompile(regexp.QuoteMeta("PXEClient")),
+		TFTPServerName: "192.168.1.5",
+		BootfileName:   "undionly.kpxe",
+		NextServerIP:   net.ParseIP("192.168.1.5").To4(),
+		Options:        map[uint8][]byte{224: []byte("lab")},
+	}})
+
+	// offer sends a DISCOVER with the given vendor class and returns the Offer
+	offer := func(mac net.HardwareAddr, vendorClass string) *layers.DHCPv4 {
+		t.Helper()
+		options := []layers.DHCPOption{layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{DHCPDiscover})}
+		if vendorClass != "" {
+			options = append(options, layers.NewDHCPOption(layers.DHCPOptClassID, []byte(vendorClass)))
+		}
+		msg := &layers.DHCPv4{
+			Operation:    layers.DHCPOpRequest,
+			HardwareType: layers.LinkTypeEthernet,
+			HardwareLen:  6,
+			Xid:          0x9001,
+			Flags:        dhcpBroadcastFlag,
+			ClientIP:     net.IPv4zero,
+			YourClientIP: net.IPv4zero,
+			NextServerIP: net.IPv4zero,
+			RelayAgentIP: net.IPv4zero,
+			ClientHWAddr: mac,
+			Options:      append(options, layers.NewDHCPOption(layers.DHCPOptEnd, nil)),
+		}
+		eth := &layers.Ethernet{SrcMAC: mac, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+		ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolUDP, SrcIP: net.IPv4zero, DstIP: net.IPv4bcast}
+		udp := &layers.UDP{SrcPort: 68, DstPort: 67}
+		udp.SetNetworkLayerForChecksum(ip)
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}, eth, ip, udp, msg); err != nil {
+			t.Fatalf("serialize discover: %v", err)
+		}
+
+		handler.HandlePacket(&Packet{Buffer: buf.Bytes(), Length: len(buf.Bytes())}, ip, udp, []*config.Device{&cfg.Devices[0]})
+
+		sent := drainSendQueue(stack)
+		if len(sent) != 1 {
+			t.Fatalf("got %d replies to Discover, want one Offer", len(sent))
+		}
+		decoded := gopacket.NewPacket(sent[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		reply, ok := decoded.Layer(layers.LayerTypeDHCPv4).(*layers.DHCPv4)
+		if !ok {
+			t.Fatal("reply is not a DHCP packet")
+		}
+		return reply
+	}
+	option := func(reply *layers.DHCPv4, code layers.DHCPOpt) string {
+		for _, opt := range reply.Options {
+			if opt.Type == code {
+				return string(opt.Data)
+			}
+		}
+		return ""
+	}
+
+	pxe := offer(net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}, "PXEClient:Arch:00000:UNDI:002001")
+	if got := option(pxe, DHCPOptBootfileName); got != "undionly.kpxe" {
+		t.Errorf("PXE client bootfile = %q, want undionly.kpxe", got)
+	}
+	if got := option(pxe, DHCPOptTFTPServer); got != "192.168.1.5" {
+		t.Errorf("PXE client TFTP server = %q, want 192.168.1.5", got)
+	}
+	if got := option(pxe, 224); got != "lab" {
+		t.Errorf("PXE client option 224 = %q, want lab", got)
+	}
+	if !pxe.NextServerIP.Equal(net.ParseIP("192.168.1.5")) {
+		t.Errorf("PXE client siaddr = %s, want 192.168.1.5", pxe.NextServerIP)
+	}
+
+	plain := offer(net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x02}, "MSFT 5.0")
+	if got := option(plain, DHCPOptBootfileName); got != "default.cfg" {
+		t.Errorf("non-PXE client bootfile = %q, want the server-wide default.cfg", got)
+	}
+	if option(plain, DHCPOptTFTPServer) != "" || option(plain, 224) != "" || !plain.NextServerIP.Equal(net.IPv4zero) {
+		t.Error("non-PXE client received PXE class options")
+	}
+}
+
+// TestDecodeUserClasses tests splitting option 77 into its user classes
+func TestDecodeUserClasses(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"RFC 3004 instances", []byte("\x04iPXE\x03lab"), []string{"iPXE", "lab"}},
+		{"single instance", []byte("\x04iPXE"), []string{"iPXE"}},
+		{"raw text", []byte("iPXE"), []string{"iPXE"}},
+		{"overrunning length", []byte("\x09iPXE"), []string{"\x09iPXE"}},
+		{"zero length", []byte("\x00\x04iPXE"), []string{"\x00\x04iPXE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeUserClasses(tt.data)
+			if len(got) != len(tt.want) {
+				t.Fatalf("decodeUserClasses(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("decodeUserClasses(%q) = %q, want %q", tt.data, got, tt.want)
+				}
+			}
+		})
+	}
+}
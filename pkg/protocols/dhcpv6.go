@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/gopacket"
@@ -65,12 +66,13 @@ const (
 
 // DHCPv6 status codes
 const (
-	DHCPv6StatusSuccess      = 0
-	DHCPv6StatusUnspecFail   = 1
-	DHCPv6StatusNoAddrsAvail = 2
-	DHCPv6StatusNoBinding    = 3
-	DHCPv6StatusNotOnLink    = 4
-	DHCPv6StatusUseMulticast = 5
+	DHCPv6StatusSuccess       = 0
+	DHCPv6StatusUnspecFail    = 1
+	DHCPv6StatusNoAddrsAvail  = 2
+	DHCPv6StatusNoBinding     = 3
+	DHCPv6StatusNotOnLink     = 4
+	DHCPv6StatusUseMulticast  = 5
+	DHCPv6StatusNoPrefixAvail = 6
 )
 
 // DHCPv6 DUID types (RFC 8415)
@@ -121,6 +123,14 @@ type DHCPv6Lease struct {
 	PreferredLifetime time.Time
 	ValidLifetime     time.Time
 	LastRenewal       time.Time
+
+	// Client endpoint and Reconfigure support (RFC 8415 section 18.2.11)
+	ClientIP          net.IP
+	ClientMAC         net.HardwareAddr
+	ReconfigureAccept bool   // Client sent the Reconfigure Accept option
+	reconfigureKey    []byte // RKAP key handed to the client in Reply
+	serverIP          net.IP
+	serverMAC         net.HardwareAddr
 }
 
 // DHCPv6Handler handles DHCPv6 server functionality
@@ -132,18 +142,20 @@ type DHCPv6Handler struct {
 	serverDUID        []byte
 	preferredLifetime time.Duration
 	validLifetime     time.Duration
+	leaseGrace        time.Duration // Expired addresses are held this long before reuse
 	dnsServers        []net.IP
 	domainList        []string
-	sntpServers       []net.IP // Option 31: SNTP servers
-	ntpServers        []net.IP // Option 56: NTP servers
-	sipServers        []net.IP // Option 22: SIP server addresses
-	sipDomains        []string // Option 21: SIP domain names
+	sntpServers       []net.IP      // Option 31: SNTP servers
+	ntpServers        []net.IP      // Option 56: NTP servers
+	sipServers        []net.IP      // Option 22: SIP server addresses
+	sipDomains        []string      // Option 21: SIP domain names
+	replayCounter     atomic.Uint64 // Authentication option replay detection (RFC 8415 section 20.3)
 	mu                sync.RWMutex
 }
 
 // NewDHCPv6Handler creates a new DHCPv6 handler
 func NewDHCPv6Handler(stack *Stack) *DHCPv6Handler {
-	return &DHCPv6Handler{
+	h := &DHCPv6Handler{
 		stack:             stack,
 		leases:            make(map[string]*DHCPv6Lease),
 		addressPool:       make([]net.IP, 0),
@@ -152,6 +164,9 @@ func NewDHCPv6Handler(stack *Stack) *DHCPv6Handler {
 		preferredLifetime: DefaultPreferredLifetime,
 		validLifetime:     DefaultValidLifetime,
 	}
+	// Replay detection values must increase across restarts too
+	h.replayCounter.Store(uint64(time.Now().Unix()) << 32)
+	return h
 }
 
 // Reset clears DHCPv6 leases and cached options.
@@ -164,6 +179,7 @@ func (h *DHCPv6Handler) Reset() {
 	h.serverDUID = generateDUID()
 	h.preferredLifetime = DefaultPreferredLifetime
 	h.validLifetime = DefaultValidLifetime
+	h.leaseGrace = 0
 	h.dnsServers = nil
 	h.domainList = nil
 	h.sntpServers = nil
@@ -195,6 +211,91 @@ func (h *DHCPv6Handler) SetAddressPool(addresses []net.IP) {
 	h.addressPool = addresses
 }
 
+// SetPools configures the address pool from configured pools. Each pool
+// hands out its range, or without one its network from ::1, skipping
+// exclude (the server's own addresses) and stopping at MaxPoolSize
+// addresses.
+func (h *DHCPv6Handler) SetPools(pools []config.DHCPv6Pool, exclude []net.IP) {
+	var addresses []net.IP
+	for _, pool := range pools {
+		addresses = append(addresses, dhcpv6PoolAddresses(pool, exclude)...)
+	}
+	h.SetAddressPool(addresses)
+}
+
+// dhcpv6PoolAddresses returns the addresses pool hands out, at most
+// MaxPoolSize of them. Pools are validated when the config is loaded.
+func dhcpv6PoolAddresses(pool config.DHCPv6Pool, exclude []net.IP) []net.IP {
+	_, network, err := net.ParseCIDR(pool.Network)
+	if err != nil {
+		return nil
+	}
+	start, end := nextIP(network.IP), lastIP(network)
+	if pool.RangeStart != "" {
+		start, end = net.ParseIP(pool.RangeStart), net.ParseIP(pool.RangeEnd)
+	}
+
+	var addresses []net.IP
+	for addr := start.To16(); len(addresses) < MaxPoolSize; addr = nextIP(addr) {
+		if !ipInList(addr, exclude) {
+			addresses = append(addresses, addr)
+		}
+		if addr.Equal(end) {
+			break
+		}
+	}
+	return addresses
+}
+
+// nextIP returns the address after ip
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// lastIP returns the last address in network
+func lastIP(network *net.IPNet) net.IP {
+	last := make(net.IP, len(network.IP))
+	for i := range last {
+		last[i] = network.IP[i] | ^network.Mask[i]
+	}
+	return last
+}
+
+// ipInList reports whether ip is one of list
+func ipInList(ip net.IP, list []net.IP) bool {
+	for _, candidate := range list {
+		if candidate.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLifetimes configures the preferred and valid lifetimes of new and
+// renewed leases
+func (h *DHCPv6Handler) SetLifetimes(preferred, valid time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.preferredLifetime = preferred
+	h.validLifetime = valid
+}
+
+// SetLeaseGrace holds an expired lease's address for grace after its valid
+// lifetime ends, so it is not handed to another client straight away.
+func (h *DHCPv6Handler) SetLeaseGrace(grace time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leaseGrace = grace
+}
+
 // SetPrefixPool configures the DHCPv6 prefix delegation pool
 func (h *DHCPv6Handler) SetPrefixPool(prefixes []net.IPNet) {
 	h.mu.Lock()
@@ -232,6 +333,7 @@ func (h *DHCPv6Handler) HandlePacket(pkt *Packet, ipv6Layer *layers.IPv6, udpLay
 		if debugLevel >= 2 {
 			fmt.Printf("DHCPv6: Failed to parse message: %v sn=%d\n", err, pkt.SerialNumber)
 		}
+		h.stack.recordErrorPacket(pkt, "dhcpv6", err)
 		return
 	}
 
@@ -276,16 +378,16 @@ func (h *DHCPv6Handler) HandlePacket(pkt *Packet, ipv6Layer *layers.IPv6, udpLay
 	// Handle message based on type
 	switch msg.MessageType {
 	case DHCPv6Solicit:
-		h.handleSolicit(msg, ipv6Layer.SrcIP, serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
+		h.handleSolicit(msg, ipv6Layer.SrcIP, pkt.GetSourceMAC(), serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
 
 	case DHCPv6Request:
-		h.handleRequest(msg, ipv6Layer.SrcIP, serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
+		h.handleRequest(msg, ipv6Layer.SrcIP, pkt.GetSourceMAC(), serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
 
 	case DHCPv6Renew:
-		h.handleRenew(msg, ipv6Layer.SrcIP, serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
+		h.handleRenew(msg, ipv6Layer.SrcIP, pkt.GetSourceMAC(), serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
 
 	case DHCPv6Rebind:
-		h.handleRebind(msg, ipv6Layer.SrcIP, serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
+		h.handleRebind(msg, ipv6Layer.SrcIP, pkt.GetSourceMAC(), serverIP, serverDevice.MACAddress, serverDevice, pkt.SerialNumber)
 
 	case DHCPv6Release:
 		h.handleRelease(msg, pkt.SerialNumber)
@@ -371,6 +473,16 @@ func (h *DHCPv6Handler) extractIANA(msg *DHCPv6Message) (uint32, bool) {
 	return 0, false
 }
 
+// extractIAPD extracts the IAID of the IA_PD option from message
+func (h *DHCPv6Handler) extractIAPD(msg *DHCPv6Message) (uint32, bool) {
+	opt := h.findOption(msg, DHCPv6OptIAPD)
+	if opt != nil && len(opt.Data) >= 4 {
+		iaid := binary.BigEndian.Uint32(opt.Data[0:4])
+		return iaid, true
+	}
+	return 0, false
+}
+
 // duidString converts DUID bytes to hex string for map key
 func duidString(duid []byte) string {
 	return fmt.Sprintf("%x", duid)
@@ -379,7 +491,7 @@ func duidString(duid []byte) string {
 // Continue in next part...
 
 // handleSolicit processes DHCPv6 Solicit message
-func (h *DHCPv6Handler) handleSolicit(msg *DHCPv6Message, clientIP, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
+func (h *DHCPv6Handler) handleSolicit(msg *DHCPv6Message, clientIP net.IP, clientMAC net.HardwareAddr, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
 	debugLevel := h.stack.GetDebugLevel()
 
 	clientDUID := h.extractClientDUID(msg)
@@ -391,21 +503,32 @@ func (h *DHCPv6Handler) handleSolicit(msg *DHCPv6Message, clientIP, serverIP net
 	}
 
 	iaid, hasIANA := h.extractIANA(msg)
-	if !hasIANA {
+	_, hasIAPD := h.extractIAPD(msg)
+	if !hasIANA && !hasIAPD {
 		if debugLevel >= 2 {
-			fmt.Printf("DHCPv6: Solicit missing IANA sn=%d\n", sn)
+			fmt.Printf("DHCPv6: Solicit missing IANA and IA_PD sn=%d\n", sn)
 		}
 		return
 	}
 
 	// Allocate or find existing lease
-	lease, err := h.allocateLease(clientDUID, iaid)
-	if err != nil {
-		if debugLevel >= 1 {
-			fmt.Printf("DHCPv6: Failed to allocate address: %v sn=%d\n", err, sn)
+	var lease *DHCPv6Lease
+	if hasIANA {
+		var err error
+		if lease, err = h.allocateLease(clientDUID, iaid); err != nil {
+			if debugLevel >= 1 {
+				fmt.Printf("DHCPv6: Failed to allocate address: %v sn=%d\n", err, sn)
+			}
+			return
 		}
-		return
+	} else {
+		lease = h.prefixLease(clientDUID)
 	}
+	if hasIAPD && !h.allocatePrefix(lease) && debugLevel >= 1 {
+		fmt.Printf("DHCPv6: No prefix available for delegation sn=%d\n", sn)
+	}
+
+	h.recordClient(lease, msg, clientIP, clientMAC, serverIP, serverMAC)
 
 	// Send Advertise
 	if err := h.sendAdvertise(msg, lease, clientIP, serverIP, serverMAC, device); err != nil {
@@ -415,13 +538,13 @@ func (h *DHCPv6Handler) handleSolicit(msg *DHCPv6Message, clientIP, serverIP net
 	} else {
 		h.stack.IncrementStat("dhcp_offers")
 		if debugLevel >= 2 {
-			fmt.Printf("DHCPv6: Sent Advertise with %s sn=%d\n", lease.Address, sn)
+			fmt.Printf("DHCPv6: Sent Advertise with %s sn=%d\n", lease.describe(), sn)
 		}
 	}
 }
 
 // handleRequest processes DHCPv6 Request message
-func (h *DHCPv6Handler) handleRequest(msg *DHCPv6Message, clientIP, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
+func (h *DHCPv6Handler) handleRequest(msg *DHCPv6Message, clientIP net.IP, clientMAC net.HardwareAddr, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
 	debugLevel := h.stack.GetDebugLevel()
 
 	clientDUID := h.extractClientDUID(msg)
@@ -433,22 +556,33 @@ func (h *DHCPv6Handler) handleRequest(msg *DHCPv6Message, clientIP, serverIP net
 	}
 
 	iaid, hasIANA := h.extractIANA(msg)
-	if !hasIANA {
+	_, hasIAPD := h.extractIAPD(msg)
+	if !hasIANA && !hasIAPD {
 		if debugLevel >= 2 {
-			fmt.Printf("DHCPv6: Request missing IANA sn=%d\n", sn)
+			fmt.Printf("DHCPv6: Request missing IANA and IA_PD sn=%d\n", sn)
 		}
 		return
 	}
 
 	// Confirm or allocate lease
-	lease, err := h.confirmLease(clientDUID, iaid)
-	if err != nil {
-		if debugLevel >= 1 {
-			fmt.Printf("DHCPv6: Failed to confirm lease: %v sn=%d\n", err, sn)
+	var lease *DHCPv6Lease
+	if hasIANA {
+		var err error
+		if lease, err = h.confirmLease(clientDUID, iaid); err != nil {
+			if debugLevel >= 1 {
+				fmt.Printf("DHCPv6: Failed to confirm lease: %v sn=%d\n", err, sn)
+			}
+			return
 		}
-		return
+	} else {
+		lease = h.prefixLease(clientDUID)
+	}
+	if hasIAPD && !h.allocatePrefix(lease) && debugLevel >= 1 {
+		fmt.Printf("DHCPv6: No prefix available for delegation sn=%d\n", sn)
 	}
 
+	h.recordClient(lease, msg, clientIP, clientMAC, serverIP, serverMAC)
+
 	// Send Reply
 	if err := h.sendReply(msg, lease, clientIP, serverIP, serverMAC, device); err != nil {
 		if debugLevel >= 1 {
@@ -457,13 +591,13 @@ func (h *DHCPv6Handler) handleRequest(msg *DHCPv6Message, clientIP, serverIP net
 	} else {
 		h.stack.IncrementStat("dhcp_acks")
 		if debugLevel >= 2 {
-			fmt.Printf("DHCPv6: Sent Reply with %s sn=%d\n", lease.Address, sn)
+			fmt.Printf("DHCPv6: Sent Reply with %s sn=%d\n", lease.describe(), sn)
 		}
 	}
 }
 
 // handleRenew processes DHCPv6 Renew message
-func (h *DHCPv6Handler) handleRenew(msg *DHCPv6Message, clientIP, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
+func (h *DHCPv6Handler) handleRenew(msg *DHCPv6Message, clientIP net.IP, clientMAC net.HardwareAddr, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
 	debugLevel := h.stack.GetDebugLevel()
 
 	clientDUID := h.extractClientDUID(msg)
@@ -479,22 +613,26 @@ func (h *DHCPv6Handler) handleRenew(msg *DHCPv6Message, clientIP, serverIP net.I
 		return
 	}
 
-	// Renew lease
+	// Renew lease; a client that now asks for a prefix is given one
 	h.renewLease(lease)
+	if _, hasIAPD := h.extractIAPD(msg); hasIAPD {
+		h.allocatePrefix(lease)
+	}
+	h.recordClient(lease, msg, clientIP, clientMAC, serverIP, serverMAC)
 
 	if err := h.sendReply(msg, lease, clientIP, serverIP, serverMAC, device); err != nil {
 		if debugLevel >= 1 {
 			fmt.Printf("DHCPv6: Failed to send Renew Reply: %v sn=%d\n", err, sn)
 		}
 	} else if debugLevel >= 2 {
-		fmt.Printf("DHCPv6: Renewed lease for %s sn=%d\n", lease.Address, sn)
+		fmt.Printf("DHCPv6: Renewed lease for %s sn=%d\n", lease.describe(), sn)
 	}
 }
 
 // handleRebind processes DHCPv6 Rebind message
-func (h *DHCPv6Handler) handleRebind(msg *DHCPv6Message, clientIP, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
+func (h *DHCPv6Handler) handleRebind(msg *DHCPv6Message, clientIP net.IP, clientMAC net.HardwareAddr, serverIP net.IP, serverMAC net.HardwareAddr, device *config.Device, sn int) {
 	// Rebind is similar to Renew but without server ID check
-	h.handleRenew(msg, clientIP, serverIP, serverMAC, device, sn)
+	h.handleRenew(msg, clientIP, clientMAC, serverIP, serverMAC, device, sn)
 }
 
 // handleRelease processes DHCPv6 Release message
@@ -557,6 +695,13 @@ func (h *DHCPv6Handler) allocateLease(clientDUID []byte, iaid uint32) (*DHCPv6Le
 
 	// Check if client already has a lease
 	if existing, ok := h.leases[duidKey]; ok {
+		// A lease that so far holds only a delegated prefix gets an address
+		if existing.Address == nil {
+			if existing.Address = h.findAvailableAddress(); existing.Address == nil {
+				return nil, fmt.Errorf("no available addresses")
+			}
+			existing.IAID = iaid
+		}
 		// Renew existing lease
 		h.renewLeaseUnlocked(existing)
 		return existing, nil
@@ -590,7 +735,7 @@ func (h *DHCPv6Handler) confirmLease(clientDUID []byte, iaid uint32) (*DHCPv6Lea
 
 	duidKey := duidString(clientDUID)
 
-	if existing, ok := h.leases[duidKey]; ok {
+	if existing, ok := h.leases[duidKey]; ok && existing.Address != nil {
 		h.renewLeaseUnlocked(existing)
 		return existing, nil
 	}
@@ -602,6 +747,41 @@ func (h *DHCPv6Handler) confirmLease(clientDUID []byte, iaid uint32) (*DHCPv6Lea
 	return lease, err
 }
 
+// prefixLease returns the client's lease, creating one without an address
+// for a client that asks only for a delegated prefix
+func (h *DHCPv6Handler) prefixLease(clientDUID []byte) *DHCPv6Lease {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	duidKey := duidString(clientDUID)
+	if existing, ok := h.leases[duidKey]; ok {
+		h.renewLeaseUnlocked(existing)
+		return existing
+	}
+
+	now := time.Now()
+	lease := &DHCPv6Lease{
+		DUID:              clientDUID,
+		PreferredLifetime: now.Add(h.preferredLifetime),
+		ValidLifetime:     now.Add(h.validLifetime),
+		LastRenewal:       now,
+	}
+	h.leases[duidKey] = lease
+	return lease
+}
+
+// allocatePrefix delegates a prefix from the prefix pool to lease, keeping
+// the one it already holds. It returns false when the pool is exhausted.
+func (h *DHCPv6Handler) allocatePrefix(lease *DHCPv6Lease) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lease.Prefix == nil {
+		lease.Prefix = h.findAvailablePrefix()
+	}
+	return lease.Prefix != nil
+}
+
 // findLease finds a lease by client DUID
 func (h *DHCPv6Handler) findLease(clientDUID []byte) *DHCPv6Lease {
 	h.mu.RLock()
@@ -629,12 +809,14 @@ func (h *DHCPv6Handler) renewLeaseUnlocked(lease *DHCPv6Lease) {
 }
 
 // findAvailableAddress finds an available IPv6 address
+// (leases keep their address through the lease grace period)
 func (h *DHCPv6Handler) findAvailableAddress() net.IP {
+	now := time.Now()
 	// Check pool for available address
 	for _, addr := range h.addressPool {
 		inUse := false
 		for _, lease := range h.leases {
-			if lease.Address.Equal(addr) && time.Now().Before(lease.ValidLifetime) {
+			if lease.Address.Equal(addr) && now.Before(lease.ValidLifetime.Add(h.leaseGrace)) {
 				inUse = true
 				break
 			}
@@ -649,6 +831,45 @@ func (h *DHCPv6Handler) findAvailableAddress() net.IP {
 	return nil
 }
 
+// findAvailablePrefix finds a prefix no other lease holds
+// (leases keep their prefix through the lease grace period)
+func (h *DHCPv6Handler) findAvailablePrefix() *net.IPNet {
+	now := time.Now()
+	for _, prefix := range h.prefixPool {
+		inUse := false
+		for _, lease := range h.leases {
+			if lease.Prefix != nil && lease.Prefix.IP.Equal(prefix.IP) &&
+				now.Before(lease.ValidLifetime.Add(h.leaseGrace)) {
+				inUse = true
+				break
+			}
+		}
+		if !inUse {
+			// Return a copy
+			result := &net.IPNet{
+				IP:   make(net.IP, len(prefix.IP)),
+				Mask: make(net.IPMask, len(prefix.Mask)),
+			}
+			copy(result.IP, prefix.IP)
+			copy(result.Mask, prefix.Mask)
+			return result
+		}
+	}
+	return nil
+}
+
+// describe names what the lease holds, for debug output
+func (l *DHCPv6Lease) describe() string {
+	switch {
+	case l.Address != nil && l.Prefix != nil:
+		return fmt.Sprintf("%s and prefix %s", l.Address, l.Prefix)
+	case l.Prefix != nil:
+		return "prefix " + l.Prefix.String()
+	default:
+		return l.Address.String()
+	}
+}
+
 // Continue in next part...
 
 // sendAdvertise sends a DHCPv6 Advertise message
@@ -693,11 +914,21 @@ func (h *DHCPv6Handler) sendDHCPv6Response(msgType uint8, clientMsg *DHCPv6Messa
 	}
 
 	// Add IA_NA with address (if not info-only)
-	if !infoOnly && lease != nil {
+	if !infoOnly && lease != nil && lease.Address != nil {
 		ianaOpt := h.buildIANAOption(lease)
 		response.Options = append(response.Options, ianaOpt)
 	}
 
+	// Add IA_PD with the delegated prefix if the client asked for one
+	if pdIAID, hasIAPD := h.extractIAPD(clientMsg); hasIAPD && !infoOnly && lease != nil {
+		response.Options = append(response.Options, h.buildIAPDOption(pdIAID, lease))
+	}
+
+	// Hand the reconfigure key to clients that accept Reconfigure
+	if msgType == DHCPv6Reply && lease != nil && len(lease.reconfigureKey) > 0 {
+		response.Options = append(response.Options, h.reconfigureAuthOption(rkapTypeKey, lease.reconfigureKey))
+	}
+
 	// Add DNS servers if configured
 	if len(h.dnsServers) > 0 {
 		dnsData := make([]byte, 0, len(h.dnsServers)*16)
@@ -804,7 +1035,7 @@ func (h *DHCPv6Handler) sendDHCPv6Response(msgType uint8, clientMsg *DHCPv6Messa
 
 	ipv6 := &layers.IPv6{
 		Version:    6,
-		HopLimit:   64,
+		HopLimit:   device.IPv6HopLimit(),
 		NextHeader: layers.IPProtocolUDP,
 		SrcIP:      serverIP,
 		DstIP:      dstIP,
@@ -837,7 +1068,7 @@ func (h *DHCPv6Handler) sendDHCPv6Response(msgType uint8, clientMsg *DHCPv6Messa
 	}
 
 	// Send packet
-	return h.stack.SendRawPacket(buf.Bytes())
+	return h.stack.SendResponse(buf.Bytes())
 }
 
 // buildIANAOption builds an IA_NA option with IA Address
@@ -898,6 +1129,70 @@ func (h *DHCPv6Handler) buildIAAddrOption(lease *DHCPv6Lease) DHCPv6Option {
 	}
 }
 
+// buildIAPDOption builds an IA_PD option with the delegated IA Prefix, or
+// with a NoPrefixAvail status code when the lease holds no prefix
+func (h *DHCPv6Handler) buildIAPDOption(iaid uint32, lease *DHCPv6Lease) DHCPv6Option {
+	// IA_PD option format (RFC 8415 section 21.21):
+	// IAID (4 bytes) + T1 (4 bytes) + T2 (4 bytes) + IA_PD options
+	iapdData := make([]byte, 12)
+	binary.BigEndian.PutUint32(iapdData[0:4], iaid)
+
+	if lease.Prefix == nil {
+		// T1 and T2 stay zero: there is nothing to renew
+		status := binary.BigEndian.AppendUint16(nil, DHCPv6StatusNoPrefixAvail)
+		status = append(status, "No prefixes available"...)
+		iapdData = append(iapdData, h.serializeOption(DHCPv6Option{
+			Code:   DHCPv6OptStatusCode,
+			Length: uint16(len(status)),
+			Data:   status,
+		})...)
+	} else {
+		// Same renewal and rebinding times as IA_NA
+		binary.BigEndian.PutUint32(iapdData[4:8], uint32(h.preferredLifetime.Seconds()/2))
+		binary.BigEndian.PutUint32(iapdData[8:12], uint32(h.preferredLifetime.Seconds()*4/5))
+		iapdData = append(iapdData, h.serializeOption(h.buildIAPrefixOption(lease))...)
+	}
+
+	return DHCPv6Option{
+		Code:   DHCPv6OptIAPD,
+		Length: uint16(len(iapdData)),
+		Data:   iapdData,
+	}
+}
+
+// buildIAPrefixOption builds an IA Prefix option
+func (h *DHCPv6Handler) buildIAPrefixOption(lease *DHCPv6Lease) DHCPv6Option {
+	// IA Prefix option format:
+	// preferred-lifetime (4 bytes) + valid-lifetime (4 bytes) +
+	// prefix-length (1 byte) + IPv6 prefix (16 bytes)
+	iaPrefixData := make([]byte, 25)
+
+	// Preferred lifetime (in seconds)
+	preferred := uint32(time.Until(lease.PreferredLifetime).Seconds())
+	if time.Now().After(lease.PreferredLifetime) {
+		preferred = 0
+	}
+	binary.BigEndian.PutUint32(iaPrefixData[0:4], preferred)
+
+	// Valid lifetime (in seconds)
+	valid := uint32(time.Until(lease.ValidLifetime).Seconds())
+	if time.Now().After(lease.ValidLifetime) {
+		valid = 0
+	}
+	binary.BigEndian.PutUint32(iaPrefixData[4:8], valid)
+
+	// Prefix length and prefix
+	ones, _ := lease.Prefix.Mask.Size()
+	iaPrefixData[8] = uint8(ones)
+	copy(iaPrefixData[9:25], lease.Prefix.IP.To16())
+
+	return DHCPv6Option{
+		Code:   DHCPv6OptIAPrefix,
+		Length: 25,
+		Data:   iaPrefixData,
+	}
+}
+
 // serializeDHCPv6Message serializes a DHCPv6 message to bytes
 func (h *DHCPv6Handler) serializeDHCPv6Message(msg *DHCPv6Message) []byte {
 	// Calculate total size
@@ -0,0 +1,138 @@
+package protocols
+
+import (
+	"crypto/hmac"
+	"crypto/md5" // #nosec G501 -- HMAC-MD5 is mandated by RKAP (RFC 8415 section 20.4)
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Reconfigure Key Authentication Protocol (RFC 8415 section 20.4)
+const (
+	rkapProtocol      = 3
+	rkapAlgorithmHMAC = 1 // HMAC-MD5
+	rkapRDMCounter    = 0 // Replay detection: monotonically increasing counter
+	rkapTypeKey       = 1 // Reconfigure key value, sent in Reply
+	rkapTypeHMAC      = 2 // HMAC-MD5 digest, sent in Reconfigure
+	rkapKeyLen        = 16
+)
+
+// recordClient remembers where the client behind lease can be reached and
+// whether it accepts Reconfigure. Clients that send Reconfigure Accept get a
+// reconfigure key, delivered in the next Reply.
+func (h *DHCPv6Handler) recordClient(lease *DHCPv6Lease, msg *DHCPv6Message, clientIP net.IP, clientMAC net.HardwareAddr, serverIP net.IP, serverMAC net.HardwareAddr) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	lease.ClientIP = clientIP
+	lease.ClientMAC = clientMAC
+	lease.serverIP = serverIP
+	lease.serverMAC = serverMAC
+	lease.ReconfigureAccept = h.findOption(msg, DHCPv6OptReconfAccept) != nil
+	if !lease.ReconfigureAccept {
+		lease.reconfigureKey = nil
+		return
+	}
+	if lease.reconfigureKey == nil {
+		key := make([]byte, rkapKeyLen)
+		if _, err := rand.Read(key); err != nil {
+			return
+		}
+		lease.reconfigureKey = key
+	}
+}
+
+// reconfigureAuthOption builds an RKAP Authentication option carrying value
+func (h *DHCPv6Handler) reconfigureAuthOption(authType uint8, value []byte) DHCPv6Option {
+	data := make([]byte, 11+1+len(value))
+	data[0] = rkapProtocol
+	data[1] = rkapAlgorithmHMAC
+	data[2] = rkapRDMCounter
+	binary.BigEndian.PutUint64(data[3:11], h.replayCounter.Add(1))
+	data[11] = authType
+	copy(data[12:], value)
+	return DHCPv6Option{Code: DHCPv6OptAuth, Length: uint16(len(data)), Data: data}
+}
+
+// HasLease reports whether the client with clientDUID holds a lease
+func (h *DHCPv6Handler) HasLease(clientDUID []byte) bool {
+	return h.findLease(clientDUID) != nil
+}
+
+// SendReconfigure sends a Reconfigure asking the client with clientDUID to
+// start a Renew, Rebind or Information-request exchange (msgType). Only
+// clients that sent the Reconfigure Accept option are reconfigured.
+func (h *DHCPv6Handler) SendReconfigure(clientDUID []byte, msgType uint8) error {
+	switch msgType {
+	case DHCPv6Renew, DHCPv6Rebind, DHCPv6InfoRequest:
+	default:
+		return fmt.Errorf("reconfigure message type must be Renew, Rebind or Information-request, not %s", h.messageTypeString(msgType))
+	}
+
+	h.mu.RLock()
+	lease, ok := h.leases[duidString(clientDUID)]
+	if !ok {
+		h.mu.RUnlock()
+		return fmt.Errorf("no lease for DUID %s", duidString(clientDUID))
+	}
+	if !lease.ReconfigureAccept || len(lease.reconfigureKey) == 0 {
+		h.mu.RUnlock()
+		return fmt.Errorf("client %s did not send Reconfigure Accept", duidString(clientDUID))
+	}
+	if lease.ClientIP == nil || len(lease.ClientMAC) == 0 {
+		h.mu.RUnlock()
+		return fmt.Errorf("no address known for client %s", duidString(clientDUID))
+	}
+	clientIP, clientMAC := lease.ClientIP, lease.ClientMAC
+	serverIP, serverMAC := lease.serverIP, lease.serverMAC
+	key := lease.reconfigureKey
+
+	// Transaction ID is zero in Reconfigure (RFC 8415 section 16.11)
+	reconfigure := &DHCPv6Message{
+		MessageType: DHCPv6Reconfigure,
+		Options: []DHCPv6Option{
+			{Code: DHCPv6OptServerID, Length: uint16(len(h.serverDUID)), Data: h.serverDUID},
+			{Code: DHCPv6OptClientID, Length: uint16(len(lease.DUID)), Data: lease.DUID},
+			{Code: DHCPv6OptReconfMsg, Length: 1, Data: []byte{msgType}},
+			h.reconfigureAuthOption(rkapTypeHMAC, make([]byte, md5.Size)),
+		},
+	}
+	h.mu.RUnlock()
+
+	// The digest covers the whole message with the digest field zeroed; the
+	// Authentication option is last, so the digest ends the message
+	msgBytes := h.serializeDHCPv6Message(reconfigure)
+	mac := hmac.New(md5.New, key)
+	mac.Write(msgBytes)
+	copy(msgBytes[len(msgBytes)-md5.Size:], mac.Sum(nil))
+
+	eth := &layers.Ethernet{SrcMAC: serverMAC, DstMAC: clientMAC, EthernetType: layers.EthernetTypeIPv6}
+	ipv6 := &layers.IPv6{
+		Version:    6,
+		HopLimit:   h.stack.originHopLimit(serverMAC),
+		NextHeader: layers.IPProtocolUDP,
+		SrcIP:      serverIP,
+		DstIP:      clientIP,
+	}
+	udp := &layers.UDP{SrcPort: DHCPv6ServerPort, DstPort: DHCPv6ClientPort}
+	_ = udp.SetNetworkLayerForChecksum(ipv6)
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ipv6, udp, gopacket.Payload(msgBytes)); err != nil {
+		return fmt.Errorf("failed to serialize DHCPv6 Reconfigure: %w", err)
+	}
+	if err := h.stack.SendResponse(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if debugLevel := h.stack.GetDebugLevel(); debugLevel >= 2 {
+		fmt.Printf("DHCPv6: Sent Reconfigure (%s) to [%s] %s\n", h.messageTypeString(msgType), clientIP, clientMAC)
+	}
+	return nil
+}
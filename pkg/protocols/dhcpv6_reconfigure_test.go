@@ -0,0 +1,161 @@
+package protocols
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5" // #nosec G501 -- RKAP digests are HMAC-MD5
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// sendDHCPv6Client runs a client message of msgType from clientMAC through
+// the DHCPv6 handler and returns the decoded replies
+func sendDHCPv6Client(t *testing.T, stack *Stack, device *config.Device, msgType uint8, duid []byte, clientMAC net.HardwareAddr, reconfigureAccept bool) []*DHCPv6Message {
+	t.Helper()
+	h := stack.dhcpv6Handler
+
+	msg := &DHCPv6Message{
+		MessageType:   msgType,
+		TransactionID: [3]byte{0x12, 0x34, 0x56},
+		Options: []DHCPv6Option{
+			{Code: DHCPv6OptClientID, Length: uint16(len(duid)), Data: duid},
+			{Code: DHCPv6OptIANA, Length: 12, Data: []byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}},
+		},
+	}
+	if reconfigureAccept {
+		msg.Options = append(msg.Options, DHCPv6Option{Code: DHCPv6OptReconfAccept})
+	}
+
+	clientIP := net.ParseIP("fe80::2")
+	clientIP[15] = clientMAC[5]
+	eth := &layers.Ethernet{SrcMAC: clientMAC, DstMAC: net.HardwareAddr{0x33, 0x33, 0x00, 0x01, 0x00, 0x02}, EthernetType: layers.EthernetTypeIPv6}
+	ipv6 := &layers.IPv6{Version: 6, HopLimit: 1, NextHeader: layers.IPProtocolUDP, SrcIP: clientIP, DstIP: AllDHCPRelayAgentsAndServers}
+	udp := &layers.UDP{SrcPort: DHCPv6ClientPort, DstPort: DHCPv6ServerPort}
+	_ = udp.SetNetworkLayerForChecksum(ipv6)
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ipv6, udp, gopacket.Payload(h.serializeDHCPv6Message(msg))); err != nil {
+		t.Fatalf("serialize client message: %v", err)
+	}
+	decoded := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	h.HandlePacket(&Packet{Buffer: buf.Bytes(), Length: len(buf.Bytes())},
+		decoded.Layer(layers.LayerTypeIPv6).(*layers.IPv6), decoded.Layer(layers.LayerTypeUDP).(*layers.UDP),
+		[]*config.Device{device})
+
+	return dhcpv6Sent(t, stack)
+}
+
+// dhcpv6Sent drains the send queue and decodes the DHCPv6 messages sent
+func dhcpv6Sent(t *testing.T, stack *Stack) []*DHCPv6Message {
+	t.Helper()
+	var msgs []*DHCPv6Message
+	for _, pkt := range drainSendQueue(stack) {
+		packet := gopacket.NewPacket(pkt.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok || udp.DstPort != DHCPv6ClientPort {
+			t.Fatalf("sent packet is not DHCPv6 to the client port: %v", packet)
+		}
+		msg, err := stack.dhcpv6Handler.parseDHCPv6Message(udp.Payload)
+		if err != nil {
+			t.Fatalf("parse sent message: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// TestDHCPv6Reconfigure tests that Reconfigure is sent only to clients that
+// sent Reconfigure Accept, authenticated with the key from their Reply
+func TestDHCPv6Reconfigure(t *testing.T) {
+	device := &config.Device{
+		Name:        "dhcpv6-server",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66},
+		IPAddresses: []net.IP{net.ParseIP("2001:db8::1")},
+	}
+	stack := NewStack(nil, &config.Config{Devices: []config.Device{*device}}, logging.NewDebugConfig(0))
+	stack.dhcpv6Handler.SetAddressPool([]net.IP{net.ParseIP("2001:db8::100"), net.ParseIP("2001:db8::101")})
+
+	acceptingMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+	accepting := append([]byte{0, DUIDTypeLL, 0, 1}, acceptingMAC...)
+	otherMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x02}
+	other := append([]byte{0, DUIDTypeLL, 0, 1}, otherMAC...)
+
+	// The accepting client gets a reconfigure key in its Reply
+	sendDHCPv6Client(t, stack, device, DHCPv6Solicit, accepting, acceptingMAC, true)
+	replies := sendDHCPv6Client(t, stack, device, DHCPv6Request, accepting, acceptingMAC, true)
+	if len(replies) != 1 || replies[0].MessageType != DHCPv6Reply {
+		t.Fatalf("replies to Request = %+v, want one Reply", replies)
+	}
+	auth := stack.dhcpv6Handler.findOption(replies[0], DHCPv6OptAuth)
+	if auth == nil || len(auth.Data) != 12+rkapKeyLen || auth.Data[0] != rkapProtocol || auth.Data[11] != rkapTypeKey {
+		t.Fatalf("Reply carries no reconfigure key: %+v", auth)
+	}
+	key := auth.Data[12:]
+
+	sendDHCPv6Client(t, stack, device, DHCPv6Solicit, other, otherMAC, false)
+	replies = sendDHCPv6Client(t, stack, device, DHCPv6Request, other, otherMAC, false)
+	if len(replies) != 1 || stack.dhcpv6Handler.findOption(replies[0], DHCPv6OptAuth) != nil {
+		t.Errorf("Reply to a client without Reconfigure Accept carries a reconfigure key")
+	}
+
+	// Only the accepting client is reconfigured
+	if err := stack.dhcpv6Handler.SendReconfigure(other, DHCPv6Renew); err == nil {
+		t.Error("SendReconfigure succeeded for a client without Reconfigure Accept")
+	}
+	if sent := drainSendQueue(stack); len(sent) != 0 {
+		t.Errorf("%d packets sent to a client without Reconfigure Accept, want none", len(sent))
+	}
+
+	if err := stack.dhcpv6Handler.SendReconfigure(accepting, DHCPv6Renew); err != nil {
+		t.Fatalf("SendReconfigure: %v", err)
+	}
+	sent := drainSendQueue(stack)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d packets, want one Reconfigure", len(sent))
+	}
+	packet := gopacket.NewPacket(sent[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	if eth := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); !bytes.Equal(eth.DstMAC, acceptingMAC) {
+		t.Errorf("Reconfigure sent to %s, want the client %s", eth.DstMAC, acceptingMAC)
+	}
+	payload := packet.Layer(layers.LayerTypeUDP).(*layers.UDP).Payload
+	msg, err := stack.dhcpv6Handler.parseDHCPv6Message(payload)
+	if err != nil {
+		t.Fatalf("parse Reconfigure: %v", err)
+	}
+	if msg.MessageType != DHCPv6Reconfigure || msg.TransactionID != [3]byte{} {
+		t.Errorf("message type %d xid %x, want Reconfigure with a zero xid", msg.MessageType, msg.TransactionID)
+	}
+	if opt := stack.dhcpv6Handler.findOption(msg, DHCPv6OptReconfMsg); opt == nil || !bytes.Equal(opt.Data, []byte{DHCPv6Renew}) {
+		t.Errorf("reconfigure-message option = %+v, want Renew", opt)
+	}
+	if opt := stack.dhcpv6Handler.findOption(msg, DHCPv6OptClientID); opt == nil || !bytes.Equal(opt.Data, accepting) {
+		t.Errorf("Client ID = %+v, want the client's DUID", opt)
+	}
+
+	// The HMAC-MD5 digest verifies with the key from the Reply
+	reconfAuth := stack.dhcpv6Handler.findOption(msg, DHCPv6OptAuth)
+	if reconfAuth == nil || reconfAuth.Data[11] != rkapTypeHMAC {
+		t.Fatalf("Reconfigure carries no HMAC: %+v", reconfAuth)
+	}
+	if binary.BigEndian.Uint64(reconfAuth.Data[3:11]) <= binary.BigEndian.Uint64(auth.Data[3:11]) {
+		t.Error("replay detection value did not increase")
+	}
+	digest := append([]byte(nil), payload[len(payload)-md5.Size:]...)
+	zeroed := append([]byte(nil), payload...)
+	copy(zeroed[len(zeroed)-md5.Size:], make([]byte, md5.Size))
+	mac := hmac.New(md5.New, key)
+	mac.Write(zeroed)
+	if !hmac.Equal(digest, mac.Sum(nil)) {
+		t.Error("Reconfigure HMAC does not verify with the reconfigure key")
+	}
+
+	if err := stack.dhcpv6Handler.SendReconfigure(accepting, DHCPv6Solicit); err == nil {
+		t.Error("SendReconfigure accepted Solicit as the reconfigure message type")
+	}
+}
@@ -292,6 +292,150 @@ func TestAllocateLeaseDHCPv6_PoolExhaustion(t *testing.T) {
 	}
 }
 
+// TestAllocateLeaseDHCPv6_LeaseGrace tests that an expired address is held
+// for the grace period before another client gets it
+func TestAllocateLeaseDHCPv6_LeaseGrace(t *testing.T) {
+	cfg := &config.Config{}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewDHCPv6Handler(stack)
+	handler.SetAddressPool([]net.IP{net.ParseIP("2001:db8::100")})
+	handler.SetLeaseGrace(time.Hour)
+
+	clientDUID1 := []byte{0x00, 0x03, 0x00, 0x01, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	lease, err := handler.allocateLease(clientDUID1, 1)
+	if err != nil {
+		t.Fatalf("First allocation failed: %v", err)
+	}
+
+	// Expired, but still within the grace period
+	lease.ValidLifetime = time.Now().Add(-30 * time.Minute)
+	clientDUID2 := []byte{0x00, 0x03, 0x00, 0x01, 0x00, 0x11, 0x22, 0x33, 0x44, 0x56}
+	if _, err := handler.allocateLease(clientDUID2, 2); err == nil {
+		t.Fatal("Expected expired address to be held during the grace period")
+	}
+
+	// Grace period over
+	lease.ValidLifetime = time.Now().Add(-2 * time.Hour)
+	reused, err := handler.allocateLease(clientDUID2, 2)
+	if err != nil {
+		t.Fatalf("Expected expired address to be reallocated after the grace period: %v", err)
+	}
+	if !reused.Address.Equal(net.ParseIP("2001:db8::100")) {
+		t.Errorf("Expected 2001:db8::100, got %s", reused.Address)
+	}
+}
+
+// TestDHCPv6ConfigPools tests that a device's dhcpv6 block configures the
+// address and prefix pools, lifetimes and lease grace
+func TestDHCPv6ConfigPools(t *testing.T) {
+	_, delegated, _ := net.ParseCIDR("2001:db8:100::/56")
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "dhcpv6-server",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x77},
+		IPAddresses: []net.IP{net.ParseIP("2001:db8:2::1")},
+		DHCPv6Config: &config.DHCPv6Config{
+			Enabled: true,
+			Pools: []config.DHCPv6Pool{
+				{Network: "2001:db8:1::/64", RangeStart: "2001:db8:1::100", RangeEnd: "2001:db8:1::1ff"},
+				{Network: "2001:db8:2::/64"},
+			},
+			DelegatedPrefixes: []net.IPNet{*delegated},
+			PreferredLifetime: 3600,
+			ValidLifetime:     7200,
+			LeaseGrace:        time.Hour,
+		},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := stack.GetDHCPv6Handler()
+
+	handler.mu.RLock()
+	defer handler.mu.RUnlock()
+	if want := 256 + MaxPoolSize; len(handler.addressPool) != want {
+		t.Fatalf("address pool holds %d addresses, want %d", len(handler.addressPool), want)
+	}
+	for i, want := range map[int]string{0: "2001:db8:1::100", 255: "2001:db8:1::1ff", 256: "2001:db8:2::2"} {
+		if !handler.addressPool[i].Equal(net.ParseIP(want)) {
+			t.Errorf("addressPool[%d] = %s, want %s", i, handler.addressPool[i], want)
+		}
+	}
+	if len(handler.prefixPool) != 1 || handler.prefixPool[0].String() != "2001:db8:100::/56" {
+		t.Errorf("prefix pool = %v, want [2001:db8:100::/56]", handler.prefixPool)
+	}
+	if handler.preferredLifetime != time.Hour || handler.validLifetime != 2*time.Hour {
+		t.Errorf("lifetimes = %v/%v, want 1h/2h", handler.preferredLifetime, handler.validLifetime)
+	}
+	if handler.leaseGrace != time.Hour {
+		t.Errorf("lease grace = %v, want 1h", handler.leaseGrace)
+	}
+}
+
+// TestDHCPv6PrefixDelegation tests that a Solicit with an IA_PD is answered
+// with a prefix from the prefix pool, and with NoPrefixAvail once the pool
+// is exhausted
+func TestDHCPv6PrefixDelegation(t *testing.T) {
+	device := &config.Device{
+		Name:        "dhcpv6-server",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x77},
+		IPAddresses: []net.IP{net.ParseIP("2001:db8::1")},
+	}
+	stack := NewStack(nil, &config.Config{Devices: []config.Device{*device}}, logging.NewDebugConfig(0))
+	handler := stack.dhcpv6Handler
+	_, delegated, _ := net.ParseCIDR("2001:db8:100::/56")
+	handler.SetPrefixPool([]net.IPNet{*delegated})
+
+	solicit := func(duid []byte) *DHCPv6Option {
+		t.Helper()
+		msg := &DHCPv6Message{
+			MessageType:   DHCPv6Solicit,
+			TransactionID: [3]byte{0x65, 0x43, 0x21},
+			Options: []DHCPv6Option{
+				{Code: DHCPv6OptClientID, Length: uint16(len(duid)), Data: duid},
+				{Code: DHCPv6OptIAPD, Length: 12, Data: []byte{0, 0, 0, 7, 0, 0, 0, 0, 0, 0, 0, 0}},
+			},
+		}
+		handler.handleSolicit(msg, net.ParseIP("fe80::2"), net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01},
+			net.ParseIP("fe80::1"), device.MACAddress, device, 1)
+		replies := dhcpv6Sent(t, stack)
+		if len(replies) != 1 || replies[0].MessageType != DHCPv6Advertise {
+			t.Fatalf("replies to Solicit = %+v, want one Advertise", replies)
+		}
+		if handler.findOption(replies[0], DHCPv6OptIANA) != nil {
+			t.Error("Advertise carries an IA_NA the client did not ask for")
+		}
+		iapd := handler.findOption(replies[0], DHCPv6OptIAPD)
+		if iapd == nil || len(iapd.Data) < 16 {
+			t.Fatalf("Advertise carries no IA_PD: %+v", iapd)
+		}
+		if iaid := binary.BigEndian.Uint32(iapd.Data[0:4]); iaid != 7 {
+			t.Errorf("IA_PD IAID = %d, want 7", iaid)
+		}
+		return iapd
+	}
+
+	iapd := solicit([]byte{0, DUIDTypeLL, 0, 1, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01})
+	code := binary.BigEndian.Uint16(iapd.Data[12:14])
+	length := binary.BigEndian.Uint16(iapd.Data[14:16])
+	if code != DHCPv6OptIAPrefix || length != 25 || len(iapd.Data) < 16+25 {
+		t.Fatalf("IA_PD carries option %d (%d bytes), want an IA Prefix", code, length)
+	}
+	prefix := iapd.Data[16 : 16+25]
+	if prefix[8] != 56 || !net.IP(prefix[9:25]).Equal(delegated.IP) {
+		t.Errorf("Delegated %s/%d, want %s", net.IP(prefix[9:25]), prefix[8], delegated)
+	}
+	if valid := binary.BigEndian.Uint32(prefix[4:8]); valid == 0 {
+		t.Error("Delegated prefix has a zero valid lifetime")
+	}
+
+	// The only prefix is taken: the next client gets NoPrefixAvail
+	iapd = solicit([]byte{0, DUIDTypeLL, 0, 1, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x02})
+	if code := binary.BigEndian.Uint16(iapd.Data[12:14]); code != DHCPv6OptStatusCode {
+		t.Fatalf("IA_PD carries option %d, want a status code", code)
+	}
+	if status := binary.BigEndian.Uint16(iapd.Data[16:18]); status != DHCPv6StatusNoPrefixAvail {
+		t.Errorf("Status code = %d, want NoPrefixAvail", status)
+	}
+}
+
 // TestConfirmLease tests lease confirmation
 func TestConfirmLease(t *testing.T) {
 	cfg := &config.Config{}
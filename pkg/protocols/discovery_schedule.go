@@ -0,0 +1,103 @@
+package protocols
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// SetRandomSeed makes randomized behavior such as discovery phase offsets
+// reproducible across runs. Call before Start.
+func (s *Stack) SetRandomSeed(seed uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.randomSeed = seed
+}
+
+// discoveryPhaseJitter returns the configured phase jitter for a discovery protocol
+func (s *Stack) discoveryPhaseJitter(protocol string) float64 {
+	cfg := s.currentConfig()
+	if cfg == nil {
+		return 0
+	}
+	return cfg.DiscoveryProtocols.PhaseJitterFor(protocol)
+}
+
+// advertisementOffset returns the delay before a device's first advertisement:
+// a random fraction (up to jitter) of interval. The fraction depends only on
+// the stack seed, protocol and device name, so a seeded run always produces the
+// same schedule regardless of start order.
+func (s *Stack) advertisementOffset(protocol, deviceName string, interval time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	seed := s.randomSeed
+	s.mu.Unlock()
+
+	h := fnv.New64a()
+	h.Write([]byte(protocol))
+	h.Write([]byte{0})
+	h.Write([]byte(deviceName))
+	rng := rand.New(rand.NewPCG(seed, h.Sum64()))
+
+	return time.Duration(rng.Float64() * jitter * float64(interval))
+}
+
+// runStaggeredAdvertisements sends periodic advertisements with each device on
+// its own phase: a device's first advertisement is delayed by its
+// advertisementOffset and later ones follow every interval. Devices added by a
+// config reload are scheduled when first seen.
+func (s *Stack) runStaggeredAdvertisements(protocol string, interval time.Duration, jitter float64,
+	stop <-chan struct{}, send func(*config.Device)) {
+	next := make(map[string]time.Time)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		wake := now.Add(interval)
+
+		devices := s.GetDevices().GetAll()
+		sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+
+		seen := make(map[string]bool, len(devices))
+		for _, device := range devices {
+			seen[device.Name] = true
+
+			due, scheduled := next[device.Name]
+			if !scheduled {
+				due = now.Add(s.advertisementOffset(protocol, device.Name, interval, jitter))
+			}
+			if !due.After(now) {
+				send(device)
+				for !due.After(now) {
+					due = due.Add(interval)
+				}
+			}
+			next[device.Name] = due
+
+			if due.Before(wake) {
+				wake = due
+			}
+		}
+
+		for name := range next {
+			if !seen[name] {
+				delete(next, name)
+			}
+		}
+
+		timer.Reset(time.Until(wake))
+	}
+}
@@ -0,0 +1,118 @@
+package protocols
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// TestAdvertisementOffset_Seeded tests that offsets stay within the jitter
+// window and are reproducible for a given seed
+func TestAdvertisementOffset_Seeded(t *testing.T) {
+	interval := 30 * time.Second
+	jitter := 0.5
+	window := time.Duration(jitter * float64(interval))
+
+	stack := NewStack(nil, &config.Config{}, logging.NewDebugConfig(0))
+	stack.SetRandomSeed(42)
+
+	first := make([]time.Duration, 50)
+	minOffset, maxOffset := window, time.Duration(0)
+	for i := range first {
+		offset := stack.advertisementOffset("lldp", fmt.Sprintf("switch-%d", i), interval, jitter)
+		if offset < 0 || offset >= window {
+			t.Fatalf("offset %v outside jitter window [0, %v)", offset, window)
+		}
+		first[i] = offset
+		minOffset = min(minOffset, offset)
+		maxOffset = max(maxOffset, offset)
+	}
+	if maxOffset-minOffset < window/2 {
+		t.Errorf("offsets not spread across window: min %v, max %v", minOffset, maxOffset)
+	}
+
+	other := NewStack(nil, &config.Config{}, logging.NewDebugConfig(0))
+	other.SetRandomSeed(42)
+	differs := false
+	for i, want := range first {
+		name := fmt.Sprintf("switch-%d", i)
+		if got := other.advertisementOffset("lldp", name, interval, jitter); got != want {
+			t.Fatalf("seed 42 gave %v for %s, previously %v", got, name, want)
+		}
+		other.SetRandomSeed(43)
+		if other.advertisementOffset("lldp", name, interval, jitter) != want {
+			differs = true
+		}
+		other.SetRandomSeed(42)
+	}
+	if !differs {
+		t.Error("expected a different seed to change the schedule")
+	}
+
+	if offset := stack.advertisementOffset("lldp", "switch-0", interval, 0); offset != 0 {
+		t.Errorf("expected no offset without jitter, got %v", offset)
+	}
+}
+
+// TestStaggeredAdvertisements_SpreadStartTimes tests that first advertisements
+// are spread across the jitter window rather than sent together
+func TestStaggeredAdvertisements_SpreadStartTimes(t *testing.T) {
+	const devices = 20
+	interval := 400 * time.Millisecond
+	jitter := 1.0
+
+	cfg := &config.Config{}
+	for i := 0; i < devices; i++ {
+		cfg.Devices = append(cfg.Devices, config.Device{
+			Name:       fmt.Sprintf("switch-%02d", i),
+			MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, byte(i)},
+		})
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	stack.SetRandomSeed(7)
+
+	var mu sync.Mutex
+	firstSent := make(map[string]time.Duration)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	start := time.Now()
+
+	go stack.runStaggeredAdvertisements("lldp", interval, jitter, stop, func(d *config.Device) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := firstSent[d.Name]; ok {
+			return
+		}
+		firstSent[d.Name] = time.Since(start)
+		if len(firstSent) == devices {
+			close(done)
+		}
+	})
+	defer close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(5 * interval):
+		t.Fatal("timed out waiting for first advertisements")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	earliest, latest := interval, time.Duration(0)
+	for name, at := range firstSent {
+		want := stack.advertisementOffset("lldp", name, interval, jitter)
+		if at < want {
+			t.Errorf("%s advertised at %v, before its offset %v", name, at, want)
+		}
+		earliest = min(earliest, at)
+		latest = max(latest, at)
+	}
+	if latest-earliest < interval/2 {
+		t.Errorf("first advertisements not spread: earliest %v, latest %v", earliest, latest)
+	}
+}
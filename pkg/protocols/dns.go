@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -20,15 +21,22 @@ type DNSHandler struct {
 	ptrRecords map[string]string   // IP -> Hostname (reverse lookup)
 	mu         sync.RWMutex
 	domain     string // Default domain
+
+	// Forwarding of names without a local record
+	forwarders   []string // Upstream resolvers (host:port)
+	cacheTTL     time.Duration
+	cache        map[dnsCacheKey]dnsCacheEntry
+	forwardSlots chan struct{} // One per forward waiting on an upstream resolver
 }
 
 // NewDNSHandler creates a new DNS handler
 func NewDNSHandler(stack *Stack) *DNSHandler {
 	return &DNSHandler{
-		stack:      stack,
-		records:    make(map[string][]net.IP),
-		ptrRecords: make(map[string]string),
-		domain:     "local",
+		stack:        stack,
+		records:      make(map[string][]net.IP),
+		ptrRecords:   make(map[string]string),
+		domain:       "local",
+		forwardSlots: make(chan struct{}, dnsMaxForwardsInFlight),
 	}
 }
 
@@ -39,6 +47,8 @@ func (h *DNSHandler) Reset() {
 	h.records = make(map[string][]net.IP)
 	h.ptrRecords = make(map[string]string)
 	h.domain = "local"
+	h.forwarders = nil
+	h.cache = nil
 }
 
 // AddRecord adds a DNS A/AAAA record
@@ -149,13 +159,21 @@ func (h *DNSHandler) HandleQuery(pkt *Packet, ipLayer *layers.IPv4, udpLayer *la
 	}
 
 	// Send response
-	if err := h.SendDNSResponse(response, serverIP, ipLayer.SrcIP, serverDevice.MACAddress, srcMAC, udpLayer.SrcPort); err != nil {
-		if debugLevel >= 1 {
-			fmt.Printf("DNS: Failed to send response: %v sn=%d\n", err, pkt.SerialNumber)
+	send := func() {
+		if err := h.SendDNSResponse(response, serverIP, ipLayer.SrcIP, serverDevice.MACAddress, srcMAC, udpLayer.SrcPort); err != nil {
+			if debugLevel >= 1 {
+				fmt.Printf("DNS: Failed to send response: %v sn=%d\n", err, pkt.SerialNumber)
+			}
+		} else if debugLevel >= 3 {
+			fmt.Printf("DNS: Sent response with %d answers sn=%d\n", len(response.Answers), pkt.SerialNumber)
 		}
-	} else if debugLevel >= 3 {
-		fmt.Printf("DNS: Sent response with %d answers sn=%d\n", len(response.Answers), pkt.SerialNumber)
 	}
+
+	if h.shouldForward(dns, response) {
+		h.forwardAndSend(response, pkt.SerialNumber, debugLevel, send)
+		return
+	}
+	send()
 }
 
 // lookupHost looks up IP addresses for a hostname
@@ -192,7 +210,7 @@ func (h *DNSHandler) SendDNSResponse(response *layers.DNS, srcIP, dstIP net.IP,
 	// Build IP layer
 	ip := &layers.IPv4{
 		Version:  4,
-		TTL:      64,
+		TTL:      h.stack.originTTL(srcMAC),
 		Protocol: layers.IPProtocolUDP,
 		SrcIP:    srcIP,
 		DstIP:    dstIP,
@@ -219,7 +237,7 @@ func (h *DNSHandler) SendDNSResponse(response *layers.DNS, srcIP, dstIP net.IP,
 	}
 
 	// Send packet
-	return h.stack.SendRawPacket(buf.Bytes())
+	return h.stack.SendResponse(buf.Bytes())
 }
 
 // SendDNSResponseV6 sends a DNS response over IPv6.
@@ -234,7 +252,7 @@ func (h *DNSHandler) SendDNSResponseV6(response *layers.DNS, srcIP, dstIP net.IP
 		TrafficClass: 0,
 		FlowLabel:    0,
 		NextHeader:   layers.IPProtocolUDP,
-		HopLimit:     64,
+		HopLimit:     h.stack.originHopLimit(srcMAC),
 		SrcIP:        srcIP,
 		DstIP:        dstIP,
 	}
@@ -257,7 +275,7 @@ func (h *DNSHandler) SendDNSResponseV6(response *layers.DNS, srcIP, dstIP net.IP
 		return fmt.Errorf("failed to serialize DNS/IPv6 response: %w", err)
 	}
 
-	return h.stack.SendRawPacket(buf.Bytes())
+	return h.stack.SendResponse(buf.Bytes())
 }
 
 // HandleQueryV6 processes a DNS query over IPv6
@@ -323,11 +341,19 @@ func (h *DNSHandler) HandleQueryV6(pkt *Packet, packet gopacket.Packet, ipv6 *la
 	}
 	dstMAC := ethLayer.(*layers.Ethernet).SrcMAC
 
-	if err := h.SendDNSResponseV6(response, serverIP, ipv6.SrcIP, serverDevice.MACAddress, dstMAC, udpLayer.SrcPort); err != nil {
-		if debugLevel >= 1 {
-			fmt.Printf("DNS/IPv6: Failed to send response: %v sn=%d\n", err, pkt.SerialNumber)
+	send := func() {
+		if err := h.SendDNSResponseV6(response, serverIP, ipv6.SrcIP, serverDevice.MACAddress, dstMAC, udpLayer.SrcPort); err != nil {
+			if debugLevel >= 1 {
+				fmt.Printf("DNS/IPv6: Failed to send response: %v sn=%d\n", err, pkt.SerialNumber)
+			}
 		}
 	}
+
+	if h.shouldForward(dns, response) {
+		h.forwardAndSend(response, pkt.SerialNumber, debugLevel, send)
+		return
+	}
+	send()
 }
 
 func (h *DNSHandler) resolveQuestions(questions []layers.DNSQuestion, debugLevel int, serial int) ([]layers.DNSResourceRecord, layers.DNSResponseCode) {
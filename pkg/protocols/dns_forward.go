@@ -0,0 +1,301 @@
+package protocols
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// dnsForwardTimeout bounds how long one upstream resolver is waited for
+	// before the next is tried
+	dnsForwardTimeout = 2 * time.Second
+
+	// dnsCacheMaxEntries caps cached upstream answers; once full, further
+	// answers are not cached until entries expire
+	dnsCacheMaxEntries = 4096
+
+	// dnsMaxUDPMessage is the largest upstream response read over UDP
+	dnsMaxUDPMessage = 4096
+
+	// dnsMaxForwardsInFlight caps queries waiting on upstream resolvers at
+	// once; further queries needing a forward get SERVFAIL
+	dnsMaxForwardsInFlight = 64
+)
+
+// dnsCacheKey identifies a cached upstream answer
+type dnsCacheKey struct {
+	name  string
+	qtype layers.DNSType
+	class layers.DNSClass
+}
+
+// dnsCacheEntry is an upstream answer and when it stops being served
+type dnsCacheEntry struct {
+	answers []layers.DNSResourceRecord
+	rcode   layers.DNSResponseCode
+	expires time.Time
+}
+
+// SetForwarders makes the handler forward queries without a local record to
+// the given upstream resolvers (host:port), caching each answer for at most
+// cacheTTL.
+func (h *DNSHandler) SetForwarders(forwarders []string, cacheTTL time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.forwarders = append([]string(nil), forwarders...)
+	h.cacheTTL = cacheTTL
+	h.cache = make(map[dnsCacheKey]dnsCacheEntry)
+}
+
+// shouldForward reports whether a query left unanswered by local records is
+// sent upstream: the client asked for recursion and forwarders are set.
+func (h *DNSHandler) shouldForward(query, response *layers.DNS) bool {
+	if len(response.Answers) > 0 || !query.RD || len(query.Questions) != 1 {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.forwarders) > 0
+}
+
+// forwardAndSend answers response from the cache, or from the upstream
+// resolvers without holding up packet processing, and then sends it. With
+// dnsMaxForwardsInFlight forwards already waiting the query gets SERVFAIL.
+func (h *DNSHandler) forwardAndSend(response *layers.DNS, serial int, debugLevel int, send func()) {
+	if answers, rcode, ok := h.cachedForward(response.Questions[0], time.Now()); ok {
+		response.AA = false
+		response.Answers = answers
+		response.ResponseCode = rcode
+		send()
+		return
+	}
+
+	select {
+	case h.forwardSlots <- struct{}{}:
+	default:
+		if debugLevel >= 1 {
+			fmt.Printf("DNS: %d forwards in flight, SERVFAIL for %s sn=%d\n", dnsMaxForwardsInFlight, response.Questions[0].Name, serial)
+		}
+		response.AA = false
+		response.ResponseCode = layers.DNSResponseCodeServFail
+		send()
+		return
+	}
+	go func() {
+		defer func() { <-h.forwardSlots }()
+		h.applyForwarded(response, serial, debugLevel)
+		send()
+	}()
+}
+
+// applyForwarded fills response from the cache or the upstream resolvers. A
+// query no resolver answers gets SERVFAIL.
+func (h *DNSHandler) applyForwarded(response *layers.DNS, serial int, debugLevel int) {
+	response.AA = false
+	answers, rcode, err := h.forward(response.Questions[0], time.Now())
+	if err != nil {
+		if debugLevel >= 1 {
+			fmt.Printf("DNS: Forwarding %s failed: %v sn=%d\n", response.Questions[0].Name, err, serial)
+		}
+		response.ResponseCode = layers.DNSResponseCodeServFail
+		return
+	}
+	response.Answers = answers
+	response.ResponseCode = rcode
+	if debugLevel >= 2 {
+		fmt.Printf("DNS: %s forwarded, %d answers (%s) sn=%d\n", response.Questions[0].Name, len(answers), rcode, serial)
+	}
+}
+
+// forward answers question from the cache, or asks each upstream resolver in
+// turn and caches the first answer.
+func (h *DNSHandler) forward(question layers.DNSQuestion, now time.Time) ([]layers.DNSResourceRecord, layers.DNSResponseCode, error) {
+	if answers, rcode, ok := h.cachedForward(question, now); ok {
+		return answers, rcode, nil
+	}
+
+	h.mu.RLock()
+	forwarders := h.forwarders
+	h.mu.RUnlock()
+	key := dnsCacheKeyFor(question)
+	var lastErr error
+	for _, forwarder := range forwarders {
+		reply, err := queryUpstream(forwarder, question)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		h.cacheAnswer(key, reply, now)
+		return reply.Answers, reply.ResponseCode, nil
+	}
+	return nil, layers.DNSResponseCodeServFail, lastErr
+}
+
+// cachedForward returns the cached upstream answer to question, if any
+func (h *DNSHandler) cachedForward(question layers.DNSQuestion, now time.Time) ([]layers.DNSResourceRecord, layers.DNSResponseCode, bool) {
+	h.mu.RLock()
+	entry, cached := h.cache[dnsCacheKeyFor(question)]
+	h.mu.RUnlock()
+	if !cached || !now.Before(entry.expires) {
+		return nil, 0, false
+	}
+	return cachedAnswers(entry, now), entry.rcode, true
+}
+
+// dnsCacheKeyFor returns the cache key of question; names are compared
+// without case or the trailing dot
+func dnsCacheKeyFor(question layers.DNSQuestion) dnsCacheKey {
+	return dnsCacheKey{
+		name:  strings.ToLower(strings.TrimSuffix(string(question.Name), ".")),
+		qtype: question.Type,
+		class: question.Class,
+	}
+}
+
+// cacheAnswer stores reply until the smaller of the cache TTL and its
+// shortest record TTL. Failures other than NXDOMAIN are not cached.
+func (h *DNSHandler) cacheAnswer(key dnsCacheKey, reply *layers.DNS, now time.Time) {
+	if reply.ResponseCode != layers.DNSResponseCodeNoErr && reply.ResponseCode != layers.DNSResponseCodeNXDomain {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ttl := h.cacheTTL
+	for _, answer := range reply.Answers {
+		if recordTTL := time.Duration(answer.TTL) * time.Second; recordTTL < ttl {
+			ttl = recordTTL
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+	if _, ok := h.cache[key]; !ok && len(h.cache) >= dnsCacheMaxEntries {
+		for k, entry := range h.cache {
+			if !now.Before(entry.expires) {
+				delete(h.cache, k)
+			}
+		}
+		if len(h.cache) >= dnsCacheMaxEntries {
+			return
+		}
+	}
+	h.cache[key] = dnsCacheEntry{answers: reply.Answers, rcode: reply.ResponseCode, expires: now.Add(ttl)}
+}
+
+// cachedAnswers returns a cached entry's records with their TTLs counted down
+// to the time left in the cache
+func cachedAnswers(entry dnsCacheEntry, now time.Time) []layers.DNSResourceRecord {
+	remaining := uint32(entry.expires.Sub(now) / time.Second)
+	answers := make([]layers.DNSResourceRecord, len(entry.answers))
+	for i, answer := range entry.answers {
+		if answer.TTL > remaining {
+			answer.TTL = remaining
+		}
+		answers[i] = answer
+	}
+	return answers
+}
+
+// queryUpstream sends question to a resolver at addr and returns its reply.
+// A truncated UDP reply is retried over TCP (RFC 7766).
+func queryUpstream(addr string, question layers.DNSQuestion) (*layers.DNS, error) {
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("query ID: %w", err)
+	}
+	query := &layers.DNS{
+		ID:        binary.BigEndian.Uint16(id[:]),
+		RD:        true,
+		QDCount:   1,
+		Questions: []layers.DNSQuestion{question},
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := query.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+		return nil, fmt.Errorf("serialize query: %w", err)
+	}
+
+	conn, err := net.DialTimeout("udp", addr, dnsForwardTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("forwarder %s: %w", addr, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(dnsForwardTimeout)); err != nil {
+		return nil, fmt.Errorf("forwarder %s: %w", addr, err)
+	}
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("forwarder %s: %w", addr, err)
+	}
+
+	data := make([]byte, dnsMaxUDPMessage)
+	for {
+		n, err := conn.Read(data)
+		if err != nil {
+			return nil, fmt.Errorf("forwarder %s: %w", addr, err)
+		}
+		reply := &layers.DNS{}
+		if err := reply.DecodeFromBytes(data[:n], gopacket.NilDecodeFeedback); err != nil {
+			continue // Not a DNS message; keep waiting for the reply
+		}
+		if !isUpstreamReply(query, reply) {
+			continue // A stray or spoofed message; keep waiting
+		}
+		if reply.TC {
+			return queryUpstreamTCP(addr, query, buf.Bytes())
+		}
+		return reply, nil
+	}
+}
+
+// queryUpstreamTCP sends the serialized query to a resolver at addr over TCP
+// and returns its reply
+func queryUpstreamTCP(addr string, query *layers.DNS, message []byte) (*layers.DNS, error) {
+	conn, err := net.DialTimeout("tcp", addr, dnsForwardTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("forwarder %s over TCP: %w", addr, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(dnsForwardTimeout)); err != nil {
+		return nil, fmt.Errorf("forwarder %s over TCP: %w", addr, err)
+	}
+
+	// Each TCP message is preceded by its two-byte length
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(message)))
+	if _, err := conn.Write(append(framed, message...)); err != nil {
+		return nil, fmt.Errorf("forwarder %s over TCP: %w", addr, err)
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("forwarder %s over TCP: %w", addr, err)
+	}
+	data := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, fmt.Errorf("forwarder %s over TCP: %w", addr, err)
+	}
+	reply := &layers.DNS{}
+	if err := reply.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		return nil, fmt.Errorf("forwarder %s over TCP: %w", addr, err)
+	}
+	if !isUpstreamReply(query, reply) {
+		return nil, fmt.Errorf("forwarder %s over TCP: reply does not match the query", addr)
+	}
+	return reply, nil
+}
+
+// isUpstreamReply reports whether reply answers query: a response with the
+// query's ID repeating its question (RFC 5452)
+func isUpstreamReply(query, reply *layers.DNS) bool {
+	if !reply.QR || reply.ID != query.ID || len(reply.Questions) != 1 {
+		return false
+	}
+	asked, got := query.Questions[0], reply.Questions[0]
+	return dnsCacheKeyFor(asked) == dnsCacheKeyFor(got)
+}
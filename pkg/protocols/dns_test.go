@@ -1,11 +1,16 @@
 package protocols
 
 import (
+	"encoding/binary"
 	"encoding/hex"
+	"io"
 	"net"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
@@ -662,3 +667,218 @@ func BenchmarkConcurrentLookups(b *testing.B) {
 		}
 	})
 }
+
+// TestDNSForwarding tests that a name without a local record is forwarded to
+// the upstream resolver, and that the answer is returned and cached
+func TestDNSForwarding(t *testing.T) {
+	// A stub upstream resolver answering every A query with 203.0.113.7
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer upstream.Close()
+	var upstreamQueries atomic.Int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := upstream.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := &layers.DNS{}
+			if err := query.DecodeFromBytes(buf[:n], gopacket.NilDecodeFeedback); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			upstreamQueries.Add(1)
+			reply := &layers.DNS{
+				ID: query.ID, QR: true, RD: true, RA: true,
+				Questions: query.Questions,
+				Answers: []layers.DNSResourceRecord{{
+					Name: query.Questions[0].Name, Type: layers.DNSTypeA, Class: layers.DNSClassIN,
+					TTL: 60, IP: net.ParseIP("203.0.113.7").To4(),
+				}},
+			}
+			out := gopacket.NewSerializeBuffer()
+			if err := reply.SerializeTo(out, gopacket.SerializeOptions{FixLengths: true}); err == nil {
+				_, _ = upstream.WriteTo(out.Bytes(), addr)
+			}
+		}
+	}()
+
+	stack := newDNSForwardStack(upstream.LocalAddr().String())
+	stack.dnsHandler.AddRecord("local.example.com", net.ParseIP("10.0.0.10"))
+
+	// Local records take precedence over the forwarder
+	if response := queryDNSForward(t, stack, "local.example.com"); len(response.Answers) != 1 || !response.Answers[0].IP.Equal(net.ParseIP("10.0.0.10")) || !response.AA {
+		t.Errorf("local.example.com answered with %+v, want the authoritative local record", response.Answers)
+	}
+	if n := upstreamQueries.Load(); n != 0 {
+		t.Errorf("upstream received %d queries for a local name, want 0", n)
+	}
+
+	response := queryDNSForward(t, stack, "www.example.org")
+	if response.ResponseCode != layers.DNSResponseCodeNoErr || len(response.Answers) != 1 || !response.Answers[0].IP.Equal(net.ParseIP("203.0.113.7")) {
+		t.Fatalf("www.example.org answered %s with %+v, want 203.0.113.7 from upstream", response.ResponseCode, response.Answers)
+	}
+	if response.AA || response.ID != 0x1234 {
+		t.Errorf("forwarded response AA=%v ID=%#x, want non-authoritative with the query ID", response.AA, response.ID)
+	}
+
+	// The second query is answered from the cache
+	response = queryDNSForward(t, stack, "WWW.example.org")
+	if len(response.Answers) != 1 || !response.Answers[0].IP.Equal(net.ParseIP("203.0.113.7")) || response.Answers[0].TTL > 60 {
+		t.Errorf("cached answer = %+v, want 203.0.113.7 with TTL <= 60", response.Answers)
+	}
+	if n := upstreamQueries.Load(); n != 1 {
+		t.Errorf("upstream received %d queries, want 1 (second answered from cache)", n)
+	}
+}
+
+// queryDNSForward sends an A query for name to the dns1 device at 10.0.0.53
+// and returns the single response, waiting for forwarded queries
+func queryDNSForward(t *testing.T, stack *Stack, name string) *layers.DNS {
+	t.Helper()
+	buffer := gopacket.NewSerializeBuffer()
+	ip := &layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP,
+		SrcIP: net.ParseIP("10.0.0.50").To4(), DstIP: net.ParseIP("10.0.0.53").To4()}
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	_ = udp.SetNetworkLayerForChecksum(ip)
+	if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+		&layers.Ethernet{SrcMAC: fragTestHostMAC, DstMAC: fragTestDeviceMAC, EthernetType: layers.EthernetTypeIPv4},
+		ip, udp,
+		&layers.DNS{ID: 0x1234, RD: true, Questions: []layers.DNSQuestion{{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN}}},
+	); err != nil {
+		t.Fatalf("serialize query: %v", err)
+	}
+	stack.decodePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+
+	// Forwarded queries are answered asynchronously
+	deadline := time.Now().Add(3 * time.Second)
+	for len(stack.sendQueue) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	queued := drainSendQueue(stack)
+	if len(queued) != 1 {
+		t.Fatalf("got %d responses to %s, want 1", len(queued), name)
+	}
+	packet := gopacket.NewPacket(queued[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	response, ok := packet.Layer(layers.LayerTypeDNS).(*layers.DNS)
+	if !ok {
+		t.Fatalf("response to %s has no DNS layer", name)
+	}
+	return response
+}
+
+// newDNSForwardStack returns a stack with one DNS device forwarding to upstream
+func newDNSForwardStack(upstream string) *Stack {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "dns1",
+		MACAddress:  fragTestDeviceMAC,
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.53").To4()},
+		DNSConfig:   &config.DNSConfig{},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	stack.dnsHandler.SetForwarders([]string{upstream}, 300*time.Second)
+	return stack
+}
+
+// TestDNSForwardingUpstreamReplies tests that replies not repeating the
+// question are ignored, that a truncated reply is retried over TCP and that
+// queries beyond dnsMaxForwardsInFlight get SERVFAIL without a forward
+func TestDNSForwardingUpstreamReplies(t *testing.T) {
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer upstream.Close()
+	tcp, err := net.Listen("tcp", upstream.LocalAddr().String())
+	if err != nil {
+		t.Skipf("listen on the upstream port over TCP: %v", err)
+	}
+	defer tcp.Close()
+
+	answer := func(query *layers.DNS, name string, ip string) []byte {
+		reply := &layers.DNS{
+			ID: query.ID, QR: true, RD: true, RA: true,
+			Questions: []layers.DNSQuestion{{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN}},
+			Answers: []layers.DNSResourceRecord{{
+				Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN,
+				TTL: 60, IP: net.ParseIP(ip).To4(),
+			}},
+		}
+		out := gopacket.NewSerializeBuffer()
+		if err := reply.SerializeTo(out, gopacket.SerializeOptions{FixLengths: true}); err != nil {
+			return nil
+		}
+		return out.Bytes()
+	}
+
+	// Over UDP every query first gets a same-ID reply to another name; names
+	// starting with "big." are then truncated
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := upstream.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := &layers.DNS{}
+			if err := query.DecodeFromBytes(buf[:n], gopacket.NilDecodeFeedback); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			name := string(query.Questions[0].Name)
+			_, _ = upstream.WriteTo(answer(query, "spoofed.example.net", "198.51.100.1"), addr)
+			if strings.HasPrefix(name, "big.") {
+				truncated := &layers.DNS{ID: query.ID, QR: true, TC: true, Questions: query.Questions}
+				out := gopacket.NewSerializeBuffer()
+				if err := truncated.SerializeTo(out, gopacket.SerializeOptions{FixLengths: true}); err == nil {
+					_, _ = upstream.WriteTo(out.Bytes(), addr)
+				}
+				continue
+			}
+			_, _ = upstream.WriteTo(answer(query, name, "203.0.113.7"), addr)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				data := make([]byte, binary.BigEndian.Uint16(length[:]))
+				query := &layers.DNS{}
+				if _, err := io.ReadFull(conn, data); err == nil && query.DecodeFromBytes(data, gopacket.NilDecodeFeedback) == nil && len(query.Questions) == 1 {
+					reply := answer(query, string(query.Questions[0].Name), "203.0.113.9")
+					_, _ = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(reply))), reply...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	stack := newDNSForwardStack(upstream.LocalAddr().String())
+
+	response := queryDNSForward(t, stack, "www.example.org")
+	if len(response.Answers) != 1 || !response.Answers[0].IP.Equal(net.ParseIP("203.0.113.7")) {
+		t.Errorf("www.example.org answered with %+v, want 203.0.113.7 and not the mismatched reply", response.Answers)
+	}
+
+	response = queryDNSForward(t, stack, "big.example.org")
+	if len(response.Answers) != 1 || !response.Answers[0].IP.Equal(net.ParseIP("203.0.113.9")) {
+		t.Errorf("big.example.org answered %s with %+v, want 203.0.113.9 over TCP", response.ResponseCode, response.Answers)
+	}
+
+	// With every slot taken a new name is refused at once; cached names are
+	// still answered
+	for i := 0; i < dnsMaxForwardsInFlight; i++ {
+		stack.dnsHandler.forwardSlots <- struct{}{}
+	}
+	if response := queryDNSForward(t, stack, "other.example.org"); response.ResponseCode != layers.DNSResponseCodeServFail || response.AA {
+		t.Errorf("query with all forwards in flight answered %s AA=%v, want non-authoritative SERVFAIL", response.ResponseCode, response.AA)
+	}
+	if response := queryDNSForward(t, stack, "www.example.org"); len(response.Answers) != 1 {
+		t.Errorf("cached name answered %s with %+v while forwards are full, want the cached answer", response.ResponseCode, response.Answers)
+	}
+}
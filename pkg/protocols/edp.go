@@ -56,6 +56,12 @@ func (h *EDPHandler) Start() {
 		fmt.Printf("EDP: Starting periodic advertisements (interval: %v)\n", EDPAdvertiseInterval)
 	}
 
+	// Spread first advertisements across the interval when phase jitter is set
+	if jitter := h.stack.discoveryPhaseJitter("edp"); jitter > 0 {
+		go h.stack.runStaggeredAdvertisements("edp", EDPAdvertiseInterval, jitter, h.stopChan, h.sendAdvertisement)
+		return
+	}
+
 	h.advertiseTicker = time.NewTicker(EDPAdvertiseInterval)
 
 	go func() {
@@ -81,28 +87,33 @@ func (h *EDPHandler) Stop() {
 
 // sendAdvertisements sends EDP advertisements for all devices
 func (h *EDPHandler) sendAdvertisements() {
+	for _, device := range h.stack.GetDevices().GetAll() {
+		h.sendAdvertisement(device)
+	}
+}
+
+// sendAdvertisement sends a EDP advertisement for one device
+func (h *EDPHandler) sendAdvertisement(device *config.Device) {
 	debugLevel := h.stack.GetDebugLevel()
 
-	devices := h.stack.GetDevices().GetAll()
-	for _, device := range devices {
-		if len(device.MACAddress) == 0 {
-			continue
-		}
+	// Devices with a boot sequence stay quiet until its discovery stage
+	if len(device.MACAddress) == 0 || !h.stack.bootStageReached(device.Name, BootStageDiscovery) {
+		return
+	}
 
-		// Skip if EDP is explicitly disabled for this device
-		if device.EDPConfig != nil && !device.EDPConfig.Enabled {
-			continue
-		}
+	// Skip if EDP is explicitly disabled for this device
+	if device.EDPConfig != nil && !device.EDPConfig.Enabled {
+		return
+	}
 
-		// Build and send EDP frame
-		frame := h.buildEDPFrame(device)
-		if frame != nil {
-			err := h.sendFrame(device, frame)
-			if err != nil && debugLevel >= 2 {
-				fmt.Printf("EDP: Error sending advertisement for %s: %v\n", device.Name, err)
-			} else if debugLevel >= 3 {
-				fmt.Printf("EDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
-			}
+	// Build and send EDP frame
+	frame := h.buildEDPFrame(device)
+	if frame != nil {
+		err := h.sendFrame(device, frame)
+		if err != nil && debugLevel >= 2 {
+			fmt.Printf("EDP: Error sending advertisement for %s: %v\n", device.Name, err)
+		} else if debugLevel >= 3 {
+			fmt.Printf("EDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
 		}
 	}
 }
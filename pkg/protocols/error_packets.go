@@ -0,0 +1,139 @@
+package protocols
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// Bounds of the buffer of packets that caused errors
+const (
+	ErrorPacketCapacity = 50         // Packets kept; the oldest is dropped first
+	ErrorPacketMaxBytes = 256 * 1024 // Packet bytes kept across the whole buffer
+	ErrorPacketSnapLen  = 9216 + 18  // Longer packets are truncated (largest jumbo frame)
+)
+
+// ErrorPacket is a received packet that failed to parse or made a protocol
+// handler fail, kept so the exact bytes can be inspected later.
+type ErrorPacket struct {
+	Time         time.Time
+	Protocol     string // Handler that failed ("ethernet" when the frame did not parse)
+	Error        string
+	Interface    string
+	SerialNumber int
+	Length       int    // Original length in bytes
+	Data         []byte // At most ErrorPacketSnapLen bytes
+}
+
+// Truncated reports whether Data holds only the start of the packet
+func (p ErrorPacket) Truncated() bool {
+	return len(p.Data) < p.Length
+}
+
+// Summary describes the packet's layers and addresses as far as they decode,
+// e.g. "Ethernet/IPv6/UDP [fe80::2]:546 > [ff02::1:2]:547".
+func (p ErrorPacket) Summary() string {
+	packet := gopacket.NewPacket(p.Data, layers.LayerTypeEthernet, gopacket.Default)
+
+	names := make([]string, 0, 4)
+	for _, layer := range packet.Layers() {
+		if layer.LayerType() != gopacket.LayerTypeDecodeFailure {
+			names = append(names, layer.LayerType().String())
+		}
+	}
+	summary := strings.Join(names, "/")
+	if summary == "" {
+		summary = "undecodable"
+	}
+
+	switch network, transport := packet.NetworkLayer(), packet.TransportLayer(); {
+	case network != nil && transport != nil:
+		src, dst := network.NetworkFlow().Endpoints()
+		srcPort, dstPort := transport.TransportFlow().Endpoints()
+		summary += fmt.Sprintf(" %s > %s", net.JoinHostPort(src.String(), srcPort.String()), net.JoinHostPort(dst.String(), dstPort.String()))
+	case network != nil:
+		src, dst := network.NetworkFlow().Endpoints()
+		summary += fmt.Sprintf(" %s > %s", src, dst)
+	case packet.LinkLayer() != nil:
+		src, dst := packet.LinkLayer().LinkFlow().Endpoints()
+		summary += fmt.Sprintf(" %s > %s", src, dst)
+	}
+
+	if failure := packet.ErrorLayer(); failure != nil {
+		summary += fmt.Sprintf(" (decode error: %v)", failure.Error())
+	}
+	return summary
+}
+
+// errorPacketBuffer keeps the most recent error packets within
+// ErrorPacketCapacity packets and ErrorPacketMaxBytes bytes
+type errorPacketBuffer struct {
+	mu      sync.Mutex
+	packets []ErrorPacket // Oldest first
+	bytes   int
+}
+
+func (b *errorPacketBuffer) add(packet ErrorPacket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.packets = append(b.packets, packet)
+	b.bytes += len(packet.Data)
+	for len(b.packets) > ErrorPacketCapacity || b.bytes > ErrorPacketMaxBytes {
+		b.bytes -= len(b.packets[0].Data)
+		b.packets[0] = ErrorPacket{} // Release the bytes
+		b.packets = b.packets[1:]
+	}
+}
+
+func (b *errorPacketBuffer) snapshot() []ErrorPacket {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]ErrorPacket(nil), b.packets...)
+}
+
+func (b *errorPacketBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.packets = nil
+	b.bytes = 0
+}
+
+// recordErrorPacket keeps a copy of pkt, which protocol failed to handle
+// with err, in the error packet buffer.
+func (s *Stack) recordErrorPacket(pkt *Packet, protocol string, err error) {
+	if s == nil || pkt == nil || err == nil {
+		return
+	}
+	frame := pkt.Buffer
+	if pkt.Length > 0 && pkt.Length <= len(frame) {
+		frame = frame[:pkt.Length]
+	}
+	data := make([]byte, min(len(frame), ErrorPacketSnapLen))
+	copy(data, frame)
+
+	s.errorPackets.add(ErrorPacket{
+		Time:         time.Now(),
+		Protocol:     protocol,
+		Error:        err.Error(),
+		Interface:    pkt.Interface,
+		SerialNumber: pkt.SerialNumber,
+		Length:       len(frame),
+		Data:         data,
+	})
+}
+
+// ErrorPackets returns the most recent packets that failed to parse or made
+// a handler fail, oldest first.
+func (s *Stack) ErrorPackets() []ErrorPacket {
+	return s.errorPackets.snapshot()
+}
+
+// ClearErrorPackets empties the error packet buffer.
+func (s *Stack) ClearErrorPackets() {
+	s.errorPackets.reset()
+}
@@ -0,0 +1,94 @@
+package protocols
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// TestErrorPackets_MalformedDHCPv6 tests that a DHCPv6 message too short to
+// parse is kept in the error packet buffer with its error
+func TestErrorPackets_MalformedDHCPv6(t *testing.T) {
+	device := config.Device{
+		Name:        "dhcpv6-server",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		IPAddresses: []net.IP{net.ParseIP("2001:db8::1")},
+	}
+	stack := NewStack(nil, &config.Config{Devices: []config.Device{device}}, logging.NewDebugConfig(0))
+
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01},
+		DstMAC:       net.HardwareAddr{0x33, 0x33, 0x00, 0x01, 0x00, 0x02},
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ipv6 := &layers.IPv6{Version: 6, HopLimit: 1, NextHeader: layers.IPProtocolUDP, SrcIP: net.ParseIP("fe80::2"), DstIP: AllDHCPRelayAgentsAndServers}
+	udp := &layers.UDP{SrcPort: DHCPv6ClientPort, DstPort: DHCPv6ServerPort}
+	_ = udp.SetNetworkLayerForChecksum(ipv6)
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{ComputeChecksums: true, FixLengths: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ipv6, udp, gopacket.Payload{DHCPv6Solicit, 0x12}); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	frame := buf.Bytes()
+	decoded := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+	stack.dhcpv6Handler.HandlePacket(&Packet{Buffer: frame, Length: len(frame), SerialNumber: 7},
+		decoded.Layer(layers.LayerTypeIPv6).(*layers.IPv6), decoded.Layer(layers.LayerTypeUDP).(*layers.UDP),
+		[]*config.Device{&stack.currentConfig().Devices[0]})
+
+	packets := stack.ErrorPackets()
+	if len(packets) != 1 {
+		t.Fatalf("Expected 1 error packet, got %d", len(packets))
+	}
+	got := packets[0]
+	if got.Protocol != "dhcpv6" || !strings.Contains(got.Error, "message too short") {
+		t.Errorf("Unexpected error packet: protocol %q, error %q", got.Protocol, got.Error)
+	}
+	if !bytes.Equal(got.Data, frame) || got.Length != len(frame) || got.Truncated() || got.SerialNumber != 7 {
+		t.Errorf("Expected the exact %d frame bytes, got %d of %d", len(frame), len(got.Data), got.Length)
+	}
+	if summary := got.Summary(); !strings.HasPrefix(summary, "Ethernet/IPv6/UDP [fe80::2]:546 > [ff02::1:2]:547 (decode error:") {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+}
+
+// TestErrorPackets_Limits tests the error packet buffer's packet, snap
+// length and total size caps
+func TestErrorPackets_Limits(t *testing.T) {
+	stack := NewStack(nil, &config.Config{}, logging.NewDebugConfig(0))
+	errFailure := errors.New("handler failed")
+
+	for i := 0; i < ErrorPacketCapacity+5; i++ {
+		stack.recordErrorPacket(&Packet{Buffer: []byte{byte(i)}, Length: 1, SerialNumber: i}, "test", errFailure)
+	}
+	packets := stack.ErrorPackets()
+	if len(packets) != ErrorPacketCapacity {
+		t.Fatalf("Expected %d packets kept, got %d", ErrorPacketCapacity, len(packets))
+	}
+	if packets[0].SerialNumber != 5 || packets[len(packets)-1].SerialNumber != ErrorPacketCapacity+4 {
+		t.Errorf("Expected the newest packets, oldest first; got sn %d..%d", packets[0].SerialNumber, packets[len(packets)-1].SerialNumber)
+	}
+
+	stack.ClearErrorPackets()
+	large := make([]byte, ErrorPacketSnapLen+100)
+	for i := 0; i < ErrorPacketCapacity; i++ {
+		stack.recordErrorPacket(&Packet{Buffer: large, Length: len(large)}, "test", errFailure)
+	}
+	packets = stack.ErrorPackets()
+	total := 0
+	for _, packet := range packets {
+		total += len(packet.Data)
+		if len(packet.Data) != ErrorPacketSnapLen || !packet.Truncated() {
+			t.Fatalf("Expected packets truncated to %d bytes, got %d", ErrorPacketSnapLen, len(packet.Data))
+		}
+	}
+	if total > ErrorPacketMaxBytes || len(packets) != ErrorPacketMaxBytes/ErrorPacketSnapLen {
+		t.Errorf("Expected at most %d bytes kept, got %d in %d packets", ErrorPacketMaxBytes, total, len(packets))
+	}
+}
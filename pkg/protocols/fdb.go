@@ -0,0 +1,288 @@
+package protocols
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+)
+
+// BRIDGE-MIB (RFC 4188) objects served by bridge devices
+const (
+	OIDDot1dBase              = "1.3.6.1.2.1.17.1"
+	OIDDot1dBaseBridgeAddress = "1.3.6.1.2.1.17.1.1.0"
+	OIDDot1dBaseNumPorts      = "1.3.6.1.2.1.17.1.2.0"
+	OIDDot1dBaseType          = "1.3.6.1.2.1.17.1.3.0"
+	OIDDot1dBasePortIfIndex   = "1.3.6.1.2.1.17.1.4.1.2"
+	OIDDot1dTp                = "1.3.6.1.2.1.17.4"
+	OIDDot1dTpLearnedDiscards = "1.3.6.1.2.1.17.4.1.0"
+	OIDDot1dTpAgingTime       = "1.3.6.1.2.1.17.4.2.0"
+	OIDDot1dTpFdbAddress      = "1.3.6.1.2.1.17.4.3.1.1"
+	OIDDot1dTpFdbPort         = "1.3.6.1.2.1.17.4.3.1.2"
+	OIDDot1dTpFdbStatus       = "1.3.6.1.2.1.17.4.3.1.3"
+)
+
+const (
+	// fdbUplinkPort is the bridge port every captured frame arrives on: the
+	// simulator sees the network through one capture interface
+	fdbUplinkPort = 1
+
+	dot1dBaseTypeTransparentOnly = 2
+	dot1dTpFdbStatusLearned      = 3
+	dot1dTpFdbStatusSelf         = 4
+)
+
+// fdbTable is the MAC addresses learned from received traffic, shared by every
+// bridge device. Each device keeps its own forwarding table of up to its
+// mac_table_size addresses, and applies its own aging time when it reports
+// them.
+type fdbTable struct {
+	mu       sync.Mutex
+	lastSeen map[string]time.Time // MAC -> last frame received from it
+	maxAge   time.Duration        // Longest aging time of any bridge device
+	capacity int                  // Largest mac_table_size of any bridge device
+	discards uint64
+	bridges  map[string]*bridgeFDB // Device name -> its forwarding table
+}
+
+// bridgeFDB is one bridge device's forwarding table: the learned MACs it has
+// room for. Once full, new addresses are discarded until entries age out.
+type bridgeFDB struct {
+	agingTime time.Duration
+	size      int
+	entries   map[string]struct{}
+	discards  uint64
+}
+
+// FDBUtilization reports how full a bridge device's forwarding table is
+type FDBUtilization struct {
+	Entries  int    `json:"entries"`  // Learned MACs within the aging time
+	Size     int    `json:"size"`     // mac_table_size
+	Discards uint64 `json:"discards"` // New MACs not learned because the table was full
+}
+
+func newFDBTable() *fdbTable {
+	return &fdbTable{
+		lastSeen: make(map[string]time.Time),
+		capacity: config.DefaultBridgeMacTableSize,
+		bridges:  make(map[string]*bridgeFDB),
+	}
+}
+
+// setMaxAge sets how long entries are kept and clears the table and every
+// bridge's forwarding table.
+func (t *fdbTable) setMaxAge(maxAge time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxAge = maxAge
+	t.lastSeen = make(map[string]time.Time)
+	t.capacity = config.DefaultBridgeMacTableSize
+	t.discards = 0
+	t.bridges = make(map[string]*bridgeFDB)
+}
+
+// addBridge gives the named device a forwarding table of size entries
+// (config.DefaultBridgeMacTableSize when 0).
+func (t *fdbTable) addBridge(name string, agingTime time.Duration, size int) {
+	if size <= 0 {
+		size = config.DefaultBridgeMacTableSize
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bridges[name] = &bridgeFDB{agingTime: agingTime, size: size, entries: make(map[string]struct{})}
+	if size > t.capacity {
+		t.capacity = size
+	}
+}
+
+// learn records a frame from mac and learns it on every bridge with room for
+// it. Multicast sources and learning while no bridge device is configured are
+// ignored.
+func (t *fdbTable) learn(mac net.HardwareAddr, now time.Time) {
+	if len(mac) != 6 || mac[0]&0x01 != 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.maxAge == 0 {
+		return
+	}
+	key := mac.String()
+	if _, ok := t.lastSeen[key]; !ok && len(t.lastSeen) >= t.capacity {
+		t.expireLocked(now)
+		if len(t.lastSeen) >= t.capacity {
+			t.discards++
+			return
+		}
+	}
+	t.lastSeen[key] = now
+
+	for _, bridge := range t.bridges {
+		if _, ok := bridge.entries[key]; ok {
+			continue
+		}
+		if len(bridge.entries) >= bridge.size {
+			t.expireBridgeLocked(bridge, now)
+			if len(bridge.entries) >= bridge.size {
+				bridge.discards++
+				continue
+			}
+		}
+		bridge.entries[key] = struct{}{}
+	}
+}
+
+// expireLocked drops entries no bridge device reports any more.
+func (t *fdbTable) expireLocked(now time.Time) {
+	for key, seen := range t.lastSeen {
+		if now.Sub(seen) >= t.maxAge {
+			delete(t.lastSeen, key)
+		}
+	}
+}
+
+// expireBridgeLocked drops the entries idle for longer than the bridge's
+// aging time from its forwarding table.
+func (t *fdbTable) expireBridgeLocked(bridge *bridgeFDB, now time.Time) {
+	for key := range bridge.entries {
+		if seen, ok := t.lastSeen[key]; !ok || now.Sub(seen) >= bridge.agingTime {
+			delete(bridge.entries, key)
+		}
+	}
+}
+
+// active returns the MACs seen within agingTime and the number of discarded
+// addresses.
+func (t *fdbTable) active(agingTime time.Duration, now time.Time) ([]net.HardwareAddr, uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expireLocked(now)
+
+	macs := make([]net.HardwareAddr, 0, len(t.lastSeen))
+	for key, seen := range t.lastSeen {
+		if now.Sub(seen) < agingTime {
+			mac, _ := net.ParseMAC(key)
+			macs = append(macs, mac)
+		}
+	}
+	return macs, t.discards
+}
+
+// bridgeActive returns the MACs in the named device's forwarding table and its
+// utilization. The second result is false for a device that is not a bridge.
+func (t *fdbTable) bridgeActive(name string, now time.Time) ([]net.HardwareAddr, FDBUtilization, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	bridge, ok := t.bridges[name]
+	if !ok {
+		return nil, FDBUtilization{}, false
+	}
+	t.expireLocked(now)
+	t.expireBridgeLocked(bridge, now)
+
+	macs := make([]net.HardwareAddr, 0, len(bridge.entries))
+	for key := range bridge.entries {
+		mac, _ := net.ParseMAC(key)
+		macs = append(macs, mac)
+	}
+	// Addresses the shared table had no room for never reached the bridge
+	discards := bridge.discards + t.discards
+	return macs, FDBUtilization{Entries: len(macs), Size: bridge.size, Discards: discards}, true
+}
+
+// learnSource feeds a received frame's source MAC to the forwarding table.
+// Frames from simulated devices are not learned; each bridge reports its own
+// MAC as a self entry instead.
+func (s *Stack) learnSource(pkt *Packet) {
+	src := pkt.GetSourceMAC()
+	if len(src) != 6 || s.devices.GetByMAC(src) != nil {
+		return
+	}
+	s.fdb.learn(src, time.Now())
+}
+
+// configureFDB sizes the forwarding table for the configured bridge devices.
+func (s *Stack) configureFDB(cfg *config.Config) {
+	var maxAge time.Duration
+	for i := range cfg.Devices {
+		if bridge := cfg.Devices[i].BridgeConfig; bridge != nil && bridge.AgingTime > maxAge {
+			maxAge = bridge.AgingTime
+		}
+	}
+	s.fdb.setMaxAge(maxAge)
+	for i := range cfg.Devices {
+		if bridge := cfg.Devices[i].BridgeConfig; bridge != nil {
+			s.fdb.addBridge(cfg.Devices[i].Name, bridge.AgingTime, bridge.MacTableSize)
+		}
+	}
+}
+
+// GetFDBUtilization returns how full the named bridge device's forwarding
+// table is. The second result is false for a device without a bridge block.
+func (s *Stack) GetFDBUtilization(name string) (FDBUtilization, bool) {
+	_, utilization, ok := s.fdb.bridgeActive(name, time.Now())
+	return utilization, ok
+}
+
+// registerBridgeMIB serves the BRIDGE-MIB base group and dot1dTpFdbTable for a
+// bridge device, built from the stack's learned MACs.
+func (s *Stack) registerBridgeMIB(agent *snmp.Agent, device *config.Device) {
+	bridge := device.BridgeConfig
+	if bridge == nil {
+		return
+	}
+
+	agent.RegisterComputedTable(OIDDot1dBase, func() []snmp.OIDResult {
+		results := []snmp.OIDResult{
+			{OID: OIDDot1dBaseNumPorts, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: 1}},
+			{OID: OIDDot1dBaseType, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: dot1dBaseTypeTransparentOnly}},
+			{OID: fmt.Sprintf("%s.%d", OIDDot1dBasePortIfIndex, fdbUplinkPort), Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: 1}},
+		}
+		if len(device.MACAddress) == 6 {
+			results = append(results, snmp.OIDResult{OID: OIDDot1dBaseBridgeAddress, Value: &snmp.OIDValue{Type: gosnmp.OctetString, Value: string(device.MACAddress)}})
+		}
+		return results
+	})
+
+	agent.RegisterComputedTable(OIDDot1dTp, func() []snmp.OIDResult {
+		macs, utilization, _ := s.fdb.bridgeActive(device.Name, time.Now())
+
+		results := []snmp.OIDResult{
+			{OID: OIDDot1dTpLearnedDiscards, Value: &snmp.OIDValue{Type: gosnmp.Counter32, Value: uint(utilization.Discards)}},
+			{OID: OIDDot1dTpAgingTime, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: int(bridge.AgingTime / time.Second)}},
+		}
+		addRow := func(mac net.HardwareAddr, port, status int) {
+			index := fdbIndex(mac)
+			results = append(results,
+				snmp.OIDResult{OID: OIDDot1dTpFdbAddress + index, Value: &snmp.OIDValue{Type: gosnmp.OctetString, Value: string(mac)}},
+				snmp.OIDResult{OID: OIDDot1dTpFdbPort + index, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: port}},
+				snmp.OIDResult{OID: OIDDot1dTpFdbStatus + index, Value: &snmp.OIDValue{Type: gosnmp.Integer, Value: status}},
+			)
+		}
+		for _, mac := range macs {
+			addRow(mac, fdbUplinkPort, dot1dTpFdbStatusLearned)
+		}
+		if len(device.MACAddress) == 6 {
+			// The bridge's own address is not on any port
+			addRow(device.MACAddress, 0, dot1dTpFdbStatusSelf)
+		}
+		return results
+	})
+}
+
+// fdbIndex returns the dot1dTpFdbTable index suffix for mac: its six octets
+// as decimal sub-identifiers.
+func fdbIndex(mac net.HardwareAddr) string {
+	var b bytes.Buffer
+	for _, octet := range mac {
+		fmt.Fprintf(&b, ".%d", octet)
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package protocols
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+func TestBridgeFDB_LearnsReceivedSources(t *testing.T) {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:         "sw1",
+		MACAddress:   net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.2").To4()},
+		SNMPConfig:   config.SNMPConfig{Community: "public"},
+		BridgeConfig: &config.BridgeConfig{AgingTime: 300 * time.Second},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	device := &cfg.Devices[0]
+
+	// A frame from a host on the wire
+	host := net.HardwareAddr{0x00, 0xAA, 0xBB, 0xCC, 0xDD, 0x05}
+	buffer := gopacket.NewSerializeBuffer()
+	eth := &layers.Ethernet{SrcMAC: host, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+	if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{}, eth, gopacket.Payload(make([]byte, 46))); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	pkt, err := ParsePacket(buffer.Bytes(), 1)
+	if err != nil {
+		t.Fatalf("ParsePacket: %v", err)
+	}
+	stack.decodePacket(pkt)
+
+	// Computed tables are rebuilt at most once a second; a fresh agent
+	// builds them immediately
+	stack.initSNMPAgent(device)
+	agent := stack.getSNMPAgent(device)
+
+	index := fdbIndex(host)
+	address, err := agent.HandleGet(OIDDot1dTpFdbAddress + index)
+	if err != nil {
+		t.Fatalf("Expected dot1dTpFdbAddress for %s: %v", host, err)
+	}
+	if address.Value != string(host) {
+		t.Errorf("dot1dTpFdbAddress = %x, want %s", address.Value, host)
+	}
+	port, err := agent.HandleGet(OIDDot1dTpFdbPort + index)
+	if err != nil || port.Value != fdbUplinkPort {
+		t.Errorf("dot1dTpFdbPort = %v (%v), want %d", port, err, fdbUplinkPort)
+	}
+	status, err := agent.HandleGet(OIDDot1dTpFdbStatus + index)
+	if err != nil || status.Value != dot1dTpFdbStatusLearned {
+		t.Errorf("dot1dTpFdbStatus = %v (%v), want learned(3)", status, err)
+	}
+
+	// Walking the table finds the bridge's own MAC as a self entry too
+	found := 0
+	for oid, _, err := agent.HandleGetNext(OIDDot1dTpFdbAddress); err == nil && strings.HasPrefix(oid, OIDDot1dTpFdbAddress+"."); oid, _, err = agent.HandleGetNext(oid) {
+		found++
+	}
+	if found != 2 {
+		t.Errorf("Walk of dot1dTpFdbAddress returned %d rows, want 2", found)
+	}
+
+	// The simulated devices' own frames are not learned
+	stack.learnSource(&Packet{Buffer: append(append([]byte{}, layers.EthernetBroadcast...), device.MACAddress...)})
+	if macs, _ := stack.fdb.active(300*time.Second, time.Now()); len(macs) != 1 {
+		t.Errorf("Expected only the host learned, got %v", macs)
+	}
+}
+
+func TestBridgeFDB_AgesOut(t *testing.T) {
+	table := newFDBTable()
+	table.setMaxAge(300 * time.Second)
+
+	start := time.Now()
+	host := net.HardwareAddr{0x00, 0xAA, 0xBB, 0xCC, 0xDD, 0x05}
+	table.learn(host, start)
+
+	if macs, _ := table.active(10*time.Second, start.Add(5*time.Second)); len(macs) != 1 {
+		t.Fatalf("Expected %s within the aging time, got %v", host, macs)
+	}
+	// A bridge with a short aging time stops reporting the MAC ...
+	if macs, _ := table.active(10*time.Second, start.Add(10*time.Second)); len(macs) != 0 {
+		t.Errorf("Expected %s aged out after 10s, got %v", host, macs)
+	}
+	// ... while one with a longer aging time still has it
+	if macs, _ := table.active(300*time.Second, start.Add(10*time.Second)); len(macs) != 1 {
+		t.Errorf("Expected %s still present for a 300s aging time, got %v", host, macs)
+	}
+	// Past the longest aging time the entry is dropped from the table
+	table.active(300*time.Second, start.Add(300*time.Second))
+	if len(table.lastSeen) != 0 {
+		t.Errorf("Expected expired entry removed, table has %d", len(table.lastSeen))
+	}
+
+	// Multicast sources are never learned
+	table.learn(net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x01}, start)
+	if len(table.lastSeen) != 0 {
+		t.Error("Expected multicast source ignored")
+	}
+}
+
+func TestBridgeFDB_MacTableSize(t *testing.T) {
+	cfg := &config.Config{Devices: []config.Device{
+		{
+			Name:         "small",
+			MACAddress:   net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+			IPAddresses:  []net.IP{net.ParseIP("10.0.0.2").To4()},
+			SNMPConfig:   config.SNMPConfig{Community: "public"},
+			BridgeConfig: &config.BridgeConfig{AgingTime: 10 * time.Second, MacTableSize: 4},
+		},
+		{
+			Name:         "large",
+			MACAddress:   net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x02},
+			BridgeConfig: &config.BridgeConfig{AgingTime: 300 * time.Second},
+		},
+	}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	// A MAC flood: ten hosts, more than the small bridge has room for
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		stack.fdb.learn(net.HardwareAddr{0x00, 0xAA, 0xBB, 0xCC, 0xDD, byte(i)}, start)
+	}
+	macs, utilization, _ := stack.fdb.bridgeActive("small", start)
+	if len(macs) != 4 || utilization != (FDBUtilization{Entries: 4, Size: 4, Discards: 6}) {
+		t.Fatalf("small bridge has %d MACs, utilization %+v; want 4 entries of 4 and 6 discards", len(macs), utilization)
+	}
+	for _, mac := range macs {
+		if mac[5] >= 4 {
+			t.Errorf("small bridge learned %s after its table was full", mac)
+		}
+	}
+	if got, ok := stack.GetFDBUtilization("large"); !ok || got.Entries != 10 || got.Discards != 0 {
+		t.Errorf("large bridge utilization = %+v, want all 10 MACs learned", got)
+	}
+	if _, ok := stack.GetFDBUtilization("nonexistent"); ok {
+		t.Error("Expected no forwarding table for a device without a bridge block")
+	}
+
+	// The discards are served in dot1dTpLearnedEntryDiscards
+	device := &cfg.Devices[0]
+	stack.initSNMPAgent(device)
+	discards, err := stack.getSNMPAgent(device).HandleGet(OIDDot1dTpLearnedDiscards)
+	if err != nil || discards.Value != uint(6) {
+		t.Errorf("dot1dTpLearnedEntryDiscards = %v (%v), want 6", discards, err)
+	}
+
+	// Once entries age out, new addresses are learned again
+	later := start.Add(10 * time.Second)
+	stack.fdb.learn(net.HardwareAddr{0x00, 0xAA, 0xBB, 0xCC, 0xDD, 0x20}, later)
+	if macs, utilization, _ := stack.fdb.bridgeActive("small", later); len(macs) != 1 || utilization.Discards != 6 {
+		t.Errorf("small bridge after aging has %v, utilization %+v; want only the new MAC", macs, utilization)
+	}
+}
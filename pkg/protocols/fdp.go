@@ -68,6 +68,12 @@ func (h *FDPHandler) Start() {
 		fmt.Printf("FDP: Starting periodic advertisements (interval: %v)\n", FDPAdvertiseInterval)
 	}
 
+	// Spread first advertisements across the interval when phase jitter is set
+	if jitter := h.stack.discoveryPhaseJitter("fdp"); jitter > 0 {
+		go h.stack.runStaggeredAdvertisements("fdp", FDPAdvertiseInterval, jitter, h.stopChan, h.sendAdvertisement)
+		return
+	}
+
 	h.advertiseTicker = time.NewTicker(FDPAdvertiseInterval)
 
 	go func() {
@@ -93,28 +99,33 @@ func (h *FDPHandler) Stop() {
 
 // sendAdvertisements sends FDP advertisements for all devices
 func (h *FDPHandler) sendAdvertisements() {
+	for _, device := range h.stack.GetDevices().GetAll() {
+		h.sendAdvertisement(device)
+	}
+}
+
+// sendAdvertisement sends a FDP advertisement for one device
+func (h *FDPHandler) sendAdvertisement(device *config.Device) {
 	debugLevel := h.stack.GetDebugLevel()
 
-	devices := h.stack.GetDevices().GetAll()
-	for _, device := range devices {
-		if len(device.MACAddress) == 0 {
-			continue
-		}
+	// Devices with a boot sequence stay quiet until its discovery stage
+	if len(device.MACAddress) == 0 || !h.stack.bootStageReached(device.Name, BootStageDiscovery) {
+		return
+	}
 
-		// Skip if FDP is explicitly disabled for this device
-		if device.FDPConfig != nil && !device.FDPConfig.Enabled {
-			continue
-		}
+	// Skip if FDP is explicitly disabled for this device
+	if device.FDPConfig != nil && !device.FDPConfig.Enabled {
+		return
+	}
 
-		// Build and send FDP frame
-		frame := h.buildFDPFrame(device)
-		if frame != nil {
-			err := h.sendFrame(device, frame)
-			if err != nil && debugLevel >= 2 {
-				fmt.Printf("FDP: Error sending advertisement for %s: %v\n", device.Name, err)
-			} else if debugLevel >= 3 {
-				fmt.Printf("FDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
-			}
+	// Build and send FDP frame
+	frame := h.buildFDPFrame(device)
+	if frame != nil {
+		err := h.sendFrame(device, frame)
+		if err != nil && debugLevel >= 2 {
+			fmt.Printf("FDP: Error sending advertisement for %s: %v\n", device.Name, err)
+		} else if debugLevel >= 3 {
+			fmt.Printf("FDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
 		}
 	}
 }
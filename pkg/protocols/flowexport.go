@@ -0,0 +1,68 @@
+package protocols
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/flow"
+)
+
+// newFlowExporter connects an exporter for cfg, or returns nil when flow
+// export is not configured or the collector cannot be reached.
+func newFlowExporter(cfg *config.FlowExportConfig) *flow.Exporter {
+	if cfg == nil {
+		return nil
+	}
+	exporter, err := flow.NewExporter(cfg)
+	if err != nil {
+		fmt.Printf("Flow export disabled: %v\n", err)
+		return nil
+	}
+	return exporter
+}
+
+// currentFlowExporter returns the flow exporter in use, or nil.
+func (s *Stack) currentFlowExporter() *flow.Exporter {
+	s.flowMu.RLock()
+	defer s.flowMu.RUnlock()
+	return s.flowExporter
+}
+
+// observeFlow hands a sent or received frame to the flow exporter, if any.
+func (s *Stack) observeFlow(frame []byte, received bool) {
+	if exporter := s.currentFlowExporter(); exporter != nil {
+		exporter.Observe(frame, received)
+	}
+}
+
+// reloadFlowExporter replaces the flow exporter when a reload changes
+// flow_export from previous to next, so a new collector, sampling rate or
+// agent address takes effect without a restart. The old exporter sends its
+// pending samples before it closes.
+func (s *Stack) reloadFlowExporter(previous, next *config.FlowExportConfig) {
+	if reflect.DeepEqual(previous, next) {
+		return
+	}
+
+	exporter := newFlowExporter(next)
+	if exporter != nil && s.running {
+		exporter.Start()
+	}
+	s.flowMu.Lock()
+	old := s.flowExporter
+	s.flowExporter = exporter
+	s.flowMu.Unlock()
+
+	if old != nil {
+		old.Stop()
+	}
+	if s.debugConfig.GetGlobal() >= 1 {
+		switch {
+		case next == nil:
+			fmt.Println("Flow export stopped")
+		case exporter != nil:
+			fmt.Printf("Flow export reconfigured: %s, 1 in %d frames\n", next.Collector, next.SamplingRate)
+		}
+	}
+}
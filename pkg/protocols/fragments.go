@@ -0,0 +1,306 @@
+package protocols
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// fragmentTimeout is how long a partial datagram waits for its missing
+	// fragments (the Linux ipfrag_time default)
+	fragmentTimeout = 30 * time.Second
+
+	// maxReassembledSize is the largest datagram payload accepted; fragments
+	// reaching past it are dropped with the rest of their datagram
+	maxReassembledSize = 65535
+
+	// maxPendingDatagrams caps partial datagrams held at once so a fragment
+	// flood cannot exhaust memory; further datagrams are dropped until
+	// pending ones complete or time out
+	maxPendingDatagrams = 1024
+
+	// maxFragmentsPerDatagram bounds the work a single datagram can cause
+	maxFragmentsPerDatagram = 1024
+)
+
+// fragmentKey identifies the datagram a fragment belongs to (RFC 791 and
+// RFC 8200: source, destination, identification and protocol)
+type fragmentKey struct {
+	src, dst string
+	id       uint32
+	proto    uint8
+}
+
+type fragment struct {
+	offset int
+	data   []byte
+}
+
+// partialDatagram collects the fragments received so far for one datagram.
+type partialDatagram struct {
+	fragments []fragment
+	total     int // Payload length, known once the last fragment arrives (-1 until then)
+	firstSeen time.Time
+}
+
+// reassembler rebuilds IPv4 and IPv6 datagrams from their fragments.
+type reassembler struct {
+	mu      sync.Mutex
+	pending map[fragmentKey]*partialDatagram
+}
+
+func newReassembler() *reassembler {
+	return &reassembler{pending: make(map[fragmentKey]*partialDatagram)}
+}
+
+// add records a fragment carrying payload at byte offset of its datagram;
+// more is false for the last fragment. Once every byte has arrived it returns
+// the reassembled payload and true.
+func (r *reassembler) add(key fragmentKey, offset int, more bool, payload []byte, now time.Time) ([]byte, bool) {
+	end := offset + len(payload)
+	if end > maxReassembledSize || (more && len(payload)%8 != 0) {
+		r.drop(key)
+		return nil, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	datagram := r.pending[key]
+	if datagram == nil {
+		if len(r.pending) >= maxPendingDatagrams {
+			r.expireLocked(now)
+			if len(r.pending) >= maxPendingDatagrams {
+				return nil, false
+			}
+		}
+		datagram = &partialDatagram{total: -1, firstSeen: now}
+		r.pending[key] = datagram
+	}
+	if len(datagram.fragments) >= maxFragmentsPerDatagram {
+		delete(r.pending, key)
+		return nil, false
+	}
+
+	// Fragments must agree on where the datagram ends: one reaching past the
+	// end the last fragment declared, or a second last fragment declaring a
+	// different end, discards the whole datagram
+	if datagram.total >= 0 && (end > datagram.total || (!more && end != datagram.total)) {
+		delete(r.pending, key)
+		return nil, false
+	}
+	if !more && datagram.total < 0 {
+		for _, f := range datagram.fragments {
+			if f.offset+len(f.data) > end {
+				delete(r.pending, key)
+				return nil, false
+			}
+		}
+	}
+
+	datagram.fragments = append(datagram.fragments, fragment{offset: offset, data: append([]byte(nil), payload...)})
+	if !more {
+		datagram.total = end
+	}
+	if datagram.total < 0 {
+		return nil, false
+	}
+
+	// Complete once the fragments cover the payload without gaps
+	sort.Slice(datagram.fragments, func(i, j int) bool {
+		return datagram.fragments[i].offset < datagram.fragments[j].offset
+	})
+	covered := 0
+	for _, f := range datagram.fragments {
+		if f.offset > covered {
+			return nil, false
+		}
+		if f.offset+len(f.data) > covered {
+			covered = f.offset + len(f.data)
+		}
+	}
+	if covered < datagram.total {
+		return nil, false
+	}
+
+	whole := make([]byte, datagram.total)
+	for _, f := range datagram.fragments {
+		copy(whole[f.offset:], f.data)
+	}
+	delete(r.pending, key)
+	return whole, true
+}
+
+// drop discards a partial datagram.
+func (r *reassembler) drop(key fragmentKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, key)
+}
+
+// expireLocked discards partial datagrams older than fragmentTimeout.
+func (r *reassembler) expireLocked(now time.Time) {
+	for key, datagram := range r.pending {
+		if now.Sub(datagram.firstSeen) >= fragmentTimeout {
+			delete(r.pending, key)
+		}
+	}
+}
+
+// startFragmentCleanupLoop periodically discards timed-out partial datagrams.
+func (s *Stack) startFragmentCleanupLoop() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(fragmentTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.fragments.mu.Lock()
+				s.fragments.expireLocked(time.Now())
+				s.fragments.mu.Unlock()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// linkHeaderLen returns the length of pkt's Ethernet header, including an
+// 802.1Q tag.
+func linkHeaderLen(pkt *Packet) int {
+	if pkt.GetEtherType() == EtherTypeVLAN {
+		return SizeOfMac*2 + 4 + 2
+	}
+	return SizeOfMac*2 + 2
+}
+
+// reassembleIPv4 adds an IPv4 fragment to its datagram. When the datagram is
+// complete it returns the whole datagram as an unfragmented packet with the
+// first fragment's link header, ready for the IP handler.
+func (s *Stack) reassembleIPv4(pkt *Packet, ip *layers.IPv4) *Packet {
+	key := fragmentKey{src: string(ip.SrcIP.To4()), dst: string(ip.DstIP.To4()), id: uint32(ip.Id), proto: uint8(ip.Protocol)}
+	more := ip.Flags&layers.IPv4MoreFragments != 0
+	payload, ok := s.fragments.add(key, int(ip.FragOffset)*8, more, ip.Payload, time.Now())
+	if !ok {
+		return nil
+	}
+
+	header := &layers.IPv4{
+		Version:  4,
+		IHL:      5,
+		TOS:      ip.TOS,
+		Id:       ip.Id,
+		TTL:      ip.TTL,
+		Protocol: ip.Protocol,
+		SrcIP:    ip.SrcIP,
+		DstIP:    ip.DstIP,
+	}
+	return rebuildPacket(pkt, header, payload)
+}
+
+// reassembleIPv6 adds an IPv6 fragment to its datagram. When the datagram is
+// complete it returns the whole datagram, without the fragment header, as a
+// packet ready for the IPv6 handler.
+func (s *Stack) reassembleIPv6(pkt *Packet, ipv6 *layers.IPv6, frag *layers.IPv6Fragment) *Packet {
+	key := fragmentKey{src: string(ipv6.SrcIP.To16()), dst: string(ipv6.DstIP.To16()), id: frag.Identification, proto: uint8(frag.NextHeader)}
+	payload, ok := s.fragments.add(key, int(frag.FragmentOffset)*8, frag.MoreFragments, frag.Payload, time.Now())
+	if !ok {
+		return nil
+	}
+
+	header := &layers.IPv6{
+		Version:      6,
+		TrafficClass: ipv6.TrafficClass,
+		FlowLabel:    ipv6.FlowLabel,
+		NextHeader:   frag.NextHeader,
+		HopLimit:     ipv6.HopLimit,
+		SrcIP:        ipv6.SrcIP,
+		DstIP:        ipv6.DstIP,
+	}
+	return rebuildPacket(pkt, header, payload)
+}
+
+// rebuildPacket returns a packet with pkt's link header followed by the
+// network header and payload.
+func rebuildPacket(pkt *Packet, header gopacket.SerializableLayer, payload []byte) *Packet {
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, header, gopacket.Payload(payload)); err != nil {
+		return nil
+	}
+
+	linkLen := linkHeaderLen(pkt)
+	frame := make([]byte, 0, linkLen+len(buffer.Bytes()))
+	frame = append(frame, pkt.Buffer[:linkLen]...)
+	frame = append(frame, buffer.Bytes()...)
+	return &Packet{
+		Buffer:       frame,
+		Length:       len(frame),
+		SerialNumber: pkt.SerialNumber,
+		Timestamp:    pkt.Timestamp,
+		VLAN:         pkt.VLAN,
+		Reassembled:  true,
+		Interface:    pkt.Interface,
+	}
+}
+
+// nextIPv4ID returns an IPv4 identification for a datagram that has to be
+// fragmented.
+func (s *Stack) nextIPv4ID() uint16 {
+	return uint16(atomic.AddUint32(&s.ipv4ID, 1))
+}
+
+// fragmentIPv4 splits an Ethernet frame carrying an IPv4 datagram into frames
+// whose IP packets fit mtu. A datagram that already fits is returned as is.
+func fragmentIPv4(frame []byte, mtu int) ([][]byte, error) {
+	packet := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+	ip, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if !ok {
+		return nil, fmt.Errorf("frame has no IPv4 layer")
+	}
+	headerLen := int(ip.IHL) * 4
+	if headerLen+len(ip.Payload) <= mtu {
+		return [][]byte{frame}, nil
+	}
+	if ip.Flags&layers.IPv4DontFragment != 0 {
+		return nil, fmt.Errorf("datagram of %d bytes exceeds MTU %d with DF set", headerLen+len(ip.Payload), mtu)
+	}
+
+	// Every fragment but the last carries a multiple of 8 bytes
+	chunk := (mtu - headerLen) &^ 7
+	if chunk <= 0 {
+		return nil, fmt.Errorf("MTU %d too small to fragment", mtu)
+	}
+	linkLen := len(frame) - len(packet.NetworkLayer().LayerContents()) - len(packet.NetworkLayer().LayerPayload())
+	var frames [][]byte
+	for offset := 0; offset < len(ip.Payload); offset += chunk {
+		end := offset + chunk
+		header := *ip
+		header.Options = nil
+		header.IHL = 5
+		header.FragOffset = uint16(offset / 8)
+		header.Flags = ip.Flags
+		if end < len(ip.Payload) {
+			header.Flags |= layers.IPv4MoreFragments
+		} else {
+			end = len(ip.Payload)
+		}
+
+		buffer := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+		if err := gopacket.SerializeLayers(buffer, opts, &header, gopacket.Payload(ip.Payload[offset:end])); err != nil {
+			return nil, fmt.Errorf("serialize fragment: %w", err)
+		}
+		fragmentFrame := append(append([]byte(nil), frame[:linkLen]...), buffer.Bytes()...)
+		frames = append(frames, fragmentFrame)
+	}
+	return frames, nil
+}
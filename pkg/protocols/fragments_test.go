@@ -0,0 +1,258 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/capture"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+var (
+	fragTestHostMAC   = net.HardwareAddr{0x00, 0xAA, 0xBB, 0xCC, 0xDD, 0x05}
+	fragTestDeviceMAC = net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01}
+)
+
+func newFragmentTestStack(ip string) *Stack {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "router1",
+		MACAddress:  fragTestDeviceMAC,
+		IPAddresses: []net.IP{net.ParseIP(ip)},
+	}}}
+	return NewStack(nil, cfg, logging.NewDebugConfig(0))
+}
+
+// drainSendQueue returns the packets queued for sending
+func drainSendQueue(stack *Stack) []*Packet {
+	var queued []*Packet
+	for {
+		select {
+		case pkt := <-stack.sendQueue:
+			queued = append(queued, pkt)
+		default:
+			return queued
+		}
+	}
+}
+
+// frameRecorder is a capture writer keeping the frames sent
+type frameRecorder struct {
+	frames [][]byte
+}
+
+func (r *frameRecorder) WritePacketData(data []byte) error {
+	r.frames = append(r.frames, bytes.Clone(data))
+	return nil
+}
+
+// sendQueuedPackets passes the packets queued for sending through sendPacket,
+// as the send thread does, and returns the frames put on the wire
+func sendQueuedPackets(stack *Stack) [][]byte {
+	recorder := &frameRecorder{}
+	stack.capture = capture.NewWithWriter("test0", recorder, 0)
+	for _, pkt := range drainSendQueue(stack) {
+		stack.sendPacket(pkt)
+	}
+	return recorder.frames
+}
+
+// TestFragmentedICMPEcho tests that a ping split into fragments is answered
+// once, after the last fragment, with a reply fragmented to the device MTU
+func TestFragmentedICMPEcho(t *testing.T) {
+	stack := newFragmentTestStack("10.0.0.1")
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 200) // 3200 bytes
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	err := gopacket.SerializeLayers(buffer, opts,
+		&layers.Ethernet{SrcMAC: fragTestHostMAC, DstMAC: fragTestDeviceMAC, EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, Id: 0x4242, TTL: 64, Protocol: layers.IPProtocolICMPv4,
+			SrcIP: net.ParseIP("10.0.0.50").To4(), DstIP: net.ParseIP("10.0.0.1").To4()},
+		&layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: 7, Seq: 1},
+		gopacket.Payload(payload),
+	)
+	if err != nil {
+		t.Fatalf("serialize echo request: %v", err)
+	}
+	fragments, err := fragmentIPv4(buffer.Bytes(), 1500)
+	if err != nil || len(fragments) != 3 {
+		t.Fatalf("fragmentIPv4 = %d fragments (%v), want 3", len(fragments), err)
+	}
+
+	// Deliver out of order; nothing is answered until the datagram is complete
+	for i, index := range []int{2, 0, 1} {
+		pkt, err := ParsePacket(fragments[index], i+1)
+		if err != nil {
+			t.Fatalf("ParsePacket: %v", err)
+		}
+		stack.decodePacket(pkt)
+		if i < 2 {
+			if queued := drainSendQueue(stack); len(queued) != 0 {
+				t.Fatalf("%d packets sent after fragment %d, want none before reassembly", len(queued), index)
+			}
+		}
+	}
+
+	// The reply is one datagram, fragmented to fit the 1500-byte MTU
+	reassembly := newReassembler()
+	var reply []byte
+	queued := drainSendQueue(stack)
+	for _, pkt := range queued {
+		packet := gopacket.NewPacket(pkt.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		ip, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		if !ok {
+			t.Fatal("reply fragment has no IPv4 layer")
+		}
+		if int(ip.Length) > 1500 {
+			t.Errorf("reply fragment of %d bytes exceeds the MTU", ip.Length)
+		}
+		key := fragmentKey{src: string(ip.SrcIP), dst: string(ip.DstIP), id: uint32(ip.Id), proto: uint8(ip.Protocol)}
+		if whole, done := reassembly.add(key, int(ip.FragOffset)*8, ip.Flags&layers.IPv4MoreFragments != 0, ip.Payload, time.Now()); done {
+			if reply != nil {
+				t.Fatal("more than one reply datagram")
+			}
+			reply = whole
+		}
+	}
+	if len(queued) != 3 || reply == nil {
+		t.Fatalf("got %d reply fragments (complete %v), want one reply in 3 fragments", len(queued), reply != nil)
+	}
+
+	icmp, ok := gopacket.NewPacket(reply, layers.LayerTypeICMPv4, gopacket.Default).Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4)
+	if !ok || icmp.TypeCode.Type() != layers.ICMPv4TypeEchoReply || icmp.Id != 7 || icmp.Seq != 1 {
+		t.Fatalf("reassembled reply = %+v, want echo reply id=7 seq=1", icmp)
+	}
+	if !bytes.Equal(icmp.Payload, payload) {
+		t.Errorf("reply payload is %d bytes, want the %d-byte request payload echoed", len(icmp.Payload), len(payload))
+	}
+}
+
+// TestFragmentedICMPv6Echo tests IPv6 fragment reassembly on the receive path
+func TestFragmentedICMPv6Echo(t *testing.T) {
+	stack := newFragmentTestStack("2001:db8::1")
+	src, dst := net.ParseIP("2001:db8::50"), net.ParseIP("2001:db8::1")
+	payload := bytes.Repeat([]byte{0x5a}, 2000)
+
+	// Serialize the whole ICMPv6 message to get its checksum, then split it
+	ipv6 := &layers.IPv6{Version: 6, NextHeader: layers.IPProtocolICMPv6, HopLimit: 64, SrcIP: src, DstIP: dst}
+	icmpv6 := &layers.ICMPv6{TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeEchoRequest, 0)}
+	if err := icmpv6.SetNetworkLayerForChecksum(ipv6); err != nil {
+		t.Fatalf("SetNetworkLayerForChecksum: %v", err)
+	}
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, icmpv6, &layers.ICMPv6Echo{Identifier: 9, SeqNumber: 2}, gopacket.Payload(payload)); err != nil {
+		t.Fatalf("serialize echo request: %v", err)
+	}
+	message := buffer.Bytes()
+
+	for i, piece := range [][2]int{{0, 1448}, {1448, len(message)}} {
+		fragHeader := make([]byte, 8)
+		fragHeader[0] = byte(layers.IPProtocolICMPv6)
+		offsetFlags := uint16(piece[0]/8) << 3
+		if piece[1] < len(message) {
+			offsetFlags |= 1 // More fragments
+		}
+		binary.BigEndian.PutUint16(fragHeader[2:], offsetFlags)
+		binary.BigEndian.PutUint32(fragHeader[4:], 0xcafe)
+
+		frame := gopacket.NewSerializeBuffer()
+		err := gopacket.SerializeLayers(frame, opts,
+			&layers.Ethernet{SrcMAC: fragTestHostMAC, DstMAC: fragTestDeviceMAC, EthernetType: layers.EthernetTypeIPv6},
+			&layers.IPv6{Version: 6, NextHeader: layers.IPProtocolIPv6Fragment, HopLimit: 64, SrcIP: src, DstIP: dst},
+			gopacket.Payload(append(fragHeader, message[piece[0]:piece[1]]...)),
+		)
+		if err != nil {
+			t.Fatalf("serialize fragment: %v", err)
+		}
+		pkt, err := ParsePacket(frame.Bytes(), i+1)
+		if err != nil {
+			t.Fatalf("ParsePacket: %v", err)
+		}
+		stack.decodePacket(pkt)
+	}
+
+	queued := drainSendQueue(stack)
+	if len(queued) != 1 {
+		t.Fatalf("got %d replies, want one", len(queued))
+	}
+	packet := gopacket.NewPacket(queued[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	echo, ok := packet.Layer(layers.LayerTypeICMPv6Echo).(*layers.ICMPv6Echo)
+	if !ok || echo.Identifier != 9 || echo.SeqNumber != 2 {
+		t.Fatalf("reply = %+v, want echo reply id=9 seq=2", echo)
+	}
+	// gopacket leaves the echo data in the ICMPv6 layer's payload, after the
+	// identifier and sequence number
+	if icmp := packet.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6); !bytes.Equal(icmp.Payload[4:], payload) {
+		t.Errorf("reply carries %d bytes of echo data, want the %d-byte request payload", len(icmp.Payload)-4, len(payload))
+	}
+}
+
+func TestReassembler_TimeoutAndLimits(t *testing.T) {
+	r := newReassembler()
+	key := fragmentKey{src: "a", dst: "b", id: 1, proto: 1}
+	now := time.Now()
+
+	r.add(key, 0, true, make([]byte, 8), now)
+	r.mu.Lock()
+	r.expireLocked(now.Add(fragmentTimeout))
+	r.mu.Unlock()
+	if _, done := r.add(key, 8, false, make([]byte, 8), now.Add(fragmentTimeout)); done {
+		t.Error("datagram completed from a fragment whose first half timed out")
+	}
+
+	// Fragments past the maximum datagram size drop the datagram
+	other := fragmentKey{src: "a", dst: "b", id: 2, proto: 1}
+	r.add(other, 0, true, make([]byte, 8), now)
+	if _, done := r.add(other, maxReassembledSize-4, false, make([]byte, 8), now); done {
+		t.Error("oversized datagram reassembled")
+	}
+	r.mu.Lock()
+	pending := len(r.pending)
+	r.mu.Unlock()
+	if pending != 1 {
+		t.Errorf("%d partial datagrams pending, want only the timed-out key's restart", pending)
+	}
+}
+
+// TestReassembler_InconsistentTotal tests that fragments disagreeing with the
+// end the last fragment declares drop the datagram instead of reassembling
+// past it
+func TestReassembler_InconsistentTotal(t *testing.T) {
+	type frag struct {
+		offset int
+		more   bool
+		size   int
+	}
+	tests := []struct {
+		name      string
+		fragments []frag
+	}{
+		{"held fragment past the last fragment's end", []frag{{0, true, 16}, {16, true, 8}, {0, false, 8}}},
+		{"fragment past a known end", []frag{{8, false, 8}, {16, true, 8}, {0, true, 8}}},
+		{"second last fragment with another end", []frag{{8, false, 8}, {0, false, 24}, {0, true, 8}}},
+	}
+	for _, tt := range tests {
+		r := newReassembler()
+		key := fragmentKey{src: "a", dst: "b", id: 1, proto: 1}
+		for i, f := range tt.fragments {
+			if _, done := r.add(key, f.offset, f.more, make([]byte, f.size), time.Now()); done {
+				t.Errorf("%s: fragment %d completed an inconsistent datagram", tt.name, i+1)
+			}
+		}
+	}
+
+	// A consistent datagram still completes after an overlapping fragment
+	r := newReassembler()
+	key := fragmentKey{src: "a", dst: "b", id: 2, proto: 1}
+	r.add(key, 8, false, make([]byte, 8), time.Now())
+	if whole, done := r.add(key, 0, true, make([]byte, 16), time.Now()); !done || len(whole) != 16 {
+		t.Errorf("overlapping datagram: done %v with %d bytes, want 16 bytes", done, len(whole))
+	}
+}
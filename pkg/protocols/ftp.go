@@ -90,7 +90,7 @@ func (h *FTPHandler) sendResponse(ipLayer *layers.IPv4, tcpLayer *layers.TCP, re
 	ipReply := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      device.TTL(),
 		Protocol: layers.IPProtocolTCP,
 		SrcIP:    ipLayer.DstIP,
 		DstIP:    ipLayer.SrcIP,
@@ -180,7 +180,7 @@ func (h *FTPHandler) sendResponseV6(ipv6 *layers.IPv6, tcpLayer *layers.TCP, res
 		TrafficClass: 0,
 		FlowLabel:    0,
 		NextHeader:   layers.IPProtocolTCP,
-		HopLimit:     64,
+		HopLimit:     device.IPv6HopLimit(),
 		SrcIP:        ipv6.DstIP,
 		DstIP:        ipv6.SrcIP,
 	}
@@ -320,7 +320,7 @@ func (h *HTTPHandler) sendResponse(ipLayer *layers.IPv4, tcpLayer *layers.TCP, r
 	ipReply := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      device.TTL(),
 		Protocol: layers.IPProtocolTCP,
 		SrcIP:    ipLayer.DstIP,
 		DstIP:    ipLayer.SrcIP,
@@ -442,7 +442,7 @@ func (h *HTTPHandler) sendResponseV6(ipv6 *layers.IPv6, tcpLayer *layers.TCP, re
 		TrafficClass: 0,
 		FlowLabel:    0,
 		NextHeader:   layers.IPProtocolTCP,
-		HopLimit:     64,
+		HopLimit:     device.IPv6HopLimit(),
 		SrcIP:        ipv6.DstIP,
 		DstIP:        ipv6.SrcIP,
 	}
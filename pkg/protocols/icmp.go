@@ -1,7 +1,9 @@
 package protocols
 
 import (
+	"bytes"
 	"fmt"
+	"net"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -62,6 +64,7 @@ func (h *ICMPHandler) handleEchoRequest(pkt *Packet, ipLayer *layers.IPv4, icmp
 
 	// Get source MAC from original packet
 	srcMAC := pkt.GetSourceMAC()
+	groupAddressed := isGroupAddressed(pkt, ipLayer)
 
 	// Send reply from each matching device
 	for _, device := range devices {
@@ -70,14 +73,48 @@ func (h *ICMPHandler) handleEchoRequest(pkt *Packet, ipLayer *layers.IPv4, icmp
 		}
 
 		// Check if device has this IP
-		hasIP := false
+		replyIP := net.IP(nil)
 		for _, deviceIP := range device.IPAddresses {
 			if deviceIP.Equal(ipLayer.DstIP) {
-				hasIP = true
+				replyIP = ipLayer.DstIP
 				break
 			}
 		}
-		if !hasIP {
+		if replyIP == nil && groupAddressed && device.ICMPConfig != nil && device.ICMPConfig.RespondToBroadcastPing {
+			// Broadcast and multicast pings are answered from the device's
+			// own address, as a real host would
+			replyIP = selectIPv4Address([]*config.Device{device})
+		}
+		if replyIP == nil {
+			continue
+		}
+
+		// Only devices on the segment of the ingress interface answer
+		if !h.stack.reachesDevice(pkt, device) {
+			if debugLevel >= 3 {
+				fmt.Printf("ICMP Echo Request to %s on %s: device %s is on another segment, not replying\n",
+					ipLayer.DstIP, pkt.Interface, device.Name)
+			}
+			continue
+		}
+
+		// A device with no default route cannot reach an off-subnet source
+		if !device.ICMPConfig.AnswersSource(ipLayer.SrcIP) {
+			if debugLevel >= 2 {
+				fmt.Printf("ICMP Echo Request from off-subnet %s to device %s, not replying\n",
+					ipLayer.SrcIP, device.Name)
+			}
+			continue
+		}
+
+		// A reply larger than the device's MTU would not fit its link; only
+		// jumbo-enabled devices answer jumbo pings. A request that arrived
+		// in fragments is answered with a fragmented reply.
+		if replyLen := int(ipLayer.IHL)*4 + 8 + len(icmp.Payload); replyLen > device.LinkMTU() && !pkt.Reassembled {
+			if debugLevel >= 2 {
+				fmt.Printf("ICMP Echo Request to %s exceeds MTU %d of device %s (%d bytes), not replying\n",
+					ipLayer.DstIP, device.LinkMTU(), device.Name, replyLen)
+			}
 			continue
 		}
 
@@ -85,7 +122,7 @@ func (h *ICMPHandler) handleEchoRequest(pkt *Packet, ipLayer *layers.IPv4, icmp
 		err := h.sendEchoReply(
 			device.MACAddress,
 			srcMAC,
-			ipLayer.DstIP,
+			replyIP,
 			ipLayer.SrcIP,
 			icmp.Id,
 			icmp.Seq,
@@ -101,16 +138,24 @@ func (h *ICMPHandler) handleEchoRequest(pkt *Packet, ipLayer *layers.IPv4, icmp
 			h.stack.IncrementStat("icmp_replies")
 			if debugLevel >= 3 {
 				fmt.Printf("ICMP Echo Reply from %s (%s) to %s device=%s\n",
-					ipLayer.DstIP, device.MACAddress, ipLayer.SrcIP, device.Name)
+					replyIP, device.MACAddress, ipLayer.SrcIP, device.Name)
 			}
 		}
 	}
 }
 
+// isGroupAddressed reports whether an IPv4 packet was sent to a broadcast
+// (limited, or directed as seen by its broadcast MAC) or multicast address
+func isGroupAddressed(pkt *Packet, ipLayer *layers.IPv4) bool {
+	return ipLayer.DstIP.Equal(net.IPv4bcast) ||
+		ipLayer.DstIP.IsMulticast() ||
+		bytes.Equal(pkt.GetDestMAC(), layers.EthernetBroadcast)
+}
+
 // sendEchoReply sends an ICMP Echo Reply
 func (h *ICMPHandler) sendEchoReply(srcMAC, dstMAC []byte, srcIP, dstIP []byte, id, seq uint16, payload []byte, device *config.Device) error {
-	// Get TTL from config, or use default
-	ttl := uint8(64)
+	// Get TTL from the ICMP config, or the device's ip_ttl
+	ttl := device.TTL()
 	if device.ICMPConfig != nil && device.ICMPConfig.TTL > 0 {
 		ttl = device.ICMPConfig.TTL
 	}
@@ -131,6 +176,10 @@ func (h *ICMPHandler) sendEchoReply(srcMAC, dstMAC []byte, srcIP, dstIP []byte,
 		SrcIP:    srcIP,
 		DstIP:    dstIP,
 	}
+	fragmented := 20+8+len(payload) > device.LinkMTU()
+	if fragmented {
+		ipLayer.Id = h.stack.nextIPv4ID()
+	}
 
 	// Build ICMP header
 	icmpLayer := &layers.ICMPv4{
@@ -156,21 +205,30 @@ func (h *ICMPHandler) sendEchoReply(srcMAC, dstMAC []byte, srcIP, dstIP []byte,
 		return fmt.Errorf("error serializing ICMP reply: %v", err)
 	}
 
-	// Get serial number
-	h.stack.mu.Lock()
-	h.stack.serialNumber++
-	serialNum := h.stack.serialNumber
-	h.stack.mu.Unlock()
-
-	// Create and send packet
-	pkt := &Packet{
-		Buffer:       buffer.Bytes(),
-		Length:       len(buffer.Bytes()),
-		SerialNumber: serialNum,
-		Device:       device,
+	frames := [][]byte{buffer.Bytes()}
+	if fragmented {
+		if frames, err = fragmentIPv4(buffer.Bytes(), device.LinkMTU()); err != nil {
+			return fmt.Errorf("error fragmenting ICMP reply: %v", err)
+		}
 	}
 
-	h.stack.Send(pkt)
+	for _, frame := range frames {
+		// Get serial number
+		h.stack.mu.Lock()
+		h.stack.serialNumber++
+		serialNum := h.stack.serialNumber
+		h.stack.mu.Unlock()
+
+		// Create and send packet
+		pkt := &Packet{
+			Buffer:       frame,
+			Length:       len(frame),
+			SerialNumber: serialNum,
+			Device:       device,
+		}
+
+		h.stack.sendResponse(pkt)
+	}
 
 	return nil
 }
@@ -188,7 +246,7 @@ func (h *ICMPHandler) SendICMPUnreachable(srcIP, dstIP []byte, srcMAC, dstMAC []
 	ipLayer := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      h.stack.originTTL(srcMAC),
 		Protocol: layers.IPProtocolICMPv4,
 		SrcIP:    srcIP,
 		DstIP:    dstIP,
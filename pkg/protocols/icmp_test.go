@@ -1,6 +1,7 @@
 package protocols
 
 import (
+	"fmt"
 	"net"
 	"testing"
 
@@ -102,6 +103,118 @@ func TestHandleICMPEchoRequest(t *testing.T) {
 	}
 }
 
+// TestHandleICMPEchoRequest_JumboMTU verifies that a 9000-byte ping is
+// answered only by a device whose MTU allows it
+func TestHandleICMPEchoRequest_JumboMTU(t *testing.T) {
+	cfg := &config.Config{}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewICMPHandler(stack)
+
+	jumbo := &config.Device{
+		Name:        "jumbo",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+		IPAddresses: []net.IP{net.ParseIP("192.168.1.1").To4()},
+		MTU:         config.JumboMTU,
+	}
+	standard := &config.Device{
+		Name:        "standard",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x02},
+		IPAddresses: []net.IP{net.ParseIP("192.168.1.2").To4()},
+	}
+
+	ping := func(device *config.Device) (*Packet, *layers.IPv4) {
+		ipLayer := &layers.IPv4{
+			Version:  4,
+			IHL:      5,
+			TTL:      64,
+			Protocol: layers.IPProtocolICMPv4,
+			SrcIP:    net.ParseIP("192.168.1.100").To4(),
+			DstIP:    device.IPAddresses[0],
+		}
+		icmpLayer := &layers.ICMPv4{
+			TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0),
+			Id:       1,
+			Seq:      1,
+		}
+		// 20-byte IP header + 8-byte ICMP header + payload = 9000-byte packet
+		payload := make([]byte, config.JumboMTU-28)
+		buffer := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+		eth := &layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF},
+			DstMAC:       device.MACAddress,
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		if err := gopacket.SerializeLayers(buffer, opts, eth, ipLayer, icmpLayer, gopacket.Payload(payload)); err != nil {
+			t.Fatalf("Failed to serialize packet: %v", err)
+		}
+		return &Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())}, ipLayer
+	}
+
+	pkt, ipLayer := ping(jumbo)
+	handler.HandlePacket(pkt, ipLayer, []*config.Device{jumbo})
+	select {
+	case reply := <-stack.sendQueue:
+		if len(reply.Buffer) != config.JumboMTU+14 {
+			t.Errorf("Jumbo reply is %d bytes, want %d", len(reply.Buffer), config.JumboMTU+14)
+		}
+	default:
+		t.Fatal("Expected a jumbo echo reply from the jumbo-enabled device")
+	}
+
+	pkt, ipLayer = ping(standard)
+	handler.HandlePacket(pkt, ipLayer, []*config.Device{standard})
+	if depth, _ := stack.SendQueueDepth(); depth != 0 {
+		t.Errorf("Device with the default MTU sent %d replies to a jumbo ping, want 0", depth)
+	}
+}
+
+// TestHandleICMPEchoRequest_InterfaceScope verifies that a ping arriving on
+// another segment's interface is ignored
+func TestHandleICMPEchoRequest_InterfaceScope(t *testing.T) {
+	_, segment1, _ := net.ParseCIDR("10.0.1.0/24")
+	cfg := &config.Config{InterfaceScope: config.InterfaceScope{"eth1": {segment1}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewICMPHandler(stack)
+	device := &config.Device{
+		Name:        "sw0",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1").To4()},
+	}
+
+	ping := func(iface string) int {
+		ipLayer := &layers.IPv4{
+			Version:  4,
+			IHL:      5,
+			TTL:      64,
+			Protocol: layers.IPProtocolICMPv4,
+			SrcIP:    net.ParseIP("10.0.0.100").To4(),
+			DstIP:    device.IPAddresses[0],
+		}
+		icmpLayer := &layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: 1, Seq: 1}
+		eth := &layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF},
+			DstMAC:       device.MACAddress,
+			EthernetType: layers.EthernetTypeIPv4,
+		}
+		buffer := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+		if err := gopacket.SerializeLayers(buffer, opts, eth, ipLayer, icmpLayer); err != nil {
+			t.Fatalf("Failed to serialize packet: %v", err)
+		}
+		pkt := &Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes()), Interface: iface}
+		handler.HandlePacket(pkt, ipLayer, []*config.Device{device})
+		return len(drainSendQueue(stack))
+	}
+
+	if got := ping("eth1"); got != 0 {
+		t.Errorf("Device on 10.0.0.0/24 sent %d replies to a ping on eth1, want 0", got)
+	}
+	if got := ping("eth0"); got != 1 {
+		t.Errorf("Device sent %d replies to a ping on unscoped eth0, want 1", got)
+	}
+}
+
 // TestHandleICMPEchoRequest_NoMatchingDevice verifies handling when IP doesn't match
 func TestHandleICMPEchoRequest_NoMatchingDevice(t *testing.T) {
 	cfg := &config.Config{}
@@ -413,3 +526,153 @@ func BenchmarkSendEchoReply(b *testing.B) {
 		handler.sendEchoReply(srcMAC, dstMAC, srcIP, dstIP, id, seq, payload, device)
 	}
 }
+
+// TestHandleICMPEchoRequest_BroadcastPing verifies that pings to a broadcast
+// or multicast address are ignored unless respond_to_broadcast_ping is set,
+// and answered from the device's own address when it is
+func TestHandleICMPEchoRequest_BroadcastPing(t *testing.T) {
+	tests := []struct {
+		name   string
+		dstMAC net.HardwareAddr
+		dstIP  net.IP
+	}{
+		{"directed broadcast", layers.EthernetBroadcast, net.ParseIP("192.168.1.255").To4()},
+		{"limited broadcast", layers.EthernetBroadcast, net.IPv4bcast.To4()},
+		{"multicast", net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x01}, net.ParseIP("224.0.0.1").To4()},
+	}
+
+	for _, tt := range tests {
+		for _, respond := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/respond=%v", tt.name, respond), func(t *testing.T) {
+				stack := NewStack(nil, &config.Config{}, logging.NewDebugConfig(0))
+				device := &config.Device{
+					Name:        "Test-Device",
+					MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+					IPAddresses: []net.IP{net.ParseIP("192.168.1.1").To4()},
+					ICMPConfig:  &config.ICMPConfig{Enabled: true, RespondToBroadcastPing: respond},
+				}
+				stack.devices.AddByMAC(device.MACAddress, device)
+				stack.devices.AddByIP(device.IPAddresses[0], device)
+
+				eth := &layers.Ethernet{
+					SrcMAC:       net.HardwareAddr{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF},
+					DstMAC:       tt.dstMAC,
+					EthernetType: layers.EthernetTypeIPv4,
+				}
+				ipLayer := &layers.IPv4{
+					Version:  4,
+					IHL:      5,
+					TTL:      64,
+					Protocol: layers.IPProtocolICMPv4,
+					SrcIP:    net.ParseIP("192.168.1.100").To4(),
+					DstIP:    tt.dstIP,
+				}
+				icmpLayer := &layers.ICMPv4{
+					TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0),
+					Id:       1234,
+					Seq:      1,
+				}
+				buffer := gopacket.NewSerializeBuffer()
+				opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+				if err := gopacket.SerializeLayers(buffer, opts, eth, ipLayer, icmpLayer, gopacket.Payload([]byte("test"))); err != nil {
+					t.Fatalf("Failed to serialize packet: %v", err)
+				}
+
+				NewIPHandler(stack).HandlePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+
+				if !respond {
+					if depth, _ := stack.SendQueueDepth(); depth != 0 {
+						t.Fatalf("Sent %d replies with respond_to_broadcast_ping off, want 0", depth)
+					}
+					return
+				}
+				select {
+				case reply := <-stack.sendQueue:
+					packet := gopacket.NewPacket(reply.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+					replyIP, _ := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+					if replyIP == nil || !replyIP.SrcIP.Equal(device.IPAddresses[0]) {
+						t.Errorf("Reply source = %v, want device address %s", replyIP, device.IPAddresses[0])
+					}
+				default:
+					t.Fatal("Expected an echo reply with respond_to_broadcast_ping set")
+				}
+			})
+		}
+	}
+}
+
+// TestHandleICMPEchoRequest_OffSubnet verifies that with reply_offsubnet off a
+// device answers pings from its own subnet and drops the rest
+func TestHandleICMPEchoRequest_OffSubnet(t *testing.T) {
+	_, local, _ := net.ParseCIDR("192.168.1.0/24")
+	tests := []struct {
+		name          string
+		dropOffSubnet bool
+		srcIP         string
+		wantReply     bool
+	}{
+		{"on-subnet source", true, "192.168.1.100", true},
+		{"off-subnet source", true, "10.9.9.9", false},
+		{"off-subnet source, reply_offsubnet on", false, "10.9.9.9", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack := NewStack(nil, &config.Config{}, logging.NewDebugConfig(0))
+			device := &config.Device{
+				Name:        "Test-Device",
+				MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+				IPAddresses: []net.IP{net.ParseIP("192.168.1.1").To4()},
+				ICMPConfig: &config.ICMPConfig{
+					Enabled:       true,
+					DropOffSubnet: tt.dropOffSubnet,
+					LocalSubnets:  []*net.IPNet{local},
+				},
+			}
+			stack.devices.AddByMAC(device.MACAddress, device)
+			stack.devices.AddByIP(device.IPAddresses[0], device)
+
+			eth := &layers.Ethernet{
+				SrcMAC:       net.HardwareAddr{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF},
+				DstMAC:       device.MACAddress,
+				EthernetType: layers.EthernetTypeIPv4,
+			}
+			ipLayer := &layers.IPv4{
+				Version:  4,
+				IHL:      5,
+				TTL:      64,
+				Protocol: layers.IPProtocolICMPv4,
+				SrcIP:    net.ParseIP(tt.srcIP).To4(),
+				DstIP:    device.IPAddresses[0],
+			}
+			icmpLayer := &layers.ICMPv4{
+				TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0),
+				Id:       1234,
+				Seq:      1,
+			}
+			buffer := gopacket.NewSerializeBuffer()
+			opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+			if err := gopacket.SerializeLayers(buffer, opts, eth, ipLayer, icmpLayer, gopacket.Payload([]byte("test"))); err != nil {
+				t.Fatalf("Failed to serialize packet: %v", err)
+			}
+
+			NewIPHandler(stack).HandlePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+
+			select {
+			case reply := <-stack.sendQueue:
+				if !tt.wantReply {
+					t.Fatal("Expected no reply to an off-subnet source")
+				}
+				packet := gopacket.NewPacket(reply.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+				icmp, _ := packet.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4)
+				if icmp == nil || icmp.TypeCode.Type() != layers.ICMPv4TypeEchoReply {
+					t.Errorf("Expected an echo reply, got %v", icmp)
+				}
+			default:
+				if tt.wantReply {
+					t.Fatalf("Expected an echo reply to %s", tt.srcIP)
+				}
+			}
+		})
+	}
+}
@@ -35,6 +35,7 @@ const (
 	ICMPv6OptPrefixInfo     = 3
 	ICMPv6OptRedirectedHdr  = 4
 	ICMPv6OptMTU            = 5
+	ICMPv6OptRouteInfo      = 24 // RFC 4191
 )
 
 // ICMPv6 Neighbor Discovery flags
@@ -49,6 +50,13 @@ const (
 	NDPrefixFlagAutonomous = 0x40
 )
 
+// Route Information Option preference bits (RFC 4191 section 2.1)
+const (
+	NDRoutePreferenceHigh   = 0x08
+	NDRoutePreferenceMedium = 0x00
+	NDRoutePreferenceLow    = 0x18
+)
+
 // ICMPv6Handler handles ICMPv6 packets (IPv6's version of ICMP)
 type ICMPv6Handler struct {
 	stack      *Stack
@@ -92,9 +100,10 @@ func (h *ICMPv6Handler) HandlePacket(pkt *Packet, packet gopacket.Packet, ipv6La
 	case ICMPv6TypeEchoReply:
 		// Silently accept echo replies
 	case ICMPv6TypeNeighborSolicitation:
-		h.handleNeighborSolicitation(pkt, packet, ipv6Layer)
+		h.learnNeighbor(packet, ipv6Layer)
+		h.handleNeighborSolicitation(pkt, packet, ipv6Layer, icmpv6)
 	case ICMPv6TypeNeighborAdvertisement:
-		// Silently accept neighbor advertisements
+		h.learnNeighbor(packet, ipv6Layer)
 	case ICMPv6TypeRouterSolicitation:
 		h.handleRouterSolicitation(pkt, packet, ipv6Layer)
 	case ICMPv6TypeRouterAdvertisement:
@@ -106,6 +115,14 @@ func (h *ICMPv6Handler) HandlePacket(pkt *Packet, packet gopacket.Packet, ipv6La
 	}
 }
 
+// learnNeighbor records the sender of a neighbor solicitation or
+// advertisement in the ARP/ND table.
+func (h *ICMPv6Handler) learnNeighbor(packet gopacket.Packet, ipv6 *layers.IPv6) {
+	if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		h.stack.learnNeighbor(ipv6.SrcIP, eth.SrcMAC)
+	}
+}
+
 // handleEchoRequest responds to ICMPv6 Echo Request (ping6)
 func (h *ICMPv6Handler) handleEchoRequest(pkt *Packet, packet gopacket.Packet, ipv6 *layers.IPv6, icmpv6 *layers.ICMPv6, devices []*config.Device) {
 	if len(devices) == 0 {
@@ -143,6 +160,15 @@ func (h *ICMPv6Handler) handleEchoRequest(pkt *Packet, packet gopacket.Packet, i
 			continue
 		}
 
+		// Only devices on the segment of the ingress interface answer
+		if !h.stack.reachesDevice(pkt, device) {
+			if h.debugLevel >= 3 {
+				fmt.Printf("ICMPv6: Echo Request to %s on %s: device %s is on another segment, not replying sn=%d\n",
+					ipv6.DstIP, pkt.Interface, device.Name, pkt.SerialNumber)
+			}
+			continue
+		}
+
 		reply := &layers.ICMPv6{
 			TypeCode: layers.CreateICMPv6TypeCode(ICMPv6TypeEchoReply, 0),
 		}
@@ -171,15 +197,15 @@ func (h *ICMPv6Handler) handleEchoRequest(pkt *Packet, packet gopacket.Packet, i
 }
 
 // handleNeighborSolicitation responds to Neighbor Solicitation (NDP - like ARP for IPv6)
-func (h *ICMPv6Handler) handleNeighborSolicitation(pkt *Packet, packet gopacket.Packet, ipv6 *layers.IPv6) {
+func (h *ICMPv6Handler) handleNeighborSolicitation(pkt *Packet, packet gopacket.Packet, ipv6 *layers.IPv6, icmpv6 *layers.ICMPv6) {
 	ethLayer := packet.Layer(layers.LayerTypeEthernet)
 	if ethLayer == nil {
 		return
 	}
 	eth := ethLayer.(*layers.Ethernet)
 
-	// Parse NS message: Type(1) | Code(1) | Checksum(2) | Reserved(4) | Target Address(16) | Options...
-	data := packet.ApplicationLayer().Payload()
+	// NS body after type, code and checksum: Reserved(4) | Target Address(16) | Options...
+	data := icmpv6.Payload
 	if len(data) < 20 {
 		if h.debugLevel >= 2 {
 			fmt.Printf("ICMPv6: NS too short sn=%d\n", pkt.SerialNumber)
@@ -203,8 +229,15 @@ func (h *ICMPv6Handler) handleNeighborSolicitation(pkt *Packet, packet gopacket.
 		return
 	}
 
-	// Send NA for each matching device
+	// Send NA for each matching device on the segment of the ingress interface
 	for _, device := range devices {
+		if !h.stack.reachesDevice(pkt, device) {
+			if h.debugLevel >= 3 {
+				fmt.Printf("ICMPv6: NS for %s on %s: device %s is on another segment, not replying sn=%d\n",
+					targetIP, pkt.Interface, device.Name, pkt.SerialNumber)
+			}
+			continue
+		}
 		err := h.sendNeighborAdvertisement(device, ipv6.SrcIP, eth.SrcMAC, targetIP)
 		if err != nil {
 			if h.debugLevel >= 2 {
@@ -287,7 +320,7 @@ func (h *ICMPv6Handler) handleRouterSolicitation(pkt *Packet, packet gopacket.Pa
 	}
 
 	for _, device := range h.stack.devices.GetAll() {
-		if !deviceCanAdvertiseIPv6(device) {
+		if !deviceCanAdvertiseIPv6(device) || !h.stack.reachesDevice(pkt, device) {
 			continue
 		}
 		srcIP := firstIPv6Address(device)
@@ -324,7 +357,7 @@ func (h *ICMPv6Handler) sendRouterAdvertisement(device *config.Device, srcIP, ds
 }
 
 func (h *ICMPv6Handler) buildRouterAdvertisementBody(device *config.Device, srcIP net.IP) []byte {
-	hopLimit := uint8(64)
+	hopLimit := device.IPv6HopLimit()
 	if device.ICMPv6Config != nil && device.ICMPv6Config.HopLimit > 0 {
 		hopLimit = device.ICMPv6Config.HopLimit
 	}
@@ -356,9 +389,46 @@ func (h *ICMPv6Handler) buildRouterAdvertisementBody(device *config.Device, srcI
 	body = append(body, []byte{0, 0, 0, 0}...)
 	body = append(body, prefix.To16()...)
 
+	if device.ICMPv6Config != nil {
+		for _, route := range device.ICMPv6Config.RouteInfo {
+			body = append(body, buildRouteInfoOption(route)...)
+		}
+	}
+
 	return body
 }
 
+// buildRouteInfoOption encodes a Route Information Option (RFC 4191). The
+// prefix is truncated to the fewest 8-octet units that hold its length.
+func buildRouteInfoOption(route config.RouteInfoOption) []byte {
+	prefixLen, _ := route.Prefix.Mask.Size()
+	prefixBytes := 0
+	switch {
+	case prefixLen > 64:
+		prefixBytes = 16
+	case prefixLen > 0:
+		prefixBytes = 8
+	}
+
+	var flags byte
+	switch route.Preference {
+	case config.RoutePreferenceHigh:
+		flags = NDRoutePreferenceHigh
+	case config.RoutePreferenceLow:
+		flags = NDRoutePreferenceLow
+	default:
+		flags = NDRoutePreferenceMedium
+	}
+
+	option := make([]byte, 8, 8+prefixBytes)
+	option[0] = ICMPv6OptRouteInfo
+	option[1] = byte(1 + prefixBytes/8)
+	option[2] = byte(prefixLen)
+	option[3] = flags
+	binary.BigEndian.PutUint32(option[4:8], route.Lifetime)
+	return append(option, route.Prefix.IP.To16()[:prefixBytes]...)
+}
+
 // sendICMPv6Packet sends an ICMPv6 packet
 func (h *ICMPv6Handler) sendICMPv6Packet(srcIP, dstIP net.IP, srcMAC, dstMAC net.HardwareAddr,
 	icmpv6 *layers.ICMPv6, payload []byte) error {
@@ -380,9 +450,12 @@ func (h *ICMPv6Handler) sendICMPv6PacketWithDevice(srcIP, dstIP net.IP, srcMAC,
 		msgType == ICMPv6TypeRouterAdvertisement ||
 		msgType == ICMPv6TypeRedirect
 
-	// For non-NDP types (like Echo Reply), use configured value
-	if !isNDP && device != nil && device.ICMPv6Config != nil && device.ICMPv6Config.HopLimit > 0 {
-		hopLimit = device.ICMPv6Config.HopLimit
+	// For non-NDP types (like Echo Reply), use the configured value
+	if !isNDP {
+		hopLimit = device.IPv6HopLimit()
+		if device != nil && device.ICMPv6Config != nil && device.ICMPv6Config.HopLimit > 0 {
+			hopLimit = device.ICMPv6Config.HopLimit
+		}
 	}
 
 	// Build Ethernet layer
@@ -404,8 +477,10 @@ func (h *ICMPv6Handler) sendICMPv6PacketWithDevice(srcIP, dstIP net.IP, srcMAC,
 		DstIP:        dstIP,
 	}
 
-	// Set ICMPv6 payload
-	icmpv6.Payload = payload
+	// The ICMPv6 checksum covers the IPv6 pseudo-header
+	if err := icmpv6.SetNetworkLayerForChecksum(ipv6); err != nil {
+		return fmt.Errorf("failed to set ICMPv6 checksum layer: %w", err)
+	}
 
 	// Serialize packet
 	buf := gopacket.NewSerializeBuffer()
@@ -414,7 +489,7 @@ func (h *ICMPv6Handler) sendICMPv6PacketWithDevice(srcIP, dstIP net.IP, srcMAC,
 		ComputeChecksums: true,
 	}
 
-	err := gopacket.SerializeLayers(buf, opts, eth, ipv6, icmpv6)
+	err := gopacket.SerializeLayers(buf, opts, eth, ipv6, icmpv6, gopacket.Payload(payload))
 	if err != nil {
 		return fmt.Errorf("failed to serialize ICMPv6 packet: %w", err)
 	}
@@ -425,7 +500,7 @@ func (h *ICMPv6Handler) sendICMPv6PacketWithDevice(srcIP, dstIP net.IP, srcMAC,
 	}
 
 	// Send the packet
-	return h.stack.SendRawPacket(buf.Bytes())
+	return h.stack.SendResponse(buf.Bytes())
 }
 
 // getTypeName returns a human-readable name for an ICMPv6 type
@@ -1,7 +1,13 @@
 package protocols
 
 import (
+	"net"
 	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
 )
 
 func TestICMPv6TypeNames(t *testing.T) {
@@ -110,3 +116,136 @@ func TestNDFlags(t *testing.T) {
 		t.Errorf("Solicited+Override flags should be 0x60, got 0x%02x", flags)
 	}
 }
+
+// TestRouterAdvertisement_RouteInfo tests that configured route_info entries
+// are sent as Route Information Options
+func TestRouterAdvertisement_RouteInfo(t *testing.T) {
+	_, specific, _ := net.ParseCIDR("2001:db8:100::/48")
+	_, host, _ := net.ParseCIDR("2001:db8:200::1/128")
+	device := config.Device{
+		Name:        "router1",
+		Type:        "router",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+		IPAddresses: []net.IP{net.ParseIP("2001:db8::1")},
+		ICMPv6Config: &config.ICMPv6Config{RouteInfo: []config.RouteInfoOption{
+			{Prefix: specific, Preference: config.RoutePreferenceHigh, Lifetime: 900},
+			{Prefix: host, Preference: config.RoutePreferenceLow, Lifetime: 60},
+		}},
+	}
+	stack := NewStack(nil, &config.Config{Devices: []config.Device{device}}, logging.NewDebugConfig(0))
+
+	dev := stack.GetDevices().GetAll()[0]
+	hostMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+	if err := stack.icmpv6Handler.sendRouterAdvertisement(dev, net.ParseIP("2001:db8::1"), net.ParseIP("fe80::50"), hostMAC); err != nil {
+		t.Fatalf("sendRouterAdvertisement: %v", err)
+	}
+	queued := drainSendQueue(stack)
+	if len(queued) != 1 {
+		t.Fatalf("got %d frames, want one Router Advertisement", len(queued))
+	}
+
+	packet := gopacket.NewPacket(queued[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	ra, ok := packet.Layer(layers.LayerTypeICMPv6RouterAdvertisement).(*layers.ICMPv6RouterAdvertisement)
+	if !ok {
+		t.Fatalf("sent frame is not a Router Advertisement: %v", packet)
+	}
+	var routes []layers.ICMPv6Option
+	for _, opt := range ra.Options {
+		if opt.Type == ICMPv6OptRouteInfo {
+			routes = append(routes, opt)
+		}
+	}
+	if len(routes) != 2 {
+		t.Fatalf("got %d Route Information Options, want 2", len(routes))
+	}
+
+	tests := []struct {
+		data     []byte
+		prefix   string
+		length   int
+		pref     byte
+		lifetime uint32
+	}{
+		{routes[0].Data, "2001:db8:100::", 48, NDRoutePreferenceHigh, 900},
+		{routes[1].Data, "2001:db8:200::1", 128, NDRoutePreferenceLow, 60},
+	}
+	for _, tt := range tests {
+		// Data follows the type and length octets
+		if int(tt.data[0]) != tt.length {
+			t.Errorf("%s: prefix length = %d, want %d", tt.prefix, tt.data[0], tt.length)
+		}
+		if tt.data[1] != tt.pref {
+			t.Errorf("%s: preference bits = %#02x, want %#02x", tt.prefix, tt.data[1], tt.pref)
+		}
+		if lifetime := uint32(tt.data[2])<<24 | uint32(tt.data[3])<<16 | uint32(tt.data[4])<<8 | uint32(tt.data[5]); lifetime != tt.lifetime {
+			t.Errorf("%s: lifetime = %d, want %d", tt.prefix, lifetime, tt.lifetime)
+		}
+		prefix := make(net.IP, net.IPv6len)
+		copy(prefix, tt.data[6:])
+		if !prefix.Equal(net.ParseIP(tt.prefix)) {
+			t.Errorf("prefix = %s, want %s", prefix, tt.prefix)
+		}
+	}
+	if len(routes[0].Data) != 14 {
+		t.Errorf("/48 option carries %d bytes, want a 16-byte (length 2) option", len(routes[0].Data)+2)
+	}
+}
+
+// TestICMPv6_InterfaceScope tests that a device only answers Neighbor
+// Solicitations and pings arriving on the capture interface whose subnet
+// holds its address
+func TestICMPv6_InterfaceScope(t *testing.T) {
+	_, segment1, _ := net.ParseCIDR("2001:db8:1::/64")
+	deviceIP := net.ParseIP("2001:db8::1")
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{Name: "sw0", MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01}, IPAddresses: []net.IP{deviceIP}},
+		},
+		InterfaceScope: config.InterfaceScope{"eth1": {segment1}},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewICMPv6Handler(stack, 0)
+	hostIP := net.ParseIP("2001:db8::100")
+	hostMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+
+	// send delivers an ICMPv6 message on iface and returns the replies sent
+	send := func(iface string, dstIP net.IP, typeCode layers.ICMPv6TypeCode, body gopacket.SerializableLayer) int {
+		t.Helper()
+		eth := &layers.Ethernet{SrcMAC: hostMAC, DstMAC: cfg.Devices[0].MACAddress, EthernetType: layers.EthernetTypeIPv6}
+		ip := &layers.IPv6{Version: 6, HopLimit: 255, NextHeader: layers.IPProtocolICMPv6, SrcIP: hostIP, DstIP: dstIP}
+		icmp := &layers.ICMPv6{TypeCode: typeCode}
+		if err := icmp.SetNetworkLayerForChecksum(ip); err != nil {
+			t.Fatalf("SetNetworkLayerForChecksum: %v", err)
+		}
+		buf := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+		if err := gopacket.SerializeLayers(buf, opts, eth, ip, icmp, body); err != nil {
+			t.Fatalf("serialize: %v", err)
+		}
+		packet := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+		pkt := &Packet{Buffer: buf.Bytes(), Length: len(buf.Bytes()), Interface: iface}
+		handler.HandlePacket(pkt, packet, ip, stack.GetDevices().GetByIPv6(dstIP))
+		return len(drainSendQueue(stack))
+	}
+	solicit := func(iface string) int {
+		return send(iface, net.ParseIP("ff02::1:ff00:1"), layers.CreateICMPv6TypeCode(ICMPv6TypeNeighborSolicitation, 0),
+			&layers.ICMPv6NeighborSolicitation{TargetAddress: deviceIP})
+	}
+	ping := func(iface string) int {
+		return send(iface, deviceIP, layers.CreateICMPv6TypeCode(ICMPv6TypeEchoRequest, 0),
+			&layers.ICMPv6Echo{Identifier: 1, SeqNumber: 1})
+	}
+
+	if got := solicit("eth1"); got != 0 {
+		t.Errorf("Device on 2001:db8::/64 sent %d advertisements to an NS on eth1, want 0", got)
+	}
+	if got := solicit("eth0"); got != 1 {
+		t.Errorf("Device sent %d advertisements to an NS on unscoped eth0, want 1", got)
+	}
+	if got := ping("eth1"); got != 0 {
+		t.Errorf("Device on 2001:db8::/64 sent %d replies to a ping on eth1, want 0", got)
+	}
+	if got := ping("eth0"); got != 1 {
+		t.Errorf("Device sent %d replies to a ping on unscoped eth0, want 1", got)
+	}
+}
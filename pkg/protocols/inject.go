@@ -0,0 +1,58 @@
+package protocols
+
+import (
+	"fmt"
+)
+
+// Size limits of a frame injected with InjectFrame
+const (
+	MinInjectFrameSize = 14   // An Ethernet header; shorter runts are padded by most NICs anyway
+	MaxInjectFrameSize = 9216 // The largest jumbo frame commonly supported
+)
+
+// InjectFrame transmits a hand-crafted Ethernet frame on behalf of the named
+// device, for protocols NIAC does not model. The frame goes out exactly as
+// given: it bypasses the send queue, the run marker and every protocol
+// handler, and only its length is checked. It counts as sent by the device.
+// A device that is powered off, disabled or still booting, or whose interface
+// is shut, cannot inject.
+func (s *Stack) InjectFrame(name string, frame []byte) error {
+	if len(frame) < MinInjectFrameSize || len(frame) > MaxInjectFrameSize {
+		return fmt.Errorf("frame is %d bytes, must be between %d and %d",
+			len(frame), MinInjectFrameSize, MaxInjectFrameSize)
+	}
+	device := s.findDevice(name)
+	if device == nil {
+		return fmt.Errorf("device %q not found", name)
+	}
+	if !s.IsDevicePowered(name) {
+		return fmt.Errorf("device %q is powered off", name)
+	}
+	if !s.IsDeviceEnabled(name) {
+		return fmt.Errorf("device %q is disabled", name)
+	}
+	if s.IsDeviceBooting(name) {
+		return fmt.Errorf("device %q is booting", name)
+	}
+	if s.linkDown(device) {
+		return fmt.Errorf("device %q interface %d is admin down", name, primaryIfIndex)
+	}
+	if s.capture == nil {
+		return fmt.Errorf("no capture interface to send on")
+	}
+
+	if err := s.capture.SendPacket(frame); err != nil {
+		s.stats.mu.Lock()
+		s.stats.Errors++
+		s.stats.mu.Unlock()
+		s.deviceStats.failed(device.Name)
+		return err
+	}
+
+	s.stats.mu.Lock()
+	s.stats.PacketsSent++
+	s.stats.mu.Unlock()
+	s.deviceStats.sent(device.Name, frame)
+	s.observeFlow(frame, false)
+	return nil
+}
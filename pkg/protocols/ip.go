@@ -58,6 +58,12 @@ func (h *IPHandler) HandlePacket(pkt *Packet) {
 	isBroadcast := ip.DstIP.Equal([]byte{255, 255, 255, 255})
 	devices := h.stack.GetDevices().GetByIP(ip.DstIP)
 
+	// Pings to a directed broadcast or multicast group also reach every
+	// device; each decides whether to answer (respond_to_broadcast_ping)
+	if len(devices) == 0 && ip.Protocol == IPProtocolICMP && isGroupAddressed(pkt, ip) {
+		isBroadcast = true
+	}
+
 	if len(devices) == 0 && !isBroadcast {
 		// Not for us and not broadcast
 		if debugLevel >= 3 {
@@ -66,6 +72,18 @@ func (h *IPHandler) HandlePacket(pkt *Packet) {
 		return
 	}
 
+	// Fragments wait for the rest of their datagram, which is then handled
+	// as if it had arrived whole
+	if ip.Flags&layers.IPv4MoreFragments != 0 || ip.FragOffset != 0 {
+		if whole := h.stack.reassembleIPv4(pkt, ip); whole != nil {
+			h.HandlePacket(whole)
+		} else if debugLevel >= 3 {
+			fmt.Printf("IP fragment %s -> %s id=%d offset=%d held for reassembly sn=%d\n",
+				ip.SrcIP, ip.DstIP, ip.Id, int(ip.FragOffset)*8, pkt.SerialNumber)
+		}
+		return
+	}
+
 	// For broadcast packets, deliver to all devices (for DHCP, etc.)
 	if isBroadcast && len(devices) == 0 {
 		devices = h.stack.GetDevices().GetAll()
@@ -99,7 +117,7 @@ func (h *IPHandler) SendIPPacket(srcIP, dstIP net.IP, protocol layers.IPProtocol
 	ipLayer := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      h.stack.originTTL(srcMAC),
 		Protocol: protocol,
 		SrcIP:    srcIP,
 		DstIP:    dstIP,
@@ -143,3 +161,9 @@ func (h *IPHandler) SendIPPacket(srcIP, dstIP net.IP, protocol layers.IPProtocol
 
 	return nil
 }
+
+// originTTL returns the TTL of an IPv4 packet sent from srcMAC: the sending
+// device's ip_ttl, or the default when no simulated device owns srcMAC.
+func (s *Stack) originTTL(srcMAC net.HardwareAddr) uint8 {
+	return s.devices.GetByMAC(srcMAC).TTL()
+}
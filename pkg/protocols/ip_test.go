@@ -359,3 +359,81 @@ func BenchmarkSendIPPacket(b *testing.B) {
 		handler.SendIPPacket(srcIP, dstIP, protocol, payload, srcMAC, dstMAC)
 	}
 }
+
+// TestDeviceIPTTL verifies that a device's ip_ttl and ipv6_hop_limit apply to
+// every packet it originates, whatever the protocol
+func TestDeviceIPTTL(t *testing.T) {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "server",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x80},
+		IPAddresses: []net.IP{net.ParseIP("192.168.1.80").To4(), net.ParseIP("2001:db8::80")},
+		TCPPorts:    map[uint16]string{22: config.TCPPortOpen},
+		DNSConfig:   &config.DNSConfig{},
+		IPTTL:       128,
+		HopLimit:    100,
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	stack.dnsHandler.AddRecord("server.example.com", net.ParseIP("192.168.1.80"))
+
+	send := func(layersToSend ...gopacket.SerializableLayer) *layers.IPv4 {
+		t.Helper()
+		buffer := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+		eth := &layers.Ethernet{SrcMAC: tcpClientMAC, DstMAC: cfg.Devices[0].MACAddress, EthernetType: layers.EthernetTypeIPv4}
+		if err := gopacket.SerializeLayers(buffer, opts, append([]gopacket.SerializableLayer{eth}, layersToSend...)...); err != nil {
+			t.Fatalf("serialize: %v", err)
+		}
+		stack.decodePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+		queued := drainSendQueue(stack)
+		if len(queued) != 1 {
+			t.Fatalf("got %d replies, want 1", len(queued))
+		}
+		packet := gopacket.NewPacket(queued[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		ip, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		if !ok {
+			t.Fatalf("reply has no IPv4 layer: %v", packet)
+		}
+		return ip
+	}
+	newIPv4 := func(protocol layers.IPProtocol) *layers.IPv4 {
+		return &layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: protocol,
+			SrcIP: net.ParseIP("192.168.1.100").To4(), DstIP: net.ParseIP("192.168.1.80").To4()}
+	}
+
+	// ICMP echo reply
+	ip := newIPv4(layers.IPProtocolICMPv4)
+	icmp := &layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: 1, Seq: 1}
+	if reply := send(ip, icmp); reply.TTL != 128 {
+		t.Errorf("ICMP echo reply TTL = %d, want 128", reply.TTL)
+	}
+
+	// DNS response
+	ip = newIPv4(layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	_ = udp.SetNetworkLayerForChecksum(ip)
+	dns := &layers.DNS{ID: 1, RD: true, Questions: []layers.DNSQuestion{
+		{Name: []byte("server.example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN},
+	}}
+	if reply := send(ip, udp, dns); reply.TTL != 128 {
+		t.Errorf("DNS response TTL = %d, want 128", reply.TTL)
+	}
+
+	// TCP SYN-ACK
+	ip = newIPv4(layers.IPProtocolTCP)
+	tcp := &layers.TCP{SrcPort: 40000, DstPort: 22, SYN: true, Seq: 1000, Window: 65535}
+	_ = tcp.SetNetworkLayerForChecksum(ip)
+	if reply := send(ip, tcp); reply.TTL != 128 {
+		t.Errorf("TCP SYN-ACK TTL = %d, want 128", reply.TTL)
+	}
+
+	// IPv6 uses the device's hop limit
+	sendClientSegment(t, stack, "2001:db8::80", 22, layers.TCP{SYN: true, Seq: 2000}, nil)
+	queued := drainSendQueue(stack)
+	if len(queued) != 1 {
+		t.Fatalf("got %d IPv6 replies, want 1", len(queued))
+	}
+	packet := gopacket.NewPacket(queued[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	if ipv6, ok := packet.Layer(layers.LayerTypeIPv6).(*layers.IPv6); !ok || ipv6.HopLimit != 100 {
+		t.Errorf("IPv6 SYN-ACK = %v, want hop limit 100", packet)
+	}
+}
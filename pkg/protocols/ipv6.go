@@ -82,6 +82,18 @@ func (h *IPv6Handler) HandlePacket(pkt *Packet) {
 		// Multicast - continue processing for NDP, MLD, etc.
 	}
 
+	// Fragments wait for the rest of their datagram, which is then handled
+	// as if it had arrived whole
+	if frag, ok := packet.Layer(layers.LayerTypeIPv6Fragment).(*layers.IPv6Fragment); ok {
+		if whole := h.stack.reassembleIPv6(pkt, ipv6, frag); whole != nil {
+			h.HandlePacket(whole)
+		} else if h.debugLevel >= 3 {
+			fmt.Printf("IPv6 fragment %s -> %s id=%d offset=%d held for reassembly sn=%d\n",
+				ipv6.SrcIP, ipv6.DstIP, frag.Identification, int(frag.FragmentOffset)*8, pkt.SerialNumber)
+		}
+		return
+	}
+
 	// Walk extension headers to find the actual next protocol
 	nextHeader, offset := h.walkExtensionHeaders(packet, ipv6)
 
@@ -303,3 +315,10 @@ func (h *IPv6Handler) SendIPv6Packet(srcIP, dstIP net.IP, srcMAC, dstMAC net.Har
 func (h *IPv6Handler) SetDebugLevel(level int) {
 	h.debugLevel = level
 }
+
+// originHopLimit returns the hop limit of an IPv6 packet sent from srcMAC: the
+// sending device's ipv6_hop_limit, or the default when no simulated device
+// owns srcMAC.
+func (s *Stack) originHopLimit(srcMAC net.HardwareAddr) uint8 {
+	return s.devices.GetByMAC(srcMAC).IPv6HopLimit()
+}
@@ -0,0 +1,61 @@
+package protocols
+
+import (
+	"net"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// SendResponse queues raw bytes as a response from a simulated device. The
+// sending device is identified by the frame's source MAC and its latency model
+// (or the global one) decides how long the response is held back.
+func (s *Stack) SendResponse(data []byte) error {
+	s.mu.Lock()
+	s.serialNumber++
+	serialNum := s.serialNumber
+	s.mu.Unlock()
+
+	s.sendResponse(&Packet{
+		Buffer:       data,
+		Length:       len(data),
+		SerialNumber: serialNum,
+	})
+	return nil
+}
+
+// sendResponse queues a response packet after the device's simulated latency.
+// Each delayed response gets its own timer so a slow reply never holds up
+// unrelated requests or the send queue.
+func (s *Stack) sendResponse(pkt *Packet) {
+	delay := s.responseLatency(pkt)
+	if delay <= 0 {
+		s.Send(pkt)
+		return
+	}
+
+	s.stats.mu.Lock()
+	s.stats.DelayedResponses++
+	s.stats.AddedLatencyNanos += uint64(delay)
+	s.stats.mu.Unlock()
+
+	time.AfterFunc(delay, func() {
+		s.Send(pkt)
+	})
+}
+
+// responseLatency samples the latency model of the device sending pkt.
+func (s *Stack) responseLatency(pkt *Packet) time.Duration {
+	cfg := s.currentConfig()
+	return cfg.LatencyFor(s.sendingDevice(pkt)).Sample()
+}
+
+// sendingDevice returns the device sending pkt: its Device, or else the
+// device owning the frame's source MAC
+func (s *Stack) sendingDevice(pkt *Packet) *config.Device {
+	device, _ := pkt.Device.(*config.Device)
+	if device == nil && len(pkt.Buffer) >= 2*SizeOfMac {
+		device = s.devices.GetByMAC(net.HardwareAddr(pkt.Buffer[SizeOfMac : 2*SizeOfMac]))
+	}
+	return device
+}
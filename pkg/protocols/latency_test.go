@@ -0,0 +1,96 @@
+package protocols
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// TestSendResponse_LatencyJitter tests that a jitter model spreads response times
+// without serializing concurrent responses
+func TestSendResponse_LatencyJitter(t *testing.T) {
+	const replies = 40
+	base := 30 * time.Millisecond
+	jitter := 25 * time.Millisecond
+
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x77}
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name:        "slow-server",
+				MACAddress:  mac,
+				IPAddresses: []net.IP{net.ParseIP("192.168.1.50")},
+				Latency: &config.LatencyConfig{
+					Base:         base,
+					Jitter:       jitter,
+					Distribution: config.LatencyDistributionUniform,
+				},
+			},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewICMPHandler(stack)
+	device := &cfg.Devices[0]
+
+	start := time.Now()
+	for i := 0; i < replies; i++ {
+		err := handler.sendEchoReply(mac, net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee},
+			net.ParseIP("192.168.1.50").To4(), net.ParseIP("192.168.1.10").To4(),
+			1, uint16(i), []byte("ping"), device)
+		if err != nil {
+			t.Fatalf("sendEchoReply failed: %v", err)
+		}
+	}
+
+	var earliest, latest time.Duration
+	for i := 0; i < replies; i++ {
+		select {
+		case <-stack.sendQueue:
+			elapsed := time.Since(start)
+			if i == 0 {
+				earliest = elapsed
+			}
+			latest = elapsed
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for reply %d", i)
+		}
+	}
+
+	if earliest < base-jitter {
+		t.Errorf("Reply arrived after %v, before the minimum latency %v", earliest, base-jitter)
+	}
+	if spread := latest - earliest; spread < jitter/2 {
+		t.Errorf("Expected jitter to spread replies, got spread %v", spread)
+	}
+	// Delays run concurrently: all replies land within one max delay, not their sum
+	if latest > base+jitter+250*time.Millisecond {
+		t.Errorf("Replies appear serialized: last arrived after %v", latest)
+	}
+
+	stats := stack.GetStats()
+	if stats.DelayedResponses != replies {
+		t.Errorf("Expected %d delayed responses, got %d", replies, stats.DelayedResponses)
+	}
+	if stats.AddedLatencyNanos == 0 {
+		t.Error("Expected added latency to be recorded")
+	}
+}
+
+// TestSendResponse_NoLatency tests that responses are queued immediately without a latency model
+func TestSendResponse_NoLatency(t *testing.T) {
+	stack := NewStack(nil, &config.Config{}, logging.NewDebugConfig(0))
+
+	frame := make([]byte, 60)
+	if err := stack.SendResponse(frame); err != nil {
+		t.Fatalf("SendResponse failed: %v", err)
+	}
+	if len(stack.sendQueue) != 1 {
+		t.Fatalf("Expected response to be queued immediately, got %d", len(stack.sendQueue))
+	}
+	if stats := stack.GetStats(); stats.DelayedResponses != 0 {
+		t.Errorf("Expected no delayed responses, got %d", stats.DelayedResponses)
+	}
+}
@@ -0,0 +1,40 @@
+package protocols
+
+import (
+	"fmt"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// primaryIfIndex is the interface connecting a simulated device to the
+// capture interface; all of its traffic goes through it
+const primaryIfIndex = 1
+
+// linkDown reports whether the device's primary interface was shut by an
+// SNMP SET of ifAdminStatus (see snmp_agent.admin_status_set).
+func (s *Stack) linkDown(device *config.Device) bool {
+	agent := s.getSNMPAgent(device)
+	return agent != nil && !agent.InterfaceUp(primaryIfIndex)
+}
+
+// dropOnLinkDown reports whether pkt comes from a device whose primary
+// interface is shut, so that it stops answering and advertising. SNMP
+// responses still go out, as over an out-of-band management port, so the
+// manager can bring the interface back up.
+func (s *Stack) dropOnLinkDown(pkt *Packet) bool {
+	sender := s.sendingDevice(pkt)
+	if sender == nil || !s.linkDown(sender) {
+		return false
+	}
+	frame := pkt.Buffer
+	if pkt.Length > 0 && pkt.Length <= len(frame) {
+		frame = frame[:pkt.Length]
+	}
+	if frameProtocol(frame) == "snmp" {
+		return false
+	}
+	if s.debugConfig.GetGlobal() >= 3 {
+		fmt.Printf("Dropping packet sn=%d from %s: interface %d is admin down\n", pkt.SerialNumber, sender.Name, primaryIfIndex)
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package protocols
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+)
+
+// TestSNMPSetAdminStatus tests that SETting ifAdminStatus to down takes the
+// interface down: ifOperStatus follows, a linkDown trap fires and the device
+// stops answering
+func TestSNMPSetAdminStatus(t *testing.T) {
+	receiver, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot open UDP listener: %v", err)
+	}
+	defer receiver.Close()
+
+	deviceIP := net.ParseIP("192.168.1.1").To4()
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "access-sw1",
+		MACAddress:  fragTestDeviceMAC,
+		IPAddresses: []net.IP{deviceIP},
+		Interfaces:  []config.Interface{{Name: "GigabitEthernet0/1"}},
+		SNMPConfig: config.SNMPConfig{
+			Community:      "private",
+			AdminStatusSet: config.SNMPAdminStatusSetLinkState,
+			Traps: &config.TrapConfig{
+				Enabled:   true,
+				Receivers: []string{receiver.LocalAddr().String()},
+				LinkState: &config.LinkStateTrapConfig{Enabled: true, LinkDown: true, LinkUp: true},
+			},
+		},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	device := &cfg.Devices[0]
+
+	set := func(oid string, value int) *gosnmp.SnmpPacket {
+		t.Helper()
+		req := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, Community: "private", PDUType: gosnmp.SetRequest, RequestID: 9,
+			Variables: []gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.Integer, Value: value}}}
+		payload, err := req.MarshalMsg()
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		frame := append(append(append([]byte{}, fragTestDeviceMAC...), fragTestHostMAC...), 0x08, 0x00)
+		udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+		udpLayer.Payload = payload
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP("192.168.1.50").To4(), DstIP: deviceIP}
+		stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{device})
+
+		queued := drainSendQueue(stack)
+		if len(queued) != 1 {
+			t.Fatalf("got %d responses to the SET, want 1", len(queued))
+		}
+		decoded := gopacket.NewPacket(queued[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		udp, ok := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok {
+			t.Fatal("response missing UDP layer")
+		}
+		decoder := gosnmp.GoSNMP{Transport: "udp", Version: gosnmp.Version2c, Community: "private"}
+		response, err := decoder.SnmpDecodePacket(udp.Payload)
+		if err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return response
+	}
+
+	// Only ifAdminStatus is writable
+	if response := set(".1.3.6.1.2.1.1.5.0", 1); response.Error != gosnmp.NotWritable || response.ErrorIndex != 1 {
+		t.Errorf("SET of sysName = %v index %d, want notWritable 1", response.Error, response.ErrorIndex)
+	}
+	if response := set(".1.3.6.1.2.1.2.2.1.7.1", 3); response.Error != gosnmp.WrongValue {
+		t.Errorf("SET of ifAdminStatus to testing(3) = %v, want wrongValue", response.Error)
+	}
+
+	if response := set(".1.3.6.1.2.1.2.2.1.7.1", 2); response.Error != gosnmp.NoError {
+		t.Fatalf("SET of ifAdminStatus to down = %v, want noError", response.Error)
+	}
+	agent := stack.getSNMPAgent(device)
+	for _, oid := range []string{snmp.OIDIfAdminStatus + ".1", snmp.OIDIfOperStatus + ".1"} {
+		if value, err := agent.HandleGet(oid); err != nil || value.Value != 2 {
+			t.Errorf("%s = %v (%v), want down(2)", oid, value, err)
+		}
+	}
+
+	_ = receiver.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 65535)
+	n, _, err := receiver.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no linkDown trap: %v", err)
+	}
+	decoder := gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	trap, err := decoder.SnmpDecodePacket(buf[:n])
+	if err != nil {
+		t.Fatalf("decode trap: %v", err)
+	}
+	if len(trap.Variables) < 3 || trap.Variables[1].Value != snmp.OIDLinkDown || trap.Variables[2].Value != 1 {
+		t.Errorf("Expected linkDown for ifIndex 1, got %+v", trap.Variables)
+	}
+
+	// The device stops answering while its interface is down ...
+	stack.decodePacket(buildARPRequestPacket(t, "192.168.1.1"))
+	if queued := drainSendQueue(stack); len(queued) != 0 {
+		t.Errorf("got %d replies from a shut interface, want none", len(queued))
+	}
+
+	// ... and answers again once it is brought back up
+	if response := set(".1.3.6.1.2.1.2.2.1.7.1", 1); response.Error != gosnmp.NoError {
+		t.Fatalf("SET of ifAdminStatus to up = %v, want noError", response.Error)
+	}
+	stack.decodePacket(buildARPRequestPacket(t, "192.168.1.1"))
+	if queued := drainSendQueue(stack); len(queued) != 1 {
+		t.Errorf("got %d ARP replies after no shutdown, want 1", len(queued))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/gopacket"
@@ -22,6 +23,9 @@ const (
 
 	// LLDP TTL (Time To Live) - typically 4x advertisement interval
 	LLDPTTL = 120 // seconds
+
+	// Minimum gap between fast start advertisements for a device (msgFastTx)
+	LLDPFastStartInterval = 1 * time.Second
 )
 
 // LLDP TLV Types (per IEEE 802.1AB)
@@ -39,6 +43,23 @@ const (
 	LLDPTLVTypeOrganizationSpecific = 127
 )
 
+// IEEE 802.3 organizationally specific TLV (IEEE 802.1AB Annex F)
+const (
+	LLDPOUIIEEE8023                 = "\x00\x12\x0f"
+	LLDPIEEE8023SubtypePowerViaMDI  = 2
+	LLDPIEEE8023SubtypeMaxFrameSize = 4
+)
+
+// IEEE 802.3at Power via MDI type/source/priority field, as sent by a PD
+const (
+	lldpPoEType1PD      = 0xc0 // Power type: Type 1 PD (802.3af)
+	lldpPoEType2PD      = 0x40 // Power type: Type 2 PD (802.3at)
+	lldpPoESourcePSE    = 0x10 // Power source: PSE
+	lldpPoEPrioCritical = 0x01
+	lldpPoEPrioHigh     = 0x02
+	lldpPoEPrioLow      = 0x03
+)
+
 // LLDP Chassis ID Subtypes
 const (
 	LLDPChassisIDSubtypeChassisComponent = 1
@@ -78,13 +99,17 @@ type LLDPHandler struct {
 	stack           *Stack
 	stopChan        chan struct{}
 	advertiseTicker *time.Ticker
+
+	fastStartMu   sync.Mutex
+	lastFastStart map[string]time.Time // Device name -> last fast start advertisement
 }
 
 // NewLLDPHandler creates a new LLDP handler
 func NewLLDPHandler(stack *Stack) *LLDPHandler {
 	return &LLDPHandler{
-		stack:    stack,
-		stopChan: make(chan struct{}),
+		stack:         stack,
+		stopChan:      make(chan struct{}),
+		lastFastStart: make(map[string]time.Time),
 	}
 }
 
@@ -96,6 +121,12 @@ func (h *LLDPHandler) Start() {
 		fmt.Printf("LLDP: Starting periodic advertisements (interval: %v)\n", LLDPAdvertiseInterval)
 	}
 
+	// Spread first advertisements across the interval when phase jitter is set
+	if jitter := h.stack.discoveryPhaseJitter("lldp"); jitter > 0 {
+		go h.stack.runStaggeredAdvertisements("lldp", LLDPAdvertiseInterval, jitter, h.stopChan, h.sendAdvertisement)
+		return
+	}
+
 	h.advertiseTicker = time.NewTicker(LLDPAdvertiseInterval)
 
 	go func() {
@@ -121,28 +152,33 @@ func (h *LLDPHandler) Stop() {
 
 // sendAdvertisements sends LLDP advertisements for all devices
 func (h *LLDPHandler) sendAdvertisements() {
+	for _, device := range h.stack.GetDevices().GetAll() {
+		h.sendAdvertisement(device)
+	}
+}
+
+// sendAdvertisement sends a LLDP advertisement for one device
+func (h *LLDPHandler) sendAdvertisement(device *config.Device) {
 	debugLevel := h.stack.GetDebugLevel()
 
-	devices := h.stack.GetDevices().GetAll()
-	for _, device := range devices {
-		if len(device.MACAddress) == 0 {
-			continue
-		}
+	// Devices with a boot sequence stay quiet until its discovery stage
+	if len(device.MACAddress) == 0 || !h.stack.bootStageReached(device.Name, BootStageDiscovery) {
+		return
+	}
 
-		// Skip if LLDP is explicitly disabled for this device
-		if device.LLDPConfig != nil && !device.LLDPConfig.Enabled {
-			continue
-		}
+	// Skip if LLDP is explicitly disabled for this device
+	if device.LLDPConfig != nil && !device.LLDPConfig.Enabled {
+		return
+	}
 
-		// Build and send LLDP frame
-		frame := h.buildLLDPFrame(device)
-		if frame != nil {
-			err := h.sendFrame(device, frame)
-			if err != nil && debugLevel >= 2 {
-				fmt.Printf("LLDP: Error sending advertisement for %s: %v\n", device.Name, err)
-			} else if debugLevel >= 3 {
-				fmt.Printf("LLDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
-			}
+	// Build and send LLDP frame
+	frame := h.buildLLDPFrame(device)
+	if frame != nil {
+		err := h.sendFrame(device, frame)
+		if err != nil && debugLevel >= 2 {
+			fmt.Printf("LLDP: Error sending advertisement for %s: %v\n", device.Name, err)
+		} else if debugLevel >= 3 {
+			fmt.Printf("LLDP: Sent advertisement for %s (%d bytes)\n", device.Name, len(frame))
 		}
 	}
 }
@@ -161,11 +197,11 @@ func (h *LLDPHandler) buildLLDPFrame(device *config.Device) []byte {
 	frame = append(frame, h.buildSystemNameTLV(device)...)
 	frame = append(frame, h.buildSystemDescriptionTLV(device)...)
 	frame = append(frame, h.buildSystemCapabilitiesTLV(device)...)
+	frame = append(frame, h.buildMaxFrameSizeTLV(device)...)
+	frame = append(frame, h.buildPowerViaMDITLV(device)...)
 
-	// Management Address TLV (if device has IP address)
-	if len(device.IPAddresses) > 0 {
-		frame = append(frame, h.buildManagementAddressTLV(device)...)
-	}
+	// Management Address TLVs, one per device IP
+	frame = append(frame, h.buildManagementAddressTLV(device)...)
 
 	// End TLV (mandatory)
 	frame = append(frame, h.buildEndTLV()...)
@@ -260,10 +296,13 @@ func (h *LLDPHandler) buildTTLTLV(device *config.Device) []byte {
 
 // buildPortDescriptionTLV builds the Port Description TLV
 func (h *LLDPHandler) buildPortDescriptionTLV(device *config.Device) []byte {
-	// Use port description from config if available, otherwise generate default
+	// Use port description from config if available, then the description of
+	// the port's interface (its SNMP ifAlias), otherwise generate default
 	var description []byte
 	if device.LLDPConfig != nil && device.LLDPConfig.PortDescription != "" {
 		description = []byte(device.LLDPConfig.PortDescription)
+	} else if len(device.Interfaces) > 0 && device.Interfaces[0].Description != "" {
+		description = []byte(device.Interfaces[0].Description)
 	} else {
 		description = []byte(fmt.Sprintf("%s interface", device.Type))
 	}
@@ -356,23 +395,26 @@ func (h *LLDPHandler) buildSystemCapabilitiesTLV(device *config.Device) []byte {
 	return tlv
 }
 
-// buildManagementAddressTLV builds the Management Address TLV
+// buildManagementAddressTLV builds one Management Address TLV per device IP,
+// so neighbors learn both the IPv4 and IPv6 addresses of a dual-stack device
 func (h *LLDPHandler) buildManagementAddressTLV(device *config.Device) []byte {
-	if len(device.IPAddresses) == 0 {
-		return nil
+	var tlvs []byte
+	for _, ip := range device.IPAddresses {
+		tlvs = append(tlvs, buildManagementAddressTLVFor(ip)...)
 	}
+	return tlvs
+}
 
-	// Use first IP address
-	ip := device.IPAddresses[0]
-
-	// Determine address subtype (IPv4 or IPv6)
+// buildManagementAddressTLVFor builds the Management Address TLV for one IP
+func buildManagementAddressTLVFor(ip net.IP) []byte {
+	// Determine address subtype (IANA address family: IPv4 or IPv6)
 	var addressSubtype byte
 	var addressBytes []byte
 
-	if len(ip) == 4 {
+	if ip4 := ip.To4(); ip4 != nil {
 		addressSubtype = 1 // IPv4
-		addressBytes = ip
-	} else if len(ip) == 16 {
+		addressBytes = ip4
+	} else if len(ip) == net.IPv6len {
 		addressSubtype = 2 // IPv6
 		addressBytes = ip
 	} else {
@@ -414,6 +456,65 @@ func (h *LLDPHandler) buildManagementAddressTLV(device *config.Device) []byte {
 	return tlv
 }
 
+// buildMaxFrameSizeTLV builds the IEEE 802.3 Maximum Frame Size TLV, which
+// tells neighbors whether the port accepts jumbo frames
+func (h *LLDPHandler) buildMaxFrameSizeTLV(device *config.Device) []byte {
+	length := 3 + 1 + 2 // OUI + subtype + frame size
+
+	tlv := make([]byte, 2+length)
+	tlv[0] = byte(LLDPTLVTypeOrganizationSpecific<<1) | byte((length>>8)&0x01)
+	tlv[1] = byte(length & 0xff)
+	copy(tlv[2:5], LLDPOUIIEEE8023)
+	tlv[5] = LLDPIEEE8023SubtypeMaxFrameSize
+	binary.BigEndian.PutUint16(tlv[6:8], uint16(device.MaxFrameSize()))
+
+	return tlv
+}
+
+// buildPowerViaMDITLV builds the IEEE 802.3 Power via MDI TLV with the
+// 802.3at power fields, advertising the device as a powered device. Devices
+// without a poe block send none.
+func (h *LLDPHandler) buildPowerViaMDITLV(device *config.Device) []byte {
+	poe := device.PoE
+	if poe == nil {
+		return nil
+	}
+
+	powerType := byte(lldpPoEType1PD)
+	if poe.Class == config.MaxPoEClass || poe.RequestedWatts > 12.95 {
+		powerType = lldpPoEType2PD
+	}
+	priority := byte(lldpPoEPrioLow)
+	switch poe.Priority {
+	case config.PoEPriorityCritical:
+		priority = lldpPoEPrioCritical
+	case config.PoEPriorityHigh:
+		priority = lldpPoEPrioHigh
+	}
+	requested := uint16(poe.RequestedWatts*10 + 0.5) // 0.1 W units
+
+	// OUI + subtype + MDI power support (1) + PSE power pair (1) + power
+	// class (1) + type/source/priority (1) + PD requested power (2) + PSE
+	// allocated power (2)
+	length := 3 + 1 + 8
+
+	tlv := make([]byte, 2+length)
+	tlv[0] = byte(LLDPTLVTypeOrganizationSpecific<<1) | byte((length>>8)&0x01)
+	tlv[1] = byte(length & 0xff)
+	copy(tlv[2:5], LLDPOUIIEEE8023)
+	tlv[5] = LLDPIEEE8023SubtypePowerViaMDI
+	tlv[6] = 0x00                // PD port class, no PSE capabilities
+	tlv[7] = 0x01                // Signal pairs
+	tlv[8] = byte(poe.Class + 1) // Encoded as class + 1
+	tlv[9] = powerType | lldpPoESourcePSE | priority
+	binary.BigEndian.PutUint16(tlv[10:12], requested)
+	// A PD echoes the power the PSE allocated; none has been learned, so the
+	// request is echoed
+	binary.BigEndian.PutUint16(tlv[12:14], requested)
+
+	return tlv
+}
+
 // buildEndTLV builds the End TLV
 func (h *LLDPHandler) buildEndTLV() []byte {
 	return []byte{0x00, 0x00} // Type=0, Length=0
@@ -422,7 +523,7 @@ func (h *LLDPHandler) buildEndTLV() []byte {
 // sendFrame sends an LLDP frame
 func (h *LLDPHandler) sendFrame(device *config.Device, lldpPayload []byte) error {
 	// Build Ethernet header
-	dstMAC, _ := net.ParseMAC(LLDPMulticastMAC)
+	dstMAC := net.HardwareAddr(LLDPMulticastMAC)
 
 	eth := &layers.Ethernet{
 		SrcMAC:       net.HardwareAddr(device.MACAddress),
@@ -514,7 +615,28 @@ func (h *LLDPHandler) HandlePacket(pkt *Packet) {
 		fmt.Printf("LLDP: Neighbor %s via %s (local %s)\n", entry.RemoteDevice, entry.RemotePort, entry.LocalDevice)
 	}
 
-	h.stack.recordNeighbor(entry)
+	if h.stack.recordNeighbor(entry) && device.LLDPConfig != nil && device.LLDPConfig.FastStart {
+		h.fastStart(device)
+	}
+}
+
+// fastStart advertises out of cycle so a newly heard neighbor learns the
+// device without waiting for the next interval (LLDP-MED fast start). It sends
+// at most one advertisement per device every LLDPFastStartInterval.
+func (h *LLDPHandler) fastStart(device *config.Device) {
+	now := time.Now()
+	h.fastStartMu.Lock()
+	if last, ok := h.lastFastStart[device.Name]; ok && now.Sub(last) < LLDPFastStartInterval {
+		h.fastStartMu.Unlock()
+		return
+	}
+	h.lastFastStart[device.Name] = now
+	h.fastStartMu.Unlock()
+
+	if h.stack.GetDebugLevel() >= 2 {
+		fmt.Printf("LLDP: Fast start advertisement for %s\n", device.Name)
+	}
+	h.sendAdvertisement(device)
 }
 
 func lldpChassisIDToString(id layers.LLDPChassisID) string {
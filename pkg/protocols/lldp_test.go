@@ -1,12 +1,16 @@
 package protocols
 
 import (
+	"encoding/binary"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
 )
 
 // TestNewLLDPHandler tests creating a new LLDP handler
@@ -284,6 +288,147 @@ func TestBuildSystemDescriptionTLV(t *testing.T) {
 	}
 }
 
+// TestBuildMaxFrameSizeTLV tests the IEEE 802.3 Maximum Frame Size TLV
+func TestBuildMaxFrameSizeTLV(t *testing.T) {
+	cfg := &config.Config{}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewLLDPHandler(stack)
+
+	tests := []struct {
+		name string
+		mtu  int
+		want uint16
+	}{
+		{"default", 0, 1518},
+		{"jumbo", config.JumboMTU, 9018},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := &config.Device{Name: "test-device", MTU: tt.mtu}
+			tlv := handler.buildMaxFrameSizeTLV(device)
+
+			if len(tlv) != 8 {
+				t.Fatalf("Expected Max Frame Size TLV length 8, got %d", len(tlv))
+			}
+			if tlvType := (tlv[0] >> 1) & 0x7f; tlvType != LLDPTLVTypeOrganizationSpecific {
+				t.Errorf("Expected TLV type %d, got %d", LLDPTLVTypeOrganizationSpecific, tlvType)
+			}
+			if string(tlv[2:5]) != LLDPOUIIEEE8023 || tlv[5] != LLDPIEEE8023SubtypeMaxFrameSize {
+				t.Errorf("Unexpected OUI/subtype % x", tlv[2:6])
+			}
+			if got := binary.BigEndian.Uint16(tlv[6:8]); got != tt.want {
+				t.Errorf("Max frame size = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildLLDPFrame_PowerViaMDI tests that a device with a poe block
+// advertises its class, priority and requested power in the Power via MDI TLV
+func TestBuildLLDPFrame_PowerViaMDI(t *testing.T) {
+	cfg, err := config.LoadYAMLBytes([]byte(`
+devices:
+  - name: ap-lobby
+    mac: "00:11:22:33:44:66"
+    ip: "192.168.1.20"
+    lldp:
+      enabled: true
+    poe:
+      class: 4
+      requested_watts: 23.4
+      priority: high
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewLLDPHandler(stack)
+
+	frame := handler.buildLLDPFrame(&cfg.Devices[0])
+	packet := gopacket.NewPacket(frame, layers.LayerTypeLinkLayerDiscovery, gopacket.Default)
+	info, ok := packet.Layer(layers.LayerTypeLinkLayerDiscoveryInfo).(*layers.LinkLayerDiscoveryInfo)
+	if !ok {
+		t.Fatalf("LLDP frame did not decode: %v", packet.ErrorLayer())
+	}
+	info8023, err := info.Decode8023()
+	if err != nil {
+		t.Fatalf("Decode8023 failed: %v", err)
+	}
+
+	power := info8023.PowerViaMDI
+	if power.PortClassPSE {
+		t.Error("Expected the PD port class")
+	}
+	if power.PSEClass != 5 {
+		t.Errorf("Power class field = %d, want 5 (class 4)", power.PSEClass)
+	}
+	if power.Type != layers.LLDPPowerType(1) || power.Priority != layers.LLDPPowerPriority(2) {
+		t.Errorf("Type/priority = %d/%d, want Type 2 PD (1) and high (2)", power.Type, power.Priority)
+	}
+	if power.Requested != 234 {
+		t.Errorf("Requested power = %d (0.1 W), want 234", power.Requested)
+	}
+
+	// Devices without a poe block advertise no power
+	device := &config.Device{Name: "no-poe", MACAddress: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x67}}
+	if tlv := handler.buildPowerViaMDITLV(device); tlv != nil {
+		t.Errorf("Expected no Power via MDI TLV, got % x", tlv)
+	}
+}
+
+// TestBuildManagementAddressTLV_DualStack tests that a dual-stack device
+// advertises one Management Address TLV per address
+func TestBuildManagementAddressTLV_DualStack(t *testing.T) {
+	cfg, err := config.LoadYAMLBytes([]byte(`
+devices:
+  - name: core-sw1
+    mac: "00:11:22:33:44:55"
+    ips: ["192.168.1.1", "2001:db8::1"]
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	device := &cfg.Devices[0]
+
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewLLDPHandler(stack)
+	handler.sendAdvertisement(device)
+	sent := drainSendQueue(stack)
+	if len(sent) != 1 {
+		t.Fatalf("got %d LLDP frames, want 1", len(sent))
+	}
+	packet := gopacket.NewPacket(sent[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	lldp, ok := packet.Layer(layers.LayerTypeLinkLayerDiscovery).(*layers.LinkLayerDiscovery)
+	if !ok {
+		t.Fatal("frame is not LLDP")
+	}
+
+	addresses := map[byte]net.IP{}
+	for _, tlv := range lldp.Values {
+		if tlv.Type != layers.LLDPTLVMgmtAddress {
+			continue
+		}
+		v := tlv.Value
+		addrLen := int(v[0]) - 1
+		subtype := v[1]
+		if _, dup := addresses[subtype]; dup {
+			t.Errorf("duplicate management address subtype %d", subtype)
+		}
+		addresses[subtype] = net.IP(v[2 : 2+addrLen])
+		rest := v[2+addrLen:]
+		if rest[0] != 2 || binary.BigEndian.Uint32(rest[1:5]) != 1 {
+			t.Errorf("subtype %d: interface numbering % x, want ifIndex 1", subtype, rest[:5])
+		}
+	}
+	if ip := addresses[1]; len(ip) != net.IPv4len || !ip.Equal(net.ParseIP("192.168.1.1")) {
+		t.Errorf("IPv4 management address = %v, want 192.168.1.1", ip)
+	}
+	if ip := addresses[2]; len(ip) != net.IPv6len || !ip.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("IPv6 management address = %v, want 2001:db8::1", ip)
+	}
+}
+
 // TestBuildEndTLV tests building End TLV
 func TestBuildEndTLV(t *testing.T) {
 	cfg := &config.Config{}
@@ -367,6 +512,68 @@ func TestBuildLLDPFrame_DisabledDevice(t *testing.T) {
 	// If this doesn't crash, the test passes
 }
 
+// TestLLDPFastStart tests that a new neighbor triggers an out-of-cycle
+// advertisement when fast_start is enabled, at most once per interval
+func TestLLDPFastStart(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	newStack := func(fastStart bool) *Stack {
+		cfg := &config.Config{Devices: []config.Device{{
+			Name:       "access-switch",
+			MACAddress: deviceMAC,
+			LLDPConfig: &config.LLDPConfig{Enabled: true, FastStart: fastStart},
+		}}}
+		return NewStack(nil, cfg, logging.NewDebugConfig(0))
+	}
+
+	// neighborFrame builds the LLDP frame a neighbor with mac advertises
+	neighborFrame := func(stack *Stack, mac net.HardwareAddr) *Packet {
+		t.Helper()
+		payload := stack.lldpHandler.buildLLDPFrame(&config.Device{Name: "phone-" + mac.String(), MACAddress: mac})
+		dstMAC := net.HardwareAddr(LLDPMulticastMAC)
+		buffer := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{FixLengths: true},
+			&layers.Ethernet{SrcMAC: mac, DstMAC: dstMAC, EthernetType: layers.EthernetType(EtherTypeLLDP)},
+			gopacket.Payload(payload),
+		); err != nil {
+			t.Fatalf("serialize LLDP frame: %v", err)
+		}
+		return &Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())}
+	}
+	phone1 := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+	phone2 := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x02}
+
+	stack := newStack(true)
+	stack.lldpHandler.HandlePacket(neighborFrame(stack, phone1))
+	sent := drainSendQueue(stack)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d frames for a new neighbor, want one fast start advertisement", len(sent))
+	}
+	packet := gopacket.NewPacket(sent[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); !ok || eth.SrcMAC.String() != deviceMAC.String() ||
+		packet.Layer(layers.LayerTypeLinkLayerDiscovery) == nil {
+		t.Errorf("fast start frame is not an LLDP advertisement from the device: %v", packet)
+	}
+
+	// A known neighbor's periodic frames do not trigger it again
+	stack.lldpHandler.HandlePacket(neighborFrame(stack, phone1))
+	if sent := drainSendQueue(stack); len(sent) != 0 {
+		t.Errorf("sent %d frames for a known neighbor, want none", len(sent))
+	}
+
+	// A second new neighbor within the interval is rate limited
+	stack.lldpHandler.HandlePacket(neighborFrame(stack, phone2))
+	if sent := drainSendQueue(stack); len(sent) != 0 {
+		t.Errorf("sent %d frames within the fast start interval, want none", len(sent))
+	}
+
+	// Without fast_start, new neighbors wait for the next interval
+	stack = newStack(false)
+	stack.lldpHandler.HandlePacket(neighborFrame(stack, phone1))
+	if sent := drainSendQueue(stack); len(sent) != 0 {
+		t.Errorf("sent %d frames without fast_start, want none", len(sent))
+	}
+}
+
 // TestLLDPConstants tests LLDP constant values
 func TestLLDPConstants(t *testing.T) {
 	// Check TLV type constants
@@ -455,3 +662,60 @@ func BenchmarkBuildChassisIDTLV(b *testing.B) {
 		handler.buildChassisIDTLV(device)
 	}
 }
+
+// TestInterfaceDescription_SNMPAndLLDP tests that a configured interface
+// description is reported identically in ifAlias, ifDescr and the LLDP Port
+// Description TLV
+func TestInterfaceDescription_SNMPAndLLDP(t *testing.T) {
+	cfg, err := config.LoadYAMLBytes([]byte(`
+devices:
+  - name: access-sw1
+    mac: "00:11:22:33:44:55"
+    ip: "192.168.1.1"
+    interfaces:
+      - name: GigabitEthernet0/1
+        description: "Uplink to core1 Gi1/0/24"
+      - name: GigabitEthernet0/2
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	device := &cfg.Devices[0]
+	const want = "Uplink to core1 Gi1/0/24"
+
+	agent := snmp.NewAgent(device, 0)
+	for _, oid := range []string{snmp.OIDIfAlias + ".1", snmp.OIDIfDescr + ".1"} {
+		value, err := agent.HandleGet(oid)
+		if err != nil || value == nil {
+			t.Fatalf("GET %s failed: %v", oid, err)
+		}
+		if value.Value != want {
+			t.Errorf("GET %s = %v, want %q", oid, value.Value, want)
+		}
+	}
+	if value, _ := agent.HandleGet(snmp.OIDIfDescr + ".2"); value == nil || value.Value != "GigabitEthernet0/2" {
+		t.Errorf("ifDescr.2 = %v, want the interface name for an interface without a description", value)
+	}
+
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	handler := NewLLDPHandler(stack)
+	handler.sendAdvertisement(device)
+	sent := drainSendQueue(stack)
+	if len(sent) != 1 {
+		t.Fatalf("got %d LLDP frames, want 1", len(sent))
+	}
+	packet := gopacket.NewPacket(sent[0].Buffer, layers.LayerTypeEthernet, gopacket.Default)
+	lldp, ok := packet.Layer(layers.LayerTypeLinkLayerDiscovery).(*layers.LinkLayerDiscovery)
+	if !ok {
+		t.Fatal("frame is not LLDP")
+	}
+	var portDescription string
+	for _, tlv := range lldp.Values {
+		if tlv.Type == layers.LLDPTLVPortDescription {
+			portDescription = string(tlv.Value)
+		}
+	}
+	if portDescription != want {
+		t.Errorf("LLDP Port Description = %q, want %q", portDescription, want)
+	}
+}
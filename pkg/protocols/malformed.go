@@ -0,0 +1,205 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+const (
+	// malformedInterval is how often devices with malformed_frames emit; the
+	// configured rate is the number of frames per interval
+	malformedInterval = time.Second
+
+	// malformedIPProtocol is the IP protocol of corrupt ipv4 frames, reserved
+	// for experimentation (RFC 3692), so they are not mistaken for real traffic
+	malformedIPProtocol = 253
+
+	// malformedPort is the UDP/TCP destination port of corrupt frames
+	// (discard)
+	malformedPort = 9
+
+	// malformedTruncatedSize is the length truncated frames are cut to: the
+	// Ethernet minimum without FCS, so the wire does not pad them back out
+	// with zeros and receivers see the packet end early
+	malformedTruncatedSize = 60
+)
+
+// malformedPayload marks corrupt frames in captures
+var malformedPayload = []byte("niac-malformed")
+
+// startMalformedLoop emits the corrupt frames of every device with an
+// enabled malformed_frames block.
+func (s *Stack) startMalformedLoop() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(malformedInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sendMalformedFrames()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// sendMalformedFrames sends one interval's worth of corrupt frames from each
+// device with malformed_frames. Powered-off devices stay silent.
+func (s *Stack) sendMalformedFrames() {
+	cfg := s.currentConfig()
+	if cfg == nil {
+		return
+	}
+	for i := range cfg.Devices {
+		device := &cfg.Devices[i]
+		if device.MalformedFrames == nil || len(device.MACAddress) == 0 || !s.isDeviceUp(device.Name) {
+			continue
+		}
+		frame, err := buildMalformedFrame(device, device.MalformedFrames)
+		if err != nil {
+			if s.debugConfig.GetGlobal() >= 2 {
+				fmt.Printf("Cannot build malformed frame for %s: %v\n", device.Name, err)
+			}
+			continue
+		}
+		// Counted by sendPacket, as Send drops frames on a shut interface
+		// or a full queue
+		for n := 0; n < device.MalformedFrames.Rate; n++ {
+			s.Send(&Packet{Buffer: frame, Device: device, Malformed: true})
+		}
+		if s.debugConfig.GetGlobal() >= 3 {
+			fmt.Printf("Sent %d malformed %s frames (%s) from %s\n", device.MalformedFrames.Rate,
+				device.MalformedFrames.Protocol, device.MalformedFrames.Corruption, device.Name)
+		}
+	}
+}
+
+// buildMalformedFrame returns a broadcast frame from device of the configured
+// protocol, corrupted as configured. Only the configured fault is present:
+// checksums other than a corrupted one stay valid.
+func buildMalformedFrame(device *config.Device, malformed *config.MalformedFramesConfig) ([]byte, error) {
+	var srcIP net.IP
+	for _, ip := range device.IPAddresses {
+		if srcIP = ip.To4(); srcIP != nil {
+			break
+		}
+	}
+	if srcIP == nil {
+		return nil, fmt.Errorf("no IPv4 address")
+	}
+
+	eth := &layers.Ethernet{SrcMAC: device.MACAddress, DstMAC: layers.EthernetBroadcast, EthernetType: layers.EthernetTypeIPv4}
+	ip := &layers.IPv4{Version: 4, TTL: device.TTL(), SrcIP: srcIP, DstIP: net.IPv4bcast.To4()}
+	frameLayers := []gopacket.SerializableLayer{eth, ip}
+
+	switch malformed.Protocol {
+	case config.MalformedProtocolIPv4:
+		ip.Protocol = malformedIPProtocol
+		if malformed.Corruption == config.MalformedBadOptionLength {
+			// Router Alert, whose length is corrupted below
+			ip.Options = []layers.IPv4Option{{OptionType: 0x94, OptionLength: 4, OptionData: []byte{0, 0}}}
+		}
+	case config.MalformedProtocolICMP:
+		ip.Protocol = layers.IPProtocolICMPv4
+		frameLayers = append(frameLayers, &layers.ICMPv4{
+			TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: 0x4e49, Seq: 1})
+	case config.MalformedProtocolUDP:
+		ip.Protocol = layers.IPProtocolUDP
+		udp := &layers.UDP{SrcPort: 49152, DstPort: malformedPort}
+		_ = udp.SetNetworkLayerForChecksum(ip)
+		frameLayers = append(frameLayers, udp)
+	case config.MalformedProtocolTCP:
+		ip.Protocol = layers.IPProtocolTCP
+		mss := make([]byte, 2)
+		binary.BigEndian.PutUint16(mss, 1460)
+		tcp := &layers.TCP{SrcPort: 49152, DstPort: malformedPort, Seq: 1, SYN: true, Window: 65535,
+			Options: []layers.TCPOption{{OptionType: layers.TCPOptionKindMSS, OptionLength: 4, OptionData: mss}}}
+		if malformed.Corruption == config.MalformedTruncated {
+			// A typical SYN's options, so the cut falls inside the header
+			tcp.Options = append(tcp.Options,
+				layers.TCPOption{OptionType: layers.TCPOptionKindSACKPermitted, OptionLength: 2},
+				layers.TCPOption{OptionType: layers.TCPOptionKindTimestamps, OptionLength: 10, OptionData: make([]byte, 8)},
+				layers.TCPOption{OptionType: layers.TCPOptionKindNop},
+				layers.TCPOption{OptionType: layers.TCPOptionKindWindowScale, OptionLength: 3, OptionData: []byte{7}})
+		}
+		_ = tcp.SetNetworkLayerForChecksum(ip)
+		frameLayers = append(frameLayers, tcp)
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", malformed.Protocol)
+	}
+	if malformed.Protocol != config.MalformedProtocolTCP {
+		payload := malformedPayload
+		if malformed.Corruption == config.MalformedTruncated {
+			// Long enough that the packet is cut short of its claimed length
+			payload = append(bytes.Clone(payload), make([]byte, malformedTruncatedSize)...)
+		}
+		frameLayers = append(frameLayers, gopacket.Payload(payload))
+	}
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, frameLayers...); err != nil {
+		return nil, err
+	}
+	frame := buffer.Bytes()
+	ipStart := 14 // Untagged Ethernet header
+	l4Start := ipStart + int(frame[ipStart]&0x0f)*4
+	l4End := ipStart + int(binary.BigEndian.Uint16(frame[ipStart+2:]))
+
+	switch malformed.Corruption {
+	case config.MalformedBadChecksum:
+		offset := map[string]int{
+			config.MalformedProtocolIPv4: ipStart + 10,
+			config.MalformedProtocolICMP: l4Start + 2,
+			config.MalformedProtocolUDP:  l4Start + 6,
+			config.MalformedProtocolTCP:  l4Start + 16,
+		}[malformed.Protocol]
+		// Off by one, skipping 0: a UDP checksum of 0 means none
+		checksum := binary.BigEndian.Uint16(frame[offset:]) + 1
+		if checksum == 0 {
+			checksum = 1
+		}
+		binary.BigEndian.PutUint16(frame[offset:], checksum)
+	case config.MalformedTruncated:
+		// The IP total length (and UDP length, or TCP data offset) still
+		// claim the full packet
+		frame = frame[:malformedTruncatedSize]
+	case config.MalformedBadOptionLength:
+		switch malformed.Protocol {
+		case config.MalformedProtocolIPv4:
+			frame[ipStart+21] = 0xff
+			binary.BigEndian.PutUint16(frame[ipStart+10:], 0)
+			binary.BigEndian.PutUint16(frame[ipStart+10:], CalculateIPChecksum(frame[ipStart:l4Start]))
+		case config.MalformedProtocolTCP:
+			frame[l4Start+21] = 0xff
+			binary.BigEndian.PutUint16(frame[l4Start+16:], 0)
+			binary.BigEndian.PutUint16(frame[l4Start+16:], tcpChecksumV4(srcIP, ip.DstIP, frame[l4Start:l4End]))
+		default:
+			return nil, fmt.Errorf("%s has no options", malformed.Protocol)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported corruption %q", malformed.Corruption)
+	}
+	return frame, nil
+}
+
+// tcpChecksumV4 returns the checksum of an even-length TCP segment over
+// IPv4, computed with the segment's checksum field zeroed
+func tcpChecksumV4(srcIP, dstIP net.IP, segment []byte) uint16 {
+	data := make([]byte, 12, 12+len(segment))
+	copy(data[0:4], srcIP.To4())
+	copy(data[4:8], dstIP.To4())
+	data[9] = byte(layers.IPProtocolTCP)
+	binary.BigEndian.PutUint16(data[10:12], uint16(len(segment)))
+	return CalculateIPChecksum(append(data, segment...))
+}
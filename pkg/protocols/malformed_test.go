@@ -0,0 +1,173 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+func newMalformedStack(malformed *config.MalformedFramesConfig) *Stack {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:            "fuzzer",
+		MACAddress:      net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x90},
+		IPAddresses:     []net.IP{net.ParseIP("192.168.1.90")},
+		MalformedFrames: malformed,
+	}}}
+	return NewStack(nil, cfg, logging.NewDebugConfig(0))
+}
+
+// TestMalformedFrames_BadChecksum tests that an active malformed_frames mode
+// sends UDP frames whose checksum is wrong and counts them
+func TestMalformedFrames_BadChecksum(t *testing.T) {
+	stack := newMalformedStack(&config.MalformedFramesConfig{
+		Protocol: config.MalformedProtocolUDP, Corruption: config.MalformedBadChecksum, Rate: 2})
+
+	stack.sendMalformedFrames()
+	if got := stack.GetStats().MalformedFramesSent; got != 0 {
+		t.Errorf("MalformedFramesSent = %d before the frames left the queue, want 0", got)
+	}
+	sent := sendQueuedPackets(stack)
+	if len(sent) != 2 {
+		t.Fatalf("Sent %d frames, want 2", len(sent))
+	}
+	for i, frame := range sent {
+		packet := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+		ip, _ := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+		udp, _ := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if ip == nil || udp == nil {
+			t.Fatalf("Frame %d is not IPv4/UDP: %v", i+1, packet)
+		}
+
+		// Reserialize the same datagram to get its correct checksum
+		// (serializing overwrites udp.Checksum)
+		got := udp.Checksum
+		_ = udp.SetNetworkLayerForChecksum(ip)
+		buffer := gopacket.NewSerializeBuffer()
+		opts := gopacket.SerializeOptions{ComputeChecksums: true}
+		if err := gopacket.SerializeLayers(buffer, opts, udp, gopacket.Payload(udp.Payload)); err != nil {
+			t.Fatalf("Reserialize frame %d: %v", i+1, err)
+		}
+		want := binary.BigEndian.Uint16(buffer.Bytes()[6:8])
+		if got == want || got == 0 {
+			t.Errorf("Frame %d UDP checksum %#04x, want a wrong nonzero checksum (correct is %#04x)", i+1, got, want)
+		}
+		if ipHeader := frame[14 : 14+int(ip.IHL)*4]; CalculateIPChecksum(ipHeader) != 0 {
+			t.Errorf("Frame %d IP checksum is also wrong", i+1)
+		}
+	}
+
+	if got := stack.GetStats().MalformedFramesSent; got != 2 {
+		t.Errorf("MalformedFramesSent = %d, want 2", got)
+	}
+
+	// Frames dropped on a full send queue are not counted
+	for len(stack.sendQueue) < cap(stack.sendQueue) {
+		stack.sendQueue <- &Packet{Buffer: []byte{0}}
+	}
+	stack.sendMalformedFrames()
+	drainSendQueue(stack)
+	if got := stack.GetStats().MalformedFramesSent; got != 2 {
+		t.Errorf("MalformedFramesSent = %d after frames were dropped, want 2", got)
+	}
+}
+
+// TestMalformedFrames_RunMarker tests that the run marker leaves malformed
+// frames as built: the DSCP rewrite would repair a bad IPv4 header checksum
+// and the trailer would extend the packet
+func TestMalformedFrames_RunMarker(t *testing.T) {
+	stack := newMalformedStack(&config.MalformedFramesConfig{
+		Protocol: config.MalformedProtocolIPv4, Corruption: config.MalformedBadChecksum, Rate: 1})
+	stack.currentConfig().RunMarker = &config.RunMarkerConfig{RunID: "lab-a", DSCP: 10}
+
+	stack.sendMalformedFrames()
+	sent := sendQueuedPackets(stack)
+	if len(sent) != 1 {
+		t.Fatalf("Sent %d frames, want 1", len(sent))
+	}
+	frame := sent[0]
+	if ihl := int(frame[14]&0x0f) * 4; CalculateIPChecksum(frame[14:14+ihl]) == 0 {
+		t.Error("IPv4 header checksum was repaired")
+	}
+	if dscp := frame[15] >> 2; dscp != 0 {
+		t.Errorf("DSCP = %d, want the frame unmarked", dscp)
+	}
+	if bytes.Contains(frame, []byte("NIAC")) {
+		t.Errorf("Frame %x carries the run marker trailer", frame)
+	}
+}
+
+// TestMalformedFrames_Off tests that nothing is sent without malformed_frames
+func TestMalformedFrames_Off(t *testing.T) {
+	stack := newMalformedStack(nil)
+
+	stack.sendMalformedFrames()
+	if sent := drainSendQueue(stack); len(sent) != 0 {
+		t.Errorf("Sent %d frames, want none", len(sent))
+	}
+	if got := stack.GetStats().MalformedFramesSent; got != 0 {
+		t.Errorf("MalformedFramesSent = %d, want 0", got)
+	}
+}
+
+// TestBuildMalformedFrame_Corruptions tests that truncated frames are as long
+// as the Ethernet minimum but shorter than their headers claim, and that bad
+// option lengths overrun the header
+func TestBuildMalformedFrame_Corruptions(t *testing.T) {
+	device := &newMalformedStack(nil).currentConfig().Devices[0]
+	tests := []struct {
+		protocol   string
+		corruption string
+		wantLen    int // 0 to skip the length check
+		optionAt   int // Offset of the corrupted option length, 0 for none
+	}{
+		{config.MalformedProtocolIPv4, config.MalformedTruncated, 60, 0},
+		{config.MalformedProtocolICMP, config.MalformedTruncated, 60, 0},
+		{config.MalformedProtocolUDP, config.MalformedTruncated, 60, 0},
+		{config.MalformedProtocolTCP, config.MalformedTruncated, 60, 0},
+		{config.MalformedProtocolIPv4, config.MalformedBadOptionLength, 0, 14 + 21},
+		{config.MalformedProtocolTCP, config.MalformedBadOptionLength, 0, 14 + 20 + 21},
+		{config.MalformedProtocolIPv4, config.MalformedBadChecksum, 0, 0},
+		{config.MalformedProtocolICMP, config.MalformedBadChecksum, 0, 0},
+		{config.MalformedProtocolTCP, config.MalformedBadChecksum, 0, 0},
+	}
+	for _, tt := range tests {
+		frame, err := buildMalformedFrame(device, &config.MalformedFramesConfig{Protocol: tt.protocol, Corruption: tt.corruption, Rate: 1})
+		if err != nil {
+			t.Errorf("%s/%s: %v", tt.protocol, tt.corruption, err)
+			continue
+		}
+		if tt.wantLen != 0 && len(frame) != tt.wantLen {
+			t.Errorf("%s/%s: frame is %d bytes, want %d", tt.protocol, tt.corruption, len(frame), tt.wantLen)
+		}
+		if tt.corruption == config.MalformedTruncated {
+			if claimed := 14 + int(binary.BigEndian.Uint16(frame[16:18])); claimed <= len(frame) {
+				t.Errorf("%s/%s: IP total length claims %d bytes of a %d-byte frame, want more", tt.protocol, tt.corruption, claimed, len(frame))
+			}
+			switch tt.protocol {
+			case config.MalformedProtocolUDP:
+				if claimed := 34 + int(binary.BigEndian.Uint16(frame[38:40])); claimed <= len(frame) {
+					t.Errorf("%s/%s: UDP length claims %d bytes of a %d-byte frame, want more", tt.protocol, tt.corruption, claimed, len(frame))
+				}
+			case config.MalformedProtocolTCP:
+				if header := 34 + int(frame[46]>>4)*4; header <= len(frame) {
+					t.Errorf("%s/%s: TCP header ends at %d in a %d-byte frame, want it cut", tt.protocol, tt.corruption, header, len(frame))
+				}
+			}
+		}
+		if tt.optionAt != 0 && frame[tt.optionAt] != 0xff {
+			t.Errorf("%s/%s: option length %d, want 255", tt.protocol, tt.corruption, frame[tt.optionAt])
+		}
+		if tt.corruption == config.MalformedBadOptionLength {
+			ihl := int(frame[14]&0x0f) * 4
+			if CalculateIPChecksum(frame[14:14+ihl]) != 0 {
+				t.Errorf("%s/%s: IP checksum is wrong too", tt.protocol, tt.corruption)
+			}
+		}
+	}
+}
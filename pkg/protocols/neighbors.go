@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
 )
 
 const (
@@ -28,14 +31,22 @@ type NeighborRecord struct {
 	TTL               time.Duration
 }
 
+// neighborSweepInterval is how often expired neighbors are evicted in the background.
+const neighborSweepInterval = 10 * time.Second
+
 type neighborTable struct {
-	mu      sync.RWMutex
-	entries map[string]map[string]*NeighborRecord
+	mu         sync.RWMutex
+	entries    map[string]map[string]*NeighborRecord
+	size       int
+	maxEntries int           // 0 = unbounded
+	version    atomic.Uint64 // Bumped when neighbors appear, change or expire (not on refresh)
+	evicted    atomic.Uint64 // Neighbors dropped to respect maxEntries
 }
 
 func newNeighborTable() *neighborTable {
 	return &neighborTable{
-		entries: make(map[string]map[string]*NeighborRecord),
+		entries:    make(map[string]map[string]*NeighborRecord),
+		maxEntries: config.DefaultMaxNeighbors,
 	}
 }
 
@@ -43,11 +54,25 @@ func (t *neighborTable) reset() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.entries = make(map[string]map[string]*NeighborRecord)
+	t.size = 0
+	t.version.Add(1)
 }
 
-func (t *neighborTable) upsert(entry NeighborRecord) {
+// setMaxEntries changes the table cap, evicting least recently seen
+// neighbors if the table is already larger.
+func (t *neighborTable) setMaxEntries(max int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxEntries = max
+	for t.maxEntries > 0 && t.size > t.maxEntries {
+		t.evictOldestLocked()
+	}
+}
+
+// upsert adds or refreshes a neighbor and reports whether it was new.
+func (t *neighborTable) upsert(entry NeighborRecord) bool {
 	if entry.LocalDevice == "" || entry.RemoteChassisID == "" {
-		return
+		return false
 	}
 
 	if entry.TTL <= 0 {
@@ -62,12 +87,59 @@ func (t *neighborTable) upsert(entry NeighborRecord) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	existing, ok := t.entries[entry.LocalDevice][key]
+	if !ok {
+		// Make room before inserting so a noisy segment cannot grow the table
+		for t.maxEntries > 0 && t.size >= t.maxEntries {
+			t.evictOldestLocked()
+		}
+		t.size++
+	}
+
 	if _, ok := t.entries[entry.LocalDevice]; !ok {
 		t.entries[entry.LocalDevice] = make(map[string]*NeighborRecord)
 	}
 
+	if !ok || existing.RemoteDevice != entry.RemoteDevice {
+		t.version.Add(1)
+	}
+
 	clone := entry
 	t.entries[entry.LocalDevice][key] = &clone
+	return !ok
+}
+
+// evictOldestLocked removes the least recently seen neighbor. Callers must hold t.mu.
+func (t *neighborTable) evictOldestLocked() {
+	var oldestLocal, oldestKey string
+	var oldest time.Time
+	for local, neighbors := range t.entries {
+		for key, record := range neighbors {
+			if oldestKey == "" || record.LastSeen.Before(oldest) {
+				oldestLocal, oldestKey, oldest = local, key, record.LastSeen
+			}
+		}
+	}
+	if oldestKey == "" {
+		return
+	}
+	t.deleteLocked(oldestLocal, oldestKey)
+	t.evicted.Add(1)
+}
+
+// deleteLocked removes one neighbor and prunes an empty per-device map.
+// Callers must hold t.mu.
+func (t *neighborTable) deleteLocked(local, key string) {
+	neighbors := t.entries[local]
+	if _, ok := neighbors[key]; !ok {
+		return
+	}
+	delete(neighbors, key)
+	if len(neighbors) == 0 {
+		delete(t.entries, local)
+	}
+	t.size--
+	t.version.Add(1)
 }
 
 func (t *neighborTable) cleanupExpired() {
@@ -79,28 +151,38 @@ func (t *neighborTable) cleanupExpired() {
 	for local, neighbors := range t.entries {
 		for key, record := range neighbors {
 			if now.After(record.ExpireAt) {
-				delete(neighbors, key)
+				t.deleteLocked(local, key)
 			}
 		}
-		if len(neighbors) == 0 {
-			delete(t.entries, local)
-		}
 	}
 }
 
+// list returns the neighbors whose hold time has not elapsed, even if the
+// background sweep has not removed the expired ones yet.
 func (t *neighborTable) list() []NeighborRecord {
+	now := time.Now().UTC()
+
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
 	var out []NeighborRecord
 	for _, neighbors := range t.entries {
 		for _, record := range neighbors {
+			if now.After(record.ExpireAt) {
+				continue
+			}
 			out = append(out, *record)
 		}
 	}
 	return out
 }
 
+func (t *neighborTable) len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size
+}
+
 func neighborKey(protocol, chassis, port string) string {
 	return fmt.Sprintf("%s|%s|%s", protocol, chassis, port)
 }
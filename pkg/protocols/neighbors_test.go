@@ -0,0 +1,112 @@
+package protocols
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+func TestNeighborTable_ExpiresAfterHoldTime(t *testing.T) {
+	table := newNeighborTable()
+	table.upsert(NeighborRecord{
+		Protocol:        ProtocolLLDP,
+		LocalDevice:     "sw1",
+		RemoteChassisID: "00:aa:bb:cc:dd:ee",
+		RemotePort:      "Gi0/1",
+		TTL:             20 * time.Millisecond,
+	})
+	if got := len(table.list()); got != 1 {
+		t.Fatalf("expected 1 neighbor before hold time, got %d", got)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if got := len(table.list()); got != 0 {
+		t.Fatalf("expected expired neighbor hidden from list, got %d", got)
+	}
+	if got := table.len(); got != 1 {
+		t.Fatalf("expected expired neighbor to remain until swept, got %d", got)
+	}
+
+	before := table.version.Load()
+	table.cleanupExpired()
+	if got := table.len(); got != 0 {
+		t.Fatalf("expected sweep to evict expired neighbor, got %d", got)
+	}
+	if table.version.Load() == before {
+		t.Fatal("expected version bump when a neighbor expires")
+	}
+	if got := table.evicted.Load(); got != 0 {
+		t.Fatalf("expiry must not count as capacity eviction, got %d", got)
+	}
+}
+
+func TestNeighborTable_RespectsMaxSize(t *testing.T) {
+	table := newNeighborTable()
+	table.setMaxEntries(3)
+
+	for i := 0; i < 5; i++ {
+		table.upsert(NeighborRecord{
+			Protocol:        ProtocolCDP,
+			LocalDevice:     fmt.Sprintf("sw%d", i%2),
+			RemoteChassisID: fmt.Sprintf("remote-%d", i),
+			RemotePort:      "Gi0/1",
+		})
+		// Distinct LastSeen values keep the eviction order deterministic
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := table.len(); got != 3 {
+		t.Fatalf("expected table capped at 3, got %d", got)
+	}
+	if got := table.evicted.Load(); got != 2 {
+		t.Fatalf("expected 2 evictions, got %d", got)
+	}
+	remaining := make(map[string]bool)
+	for _, n := range table.list() {
+		remaining[n.RemoteChassisID] = true
+	}
+	for _, id := range []string{"remote-2", "remote-3", "remote-4"} {
+		if !remaining[id] {
+			t.Errorf("expected most recently seen neighbor %s to be kept, got %v", id, remaining)
+		}
+	}
+
+	// Refreshing an existing neighbor must not evict anything
+	table.upsert(NeighborRecord{Protocol: ProtocolCDP, LocalDevice: "sw0", RemoteChassisID: "remote-2", RemotePort: "Gi0/1"})
+	if got := table.evicted.Load(); got != 2 {
+		t.Fatalf("expected refresh to leave evictions at 2, got %d", got)
+	}
+
+	// Shrinking the cap trims the table immediately
+	table.setMaxEntries(1)
+	if got := table.len(); got != 1 {
+		t.Fatalf("expected table trimmed to 1, got %d", got)
+	}
+	if got := table.list(); len(got) != 1 || got[0].RemoteChassisID != "remote-2" {
+		t.Fatalf("expected refreshed neighbor remote-2 to survive, got %+v", got)
+	}
+}
+
+func TestStack_NeighborTableSizeFromConfig(t *testing.T) {
+	cfg := &config.Config{DiscoveryProtocols: &config.DiscoveryProtocols{MaxNeighbors: 2}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	for i := 0; i < 4; i++ {
+		stack.recordNeighbor(NeighborRecord{
+			Protocol:        ProtocolLLDP,
+			LocalDevice:     "sw1",
+			RemoteChassisID: fmt.Sprintf("chassis-%d", i),
+		})
+	}
+
+	stats := stack.GetStats()
+	if stats.Neighbors != 2 {
+		t.Fatalf("expected neighbor gauge 2, got %d", stats.Neighbors)
+	}
+	if stats.NeighborsEvicted != 2 {
+		t.Fatalf("expected 2 evicted neighbors, got %d", stats.NeighborsEvicted)
+	}
+}
@@ -121,6 +121,7 @@ func (h *NetBIOSHandler) handleNameQuery(pkt *Packet, packet gopacket.Packet, tr
 		if h.debugLevel >= 2 {
 			fmt.Printf("NetBIOS NS: Failed to decode name sn=%d\n", pkt.SerialNumber)
 		}
+		h.stack.recordErrorPacket(pkt, "netbios", fmt.Errorf("failed to decode name"))
 		return
 	}
 
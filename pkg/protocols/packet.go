@@ -20,6 +20,9 @@ type Packet struct {
 	LoopTime     time.Duration // For periodic packets
 	Device       interface{}   // Associated device
 	VLAN         int           // -1 if no VLAN
+	Reassembled  bool          // Rebuilt from IP fragments
+	Interface    string        // Capture interface it arrived on ("" if unknown)
+	Malformed    bool          // Corrupt frame from malformed_frames: sent without the run marker, counted when sent
 }
 
 // Constants for packet parsing
@@ -56,6 +59,7 @@ func (p *Packet) Clone() *Packet {
 		LoopTime:     p.LoopTime,
 		Device:       p.Device,
 		VLAN:         p.VLAN,
+		Interface:    p.Interface,
 	}
 	copy(clone.Buffer, p.Buffer)
 	return clone
@@ -0,0 +1,163 @@
+package protocols
+
+import (
+	"fmt"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// SetDevicePower powers a simulated device on or off by name. A powered-off
+// device is removed from the device table so it stops answering ARP, ICMP,
+// SNMP and the other protocol handlers, and it is skipped for discovery
+// advertisements. Powering it back on restores its MAC and IP entries.
+func (s *Stack) SetDevicePower(name string, on bool) error {
+	device := s.findDevice(name)
+	if device == nil {
+		return fmt.Errorf("device %q not found", name)
+	}
+
+	s.powerMu.Lock()
+	defer s.powerMu.Unlock()
+
+	if s.poweredOff == nil {
+		s.poweredOff = make(map[string]bool)
+	}
+	wasOff := s.poweredOff[name]
+	switch {
+	case !on && !wasOff:
+		s.poweredOff[name] = true
+		s.devices.Remove(device)
+	case on && wasOff:
+		delete(s.poweredOff, name)
+		// A device still booting joins the table when its boot delay ends
+		if s.booting[name] == nil && !s.disabled[name] {
+			s.addDeviceToTable(device)
+		}
+	}
+
+	if s.debugConfig.GetGlobal() >= 1 {
+		state := "off"
+		if on {
+			state = "on"
+		}
+		fmt.Printf("Device %s powered %s\n", name, state)
+	}
+	return nil
+}
+
+// IsDevicePowered reports whether the named device is currently powered on.
+func (s *Stack) IsDevicePowered(name string) bool {
+	s.powerMu.RLock()
+	defer s.powerMu.RUnlock()
+	return !s.poweredOff[name]
+}
+
+// PoweredOffCount returns how many devices are currently powered off.
+func (s *Stack) PoweredOffCount() int {
+	s.powerMu.RLock()
+	defer s.powerMu.RUnlock()
+	return len(s.poweredOff)
+}
+
+// SetDeviceEnabled enables or disables a simulated device by name. Like
+// powering it off, disabling removes the device from the device table so it
+// stops answering ARP, ICMP, SNMP and the other protocol handlers; enabling
+// restores its entries at once unless it is also powered off or booting.
+// The state is kept in the device's Enabled field and survives reloads.
+func (s *Stack) SetDeviceEnabled(name string, enabled bool) error {
+	device := s.findDevice(name)
+	if device == nil {
+		return fmt.Errorf("device %q not found", name)
+	}
+
+	s.powerMu.Lock()
+	defer s.powerMu.Unlock()
+
+	if s.disabled == nil {
+		s.disabled = make(map[string]bool)
+	}
+	wasDisabled := s.disabled[name]
+	switch {
+	case !enabled && !wasDisabled:
+		s.disabled[name] = true
+		s.devices.Remove(device)
+	case enabled && wasDisabled:
+		delete(s.disabled, name)
+		if !s.poweredOff[name] && s.booting[name] == nil {
+			s.addDeviceToTable(device)
+		}
+	}
+	device.Enabled = enabled
+
+	if s.debugConfig.GetGlobal() >= 1 {
+		state := "disabled"
+		if enabled {
+			state = "enabled"
+		}
+		fmt.Printf("Device %s %s\n", name, state)
+	}
+	return nil
+}
+
+// IsDeviceEnabled reports whether the named device is currently enabled.
+func (s *Stack) IsDeviceEnabled(name string) bool {
+	s.powerMu.RLock()
+	defer s.powerMu.RUnlock()
+	return !s.disabled[name]
+}
+
+// syncDeviceEnabled sets a newly configured device's Enabled field from the
+// runtime state, which outlives config reloads.
+func (s *Stack) syncDeviceEnabled(device *config.Device) {
+	s.powerMu.Lock()
+	defer s.powerMu.Unlock()
+	device.Enabled = !s.disabled[device.Name]
+}
+
+// RebootDevice simulates a reboot of the named device's SNMP agent: sysUpTime
+// restarts from zero, counters clear and a coldStart trap is sent.
+func (s *Stack) RebootDevice(name string) error {
+	device := s.findDevice(name)
+	if device == nil {
+		return fmt.Errorf("device %q not found", name)
+	}
+	agent := s.getSNMPAgent(device)
+	if agent == nil {
+		return fmt.Errorf("device %q has no SNMP agent", name)
+	}
+	err := agent.Reboot()
+
+	// Logical devices in SNMPv3 contexts restart with their chassis
+	if engine := s.getSNMPV3Engine(device); engine != nil {
+		for context, contextAgent := range engine.contexts {
+			if context != "" {
+				_ = contextAgent.Reboot()
+			}
+		}
+	}
+	return err
+}
+
+// findDevice returns the configured device with the given name.
+func (s *Stack) findDevice(name string) *config.Device {
+	cfg := s.currentConfig()
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Devices {
+		if cfg.Devices[i].Name == name {
+			return &cfg.Devices[i]
+		}
+	}
+	return nil
+}
+
+// addDeviceToTable indexes a device by MAC and IP addresses.
+func (s *Stack) addDeviceToTable(device *config.Device) {
+	if len(device.MACAddress) > 0 {
+		s.devices.AddByMAC(device.MACAddress, device)
+	}
+	for _, ip := range device.IPAddresses {
+		s.devices.AddByIP(ip, device)
+	}
+}
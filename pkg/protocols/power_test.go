@@ -0,0 +1,118 @@
+package protocols
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// TestSetDeviceEnabled tests that a disabled device stops answering SNMP GET
+// and ARP while its sibling keeps responding, and answers again as soon as it
+// is re-enabled
+func TestSetDeviceEnabled(t *testing.T) {
+	cfg := &config.Config{Devices: []config.Device{
+		{
+			Name:        "router1",
+			Enabled:     true,
+			MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+			IPAddresses: []net.IP{net.ParseIP("192.168.1.1")},
+			SNMPConfig:  config.SNMPConfig{Community: "public", SysName: "router1"},
+		},
+		{
+			Name:        "router2",
+			Enabled:     true,
+			MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x02},
+			IPAddresses: []net.IP{net.ParseIP("192.168.1.2")},
+			SNMPConfig:  config.SNMPConfig{Community: "public", SysName: "router2"},
+		},
+	}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	request := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.GetRequest,
+		RequestID: 1,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+	}
+	payload, err := request.MarshalMsg()
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	// snmpGet sends a GET for sysName to the device and returns the name in
+	// the response, or "" if there is none
+	snmpGet := func(device *config.Device) string {
+		t.Helper()
+		buffer := gopacket.NewSerializeBuffer()
+		udp := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+		ip := &layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolUDP,
+			SrcIP: net.ParseIP("192.168.1.50").To4(), DstIP: device.IPAddresses[0].To4()}
+		_ = udp.SetNetworkLayerForChecksum(ip)
+		if err := gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+			&layers.Ethernet{SrcMAC: net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x50}, DstMAC: device.MACAddress, EthernetType: layers.EthernetTypeIPv4},
+			ip, udp, gopacket.Payload(payload),
+		); err != nil {
+			t.Fatalf("serialize GET: %v", err)
+		}
+		stack.decodePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+
+		name := ""
+		for _, pkt := range drainSendQueue(stack) {
+			packet := gopacket.NewPacket(pkt.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+			reply, _ := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			if reply == nil || reply.SrcPort != layers.UDPPort(UDPPortSNMP) {
+				continue
+			}
+			decoded, err := (&gosnmp.GoSNMP{Version: gosnmp.Version2c}).SnmpDecodePacket(reply.Payload)
+			if err != nil || len(decoded.Variables) != 1 {
+				t.Fatalf("bad response from %s: %v", device.Name, err)
+			}
+			if value, ok := decoded.Variables[0].Value.([]byte); ok {
+				name = string(value)
+			}
+		}
+		return name
+	}
+	router1, router2 := &cfg.Devices[0], &cfg.Devices[1]
+
+	if got := snmpGet(router1); got != "router1" {
+		t.Fatalf("router1 answered %q before disabling, want router1", got)
+	}
+
+	if err := stack.SetDeviceEnabled("router1", false); err != nil {
+		t.Fatalf("disable router1: %v", err)
+	}
+	if stack.IsDeviceEnabled("router1") || router1.Enabled {
+		t.Error("router1 still enabled")
+	}
+	if got := snmpGet(router1); got != "" {
+		t.Errorf("disabled router1 answered SNMP GET with %q", got)
+	}
+	if got := snmpGet(router2); got != "router2" {
+		t.Errorf("router2 answered %q while router1 is disabled, want router2", got)
+	}
+	stack.decodePacket(buildARPRequestPacket(t, "192.168.1.1"))
+	if sent := drainSendQueue(stack); len(sent) != 0 {
+		t.Errorf("disabled router1 sent %d ARP replies", len(sent))
+	}
+
+	if err := stack.SetDeviceEnabled("router1", true); err != nil {
+		t.Fatalf("enable router1: %v", err)
+	}
+	if !stack.IsDeviceEnabled("router1") || !router1.Enabled {
+		t.Error("router1 not enabled")
+	}
+	if got := snmpGet(router1); got != "router1" {
+		t.Errorf("re-enabled router1 answered %q, want router1", got)
+	}
+
+	if err := stack.SetDeviceEnabled("missing", false); err == nil {
+		t.Error("expected an error for an unknown device")
+	}
+}
@@ -0,0 +1,96 @@
+package protocols
+
+import (
+	"encoding/binary"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// SetRunID sets the run identifier whose marker is appended to every
+// generated frame, overriding run_marker.run_id. Call before Start.
+func (s *Stack) SetRunID(runID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runID = runID
+}
+
+// runMarker returns the Ethernet trailer and DSCP applied to generated frames
+func (s *Stack) runMarker() ([]byte, int) {
+	s.mu.Lock()
+	runID := s.runID
+	s.mu.Unlock()
+
+	marker := s.currentConfig().RunMarker
+	dscp := 0
+	if marker != nil {
+		dscp = marker.DSCP
+		if runID == "" {
+			runID = marker.RunID
+		}
+	}
+	return config.RunMarkerTrailer(runID), dscp
+}
+
+// markFrame returns frame tagged with the run marker: the configured DSCP on
+// IPv4/IPv6 packets and the run ID trailer after the frame's payload, which
+// receivers ignore (IP, 802.3 and LLDP frames all carry their own length).
+// The trailer is left off frames it would push past the device MTU. frame
+// itself is never modified, so looping packets are marked once per send.
+func (s *Stack) markFrame(frame []byte, device *config.Device) []byte {
+	trailer, dscp := s.runMarker()
+	if len(trailer) == 0 && dscp == 0 {
+		return frame
+	}
+
+	marked := make([]byte, len(frame), len(frame)+len(trailer))
+	copy(marked, frame)
+
+	if len(marked) < SizeOfMac*2+2 {
+		return marked
+	}
+	headerLen := SizeOfMac*2 + 2
+	etherType := binary.BigEndian.Uint16(marked[SizeOfMac*2:])
+	if etherType == EtherTypeVLAN && len(marked) >= headerLen+4 {
+		headerLen += 4
+		etherType = binary.BigEndian.Uint16(marked[SizeOfMac*2+4:])
+	}
+
+	if dscp != 0 {
+		setDSCP(marked[headerLen:], etherType, dscp)
+	}
+
+	mtu := config.DefaultMTU
+	if device != nil {
+		mtu = device.LinkMTU()
+	}
+	if len(trailer) > 0 && len(marked)-headerLen+len(trailer) <= mtu {
+		marked = append(marked, trailer...)
+	}
+	return marked
+}
+
+// setDSCP sets the DSCP bits of the IPv4 or IPv6 header at the start of
+// packet, keeping the ECN bits. The IPv4 header checksum is recomputed.
+func setDSCP(packet []byte, etherType uint16, dscp int) {
+	switch etherType {
+	case EtherTypeIP:
+		if len(packet) < 20 {
+			return
+		}
+		headerLen := int(packet[0]&0x0f) * 4
+		if headerLen < 20 || len(packet) < headerLen {
+			return
+		}
+		packet[1] = byte(dscp)<<2 | packet[1]&0x03
+		packet[10], packet[11] = 0, 0
+		binary.BigEndian.PutUint16(packet[10:], CalculateIPChecksum(packet[:headerLen]))
+	case EtherTypeIPv6:
+		if len(packet) < 40 {
+			return
+		}
+		ecn := (packet[1] >> 4) & 0x03
+		trafficClass := byte(dscp)<<2 | ecn
+		packet[0] = 0x60 | trafficClass>>4
+		packet[1] = trafficClass<<4 | packet[1]&0x0f
+	}
+}
@@ -0,0 +1,109 @@
+package protocols
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// TestRunMarker tests that generated frames carry the run ID trailer and the
+// configured DSCP, and that the IP headers stay valid
+func TestRunMarker(t *testing.T) {
+	cfg := &config.Config{
+		Devices: []config.Device{{
+			Name:        "router1",
+			MACAddress:  fragTestDeviceMAC,
+			IPAddresses: []net.IP{net.ParseIP("192.168.1.1"), net.ParseIP("2001:db8::1")},
+		}},
+		RunMarker: &config.RunMarkerConfig{RunID: "lab-a", DSCP: 10},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	trailer := config.RunMarkerTrailer("lab-a")
+
+	// An ARP reply and IPv4 and IPv6 echo replies
+	stack.decodePacket(buildARPRequestPacket(t, "192.168.1.1"))
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	buffer := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buffer, opts,
+		&layers.Ethernet{SrcMAC: fragTestHostMAC, DstMAC: fragTestDeviceMAC, EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolICMPv4,
+			SrcIP: net.ParseIP("192.168.1.50").To4(), DstIP: net.ParseIP("192.168.1.1").To4()},
+		&layers.ICMPv4{TypeCode: layers.CreateICMPv4TypeCode(layers.ICMPv4TypeEchoRequest, 0), Id: 1, Seq: 1},
+		gopacket.Payload("ping"),
+	); err != nil {
+		t.Fatalf("serialize echo request: %v", err)
+	}
+	stack.decodePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+
+	ipv6 := &layers.IPv6{Version: 6, NextHeader: layers.IPProtocolICMPv6, HopLimit: 64,
+		SrcIP: net.ParseIP("2001:db8::50"), DstIP: net.ParseIP("2001:db8::1")}
+	icmpv6 := &layers.ICMPv6{TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeEchoRequest, 0)}
+	_ = icmpv6.SetNetworkLayerForChecksum(ipv6)
+	buffer = gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buffer, opts,
+		&layers.Ethernet{SrcMAC: fragTestHostMAC, DstMAC: fragTestDeviceMAC, EthernetType: layers.EthernetTypeIPv6},
+		ipv6, icmpv6, &layers.ICMPv6Echo{Identifier: 1, SeqNumber: 1}, gopacket.Payload("ping"),
+	); err != nil {
+		t.Fatalf("serialize echo request: %v", err)
+	}
+	stack.decodePacket(&Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())})
+
+	queued := drainSendQueue(stack)
+	if len(queued) != 3 {
+		t.Fatalf("got %d generated frames, want ARP, ICMP and ICMPv6 replies", len(queued))
+	}
+	for _, pkt := range queued {
+		original := append([]byte(nil), pkt.Buffer[:pkt.Length]...)
+		device, _ := pkt.Device.(*config.Device)
+		frame := stack.markFrame(pkt.Buffer[:pkt.Length], device)
+		if !bytes.Equal(pkt.Buffer[:pkt.Length], original) {
+			t.Error("markFrame modified the queued packet")
+		}
+		if !bytes.HasSuffix(frame, trailer) {
+			t.Errorf("frame %x does not end with the run marker %x", frame, trailer)
+		}
+
+		packet := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+		if ip, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+			if ip.TOS>>2 != 10 {
+				t.Errorf("IPv4 DSCP = %d, want 10", ip.TOS>>2)
+			}
+			if sum := CalculateIPChecksum(ip.Contents); sum != 0 {
+				t.Errorf("IPv4 header checksum invalid after marking (residue %#x)", sum)
+			}
+			if icmp, ok := packet.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4); !ok || string(icmp.Payload) != "ping" {
+				t.Errorf("ICMP reply payload changed by the trailer: %+v", icmp)
+			}
+		}
+		if ip, ok := packet.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok && ip.TrafficClass>>2 != 10 {
+			t.Errorf("IPv6 DSCP = %d, want 10", ip.TrafficClass>>2)
+		}
+	}
+
+	// --run-id overrides the configured run ID
+	stack.SetRunID("lab-b")
+	frame := stack.markFrame(queued[0].Buffer, nil)
+	if want := config.RunMarkerTrailer("lab-b"); !bytes.HasSuffix(frame, want) || bytes.Equal(want, trailer) {
+		t.Errorf("frame does not end with the lab-b marker %x", want)
+	}
+}
+
+// TestRunMarker_MTU tests that the trailer is left off full-size frames
+func TestRunMarker_MTU(t *testing.T) {
+	cfg := &config.Config{RunMarker: &config.RunMarkerConfig{RunID: "lab-a"}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	frame := make([]byte, 14+config.DefaultMTU)
+	if marked := stack.markFrame(frame, nil); len(marked) != len(frame) {
+		t.Errorf("full-size frame grew to %d bytes, want the trailer omitted", len(marked))
+	}
+	jumbo := &config.Device{Name: "storage", MTU: 9000}
+	if marked := stack.markFrame(frame, jumbo); len(marked) != len(frame)+8 {
+		t.Errorf("frame on a jumbo device is %d bytes, want the 8-byte trailer appended", len(marked))
+	}
+}
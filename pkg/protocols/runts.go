@@ -0,0 +1,78 @@
+package protocols
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
+)
+
+const (
+	// runtFrameLen is the length of an injected runt, well below the 60
+	// bytes (64 with FCS) every Ethernet frame must be padded to
+	runtFrameLen = 32
+
+	// runtInterval is how often active runt injections emit; the injected
+	// value is the number of runts per interval
+	runtInterval = time.Second
+
+	// runtEtherType is the IEEE 802 local experimental EtherType, so runts
+	// that do get through are not mistaken for real traffic
+	runtEtherType = 0x88B5
+)
+
+// startRuntLoop emits runt frames for every active "Runt Frames" injection.
+func (s *Stack) startRuntLoop() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(runtInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sendRunts()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// sendRunts sends one interval's worth of runts from each device with an
+// active runt injection. Powered-off devices stay silent.
+func (s *Stack) sendRunts() {
+	for _, state := range s.errorManager.GetAllStates() {
+		if state.ErrorType != errors.ErrorTypeRunts {
+			continue
+		}
+		ip := net.ParseIP(state.DeviceIP)
+		if ip == nil {
+			continue
+		}
+		for _, device := range s.devices.GetByIP(ip) {
+			if len(device.MACAddress) == 0 || !s.isDeviceUp(device.Name) {
+				continue
+			}
+			for i := 0; i < state.Value; i++ {
+				s.Send(&Packet{Buffer: buildRuntFrame(device.MACAddress), Device: device})
+			}
+			if s.debugConfig.GetGlobal() >= 3 {
+				fmt.Printf("Sent %d runt frames from %s (%s)\n", state.Value, device.Name, state.Interface)
+			}
+		}
+	}
+}
+
+// buildRuntFrame returns an unpadded broadcast frame of runtFrameLen bytes.
+// The frame is built by hand because gopacket pads to the Ethernet minimum.
+func buildRuntFrame(srcMAC net.HardwareAddr) []byte {
+	frame := make([]byte, runtFrameLen)
+	copy(frame[0:6], layers.EthernetBroadcast)
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], runtEtherType)
+	return frame
+}
@@ -0,0 +1,53 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+func TestStackSendRunts(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "sw1",
+		MACAddress:  mac,
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.2")},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	stack.sendRunts()
+	if depth, _ := stack.SendQueueDepth(); depth != 0 {
+		t.Fatalf("expected no runts without an injection, got %d", depth)
+	}
+
+	stack.GetErrorManager().SetError("10.0.0.2", "eth0", errors.ErrorTypeRunts, 3)
+	stack.sendRunts()
+	if depth, _ := stack.SendQueueDepth(); depth != 3 {
+		t.Fatalf("expected 3 runts queued, got %d", depth)
+	}
+	for i := 0; i < 3; i++ {
+		pkt := <-stack.sendQueue
+		if len(pkt.Buffer) >= 60 {
+			t.Fatalf("expected a sub-64-byte frame, got %d bytes", len(pkt.Buffer))
+		}
+		if !bytes.Equal(pkt.Buffer[6:12], mac) {
+			t.Fatalf("expected runt sourced from device MAC, got %x", pkt.Buffer[6:12])
+		}
+		if etherType := binary.BigEndian.Uint16(pkt.Buffer[12:14]); etherType != runtEtherType {
+			t.Fatalf("unexpected EtherType %#04x", etherType)
+		}
+	}
+
+	if err := stack.SetDevicePower("sw1", false); err != nil {
+		t.Fatalf("power off: %v", err)
+	}
+	stack.sendRunts()
+	if depth, _ := stack.SendQueueDepth(); depth != 0 {
+		t.Fatalf("expected powered-off device to send no runts, got %d", depth)
+	}
+}
@@ -1,8 +1,12 @@
 package protocols
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net"
+	"time"
 
 	"github.com/google/gopacket/layers"
 	"github.com/gosnmp/gosnmp"
@@ -11,14 +15,25 @@ import (
 	"github.com/krisarmstrong/niac-go/pkg/snmp"
 )
 
+// snmpEphemeralPortMin is the start of the IANA dynamic port range used for
+// ephemeral response source ports.
+const snmpEphemeralPortMin = 49152
+
+// snmpMaxAuthTrapsInFlight caps authenticationFailure traps being sent at
+// once; denials beyond it raise no trap, so a flood of denied requests cannot
+// start a goroutine and socket each.
+const snmpMaxAuthTrapsInFlight = 4
+
 // SNMPHandler routes SNMP queries to per-device agents.
 type SNMPHandler struct {
-	stack *Stack
+	stack     *Stack
+	walks     snmpWalkTracker // Walks in progress, for slow_walk agents
+	authTraps chan struct{}   // One per authenticationFailure trap being sent
 }
 
 // NewSNMPHandler creates an SNMP handler bound to the stack.
 func NewSNMPHandler(stack *Stack) *SNMPHandler {
-	return &SNMPHandler{stack: stack}
+	return &SNMPHandler{stack: stack, authTraps: make(chan struct{}, snmpMaxAuthTrapsInFlight)}
 }
 
 // HandlePacket processes an SNMP request delivered over IPv4/UDP.
@@ -37,34 +52,47 @@ func (h *SNMPHandler) HandlePacket(pkt *Packet, ip *layers.IPv4, udp *layers.UDP
 		}
 		return
 	}
+	if !h.stack.bootStageReached(device.Name, BootStageSNMP) {
+		if h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 3 {
+			fmt.Printf("SNMP: device %s still booting, request dropped sn=%d\n", device.Name, pkt.SerialNumber)
+		}
+		return
+	}
 
-	request, err := h.decodeRequest(udp.Payload)
-	if err != nil {
+	if !agent.AllowsManager(ip.SrcIP) {
+		h.denyRequest(device, agent, ip.SrcIP, pkt)
+		return
+	}
+
+	request, err := h.decodeRequest(device, udp.Payload)
+	undecryptable := errors.Is(err, errSNMPv3Undecryptable)
+	if err != nil && !undecryptable {
 		if h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 2 {
 			fmt.Printf("SNMP: decode failed for %s sn=%d err=%v\n", ip.DstIP, pkt.SerialNumber, err)
 		}
+		h.stack.recordErrorPacket(pkt, "snmp", err)
 		return
 	}
 
-	if request.Community != agent.GetCommunity() {
+	delay, answer := h.walkDelay(device, request, ip.SrcIP, uint16(udp.SrcPort))
+	if !answer {
 		if h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 2 {
-			// SECURITY FIX MEDIUM-5: Redact community strings to prevent credential exposure
-			fmt.Printf("SNMP: community mismatch [REDACTED] (expected [REDACTED]) for device %s sn=%d\n",
-				device.Name, pkt.SerialNumber)
+			fmt.Printf("SNMP: device %s overloaded, walk request dropped sn=%d\n", device.Name, pkt.SerialNumber)
 		}
 		return
 	}
 
-	responseVars := agent.ProcessPDU(request.PDUType, request.Variables, request.MaxRepetitions)
-
-	response := &gosnmp.SnmpPacket{
-		Version:    request.Version,
-		Community:  request.Community,
-		PDUType:    gosnmp.GetResponse,
-		RequestID:  request.RequestID,
-		Error:      gosnmp.NoError,
-		ErrorIndex: 0,
-		Variables:  responseVars,
+	var response *gosnmp.SnmpPacket
+	if request.Version == gosnmp.Version3 {
+		response = h.respondV3(device, agent, request, udp.Payload, undecryptable)
+		if response == nil && h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 2 {
+			fmt.Printf("SNMP: SNMPv3 not enabled for device %s sn=%d\n", device.Name, pkt.SerialNumber)
+		}
+	} else {
+		response = h.respondCommunity(device, agent, request, pkt.SerialNumber)
+	}
+	if response == nil {
+		return
 	}
 
 	payload, err := response.MarshalMsg()
@@ -91,10 +119,131 @@ func (h *SNMPHandler) HandlePacket(pkt *Packet, ip *layers.IPv4, udp *layers.UDP
 		return
 	}
 
-	err = h.stack.udpHandler.SendUDP(srcIP, dstIP, uint16(udp.DstPort), uint16(udp.SrcPort), payload, []byte(srcMAC), []byte(dstMAC))
-	if err != nil && h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 1 {
-		fmt.Printf("SNMP: failed to emit response for device %s sn=%d err=%v\n", device.Name, pkt.SerialNumber, err)
+	srcPort, dstPort := responseSourcePort(device), uint16(udp.SrcPort)
+	send := func() {
+		err := h.stack.udpHandler.SendUDP(srcIP, dstIP, srcPort, dstPort, payload, []byte(srcMAC), []byte(dstMAC))
+		if err != nil && h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 1 {
+			fmt.Printf("SNMP: failed to emit response for device %s sn=%d err=%v\n", device.Name, pkt.SerialNumber, err)
+		}
+	}
+	if delay > 0 {
+		// An overloaded agent answers the walk late
+		time.AfterFunc(delay, send)
+		return
+	}
+	send()
+}
+
+// respondCommunity answers an SNMPv1/v2c request through the view of its
+// community. It returns nil when the community is not accepted.
+func (h *SNMPHandler) respondCommunity(device *config.Device, agent *snmp.Agent, request *gosnmp.SnmpPacket, serialNumber int) *gosnmp.SnmpPacket {
+	view, ok := agent.ViewFor(request.Community)
+	if !ok {
+		if h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 2 {
+			// SECURITY FIX MEDIUM-5: Redact community strings to prevent credential exposure
+			fmt.Printf("SNMP: community mismatch [REDACTED] (expected [REDACTED]) for device %s sn=%d\n",
+				device.Name, serialNumber)
+		}
+		return nil
+	}
+
+	response := &gosnmp.SnmpPacket{
+		Version:   request.Version,
+		Community: request.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: request.RequestID,
+	}
+	if request.PDUType == gosnmp.SetRequest && view != nil {
+		// Restricted communities are read-only views
+		response.Error = gosnmp.AuthorizationError
+		response.ErrorIndex = 1
+		response.Variables = request.Variables
+		return response
+	}
+	if request.PDUType == gosnmp.SetRequest {
+		response.Variables, response.Error, response.ErrorIndex = agent.ProcessSet(request.Variables, view)
+		if request.Version == gosnmp.Version1 {
+			response.Error = snmpv1SetError(response.Error)
+		}
+		return response
+	}
+
+	budget := snmp.VarbindBudget(response, agent.MaxMessageSize())
+	response.Variables, response.Error = agent.ProcessPDUWithLimit(request.PDUType, request.Variables, request.MaxRepetitions, view, budget)
+	if request.Version == gosnmp.Version1 && response.Error == gosnmp.NoError {
+		response.Error, response.ErrorIndex, response.Variables = snmpv1Response(request.Variables, response.Variables)
+	}
+	return response
+}
+
+// snmpv1Response converts a response to SNMPv1 semantics. SNMPv1 has no
+// per-varbind exceptions (RFC 1157 section 4.1.2): the first variable that
+// cannot be answered fails the whole request with noSuchName, error-index
+// gives its 1-based position, and the request's varbinds are echoed back.
+func snmpv1Response(requestVars, responseVars []gosnmp.SnmpPDU) (gosnmp.SNMPError, uint8, []gosnmp.SnmpPDU) {
+	for i, v := range responseVars {
+		switch v.Type {
+		case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView:
+			return gosnmp.NoSuchName, uint8(i + 1), requestVars
+		}
+	}
+	return gosnmp.NoError, 0, responseVars
+}
+
+// snmpv1SetError maps a SET error to the SNMPv1 error-status an agent
+// returns instead (RFC 3584 section 4.4)
+func snmpv1SetError(status gosnmp.SNMPError) gosnmp.SNMPError {
+	switch status {
+	case gosnmp.NoAccess, gosnmp.NotWritable, gosnmp.NoCreation, gosnmp.InconsistentName, gosnmp.AuthorizationError:
+		return gosnmp.NoSuchName
+	case gosnmp.WrongValue, gosnmp.WrongEncoding, gosnmp.WrongType, gosnmp.WrongLength, gosnmp.InconsistentValue:
+		return gosnmp.BadValue
+	case gosnmp.ResourceUnavailable, gosnmp.CommitFailed, gosnmp.UndoFailed:
+		return gosnmp.GenErr
+	}
+	return status
+}
+
+// responseSourcePort picks the UDP source port for a response. Agents normally
+// reply from 161 whatever port the request was addressed to; the ephemeral
+// mode replies from a random dynamic port so firewall pinhole handling can be
+// tested.
+func responseSourcePort(device *config.Device) uint16 {
+	if device.SNMPConfig.ResponseSourcePort == config.SNMPResponsePortEphemeral {
+		return snmpEphemeralPortMin + uint16(rand.IntN(65536-snmpEphemeralPortMin))
+	}
+	return UDPPortSNMP
+}
+
+// denyRequest drops a request from a source outside the agent's allowed
+// managers, as a real agent's access list would, and raises an
+// authenticationFailure trap when traps are configured for it.
+func (h *SNMPHandler) denyRequest(device *config.Device, agent *snmp.Agent, source net.IP, pkt *Packet) {
+	h.stack.stats.mu.Lock()
+	h.stack.stats.SNMPDenied++
+	h.stack.stats.mu.Unlock()
+
+	if h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 2 {
+		fmt.Printf("SNMP: request from %s not in allowed managers for device %s sn=%d\n", source, device.Name, pkt.SerialNumber)
 	}
+
+	// Trap delivery does network I/O; keep it off the receive path. The trap
+	// also draws from the receiver's rate limit budget in sendTrap.
+	select {
+	case h.authTraps <- struct{}{}:
+	default:
+		if h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 2 {
+			fmt.Printf("SNMP: %d authenticationFailure traps in flight, none sent for device %s sn=%d\n",
+				snmpMaxAuthTrapsInFlight, device.Name, pkt.SerialNumber)
+		}
+		return
+	}
+	go func() {
+		defer func() { <-h.authTraps }()
+		if err := agent.NotifyAuthenticationFailure(); err != nil && h.stack.GetProtocolDebugLevel(logging.ProtocolSNMP) >= 1 {
+			fmt.Printf("SNMP: authenticationFailure trap failed for device %s: %v\n", device.Name, err)
+		}
+	}()
 }
 
 func (h *SNMPHandler) selectAgent(devices []*config.Device) (*config.Device, *snmp.Agent) {
@@ -106,14 +255,26 @@ func (h *SNMPHandler) selectAgent(devices []*config.Device) (*config.Device, *sn
 	return nil, nil
 }
 
-func (h *SNMPHandler) decodeRequest(payload []byte) (*gosnmp.SnmpPacket, error) {
-	decoder := gosnmp.GoSNMP{
-		Transport: "udp",
-		Version:   gosnmp.Version2c,
-		Community: "public",
-		MaxOids:   gosnmp.MaxOids,
+// decodeRequest decodes an SNMP request to the device. An authPriv request
+// that fails to decode once its USM header has been read is returned with
+// errSNMPv3Undecryptable, so respondV3 can check the user and digest before
+// reporting the decryption error.
+func (h *SNMPHandler) decodeRequest(device *config.Device, payload []byte) (*gosnmp.SnmpPacket, error) {
+	engine := h.stack.getSNMPV3Engine(device)
+	decoder := engine.decoder()
+	if decoder.SecurityParameters != nil {
+		// Decoding blanks the digest and decrypts in place; keep the
+		// received bytes for the digest check
+		payload = bytes.Clone(payload)
+	}
+
+	request, err := decoder.SnmpDecodePacket(payload)
+	if err != nil && request != nil && request.Version == gosnmp.Version3 && request.MsgFlags&gosnmp.AuthPriv == gosnmp.AuthPriv {
+		if usm, ok := request.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok && usm.AuthenticationParameters != "" {
+			return request, fmt.Errorf("%w: %v", errSNMPv3Undecryptable, err)
+		}
 	}
-	return decoder.SnmpDecodePacket(payload)
+	return request, err
 }
 
 func (h *SNMPHandler) sourceMAC(device *config.Device, pkt *Packet) net.HardwareAddr {
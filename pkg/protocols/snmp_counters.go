@@ -0,0 +1,38 @@
+package protocols
+
+import (
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+)
+
+// MIB-II (RFC 1213) scalar counters computed from live stack statistics
+const (
+	OIDIPInReceives    = "1.3.6.1.2.1.4.3.0"
+	OIDIPOutRequests   = "1.3.6.1.2.1.4.10.0"
+	OIDICMPInEchos     = "1.3.6.1.2.1.5.8.0"
+	OIDICMPOutEchoReps = "1.3.6.1.2.1.5.22.0"
+	OIDSNMPInPkts      = "1.3.6.1.2.1.11.1.0"
+	OIDSNMPOutPkts     = "1.3.6.1.2.1.11.2.0"
+)
+
+// registerStackCounters maps stack statistics onto an agent's standard
+// counters so an NMS polling any simulated device graphs the simulator's real
+// activity. The statistics are simulator-wide, so every agent reports the same
+// values (each restarting from zero when its device reboots).
+func (s *Stack) registerStackCounters(agent *snmp.Agent) {
+	counter := func(read func(st *Statistics) uint64) func() *snmp.OIDValue {
+		return func() *snmp.OIDValue {
+			s.stats.mu.RLock()
+			value := read(s.stats)
+			s.stats.mu.RUnlock()
+			return &snmp.OIDValue{Type: gosnmp.Counter32, Value: uint(value)}
+		}
+	}
+
+	agent.RegisterComputedOID(OIDIPInReceives, counter(func(st *Statistics) uint64 { return st.PacketsReceived }))
+	agent.RegisterComputedOID(OIDIPOutRequests, counter(func(st *Statistics) uint64 { return st.PacketsSent }))
+	agent.RegisterComputedOID(OIDICMPInEchos, counter(func(st *Statistics) uint64 { return st.ICMPRequests }))
+	agent.RegisterComputedOID(OIDICMPOutEchoReps, counter(func(st *Statistics) uint64 { return st.ICMPReplies }))
+	agent.RegisterComputedOID(OIDSNMPInPkts, counter(func(st *Statistics) uint64 { return st.SNMPQueries + st.SNMPDenied }))
+	agent.RegisterComputedOID(OIDSNMPOutPkts, counter(func(st *Statistics) uint64 { return st.SNMPQueries }))
+}
@@ -0,0 +1,89 @@
+package protocols
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
+)
+
+// snmpWalkTrackerLimit is the number of walks tracked before finished ones
+// are pruned
+const snmpWalkTrackerLimit = 1024
+
+// snmpWalkTracker counts the requests of the walks in progress, per device
+// and manager address, for agents with a slow_walk model
+type snmpWalkTracker struct {
+	mu    sync.Mutex
+	walks map[string]*snmpWalk
+}
+
+type snmpWalk struct {
+	requests int
+	last     time.Time
+}
+
+// next records a walk request and returns its position in the walk, from 1.
+// A request more than gap after the previous one starts a new walk.
+func (t *snmpWalkTracker) next(key string, gap time.Duration, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.walks == nil {
+		t.walks = make(map[string]*snmpWalk)
+	}
+	walk, ok := t.walks[key]
+	if !ok || now.Sub(walk.last) > gap {
+		if len(t.walks) >= snmpWalkTrackerLimit {
+			for k, w := range t.walks {
+				if now.Sub(w.last) > gap {
+					delete(t.walks, k)
+				}
+			}
+		}
+		walk = &snmpWalk{}
+		t.walks[key] = walk
+	}
+	walk.requests++
+	walk.last = now
+	return walk.requests
+}
+
+// walkDelay returns how long the response to a walk request is held back by
+// the device's slow_walk model, and false when the overloaded agent drops it.
+// Requests other than GETNEXT/GETBULK, and devices without injected High CPU,
+// are answered at normal speed.
+func (h *SNMPHandler) walkDelay(device *config.Device, request *gosnmp.SnmpPacket, manager net.IP, port uint16) (time.Duration, bool) {
+	slow := device.SNMPConfig.SlowWalk
+	if slow == nil || (request.PDUType != gosnmp.GetNextRequest && request.PDUType != gosnmp.GetBulkRequest) {
+		return 0, true
+	}
+	cpu := h.stack.injectedCPU(device)
+	if cpu == 0 {
+		return 0, true
+	}
+
+	key := fmt.Sprintf("%s|%s", device.Name, net.JoinHostPort(manager.String(), fmt.Sprint(port)))
+	position := h.walks.next(key, slow.WalkGap, time.Now())
+	delay := time.Duration(position) * slow.Step * time.Duration(cpu) / 100
+	return delay, delay <= slow.Max
+}
+
+// injectedCPU returns the High CPU percentage injected for device, or 0
+func (s *Stack) injectedCPU(device *config.Device) int {
+	for _, state := range s.errorManager.GetAllStates() {
+		if state.ErrorType != errors.ErrorTypeCPU {
+			continue
+		}
+		for _, ip := range device.IPAddresses {
+			if ip.String() == state.DeviceIP {
+				return min(max(state.Value, 0), 100)
+			}
+		}
+	}
+	return 0
+}
@@ -1,14 +1,19 @@
 package protocols
 
 import (
+	"math"
 	"net"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/gosnmp/gosnmp"
 	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
 )
 
 func TestSNMPHandler_HandlePacket(t *testing.T) {
@@ -131,3 +136,558 @@ func TestSNMPHandler_HandlePacket(t *testing.T) {
 		t.Fatalf("expected SNMPQueries=1, got %d", stats.SNMPQueries)
 	}
 }
+
+// TestSNMPHandler_AllowedManagers tests that requests from sources outside
+// allowed_managers go unanswered while allowed sources get a response
+func TestSNMPHandler_AllowedManagers(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xef}
+	deviceIP := net.ParseIP("10.0.0.11").To4()
+
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name:        "locked-down",
+				Type:        "router",
+				MACAddress:  deviceMAC,
+				IPAddresses: []net.IP{deviceIP},
+				SNMPConfig: config.SNMPConfig{
+					Community:       "public",
+					AllowedManagers: []string{"10.0.1.0/24"},
+				},
+			},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	req := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.GetRequest,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+	}
+	payload, err := req.MarshalMsg()
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	frame := make([]byte, 14)
+	copy(frame[0:6], deviceMAC)
+	copy(frame[6:12], []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55})
+	frame[12] = 0x08
+
+	send := func(source string) {
+		udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+		udpLayer.Payload = payload
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP(source).To4(), DstIP: deviceIP}
+		packet := &Packet{Buffer: frame, Length: len(frame), SerialNumber: 1}
+		stack.snmpHandler.HandlePacket(packet, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+	}
+
+	send("10.0.0.5")
+	select {
+	case <-stack.sendQueue:
+		t.Fatal("expected no response to a disallowed manager")
+	default:
+	}
+
+	send("10.0.1.20")
+	select {
+	case <-stack.sendQueue:
+	default:
+		t.Fatal("expected a response to an allowed manager")
+	}
+
+	stats := stack.GetStats()
+	if stats.SNMPDenied != 1 || stats.SNMPQueries != 1 {
+		t.Errorf("expected SNMPDenied=1 and SNMPQueries=1, got %d and %d", stats.SNMPDenied, stats.SNMPQueries)
+	}
+}
+
+// TestSNMPHandler_AuthFailureTrapsBounded tests that denied requests raise an
+// authenticationFailure trap only while fewer than snmpMaxAuthTrapsInFlight
+// are being sent
+func TestSNMPHandler_AuthFailureTrapsBounded(t *testing.T) {
+	receiver, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot open UDP listener: %v", err)
+	}
+	defer receiver.Close()
+
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee}
+	deviceIP := net.ParseIP("127.0.0.1").To4()
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name:        "trapping",
+				Type:        "router",
+				MACAddress:  deviceMAC,
+				IPAddresses: []net.IP{deviceIP},
+				SNMPConfig: config.SNMPConfig{
+					Community:       "public",
+					AllowedManagers: []string{"10.0.1.0/24"},
+					Traps: &config.TrapConfig{
+						Enabled:               true,
+						Receivers:             []string{receiver.LocalAddr().String()},
+						AuthenticationFailure: &config.TrapTriggerConfig{Enabled: true},
+					},
+				},
+			},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	req := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.GetRequest,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+	}
+	payload, err := req.MarshalMsg()
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	deny := func() {
+		udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP), BaseLayer: layers.BaseLayer{Payload: payload}}
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5").To4(), DstIP: deviceIP}
+		stack.snmpHandler.HandlePacket(&Packet{SerialNumber: 1}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+	}
+	traps := func() int {
+		received := 0
+		buf := make([]byte, 65535)
+		for {
+			_ = receiver.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+			if _, _, err := receiver.ReadFrom(buf); err != nil {
+				return received
+			}
+			received++
+		}
+	}
+
+	// With every slot taken a denial raises no trap
+	for i := 0; i < snmpMaxAuthTrapsInFlight; i++ {
+		stack.snmpHandler.authTraps <- struct{}{}
+	}
+	deny()
+	if n := traps(); n != 0 {
+		t.Errorf("receiver got %d traps with every slot taken, want 0", n)
+	}
+
+	for i := 0; i < snmpMaxAuthTrapsInFlight; i++ {
+		<-stack.snmpHandler.authTraps
+	}
+	deny()
+	if n := traps(); n != 1 {
+		t.Errorf("receiver got %d traps for one denial, want 1", n)
+	}
+	if denied := stack.GetStats().SNMPDenied; denied != 2 {
+		t.Errorf("expected SNMPDenied=2, got %d", denied)
+	}
+}
+
+// TestSNMPHandler_ComputedCounters tests that stack counters mapped to MIB-II
+// OIDs are computed at request time and grow as packets are processed
+func TestSNMPHandler_ComputedCounters(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xf0}
+	deviceIP := net.ParseIP("10.0.0.12").To4()
+
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name:        "counting-router",
+				Type:        "router",
+				MACAddress:  deviceMAC,
+				IPAddresses: []net.IP{deviceIP},
+				SNMPConfig:  config.SNMPConfig{Community: "public"},
+			},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	frame := make([]byte, 14)
+	copy(frame[0:6], deviceMAC)
+	copy(frame[6:12], []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55})
+	frame[12] = 0x08
+
+	get := func(oid string) uint {
+		t.Helper()
+		req := &gosnmp.SnmpPacket{
+			Version:   gosnmp.Version2c,
+			Community: "public",
+			PDUType:   gosnmp.GetRequest,
+			Variables: []gosnmp.SnmpPDU{{Name: "." + oid, Type: gosnmp.Null}},
+		}
+		payload, err := req.MarshalMsg()
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+		udpLayer.Payload = payload
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5").To4(), DstIP: deviceIP}
+		stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+
+		select {
+		case resp := <-stack.sendQueue:
+			decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+			udp := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			decoder := gosnmp.GoSNMP{Version: gosnmp.Version2c}
+			respSNMP, err := decoder.SnmpDecodePacket(udp.Payload)
+			if err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			v := respSNMP.Variables[0]
+			if v.Type != gosnmp.Counter32 {
+				t.Fatalf("expected Counter32 for %s, got %v", oid, v.Type)
+			}
+			return v.Value.(uint)
+		default:
+			t.Fatalf("expected SNMP response for %s", oid)
+		}
+		return 0
+	}
+
+	first := get(OIDSNMPOutPkts)
+	second := get(OIDSNMPOutPkts)
+	if second <= first {
+		t.Errorf("expected snmpOutPkts to grow, got %d then %d", first, second)
+	}
+
+	before := get(OIDICMPInEchos)
+	stack.IncrementStat("icmp_requests")
+	stack.IncrementStat("icmp_requests")
+	if after := get(OIDICMPInEchos); after != before+2 {
+		t.Errorf("expected icmpInEchos %d, got %d", before+2, after)
+	}
+}
+
+func TestSNMPHandler_ResponseSourcePort(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xf1}
+	deviceIP := net.ParseIP("10.0.0.13").To4()
+	managerIP := net.ParseIP("10.0.0.5").To4()
+
+	frame := make([]byte, 14)
+	copy(frame[0:6], deviceMAC)
+	copy(frame[6:12], []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55})
+	frame[12] = 0x08
+
+	req := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.GetRequest,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+	}
+	payload, err := req.MarshalMsg()
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		mode      string
+		ephemeral bool
+	}{
+		{name: "default", mode: ""},
+		{name: "standard", mode: config.SNMPResponsePortStandard},
+		{name: "ephemeral", mode: config.SNMPResponsePortEphemeral, ephemeral: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Devices: []config.Device{
+					{
+						Name:        "port-router",
+						Type:        "router",
+						MACAddress:  deviceMAC,
+						IPAddresses: []net.IP{deviceIP},
+						SNMPConfig:  config.SNMPConfig{Community: "public", ResponseSourcePort: tt.mode},
+					},
+				},
+			}
+			stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+			udpLayer := &layers.UDP{SrcPort: 40123, DstPort: layers.UDPPort(UDPPortSNMP)}
+			udpLayer.Payload = payload
+			ipLayer := &layers.IPv4{SrcIP: managerIP, DstIP: deviceIP}
+			stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+
+			var resp *Packet
+			select {
+			case resp = <-stack.sendQueue:
+			default:
+				t.Fatal("expected SNMP response")
+			}
+			decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+			udp, ok := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			if !ok {
+				t.Fatal("response missing UDP layer")
+			}
+			if udp.DstPort != 40123 {
+				t.Errorf("expected response to requester port 40123, got %d", udp.DstPort)
+			}
+			if tt.ephemeral {
+				if udp.SrcPort < snmpEphemeralPortMin {
+					t.Errorf("expected ephemeral source port >= %d, got %d", snmpEphemeralPortMin, udp.SrcPort)
+				}
+			} else if udp.SrcPort != UDPPortSNMP {
+				t.Errorf("expected source port %d, got %d", UDPPortSNMP, udp.SrcPort)
+			}
+		})
+	}
+}
+
+// TestSNMPHandler_SlowWalkUnderLoad tests that under injected High CPU each
+// GETNEXT of a walk is answered later than the one before until requests
+// are dropped, and that clearing the load restores normal responses
+func TestSNMPHandler_SlowWalkUnderLoad(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xf2}
+	deviceIP := net.ParseIP("10.0.0.14").To4()
+	step := 40 * time.Millisecond
+
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name:        "busy-router",
+				Type:        "router",
+				MACAddress:  deviceMAC,
+				IPAddresses: []net.IP{deviceIP},
+				SNMPConfig: config.SNMPConfig{
+					Community: "public",
+					SlowWalk:  &config.SNMPSlowWalk{Step: step, Max: 3 * step, WalkGap: 10 * time.Second},
+				},
+			},
+		},
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	stack.GetErrorManager().SetError(deviceIP.String(), "eth0", errors.ErrorTypeCPU, 100)
+
+	frame := make([]byte, 14)
+	copy(frame[0:6], deviceMAC)
+	copy(frame[6:12], []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55})
+	frame[12] = 0x08
+
+	// getNext sends a GETNEXT and returns how long its response took
+	getNext := func(requestID uint32) (time.Duration, bool) {
+		t.Helper()
+		req := &gosnmp.SnmpPacket{
+			Version:   gosnmp.Version2c,
+			Community: "public",
+			PDUType:   gosnmp.GetNextRequest,
+			RequestID: requestID,
+			Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1", Type: gosnmp.Null}},
+		}
+		payload, err := req.MarshalMsg()
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		udpLayer := &layers.UDP{SrcPort: 40124, DstPort: layers.UDPPort(UDPPortSNMP)}
+		udpLayer.Payload = payload
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5").To4(), DstIP: deviceIP}
+
+		start := time.Now()
+		stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+		select {
+		case <-stack.sendQueue:
+			return time.Since(start), true
+		case <-time.After(8 * step):
+			return 0, false
+		}
+	}
+
+	var previous time.Duration
+	for i := 1; i <= 3; i++ {
+		latency, ok := getNext(uint32(i))
+		if !ok {
+			t.Fatalf("GETNEXT %d went unanswered", i)
+		}
+		if latency < time.Duration(i)*step || latency <= previous {
+			t.Errorf("GETNEXT %d answered after %v, want at least %v and more than the previous %v",
+				i, latency, time.Duration(i)*step, previous)
+		}
+		previous = latency
+	}
+	if _, ok := getNext(4); ok {
+		t.Error("GETNEXT past max_ms was answered, want it dropped")
+	}
+
+	stack.GetErrorManager().ClearError(deviceIP.String(), "eth0")
+	if latency, ok := getNext(5); !ok || latency >= step {
+		t.Errorf("GETNEXT without load answered after %v (answered %v), want at once", latency, ok)
+	}
+}
+
+// TestSNMPHandler_MultiVarbindGetMissingOID tests that a GET response keeps
+// the request's varbind order and reports a missing OID at its position: as
+// an exception in SNMPv2c, as noSuchName with the error-index in SNMPv1.
+func TestSNMPHandler_MultiVarbindGetMissingOID(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x07}
+	deviceIP := net.ParseIP("10.0.0.17").To4()
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "order-router",
+		Type:        "router",
+		MACAddress:  deviceMAC,
+		IPAddresses: []net.IP{deviceIP},
+		SNMPConfig:  config.SNMPConfig{Community: "public", SysName: "order-router"},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+
+	oids := []string{
+		".1.3.6.1.2.1.1.5.0",  // sysName
+		".1.3.6.1.2.1.1.99.0", // missing
+		".1.3.6.1.2.1.1.3.0",  // sysUpTime
+		".1.3.6.1.2.1.1.98.0", // missing
+	}
+	frame := append(append(append([]byte{}, deviceMAC...), 0x00, 0x11, 0x22, 0x33, 0x44, 0x55), 0x08, 0x00)
+
+	get := func(version gosnmp.SnmpVersion) *gosnmp.SnmpPacket {
+		t.Helper()
+		req := &gosnmp.SnmpPacket{Version: version, Community: "public", PDUType: gosnmp.GetRequest, RequestID: 7}
+		for _, oid := range oids {
+			req.Variables = append(req.Variables, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Null})
+		}
+		payload, err := req.MarshalMsg()
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+		udpLayer.Payload = payload
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5"), DstIP: deviceIP}
+		stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+
+		var resp *Packet
+		select {
+		case resp = <-stack.sendQueue:
+		default:
+			t.Fatalf("expected SNMP %v response", version)
+		}
+		decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		udp, ok := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok {
+			t.Fatal("response missing UDP layer")
+		}
+		decoder := gosnmp.GoSNMP{Transport: "udp", Version: version, Community: "public"}
+		respSNMP, err := decoder.SnmpDecodePacket(udp.Payload)
+		if err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if len(respSNMP.Variables) != len(oids) {
+			t.Fatalf("expected %d varbinds, got %d", len(oids), len(respSNMP.Variables))
+		}
+		for i, v := range respSNMP.Variables {
+			if v.Name != oids[i] {
+				t.Errorf("%v varbind %d = %s, want %s", version, i+1, v.Name, oids[i])
+			}
+		}
+		return respSNMP
+	}
+
+	v2 := get(gosnmp.Version2c)
+	if v2.Error != gosnmp.NoError || v2.ErrorIndex != 0 {
+		t.Errorf("v2c error = %v index %d, want noError 0", v2.Error, v2.ErrorIndex)
+	}
+	wantTypes := []gosnmp.Asn1BER{gosnmp.OctetString, gosnmp.NoSuchObject, gosnmp.TimeTicks, gosnmp.NoSuchObject}
+	for i, v := range v2.Variables {
+		if v.Type != wantTypes[i] {
+			t.Errorf("v2c varbind %d type = %v, want %v", i+1, v.Type, wantTypes[i])
+		}
+	}
+
+	// SNMPv1 fails the request at the first missing OID, echoing the
+	// request's varbinds
+	v1 := get(gosnmp.Version1)
+	if v1.Error != gosnmp.NoSuchName || v1.ErrorIndex != 2 {
+		t.Errorf("v1 error = %v index %d, want noSuchName 2", v1.Error, v1.ErrorIndex)
+	}
+	for i, v := range v1.Variables {
+		if v.Type != gosnmp.Null {
+			t.Errorf("v1 varbind %d type = %v, want the request's Null", i+1, v.Type)
+		}
+	}
+}
+
+// TestSNMPHandler_ValueEncoding tests that values of the less common SNMP
+// types, set with the Go types walk files, SETs and integrations produce,
+// reach a gosnmp client with exactly the type and value that were set
+func TestSNMPHandler_ValueEncoding(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x08}
+	deviceIP := net.ParseIP("10.0.0.18").To4()
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "typed-router",
+		Type:        "router",
+		MACAddress:  deviceMAC,
+		IPAddresses: []net.IP{deviceIP},
+		SNMPConfig:  config.SNMPConfig{Community: "public"},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	agent := stack.getSNMPAgent(&cfg.Devices[0])
+	if agent == nil {
+		t.Fatal("no SNMP agent for the device")
+	}
+
+	tests := []struct {
+		oid       string
+		set       snmp.OIDValue
+		wantType  gosnmp.Asn1BER
+		wantValue interface{}
+	}{
+		{".1.3.6.1.4.1.99999.1.1.0", snmp.OIDValue{Type: gosnmp.Counter64, Value: uint64(math.MaxUint64)}, gosnmp.Counter64, uint64(math.MaxUint64)},
+		{".1.3.6.1.4.1.99999.1.2.0", snmp.OIDValue{Type: gosnmp.Counter64, Value: uint64(1 << 63)}, gosnmp.Counter64, uint64(1 << 63)},
+		{".1.3.6.1.4.1.99999.1.3.0", snmp.OIDValue{Type: gosnmp.Counter64, Value: 42}, gosnmp.Counter64, uint64(42)},
+		{".1.3.6.1.4.1.99999.1.4.0", snmp.OIDValue{Type: gosnmp.Counter64, Value: uint64(0)}, gosnmp.Counter64, uint64(0)},
+		{".1.3.6.1.4.1.99999.1.5.0", snmp.OIDValue{Type: gosnmp.Opaque, Value: []byte{0x01, 0x80, 0xff}}, gosnmp.Opaque, []byte{0x01, 0x80, 0xff}},
+		{".1.3.6.1.4.1.99999.1.6.0", snmp.OIDValue{Type: gosnmp.OpaqueFloat, Value: float32(1.5)}, gosnmp.OpaqueFloat, float32(1.5)},
+		{".1.3.6.1.4.1.99999.1.7.0", snmp.OIDValue{Type: gosnmp.IPAddress, Value: "192.0.2.77"}, gosnmp.IPAddress, "192.0.2.77"},
+		{".1.3.6.1.4.1.99999.1.8.0", snmp.OIDValue{Type: gosnmp.IPAddress, Value: net.ParseIP("198.51.100.1")}, gosnmp.IPAddress, "198.51.100.1"},
+		{".1.3.6.1.4.1.99999.1.9.0", snmp.OIDValue{Type: gosnmp.IPAddress, Value: "2001:db8::1"}, gosnmp.IPAddress, "0.0.0.0"},
+		{".1.3.6.1.4.1.99999.1.10.0", snmp.OIDValue{Type: gosnmp.Gauge32, Value: 7}, gosnmp.Gauge32, uint(7)},
+		{".1.3.6.1.4.1.99999.1.11.0", snmp.OIDValue{Type: gosnmp.Gauge32, Value: uint64(1 << 40)}, gosnmp.Gauge32, uint(math.MaxUint32)},
+		{".1.3.6.1.4.1.99999.1.12.0", snmp.OIDValue{Type: gosnmp.Counter32, Value: int64(1<<32 + 5)}, gosnmp.Counter32, uint(5)},
+		{".1.3.6.1.4.1.99999.1.13.0", snmp.OIDValue{Type: gosnmp.TimeTicks, Value: uint32(math.MaxUint32)}, gosnmp.TimeTicks, uint32(math.MaxUint32)},
+		{".1.3.6.1.4.1.99999.1.14.0", snmp.OIDValue{Type: gosnmp.TimeTicks, Value: 360000}, gosnmp.TimeTicks, uint32(360000)},
+		{".1.3.6.1.4.1.99999.1.15.0", snmp.OIDValue{Type: gosnmp.Uinteger32, Value: uint(0x80000000)}, gosnmp.Uinteger32, uint32(0x80000000)},
+		{".1.3.6.1.4.1.99999.1.16.0", snmp.OIDValue{Type: gosnmp.Integer, Value: int64(-5)}, gosnmp.Integer, -5},
+	}
+	req := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, Community: "public", PDUType: gosnmp.GetRequest, RequestID: 9}
+	for _, tt := range tests {
+		set := tt.set
+		if err := agent.SetOID(tt.oid, &set); err != nil {
+			t.Fatalf("SetOID(%s): %v", tt.oid, err)
+		}
+		req.Variables = append(req.Variables, gosnmp.SnmpPDU{Name: tt.oid, Type: gosnmp.Null})
+	}
+	payload, err := req.MarshalMsg()
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	frame := append(append(append([]byte{}, deviceMAC...), 0x00, 0x11, 0x22, 0x33, 0x44, 0x55), 0x08, 0x00)
+	udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+	udpLayer.Payload = payload
+	ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5").To4(), DstIP: deviceIP}
+	stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+
+	var resp *Packet
+	select {
+	case resp = <-stack.sendQueue:
+	default:
+		t.Fatal("expected an SNMP response")
+	}
+	udp, ok := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default).Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok {
+		t.Fatal("response missing UDP layer")
+	}
+	decoder := gosnmp.GoSNMP{Transport: "udp", Version: gosnmp.Version2c, Community: "public"}
+	respSNMP, err := decoder.SnmpDecodePacket(udp.Payload)
+	if err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if respSNMP.Error != gosnmp.NoError || len(respSNMP.Variables) != len(tests) {
+		t.Fatalf("response error %v with %d varbinds, want %d", respSNMP.Error, len(respSNMP.Variables), len(tests))
+	}
+	for i, tt := range tests {
+		got := respSNMP.Variables[i]
+		if got.Name != tt.oid || got.Type != tt.wantType || !reflect.DeepEqual(got.Value, tt.wantValue) {
+			t.Errorf("%s (%v %T %v) decoded as %s %v %T %v, want %v %T %v", tt.oid, tt.set.Type, tt.set.Value, tt.set.Value,
+				got.Name, got.Type, got.Value, got.Value, tt.wantType, tt.wantValue, tt.wantValue)
+		}
+	}
+}
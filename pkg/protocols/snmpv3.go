@@ -0,0 +1,296 @@
+package protocols
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5" // #nosec G501 -- HMAC-MD5-96 is a USM authentication protocol (RFC 3414)
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- HMAC-SHA-96 is a USM authentication protocol (RFC 3414)
+	"errors"
+	"fmt"
+	"maps"
+	"sync/atomic"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+	"github.com/krisarmstrong/niac-go/pkg/snmp"
+)
+
+// SNMPv3 report counters (RFC 3414 usmStats, RFC 3413 snmpUnknownContexts)
+const (
+	OIDUsmStatsUnsupportedSecLevels = "1.3.6.1.6.3.15.1.1.1.0"
+	OIDUsmStatsNotInTimeWindows     = "1.3.6.1.6.3.15.1.1.2.0"
+	OIDUsmStatsUnknownUserNames     = "1.3.6.1.6.3.15.1.1.3.0"
+	OIDUsmStatsUnknownEngineIDs     = "1.3.6.1.6.3.15.1.1.4.0"
+	OIDUsmStatsWrongDigests         = "1.3.6.1.6.3.15.1.1.5.0"
+	OIDUsmStatsDecryptionErrors     = "1.3.6.1.6.3.15.1.1.6.0"
+	OIDSnmpUnknownContexts          = "1.3.6.1.6.3.12.1.5.0"
+)
+
+// snmpTimeWindow is how far, in seconds, an authenticated request's
+// snmpEngineTime may differ from the agent's (RFC 3414 section 3.2 step 7)
+const snmpTimeWindow = 150
+
+// snmpDigestLength is the length of the HMAC-MD5-96 and HMAC-SHA-96 digests
+const snmpDigestLength = 12
+
+// errSNMPv3Undecryptable marks an authPriv request whose scoped PDU could not
+// be decrypted; respondV3 answers it once the USM checks that come first pass.
+var errSNMPv3Undecryptable = errors.New("SNMPv3 scoped PDU could not be decrypted")
+
+// snmpEngineEnterprise is the enterprise number in generated engine IDs
+// (net-snmp's, as used by most lab agents).
+const snmpEngineEnterprise = 8072
+
+// snmpV3Engine answers SNMPv3 requests for one device. Each context name
+// selects its own agent; the empty context is the device's main agent.
+type snmpV3Engine struct {
+	engineID string
+	contexts map[string]*snmp.Agent
+	user     *gosnmp.UsmSecurityParameters // USM user with keys localized to engineID (nil = noAuthNoPriv only)
+
+	unsupportedSecLevels atomic.Uint32
+	notInTimeWindows     atomic.Uint32
+	unknownUserNames     atomic.Uint32
+	unknownEngineIDs     atomic.Uint32
+	wrongDigests         atomic.Uint32
+	decryptionErrors     atomic.Uint32
+	unknownContexts      atomic.Uint32
+}
+
+// snmpEngineID builds an RFC 3411 engine ID from the device MAC: the
+// enterprise number with the high bit set, format 3 (MAC address), the MAC.
+func snmpEngineID(device *config.Device) string {
+	enterprise := uint32(snmpEngineEnterprise)
+	id := []byte{0x80 | byte(enterprise>>24), byte(enterprise >> 16), byte(enterprise >> 8), byte(enterprise), 3}
+	return string(append(id, device.MACAddress...))
+}
+
+// initSNMPv3 creates the device's SNMPv3 engine when it has contexts or a USM
+// user configured, with an agent per context. A context agent is a copy of the
+// device with the context's sysName and walk file; it shares the device's live
+// counters.
+func (s *Stack) initSNMPv3(device *config.Device, main *snmp.Agent) {
+	if len(device.SNMPConfig.Contexts) == 0 && device.SNMPConfig.V3 == nil {
+		return
+	}
+
+	debugLevel := s.debugConfig.GetProtocolLevel(logging.ProtocolSNMP)
+	engine := &snmpV3Engine{
+		engineID: snmpEngineID(device),
+		contexts: map[string]*snmp.Agent{"": main},
+	}
+	if v3 := device.SNMPConfig.V3; v3 != nil {
+		user, err := newUSMUser(v3, engine.engineID)
+		if err != nil {
+			if debugLevel >= 1 {
+				fmt.Printf("SNMP: SNMPv3 user unavailable for %s: %v\n", device.Name, err)
+			}
+			return
+		}
+		engine.user = user
+	}
+	for _, context := range device.SNMPConfig.Contexts {
+		contextDevice := *device
+		contextDevice.Properties = maps.Clone(device.Properties)
+		if contextDevice.Properties == nil {
+			contextDevice.Properties = make(map[string]string)
+		}
+		contextDevice.Properties["sysName"] = context.SysName
+		contextDevice.SNMPConfig.WalkFile = context.WalkFile
+		contextDevice.SNMPConfig.WalkSeries = nil
+		contextDevice.SNMPConfig.Traps = nil
+		contextDevice.SNMPConfig.Contexts = nil
+		contextDevice.SNMPConfig.V3 = nil
+
+		agent := snmp.NewAgent(&contextDevice, debugLevel)
+		agent.SetErrorStateManager(s.errorManager)
+		agent.SetWalkLimits(s.walkLimits)
+		if context.WalkFile != "" {
+			if err := agent.LoadWalkFile(context.WalkFile); err != nil && debugLevel >= 1 {
+				fmt.Printf("SNMP: failed to load walk file for %s context %s: %v\n", device.Name, context.Name, err)
+			}
+		}
+		s.registerStackCounters(agent)
+		engine.contexts[context.Name] = agent
+	}
+	s.snmpV3[device] = engine
+}
+
+// newUSMUser localizes the configured user's passphrases to the engine ID
+// (RFC 3414 section 2.6).
+func newUSMUser(v3 *config.SNMPv3Config, engineID string) (*gosnmp.UsmSecurityParameters, error) {
+	user := &gosnmp.UsmSecurityParameters{
+		UserName:                 v3.User,
+		AuthoritativeEngineID:    engineID,
+		AuthenticationProtocol:   gosnmp.MD5,
+		AuthenticationPassphrase: v3.AuthKey,
+		PrivacyProtocol:          gosnmp.NoPriv,
+		PrivacyPassphrase:        v3.PrivKey,
+	}
+	if v3.AuthProtocol == config.SNMPv3AuthSHA {
+		user.AuthenticationProtocol = gosnmp.SHA
+	}
+	switch v3.PrivProtocol {
+	case config.SNMPv3PrivDES:
+		user.PrivacyProtocol = gosnmp.DES
+	case config.SNMPv3PrivAES:
+		user.PrivacyProtocol = gosnmp.AES
+	}
+	if err := user.InitSecurityKeys(); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// getSNMPV3Engine returns the device's SNMPv3 engine, or nil when it has no
+// contexts or USM user configured.
+func (s *Stack) getSNMPV3Engine(device *config.Device) *snmpV3Engine {
+	if s == nil {
+		return nil
+	}
+	return s.snmpV3[device]
+}
+
+// respondV3 answers an SNMPv3 request. Without a USM user the engine serves
+// the noAuthNoPriv security level only; with one, requests must come from
+// that user, authenticated and, at authPriv, encrypted with its keys (RFC 3414
+// section 3.2). Requests before engine discovery, failing a USM check or for
+// an unknown context get the matching Report PDU. It returns nil when the
+// device does not speak SNMPv3.
+func (h *SNMPHandler) respondV3(device *config.Device, agent *snmp.Agent, request *gosnmp.SnmpPacket, payload []byte, undecryptable bool) *gosnmp.SnmpPacket {
+	engine := h.stack.getSNMPV3Engine(device)
+	usm, ok := request.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if engine == nil || !ok || request.SecurityModel != gosnmp.UserSecurityModel {
+		return nil
+	}
+
+	boots, engineTime := agent.EngineState()
+	params := &gosnmp.UsmSecurityParameters{
+		UserName:                 usm.UserName,
+		AuthoritativeEngineID:    engine.engineID,
+		AuthoritativeEngineBoots: uint32(boots),
+		AuthoritativeEngineTime:  uint32(engineTime),
+		AuthenticationProtocol:   gosnmp.NoAuth,
+		PrivacyProtocol:          gosnmp.NoPriv,
+	}
+	response := &gosnmp.SnmpPacket{
+		Version:            gosnmp.Version3,
+		MsgFlags:           gosnmp.NoAuthNoPriv,
+		SecurityModel:      gosnmp.UserSecurityModel,
+		SecurityParameters: params,
+		ContextEngineID:    engine.engineID,
+		ContextName:        request.ContextName,
+		MsgID:              request.MsgID,
+		MsgMaxSize:         request.MsgMaxSize,
+		PDUType:            gosnmp.GetResponse,
+		RequestID:          request.RequestID,
+	}
+	report := func(oid string, counter *atomic.Uint32) *gosnmp.SnmpPacket {
+		response.PDUType = gosnmp.Report
+		response.Variables = []gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.Counter32, Value: uint(counter.Add(1))}}
+		return response
+	}
+
+	level := request.MsgFlags & gosnmp.AuthPriv
+	switch {
+	case usm.AuthoritativeEngineID != engine.engineID:
+		// Engine discovery (RFC 3414 section 4)
+		return report(OIDUsmStatsUnknownEngineIDs, &engine.unknownEngineIDs)
+	case engine.user == nil && level != gosnmp.NoAuthNoPriv:
+		return report(OIDUsmStatsUnsupportedSecLevels, &engine.unsupportedSecLevels)
+	}
+	if engine.user != nil {
+		switch {
+		case usm.UserName != engine.user.UserName:
+			return report(OIDUsmStatsUnknownUserNames, &engine.unknownUserNames)
+		case level == gosnmp.NoAuthNoPriv, level == gosnmp.AuthPriv && engine.user.PrivacyProtocol == gosnmp.NoPriv:
+			return report(OIDUsmStatsUnsupportedSecLevels, &engine.unsupportedSecLevels)
+		case !engine.authentic(payload, usm):
+			return report(OIDUsmStatsWrongDigests, &engine.wrongDigests)
+		}
+
+		engine.secure(response, params, level)
+		drift := int(usm.AuthoritativeEngineTime) - engineTime
+		switch {
+		case boots == config.MaxSNMPEngineBoots, int(usm.AuthoritativeEngineBoots) != boots,
+			drift < -snmpTimeWindow || drift > snmpTimeWindow:
+			// Sent authenticated so the manager can trust the time it resyncs to
+			response.MsgFlags = gosnmp.AuthNoPriv
+			return report(OIDUsmStatsNotInTimeWindows, &engine.notInTimeWindows)
+		case undecryptable:
+			response.MsgFlags = gosnmp.NoAuthNoPriv
+			return report(OIDUsmStatsDecryptionErrors, &engine.decryptionErrors)
+		}
+	}
+	contextAgent, ok := engine.contexts[request.ContextName]
+	if !ok {
+		return report(OIDSnmpUnknownContexts, &engine.unknownContexts)
+	}
+
+	// The response must fit both the agent's limit and the manager's msgMaxSize
+	maxSize := contextAgent.MaxMessageSize()
+	if request.MsgMaxSize > 0 && int(request.MsgMaxSize) < maxSize {
+		maxSize = int(request.MsgMaxSize)
+	}
+	if request.PDUType == gosnmp.SetRequest {
+		response.Variables, response.Error, response.ErrorIndex = contextAgent.ProcessSet(request.Variables, nil)
+		return response
+	}
+	budget := snmp.VarbindBudget(response, maxSize)
+	response.Variables, response.Error = contextAgent.ProcessPDUWithLimit(request.PDUType, request.Variables, request.MaxRepetitions, nil, budget)
+	return response
+}
+
+// decoder returns a decoder for requests to the engine. With a USM user it
+// holds the user's keys, so authPriv scoped PDUs decrypt as they are decoded.
+func (e *snmpV3Engine) decoder() *gosnmp.GoSNMP {
+	decoder := &gosnmp.GoSNMP{
+		Transport: "udp",
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if e != nil && e.user != nil {
+		decoder.SecurityParameters = e.user.Copy()
+	}
+	return decoder
+}
+
+// authentic reports whether the request's digest is the HMAC, under the
+// user's localized key, of the whole message with the digest zeroed
+// (RFC 3414 sections 6.3.2 and 7.3.2).
+func (e *snmpV3Engine) authentic(payload []byte, usm *gosnmp.UsmSecurityParameters) bool {
+	digest := []byte(usm.AuthenticationParameters)
+	if len(digest) != snmpDigestLength {
+		return false
+	}
+	at := bytes.Index(payload, append([]byte{byte(gosnmp.OctetString), snmpDigestLength}, digest...))
+	if at < 0 {
+		return false
+	}
+	msg := bytes.Clone(payload)
+	clear(msg[at+2 : at+2+snmpDigestLength])
+
+	newHash := md5.New
+	if e.user.AuthenticationProtocol == gosnmp.SHA {
+		newHash = sha1.New
+	}
+	mac := hmac.New(newHash, e.user.SecretKey)
+	mac.Write(msg)
+	return hmac.Equal(mac.Sum(nil)[:snmpDigestLength], digest)
+}
+
+// secure sets the response to the request's security level with the user's
+// keys. Each response gets a fresh random salt; the manager decrypts with the
+// salt carried in msgPrivacyParameters.
+func (e *snmpV3Engine) secure(response *gosnmp.SnmpPacket, params *gosnmp.UsmSecurityParameters, level gosnmp.SnmpV3MsgFlags) {
+	params.AuthenticationProtocol = e.user.AuthenticationProtocol
+	params.PrivacyProtocol = e.user.PrivacyProtocol
+	params.SecretKey = e.user.SecretKey
+	params.PrivacyKey = e.user.PrivacyKey
+	params.PrivacyParameters = make([]byte, 8)
+	rand.Read(params.PrivacyParameters)
+	response.MsgFlags = level
+}
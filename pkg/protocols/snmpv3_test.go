@@ -0,0 +1,453 @@
+package protocols
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+// TestSNMPHandler_V3Contexts tests that SNMPv3 requests to one IP are answered
+// from a different MIB per context name, after engine discovery
+func TestSNMPHandler_V3Contexts(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x31}
+	deviceIP := net.ParseIP("10.0.0.31").To4()
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "chassis",
+		Type:        "switch",
+		MACAddress:  deviceMAC,
+		IPAddresses: []net.IP{deviceIP},
+		SNMPConfig: config.SNMPConfig{
+			Community: "public",
+			Contexts: []config.SNMPContext{
+				{Name: "vdc1", SysName: "chassis-vdc1"},
+				{Name: "vdc2", SysName: "chassis-vdc2"},
+			},
+		},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	frame := append(append(append([]byte{}, deviceMAC...), 0x00, 0x11, 0x22, 0x33, 0x44, 0x55), 0x08, 0x00)
+
+	query := func(engineID, contextName string) *gosnmp.SnmpPacket {
+		t.Helper()
+		req := &gosnmp.SnmpPacket{
+			Version:       gosnmp.Version3,
+			MsgFlags:      gosnmp.NoAuthNoPriv | gosnmp.Reportable,
+			SecurityModel: gosnmp.UserSecurityModel,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{
+				UserName:               "lab",
+				AuthoritativeEngineID:  engineID,
+				AuthenticationProtocol: gosnmp.NoAuth,
+				PrivacyProtocol:        gosnmp.NoPriv,
+			},
+			ContextEngineID: engineID,
+			ContextName:     contextName,
+			MsgID:           42,
+			MsgMaxSize:      65507,
+			PDUType:         gosnmp.GetRequest,
+			RequestID:       7,
+			Variables:       []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+		}
+		payload, err := req.MarshalMsg()
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+		udpLayer.Payload = payload
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5"), DstIP: deviceIP}
+		stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+
+		var resp *Packet
+		select {
+		case resp = <-stack.sendQueue:
+		default:
+			t.Fatalf("expected SNMPv3 response for context %q", contextName)
+		}
+		decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		udp, ok := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		if !ok {
+			t.Fatal("response missing UDP layer")
+		}
+		decoder := gosnmp.GoSNMP{
+			Version:            gosnmp.Version3,
+			SecurityModel:      gosnmp.UserSecurityModel,
+			MsgFlags:           gosnmp.NoAuthNoPriv,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{UserName: "lab"},
+		}
+		respSNMP, err := decoder.SnmpDecodePacket(udp.Payload)
+		if err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if respSNMP.MsgID != 42 || respSNMP.RequestID != 7 {
+			t.Errorf("response msgID/requestID = %d/%d, want 42/7", respSNMP.MsgID, respSNMP.RequestID)
+		}
+		return respSNMP
+	}
+
+	// Engine discovery: an unknown engine ID gets a report carrying ours
+	discovery := query("", "")
+	if discovery.PDUType != gosnmp.Report || len(discovery.Variables) != 1 ||
+		discovery.Variables[0].Name != "."+OIDUsmStatsUnknownEngineIDs {
+		t.Fatalf("expected usmStatsUnknownEngineIDs report, got %v %v", discovery.PDUType, discovery.Variables)
+	}
+	engineID := discovery.SecurityParameters.(*gosnmp.UsmSecurityParameters).AuthoritativeEngineID
+	if engineID != snmpEngineID(&cfg.Devices[0]) {
+		t.Fatalf("discovered engine ID %x, want %x", engineID, snmpEngineID(&cfg.Devices[0]))
+	}
+
+	sysName := func(resp *gosnmp.SnmpPacket) string {
+		t.Helper()
+		if resp.PDUType != gosnmp.GetResponse || len(resp.Variables) != 1 {
+			t.Fatalf("expected GetResponse with one varbind, got %v %v", resp.PDUType, resp.Variables)
+		}
+		switch v := resp.Variables[0].Value.(type) {
+		case string:
+			return v
+		case []byte:
+			return string(v)
+		}
+		t.Fatalf("unexpected sysName type %T", resp.Variables[0].Value)
+		return ""
+	}
+	for context, want := range map[string]string{"vdc1": "chassis-vdc1", "vdc2": "chassis-vdc2", "": "chassis"} {
+		resp := query(engineID, context)
+		if got := sysName(resp); got != want {
+			t.Errorf("context %q sysName = %q, want %q", context, got, want)
+		}
+		if resp.ContextName != context {
+			t.Errorf("context %q answered as %q", context, resp.ContextName)
+		}
+	}
+
+	unknown := query(engineID, "vdc9")
+	if unknown.PDUType != gosnmp.Report || unknown.Variables[0].Name != "."+OIDSnmpUnknownContexts {
+		t.Errorf("expected snmpUnknownContexts report, got %v %v", unknown.PDUType, unknown.Variables)
+	}
+}
+
+// serveSNMPOverUDP relays SNMP datagrams between a loopback UDP socket and the
+// stack's SNMP handler so a real gosnmp client can query the device. It
+// returns the socket's port.
+func serveSNMPOverUDP(t *testing.T, stack *Stack, device *config.Device) uint16 {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("loopback UDP unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	frame := append(append(append([]byte{}, device.MACAddress...), 0x00, 0x11, 0x22, 0x33, 0x44, 0x55), 0x08, 0x00)
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, client, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			udpLayer := &layers.UDP{SrcPort: layers.UDPPort(client.Port), DstPort: layers.UDPPort(UDPPortSNMP)}
+			udpLayer.Payload = append([]byte(nil), buf[:n]...)
+			ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5"), DstIP: device.IPAddresses[0]}
+			stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{device})
+
+			select {
+			case resp := <-stack.sendQueue:
+				decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+				if udp, ok := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP); ok {
+					_, _ = conn.WriteToUDP(udp.Payload, client)
+				}
+			default:
+			}
+		}
+	}()
+	return uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// TestSNMPHandler_V3USM tests authenticated and encrypted SNMPv3 GETs from a
+// gosnmp client, through engine discovery, for each auth/priv protocol pair
+func TestSNMPHandler_V3USM(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     config.SNMPv3Config
+		auth     gosnmp.SnmpV3AuthProtocol
+		priv     gosnmp.SnmpV3PrivProtocol
+		msgFlags gosnmp.SnmpV3MsgFlags
+	}{
+		{"SHA/AES authPriv", config.SNMPv3Config{User: "auditor", AuthProtocol: config.SNMPv3AuthSHA, AuthKey: "auth-secret", PrivProtocol: config.SNMPv3PrivAES, PrivKey: "priv-secret"},
+			gosnmp.SHA, gosnmp.AES, gosnmp.AuthPriv},
+		{"MD5/DES authPriv", config.SNMPv3Config{User: "auditor", AuthProtocol: config.SNMPv3AuthMD5, AuthKey: "auth-secret", PrivProtocol: config.SNMPv3PrivDES, PrivKey: "priv-secret"},
+			gosnmp.MD5, gosnmp.DES, gosnmp.AuthPriv},
+		{"SHA/AES user at authNoPriv", config.SNMPv3Config{User: "auditor", AuthProtocol: config.SNMPv3AuthSHA, AuthKey: "auth-secret", PrivProtocol: config.SNMPv3PrivAES, PrivKey: "priv-secret"},
+			gosnmp.SHA, gosnmp.NoPriv, gosnmp.AuthNoPriv},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := tt.user
+			cfg := &config.Config{Devices: []config.Device{{
+				Name:        "firewall",
+				Type:        "firewall",
+				MACAddress:  net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x32},
+				IPAddresses: []net.IP{net.ParseIP("10.0.0.32").To4()},
+				SNMPConfig:  config.SNMPConfig{Community: "public", V3: &user},
+			}}}
+			stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+			port := serveSNMPOverUDP(t, stack, &cfg.Devices[0])
+
+			client := &gosnmp.GoSNMP{
+				Target:        "127.0.0.1",
+				Port:          port,
+				Version:       gosnmp.Version3,
+				Timeout:       2 * time.Second,
+				SecurityModel: gosnmp.UserSecurityModel,
+				MsgFlags:      tt.msgFlags,
+				SecurityParameters: &gosnmp.UsmSecurityParameters{
+					UserName:                 "auditor",
+					AuthenticationProtocol:   tt.auth,
+					AuthenticationPassphrase: "auth-secret",
+					PrivacyProtocol:          tt.priv,
+					PrivacyPassphrase:        "priv-secret",
+				},
+			}
+			if err := client.Connect(); err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer client.Conn.Close()
+
+			result, err := client.Get([]string{".1.3.6.1.2.1.1.5.0"})
+			if err != nil {
+				t.Fatalf("SNMPv3 GET failed: %v", err)
+			}
+			if result.PDUType != gosnmp.GetResponse || len(result.Variables) != 1 {
+				t.Fatalf("expected GetResponse with one varbind, got %v %v", result.PDUType, result.Variables)
+			}
+			if got := string(result.Variables[0].Value.([]byte)); got != "firewall" {
+				t.Errorf("sysName = %q, want %q", got, "firewall")
+			}
+			if result.MsgFlags&gosnmp.AuthPriv != tt.msgFlags {
+				t.Errorf("response security level = %v, want %v", result.MsgFlags&gosnmp.AuthPriv, tt.msgFlags)
+			}
+		})
+	}
+}
+
+// TestSNMPHandler_V3USMReports tests that SNMPv3 requests failing a USM check
+// get the matching Report PDU instead of being dropped
+func TestSNMPHandler_V3USMReports(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x33}
+	deviceIP := net.ParseIP("10.0.0.33").To4()
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "router",
+		Type:        "router",
+		MACAddress:  deviceMAC,
+		IPAddresses: []net.IP{deviceIP},
+		SNMPConfig: config.SNMPConfig{
+			Community: "public",
+			V3:        &config.SNMPv3Config{User: "auditor", AuthProtocol: config.SNMPv3AuthSHA, AuthKey: "auth-secret"},
+		},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	frame := append(append(append([]byte{}, deviceMAC...), 0x00, 0x11, 0x22, 0x33, 0x44, 0x55), 0x08, 0x00)
+	engineID := snmpEngineID(&cfg.Devices[0])
+	boots, engineTime := stack.getSNMPAgent(&cfg.Devices[0]).EngineState()
+
+	tests := []struct {
+		name       string
+		userName   string
+		authKey    string
+		msgFlags   gosnmp.SnmpV3MsgFlags
+		privacy    gosnmp.SnmpV3PrivProtocol
+		engineTime int
+		want       string
+	}{
+		{"unknown user", "intruder", "auth-secret", gosnmp.AuthNoPriv, gosnmp.NoPriv, engineTime, OIDUsmStatsUnknownUserNames},
+		{"wrong digest", "auditor", "wrong-secret", gosnmp.AuthNoPriv, gosnmp.NoPriv, engineTime, OIDUsmStatsWrongDigests},
+		{"noAuthNoPriv", "auditor", "", gosnmp.NoAuthNoPriv, gosnmp.NoPriv, engineTime, OIDUsmStatsUnsupportedSecLevels},
+		{"authPriv without priv key", "auditor", "auth-secret", gosnmp.AuthPriv, gosnmp.AES, engineTime, OIDUsmStatsUnsupportedSecLevels},
+		{"outside time window", "auditor", "auth-secret", gosnmp.AuthNoPriv, gosnmp.NoPriv, engineTime + 1000, OIDUsmStatsNotInTimeWindows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &gosnmp.UsmSecurityParameters{
+				UserName:                 tt.userName,
+				AuthoritativeEngineID:    engineID,
+				AuthoritativeEngineBoots: uint32(boots),
+				AuthoritativeEngineTime:  uint32(tt.engineTime),
+				AuthenticationProtocol:   gosnmp.NoAuth,
+				PrivacyProtocol:          tt.privacy,
+				PrivacyPassphrase:        "priv-secret",
+				PrivacyParameters:        make([]byte, 8),
+			}
+			if tt.msgFlags != gosnmp.NoAuthNoPriv {
+				params.AuthenticationProtocol = gosnmp.SHA
+				params.AuthenticationPassphrase = tt.authKey
+			}
+			if err := params.InitSecurityKeys(); err != nil {
+				t.Fatalf("init keys: %v", err)
+			}
+			req := &gosnmp.SnmpPacket{
+				Version:            gosnmp.Version3,
+				MsgFlags:           tt.msgFlags | gosnmp.Reportable,
+				SecurityModel:      gosnmp.UserSecurityModel,
+				SecurityParameters: params,
+				ContextEngineID:    engineID,
+				MsgID:              99,
+				MsgMaxSize:         65507,
+				PDUType:            gosnmp.GetRequest,
+				RequestID:          11,
+				Variables:          []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+			}
+			payload, err := req.MarshalMsg()
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+			udpLayer.Payload = payload
+			ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5"), DstIP: deviceIP}
+			stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+
+			var resp *Packet
+			select {
+			case resp = <-stack.sendQueue:
+			default:
+				t.Fatal("expected a Report PDU, request was dropped")
+			}
+			decoded := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+			udp := decoded.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			decoder := gosnmp.GoSNMP{
+				Version:            gosnmp.Version3,
+				SecurityModel:      gosnmp.UserSecurityModel,
+				SecurityParameters: &gosnmp.UsmSecurityParameters{UserName: tt.userName, AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "auth-secret"},
+			}
+			report, err := decoder.SnmpDecodePacket(udp.Payload)
+			if err != nil {
+				t.Fatalf("decode report: %v", err)
+			}
+			if report.PDUType != gosnmp.Report || len(report.Variables) != 1 || report.Variables[0].Name != "."+tt.want {
+				t.Fatalf("expected %s report, got %v %v", tt.want, report.PDUType, report.Variables)
+			}
+			if report.MsgID != 99 {
+				t.Errorf("report msgID = %d, want 99", report.MsgID)
+			}
+		})
+	}
+}
+
+// TestSNMPHandler_V3EngineTime tests that the configured engine state is
+// reported to managers, that a request with a stale snmpEngineTime or from
+// before a reboot gets the notInTimeWindows report, and that the report
+// resynchronizes the manager
+func TestSNMPHandler_V3EngineTime(t *testing.T) {
+	deviceMAC := net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x34}
+	deviceIP := net.ParseIP("10.0.0.34").To4()
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "router",
+		Type:        "router",
+		MACAddress:  deviceMAC,
+		IPAddresses: []net.IP{deviceIP},
+		SNMPConfig: config.SNMPConfig{
+			Community:   "public",
+			V3:          &config.SNMPv3Config{User: "auditor", AuthProtocol: config.SNMPv3AuthSHA, AuthKey: "auth-secret"},
+			EngineBoots: 7,
+			EngineTime:  time.Hour,
+		},
+	}}}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	frame := append(append(append([]byte{}, deviceMAC...), 0x00, 0x11, 0x22, 0x33, 0x44, 0x55), 0x08, 0x00)
+	engineID := snmpEngineID(&cfg.Devices[0])
+
+	boots, engineTime := stack.getSNMPAgent(&cfg.Devices[0]).EngineState()
+	if boots != 7 || engineTime < 3600 || engineTime > 3601 {
+		t.Fatalf("EngineState() = %d, %d; want 7, 3600", boots, engineTime)
+	}
+
+	send := func(boots, engineTime uint32) *gosnmp.SnmpPacket {
+		t.Helper()
+		params := &gosnmp.UsmSecurityParameters{
+			UserName:                 "auditor",
+			AuthoritativeEngineID:    engineID,
+			AuthoritativeEngineBoots: boots,
+			AuthoritativeEngineTime:  engineTime,
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: "auth-secret",
+			PrivacyProtocol:          gosnmp.NoPriv,
+		}
+		if err := params.InitSecurityKeys(); err != nil {
+			t.Fatalf("init keys: %v", err)
+		}
+		req := &gosnmp.SnmpPacket{
+			Version:            gosnmp.Version3,
+			MsgFlags:           gosnmp.AuthNoPriv | gosnmp.Reportable,
+			SecurityModel:      gosnmp.UserSecurityModel,
+			SecurityParameters: params,
+			ContextEngineID:    engineID,
+			MsgID:              100,
+			MsgMaxSize:         65507,
+			PDUType:            gosnmp.GetRequest,
+			RequestID:          12,
+			Variables:          []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+		}
+		payload, err := req.MarshalMsg()
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+		udpLayer.Payload = payload
+		ipLayer := &layers.IPv4{SrcIP: net.ParseIP("10.0.0.5"), DstIP: deviceIP}
+		stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, []*config.Device{&cfg.Devices[0]})
+
+		var resp *Packet
+		select {
+		case resp = <-stack.sendQueue:
+		default:
+			t.Fatal("expected a response, request was dropped")
+		}
+		udp := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default).Layer(layers.LayerTypeUDP).(*layers.UDP)
+		decoder := gosnmp.GoSNMP{
+			Version:            gosnmp.Version3,
+			SecurityModel:      gosnmp.UserSecurityModel,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{UserName: "auditor", AuthenticationProtocol: gosnmp.SHA, AuthenticationPassphrase: "auth-secret"},
+		}
+		result, err := decoder.SnmpDecodePacket(udp.Payload)
+		if err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return result
+	}
+	isTimeWindowReport := func(result *gosnmp.SnmpPacket) bool {
+		return result.PDUType == gosnmp.Report && len(result.Variables) == 1 &&
+			result.Variables[0].Name == "."+OIDUsmStatsNotInTimeWindows
+	}
+
+	if result := send(7, uint32(engineTime)); result.PDUType != gosnmp.GetResponse {
+		t.Fatalf("expected a GetResponse in the time window, got %v %v", result.PDUType, result.Variables)
+	}
+
+	// A manager whose clock fell behind resyncs from the report
+	result := send(7, uint32(engineTime-snmpTimeWindow-10))
+	if !isTimeWindowReport(result) {
+		t.Fatalf("expected a notInTimeWindows report for a stale engineTime, got %v %v", result.PDUType, result.Variables)
+	}
+	usm := result.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if usm.AuthoritativeEngineBoots != 7 || usm.AuthoritativeEngineTime < 3600 {
+		t.Errorf("report carries engine state %d/%d, want 7/3600", usm.AuthoritativeEngineBoots, usm.AuthoritativeEngineTime)
+	}
+
+	if err := stack.RebootDevice("router"); err != nil {
+		t.Fatalf("RebootDevice: %v", err)
+	}
+	if result := send(7, uint32(engineTime)); !isTimeWindowReport(result) {
+		t.Fatalf("expected a notInTimeWindows report for the boots before the reboot, got %v %v", result.PDUType, result.Variables)
+	}
+	if boots, engineTime = stack.getSNMPAgent(&cfg.Devices[0]).EngineState(); boots != 8 || engineTime > 1 {
+		t.Fatalf("EngineState() after reboot = %d, %d; want 8, 0", boots, engineTime)
+	}
+	if result := send(8, uint32(engineTime)); result.PDUType != gosnmp.GetResponse {
+		t.Errorf("expected a GetResponse after resync, got %v %v", result.PDUType, result.Variables)
+	}
+}
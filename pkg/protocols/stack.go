@@ -2,14 +2,17 @@ package protocols
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"sync"
 	"time"
 
 	"github.com/krisarmstrong/niac-go/pkg/capture"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/errors"
+	"github.com/krisarmstrong/niac-go/pkg/flow"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
 	"github.com/krisarmstrong/niac-go/pkg/snmp"
+	"github.com/krisarmstrong/niac-go/pkg/stats"
 )
 
 const (
@@ -53,6 +56,7 @@ type Stack struct {
 	dhcpv6Handler  *DHCPv6Handler
 	httpHandler    *HTTPHandler
 	ftpHandler     *FTPHandler
+	bgpHandler     *BGPHandler
 	netbiosHandler *NetBIOSHandler
 	stpHandler     *STPHandler
 	lldpHandler    *LLDPHandler
@@ -61,9 +65,17 @@ type Stack struct {
 	fdpHandler     *FDPHandler
 	snmpHandler    *SNMPHandler
 	neighbors      *neighborTable
+	fdb            *fdbTable    // MACs learned from received traffic (BRIDGE-MIB)
+	arp            *arpTable    // IP-to-MAC bindings learned from ARP/ND (IP-MIB)
+	fragments      *reassembler // Partial IPv4/IPv6 datagrams awaiting fragments
+	ipv4ID         uint32       // Identification of the last fragmented datagram sent
 
 	// Statistics
-	stats *Statistics
+	stats       *Statistics
+	deviceStats *deviceStatsTable // Per-device traffic counters
+
+	// Recent packets that failed to parse or made a handler fail
+	errorPackets errorPacketBuffer
 
 	// Control
 	running  bool
@@ -72,7 +84,27 @@ type Stack struct {
 
 	debugConfig  *logging.DebugConfig
 	snmpAgents   map[*config.Device]*snmp.Agent
+	snmpV3       map[*config.Device]*snmpV3Engine
+	walkLimits   snmp.WalkLimits // Caps on the walk files agents load
 	errorManager *errors.StateManager
+
+	// Power state by device name (devices absent from the map are powered on)
+	powerMu    sync.RWMutex
+	poweredOff map[string]bool
+	disabled   map[string]bool          // Devices disabled through SetDeviceEnabled
+	booting    map[string]*time.Timer   // Devices still in their boot delay
+	bootStages map[string]*bootProgress // Devices with a boot sequence, by name
+
+	// Optional sFlow export of sent and received frames, replaced when a
+	// reload changes flow_export
+	flowMu       sync.RWMutex
+	flowExporter *flow.Exporter
+
+	// Seed for reproducible randomized behavior (guarded by mu)
+	randomSeed uint64
+
+	// Run ID overriding run_marker.run_id (guarded by mu)
+	runID string
 }
 
 // Statistics holds protocol statistics
@@ -86,8 +118,23 @@ type Statistics struct {
 	ICMPReplies     uint64
 	DNSQueries      uint64
 	DHCPRequests    uint64
+	DHCPRetransmits uint64 // DISCOVER retransmissions answered with the original Offer
 	SNMPQueries     uint64
 	Errors          uint64
+
+	SNMPDenied uint64 // SNMP requests dropped by allowed_managers
+
+	// Simulated response latency
+	DelayedResponses  uint64 // Responses held back by a latency model
+	AddedLatencyNanos uint64 // Total latency added to those responses
+
+	TCPConnectionsRefused uint64 // Connections refused by TCP service limits
+
+	MalformedFramesSent uint64 // Deliberately corrupt frames sent by malformed_frames
+
+	// Learned neighbor table (filled from the table by GetStats)
+	Neighbors        uint64 // Current table size
+	NeighborsEvicted uint64 // Neighbors dropped to respect max_neighbors
 }
 
 // NewStack creates a new protocol stack
@@ -102,11 +149,19 @@ func NewStack(captureEngine *capture.Engine, cfg *config.Config, debugConfig *lo
 		sendQueue:    make(chan *Packet, bufferSize),
 		recvQueue:    make(chan *Packet, bufferSize),
 		stats:        &Statistics{},
+		deviceStats:  newDeviceStatsTable(),
 		stopChan:     make(chan struct{}),
 		debugConfig:  debugConfig,
 		snmpAgents:   make(map[*config.Device]*snmp.Agent),
+		snmpV3:       make(map[*config.Device]*snmpV3Engine),
 		neighbors:    newNeighborTable(),
+		fdb:          newFDBTable(),
+		arp:          newARPTable(),
+		fragments:    newReassembler(),
 		errorManager: errors.NewStateManager(),
+		poweredOff:   make(map[string]bool),
+		disabled:     make(map[string]bool),
+		randomSeed:   rand.Uint64(),
 	}
 
 	// Create protocol handlers
@@ -122,6 +177,7 @@ func NewStack(captureEngine *capture.Engine, cfg *config.Config, debugConfig *lo
 	stack.dhcpv6Handler = NewDHCPv6Handler(stack)
 	stack.httpHandler = NewHTTPHandler(stack)
 	stack.ftpHandler = NewFTPHandler(stack)
+	stack.bgpHandler = NewBGPHandler(stack)
 	stack.netbiosHandler = NewNetBIOSHandler(stack, debugConfig.GetProtocolLevel(logging.ProtocolNetBIOS))
 	stack.stpHandler = NewSTPHandler(stack, debugConfig.GetProtocolLevel(logging.ProtocolSTP))
 	stack.lldpHandler = NewLLDPHandler(stack)
@@ -133,6 +189,11 @@ func NewStack(captureEngine *capture.Engine, cfg *config.Config, debugConfig *lo
 	// Initialize device table from config (requires handlers for DHCP/SNMP setup)
 	stack.initializeDevices(cfg)
 
+	// Export sampled traffic to a flow collector when configured
+	if cfg != nil {
+		stack.flowExporter = newFlowExporter(cfg.FlowExport)
+	}
+
 	return stack
 }
 
@@ -148,6 +209,14 @@ func (s *Stack) initializeDevices(cfg *config.Config) {
 		s.devices.Reset()
 	}
 	s.snmpAgents = make(map[*config.Device]*snmp.Agent)
+	s.snmpV3 = make(map[*config.Device]*snmpV3Engine)
+	s.walkLimits = snmp.NewWalkLimits(cfg.WalkLimits)
+	if s.neighbors != nil {
+		s.neighbors.setMaxEntries(cfg.DiscoveryProtocols.NeighborTableSize())
+	}
+	if s.fdb != nil {
+		s.configureFDB(cfg)
+	}
 	if s.dhcpHandler != nil {
 		s.dhcpHandler.Reset()
 	}
@@ -161,14 +230,11 @@ func (s *Stack) initializeDevices(cfg *config.Config) {
 	for i := range cfg.Devices {
 		device := &cfg.Devices[i]
 
-		// Add by MAC
-		if len(device.MACAddress) > 0 {
-			s.devices.AddByMAC(device.MACAddress, device)
-		}
-
-		// Add by IP addresses
-		for _, ip := range device.IPAddresses {
-			s.devices.AddByIP(ip, device)
+		// Index by MAC and IP unless the device is powered off, disabled or
+		// booting. A reloaded device keeps its runtime enabled state.
+		s.syncDeviceEnabled(device)
+		if s.isDeviceUp(device.Name) {
+			s.addDeviceToTable(device)
 		}
 
 		// Configure DHCP server if device has DHCP config
@@ -197,11 +263,51 @@ func (s *Stack) initializeDevices(cfg *config.Config) {
 				device.DHCPConfig.BootfileName,
 				device.DHCPConfig.VendorSpecific,
 			)
+			s.dhcpHandler.SetClientClasses(device.DHCPConfig.ClientClasses)
+			s.dhcpHandler.SetAlwaysBroadcast(device.DHCPConfig.AlwaysBroadcast)
+			if device.DHCPConfig.RetransmitWindow > 0 {
+				s.dhcpHandler.SetRetransmitWindow(device.DHCPConfig.RetransmitWindow)
+			}
+			s.dhcpHandler.SetLeaseGrace(device.DHCPConfig.LeaseGrace)
 
 			if s.debugConfig.GetGlobal() >= 1 {
 				fmt.Printf("Configured DHCP server for device %s\n", device.Name)
 			}
 		}
+		if device.DHCPConfig != nil && len(device.IPAddresses) > 0 {
+			dhcp := device.DHCPConfig
+			if len(dhcp.SNTPServersV6) > 0 || len(dhcp.NTPServersV6) > 0 || len(dhcp.SIPServersV6) > 0 || len(dhcp.SIPDomainsV6) > 0 {
+				s.dhcpv6Handler.SetAdvancedOptions(dhcp.SNTPServersV6, dhcp.NTPServersV6, dhcp.SIPServersV6, dhcp.SIPDomainsV6)
+			}
+		}
+		if device.DHCPv6Config != nil && device.DHCPv6Config.Enabled {
+			dhcpv6 := device.DHCPv6Config
+			s.dhcpv6Handler.SetPools(dhcpv6.Pools, device.IPAddresses)
+			s.dhcpv6Handler.SetPrefixPool(dhcpv6.DelegatedPrefixes)
+			if dhcpv6.PreferredLifetime > 0 && dhcpv6.ValidLifetime > 0 {
+				s.dhcpv6Handler.SetLifetimes(time.Duration(dhcpv6.PreferredLifetime)*time.Second,
+					time.Duration(dhcpv6.ValidLifetime)*time.Second)
+			}
+			s.dhcpv6Handler.SetServerConfig(dhcpv6.DNSServers, dhcpv6.DomainList)
+			if len(dhcpv6.SNTPServers) > 0 || len(dhcpv6.NTPServers) > 0 || len(dhcpv6.SIPServers) > 0 || len(dhcpv6.SIPDomains) > 0 {
+				s.dhcpv6Handler.SetAdvancedOptions(dhcpv6.SNTPServers, dhcpv6.NTPServers, dhcpv6.SIPServers, dhcpv6.SIPDomains)
+			}
+			s.dhcpv6Handler.SetLeaseGrace(dhcpv6.LeaseGrace)
+
+			if s.debugConfig.GetGlobal() >= 1 {
+				fmt.Printf("Configured DHCPv6 server for device %s\n", device.Name)
+			}
+		}
+
+		// Load DNS records; PTR records are added with them
+		if device.DNSConfig != nil {
+			for _, record := range device.DNSConfig.ForwardRecords {
+				s.dnsHandler.AddRecord(record.Name, record.IP)
+			}
+			if len(device.DNSConfig.Forwarders) > 0 {
+				s.dnsHandler.SetForwarders(device.DNSConfig.Forwarders, device.DNSConfig.CacheTTL)
+			}
+		}
 
 		s.initSNMPAgent(device)
 	}
@@ -245,6 +351,15 @@ func (s *Stack) Start() error {
 	s.edpHandler.Start()
 	s.fdpHandler.Start()
 	s.startNeighborCleanupLoop()
+	s.startFragmentCleanupLoop()
+	s.startRuntLoop()
+	s.startMalformedLoop()
+	s.startBGPTimerLoop()
+	s.startBootDelays()
+
+	if exporter := s.currentFlowExporter(); exporter != nil {
+		exporter.Start()
+	}
 
 	if s.debugConfig.GetGlobal() >= 1 {
 		fmt.Println("Protocol stack started")
@@ -269,6 +384,11 @@ func (s *Stack) Stop() {
 
 	close(s.stopChan)
 	s.wg.Wait()
+	s.stopBootDelays()
+
+	if exporter := s.currentFlowExporter(); exporter != nil {
+		exporter.Stop()
+	}
 
 	if s.debugConfig.GetGlobal() >= 1 {
 		fmt.Println("Protocol stack stopped")
@@ -310,13 +430,17 @@ func (s *Stack) receiveThread() {
 				s.stats.mu.Lock()
 				s.stats.Errors++
 				s.stats.mu.Unlock()
+				s.recordErrorPacket(&Packet{Buffer: data, Length: len(data), SerialNumber: serialNum, Interface: s.capture.InterfaceName()}, "ethernet", err)
 				continue
 			}
+			pkt.Interface = s.capture.InterfaceName()
 
 			s.stats.mu.Lock()
 			s.stats.PacketsReceived++
 			s.stats.mu.Unlock()
 
+			s.observeFlow(data, true)
+
 			// Queue for decoding
 			select {
 			case s.recvQueue <- pkt:
@@ -348,8 +472,14 @@ func (s *Stack) decodeThread() {
 
 // decodePacket decodes a packet and routes to appropriate handler
 func (s *Stack) decodePacket(pkt *Packet) {
-	// Check for STP (multicast MAC 01:80:C2:00:00:00)
+	s.learnSource(pkt)
+
 	dstMAC := pkt.GetDestMAC()
+	if device := s.devices.GetByMAC(dstMAC); device != nil {
+		s.deviceStats.received(device.Name)
+	}
+
+	// Check for STP (multicast MAC 01:80:C2:00:00:00)
 	if len(dstMAC) == 6 && dstMAC[0] == 0x01 && dstMAC[1] == 0x80 &&
 		dstMAC[2] == 0xC2 && dstMAC[3] == 0x00 && dstMAC[4] == 0x00 && dstMAC[5] == 0x00 {
 		s.stpHandler.HandlePacket(pkt)
@@ -442,7 +572,14 @@ func (s *Stack) sendPacket(pkt *Packet) {
 		pkt.Length = len(pkt.Buffer)
 	}
 
-	err := s.capture.SendPacket(pkt.Buffer[:pkt.Length])
+	device, _ := pkt.Device.(*config.Device)
+	frame := pkt.Buffer[:pkt.Length]
+	if !pkt.Malformed {
+		// Marking would rewrite a corrupt frame: setDSCP repairs a bad IPv4
+		// header checksum and the trailer extends a truncated packet
+		frame = s.markFrame(frame, device)
+	}
+	err := s.capture.SendPacket(frame)
 	if err != nil {
 		if s.debugConfig.GetGlobal() >= 2 {
 			fmt.Printf("Error sending packet sn=%d: %v\n", pkt.SerialNumber, err)
@@ -450,12 +587,23 @@ func (s *Stack) sendPacket(pkt *Packet) {
 		s.stats.mu.Lock()
 		s.stats.Errors++
 		s.stats.mu.Unlock()
+		if sender := s.sendingDevice(pkt); sender != nil {
+			s.deviceStats.failed(sender.Name)
+		}
 		return
 	}
 
 	s.stats.mu.Lock()
 	s.stats.PacketsSent++
+	if pkt.Malformed {
+		s.stats.MalformedFramesSent++
+	}
 	s.stats.mu.Unlock()
+	if sender := s.sendingDevice(pkt); sender != nil {
+		s.deviceStats.sent(sender.Name, frame)
+	}
+
+	s.observeFlow(frame, false)
 
 	if s.debugConfig.GetGlobal() >= 3 {
 		fmt.Printf("Sent packet sn=%d length=%d\n", pkt.SerialNumber, pkt.Length)
@@ -490,8 +638,12 @@ func (s *Stack) babbleThread() {
 	}
 }
 
-// Send queues a packet for sending
+// Send queues a packet for sending. Packets from a device whose interface is
+// shut by SNMP are dropped (see dropOnLinkDown).
 func (s *Stack) Send(pkt *Packet) {
+	if s.dropOnLinkDown(pkt) {
+		return
+	}
 	select {
 	case s.sendQueue <- pkt:
 	default:
@@ -523,7 +675,10 @@ func (s *Stack) GetDevices() *DeviceTable {
 	return s.devices
 }
 
-// ReloadConfig applies a new configuration to the running stack.
+// ReloadConfig applies a new configuration to the running stack. The device
+// table, DHCP, DNS and SNMP handlers are rebuilt in place from cfg, as is the
+// flow exporter if flow_export changed; capture and the protocol goroutines
+// keep running.
 func (s *Stack) ReloadConfig(cfg *config.Config) error {
 	if cfg == nil {
 		return fmt.Errorf("reload config: nil config")
@@ -532,7 +687,12 @@ func (s *Stack) ReloadConfig(cfg *config.Config) error {
 	s.reloadMu.Lock()
 	defer s.reloadMu.Unlock()
 
+	var previous *config.FlowExportConfig
+	if old := s.currentConfig(); old != nil {
+		previous = old.FlowExport
+	}
 	s.initializeDevices(cfg)
+	s.reloadFlowExporter(previous, cfg.FlowExport)
 	if s.neighbors != nil {
 		s.neighbors.reset()
 	}
@@ -543,11 +703,81 @@ func (s *Stack) ReloadConfig(cfg *config.Config) error {
 	return nil
 }
 
+// SendQueueDepth returns the number of packets waiting to be sent and the
+// queue capacity.
+func (s *Stack) SendQueueDepth() (depth, capacity int) {
+	return len(s.sendQueue), cap(s.sendQueue)
+}
+
 // GetStats returns current statistics (copy without mutex)
 func (s *Stack) GetStats() Statistics {
 	s.stats.mu.RLock()
 	defer s.stats.mu.RUnlock()
+	return s.statsLocked()
+}
 
+// Counters copies the counters reported as Prometheus metrics out of a
+// statistics snapshot
+func (st *Statistics) Counters() stats.StackCounters {
+	return stats.StackCounters{
+		PacketsSent:           st.PacketsSent,
+		PacketsReceived:       st.PacketsReceived,
+		SNMPQueries:           st.SNMPQueries,
+		SNMPDenied:            st.SNMPDenied,
+		Errors:                st.Errors,
+		Neighbors:             st.Neighbors,
+		NeighborsEvicted:      st.NeighborsEvicted,
+		ARPRequests:           st.ARPRequests,
+		ARPReplies:            st.ARPReplies,
+		ICMPRequests:          st.ICMPRequests,
+		ICMPReplies:           st.ICMPReplies,
+		DNSQueries:            st.DNSQueries,
+		DHCPRequests:          st.DHCPRequests,
+		DHCPRetransmits:       st.DHCPRetransmits,
+		DelayedResponses:      st.DelayedResponses,
+		AddedLatency:          time.Duration(st.AddedLatencyNanos),
+		TCPConnectionsRefused: st.TCPConnectionsRefused,
+		MalformedFramesSent:   st.MalformedFramesSent,
+	}
+}
+
+// ResetStats zeroes all counters and returns their values from just before
+// the reset. The neighbor table size is a gauge and is not reset.
+func (s *Stack) ResetStats() Statistics {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	// Deferred, so the counters are zeroed after the snapshot is taken
+	defer s.zeroStatsLocked()
+	return s.statsLocked()
+}
+
+// zeroStatsLocked zeroes the counters; the caller holds s.stats.mu
+func (s *Stack) zeroStatsLocked() {
+	s.stats.PacketsReceived = 0
+	s.stats.PacketsSent = 0
+	s.stats.ARPRequests = 0
+	s.stats.ARPReplies = 0
+	s.stats.ICMPRequests = 0
+	s.stats.ICMPReplies = 0
+	s.stats.DNSQueries = 0
+	s.stats.DHCPRequests = 0
+	s.stats.DHCPRetransmits = 0
+	s.stats.SNMPQueries = 0
+	s.stats.Errors = 0
+	s.stats.SNMPDenied = 0
+	s.stats.DelayedResponses = 0
+	s.stats.AddedLatencyNanos = 0
+	s.stats.TCPConnectionsRefused = 0
+	s.stats.MalformedFramesSent = 0
+	if s.neighbors != nil {
+		s.neighbors.evicted.Store(0)
+	}
+	s.deviceStats.reset()
+}
+
+// statsLocked copies the statistics; the caller holds s.stats.mu
+func (s *Stack) statsLocked() Statistics {
 	// Return copy of data without mutex
 	return Statistics{
 		PacketsReceived: s.stats.PacketsReceived,
@@ -558,8 +788,21 @@ func (s *Stack) GetStats() Statistics {
 		ICMPReplies:     s.stats.ICMPReplies,
 		DNSQueries:      s.stats.DNSQueries,
 		DHCPRequests:    s.stats.DHCPRequests,
+		DHCPRetransmits: s.stats.DHCPRetransmits,
 		SNMPQueries:     s.stats.SNMPQueries,
 		Errors:          s.stats.Errors,
+
+		SNMPDenied: s.stats.SNMPDenied,
+
+		DelayedResponses:  s.stats.DelayedResponses,
+		AddedLatencyNanos: s.stats.AddedLatencyNanos,
+
+		TCPConnectionsRefused: s.stats.TCPConnectionsRefused,
+
+		MalformedFramesSent: s.stats.MalformedFramesSent,
+
+		Neighbors:        uint64(s.NeighborCount()),
+		NeighborsEvicted: s.neighborsEvicted(),
 	}
 }
 
@@ -570,24 +813,66 @@ func (s *Stack) initSNMPAgent(device *config.Device) {
 
 	debugLevel := s.debugConfig.GetProtocolLevel(logging.ProtocolSNMP)
 	agent := snmp.NewAgent(device, debugLevel)
+	agent.SetErrorStateManager(s.errorManager)
+	agent.SetWalkLimits(s.walkLimits)
 
 	if device.SNMPConfig.WalkFile != "" {
 		if err := agent.LoadWalkFile(device.SNMPConfig.WalkFile); err != nil && debugLevel >= 1 {
 			fmt.Printf("SNMP: failed to load walk file for %s: %v\n", device.Name, err)
 		}
 	}
+	if files := device.SNMPConfig.WalkFiles; len(files) > 0 {
+		if err := agent.LoadWalkFiles(files); err != nil && debugLevel >= 1 {
+			fmt.Printf("SNMP: failed to load walk directory for %s: %v\n", device.Name, err)
+		}
+	}
+	if series := device.SNMPConfig.WalkSeries; series != nil {
+		if err := agent.LoadWalkSeries(series.Files, series.Interval, series.Loop); err != nil && debugLevel >= 1 {
+			fmt.Printf("SNMP: failed to load walk series for %s: %v\n", device.Name, err)
+		}
+	}
+
+	// Live packet counters instead of static walk file values
+	s.registerStackCounters(agent)
+	s.registerBridgeMIB(agent, device)
+	s.registerIPNetToMedia(agent, device)
+
+	// Attach a trap sender so simulated reboots announce themselves with coldStart
+	if device.SNMPConfig.Traps != nil && device.SNMPConfig.Traps.Enabled && len(device.IPAddresses) > 0 {
+		if ts, err := snmp.NewTrapSender(device.Name, device.IPAddresses[0], device.SNMPConfig.Traps, debugLevel); err == nil {
+			agent.SetTrapSender(ts)
+		} else if debugLevel >= 1 {
+			fmt.Printf("SNMP: trap sender unavailable for %s: %v\n", device.Name, err)
+		}
+	}
 
 	s.snmpAgents[device] = agent
+	s.initSNMPv3(device, agent)
+}
+
+// SNMPWalkOIDs returns the number of OIDs loaded from walk files across every
+// device's SNMP agent.
+func (s *Stack) SNMPWalkOIDs() int {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	total := 0
+	for _, agent := range s.snmpAgents {
+		total += agent.WalkOIDCount()
+	}
+	return total
 }
 
 func snmpEnabled(cfg config.SNMPConfig) bool {
-	if cfg.Community != "" || cfg.WalkFile != "" || cfg.SysName != "" ||
+	if cfg.Community != "" || cfg.WalkFile != "" || len(cfg.WalkFiles) > 0 || cfg.WalkSeries != nil || cfg.SysName != "" ||
 		cfg.SysDescr != "" || cfg.SysContact != "" || cfg.SysLocation != "" {
 		return true
 	}
 	if cfg.Traps != nil && cfg.Traps.Enabled {
 		return true
 	}
+	if len(cfg.Communities) > 0 || cfg.HostResources != nil || len(cfg.Contexts) > 0 {
+		return true
+	}
 	return false
 }
 
@@ -616,6 +901,10 @@ func (s *Stack) IncrementStat(stat string) {
 		s.stats.DNSQueries++
 	case "dhcp_requests":
 		s.stats.DHCPRequests++
+	case "dhcp_retransmits":
+		s.stats.DHCPRetransmits++
+	case "tcp_connections_refused":
+		s.stats.TCPConnectionsRefused++
 	}
 }
 
@@ -649,6 +938,15 @@ func (s *Stack) GetDNSHandler() *DNSHandler {
 	return s.dnsHandler
 }
 
+// reachesDevice reports whether device is on the segment of the interface
+// pkt arrived on (see config.InterfaceScope). Requests from another segment
+// are not answered, as a real host on a separate L2 segment would not hear
+// them.
+func (s *Stack) reachesDevice(pkt *Packet, device *config.Device) bool {
+	cfg := s.currentConfig()
+	return cfg == nil || cfg.InterfaceScope.Reaches(pkt.Interface, device)
+}
+
 func (s *Stack) currentConfig() *config.Config {
 	s.configMu.RLock()
 	defer s.configMu.RUnlock()
@@ -663,16 +961,44 @@ func (s *Stack) GetNeighbors() []NeighborRecord {
 	return s.neighbors.list()
 }
 
+// NeighborsVersion returns a counter that changes whenever a discovered
+// neighbor appears, changes or expires. Periodic refreshes of known neighbors
+// do not change it.
+func (s *Stack) NeighborsVersion() uint64 {
+	if s.neighbors == nil {
+		return 0
+	}
+	return s.neighbors.version.Load()
+}
+
+// NeighborCount returns the number of entries in the learned neighbor table,
+// including expired ones the background sweep has not removed yet.
+func (s *Stack) NeighborCount() int {
+	if s.neighbors == nil {
+		return 0
+	}
+	return s.neighbors.len()
+}
+
+func (s *Stack) neighborsEvicted() uint64 {
+	if s.neighbors == nil {
+		return 0
+	}
+	return s.neighbors.evicted.Load()
+}
+
 // GetErrorManager returns the error state manager
 func (s *Stack) GetErrorManager() *errors.StateManager {
 	return s.errorManager
 }
 
-func (s *Stack) recordNeighbor(entry NeighborRecord) {
+// recordNeighbor adds or refreshes a learned neighbor and reports whether it
+// was new.
+func (s *Stack) recordNeighbor(entry NeighborRecord) bool {
 	if s.neighbors == nil {
-		return
+		return false
 	}
-	s.neighbors.upsert(entry)
+	return s.neighbors.upsert(entry)
 }
 
 func (s *Stack) startNeighborCleanupLoop() {
@@ -682,7 +1008,7 @@ func (s *Stack) startNeighborCleanupLoop() {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		ticker := time.NewTicker(30 * time.Second)
+		ticker := time.NewTicker(neighborSweepInterval)
 		defer ticker.Stop()
 		for {
 			select {
@@ -703,6 +1029,9 @@ func (s *Stack) selectDiscoveryDevice(proto string) *config.Device {
 
 	for i := range cfg.Devices {
 		dev := &cfg.Devices[i]
+		if !s.isDeviceUp(dev.Name) {
+			continue
+		}
 		switch proto {
 		case ProtocolLLDP:
 			if dev.LLDPConfig == nil || dev.LLDPConfig.Enabled {
@@ -724,8 +1053,10 @@ func (s *Stack) selectDiscoveryDevice(proto string) *config.Device {
 			return dev
 		}
 	}
-	if len(cfg.Devices) > 0 {
-		return &cfg.Devices[0]
+	for i := range cfg.Devices {
+		if s.isDeviceUp(cfg.Devices[i].Name) {
+			return &cfg.Devices[i]
+		}
 	}
 	return nil
 }
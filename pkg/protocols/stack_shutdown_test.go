@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/gosnmp/gosnmp"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 	"github.com/krisarmstrong/niac-go/pkg/logging"
 )
@@ -154,6 +157,138 @@ func TestStackReloadConfig(t *testing.T) {
 	}
 }
 
+// TestStackReloadConfig_FlowExport tests that a reload moving flow_export to
+// another collector sends later samples there, and that removing it stops
+// flow export
+func TestStackReloadConfig_FlowExport(t *testing.T) {
+	listen := func() net.PacketConn {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Skipf("cannot open UDP listener: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	first, second := listen(), listen()
+
+	// datagrams counts the sFlow datagrams a collector receives
+	datagrams := func(collector net.PacketConn) int {
+		received := 0
+		buf := make([]byte, 65535)
+		for {
+			_ = collector.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			if _, _, err := collector.ReadFrom(buf); err != nil {
+				return received
+			}
+			received++
+		}
+	}
+	// Sampling every frame, six frames fill and send one datagram
+	observe := func(stack *Stack) {
+		for i := 0; i < 6; i++ {
+			stack.observeFlow(make([]byte, 64), false)
+		}
+	}
+	withCollector := func(collector net.PacketConn) *config.Config {
+		return &config.Config{FlowExport: &config.FlowExportConfig{
+			Collector: collector.LocalAddr().String(), SamplingRate: 1, Protocol: config.FlowProtocolSFlow,
+		}}
+	}
+
+	stack := NewStack(nil, withCollector(first), logging.NewDebugConfig(0))
+	observe(stack)
+	if n := datagrams(first); n != 1 {
+		t.Fatalf("first collector got %d datagrams, want 1", n)
+	}
+
+	if err := stack.ReloadConfig(withCollector(second)); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	observe(stack)
+	if n := datagrams(second); n != 1 {
+		t.Errorf("second collector got %d datagrams after the reload, want 1", n)
+	}
+	if n := datagrams(first); n != 0 {
+		t.Errorf("first collector got %d datagrams after the reload, want 0", n)
+	}
+
+	if err := stack.ReloadConfig(&config.Config{}); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+	if stack.currentFlowExporter() != nil {
+		t.Error("flow exporter still set after flow_export was removed")
+	}
+}
+
+// TestStackReloadConfig_AddedDevice tests that a device added by a reload
+// answers SNMP, and that DNS records are rebuilt from the new config
+func TestStackReloadConfig_AddedDevice(t *testing.T) {
+	router := config.Device{
+		Name:        "router",
+		Type:        "router",
+		MACAddress:  net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01},
+		IPAddresses: []net.IP{net.IPv4(10, 0, 0, 1).To4()},
+		SNMPConfig:  config.SNMPConfig{Community: "public"},
+		DNSConfig:   &config.DNSConfig{ForwardRecords: []config.DNSRecord{{Name: "router.lab", IP: net.IPv4(10, 0, 0, 1)}}},
+	}
+	stack := NewStack(nil, &config.Config{Devices: []config.Device{router}}, logging.NewDebugConfig(0))
+
+	switchIP := net.IPv4(10, 0, 0, 2).To4()
+	cfg := &config.Config{Devices: []config.Device{router, {
+		Name:        "switch",
+		Type:        "switch",
+		MACAddress:  net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x02},
+		IPAddresses: []net.IP{switchIP},
+		SNMPConfig:  config.SNMPConfig{Community: "public"},
+		DNSConfig:   &config.DNSConfig{ForwardRecords: []config.DNSRecord{{Name: "switch.lab", IP: switchIP}}},
+	}}}
+	if err := stack.ReloadConfig(cfg); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+
+	devices := stack.GetDevices().GetByIP(switchIP)
+	if len(devices) != 1 || devices[0].Name != "switch" {
+		t.Fatalf("Expected the added device to be indexed by IP, got %v", devices)
+	}
+	req := &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		PDUType:   gosnmp.GetRequest,
+		RequestID: 1,
+		Variables: []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.Null}},
+	}
+	payload, err := req.MarshalMsg()
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	frame := append(append(append([]byte{}, devices[0].MACAddress...), 0x00, 0x11, 0x22, 0x33, 0x44, 0x55), 0x08, 0x00)
+	udpLayer := &layers.UDP{SrcPort: 40000, DstPort: layers.UDPPort(UDPPortSNMP)}
+	udpLayer.Payload = payload
+	ipLayer := &layers.IPv4{SrcIP: net.IPv4(10, 0, 0, 5).To4(), DstIP: switchIP}
+	stack.snmpHandler.HandlePacket(&Packet{Buffer: frame, Length: len(frame)}, ipLayer, udpLayer, devices)
+
+	var resp *Packet
+	select {
+	case resp = <-stack.sendQueue:
+	default:
+		t.Fatal("Expected the added device to answer SNMP")
+	}
+	udp := gopacket.NewPacket(resp.Buffer, layers.LayerTypeEthernet, gopacket.Default).Layer(layers.LayerTypeUDP).(*layers.UDP)
+	result, err := gosnmp.Default.SnmpDecodePacket(udp.Payload)
+	if err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(result.Variables) != 1 || string(result.Variables[0].Value.([]byte)) != "switch" {
+		t.Errorf("Expected sysName.0 = switch, got %v", result.Variables)
+	}
+
+	for _, name := range []string{"router.lab", "switch.lab"} {
+		if ips := stack.GetDNSHandler().lookupHost(name); len(ips) != 1 {
+			t.Errorf("Expected a DNS record for %s after reload, got %v", name, ips)
+		}
+	}
+}
+
 // TestStackCleanupOrder tests that cleanup happens in correct order
 func TestStackCleanupOrder(t *testing.T) {
 	cfg := &config.Config{}
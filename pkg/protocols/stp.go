@@ -117,6 +117,7 @@ func (h *STPHandler) HandlePacket(pkt *Packet) {
 		if h.debugLevel >= 2 {
 			fmt.Printf("STP: Invalid LLC header sn=%d\n", pkt.SerialNumber)
 		}
+		h.stack.recordErrorPacket(pkt, "stp", fmt.Errorf("invalid LLC header: DSAP 0x%02x SSAP 0x%02x", dsap, ssap))
 		return
 	}
 
@@ -131,6 +132,7 @@ func (h *STPHandler) HandlePacket(pkt *Packet) {
 		if h.debugLevel >= 2 {
 			fmt.Printf("STP: Invalid protocol ID 0x%04x sn=%d\n", protocolID, pkt.SerialNumber)
 		}
+		h.stack.recordErrorPacket(pkt, "stp", fmt.Errorf("invalid protocol ID 0x%04x", protocolID))
 		return
 	}
 
@@ -2,6 +2,7 @@ package protocols
 
 import (
 	"fmt"
+	"net"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -14,18 +15,23 @@ const (
 	TCPPortSSH    = 22
 	TCPPortTelnet = 23
 	TCPPortHTTP   = 80
+	TCPPortBGP    = 179
 	TCPPortHTTPS  = 443
 )
 
 // TCPHandler handles TCP packets
 type TCPHandler struct {
-	stack *Stack
+	stack      *Stack
+	conns      *tcpConnTable      // Connection limits for HTTP/FTP services
+	handshakes *tcpHandshakeTable // Handshakes on open tcp_ports
 }
 
 // NewTCPHandler creates a new TCP handler
 func NewTCPHandler(stack *Stack) *TCPHandler {
 	return &TCPHandler{
-		stack: stack,
+		stack:      stack,
+		conns:      newTCPConnTable(),
+		handshakes: newTCPHandshakeTable(),
 	}
 }
 
@@ -67,6 +73,18 @@ func (h *TCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, devices []*
 			flags, tcp.Seq, tcp.Ack, pkt.SerialNumber)
 	}
 
+	// Simulated port states (tcp_ports) take precedence over the defaults
+	if h.handlePortState(pkt, ipLayer.SrcIP, ipLayer.DstIP, tcp, devices) {
+		return
+	}
+
+	// Enforce connection limits on the simulated services
+	if tcp.DstPort == TCPPortHTTP || tcp.DstPort == TCPPortFTP {
+		if !h.trackConnection(pkt, ipLayer.SrcIP, ipLayer.DstIP, tcp, devices) {
+			return
+		}
+	}
+
 	// Route to application handlers based on destination port
 	switch tcp.DstPort {
 	case TCPPortHTTP:
@@ -79,16 +97,22 @@ func (h *TCPHandler) HandlePacket(pkt *Packet, ipLayer *layers.IPv4, devices []*
 		if len(tcp.Payload) > 0 {
 			h.stack.ftpHandler.HandleRequest(pkt, ipLayer, tcp, devices)
 		}
+	case TCPPortBGP:
+		// BGP sessions on devices with a bgp block
+		if !h.stack.bgpHandler.HandleSegment(pkt, ipLayer.SrcIP, ipLayer.DstIP, tcp, devices) && tcp.SYN && !tcp.ACK {
+			h.sendRST(ipLayer, tcp, devices, pkt.GetSourceMAC())
+		}
 	default:
-		// For unsupported ports, send RST on SYN
+		// For unsupported ports not in tcp_ports, send RST on SYN
 		if tcp.SYN && !tcp.ACK {
-			h.sendRST(ipLayer, tcp, devices)
+			h.sendRST(ipLayer, tcp, devices, pkt.GetSourceMAC())
 		}
 	}
 }
 
-// sendRST sends a TCP RST packet
-func (h *TCPHandler) sendRST(ipLayer *layers.IPv4, tcp *layers.TCP, devices []*config.Device) {
+// sendRST sends a TCP RST packet. clientMAC is used when the client is not a
+// simulated device.
+func (h *TCPHandler) sendRST(ipLayer *layers.IPv4, tcp *layers.TCP, devices []*config.Device, clientMAC net.HardwareAddr) {
 	debugLevel := h.stack.GetDebugLevel()
 
 	// Get source device
@@ -115,9 +139,9 @@ func (h *TCPHandler) sendRST(ipLayer *layers.IPv4, tcp *layers.TCP, devices []*c
 		var dstMAC []byte
 		if len(srcDevice) > 0 && len(srcDevice[0].MACAddress) > 0 {
 			dstMAC = srcDevice[0].MACAddress
+		} else if len(clientMAC) > 0 {
+			dstMAC = clientMAC
 		} else {
-			// Use MAC from original packet (reverse lookup)
-			// For now, skip if we can't find it
 			if debugLevel >= 2 {
 				fmt.Printf("Cannot send RST: no MAC for %s\n", ipLayer.SrcIP)
 			}
@@ -135,7 +159,7 @@ func (h *TCPHandler) sendRST(ipLayer *layers.IPv4, tcp *layers.TCP, devices []*c
 		ipReply := &layers.IPv4{
 			Version:  4,
 			IHL:      5,
-			TTL:      64,
+			TTL:      device.TTL(),
 			Protocol: layers.IPProtocolTCP,
 			SrcIP:    ipLayer.DstIP,
 			DstIP:    ipLayer.SrcIP,
@@ -207,7 +231,7 @@ func (h *TCPHandler) SendTCP(srcIP, dstIP []byte, srcPort, dstPort uint16, seq,
 	ipLayer := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      h.stack.originTTL(srcMAC),
 		Protocol: layers.IPProtocolTCP,
 		SrcIP:    srcIP,
 		DstIP:    dstIP,
@@ -308,6 +332,18 @@ func (h *TCPHandler) HandlePacketV6(pkt *Packet, packet gopacket.Packet, ipv6 *l
 			flags, tcp.Seq, tcp.Ack, pkt.SerialNumber)
 	}
 
+	// Simulated port states (tcp_ports) take precedence over the defaults
+	if h.handlePortState(pkt, ipv6.SrcIP, ipv6.DstIP, tcp, devices) {
+		return
+	}
+
+	// Enforce connection limits on the simulated services
+	if tcp.DstPort == TCPPortHTTP || tcp.DstPort == TCPPortFTP {
+		if !h.trackConnection(pkt, ipv6.SrcIP, ipv6.DstIP, tcp, devices) {
+			return
+		}
+	}
+
 	// Route to application handlers based on destination port
 	switch tcp.DstPort {
 	case TCPPortHTTP:
@@ -320,16 +356,22 @@ func (h *TCPHandler) HandlePacketV6(pkt *Packet, packet gopacket.Packet, ipv6 *l
 		if len(tcp.Payload) > 0 {
 			h.stack.ftpHandler.HandleRequestV6(pkt, packet, ipv6, tcp, devices)
 		}
+	case TCPPortBGP:
+		// BGP sessions on devices with a bgp block
+		if !h.stack.bgpHandler.HandleSegment(pkt, ipv6.SrcIP, ipv6.DstIP, tcp, devices) && tcp.SYN && !tcp.ACK {
+			h.sendRSTV6(ipv6, tcp, devices, pkt.GetSourceMAC())
+		}
 	default:
-		// For unsupported ports, send RST on SYN
+		// For unsupported ports not in tcp_ports, send RST on SYN
 		if tcp.SYN && !tcp.ACK {
-			h.sendRSTV6(ipv6, tcp, devices)
+			h.sendRSTV6(ipv6, tcp, devices, pkt.GetSourceMAC())
 		}
 	}
 }
 
-// sendRSTV6 sends a TCP RST packet over IPv6
-func (h *TCPHandler) sendRSTV6(ipv6 *layers.IPv6, tcp *layers.TCP, devices []*config.Device) {
+// sendRSTV6 sends a TCP RST packet over IPv6. clientMAC is used when the
+// client is not a simulated device.
+func (h *TCPHandler) sendRSTV6(ipv6 *layers.IPv6, tcp *layers.TCP, devices []*config.Device, clientMAC net.HardwareAddr) {
 	debugLevel := h.stack.GetDebugLevel()
 
 	// Get source device
@@ -355,6 +397,8 @@ func (h *TCPHandler) sendRSTV6(ipv6 *layers.IPv6, tcp *layers.TCP, devices []*co
 		var dstMAC []byte
 		if len(srcDevice) > 0 && len(srcDevice[0].MACAddress) > 0 {
 			dstMAC = srcDevice[0].MACAddress
+		} else if len(clientMAC) > 0 {
+			dstMAC = clientMAC
 		} else {
 			if debugLevel >= 2 {
 				fmt.Printf("Cannot send RST: no MAC for [%s]\n", ipv6.SrcIP)
@@ -372,7 +416,7 @@ func (h *TCPHandler) sendRSTV6(ipv6 *layers.IPv6, tcp *layers.TCP, devices []*co
 		// Build IPv6 header
 		ipv6Reply := &layers.IPv6{
 			Version:    6,
-			HopLimit:   64,
+			HopLimit:   device.IPv6HopLimit(),
 			NextHeader: layers.IPProtocolTCP,
 			SrcIP:      ipv6.DstIP,
 			DstIP:      ipv6.SrcIP,
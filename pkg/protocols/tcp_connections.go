@@ -0,0 +1,159 @@
+package protocols
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// tcpConnectionIdleTimeout expires tracked connections whose client vanished
+// without sending FIN or RST (e.g. half-open scanner probes).
+const tcpConnectionIdleTimeout = 2 * time.Minute
+
+// tcpConnKey identifies a client connection to a device's TCP service
+type tcpConnKey struct {
+	device string
+	client string // client ip:port
+	port   layers.TCPPort
+}
+
+// tcpConnTable tracks concurrent connections to the simulated TCP services so
+// per-device and global connection caps can be enforced.
+type tcpConnTable struct {
+	mu        sync.Mutex
+	conns     map[tcpConnKey]time.Time // last activity
+	perDevice map[string]int
+}
+
+func newTCPConnTable() *tcpConnTable {
+	return &tcpConnTable{
+		conns:     make(map[tcpConnKey]time.Time),
+		perDevice: make(map[string]int),
+	}
+}
+
+// admit records activity on a connection. New connections are refused when
+// the device already has deviceLimit connections (0 = unlimited) or the table
+// holds globalLimit connections.
+func (t *tcpConnTable) admit(key tcpConnKey, deviceLimit, globalLimit int, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.conns[key]; ok {
+		t.conns[key] = now
+		return true
+	}
+
+	t.expireLocked(now)
+	if deviceLimit > 0 && t.perDevice[key.device] >= deviceLimit {
+		return false
+	}
+	if globalLimit > 0 && len(t.conns) >= globalLimit {
+		return false
+	}
+
+	t.conns[key] = now
+	t.perDevice[key.device]++
+	return true
+}
+
+// release forgets a closed connection
+func (t *tcpConnTable) release(key tcpConnKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.removeLocked(key)
+}
+
+// count returns the number of tracked connections for a device
+func (t *tcpConnTable) count(device string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.perDevice[device]
+}
+
+func (t *tcpConnTable) expireLocked(now time.Time) {
+	for key, lastSeen := range t.conns {
+		if now.Sub(lastSeen) > tcpConnectionIdleTimeout {
+			t.removeLocked(key)
+		}
+	}
+}
+
+func (t *tcpConnTable) removeLocked(key tcpConnKey) {
+	if _, ok := t.conns[key]; !ok {
+		return
+	}
+	delete(t.conns, key)
+	if t.perDevice[key.device]--; t.perDevice[key.device] <= 0 {
+		delete(t.perDevice, key.device)
+	}
+}
+
+// trackConnection applies the connection limits to a packet for a TCP service.
+// It returns false when the packet must not be served: the client reset the
+// connection, or a new connection was refused (answered with RST).
+func (h *TCPHandler) trackConnection(pkt *Packet, srcIP, dstIP net.IP, tcp *layers.TCP, devices []*config.Device) bool {
+	device := serviceDevice(dstIP, devices)
+	if device == nil {
+		return true
+	}
+
+	key := tcpConnKey{
+		device: device.Name,
+		client: net.JoinHostPort(srcIP.String(), fmt.Sprintf("%d", tcp.SrcPort)),
+		port:   tcp.DstPort,
+	}
+
+	if tcp.RST {
+		h.conns.release(key)
+		return false
+	}
+	if tcp.FIN {
+		// Closing connections are always served so their final data is answered
+		h.conns.release(key)
+		return true
+	}
+
+	deviceLimit := 0
+	if device.TCPConfig != nil {
+		deviceLimit = device.TCPConfig.MaxConnections
+	}
+	if !h.conns.admit(key, deviceLimit, h.stack.currentConfig().MaxConnections(), time.Now()) {
+		h.stack.IncrementStat("tcp_connections_refused")
+		if h.stack.GetDebugLevel() >= 2 {
+			fmt.Printf("TCP connection limit reached on %s: refusing %s -> port %d\n",
+				device.Name, key.client, tcp.DstPort)
+		}
+		if (tcp.SYN && !tcp.ACK) || len(tcp.Payload) > 0 {
+			h.refuseConnection(pkt, srcIP, dstIP, tcp, device)
+		}
+		return false
+	}
+	return true
+}
+
+// refuseConnection answers a refused connection with RST
+func (h *TCPHandler) refuseConnection(pkt *Packet, srcIP, dstIP net.IP, tcp *layers.TCP, device *config.Device) {
+	devices := []*config.Device{device}
+	if srcIP.To4() != nil {
+		h.sendRST(&layers.IPv4{SrcIP: srcIP, DstIP: dstIP}, tcp, devices, pkt.GetSourceMAC())
+		return
+	}
+	h.sendRSTV6(&layers.IPv6{SrcIP: srcIP, DstIP: dstIP}, tcp, devices, pkt.GetSourceMAC())
+}
+
+// serviceDevice returns the device that owns dstIP
+func serviceDevice(dstIP net.IP, devices []*config.Device) *config.Device {
+	for _, device := range devices {
+		for _, ip := range device.IPAddresses {
+			if ip.Equal(dstIP) {
+				return device
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package protocols
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+var tcpClientMAC = net.HardwareAddr{0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0x01}
+
+// buildTCPPacket builds a client TCP segment to the web server at 192.168.1.80
+func buildTCPPacket(t *testing.T, srcPort uint16, syn, rst bool) (*Packet, *layers.IPv4) {
+	t.Helper()
+
+	eth := &layers.Ethernet{
+		SrcMAC:       tcpClientMAC,
+		DstMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x80},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP("10.0.0.100").To4(),
+		DstIP:    net.ParseIP("192.168.1.80").To4(),
+	}
+	tcp := &layers.TCP{
+		SrcPort: layers.TCPPort(srcPort),
+		DstPort: TCPPortHTTP,
+		Seq:     1000,
+		SYN:     syn,
+		RST:     rst,
+		Window:  65535,
+	}
+	_ = tcp.SetNetworkLayerForChecksum(ip)
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, eth, ip, tcp); err != nil {
+		t.Fatalf("Failed to build TCP packet: %v", err)
+	}
+	return &Packet{Buffer: buffer.Bytes(), Length: len(buffer.Bytes())}, ip
+}
+
+func newTCPLimitStack(deviceLimit, globalLimit int) (*Stack, []*config.Device) {
+	cfg := &config.Config{
+		Devices: []config.Device{
+			{
+				Name:        "web-server",
+				MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x80},
+				IPAddresses: []net.IP{net.ParseIP("192.168.1.80")},
+				TCPConfig:   &config.TCPConfig{MaxConnections: deviceLimit},
+			},
+		},
+	}
+	if globalLimit > 0 {
+		cfg.TCPConfig = &config.TCPConfig{MaxConnections: globalLimit}
+	}
+	stack := NewStack(nil, cfg, logging.NewDebugConfig(0))
+	return stack, []*config.Device{&cfg.Devices[0]}
+}
+
+// countRSTs drains the send queue and counts RST segments addressed to the client
+func countRSTs(t *testing.T, stack *Stack) int {
+	t.Helper()
+
+	resets := 0
+	for len(stack.sendQueue) > 0 {
+		reply := <-stack.sendQueue
+		packet := gopacket.NewPacket(reply.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+		eth, _ := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+		if ok && tcp.RST && eth.DstMAC.String() == tcpClientMAC.String() {
+			resets++
+		}
+	}
+	return resets
+}
+
+// TestTCPHandler_ConnectionLimit tests that connections beyond the device limit are refused with RST
+func TestTCPHandler_ConnectionLimit(t *testing.T) {
+	stack, devices := newTCPLimitStack(3, 0)
+	handler := stack.tcpHandler
+
+	for port := uint16(40000); port < 40005; port++ {
+		pkt, ip := buildTCPPacket(t, port, true, false)
+		handler.HandlePacket(pkt, ip, devices)
+	}
+
+	if resets := countRSTs(t, stack); resets != 2 {
+		t.Errorf("Expected 2 excess connections to be reset, got %d", resets)
+	}
+	if refused := stack.GetStats().TCPConnectionsRefused; refused != 2 {
+		t.Errorf("Expected 2 refused connections, got %d", refused)
+	}
+	if open := handler.conns.count("web-server"); open != 3 {
+		t.Errorf("Expected 3 tracked connections, got %d", open)
+	}
+
+	// Closing one connection frees a slot
+	pkt, ip := buildTCPPacket(t, 40000, false, true)
+	handler.HandlePacket(pkt, ip, devices)
+	pkt, ip = buildTCPPacket(t, 40010, true, false)
+	handler.HandlePacket(pkt, ip, devices)
+
+	if resets := countRSTs(t, stack); resets != 0 {
+		t.Errorf("Expected connection to be accepted after a close, got %d resets", resets)
+	}
+}
+
+// TestTCPHandler_GlobalConnectionLimit tests the global cap across devices
+func TestTCPHandler_GlobalConnectionLimit(t *testing.T) {
+	stack, devices := newTCPLimitStack(0, 2)
+	handler := stack.tcpHandler
+
+	for port := uint16(41000); port < 41004; port++ {
+		pkt, ip := buildTCPPacket(t, port, true, false)
+		handler.HandlePacket(pkt, ip, devices)
+	}
+
+	if resets := countRSTs(t, stack); resets != 2 {
+		t.Errorf("Expected 2 connections over the global cap to be reset, got %d", resets)
+	}
+}
@@ -0,0 +1,308 @@
+package protocols
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// maxTCPHandshakes caps the connections tracked for open tcp_ports. SYNs
+// beyond it are dropped, like a full listen backlog.
+const maxTCPHandshakes = 4096
+
+// tcpHandshake is the state kept for a connection to an open simulated port
+type tcpHandshake struct {
+	iss         uint32 // Our initial sequence number
+	established bool
+	lastSeen    time.Time
+}
+
+// tcpHandshakeTable tracks handshakes on open tcp_ports so the client's final
+// ACK can be matched against the SYN-ACK that was sent.
+type tcpHandshakeTable struct {
+	mu    sync.Mutex
+	conns map[tcpConnKey]*tcpHandshake
+}
+
+func newTCPHandshakeTable() *tcpHandshakeTable {
+	return &tcpHandshakeTable{conns: make(map[tcpConnKey]*tcpHandshake)}
+}
+
+// syn returns the initial sequence number for a SYN on key. A retransmitted
+// SYN gets the same ISN so the repeated SYN-ACK stays consistent. It returns
+// false when the table is full.
+func (t *tcpHandshakeTable) syn(key tcpConnKey, clientSeq uint32, now time.Time) (uint32, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if conn, ok := t.conns[key]; ok && !conn.established {
+		conn.lastSeen = now
+		return conn.iss, true
+	}
+
+	t.expireLocked(now)
+	if len(t.conns) >= maxTCPHandshakes {
+		return 0, false
+	}
+
+	conn := &tcpHandshake{iss: tcpISN(clientSeq, now), lastSeen: now}
+	t.conns[key] = conn
+	return conn.iss, true
+}
+
+// tcpISN picks a random initial sequence number for a connection
+func tcpISN(clientSeq uint32, now time.Time) uint32 {
+	var isn [4]byte
+	if _, err := rand.Read(isn[:]); err != nil {
+		binary.BigEndian.PutUint32(isn[:], uint32(now.UnixNano())^clientSeq)
+	}
+	return binary.BigEndian.Uint32(isn[:])
+}
+
+// ack completes the handshake on key when ack acknowledges our SYN. It reports
+// whether the connection is (now) established.
+func (t *tcpHandshakeTable) ack(key tcpConnKey, ack uint32, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conn, ok := t.conns[key]
+	if !ok {
+		return false
+	}
+	if !conn.established && ack != conn.iss+1 {
+		return false
+	}
+	conn.established = true
+	conn.lastSeen = now
+	return true
+}
+
+// state returns the tracked handshake for key, if any
+func (t *tcpHandshakeTable) state(key tcpConnKey) (tcpHandshake, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn, ok := t.conns[key]
+	if !ok {
+		return tcpHandshake{}, false
+	}
+	return *conn, true
+}
+
+// release forgets a connection
+func (t *tcpHandshakeTable) release(key tcpConnKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, key)
+}
+
+func (t *tcpHandshakeTable) expireLocked(now time.Time) {
+	for key, conn := range t.conns {
+		if now.Sub(conn.lastSeen) > tcpConnectionIdleTimeout {
+			delete(t.conns, key)
+		}
+	}
+}
+
+// handlePortState answers a segment according to the destination device's
+// tcp_ports. It returns false when the port is not listed, or is open and
+// served by a built-in service (HTTP, FTP, BGP) that should see the segment.
+func (h *TCPHandler) handlePortState(pkt *Packet, srcIP, dstIP net.IP, tcp *layers.TCP, devices []*config.Device) bool {
+	device := serviceDevice(dstIP, devices)
+	if device == nil {
+		return false
+	}
+	state, ok := device.TCPPorts[uint16(tcp.DstPort)]
+	if !ok {
+		return false
+	}
+
+	debugLevel := h.stack.GetDebugLevel()
+	switch state {
+	case config.TCPPortFiltered:
+		if debugLevel >= 3 {
+			fmt.Printf("TCP port %d filtered on %s: dropping segment from %s\n", tcp.DstPort, device.Name, srcIP)
+		}
+		return true
+	case config.TCPPortClosed:
+		if tcp.SYN && !tcp.ACK {
+			h.refuseConnection(pkt, srcIP, dstIP, tcp, device)
+		}
+		return true
+	}
+	if tcp.DstPort == TCPPortBGP && device.BGP != nil {
+		return false // The BGP peer tracks its own connections
+	}
+
+	key := tcpConnKey{
+		device: device.Name,
+		client: net.JoinHostPort(srcIP.String(), fmt.Sprintf("%d", tcp.SrcPort)),
+		port:   tcp.DstPort,
+	}
+	service := tcp.DstPort == TCPPortHTTP || tcp.DstPort == TCPPortFTP
+	now := time.Now()
+
+	switch {
+	case tcp.RST:
+		h.handshakes.release(key)
+		return !service
+	case tcp.SYN && !tcp.ACK:
+		if service && !h.trackConnection(pkt, srcIP, dstIP, tcp, devices) {
+			return true // Refused by the service connection limits
+		}
+		iss, ok := h.handshakes.syn(key, tcp.Seq, now)
+		if !ok {
+			if debugLevel >= 2 {
+				fmt.Printf("TCP handshake table full: dropping SYN from %s to %s port %d\n", key.client, device.Name, tcp.DstPort)
+			}
+			return true
+		}
+		h.sendSegment(pkt, device, srcIP, dstIP, &layers.TCP{
+			SrcPort: tcp.DstPort,
+			DstPort: tcp.SrcPort,
+			Seq:     iss,
+			Ack:     tcp.Seq + 1,
+			SYN:     true,
+			ACK:     true,
+			Window:  65535,
+			Options: []layers.TCPOption{tcpMSSOption(device, srcIP)},
+		})
+		return true
+	case service:
+		// The handshake is ours; the service answers the data
+		if tcp.ACK {
+			h.handshakes.ack(key, tcp.Ack, now)
+		}
+		if tcp.FIN {
+			h.handshakes.release(key)
+		}
+		return false
+	}
+
+	if tcp.ACK && h.handshakes.ack(key, tcp.Ack, now) && len(tcp.Payload) == 0 && !tcp.FIN {
+		// Handshake complete (or a bare ACK on it); nothing listens behind the
+		// port, so the connection just idles until the client closes it
+		return true
+	}
+
+	// Data, FIN or a segment for an unknown connection: nothing serves the
+	// port, so reset it
+	h.handshakes.release(key)
+	rst := &layers.TCP{
+		SrcPort: tcp.DstPort,
+		DstPort: tcp.SrcPort,
+		RST:     true,
+	}
+	if tcp.ACK {
+		rst.Seq = tcp.Ack
+	} else {
+		rst.ACK = true
+		rst.Ack = tcp.Seq + uint32(len(tcp.Payload))
+	}
+	h.sendSegment(pkt, device, srcIP, dstIP, rst)
+	return true
+}
+
+// tcpMSSOption returns the MSS option advertised in a SYN-ACK, derived from
+// the device MTU
+func tcpMSSOption(device *config.Device, clientIP net.IP) layers.TCPOption {
+	headers := 40 // IPv4 + TCP
+	if clientIP.To4() == nil {
+		headers = 60 // IPv6 + TCP
+	}
+	mss := make([]byte, 2)
+	binary.BigEndian.PutUint16(mss, uint16(device.LinkMTU()-headers))
+	return layers.TCPOption{OptionType: layers.TCPOptionKindMSS, OptionLength: 4, OptionData: mss}
+}
+
+// sendSegment sends tcpReply from device (owner of dstIP) back to the client
+// at srcIP over the client's IP version.
+func (h *TCPHandler) sendSegment(pkt *Packet, device *config.Device, srcIP, dstIP net.IP, tcpReply *layers.TCP) {
+	dstMAC := h.clientMAC(pkt, srcIP)
+	if dstMAC == nil {
+		if h.stack.GetDebugLevel() >= 2 {
+			fmt.Printf("Cannot send TCP segment: no MAC for %s\n", srcIP)
+		}
+		return
+	}
+	h.sendSegmentTo(device, dstMAC, srcIP, dstIP, tcpReply, nil)
+}
+
+// clientMAC returns the MAC to answer the client at srcIP on: the simulated
+// device owning srcIP, or the sender of pkt. It returns nil when neither is
+// known.
+func (h *TCPHandler) clientMAC(pkt *Packet, srcIP net.IP) net.HardwareAddr {
+	if srcDevice := h.stack.GetDevices().GetByIP(srcIP); len(srcDevice) > 0 && len(srcDevice[0].MACAddress) > 0 {
+		return srcDevice[0].MACAddress
+	}
+	if clientMAC := pkt.GetSourceMAC(); len(clientMAC) > 0 {
+		return clientMAC
+	}
+	return nil
+}
+
+// sendSegmentTo sends tcpReply carrying payload from device (owner of dstIP)
+// to the client at srcIP, whose frames go to dstMAC.
+func (h *TCPHandler) sendSegmentTo(device *config.Device, dstMAC net.HardwareAddr, srcIP, dstIP net.IP, tcpReply *layers.TCP, payload []byte) {
+	debugLevel := h.stack.GetDebugLevel()
+
+	eth := &layers.Ethernet{SrcMAC: device.MACAddress, DstMAC: dstMAC}
+	var ipReply gopacket.SerializableLayer
+	if srcIP.To4() != nil {
+		eth.EthernetType = layers.EthernetTypeIPv4
+		ipv4 := &layers.IPv4{
+			Version:  4,
+			IHL:      5,
+			TTL:      device.TTL(),
+			Protocol: layers.IPProtocolTCP,
+			SrcIP:    dstIP.To4(),
+			DstIP:    srcIP.To4(),
+		}
+		_ = tcpReply.SetNetworkLayerForChecksum(ipv4)
+		ipReply = ipv4
+	} else {
+		eth.EthernetType = layers.EthernetTypeIPv6
+		ipv6 := &layers.IPv6{
+			Version:    6,
+			HopLimit:   device.IPv6HopLimit(),
+			NextHeader: layers.IPProtocolTCP,
+			SrcIP:      dstIP,
+			DstIP:      srcIP,
+		}
+		_ = tcpReply.SetNetworkLayerForChecksum(ipv6)
+		ipReply = ipv6
+	}
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, eth, ipReply, tcpReply, gopacket.Payload(payload)); err != nil {
+		if debugLevel >= 2 {
+			fmt.Printf("Error serializing TCP segment: %v\n", err)
+		}
+		return
+	}
+
+	h.stack.mu.Lock()
+	h.stack.serialNumber++
+	serialNum := h.stack.serialNumber
+	h.stack.mu.Unlock()
+
+	h.stack.Send(&Packet{
+		Buffer:       buffer.Bytes(),
+		Length:       len(buffer.Bytes()),
+		SerialNumber: serialNum,
+		Device:       device,
+	})
+
+	if debugLevel >= 3 {
+		fmt.Printf("Sent TCP SYN=%v ACK=%v FIN=%v RST=%v from %s:%d to %s:%d length=%d device=%s sn=%d\n",
+			tcpReply.SYN, tcpReply.ACK, tcpReply.FIN, tcpReply.RST, dstIP, tcpReply.SrcPort, srcIP, tcpReply.DstPort,
+			len(payload), device.Name, serialNum)
+	}
+}
@@ -0,0 +1,154 @@
+package protocols
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/logging"
+)
+
+func newTCPPortsStack() *Stack {
+	cfg := &config.Config{Devices: []config.Device{{
+		Name:        "server",
+		MACAddress:  net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x80},
+		IPAddresses: []net.IP{net.ParseIP("192.168.1.80"), net.ParseIP("2001:db8::80")},
+		TCPPorts: map[uint16]string{
+			22:  config.TCPPortOpen,
+			23:  config.TCPPortClosed,
+			445: config.TCPPortFiltered,
+		},
+	}}}
+	return NewStack(nil, cfg, logging.NewDebugConfig(0))
+}
+
+// sendClientSegment delivers a client segment carrying data from
+// 10.0.0.100:40000 (or 2001:db8::100 when dst is IPv6) to dst:port
+func sendClientSegment(t *testing.T, stack *Stack, dst string, port uint16, segment layers.TCP, data []byte) {
+	t.Helper()
+
+	segment.SrcPort = 40000
+	segment.DstPort = layers.TCPPort(port)
+	segment.Window = 65535
+
+	eth := &layers.Ethernet{SrcMAC: tcpClientMAC, DstMAC: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x80}}
+	var ip gopacket.SerializableLayer
+	if dstIP := net.ParseIP(dst); dstIP.To4() != nil {
+		eth.EthernetType = layers.EthernetTypeIPv4
+		ipv4 := &layers.IPv4{Version: 4, IHL: 5, TTL: 64, Protocol: layers.IPProtocolTCP,
+			SrcIP: net.ParseIP("10.0.0.100").To4(), DstIP: dstIP.To4()}
+		_ = segment.SetNetworkLayerForChecksum(ipv4)
+		ip = ipv4
+	} else {
+		eth.EthernetType = layers.EthernetTypeIPv6
+		ipv6 := &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolTCP,
+			SrcIP: net.ParseIP("2001:db8::100"), DstIP: dstIP}
+		_ = segment.SetNetworkLayerForChecksum(ipv6)
+		ip = ipv6
+	}
+
+	buffer := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buffer, opts, eth, ip, &segment, gopacket.Payload(data)); err != nil {
+		t.Fatalf("Failed to build TCP packet: %v", err)
+	}
+	pkt, err := ParsePacket(buffer.Bytes(), 1)
+	if err != nil {
+		t.Fatalf("ParsePacket: %v", err)
+	}
+	stack.decodePacket(pkt)
+}
+
+// tcpReplies drains the send queue and returns the TCP segments sent
+func tcpReplies(t *testing.T, stack *Stack) []*layers.TCP {
+	t.Helper()
+
+	var segments []*layers.TCP
+	for _, pkt := range drainSendQueue(stack) {
+		packet := gopacket.NewPacket(pkt.Buffer, layers.LayerTypeEthernet, gopacket.Default)
+		tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+		if !ok {
+			t.Fatalf("Sent packet has no TCP layer: %v", packet)
+		}
+		if eth := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); eth.DstMAC.String() != tcpClientMAC.String() {
+			t.Errorf("Reply sent to %s, want the client %s", eth.DstMAC, tcpClientMAC)
+		}
+		segments = append(segments, tcp)
+	}
+	return segments
+}
+
+// TestTCPPorts_Open tests that an open port answers SYN with SYN-ACK and
+// completes the handshake on the client's ACK
+func TestTCPPorts_Open(t *testing.T) {
+	for _, dst := range []string{"192.168.1.80", "2001:db8::80"} {
+		stack := newTCPPortsStack()
+
+		sendClientSegment(t, stack, dst, 22, layers.TCP{Seq: 1000, SYN: true}, nil)
+		replies := tcpReplies(t, stack)
+		if len(replies) != 1 || !replies[0].SYN || !replies[0].ACK || replies[0].RST {
+			t.Fatalf("%s: replies to SYN = %+v, want one SYN-ACK", dst, replies)
+		}
+		synAck := replies[0]
+		if synAck.SrcPort != 22 || synAck.DstPort != 40000 || synAck.Ack != 1001 {
+			t.Errorf("%s: SYN-ACK %d->%d ack=%d, want 22->40000 ack=1001", dst, synAck.SrcPort, synAck.DstPort, synAck.Ack)
+		}
+
+		// A retransmitted SYN gets the same SYN-ACK
+		sendClientSegment(t, stack, dst, 22, layers.TCP{Seq: 1000, SYN: true}, nil)
+		if replies := tcpReplies(t, stack); len(replies) != 1 || replies[0].Seq != synAck.Seq {
+			t.Errorf("%s: retransmitted SYN answered with %+v, want the same SYN-ACK", dst, replies)
+		}
+
+		// The final ACK completes the handshake silently
+		sendClientSegment(t, stack, dst, 22, layers.TCP{Seq: 1001, Ack: synAck.Seq + 1, ACK: true}, nil)
+		if replies := tcpReplies(t, stack); len(replies) != 0 {
+			t.Errorf("%s: %d replies to the handshake ACK, want none", dst, len(replies))
+		}
+		client := net.JoinHostPort("10.0.0.100", "40000")
+		if dst != "192.168.1.80" {
+			client = net.JoinHostPort("2001:db8::100", "40000")
+		}
+		conn, ok := stack.tcpHandler.handshakes.state(tcpConnKey{device: "server", client: client, port: 22})
+		if !ok || !conn.established {
+			t.Errorf("%s: connection state = %+v (tracked %v), want established", dst, conn, ok)
+		}
+
+		// Nothing serves the port, so data is reset
+		sendClientSegment(t, stack, dst, 22, layers.TCP{Seq: 1001, Ack: synAck.Seq + 1, ACK: true, PSH: true}, []byte("SSH-2.0\r\n"))
+		if replies := tcpReplies(t, stack); len(replies) != 1 || !replies[0].RST || replies[0].Seq != synAck.Seq+1 {
+			t.Errorf("%s: replies to data = %+v, want RST seq=%d", dst, replies, synAck.Seq+1)
+		}
+	}
+}
+
+// TestTCPPorts_Closed tests that a closed port answers SYN with RST
+func TestTCPPorts_Closed(t *testing.T) {
+	stack := newTCPPortsStack()
+
+	sendClientSegment(t, stack, "192.168.1.80", 23, layers.TCP{Seq: 5000, SYN: true}, nil)
+	replies := tcpReplies(t, stack)
+	if len(replies) != 1 || !replies[0].RST || !replies[0].ACK || replies[0].Ack != 5001 {
+		t.Fatalf("replies to SYN = %+v, want one RST-ACK ack=5001", replies)
+	}
+}
+
+// TestTCPPorts_Filtered tests that a filtered port never answers
+func TestTCPPorts_Filtered(t *testing.T) {
+	stack := newTCPPortsStack()
+
+	sendClientSegment(t, stack, "192.168.1.80", 445, layers.TCP{Seq: 7000, SYN: true}, nil)
+	sendClientSegment(t, stack, "192.168.1.80", 445, layers.TCP{Seq: 7001, Ack: 1, ACK: true}, nil)
+	sendClientSegment(t, stack, "2001:db8::80", 445, layers.TCP{Seq: 7000, SYN: true}, nil)
+	if replies := tcpReplies(t, stack); len(replies) != 0 {
+		t.Errorf("Filtered port answered with %d segments, want none", len(replies))
+	}
+
+	// Unlisted ports keep the default behavior (RST)
+	sendClientSegment(t, stack, "192.168.1.80", 8443, layers.TCP{Seq: 9000, SYN: true}, nil)
+	if replies := tcpReplies(t, stack); len(replies) != 1 || !replies[0].RST {
+		t.Errorf("Unlisted port answered with %+v, want RST", replies)
+	}
+}
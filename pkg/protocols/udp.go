@@ -99,7 +99,7 @@ func (h *UDPHandler) SendUDP(srcIP, dstIP []byte, srcPort, dstPort uint16, paylo
 	ipLayer := &layers.IPv4{
 		Version:  4,
 		IHL:      5,
-		TTL:      64,
+		TTL:      h.stack.originTTL(srcMAC),
 		Protocol: layers.IPProtocolUDP,
 		SrcIP:    srcIP,
 		DstIP:    dstIP,
@@ -142,7 +142,7 @@ func (h *UDPHandler) SendUDP(srcIP, dstIP []byte, srcPort, dstPort uint16, paylo
 		SerialNumber: serialNum,
 	}
 
-	h.stack.Send(pkt)
+	h.stack.sendResponse(pkt)
 
 	if h.stack.GetDebugLevel() >= 3 {
 		fmt.Printf("Sent UDP packet: %s:%d -> %s:%d length=%d sn=%d\n",
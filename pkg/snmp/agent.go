@@ -7,39 +7,94 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gosnmp/gosnmp"
 	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
 )
 
 // Agent represents an SNMP agent instance for a device
 type Agent struct {
-	device     *config.Device
-	mib        *MIB
-	community  string
-	startTime  time.Time
-	debugLevel int
-	mu         sync.RWMutex
+	device      *config.Device
+	mib         *MIB
+	community   string
+	views       map[string]*MIBView       // Additional communities and their views (nil = full access)
+	enabled     *MIBView                  // Everything but the disabled subtrees (nil = all); see isDisabled
+	managers    []*net.IPNet              // Source networks allowed to query (empty = all)
+	computed    map[string]*computedOID   // Objects computed from live data (see RegisterComputedOID)
+	tables      map[string]*computedTable // Subtrees computed from live data (see RegisterComputedTable)
+	sysOROIDs   map[string]*OIDValue      // sysORTable objects set by initializeSysORTable
+	startTime   time.Time
+	engineBoots int
+	walkFile    string
+	walkFiles   []string    // Walk files merged over walkFile (see LoadWalkFiles)
+	series      *walkSeries // Walk snapshots replayed over time (see LoadWalkSeries)
+	walkLimits  WalkLimits  // Size and OID caps for walk files (see SetWalkLimits)
+	walkOIDs    int         // OIDs loaded from walk_file and walk_files
+	trapSender  *TrapSender
+	adminDown   map[int]bool                        // ifIndexes shut by a SET of ifAdminStatus (see ProcessSet)
+	errorStates atomic.Pointer[errors.StateManager] // Injected errors that drive hrStorageUsed
+	debugLevel  int
+	mu          sync.RWMutex
 }
 
+// SNMP-FRAMEWORK-MIB engine objects (RFC 3411)
+const (
+	OIDSnmpEngineBoots = "1.3.6.1.6.3.10.2.1.2.0"
+	OIDSnmpEngineTime  = "1.3.6.1.6.3.10.2.1.3.0"
+)
+
 // NewAgent creates a new SNMP agent for a device
 func NewAgent(device *config.Device, debugLevel int) *Agent {
 	agent := &Agent{
-		device:     device,
-		mib:        NewMIB(),
-		community:  "public",
-		startTime:  time.Now(),
-		debugLevel: debugLevel,
+		device:      device,
+		mib:         NewMIB(),
+		community:   "public",
+		startTime:   time.Now(),
+		engineBoots: 1,
+		debugLevel:  debugLevel,
 	}
 
+	// Start part way into the current boot, as a device that has been up a
+	// while, after as many reboots as configured
+	if device.SNMPConfig.EngineBoots > 0 {
+		agent.engineBoots = device.SNMPConfig.EngineBoots
+	}
+	agent.startTime = agent.startTime.Add(-device.SNMPConfig.EngineTime)
+
 	// Set community from device config if available
 	if device.SNMPConfig.Community != "" {
 		agent.community = device.SNMPConfig.Community
 	}
 
+	// Additional communities restricted to MIB views
+	if len(device.SNMPConfig.Communities) > 0 {
+		agent.views = make(map[string]*MIBView, len(device.SNMPConfig.Communities))
+		for _, c := range device.SNMPConfig.Communities {
+			agent.views[c.Name] = NewMIBView(c.View)
+		}
+	}
+
+	// Subtrees treated as absent whatever the MIB holds
+	if len(device.SNMPConfig.DisabledSubtrees) > 0 {
+		agent.enabled = NewMIBView(&config.SNMPView{Excluded: device.SNMPConfig.DisabledSubtrees})
+	}
+
+	// Manager access list (validated during config load)
+	for _, cidr := range device.SNMPConfig.AllowedManagers {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			agent.managers = append(agent.managers, network)
+		}
+	}
+
 	// Initialize standard MIB-II system objects
 	agent.initializeSystemMIB()
+	agent.initializeHostResources()
+	agent.applyInterfaceMTU()
+	agent.applyInterfaceDescriptions()
+	agent.initializeSysORTable()
 
 	return agent
 }
@@ -66,15 +121,8 @@ func (a *Agent) initializeSystemMIB() {
 		Value: sysObjectID,
 	})
 
-	// sysUpTime (1.3.6.1.2.1.1.3.0) - TimeTicks (hundredths of second)
-	a.mib.SetDynamic("1.3.6.1.2.1.1.3.0", func() *OIDValue {
-		uptime := time.Since(a.startTime)
-		timeticks := uint32(uptime.Milliseconds() / 10) // Convert to hundredths of second
-		return &OIDValue{
-			Type:  gosnmp.TimeTicks,
-			Value: timeticks,
-		}
-	})
+	// sysUpTime, snmpEngineBoots, snmpEngineTime
+	a.setUptimeOIDs()
 
 	// sysContact (1.3.6.1.2.1.1.4.0)
 	sysContact := a.device.Properties["sysContact"]
@@ -122,24 +170,180 @@ func (a *Agent) initializeSystemMIB() {
 	}
 }
 
+// setUptimeOIDs installs the dynamic objects derived from the agent's boot time.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) setUptimeOIDs() {
+	// sysUpTime (1.3.6.1.2.1.1.3.0) - TimeTicks (hundredths of second)
+	a.mib.SetDynamic("1.3.6.1.2.1.1.3.0", func() *OIDValue {
+		uptime := time.Since(a.startTime)
+		timeticks := uint32(uptime.Milliseconds() / 10) // Convert to hundredths of second
+		return &OIDValue{
+			Type:  gosnmp.TimeTicks,
+			Value: timeticks,
+		}
+	})
+
+	// snmpEngineBoots - number of times the agent has (re)started
+	a.mib.SetDynamic(OIDSnmpEngineBoots, func() *OIDValue {
+		return &OIDValue{
+			Type:  gosnmp.Integer,
+			Value: a.engineBoots,
+		}
+	})
+
+	// snmpEngineTime - seconds since the last boot
+	a.mib.SetDynamic(OIDSnmpEngineTime, func() *OIDValue {
+		return &OIDValue{
+			Type:  gosnmp.Integer,
+			Value: int(time.Since(a.startTime).Seconds()),
+		}
+	})
+}
+
+// SetTrapSender attaches the trap sender used to announce reboots.
+func (a *Agent) SetTrapSender(ts *TrapSender) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.trapSender = ts
+}
+
+// SetWalkLimits sets the size and OID caps applied to the walk files the agent
+// loads afterwards.
+func (a *Agent) SetWalkLimits(limits WalkLimits) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.walkLimits = limits
+}
+
+// WalkOIDCount returns the number of OIDs loaded from the device's walk files.
+func (a *Agent) WalkOIDCount() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.walkOIDs
+}
+
+// EngineState returns snmpEngineBoots and snmpEngineTime (seconds since the
+// last boot), as reported in SNMPv3 USM security parameters.
+func (a *Agent) EngineState() (boots int, engineTime int) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.engineBoots, int(time.Since(a.startTime).Seconds())
+}
+
+// BootComplete marks the end of a simulated boot delay: sysUpTime counts from
+// now and a coldStart trap is sent when a trap sender is attached.
+func (a *Agent) BootComplete() error {
+	a.mu.Lock()
+	a.startTime = time.Now()
+	ts := a.trapSender
+	a.mu.Unlock()
+
+	if ts != nil {
+		return ts.SendColdStart()
+	}
+	return nil
+}
+
+// Reboot simulates a device restart: sysUpTime resets to zero, snmpEngineBoots
+// increments, the MIB is rebuilt from the system group and walk file (discarding
+// SET values), counters are cleared, and a coldStart trap is sent when a trap
+// sender is attached.
+func (a *Agent) Reboot() error {
+	a.mu.Lock()
+	a.startTime = time.Now()
+	// snmpEngineBoots stays latched at its maximum (RFC 3414 section 2.2.3)
+	a.engineBoots = min(a.engineBoots+1, config.MaxSNMPEngineBoots)
+	a.adminDown = nil
+	a.walkOIDs = 0
+	a.mib = NewMIB()
+	a.initializeSystemMIB()
+	a.initializeHostResources()
+
+	var walkErr error
+	if a.walkFile != "" {
+		walkErr = a.loadWalkEntries(a.walkFile)
+		// Walk files capture a static sysUpTime; a rebooted agent counts from zero
+		a.setUptimeOIDs()
+	}
+	if len(a.walkFiles) > 0 && walkErr == nil {
+		walkErr = a.reloadWalkFiles()
+		a.setUptimeOIDs()
+	}
+	if a.series != nil {
+		// The recording keeps playing; only the rebuilt MIB needs the
+		// current snapshot again
+		a.series.installed = nil
+		a.applyWalkSnapshot(a.series.current)
+		a.setUptimeOIDs()
+	}
+
+	// Counters restart from zero after a reboot
+	for _, oid := range a.mib.AllOIDs() {
+		value := a.mib.Get(oid)
+		if value == nil {
+			continue
+		}
+		switch value.Type {
+		case gosnmp.Counter32:
+			a.mib.Set(oid, &OIDValue{Type: gosnmp.Counter32, Value: uint(0)})
+		case gosnmp.Counter64:
+			a.mib.Set(oid, &OIDValue{Type: gosnmp.Counter64, Value: uint64(0)})
+		}
+	}
+	a.applyComputedOIDs(true)
+	a.applyInterfaceMTU()
+	a.applyInterfaceDescriptions()
+	a.sysOROIDs = nil // The rebuilt MIB holds none of them
+	a.initializeSysORTable()
+	ts := a.trapSender
+	boots := a.engineBoots
+	a.mu.Unlock()
+
+	if a.debugLevel >= 1 {
+		log.Printf("SNMP agent rebooted for device %s (engine boots %d)", a.device.Name, boots)
+	}
+
+	if walkErr != nil {
+		return fmt.Errorf("reload walk file: %w", walkErr)
+	}
+	if ts != nil {
+		return ts.SendColdStart()
+	}
+	return nil
+}
+
 // LoadWalkFile loads SNMP walk file data into the MIB
 func (a *Agent) LoadWalkFile(filename string) error {
 	if filename == "" {
 		return fmt.Errorf("no walk file specified")
 	}
 
-	entries, err := ParseWalkFile(filename)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.loadWalkEntries(filename); err != nil {
+		return err
+	}
+	a.walkFile = filename
+
+	// Live values take precedence over the walk file's static snapshot, and
+	// sysORTable now advertises the modules the walk file added
+	a.applyComputedOIDs(false)
+	a.applyInterfaceMTU()
+	a.applyInterfaceDescriptions()
+	a.initializeSysORTable()
+	return nil
+}
+
+// loadWalkEntries parses a walk file and adds its entries to the MIB.
+// Callers must hold a.mu.
+func (a *Agent) loadWalkEntries(filename string) error {
+	entries, err := ParseWalkFileWithLimits(filename, a.walkLimits)
 	if err != nil {
 		return fmt.Errorf("failed to parse walk file: %v", err)
 	}
 
-	// Add all entries to MIB
-	for _, entry := range entries {
-		a.mib.Set(entry.OID, &OIDValue{
-			Type:  entry.Type,
-			Value: entry.Value,
-		})
-	}
+	a.setWalkEntries(entries)
+	a.walkOIDs += len(entries)
 
 	if a.debugLevel >= 1 {
 		log.Printf("Loaded %d OIDs from walk file %s for device %s",
@@ -151,11 +355,14 @@ func (a *Agent) LoadWalkFile(filename string) error {
 
 // HandleGet processes an SNMP GET request
 func (a *Agent) HandleGet(oid string) (*OIDValue, error) {
+	a.advanceWalkSeries()
+	a.refreshComputedTables()
+
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
 	value := a.mib.Get(oid)
-	if value == nil {
+	if value == nil || a.isDisabled(oid) {
 		return nil, fmt.Errorf("no such object: %s", oid)
 	}
 
@@ -168,10 +375,13 @@ func (a *Agent) HandleGet(oid string) (*OIDValue, error) {
 
 // HandleGetNext processes an SNMP GET-NEXT request
 func (a *Agent) HandleGetNext(oid string) (string, *OIDValue, error) {
+	a.advanceWalkSeries()
+	a.refreshComputedTables()
+
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	nextOID, value := a.mib.GetNext(oid)
+	nextOID, value := a.nextEnabledLocked(oid)
 	if nextOID == "" || value == nil {
 		return "", nil, fmt.Errorf("end of MIB view")
 	}
@@ -184,8 +394,30 @@ func (a *Agent) HandleGetNext(oid string) (string, *OIDValue, error) {
 	return nextOID, value, nil
 }
 
+// isDisabled reports whether oid is under a disabled subtree, answered as
+// absent in every view.
+func (a *Agent) isDisabled(oid string) bool {
+	return !a.enabled.Contains(oid)
+}
+
+// nextEnabledLocked returns the next OID after oid outside the disabled
+// subtrees, or "" at the end of the MIB.
+// Callers must hold a.mu.
+func (a *Agent) nextEnabledLocked(oid string) (string, *OIDValue) {
+	for {
+		nextOID, value := a.mib.GetNext(oid)
+		if nextOID == "" || value == nil || !a.isDisabled(nextOID) {
+			return nextOID, value
+		}
+		oid = nextOID
+	}
+}
+
 // HandleGetBulk processes an SNMP GET-BULK request
 func (a *Agent) HandleGetBulk(oid string, maxRepetitions int) ([]OIDResult, error) {
+	a.advanceWalkSeries()
+	a.refreshComputedTables()
+
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
@@ -193,7 +425,7 @@ func (a *Agent) HandleGetBulk(oid string, maxRepetitions int) ([]OIDResult, erro
 	currentOID := oid
 
 	for i := 0; i < maxRepetitions; i++ {
-		nextOID, value := a.mib.GetNext(currentOID)
+		nextOID, value := a.nextEnabledLocked(currentOID)
 		if nextOID == "" || value == nil {
 			break
 		}
@@ -237,14 +469,86 @@ func (a *Agent) GetCommunity() string {
 	return a.community
 }
 
+// ViewFor returns the MIB view for a community and whether the community is
+// accepted. A nil view grants full access. Communities listed in the device's
+// communities block take precedence over the primary community.
+func (a *Agent) ViewFor(community string) (*MIBView, bool) {
+	if view, ok := a.views[community]; ok {
+		return view, true
+	}
+	if community == a.community {
+		return nil, true
+	}
+	return nil, false
+}
+
+// AllowsManager reports whether requests from source may be answered. With no
+// allowed_managers configured every source is accepted.
+func (a *Agent) AllowsManager(source net.IP) bool {
+	if len(a.managers) == 0 {
+		return true
+	}
+	for _, network := range a.managers {
+		if network.Contains(source) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyAuthenticationFailure sends an authenticationFailure trap when a trap
+// sender is attached and the trap is enabled.
+func (a *Agent) NotifyAuthenticationFailure() error {
+	a.mu.RLock()
+	ts := a.trapSender
+	a.mu.RUnlock()
+
+	if ts == nil {
+		return nil
+	}
+	return ts.SendAuthenticationFailure()
+}
+
 // ProcessPDU processes SNMP PDU variables and returns response variables
 // This is typically called by an SNMP server implementation
 func (a *Agent) ProcessPDU(pduType gosnmp.PDUType, vars []gosnmp.SnmpPDU, maxRepetitions uint32) []gosnmp.SnmpPDU {
+	return a.ProcessPDUWithView(pduType, vars, maxRepetitions, nil)
+}
+
+// ProcessPDUWithView processes SNMP PDU variables restricted to a MIB view.
+// GETs outside the view return noSuchObject; GETs of a missing instance of an
+// object the agent has return noSuchInstance (unless the device's
+// missing_instance setting asks for noSuchObject). GET-NEXT/GET-BULK skip
+// OIDs outside the view. Responses are limited to the agent's
+// max_message_size as in ProcessPDUWithLimit; a response too big to send has
+// no varbinds.
+func (a *Agent) ProcessPDUWithView(pduType gosnmp.PDUType, vars []gosnmp.SnmpPDU, maxRepetitions uint32, view *MIBView) []gosnmp.SnmpPDU {
+	response, _ := a.ProcessPDUWithLimit(pduType, vars, maxRepetitions, view, a.defaultVarbindBudget())
+	return response
+}
+
+// ProcessPDUWithLimit processes a request like ProcessPDUWithView, keeping the
+// response varbinds within budget encoded bytes (see VarbindBudget). A
+// GET-BULK response is cut to the repetitions that fit; a GET or GET-NEXT
+// response that does not fit returns tooBig with no varbinds. The device's
+// snmp_quirks are applied, and values converted to the types gosnmp encodes
+// (see encodeValue), before the response is sized. The processing time is
+// reported to the SetRequestDurationObserver observer.
+func (a *Agent) ProcessPDUWithLimit(pduType gosnmp.PDUType, vars []gosnmp.SnmpPDU, maxRepetitions uint32, view *MIBView, budget int) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+	if ref := requestObserver.Load(); ref != nil {
+		defer observeRequest(ref, time.Now())
+	}
+	response := a.applyQuirks(a.processPDU(pduType, vars, maxRepetitions, view))
+	encodeVarbinds(response)
+	return fitResponse(pduType, response, budget)
+}
+
+func (a *Agent) processPDU(pduType gosnmp.PDUType, vars []gosnmp.SnmpPDU, maxRepetitions uint32, view *MIBView) []gosnmp.SnmpPDU {
 	switch pduType {
 	case gosnmp.GetRequest:
-		return a.processGetRequest(vars)
+		return a.processGetRequest(vars, view)
 	case gosnmp.GetNextRequest:
-		return a.processGetNextRequest(vars)
+		return a.processGetNextRequest(vars, view)
 	case gosnmp.GetBulkRequest:
 		reps := int(maxRepetitions)
 		if reps <= 0 {
@@ -253,7 +557,7 @@ func (a *Agent) ProcessPDU(pduType gosnmp.PDUType, vars []gosnmp.SnmpPDU, maxRep
 		if reps > 50 {
 			reps = 50
 		}
-		return a.processGetBulkRequestVars(vars, reps)
+		return a.processGetBulkRequestVars(vars, reps, view)
 	default:
 		// Return error PDU
 		return []gosnmp.SnmpPDU{{
@@ -265,15 +569,24 @@ func (a *Agent) ProcessPDU(pduType gosnmp.PDUType, vars []gosnmp.SnmpPDU, maxRep
 }
 
 // processGetRequest processes GET request variables
-func (a *Agent) processGetRequest(vars []gosnmp.SnmpPDU) []gosnmp.SnmpPDU {
+func (a *Agent) processGetRequest(vars []gosnmp.SnmpPDU, view *MIBView) []gosnmp.SnmpPDU {
 	response := make([]gosnmp.SnmpPDU, len(vars))
 
 	for i, snmpVar := range vars {
-		value, err := a.HandleGet(snmpVar.Name)
+		var value *OIDValue
+		err := fmt.Errorf("not in view: %s", snmpVar.Name)
+		inView := view.Contains(snmpVar.Name)
+		if inView {
+			value, err = a.HandleGet(snmpVar.Name)
+		}
 		if err != nil {
+			exception := gosnmp.NoSuchObject
+			if inView && a.reportsMissingInstance(snmpVar.Name) {
+				exception = gosnmp.NoSuchInstance
+			}
 			response[i] = gosnmp.SnmpPDU{
 				Name:  snmpVar.Name,
-				Type:  gosnmp.NoSuchObject,
+				Type:  exception,
 				Value: nil,
 			}
 		} else {
@@ -288,12 +601,24 @@ func (a *Agent) processGetRequest(vars []gosnmp.SnmpPDU) []gosnmp.SnmpPDU {
 	return response
 }
 
+// reportsMissingInstance reports whether a GET of oid, which has no value,
+// should answer noSuchInstance: the agent has the object but not the instance.
+func (a *Agent) reportsMissingInstance(oid string) bool {
+	if a.device.SNMPConfig.MissingInstance == config.SNMPMissingInstanceNoSuchObject || a.isDisabled(oid) {
+		return false
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.mib.HasObject(oid)
+}
+
 // processGetNextRequest processes GET-NEXT request variables
-func (a *Agent) processGetNextRequest(vars []gosnmp.SnmpPDU) []gosnmp.SnmpPDU {
+func (a *Agent) processGetNextRequest(vars []gosnmp.SnmpPDU, view *MIBView) []gosnmp.SnmpPDU {
 	response := make([]gosnmp.SnmpPDU, len(vars))
 
 	for i, snmpVar := range vars {
-		nextOID, value, err := a.HandleGetNext(snmpVar.Name)
+		nextOID, value, err := a.getNextInView(snmpVar.Name, view)
 		if err != nil {
 			response[i] = gosnmp.SnmpPDU{
 				Name:  snmpVar.Name,
@@ -313,11 +638,11 @@ func (a *Agent) processGetNextRequest(vars []gosnmp.SnmpPDU) []gosnmp.SnmpPDU {
 }
 
 // processGetBulkRequestVars processes GET-BULK request variables
-func (a *Agent) processGetBulkRequestVars(vars []gosnmp.SnmpPDU, maxRepetitions int) []gosnmp.SnmpPDU {
+func (a *Agent) processGetBulkRequestVars(vars []gosnmp.SnmpPDU, maxRepetitions int, view *MIBView) []gosnmp.SnmpPDU {
 	var response []gosnmp.SnmpPDU
 
 	for _, snmpVar := range vars {
-		results, err := a.HandleGetBulk(snmpVar.Name, maxRepetitions)
+		results, err := a.getBulkInView(snmpVar.Name, maxRepetitions, view)
 		if err != nil {
 			response = append(response, gosnmp.SnmpPDU{
 				Name:  snmpVar.Name,
@@ -339,6 +664,40 @@ func (a *Agent) processGetBulkRequestVars(vars []gosnmp.SnmpPDU, maxRepetitions
 	return response
 }
 
+// getNextInView returns the next OID after oid that is visible in view.
+func (a *Agent) getNextInView(oid string, view *MIBView) (string, *OIDValue, error) {
+	current := oid
+	for {
+		nextOID, value, err := a.HandleGetNext(current)
+		if err != nil {
+			return "", nil, err
+		}
+		if view.Contains(nextOID) {
+			return nextOID, value, nil
+		}
+		current = nextOID
+	}
+}
+
+// getBulkInView collects up to maxRepetitions successive OIDs visible in view.
+func (a *Agent) getBulkInView(oid string, maxRepetitions int, view *MIBView) ([]OIDResult, error) {
+	if view == nil {
+		return a.HandleGetBulk(oid, maxRepetitions)
+	}
+
+	results := make([]OIDResult, 0, maxRepetitions)
+	current := oid
+	for len(results) < maxRepetitions {
+		nextOID, value, err := a.getNextInView(current, view)
+		if err != nil {
+			break
+		}
+		results = append(results, OIDResult{OID: nextOID, Value: value})
+		current = nextOID
+	}
+	return results, nil
+}
+
 // OIDResult represents an OID and its value
 type OIDResult struct {
 	OID   string
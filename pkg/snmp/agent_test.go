@@ -2,6 +2,7 @@ package snmp
 
 import (
 	"fmt"
+	"log"
 	"net"
 	"os"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/gosnmp/gosnmp"
 	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
 )
 
 // createTestDevice creates a test device configuration
@@ -657,6 +659,48 @@ func TestAgent_LoadWalkFile(t *testing.T) {
 	}
 }
 
+// TestAgent_LoadWalkFile_Opaque tests that Opaque values in net-snmp's walk
+// formats load as their encoded bytes or float and export in the same format
+func TestAgent_LoadWalkFile_Opaque(t *testing.T) {
+	agent := NewAgent(createTestDevice(), 0)
+	walkFile := t.TempDir() + "/opaque.walk"
+	walkContent := `.1.3.6.1.4.1.9999.2.1.0 = Opaque: 04 03 41 42 43
+.1.3.6.1.4.1.9999.2.2.0 = Opaque: Float: 1.500000
+.1.3.6.1.4.1.9999.2.3.0 = Opaque: Double: 0.25
+.1.3.6.1.4.1.9999.2.4.0 = Unsigned32: 4000000000
+`
+	if err := os.WriteFile(walkFile, []byte(walkContent), 0644); err != nil {
+		t.Fatalf("Failed to create walk file: %v", err)
+	}
+	if err := agent.LoadWalkFile(walkFile); err != nil {
+		t.Fatalf("LoadWalkFile failed: %v", err)
+	}
+
+	tests := []struct {
+		oid       string
+		wantType  gosnmp.Asn1BER
+		wantValue interface{}
+		wantLine  string
+	}{
+		{"1.3.6.1.4.1.9999.2.1.0", gosnmp.Opaque, []byte{0x04, 0x03, 'A', 'B', 'C'}, "Opaque: 04 03 41 42 43"},
+		{"1.3.6.1.4.1.9999.2.2.0", gosnmp.OpaqueFloat, float32(1.5), "Opaque: Float: 1.5"},
+		{"1.3.6.1.4.1.9999.2.3.0", gosnmp.OpaqueDouble, 0.25, "Opaque: Double: 0.25"},
+		{"1.3.6.1.4.1.9999.2.4.0", gosnmp.Gauge32, uint(4000000000), "Gauge32: 4000000000"},
+	}
+	for _, tt := range tests {
+		value, err := agent.HandleGet(tt.oid)
+		if err != nil {
+			t.Fatalf("HandleGet(%s) failed: %v", tt.oid, err)
+		}
+		if value.Type != tt.wantType || fmt.Sprintf("%#v", value.Value) != fmt.Sprintf("%#v", tt.wantValue) {
+			t.Errorf("%s = %v %#v, want %v %#v", tt.oid, value.Type, value.Value, tt.wantType, tt.wantValue)
+		}
+		if line := formatWalkEntry(tt.oid, value); !strings.HasSuffix(line, " = "+tt.wantLine) {
+			t.Errorf("%s exports as %q, want %q", tt.oid, line, tt.wantLine)
+		}
+	}
+}
+
 // TestAgent_LoadWalkFile_EmptyPath tests loading with empty path
 func TestAgent_LoadWalkFile_EmptyPath(t *testing.T) {
 	device := createTestDevice()
@@ -739,6 +783,59 @@ func TestAgent_ProcessPDU_GetBulkRequest(t *testing.T) {
 	}
 }
 
+// TestAgent_ProcessPDU_MaxMessageSize tests that a GET-BULK response is cut to
+// the repetitions that fit in max_message_size and that a GET that cannot fit
+// answers tooBig
+func TestAgent_ProcessPDU_MaxMessageSize(t *testing.T) {
+	device := createTestDevice()
+	device.SNMPConfig.MaxMessageSize = config.MinSNMPMaxMessageSize
+	agent := NewAgent(device, 0)
+	descr := strings.Repeat("x", 40)
+	for i := 1; i <= 50; i++ {
+		if err := agent.SetOID(fmt.Sprintf("1.3.6.1.2.1.2.2.1.2.%d", i), &OIDValue{Type: gosnmp.OctetString, Value: descr}); err != nil {
+			t.Fatalf("SetOID: %v", err)
+		}
+	}
+	bulk := []gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.2.2.1.2", Type: gosnmp.Null}}
+
+	response := agent.ProcessPDU(gosnmp.GetBulkRequest, bulk, 50)
+	if len(response) == 0 || len(response) >= 50 {
+		t.Fatalf("GET-BULK of 50 returned %d varbinds, want fewer that fit in %d bytes", len(response), config.MinSNMPMaxMessageSize)
+	}
+	packet := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, Community: "public", PDUType: gosnmp.GetResponse, RequestID: 1, Variables: response}
+	encoded, err := packet.MarshalMsg()
+	if err != nil {
+		t.Fatalf("MarshalMsg: %v", err)
+	}
+	if len(encoded) > config.MinSNMPMaxMessageSize {
+		t.Errorf("response is %d bytes, want at most %d", len(encoded), config.MinSNMPMaxMessageSize)
+	}
+	// Each varbind is ~60 bytes, so a 484-byte message holds 6 or 7
+	if len(response) < 6 {
+		t.Errorf("GET-BULK returned %d varbinds, want as many as fit (6 or more)", len(response))
+	}
+
+	// A GET of more than fits answers tooBig with no varbinds
+	get := make([]gosnmp.SnmpPDU, 20)
+	for i := range get {
+		get[i] = gosnmp.SnmpPDU{Name: fmt.Sprintf("1.3.6.1.2.1.2.2.1.2.%d", i+1), Type: gosnmp.Null}
+	}
+	budget := VarbindBudget(&gosnmp.SnmpPacket{Version: gosnmp.Version2c, Community: "public", PDUType: gosnmp.GetResponse}, agent.MaxMessageSize())
+	vars, status := agent.ProcessPDUWithLimit(gosnmp.GetRequest, get, 0, nil, budget)
+	if status != gosnmp.TooBig || len(vars) != 0 {
+		t.Errorf("GET of 20 large objects = %d varbinds, status %v, want tooBig with none", len(vars), status)
+	}
+	if vars, status := agent.ProcessPDUWithLimit(gosnmp.GetRequest, get[:2], 0, nil, budget); status != gosnmp.NoError || len(vars) != 2 {
+		t.Errorf("GET of 2 objects = %d varbinds, status %v, want both", len(vars), status)
+	}
+
+	// The default limit returns every repetition
+	device.SNMPConfig.MaxMessageSize = 0
+	if response := agent.ProcessPDU(gosnmp.GetBulkRequest, bulk, 50); len(response) != 50 {
+		t.Errorf("GET-BULK under the default limit returned %d varbinds, want 50", len(response))
+	}
+}
+
 // TestAgent_ProcessPDU_InvalidRequest tests ProcessPDU with invalid request type
 func TestAgent_ProcessPDU_InvalidRequest(t *testing.T) {
 	device := createTestDevice()
@@ -779,6 +876,51 @@ func TestAgent_ProcessPDU_NoSuchObject(t *testing.T) {
 	}
 }
 
+// TestAgent_ProcessPDU_NoSuchInstance tests that a GET of a missing instance
+// of a known scalar or column answers noSuchInstance, and an unknown OID
+// noSuchObject
+func TestAgent_ProcessPDU_NoSuchInstance(t *testing.T) {
+	device := createTestDevice()
+	agent := NewAgent(device, 0)
+	for i := 1; i <= 3; i++ {
+		if err := agent.SetOID(fmt.Sprintf("1.3.6.1.2.1.2.2.1.2.%d", i), &OIDValue{Type: gosnmp.OctetString, Value: fmt.Sprintf("eth%d", i)}); err != nil {
+			t.Fatalf("SetOID: %v", err)
+		}
+	}
+
+	tests := []struct {
+		oid  string
+		want gosnmp.Asn1BER
+	}{
+		{"1.3.6.1.2.1.1.5.1", gosnmp.NoSuchInstance},     // sysName with a wrong instance
+		{"1.3.6.1.2.1.1.5", gosnmp.NoSuchInstance},       // sysName without its instance
+		{"1.3.6.1.2.1.2.2.1.2.7", gosnmp.NoSuchInstance}, // ifDescr row that does not exist
+		{"1.3.6.1.2.1.1.99.0", gosnmp.NoSuchObject},      // unknown object in the system group
+		{"1.2.3.4.5.6.7.8.9", gosnmp.NoSuchObject},       // unknown branch
+	}
+	for _, tt := range tests {
+		response := agent.ProcessPDU(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: tt.oid, Type: gosnmp.Null}}, 0)
+		if len(response) != 1 || response[0].Type != tt.want {
+			t.Errorf("GET %s = %v, want %v", tt.oid, response, tt.want)
+		}
+	}
+
+	// Agents configured not to distinguish answer noSuchObject throughout
+	device.SNMPConfig.MissingInstance = config.SNMPMissingInstanceNoSuchObject
+	response := agent.ProcessPDU(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.1.5.1", Type: gosnmp.Null}}, 0)
+	if response[0].Type != gosnmp.NoSuchObject {
+		t.Errorf("GET sysName.1 with missing_instance no_such_object = %v, want noSuchObject", response[0].Type)
+	}
+
+	// Objects outside the view are always noSuchObject
+	device.SNMPConfig.MissingInstance = ""
+	view := NewMIBView(&config.SNMPView{Included: []string{"1.3.6.1.2.1.2"}})
+	response = agent.ProcessPDUWithView(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.1.5.1", Type: gosnmp.Null}}, 0, view)
+	if response[0].Type != gosnmp.NoSuchObject {
+		t.Errorf("GET outside the view = %v, want noSuchObject", response[0].Type)
+	}
+}
+
 // TestParseOID tests OID parsing
 func TestParseOID(t *testing.T) {
 	tests := []struct {
@@ -916,6 +1058,52 @@ func TestAgent_LargeWalkFile(t *testing.T) {
 	}
 }
 
+// TestAgent_WalkFileLimits tests that walk files over the OID or size cap are
+// rejected with a descriptive error and nothing from them is loaded
+func TestAgent_WalkFileLimits(t *testing.T) {
+	walkFile := t.TempDir() + "/oversized.walk"
+	var content strings.Builder
+	for i := 1; i <= 100; i++ {
+		content.WriteString(fmt.Sprintf(".1.3.6.1.4.1.9999.%d.0 = INTEGER: %d\n", i, i))
+	}
+	if err := os.WriteFile(walkFile, []byte(content.String()), 0644); err != nil {
+		t.Fatalf("Failed to create walk file: %v", err)
+	}
+
+	// Within the caps the whole file loads and is counted
+	agent := NewAgent(createTestDevice(), 0)
+	agent.SetWalkLimits(WalkLimits{MaxOIDs: 100})
+	if err := agent.LoadWalkFile(walkFile); err != nil {
+		t.Fatalf("LoadWalkFile at the OID cap failed: %v", err)
+	}
+	if got := agent.WalkOIDCount(); got != 100 {
+		t.Errorf("WalkOIDCount = %d, want 100", got)
+	}
+
+	// One OID over the cap rejects the file
+	agent = NewAgent(createTestDevice(), 0)
+	agent.SetWalkLimits(WalkLimits{MaxOIDs: 99})
+	err := agent.LoadWalkFile(walkFile)
+	if err == nil || !strings.Contains(err.Error(), "more than 99 OIDs") || !strings.Contains(err.Error(), "max_oids") {
+		t.Fatalf("Expected an OID cap error naming max_oids, got %v", err)
+	}
+	if _, err := agent.HandleGet("1.3.6.1.4.1.9999.1.0"); err == nil {
+		t.Error("Expected no OIDs from a rejected walk file")
+	}
+	if got := agent.WalkOIDCount(); got != 0 {
+		t.Errorf("WalkOIDCount after rejection = %d, want 0", got)
+	}
+
+	// So does a file over the size cap
+	_, err = ParseWalkFileWithLimits(walkFile, WalkLimits{MaxFileSize: int64(content.Len() - 1)})
+	if err == nil || !strings.Contains(err.Error(), "max_file_size") {
+		t.Errorf("Expected a size cap error naming max_file_size, got %v", err)
+	}
+	if _, err := ParseWalkFileWithLimits(walkFile, WalkLimits{MaxFileSize: int64(content.Len())}); err != nil {
+		t.Errorf("File at the size cap failed: %v", err)
+	}
+}
+
 // TestAgent_OIDTreeNavigation tests navigating OID tree structure
 func TestAgent_OIDTreeNavigation(t *testing.T) {
 	device := createTestDevice()
@@ -1035,3 +1223,796 @@ func BenchmarkAgent_LoadWalkFile(b *testing.B) {
 		_ = agent.LoadWalkFile(walkFile)
 	}
 }
+
+// TestAgentReboot tests that a reboot resets sysUpTime and emits a coldStart trap
+func TestAgentReboot(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	device := createTestDevice()
+	device.SNMPConfig.Traps = &config.TrapConfig{
+		Enabled:   true,
+		Receivers: []string{conn.LocalAddr().String()},
+	}
+	agent := NewAgent(device, 0)
+	ts, err := NewTrapSender(device.Name, device.IPAddresses[0], device.SNMPConfig.Traps, 0)
+	if err != nil {
+		t.Fatalf("NewTrapSender failed: %v", err)
+	}
+	agent.SetTrapSender(ts)
+	agent.SetOID("1.3.6.1.2.1.2.2.1.10.1", &OIDValue{Type: gosnmp.Counter32, Value: uint(12345)})
+
+	// Age the agent so the uptime drop is observable
+	agent.mu.Lock()
+	agent.startTime = time.Now().Add(-time.Hour)
+	agent.mu.Unlock()
+
+	before, err := agent.HandleGet("1.3.6.1.2.1.1.3.0")
+	if err != nil {
+		t.Fatalf("HandleGet sysUpTime failed: %v", err)
+	}
+
+	if err := agent.Reboot(); err != nil {
+		t.Fatalf("Reboot failed: %v", err)
+	}
+
+	after, err := agent.HandleGet("1.3.6.1.2.1.1.3.0")
+	if err != nil {
+		t.Fatalf("HandleGet sysUpTime after reboot failed: %v", err)
+	}
+	if after.Value.(uint32) >= before.Value.(uint32) {
+		t.Errorf("Expected sysUpTime to drop after reboot, before=%v after=%v", before.Value, after.Value)
+	}
+
+	boots, _ := agent.HandleGet(OIDSnmpEngineBoots)
+	if boots == nil || boots.Value.(int) != 2 {
+		t.Errorf("Expected snmpEngineBoots 2 after reboot, got %v", boots)
+	}
+	if _, err := agent.HandleGet("1.3.6.1.2.1.2.2.1.10.1"); err == nil {
+		t.Error("Expected SET value to be discarded after reboot")
+	}
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected coldStart trap, got error: %v", err)
+	}
+	packet, err := gosnmp.Default.UnmarshalTrap(buf[:n], false)
+	if err != nil {
+		t.Fatalf("UnmarshalTrap failed: %v", err)
+	}
+	var trapOID string
+	for _, v := range packet.Variables {
+		if v.Name == ".1.3.6.1.6.3.1.1.4.1.0" {
+			trapOID, _ = v.Value.(string)
+		}
+	}
+	if trapOID != OIDColdStart {
+		t.Errorf("Expected coldStart trap OID %s, got %q", OIDColdStart, trapOID)
+	}
+}
+
+// TestAgentCommunityView tests that a restricted community sees the system group but not the interface table
+func TestAgentCommunityView(t *testing.T) {
+	device := createTestDevice()
+	device.SNMPConfig.Communities = []config.SNMPCommunity{
+		{Name: "sysonly", View: &config.SNMPView{Included: []string{"1.3.6.1.2.1.1"}}},
+	}
+	agent := NewAgent(device, 0)
+	agent.SetOID("1.3.6.1.2.1.2.2.1.2.1", &OIDValue{Type: gosnmp.OctetString, Value: "GigabitEthernet0/1"})
+
+	if _, ok := agent.ViewFor("wrong"); ok {
+		t.Fatal("Expected unknown community to be rejected")
+	}
+	if view, ok := agent.ViewFor("public"); !ok || view != nil {
+		t.Fatal("Expected primary community to keep full access")
+	}
+	view, ok := agent.ViewFor("sysonly")
+	if !ok || view == nil {
+		t.Fatal("Expected restricted community to have a view")
+	}
+
+	// System group is readable
+	resp := agent.ProcessPDUWithView(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.1.5.0"}}, 0, view)
+	if resp[0].Type != gosnmp.OctetString || resp[0].Value != "test-device" {
+		t.Errorf("Expected sysName readable, got type %v value %v", resp[0].Type, resp[0].Value)
+	}
+
+	// Interface table is not
+	resp = agent.ProcessPDUWithView(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.2.2.1.2.1"}}, 0, view)
+	if resp[0].Type != gosnmp.NoSuchObject {
+		t.Errorf("Expected noSuchObject for ifDescr, got %v", resp[0].Type)
+	}
+
+	// Walking past the system group (ending with sysORTable) ends the view
+	resp = agent.ProcessPDUWithView(gosnmp.GetNextRequest, []gosnmp.SnmpPDU{{Name: OIDSysOREntry + ".4.99"}}, 0, view)
+	if resp[0].Type != gosnmp.EndOfMibView {
+		t.Errorf("Expected endOfMibView after system group, got %s (%v)", resp[0].Name, resp[0].Type)
+	}
+
+	// The full-access community still sees the interface table
+	resp = agent.ProcessPDU(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.2.1.2.2.1.2.1"}}, 0)
+	if resp[0].Value != "GigabitEthernet0/1" {
+		t.Errorf("Expected ifDescr for full view, got %v", resp[0].Value)
+	}
+}
+
+// TestAgentDisabledSubtrees tests that a disabled subtree is answered as
+// absent, and skipped by walks, even though the walk file has data there
+func TestAgentDisabledSubtrees(t *testing.T) {
+	device := createTestDevice()
+	device.SNMPConfig.DisabledSubtrees = []string{"1.3.6.1.4.1.9999.2"}
+	agent := NewAgent(device, 0)
+
+	walkFile := t.TempDir() + "/test.walk"
+	walkContent := `.1.3.6.1.4.1.9999.1.1.0 = STRING: "before"
+.1.3.6.1.4.1.9999.2.1.0 = STRING: "disabled feature"
+.1.3.6.1.4.1.9999.2.2.1 = INTEGER: 7
+.1.3.6.1.4.1.9999.3.1.0 = STRING: "after"
+`
+	if err := os.WriteFile(walkFile, []byte(walkContent), 0644); err != nil {
+		t.Fatalf("Failed to create walk file: %v", err)
+	}
+	if err := agent.LoadWalkFile(walkFile); err != nil {
+		t.Fatalf("LoadWalkFile failed: %v", err)
+	}
+
+	resp := agent.ProcessPDU(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.4.1.9999.2.1.0"}, {Name: "1.3.6.1.4.1.9999.3.1.0"}}, 0)
+	if resp[0].Type != gosnmp.NoSuchObject {
+		t.Errorf("Expected noSuchObject in the disabled subtree, got %v", resp[0].Type)
+	}
+	if resp[1].Value != "after" {
+		t.Errorf("Expected the rest of the walk file served, got %v", resp[1].Value)
+	}
+
+	// A walk of the enterprise subtree steps straight past the disabled part
+	var walked []string
+	for oid := "1.3.6.1.4.1.9999"; ; {
+		resp = agent.ProcessPDU(gosnmp.GetNextRequest, []gosnmp.SnmpPDU{{Name: oid}}, 0)
+		oid = strings.TrimPrefix(resp[0].Name, ".")
+		if resp[0].Type == gosnmp.EndOfMibView || !strings.HasPrefix(oid, "1.3.6.1.4.1.9999.") {
+			break
+		}
+		walked = append(walked, oid)
+	}
+	want := "1.3.6.1.4.1.9999.1.1.0 1.3.6.1.4.1.9999.3.1.0"
+	if strings.Join(walked, " ") != want {
+		t.Errorf("GET-NEXT walk = %v, want %v", walked, want)
+	}
+
+	resp = agent.ProcessPDU(gosnmp.GetBulkRequest, []gosnmp.SnmpPDU{{Name: "1.3.6.1.4.1.9999.1"}}, 3)
+	if len(resp) == 0 || strings.TrimPrefix(resp[0].Name, ".") != "1.3.6.1.4.1.9999.1.1.0" || strings.TrimPrefix(resp[1].Name, ".") != "1.3.6.1.4.1.9999.3.1.0" {
+		t.Errorf("GET-BULK did not skip the disabled subtree: %+v", resp)
+	}
+}
+
+// TestMIBViewLongestMatch tests that the most specific subtree decides visibility
+func TestMIBViewLongestMatch(t *testing.T) {
+	view := NewMIBView(&config.SNMPView{
+		Included: []string{"1.3.6.1.2.1", "1.3.6.1.2.1.2.2.1.2"},
+		Excluded: []string{"1.3.6.1.2.1.2"},
+	})
+
+	tests := []struct {
+		oid  string
+		want bool
+	}{
+		{"1.3.6.1.2.1.1.5.0", true},
+		{"1.3.6.1.2.1.2.2.1.10.1", false},
+		{"1.3.6.1.2.1.2.2.1.2.1", true},
+		{".1.3.6.1.2.1.1.1.0", true},
+		{"1.3.6.1.4.1.9", false},
+		{"1.3.6.1.2.10", false},
+	}
+	for _, tt := range tests {
+		if got := view.Contains(tt.oid); got != tt.want {
+			t.Errorf("Contains(%s) = %v, want %v", tt.oid, got, tt.want)
+		}
+	}
+}
+
+// TestAgentHostResources tests hrStorageTable/hrSWRunTable and injected disk and memory usage
+func TestAgentHostResources(t *testing.T) {
+	device := createTestDevice()
+	device.Type = "server"
+	device.SNMPConfig.HostResources = &config.HostResourcesConfig{
+		MemoryMB:          4096,
+		MemoryUsedPercent: 40,
+		SwapMB:            1024,
+		Filesystems: []config.HostFilesystem{
+			{Path: "/", SizeGB: 100, UsedPercent: 30},
+			{Path: "/var", SizeGB: 50, UsedPercent: 10},
+		},
+		Processes: []string{"sshd", "nginx"},
+	}
+
+	agent := NewAgent(device, 0)
+	sm := errors.NewStateManager()
+	agent.SetErrorStateManager(sm)
+
+	// walkStorage returns hrStorageDescr -> hrStorageUsed for every row
+	walkStorage := func() map[string]int {
+		t.Helper()
+		descrs := make(map[string]string)
+		used := make(map[string]int)
+		oid := OIDHrStorageEntry
+		for {
+			next, value, err := agent.HandleGetNext(oid)
+			if err != nil || !strings.HasPrefix(next, OIDHrStorageEntry+".") {
+				break
+			}
+			parts := strings.Split(strings.TrimPrefix(next, OIDHrStorageEntry+"."), ".")
+			switch parts[0] {
+			case "3":
+				descrs[parts[1]] = value.Value.(string)
+			case "6":
+				used[parts[1]] = value.Value.(int)
+			}
+			oid = next
+		}
+		result := make(map[string]int)
+		for index, descr := range descrs {
+			result[descr] = used[index]
+		}
+		return result
+	}
+
+	const rootSize = 100 * (1 << 30) / hrDiskAllocationUnit
+	const ramSize = 4096 * 1024 * 1024 / hrMemoryAllocationUnit
+
+	storage := walkStorage()
+	if len(storage) != 4 {
+		t.Fatalf("Expected RAM, swap and 2 filesystems, got %v", storage)
+	}
+	if storage["/"] != rootSize*30/100 {
+		t.Errorf("Expected baseline / used %d, got %d", rootSize*30/100, storage["/"])
+	}
+	if storage["Physical memory"] != ramSize*40/100 {
+		t.Errorf("Expected baseline RAM used %d, got %d", ramSize*40/100, storage["Physical memory"])
+	}
+
+	sm.SetError("192.168.1.1", "eth0", errors.ErrorTypeDisk, 90)
+	sm.SetError("192.168.1.1", "eth1", errors.ErrorTypeMemory, 75)
+
+	storage = walkStorage()
+	if storage["/"] != rootSize*90/100 {
+		t.Errorf("Expected injected 90%% disk to report %d used, got %d", rootSize*90/100, storage["/"])
+	}
+	if storage["Physical memory"] != ramSize*75/100 {
+		t.Errorf("Expected injected 75%% memory to report %d used, got %d", ramSize*75/100, storage["Physical memory"])
+	}
+	if storage["Swap space"] != (1024*1024*1024/hrMemoryAllocationUnit)*hrSwapUsedPercent/100 {
+		t.Errorf("Expected swap to ignore injected errors, got %d", storage["Swap space"])
+	}
+
+	// Errors injected for other devices do not apply
+	sm.ClearAll()
+	sm.SetError("192.168.1.99", "eth0", errors.ErrorTypeDisk, 95)
+	if storage = walkStorage(); storage["/"] != rootSize*30/100 {
+		t.Errorf("Expected other device's disk error to be ignored, got %d", storage["/"])
+	}
+
+	value, err := agent.HandleGet(OIDHrSystemProcesses)
+	if err != nil || value.Value.(uint) != 2 {
+		t.Errorf("Expected hrSystemProcesses 2, got %v (%v)", value, err)
+	}
+	value, err = agent.HandleGet(hrColumn(OIDHrSWRunEntry, 2, hrSWRunFirstPID+1))
+	if err != nil || value.Value.(string) != "nginx" {
+		t.Errorf("Expected second hrSWRunName nginx, got %v (%v)", value, err)
+	}
+}
+
+// TestAgentHostResourcesServerDefault tests that server devices get default tables and routers none
+func TestAgentHostResourcesServerDefault(t *testing.T) {
+	router := NewAgent(createTestDevice(), 0)
+	if _, err := router.HandleGet(OIDHrMemorySize); err == nil {
+		t.Error("Expected routers to have no HOST-RESOURCES-MIB")
+	}
+
+	device := createTestDevice()
+	device.Type = "server"
+	server := NewAgent(device, 0)
+	value, err := server.HandleGet(OIDHrMemorySize)
+	if err != nil || value.Value.(int) != config.DefaultHostMemoryMB*1024 {
+		t.Errorf("Expected default hrMemorySize, got %v (%v)", value, err)
+	}
+}
+
+func TestAgentComputedOID(t *testing.T) {
+	agent := NewAgent(createTestDevice(), 0)
+
+	var packets uint64 = 100
+	const oid = "1.3.6.1.2.1.4.3.0"
+	agent.RegisterComputedOID("."+oid, func() *OIDValue {
+		return &OIDValue{Type: gosnmp.Counter32, Value: uint(packets)}
+	})
+
+	value, err := agent.HandleGet(oid)
+	if err != nil || value.Value != uint(100) {
+		t.Fatalf("expected computed value 100, got %v (err %v)", value, err)
+	}
+	packets = 150
+	if value, _ = agent.HandleGet(oid); value.Value != uint(150) {
+		t.Errorf("expected value computed per request (150), got %v", value.Value)
+	}
+
+	// Counters restart from zero on reboot and keep counting afterwards
+	if err := agent.Reboot(); err != nil {
+		t.Fatalf("Reboot failed: %v", err)
+	}
+	if value, _ = agent.HandleGet(oid); value.Value != uint(0) {
+		t.Errorf("expected counter reset to 0 after reboot, got %v", value.Value)
+	}
+	packets = 175
+	if value, _ = agent.HandleGet(oid); value.Value != uint(25) {
+		t.Errorf("expected 25 after reboot, got %v", value.Value)
+	}
+}
+
+// TestAgentSysORTable tests that sysORTable lists the MIB modules the agent serves
+func TestAgentSysORTable(t *testing.T) {
+	// walkSysOR returns sysORID -> sysORDescr for every row
+	walkSysOR := func(agent *Agent) map[string]string {
+		t.Helper()
+		ids := make(map[string]string)
+		descrs := make(map[string]string)
+		oid := OIDSysORTable
+		for {
+			next, value, err := agent.HandleGetNext(oid)
+			if err != nil || !strings.HasPrefix(next, OIDSysOREntry+".") {
+				break
+			}
+			parts := strings.Split(strings.TrimPrefix(next, OIDSysOREntry+"."), ".")
+			switch parts[0] {
+			case "2":
+				ids[parts[1]] = value.Value.(string)
+			case "3":
+				descrs[parts[1]] = value.Value.(string)
+			case "4":
+				if value.Type != gosnmp.TimeTicks {
+					t.Errorf("Expected sysORUpTime TimeTicks, got %v", value.Type)
+				}
+			}
+			oid = next
+		}
+		result := make(map[string]string)
+		for index, id := range ids {
+			result[id] = descrs[index]
+		}
+		return result
+	}
+
+	device := createTestDevice()
+	rows := walkSysOR(NewAgent(device, 0))
+	if _, ok := rows["1.3.6.1.6.3.1"]; !ok {
+		t.Errorf("Expected SNMPv2-MIB in sysORTable, got %v", rows)
+	}
+	for _, oid := range []string{oidIfMIB, oidHostResources, oidEntityMIB} {
+		if _, ok := rows[oid]; ok {
+			t.Errorf("Expected %s absent without its objects, got %v", oid, rows)
+		}
+	}
+
+	device.Type = "server"
+	device.Interfaces = []config.Interface{{Name: "eth0", Speed: 1000}}
+	agent := NewAgent(device, 0)
+	rows = walkSysOR(agent)
+	if descr := rows[oidIfMIB]; !strings.Contains(descr, "IF-MIB") {
+		t.Errorf("Expected IF-MIB listed with interfaces configured, got %v", rows)
+	}
+	if _, ok := rows[oidHostResources]; !ok {
+		t.Errorf("Expected HOST-RESOURCES-MIB listed for server, got %v", rows)
+	}
+	if _, err := agent.HandleGet(OIDSysORLastChange); err != nil {
+		t.Errorf("Expected sysORLastChange: %v", err)
+	}
+
+	// A walk file adding vendor objects extends the table
+	walkFile := t.TempDir() + "/entity.walk"
+	walk := ".1.3.6.1.2.1.47.1.1.1.1.2.1 = STRING: \"Chassis\"\n" +
+		".1.3.6.1.4.1.9.9.13.1.3.1.3.1 = Gauge32: 30\n"
+	if err := os.WriteFile(walkFile, []byte(walk), 0o600); err != nil {
+		t.Fatalf("write walk file: %v", err)
+	}
+	if err := agent.LoadWalkFile(walkFile); err != nil {
+		t.Fatalf("LoadWalkFile: %v", err)
+	}
+	rows = walkSysOR(agent)
+	if _, ok := rows[oidEntityMIB]; !ok {
+		t.Errorf("Expected ENTITY-MIB after walk load, got %v", rows)
+	}
+	if _, ok := rows["1.3.6.1.4.1.9"]; !ok {
+		t.Errorf("Expected vendor enterprise MIB after walk load, got %v", rows)
+	}
+	if len(rows) != 5 {
+		t.Errorf("Expected 5 sysORTable rows, got %d: %v", len(rows), rows)
+	}
+
+	if err := agent.Reboot(); err != nil {
+		t.Fatalf("Reboot: %v", err)
+	}
+	if got := walkSysOR(agent); len(got) != 5 {
+		t.Errorf("Expected sysORTable rebuilt after reboot, got %v", got)
+	}
+}
+
+// TestAgentSysORTable_FromWalk tests that a sysORTable recorded in a walk file
+// survives loading with LoadWalkFile and LoadWalkFiles, replacing every row
+// the agent had built
+func TestAgentSysORTable_FromWalk(t *testing.T) {
+	walkFile := t.TempDir() + "/sysor.walk"
+	walk := ".1.3.6.1.2.1.1.9.1.2.1 = OID: .1.3.6.1.6.3.11.3.1.1\n" +
+		".1.3.6.1.2.1.1.9.1.2.2 = OID: .1.3.6.1.6.3.15.2.1.1\n" +
+		".1.3.6.1.2.1.1.9.1.3.1 = STRING: \"The MIB for Message Processing and Dispatching.\"\n" +
+		".1.3.6.1.2.1.1.9.1.3.2 = STRING: \"The management information definitions for the SNMP User-based Security Model.\"\n" +
+		".1.3.6.1.2.1.1.9.1.4.1 = Timeticks: (5) 0:00:00.05\n" +
+		".1.3.6.1.2.1.1.9.1.4.2 = Timeticks: (5) 0:00:00.05\n"
+	if err := os.WriteFile(walkFile, []byte(walk), 0o600); err != nil {
+		t.Fatalf("write walk file: %v", err)
+	}
+
+	// The recorded rows, and nothing else, are served
+	check := func(agent *Agent) {
+		t.Helper()
+		want := map[string]interface{}{
+			OIDSysOREntry + ".2.1": "1.3.6.1.6.3.11.3.1.1",
+			OIDSysOREntry + ".2.2": "1.3.6.1.6.3.15.2.1.1",
+			OIDSysOREntry + ".3.1": "The MIB for Message Processing and Dispatching.",
+			OIDSysOREntry + ".4.2": uint32(5),
+		}
+		for oid, value := range want {
+			got, err := agent.HandleGet(oid)
+			if err != nil {
+				t.Errorf("HandleGet(%s): %v", oid, err)
+				continue
+			}
+			if v, ok := got.Value.(string); ok {
+				got.Value = strings.TrimPrefix(v, ".")
+			}
+			if got.Value != value {
+				t.Errorf("%s = %v, want %v", oid, got.Value, value)
+			}
+		}
+		rows := 0
+		for oid := OIDSysORTable; ; {
+			next, _, err := agent.HandleGetNext(oid)
+			if err != nil || !strings.HasPrefix(next, OIDSysOREntry+".") {
+				break
+			}
+			rows++
+			oid = next
+		}
+		if rows != 6 {
+			t.Errorf("sysORTable holds %d objects, want the 6 recorded", rows)
+		}
+	}
+
+	// A server with interfaces starts with more rows than the walk records
+	device := createTestDevice()
+	device.Type = "server"
+	device.Interfaces = []config.Interface{{Name: "eth0", Speed: 1000}}
+
+	agent := NewAgent(device, 0)
+	if err := agent.LoadWalkFile(walkFile); err != nil {
+		t.Fatalf("LoadWalkFile: %v", err)
+	}
+	check(agent)
+
+	agent = NewAgent(device, 0)
+	if err := agent.LoadWalkFiles([]string{walkFile}); err != nil {
+		t.Fatalf("LoadWalkFiles: %v", err)
+	}
+	check(agent)
+
+	if err := agent.Reboot(); err != nil {
+		t.Fatalf("Reboot: %v", err)
+	}
+	check(agent)
+}
+
+func TestAgentInterfaceMTU(t *testing.T) {
+	device := &config.Device{Name: "jumbo1", Type: "switch", MTU: config.JumboMTU}
+	agent := NewAgent(device, 0)
+
+	value, err := agent.HandleGet(OIDIfMtu + ".1")
+	if err != nil {
+		t.Fatalf("Expected ifMtu.1 for a jumbo device: %v", err)
+	}
+	if value.Value != config.JumboMTU {
+		t.Errorf("ifMtu.1 = %v, want %d", value.Value, config.JumboMTU)
+	}
+
+	// Ethernet rows from a walk file take the device MTU; the loopback keeps
+	// its own
+	walkFile := t.TempDir() + "/if.walk"
+	walk := ".1.3.6.1.2.1.2.2.1.3.1 = INTEGER: 6\n" +
+		".1.3.6.1.2.1.2.2.1.3.2 = INTEGER: 24\n" +
+		".1.3.6.1.2.1.2.2.1.4.1 = INTEGER: 1500\n" +
+		".1.3.6.1.2.1.2.2.1.4.2 = INTEGER: 65536\n"
+	if err := os.WriteFile(walkFile, []byte(walk), 0o600); err != nil {
+		t.Fatalf("write walk file: %v", err)
+	}
+	if err := agent.LoadWalkFile(walkFile); err != nil {
+		t.Fatalf("LoadWalkFile: %v", err)
+	}
+	for oid, want := range map[string]int{OIDIfMtu + ".1": config.JumboMTU, OIDIfMtu + ".2": 65536} {
+		value, err := agent.HandleGet(oid)
+		if err != nil {
+			t.Fatalf("HandleGet(%s): %v", oid, err)
+		}
+		if value.Value != want {
+			t.Errorf("%s = %v, want %d", oid, value.Value, want)
+		}
+	}
+
+	// Without an explicit MTU no ifMtu is invented
+	plain := NewAgent(&config.Device{Name: "plain"}, 0)
+	if _, err := plain.HandleGet(OIDIfMtu + ".1"); err == nil {
+		t.Error("Expected no ifMtu for a device without an MTU")
+	}
+}
+
+func TestAgentWalkSeries(t *testing.T) {
+	dir := t.TempDir()
+	snapshots := map[string]string{
+		"20240101T1000.walk": ".1.3.6.1.2.1.2.2.1.10.1 = Counter32: 1000\n" +
+			".1.3.6.1.2.1.2.2.1.10.2 = Counter32: 5\n",
+		"20240101T1005.walk": ".1.3.6.1.2.1.2.2.1.10.1 = Counter32: 4000\n",
+	}
+	var files []string
+	for _, name := range []string{"20240101T1000.walk", "20240101T1005.walk"} {
+		file := dir + "/" + name
+		if err := os.WriteFile(file, []byte(snapshots[name]), 0o600); err != nil {
+			t.Fatalf("write walk file: %v", err)
+		}
+		files = append(files, file)
+	}
+
+	agent := NewAgent(&config.Device{Name: "replay1"}, 0)
+	interval := 50 * time.Millisecond
+	if err := agent.LoadWalkSeries(files, interval, false); err != nil {
+		t.Fatalf("LoadWalkSeries: %v", err)
+	}
+
+	ifInOctets := func(index int) (interface{}, error) {
+		value, err := agent.HandleGet(fmt.Sprintf("1.3.6.1.2.1.2.2.1.10.%d", index))
+		if err != nil {
+			return nil, err
+		}
+		return value.Value, nil
+	}
+
+	if got, err := ifInOctets(1); err != nil || got != uint(1000) {
+		t.Fatalf("first snapshot: ifInOctets.1 = %v (%v), want 1000", got, err)
+	}
+	if _, err := ifInOctets(2); err != nil {
+		t.Fatalf("first snapshot: expected ifInOctets.2: %v", err)
+	}
+
+	time.Sleep(interval + 20*time.Millisecond)
+	if got, err := ifInOctets(1); err != nil || got != uint(4000) {
+		t.Errorf("second snapshot: ifInOctets.1 = %v (%v), want 4000", got, err)
+	}
+	if _, err := ifInOctets(2); err == nil {
+		t.Error("second snapshot: expected ifInOctets.2 removed")
+	}
+
+	// Without loop the last snapshot is held
+	time.Sleep(2 * interval)
+	if got, _ := ifInOctets(1); got != uint(4000) {
+		t.Errorf("after the series ended: ifInOctets.1 = %v, want 4000", got)
+	}
+}
+
+func TestAgentWalkDirectory(t *testing.T) {
+	base := t.TempDir()
+	dir := base + "/core1"
+	if err := os.Mkdir(dir, 0o750); err != nil {
+		t.Fatalf("create walk directory: %v", err)
+	}
+	walks := map[string]string{
+		// System and interfaces subtrees captured separately; both carry sysName
+		"a-system.walk": ".1.3.6.1.2.1.1.5.0 = STRING: \"core1-old\"\n" +
+			".1.3.6.1.2.1.1.6.0 = STRING: \"Lab\"\n",
+		"b-interfaces.walk": ".1.3.6.1.2.1.1.5.0 = STRING: \"core1\"\n" +
+			".1.3.6.1.2.1.2.2.1.2.1 = STRING: \"Gi0/1\"\n",
+		"notes.txt": ".1.3.6.1.4.1.9999.1.0 = STRING: \"not a walk\"\n",
+	}
+	for name, content := range walks {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0o600); err != nil {
+			t.Fatalf("write walk file: %v", err)
+		}
+	}
+
+	cfg, err := config.LoadYAMLBytes([]byte(`
+include_path: "` + base + `"
+devices:
+  - name: core1
+    mac: "00:11:22:33:44:55"
+    snmp_agent:
+      walk_directory: core1
+`))
+	if err != nil {
+		t.Fatalf("LoadYAMLBytes failed: %v", err)
+	}
+	device := &cfg.Devices[0]
+	if len(device.SNMPConfig.WalkFiles) != 2 {
+		t.Fatalf("WalkFiles = %v, want the two .walk files", device.SNMPConfig.WalkFiles)
+	}
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	agent := NewAgent(device, 1)
+	if err := agent.LoadWalkFiles(device.SNMPConfig.WalkFiles); err != nil {
+		t.Fatalf("LoadWalkFiles: %v", err)
+	}
+
+	for oid, want := range map[string]string{
+		"1.3.6.1.2.1.1.6.0":     "Lab",   // Only in the system walk
+		"1.3.6.1.2.1.2.2.1.2.1": "Gi0/1", // Only in the interfaces walk
+		"1.3.6.1.2.1.1.5.0":     "core1", // In both: the later file wins
+	} {
+		value, err := agent.HandleGet(oid)
+		if err != nil || value.Value != want {
+			t.Errorf("%s = %v (%v), want %q", oid, value, err, want)
+		}
+	}
+	if _, err := agent.HandleGet("1.3.6.1.4.1.9999.1.0"); err == nil {
+		t.Error("Expected files without the .walk extension to be skipped")
+	}
+
+	output := logs.String()
+	if !strings.Contains(output, "1.3.6.1.2.1.1.5.0 from "+dir+"/b-interfaces.walk overrides the value in "+dir+"/a-system.walk") {
+		t.Errorf("Expected the sysName conflict to be logged, got:\n%s", output)
+	}
+	if strings.Contains(output, "1.3.6.1.2.1.1.6.0 from") {
+		t.Errorf("Expected only conflicting OIDs to be logged, got:\n%s", output)
+	}
+
+	// The merged MIB survives a simulated reboot
+	if err := agent.Reboot(); err != nil {
+		t.Fatalf("Reboot: %v", err)
+	}
+	if value, err := agent.HandleGet("1.3.6.1.2.1.2.2.1.2.1"); err != nil || value.Value != "Gi0/1" {
+		t.Errorf("after reboot ifDescr.1 = %v (%v), want Gi0/1", value, err)
+	}
+}
+
+// TestAgentSNMPQuirks tests that each snmp_quirks entry breaks responses as
+// documented and that without it the agent stays RFC compliant
+func TestAgentSNMPQuirks(t *testing.T) {
+	longDescr := strings.Repeat("Cisco IOS Software, C3750E Software, Version 15.2(4)E10 ", 3)
+	mac := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	newAgent := func(quirks ...string) *Agent {
+		device := createTestDevice()
+		device.SNMPConfig.Quirks = quirks
+		agent := NewAgent(device, 0)
+		agent.SetOID("1.3.6.1.2.1.1.1.0", &OIDValue{Type: gosnmp.OctetString, Value: longDescr})
+		agent.SetOID("1.3.6.1.2.1.2.2.1.6.1", &OIDValue{Type: gosnmp.OctetString, Value: mac})
+		return agent
+	}
+	get := func(agent *Agent, oid string) gosnmp.SnmpPDU {
+		t.Helper()
+		resp := agent.ProcessPDU(gosnmp.GetRequest, []gosnmp.SnmpPDU{{Name: oid}}, 0)
+		if len(resp) != 1 {
+			t.Fatalf("GET %s returned %d varbinds", oid, len(resp))
+		}
+		return resp[0]
+	}
+	pastEnd := func(agent *Agent) gosnmp.SnmpPDU {
+		return agent.ProcessPDU(gosnmp.GetNextRequest, []gosnmp.SnmpPDU{{Name: "9.9.9.9"}}, 0)[0]
+	}
+
+	// Without quirks strings are intact and a walk ends with endOfMibView
+	agent := newAgent()
+	if descr := get(agent, "1.3.6.1.2.1.1.1.0"); descr.Value != longDescr {
+		t.Errorf("sysDescr = %q, want it unchanged", descr.Value)
+	}
+	if end := pastEnd(agent); end.Type != gosnmp.EndOfMibView {
+		t.Errorf("GET-NEXT past the end = %v, want endOfMibView", end.Type)
+	}
+
+	// trailing_null appends a NUL to text but not to binary strings
+	agent = newAgent(config.SNMPQuirkTrailingNull)
+	if descr := get(agent, "1.3.6.1.2.1.1.1.0"); descr.Value != longDescr+"\x00" {
+		t.Errorf("trailing_null sysDescr = %q, want a trailing NUL", descr.Value)
+	}
+	if name := get(agent, "1.3.6.1.2.1.1.5.0"); name.Value != "test-device\x00" {
+		t.Errorf("trailing_null sysName = %q, want a trailing NUL", name.Value)
+	}
+	if phys := get(agent, "1.3.6.1.2.1.2.2.1.6.1"); string(phys.Value.([]byte)) != string(mac) {
+		t.Errorf("trailing_null changed ifPhysAddress to %x", phys.Value)
+	}
+
+	// truncate_long_strings cuts text to SNMPQuirkStringLimit bytes
+	agent = newAgent(config.SNMPQuirkTruncateLongStrings)
+	if descr := get(agent, "1.3.6.1.2.1.1.1.0"); descr.Value != longDescr[:config.SNMPQuirkStringLimit] {
+		t.Errorf("truncate_long_strings sysDescr = %q, want the first %d bytes", descr.Value, config.SNMPQuirkStringLimit)
+	}
+	if name := get(agent, "1.3.6.1.2.1.1.5.0"); name.Value != "test-device" {
+		t.Errorf("truncate_long_strings changed short sysName to %q", name.Value)
+	}
+
+	// no_endofmibview answers past the end with the requested OID and NULL
+	agent = newAgent(config.SNMPQuirkNoEndOfMibView)
+	if end := pastEnd(agent); end.Type != gosnmp.Null || end.Name != "9.9.9.9" {
+		t.Errorf("no_endofmibview GET-NEXT past the end = %s %v, want 9.9.9.9 NULL", end.Name, end.Type)
+	}
+
+	// Quirks combine: the truncated string still gets its NUL
+	agent = newAgent(config.SNMPQuirkTruncateLongStrings, config.SNMPQuirkTrailingNull)
+	if descr := get(agent, "1.3.6.1.2.1.1.1.0"); descr.Value != longDescr[:config.SNMPQuirkStringLimit]+"\x00" {
+		t.Errorf("combined quirks sysDescr = %q", descr.Value)
+	}
+}
+
+// TestAgentProcessSet_Writable tests that SETs change only the objects in a
+// device's writable list, and that an empty list makes the device read-only
+func TestAgentProcessSet_Writable(t *testing.T) {
+	newAgent := func(writable []string) *Agent {
+		device := createTestDevice()
+		device.SNMPConfig.AdminStatusSet = config.SNMPAdminStatusSetLinkState
+		device.SNMPConfig.Writable = writable
+		return NewAgent(device, 0)
+	}
+	set := func(agent *Agent, oid string, typ gosnmp.Asn1BER, value interface{}) gosnmp.SNMPError {
+		_, status, _ := agent.ProcessSet([]gosnmp.SnmpPDU{{Name: oid, Type: typ, Value: value}}, nil)
+		return status
+	}
+	const (
+		sysContact  = "1.3.6.1.2.1.1.4.0"
+		sysName     = "1.3.6.1.2.1.1.5.0"
+		sysLocation = "1.3.6.1.2.1.1.6.0"
+		adminStatus = "1.3.6.1.2.1.2.2.1.7.1"
+	)
+
+	// An empty list rejects every SET and changes nothing
+	agent := newAgent([]string{})
+	for _, oid := range []string{sysContact, sysName, sysLocation} {
+		if status := set(agent, oid, gosnmp.OctetString, []byte("changed")); status != gosnmp.NotWritable {
+			t.Errorf("read-only device: SET %s = %v, want notWritable", oid, status)
+		}
+	}
+	if status := set(agent, adminStatus, gosnmp.Integer, ifStatusDown); status != gosnmp.NotWritable {
+		t.Errorf("read-only device: SET ifAdminStatus = %v, want notWritable", status)
+	}
+	if value, _ := agent.HandleGet(sysName); value.Value != "test-device" || !agent.InterfaceUp(1) {
+		t.Errorf("read-only device changed: sysName %v, interface up %v", value.Value, agent.InterfaceUp(1))
+	}
+
+	// Listed objects are writable, the rest are not
+	agent = newAgent([]string{config.SNMPWritableSysContact, config.SNMPWritableSysLocation})
+	if status := set(agent, sysContact, gosnmp.OctetString, []byte("noc@example.com")); status != gosnmp.NoError {
+		t.Fatalf("SET sysContact = %v, want noError", status)
+	}
+	if value, _ := agent.HandleGet(sysContact); value.Value != "noc@example.com" {
+		t.Errorf("sysContact = %v after SET, want noc@example.com", value.Value)
+	}
+	if status := set(agent, sysName, gosnmp.OctetString, []byte("renamed")); status != gosnmp.NotWritable {
+		t.Errorf("SET sysName = %v, want notWritable", status)
+	}
+	if status := set(agent, adminStatus, gosnmp.Integer, ifStatusDown); status != gosnmp.NotWritable {
+		t.Errorf("SET ifAdminStatus = %v, want notWritable", status)
+	}
+	if status := set(agent, sysLocation, gosnmp.Integer, 1); status != gosnmp.WrongType {
+		t.Errorf("SET sysLocation to an integer = %v, want wrongType", status)
+	}
+	if status := set(agent, sysLocation, gosnmp.OctetString, []byte(strings.Repeat("x", 256))); status != gosnmp.WrongLength {
+		t.Errorf("SET sysLocation to 256 bytes = %v, want wrongLength", status)
+	}
+
+	// Without a list only ifAdminStatus is writable, as before
+	agent = newAgent(nil)
+	if status := set(agent, adminStatus, gosnmp.Integer, ifStatusDown); status != gosnmp.NoError || agent.InterfaceUp(1) {
+		t.Errorf("default: SET ifAdminStatus = %v, want the interface shut", status)
+	}
+	if status := set(agent, sysContact, gosnmp.OctetString, []byte("noc")); status != gosnmp.NotWritable {
+		t.Errorf("default: SET sysContact = %v, want notWritable", status)
+	}
+}
@@ -0,0 +1,154 @@
+package snmp
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// computedOID is an object whose value is computed on each request. Counters
+// are reported relative to their value at the agent's last reboot, so they
+// restart from zero like the agent's other counters.
+type computedOID struct {
+	fn       func() *OIDValue
+	baseline atomic.Uint64
+}
+
+// RegisterComputedOID installs an object computed from live data on every GET
+// or walk, the way sysUpTime is. Computed objects override walk file values
+// and survive reboots and walk file reloads. fn must not return nil; Counter32
+// and Counter64 values must be unsigned integers.
+func (a *Agent) RegisterComputedOID(oid string, fn func() *OIDValue) {
+	oid = strings.TrimPrefix(oid, ".")
+	c := &computedOID{fn: fn}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.computed == nil {
+		a.computed = make(map[string]*computedOID)
+	}
+	a.computed[oid] = c
+	a.mib.SetDynamic(oid, c.value)
+}
+
+// applyComputedOIDs reinstalls computed objects after the MIB was rebuilt or
+// overwritten. When rebase is set, counters restart from zero.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) applyComputedOIDs(rebase bool) {
+	for oid, c := range a.computed {
+		if rebase {
+			c.rebase()
+		}
+		a.mib.SetDynamic(oid, c.value)
+	}
+	now := time.Now()
+	for prefix, table := range a.tables {
+		a.rebuildComputedTable(prefix, table, now)
+	}
+}
+
+// value returns the current value, counters offset by the reboot baseline
+func (c *computedOID) value() *OIDValue {
+	v := c.fn()
+	switch v.Type {
+	case gosnmp.Counter32:
+		return &OIDValue{Type: v.Type, Value: uint((counterValue(v.Value) - c.baseline.Load()) & 0xffffffff)}
+	case gosnmp.Counter64:
+		return &OIDValue{Type: v.Type, Value: counterValue(v.Value) - c.baseline.Load()}
+	}
+	return v
+}
+
+// rebase records the current raw counter value as the new zero point
+func (c *computedOID) rebase() {
+	v := c.fn()
+	if v.Type == gosnmp.Counter32 || v.Type == gosnmp.Counter64 {
+		c.baseline.Store(counterValue(v.Value))
+	}
+}
+
+// counterValue converts an unsigned counter value to uint64
+func counterValue(value interface{}) uint64 {
+	switch n := value.(type) {
+	case uint:
+		return uint64(n)
+	case uint32:
+		return uint64(n)
+	case uint64:
+		return n
+	}
+	return 0
+}
+
+// computedTableRefresh bounds how often a computed table is rebuilt, so a walk
+// spanning many requests sees one consistent table.
+const computedTableRefresh = time.Second
+
+// computedTable is a subtree rebuilt from live data, for tables whose rows
+// come and go (e.g. a MAC forwarding table).
+type computedTable struct {
+	fn        func() []OIDResult
+	refreshed time.Time
+}
+
+// RegisterComputedTable installs a subtree whose objects are produced by fn.
+// Every object under prefix is replaced by fn's rows at most once per second,
+// when the agent is polled. Like computed objects, computed tables override
+// walk file values and survive reboots and walk file reloads.
+func (a *Agent) RegisterComputedTable(prefix string, fn func() []OIDResult) {
+	prefix = strings.TrimPrefix(prefix, ".")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.tables == nil {
+		a.tables = make(map[string]*computedTable)
+	}
+	table := &computedTable{fn: fn}
+	a.tables[prefix] = table
+	a.rebuildComputedTable(prefix, table, time.Now())
+}
+
+// refreshComputedTables rebuilds tables older than computedTableRefresh.
+// Called at the start of every request.
+func (a *Agent) refreshComputedTables() {
+	now := time.Now()
+	a.mu.RLock()
+	stale := false
+	for _, table := range a.tables {
+		if now.Sub(table.refreshed) >= computedTableRefresh {
+			stale = true
+			break
+		}
+	}
+	a.mu.RUnlock()
+	if !stale {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for prefix, table := range a.tables {
+		if now.Sub(table.refreshed) >= computedTableRefresh {
+			a.rebuildComputedTable(prefix, table, now)
+		}
+	}
+}
+
+// rebuildComputedTable replaces everything under prefix with the table's
+// current rows.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) rebuildComputedTable(prefix string, table *computedTable, now time.Time) {
+	var stale []string
+	for oid, _ := a.mib.GetNext(prefix); strings.HasPrefix(oid, prefix+"."); oid, _ = a.mib.GetNext(oid) {
+		stale = append(stale, oid)
+	}
+	for _, oid := range stale {
+		a.mib.Delete(oid)
+	}
+	for _, row := range table.fn() {
+		a.mib.Set(strings.TrimPrefix(row.OID, "."), row.Value)
+	}
+	table.refreshed = now
+}
@@ -0,0 +1,117 @@
+package snmp
+
+import (
+	"math"
+	"net"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// encodeVarbinds converts each varbind's value to the Go type gosnmp
+// marshals for its ASN.1 type. Values reach the MIB with whatever integer
+// type their source used (walk files, SETs, computed OIDs, integrations), and
+// gosnmp fails the whole message for a Counter64 that is not a uint64 or a
+// Gauge32 that is an int, and panics on an IpAddress that is not IPv4.
+func encodeVarbinds(vars []gosnmp.SnmpPDU) {
+	for i := range vars {
+		vars[i].Value = encodeValue(vars[i].Type, vars[i].Value)
+	}
+}
+
+// encodeValue returns value as the type gosnmp marshals for berType:
+//   - Integer: int within the INTEGER (Integer32) range
+//   - Counter32, TimeTicks: uint32, wrapped like the 32-bit counter it is
+//   - Gauge32 (Unsigned32), Uinteger32: uint32, latched at the maximum (RFC 2578)
+//   - Counter64: uint64
+//   - IpAddress: 4 bytes; a value that is not an IPv4 address is 0.0.0.0
+//   - Opaque: []byte
+//
+// Negative values of unsigned types become 0. Values of other types, and
+// values that are not numbers where one is needed, are returned unchanged.
+func encodeValue(berType gosnmp.Asn1BER, value interface{}) interface{} {
+	switch berType {
+	case gosnmp.Integer:
+		if n, ok := signedValue(value); ok {
+			return int(max(math.MinInt32, min(math.MaxInt32, n)))
+		}
+	case gosnmp.Counter32, gosnmp.TimeTicks:
+		if n, ok := unsignedValue(value); ok {
+			return uint32(n & math.MaxUint32)
+		}
+	case gosnmp.Gauge32, gosnmp.Uinteger32:
+		if n, ok := unsignedValue(value); ok {
+			return uint32(min(n, math.MaxUint32))
+		}
+	case gosnmp.Counter64:
+		if n, ok := unsignedValue(value); ok {
+			return n
+		}
+	case gosnmp.IPAddress:
+		return ipAddressValue(value)
+	case gosnmp.Opaque:
+		if s, ok := value.(string); ok {
+			return []byte(s)
+		}
+	}
+	return value
+}
+
+// signedValue returns an integer value of any Go integer type as an int64;
+// uint64 values beyond its range saturate
+func signedValue(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	if n, ok := unsignedValue(value); ok {
+		return int64(min(n, math.MaxInt64)), true
+	}
+	return 0, false
+}
+
+// unsignedValue returns an integer value of any Go integer type as a
+// uint64; negative values are 0
+func unsignedValue(value interface{}) (uint64, bool) {
+	switch n := value.(type) {
+	case uint:
+		return uint64(n), true
+	case uint8:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	case int, int8, int16, int32, int64:
+		s, _ := signedValue(n)
+		return uint64(max(s, 0)), true
+	}
+	return 0, false
+}
+
+// ipAddressValue returns the 4 bytes of an IpAddress value given as a
+// string, net.IP or []byte
+func ipAddressValue(value interface{}) []byte {
+	var ip net.IP
+	switch v := value.(type) {
+	case string:
+		ip = net.ParseIP(v)
+	case net.IP:
+		ip = v
+	case []byte:
+		ip = net.IP(v)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return []byte(v4)
+	}
+	return []byte{0, 0, 0, 0}
+}
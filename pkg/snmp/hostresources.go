@@ -0,0 +1,180 @@
+package snmp
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+	"github.com/krisarmstrong/niac-go/pkg/errors"
+)
+
+// HOST-RESOURCES-MIB objects (RFC 2790)
+const (
+	OIDHrSystemProcesses = "1.3.6.1.2.1.25.1.6.0"
+	OIDHrMemorySize      = "1.3.6.1.2.1.25.2.2.0"
+	OIDHrStorageEntry    = "1.3.6.1.2.1.25.2.3.1"
+	OIDHrSWRunEntry      = "1.3.6.1.2.1.25.4.2.1"
+
+	// hrStorageTypes
+	hrStorageRAM           = "1.3.6.1.2.1.25.2.1.2"
+	hrStorageVirtualMemory = "1.3.6.1.2.1.25.2.1.3"
+	hrStorageFixedDisk     = "1.3.6.1.2.1.25.2.1.4"
+
+	// Allocation units in bytes
+	hrMemoryAllocationUnit = 1024
+	hrDiskAllocationUnit   = 4096
+
+	// hrSWRunType / hrSWRunStatus values
+	hrSWRunTypeApplication = 4
+	hrSWRunStatusRunning   = 1
+
+	// hrSWRunIndex of the first process (a plausible low PID)
+	hrSWRunFirstPID = 1
+
+	// Baseline swap usage; no error type injects swap pressure
+	hrSwapUsedPercent = 5
+)
+
+// hrStorageEntry is one row of hrStorageTable. Used tracks the injected error
+// type that overrides the baseline usage percentage.
+type hrStorageEntry struct {
+	storageType string
+	descr       string
+	units       int
+	size        int
+	usedPercent int
+	injectedBy  errors.ErrorType
+}
+
+// SetErrorStateManager lets injected "High Memory" and "High Disk" errors for
+// this device drive hrStorageUsed.
+func (a *Agent) SetErrorStateManager(sm *errors.StateManager) {
+	a.errorStates.Store(sm)
+}
+
+// hostResourcesConfig returns the configured host resources tables, falling
+// back to defaults for server devices.
+func (a *Agent) hostResourcesConfig() *config.HostResourcesConfig {
+	if hr := a.device.SNMPConfig.HostResources; hr != nil {
+		return hr
+	}
+	if a.device.Type == "server" {
+		return config.DefaultHostResourcesConfig()
+	}
+	return nil
+}
+
+// initializeHostResources installs hrStorageTable and hrSWRunTable for server
+// devices. hrStorageUsed is dynamic so injected errors take effect immediately.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) initializeHostResources() {
+	hr := a.hostResourcesConfig()
+	if hr == nil {
+		return
+	}
+
+	a.mib.Set(OIDHrMemorySize, &OIDValue{Type: gosnmp.Integer, Value: hr.MemoryMB * 1024}) // KBytes
+
+	storage := []hrStorageEntry{
+		{
+			storageType: hrStorageRAM,
+			descr:       "Physical memory",
+			units:       hrMemoryAllocationUnit,
+			size:        hr.MemoryMB * 1024 * 1024 / hrMemoryAllocationUnit,
+			usedPercent: hr.MemoryUsedPercent,
+			injectedBy:  errors.ErrorTypeMemory,
+		},
+	}
+	if hr.SwapMB > 0 {
+		storage = append(storage, hrStorageEntry{
+			storageType: hrStorageVirtualMemory,
+			descr:       "Swap space",
+			units:       hrMemoryAllocationUnit,
+			size:        hr.SwapMB * 1024 * 1024 / hrMemoryAllocationUnit,
+			usedPercent: hrSwapUsedPercent,
+		})
+	}
+	for _, fs := range hr.Filesystems {
+		storage = append(storage, hrStorageEntry{
+			storageType: hrStorageFixedDisk,
+			descr:       fs.Path,
+			units:       hrDiskAllocationUnit,
+			size:        fs.SizeGB * (1 << 30 / hrDiskAllocationUnit),
+			usedPercent: fs.UsedPercent,
+			injectedBy:  errors.ErrorTypeDisk,
+		})
+	}
+
+	for i, entry := range storage {
+		index := i + 1
+		a.mib.Set(hrColumn(OIDHrStorageEntry, 1, index), &OIDValue{Type: gosnmp.Integer, Value: index})
+		a.mib.Set(hrColumn(OIDHrStorageEntry, 2, index), &OIDValue{Type: gosnmp.ObjectIdentifier, Value: entry.storageType})
+		a.mib.Set(hrColumn(OIDHrStorageEntry, 3, index), &OIDValue{Type: gosnmp.OctetString, Value: entry.descr})
+		a.mib.Set(hrColumn(OIDHrStorageEntry, 4, index), &OIDValue{Type: gosnmp.Integer, Value: entry.units})
+		a.mib.Set(hrColumn(OIDHrStorageEntry, 5, index), &OIDValue{Type: gosnmp.Integer, Value: entry.size})
+
+		a.mib.SetDynamic(hrColumn(OIDHrStorageEntry, 6, index), func() *OIDValue {
+			percent := entry.usedPercent
+			if injected, ok := a.injectedUsage(entry.injectedBy); ok {
+				percent = injected
+			}
+			return &OIDValue{Type: gosnmp.Integer, Value: entry.size * percent / 100}
+		})
+
+		a.mib.Set(hrColumn(OIDHrStorageEntry, 7, index), &OIDValue{Type: gosnmp.Counter32, Value: uint(0)})
+	}
+
+	for i, name := range hr.Processes {
+		pid := hrSWRunFirstPID + i
+		a.mib.Set(hrColumn(OIDHrSWRunEntry, 1, pid), &OIDValue{Type: gosnmp.Integer, Value: pid})
+		a.mib.Set(hrColumn(OIDHrSWRunEntry, 2, pid), &OIDValue{Type: gosnmp.OctetString, Value: name})
+		a.mib.Set(hrColumn(OIDHrSWRunEntry, 3, pid), &OIDValue{Type: gosnmp.ObjectIdentifier, Value: "0.0"})
+		a.mib.Set(hrColumn(OIDHrSWRunEntry, 4, pid), &OIDValue{Type: gosnmp.OctetString, Value: "/usr/sbin/" + name})
+		a.mib.Set(hrColumn(OIDHrSWRunEntry, 5, pid), &OIDValue{Type: gosnmp.OctetString, Value: ""})
+		a.mib.Set(hrColumn(OIDHrSWRunEntry, 6, pid), &OIDValue{Type: gosnmp.Integer, Value: hrSWRunTypeApplication})
+		a.mib.Set(hrColumn(OIDHrSWRunEntry, 7, pid), &OIDValue{Type: gosnmp.Integer, Value: hrSWRunStatusRunning})
+	}
+	a.mib.Set(OIDHrSystemProcesses, &OIDValue{Type: gosnmp.Gauge32, Value: uint(len(hr.Processes))})
+
+	if a.debugLevel >= 2 {
+		log.Printf("Initialized HOST-RESOURCES-MIB for device %s (%d storage, %d processes)",
+			a.device.Name, len(storage), len(hr.Processes))
+	}
+}
+
+// injectedUsage returns the percentage injected for this device with the given
+// error type, if any.
+func (a *Agent) injectedUsage(errorType errors.ErrorType) (int, bool) {
+	sm := a.errorStates.Load()
+	if sm == nil || errorType == "" {
+		return 0, false
+	}
+
+	for _, state := range sm.GetAllStates() {
+		if state.ErrorType != errorType {
+			continue
+		}
+		for _, ip := range a.device.IPAddresses {
+			if ip.String() == state.DeviceIP {
+				return clampPercent(state.Value), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func clampPercent(value int) int {
+	if value < 0 {
+		return 0
+	}
+	if value > 100 {
+		return 100
+	}
+	return value
+}
+
+// hrColumn builds the OID of a table cell from its entry OID, column and index
+func hrColumn(entry string, column, index int) string {
+	return fmt.Sprintf("%s.%d.%d", entry, column, index)
+}
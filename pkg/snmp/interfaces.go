@@ -0,0 +1,76 @@
+package snmp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// IF-MIB ifTable columns (RFC 2863)
+const (
+	OIDIfType = "1.3.6.1.2.1.2.2.1.3"
+	OIDIfMtu  = "1.3.6.1.2.1.2.2.1.4"
+
+	ifTypeEthernetCsmacd = 6
+)
+
+// IF-MIB ifXTable columns (RFC 2863)
+const (
+	OIDIfName  = "1.3.6.1.2.1.31.1.1.1.1"
+	OIDIfAlias = "1.3.6.1.2.1.31.1.1.1.18"
+)
+
+// applyInterfaceMTU reports a configured device MTU in ifMtu. Every Ethernet
+// row of an ifTable loaded from a walk file is updated; with no ifTable the
+// primary interface (ifIndex 1) is created. Devices without an explicit MTU
+// keep whatever the walk file recorded.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) applyInterfaceMTU() {
+	if a.device.MTU == 0 {
+		return
+	}
+	mtu := &OIDValue{Type: gosnmp.Integer, Value: a.device.MTU}
+
+	var rows []string
+	for oid, _ := a.mib.GetNext(OIDIfMtu); strings.HasPrefix(oid, OIDIfMtu+"."); oid, _ = a.mib.GetNext(oid) {
+		rows = append(rows, oid)
+	}
+	if len(rows) == 0 {
+		a.mib.Set(OIDIfMtu+".1", mtu)
+		return
+	}
+
+	for _, oid := range rows {
+		index := strings.TrimPrefix(oid, OIDIfMtu+".")
+		if ifType := a.mib.Get(OIDIfType + "." + index); ifType != nil {
+			if t, ok := ifType.Value.(int); ok && t != ifTypeEthernetCsmacd {
+				continue
+			}
+		}
+		a.mib.Set(oid, mtu)
+	}
+}
+
+// applyInterfaceDescriptions reports the configured interfaces, in ifIndex
+// order, over any walk file values. ifName is the interface name. A
+// description is reported as both ifDescr and ifAlias, matching the LLDP Port
+// Description; without one, ifDescr falls back to the name.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) applyInterfaceDescriptions() {
+	for i, iface := range a.device.Interfaces {
+		index := "." + strconv.Itoa(i+1)
+		if a.mib.Get(OIDIfIndex+index) == nil {
+			a.mib.Set(OIDIfIndex+index, &OIDValue{Type: gosnmp.Integer, Value: i + 1})
+		}
+		a.mib.Set(OIDIfName+index, &OIDValue{Type: gosnmp.OctetString, Value: iface.Name})
+
+		if iface.Description != "" {
+			descr := &OIDValue{Type: gosnmp.OctetString, Value: iface.Description}
+			a.mib.Set(OIDIfDescr+index, descr)
+			a.mib.Set(OIDIfAlias+index, descr)
+		} else if a.mib.Get(OIDIfDescr+index) == nil {
+			a.mib.Set(OIDIfDescr+index, &OIDValue{Type: gosnmp.OctetString, Value: iface.Name})
+		}
+	}
+}
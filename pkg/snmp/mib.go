@@ -152,6 +152,47 @@ func (m *MIB) Delete(oid string) {
 	}
 }
 
+// HasObject reports whether oid names an object the MIB holds instances of,
+// even though oid itself is not one of them. That is the case when oid is,
+// or is under, a scalar with a ".0" instance, or a column with instances
+// directly below it (ifDescr.7 when only ifDescr.1-3 exist). Without MIB
+// definitions, tables indexed by several sub-identifiers are only recognized
+// when the queried index shares all but its last sub-identifier with an
+// existing row.
+func (m *MIB) HasObject(oid string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	oid = strings.TrimPrefix(oid, ".")
+	under := func(prefix string) bool {
+		return oid == prefix || strings.HasPrefix(oid, prefix+".")
+	}
+
+	// Scalar: oid is the object or below its instance
+	for prefix := oid; prefix != ""; {
+		if _, ok := m.entries[prefix+".0"]; ok {
+			return true
+		}
+		i := strings.LastIndex(prefix, ".")
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+
+	// Column: some instance sits directly below a prefix of oid
+	for entry := range m.entries {
+		i := strings.LastIndex(entry, ".")
+		if i < 0 || entry[i+1:] == "0" {
+			continue
+		}
+		if under(entry[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
 // Count returns the number of OIDs in the MIB
 func (m *MIB) Count() int {
 	m.mu.RLock()
@@ -0,0 +1,85 @@
+package snmp
+
+import (
+	"math"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// lengthGrowthReserve covers the BER length fields of the enclosing
+// sequences (message, PDU, varbind list) growing from the short to the long
+// form as varbinds are added, which per-varbind sizes do not account for.
+const lengthGrowthReserve = 8
+
+// MaxMessageSize returns the largest response message the agent sends, from
+// the device's max_message_size
+func (a *Agent) MaxMessageSize() int {
+	if size := a.device.SNMPConfig.MaxMessageSize; size > 0 {
+		return size
+	}
+	return config.DefaultSNMPMaxMessageSize
+}
+
+// VarbindBudget returns the bytes left for variable bindings in a response
+// built from template (its Variables are ignored) when the whole message may
+// be at most maxSize bytes.
+func VarbindBudget(template *gosnmp.SnmpPacket, maxSize int) int {
+	header := *template
+	header.Variables = nil
+	return maxSize - encodedSize(&header) - lengthGrowthReserve
+}
+
+// defaultVarbindBudget is the budget of a response under the agent's own
+// limit, assuming an SNMPv2c header with an empty community
+func (a *Agent) defaultVarbindBudget() int {
+	return VarbindBudget(&gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: math.MaxInt32,
+	}, a.MaxMessageSize())
+}
+
+// varbindSizes returns the encoded size of each varbind
+func varbindSizes(vars []gosnmp.SnmpPDU) []int {
+	packet := &gosnmp.SnmpPacket{Version: gosnmp.Version2c, PDUType: gosnmp.GetResponse}
+	empty := encodedSize(packet)
+
+	sizes := make([]int, len(vars))
+	for i := range vars {
+		packet.Variables = vars[i : i+1]
+		if size := encodedSize(packet); size > empty {
+			sizes[i] = size - empty
+		}
+	}
+	return sizes
+}
+
+// encodedSize returns the length of packet once marshaled, or 0 if it cannot
+// be marshaled (the response fails to marshal later anyway)
+func encodedSize(packet *gosnmp.SnmpPacket) int {
+	encoded, err := packet.MarshalMsg()
+	if err != nil {
+		return 0
+	}
+	return len(encoded)
+}
+
+// fitResponse limits a response to budget bytes of varbinds. A GET-BULK
+// response keeps the varbinds that fit (fewer repetitions); any other
+// response that does not fit becomes tooBig with no varbinds (RFC 3416
+// sections 4.2.1 and 4.2.3).
+func fitResponse(pduType gosnmp.PDUType, vars []gosnmp.SnmpPDU, budget int) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+	total := 0
+	for i, size := range varbindSizes(vars) {
+		total += size
+		if total <= budget {
+			continue
+		}
+		if pduType == gosnmp.GetBulkRequest {
+			return vars[:i], gosnmp.NoError
+		}
+		return []gosnmp.SnmpPDU{}, gosnmp.TooBig
+	}
+	return vars, gosnmp.NoError
+}
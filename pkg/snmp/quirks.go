@@ -0,0 +1,69 @@
+package snmp
+
+import (
+	"unicode/utf8"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// applyQuirks deliberately breaks a response the way the device's configured
+// vendor quirks (snmp_quirks) do, so managers can be tested against agents
+// that are not RFC compliant. Without quirks the response is unchanged.
+func (a *Agent) applyQuirks(response []gosnmp.SnmpPDU) []gosnmp.SnmpPDU {
+	cfg := &a.device.SNMPConfig
+	if len(cfg.Quirks) == 0 {
+		return response
+	}
+	trailingNull := cfg.HasQuirk(config.SNMPQuirkTrailingNull)
+	truncate := cfg.HasQuirk(config.SNMPQuirkTruncateLongStrings)
+	noEndOfMibView := cfg.HasQuirk(config.SNMPQuirkNoEndOfMibView)
+
+	for i, v := range response {
+		switch {
+		case v.Type == gosnmp.EndOfMibView && noEndOfMibView:
+			// The walk never ends cleanly: the requested OID comes back again
+			response[i] = gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.Null}
+		case v.Type == gosnmp.OctetString && (trailingNull || truncate):
+			text, ok := textValue(v.Value)
+			if !ok {
+				continue // Binary strings such as MAC addresses are left alone
+			}
+			if truncate && len(text) > config.SNMPQuirkStringLimit {
+				text = text[:config.SNMPQuirkStringLimit]
+			}
+			if trailingNull {
+				text += "\x00"
+			}
+			if _, isBytes := v.Value.([]byte); isBytes {
+				response[i].Value = []byte(text)
+			} else {
+				response[i].Value = text
+			}
+		}
+	}
+	return response
+}
+
+// textValue returns an OCTET STRING value as text, or false if it holds
+// binary data rather than a printable string
+func textValue(value interface{}) (string, bool) {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return "", false
+	}
+	if !utf8.ValidString(text) {
+		return "", false
+	}
+	for _, r := range text {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return "", false
+		}
+	}
+	return text, true
+}
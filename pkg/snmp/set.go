@@ -0,0 +1,217 @@
+package snmp
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// IF-MIB ifTable columns changed by a SET of ifAdminStatus (RFC 2863)
+const (
+	OIDIfIndex       = "1.3.6.1.2.1.2.2.1.1"
+	OIDIfDescr       = "1.3.6.1.2.1.2.2.1.2"
+	OIDIfAdminStatus = "1.3.6.1.2.1.2.2.1.7"
+	OIDIfOperStatus  = "1.3.6.1.2.1.2.2.1.8"
+
+	ifStatusUp   = 1
+	ifStatusDown = 2
+)
+
+// SNMPv2-MIB system objects a SET can change (RFC 3418), by OID
+var writableSystemObjects = map[string]string{
+	"1.3.6.1.2.1.1.4.0": config.SNMPWritableSysContact,
+	"1.3.6.1.2.1.1.5.0": config.SNMPWritableSysName,
+	"1.3.6.1.2.1.1.6.0": config.SNMPWritableSysLocation,
+}
+
+// maxDisplayStringLen is the largest DisplayString (RFC 2579)
+const maxDisplayStringLen = 255
+
+// setChange is a change a SET makes: an interface's ifAdminStatus, or the
+// value of a system object
+type setChange struct {
+	ifIndex int // Interface whose ifAdminStatus changes (0 for a system object)
+	up      bool
+	oid     string // System object whose value changes
+	value   string
+}
+
+// ProcessSet handles a SET request. The writable objects are those the
+// device's writable list names; without a list only ifAdminStatus is, and
+// only when admin_status_set is link_state. Every other varbind fails the
+// request with notWritable. The request is applied only if every varbind is
+// valid (RFC 3416 section 4.2.5). It returns the response varbinds, error
+// status and 1-based error index.
+func (a *Agent) ProcessSet(vars []gosnmp.SnmpPDU, view *MIBView) ([]gosnmp.SnmpPDU, gosnmp.SNMPError, uint8) {
+	a.mu.Lock()
+	changes := make([]setChange, 0, len(vars))
+	for i, v := range vars {
+		change, status := a.checkSet(v, view)
+		if status != gosnmp.NoError {
+			a.mu.Unlock()
+			return vars, status, uint8(i + 1)
+		}
+		changes = append(changes, change)
+	}
+
+	var applied []setChange
+	for _, change := range changes {
+		if change.oid != "" {
+			a.mib.Set(change.oid, &OIDValue{Type: gosnmp.OctetString, Value: change.value})
+			if a.debugLevel >= 2 {
+				log.Printf("SNMP SET %s = %q (device: %s)", writableSystemObjects[change.oid], change.value, a.device.Name)
+			}
+			continue
+		}
+		if a.setAdminStatusLocked(change.ifIndex, change.up) {
+			applied = append(applied, change)
+		}
+	}
+	ts := a.trapSender
+	a.mu.Unlock()
+
+	// linkDown/linkUp announce the change, as a real agent does
+	for _, change := range applied {
+		if a.debugLevel >= 2 {
+			log.Printf("SNMP SET ifAdminStatus.%d up=%v (device: %s)", change.ifIndex, change.up, a.device.Name)
+		}
+		if ts == nil {
+			continue
+		}
+		descr := a.ifDescr(change.ifIndex)
+		var err error
+		if change.up {
+			err = ts.SendLinkUp(change.ifIndex, descr)
+		} else {
+			err = ts.SendLinkDown(change.ifIndex, descr)
+		}
+		if err != nil && a.debugLevel >= 1 {
+			log.Printf("SNMP link state trap failed for %s ifIndex %d: %v", a.device.Name, change.ifIndex, err)
+		}
+	}
+	return vars, gosnmp.NoError, 0
+}
+
+// checkSet validates one SET varbind.
+// Callers must hold a.mu.
+func (a *Agent) checkSet(v gosnmp.SnmpPDU, view *MIBView) (setChange, gosnmp.SNMPError) {
+	oid := strings.TrimPrefix(v.Name, ".")
+	if !view.Contains(oid) || a.isDisabled(oid) {
+		return setChange{}, gosnmp.NoAccess
+	}
+	if object, ok := writableSystemObjects[oid]; ok {
+		if !a.device.SNMPConfig.IsWritable(object) {
+			return setChange{}, gosnmp.NotWritable
+		}
+		return checkSystemObjectSet(oid, v)
+	}
+	if !a.device.SNMPConfig.IsWritable(config.SNMPWritableIfAdminStatus) || !strings.HasPrefix(oid, OIDIfAdminStatus+".") {
+		return setChange{}, gosnmp.NotWritable
+	}
+	ifIndex, err := strconv.Atoi(strings.TrimPrefix(oid, OIDIfAdminStatus+"."))
+	if err != nil || !a.hasInterfaceLocked(ifIndex) {
+		return setChange{}, gosnmp.NoCreation
+	}
+	if v.Type != gosnmp.Integer {
+		return setChange{}, gosnmp.WrongType
+	}
+	switch gosnmp.ToBigInt(v.Value).Int64() {
+	case ifStatusUp:
+		return setChange{ifIndex: ifIndex, up: true}, gosnmp.NoError
+	case ifStatusDown:
+		return setChange{ifIndex: ifIndex, up: false}, gosnmp.NoError
+	default:
+		// testing(3) is not simulated
+		return setChange{}, gosnmp.WrongValue
+	}
+}
+
+// checkSystemObjectSet validates a SET of a DisplayString system object
+func checkSystemObjectSet(oid string, v gosnmp.SnmpPDU) (setChange, gosnmp.SNMPError) {
+	if v.Type != gosnmp.OctetString {
+		return setChange{}, gosnmp.WrongType
+	}
+	var value string
+	switch raw := v.Value.(type) {
+	case []byte:
+		value = string(raw)
+	case string:
+		value = raw
+	default:
+		return setChange{}, gosnmp.WrongValue
+	}
+	if len(value) > maxDisplayStringLen {
+		return setChange{}, gosnmp.WrongLength
+	}
+	return setChange{oid: oid, value: value}, gosnmp.NoError
+}
+
+// hasInterfaceLocked reports whether ifIndex is a row of the device's
+// ifTable. The primary interface (ifIndex 1) always exists.
+// Callers must hold a.mu.
+func (a *Agent) hasInterfaceLocked(ifIndex int) bool {
+	if ifIndex == 1 {
+		return true
+	}
+	if ifIndex < 1 {
+		return false
+	}
+	suffix := "." + strconv.Itoa(ifIndex)
+	for _, column := range []string{OIDIfIndex, OIDIfDescr, OIDIfAdminStatus} {
+		if a.mib.Get(column+suffix) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// setAdminStatusLocked sets ifAdminStatus and ifOperStatus of an interface and
+// reports whether its admin state changed.
+// Callers must hold a.mu.
+func (a *Agent) setAdminStatusLocked(ifIndex int, up bool) bool {
+	if a.adminDown == nil {
+		a.adminDown = make(map[int]bool)
+	}
+	changed := a.adminDown[ifIndex] == up
+	if up {
+		delete(a.adminDown, ifIndex)
+	} else {
+		a.adminDown[ifIndex] = true
+	}
+
+	status := ifStatusUp
+	if !up {
+		status = ifStatusDown
+	}
+	suffix := "." + strconv.Itoa(ifIndex)
+	a.mib.Set(OIDIfAdminStatus+suffix, &OIDValue{Type: gosnmp.Integer, Value: status})
+	a.mib.Set(OIDIfOperStatus+suffix, &OIDValue{Type: gosnmp.Integer, Value: status})
+	return changed
+}
+
+// InterfaceUp reports whether an interface is administratively up. Only a SET
+// of ifAdminStatus shuts an interface; a reboot brings every interface back
+// up.
+func (a *Agent) InterfaceUp(ifIndex int) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return !a.adminDown[ifIndex]
+}
+
+// ifDescr returns the interface's ifDescr, or its configured name
+func (a *Agent) ifDescr(ifIndex int) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if value := a.mib.Get(OIDIfDescr + "." + strconv.Itoa(ifIndex)); value != nil {
+		if descr, ok := value.Value.(string); ok {
+			return descr
+		}
+	}
+	if ifIndex <= len(a.device.Interfaces) {
+		return a.device.Interfaces[ifIndex-1].Name
+	}
+	return ""
+}
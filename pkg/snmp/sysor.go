@@ -0,0 +1,110 @@
+package snmp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// SNMPv2-MIB sysORTable objects (RFC 3418)
+const (
+	OIDSysORLastChange = "1.3.6.1.2.1.1.8.0"
+	OIDSysORTable      = "1.3.6.1.2.1.1.9"
+	OIDSysOREntry      = "1.3.6.1.2.1.1.9.1"
+
+	// MIB subtrees whose presence decides which modules are advertised
+	oidInterfaces    = "1.3.6.1.2.1.2"
+	oidIfMIB         = "1.3.6.1.2.1.31"
+	oidHostResources = "1.3.6.1.2.1.25"
+	oidEntityMIB     = "1.3.6.1.2.1.47"
+	oidEnterprises   = "1.3.6.1.4.1"
+)
+
+// sysORRow is one sysORTable entry: a MIB module the agent implements.
+type sysORRow struct {
+	id    string
+	descr string
+}
+
+// sysORRows returns the MIB modules the agent actually serves. The system
+// group is always present; the others are listed only when their objects are
+// configured or were loaded from a walk file.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) sysORRows() []sysORRow {
+	rows := []sysORRow{
+		{id: "1.3.6.1.6.3.1", descr: "The MIB module for SNMP entities (SNMPv2-MIB)"},
+	}
+	if len(a.device.Interfaces) > 0 || a.hasSubtree(oidInterfaces) || a.hasSubtree(oidIfMIB) {
+		rows = append(rows, sysORRow{id: oidIfMIB, descr: "The MIB module to describe generic objects for network interface sub-layers (IF-MIB)"})
+	}
+	if a.hasSubtree(oidHostResources) {
+		rows = append(rows, sysORRow{id: oidHostResources, descr: "The MIB module for managing host systems (HOST-RESOURCES-MIB)"})
+	}
+	if a.hasSubtree(oidEntityMIB) {
+		rows = append(rows, sysORRow{id: oidEntityMIB, descr: "The MIB module for representing multiple logical entities (ENTITY-MIB)"})
+	}
+	if enterprise := a.enterpriseOID(); enterprise != "" && a.hasSubtree(enterprise) {
+		rows = append(rows, sysORRow{id: enterprise, descr: fmt.Sprintf("Vendor enterprise MIB (%s)", enterprise)})
+	}
+	return rows
+}
+
+// enterpriseOID returns the enterprise subtree named by sysObjectID, e.g.
+// 1.3.6.1.4.1.9 for a Cisco device.
+func (a *Agent) enterpriseOID() string {
+	value := a.mib.Get("1.3.6.1.2.1.1.2.0")
+	if value == nil {
+		return ""
+	}
+	sysObjectID, _ := value.Value.(string)
+	sysObjectID = strings.TrimPrefix(sysObjectID, ".")
+	rest, ok := strings.CutPrefix(sysObjectID, oidEnterprises+".")
+	if !ok {
+		return ""
+	}
+	number, _, _ := strings.Cut(rest, ".")
+	return oidEnterprises + "." + number
+}
+
+// hasSubtree reports whether any object exists below prefix.
+func (a *Agent) hasSubtree(prefix string) bool {
+	next, _ := a.mib.GetNext(prefix)
+	return strings.HasPrefix(next, prefix+".")
+}
+
+// initializeSysORTable (re)builds sysORTable from the modules currently in
+// the MIB. A sysORTable captured in a walk file is left as recorded.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) initializeSysORTable() {
+	// Remove the rows installed last time, unless a walk file has since
+	// replaced them, so the check below sees only recorded rows
+	for oid, value := range a.sysOROIDs {
+		if a.mib.Get(oid) == value {
+			a.mib.Delete(oid)
+		}
+	}
+	a.sysOROIDs = make(map[string]*OIDValue)
+	if a.hasSubtree(OIDSysORTable) {
+		return
+	}
+
+	// Rows are instantiated now, so sysORUpTime and sysORLastChange carry
+	// the current sysUpTime
+	uptime := uint32(time.Since(a.startTime).Milliseconds() / 10)
+	for i, row := range a.sysORRows() {
+		index := i + 1
+		a.setSysOR(fmt.Sprintf("%s.2.%d", OIDSysOREntry, index), &OIDValue{Type: gosnmp.ObjectIdentifier, Value: row.id})
+		a.setSysOR(fmt.Sprintf("%s.3.%d", OIDSysOREntry, index), &OIDValue{Type: gosnmp.OctetString, Value: row.descr})
+		a.setSysOR(fmt.Sprintf("%s.4.%d", OIDSysOREntry, index), &OIDValue{Type: gosnmp.TimeTicks, Value: uptime})
+	}
+	a.setSysOR(OIDSysORLastChange, &OIDValue{Type: gosnmp.TimeTicks, Value: uptime})
+}
+
+// setSysOR sets a sysORTable object and records it as installed by the agent.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) setSysOR(oid string, value *OIDValue) {
+	a.mib.Set(oid, value)
+	a.sysOROIDs[oid] = value
+}
@@ -0,0 +1,36 @@
+package snmp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DurationObserver receives the time an agent took to process a request
+type DurationObserver interface {
+	Observe(time.Duration)
+}
+
+// observerRef wraps a DurationObserver so it can be swapped atomically
+type observerRef struct {
+	observer DurationObserver
+}
+
+// requestObserver is shared by every agent; it is read on each request
+// without taking a lock
+var requestObserver atomic.Pointer[observerRef]
+
+// SetRequestDurationObserver makes every agent report how long it takes to
+// process each request (ProcessPDU and its variants) to observer. A nil
+// observer stops the reports.
+func SetRequestDurationObserver(observer DurationObserver) {
+	if observer == nil {
+		requestObserver.Store(nil)
+		return
+	}
+	requestObserver.Store(&observerRef{observer: observer})
+}
+
+// observeRequest reports the time since start to the request observer
+func observeRequest(ref *observerRef, start time.Time) {
+	ref.observer.Observe(time.Since(start))
+}
@@ -0,0 +1,165 @@
+package snmp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// Rate limit summary notification, sent to a receiver when a window in which
+// traps were suppressed closes. NIAC has no enterprise number of its own, so
+// it lives in the NET-SNMP experimental subtree (netSnmpPlaypen).
+const (
+	OIDTrapsSuppressed       = ".1.3.6.1.4.1.8072.9999.9999.1.1"
+	OIDTrapsSuppressedCount  = ".1.3.6.1.4.1.8072.9999.9999.1.2.1.0"
+	OIDTrapsSuppressedWindow = ".1.3.6.1.4.1.8072.9999.9999.1.2.2.0"
+	OIDTrapsSuppressedDetail = ".1.3.6.1.4.1.8072.9999.9999.1.2.3.0"
+)
+
+// trapLimiter enforces trap rate limits per receiver. It is shared by every
+// TrapSender so that many devices tripping a threshold at once draw from the
+// same receiver budget instead of each sending its own burst.
+type trapLimiter struct {
+	mu        sync.Mutex
+	receivers map[budgetKey]*receiverBudget
+}
+
+// budgetKey identifies a budget: devices share one only if they send to the
+// same receiver under the same limit, so each device's configured limit is
+// the one it is held to.
+type budgetKey struct {
+	receiver string
+	maxTraps int
+	window   time.Duration
+}
+
+// receiverBudget is one receiver's fixed window: how many traps were
+// delivered and which were suppressed since the window opened.
+type receiverBudget struct {
+	windowStart time.Time
+	delivered   int
+	suppressed  map[string]int // trap name -> count
+	flushing    bool
+}
+
+// suppressionReport delivers the summary of a closed window to the receiver.
+type suppressionReport func(window time.Duration, total int, detail string)
+
+// sharedTrapLimiter is the process-wide limiter used by all trap senders.
+var sharedTrapLimiter = &trapLimiter{receivers: make(map[budgetKey]*receiverBudget)}
+
+// allow reports whether a trap may be delivered to receiver under limit.
+// Suppressed traps are summarised once the window closes: the summary is
+// logged and handed to report, which sends it to the receiver.
+func (l *trapLimiter) allow(receiver, trapName string, limit *config.TrapRateLimit, debugLevel int, report suppressionReport) bool {
+	if limit == nil {
+		return true
+	}
+
+	now := time.Now()
+	key := budgetKey{receiver: receiver, maxTraps: limit.MaxTraps, window: limit.Window}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	budget, ok := l.receivers[key]
+	if !ok || now.Sub(budget.windowStart) >= limit.Window {
+		budget = &receiverBudget{windowStart: now, suppressed: make(map[string]int)}
+		l.receivers[key] = budget
+	}
+
+	if budget.delivered < limit.MaxTraps {
+		budget.delivered++
+		return true
+	}
+
+	budget.suppressed[trapName]++
+	if !budget.flushing {
+		// Report the suppressed traps when this window closes, even if no
+		// further trap arrives to open the next one
+		budget.flushing = true
+		time.AfterFunc(time.Until(budget.windowStart.Add(limit.Window)), func() {
+			l.mu.Lock()
+			delivered, total, detail := budget.summary()
+			l.mu.Unlock()
+
+			if debugLevel >= 1 {
+				log.Printf("Trap receiver %s: delivered %d, suppressed %d over rate limit (%s)",
+					receiver, delivered, total, detail)
+			}
+			if report != nil {
+				report(limit.Window, total, detail)
+			}
+		})
+	}
+	return false
+}
+
+// summary returns the traps delivered and suppressed in the window, with the
+// suppressed ones listed by name. Callers must hold the limiter lock.
+func (b *receiverBudget) summary() (delivered, total int, detail string) {
+	names := make([]string, 0, len(b.suppressed))
+	for name, count := range b.suppressed {
+		names = append(names, fmt.Sprintf("%s x%d", name, count))
+		total += count
+	}
+	sort.Strings(names)
+	return b.delivered, total, strings.Join(names, ", ")
+}
+
+// suppressedCount returns the traps suppressed for receiver in the current
+// window, across every limit it is sent under.
+func (l *trapLimiter) suppressedCount(receiver string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	total := 0
+	for key, budget := range l.receivers {
+		if key.receiver != receiver {
+			continue
+		}
+		for _, count := range budget.suppressed {
+			total += count
+		}
+	}
+	return total
+}
+
+// reportSuppressed sends receiver a summary of the traps suppressed in a rate
+// limit window. The summary itself is not rate limited.
+func (ts *TrapSender) reportSuppressed(receiver *gosnmp.GoSNMP, window time.Duration, total int, detail string) {
+	trap := gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(time.Now().Unix() % 4294967296)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: OIDTrapsSuppressed},
+			{Name: OIDTrapsSuppressedCount, Type: gosnmp.Counter32, Value: uint(total)},
+			{Name: OIDTrapsSuppressedWindow, Type: gosnmp.Integer, Value: int(window / time.Second)},
+			{Name: OIDTrapsSuppressedDetail, Type: gosnmp.OctetString, Value: detail},
+		},
+	}
+
+	// A client of its own, so the summary does not race a trap sent on receiver
+	client := &gosnmp.GoSNMP{
+		Target:    receiver.Target,
+		Port:      receiver.Port,
+		Community: receiver.Community,
+		Version:   receiver.Version,
+		Timeout:   receiver.Timeout,
+		Retries:   receiver.Retries,
+	}
+	if err := client.Connect(); err != nil {
+		if ts.debugLevel >= 2 {
+			log.Printf("[%s] Failed to connect to trap receiver %s for the rate limit summary: %v",
+				ts.deviceName, client.Target, err)
+		}
+		return
+	}
+	defer client.Conn.Close()
+	if _, err := client.SendTrap(trap); err != nil && ts.debugLevel >= 2 {
+		log.Printf("[%s] Failed to send the rate limit summary to %s: %v", ts.deviceName, client.Target, err)
+	}
+}
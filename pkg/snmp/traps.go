@@ -5,6 +5,7 @@ import (
 	"log"
 	"math/rand" // Note: math/rand used for simulation traffic generation (not security-critical)
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/gosnmp/gosnmp"
@@ -26,6 +27,7 @@ type TrapSender struct {
 	deviceName string
 	deviceIP   net.IP
 	trapConfig *config.TrapConfig
+	varbinds   []extraVarbind // Platform-specific varbinds appended to traps
 	// nolint:unused // Reserved for future SNMP trap sending
 	snmpClient *gosnmp.GoSNMP
 	receivers  []*gosnmp.GoSNMP
@@ -50,10 +52,16 @@ func NewTrapSender(deviceName string, deviceIP net.IP, trapConfig *config.TrapCo
 		return nil, fmt.Errorf("no trap receivers configured")
 	}
 
+	varbinds, err := compileTrapVarbinds(trapConfig.Varbinds)
+	if err != nil {
+		return nil, err
+	}
+
 	ts := &TrapSender{
 		deviceName: deviceName,
 		deviceIP:   deviceIP,
 		trapConfig: trapConfig,
+		varbinds:   varbinds,
 		receivers:  make([]*gosnmp.GoSNMP, 0),
 		stopChan:   make(chan struct{}),
 		debugLevel: debugLevel,
@@ -67,16 +75,14 @@ func NewTrapSender(deviceName string, deviceIP net.IP, trapConfig *config.TrapCo
 
 	// Initialize SNMP clients for each receiver
 	for _, receiver := range trapConfig.Receivers {
-		host, port, err := net.SplitHostPort(receiver)
+		ip, port, err := config.ParseTrapReceiver(receiver)
 		if err != nil {
-			// Assume port 162 if not specified
-			host = receiver
-			port = "162"
+			return nil, err
 		}
 
 		client := &gosnmp.GoSNMP{
-			Target:    host,
-			Port:      parsePort(port),
+			Target:    ip.String(),
+			Port:      port,
 			Community: community,
 			Version:   gosnmp.Version2c,
 			Timeout:   time.Duration(2) * time.Second,
@@ -89,16 +95,6 @@ func NewTrapSender(deviceName string, deviceIP net.IP, trapConfig *config.TrapCo
 	return ts, nil
 }
 
-// parsePort converts port string to uint16
-func parsePort(portStr string) uint16 {
-	var port int
-	fmt.Sscanf(portStr, "%d", &port)
-	if port < 1 || port > 65535 {
-		return 162 // Default SNMP trap port
-	}
-	return uint16(port)
-}
-
 // Start starts the trap sender and monitoring loops
 func (ts *TrapSender) Start() error {
 	if ts.running {
@@ -151,7 +147,7 @@ func (ts *TrapSender) Stop() {
 
 // SendColdStart sends a coldStart trap (device initialization/boot)
 func (ts *TrapSender) SendColdStart() error {
-	return ts.sendTrap(OIDColdStart, "coldStart", []gosnmp.SnmpPDU{})
+	return ts.sendTrap(OIDColdStart, TrapData{Trap: "coldStart"}, []gosnmp.SnmpPDU{})
 }
 
 // SendLinkDown sends a linkDown trap (interface went down)
@@ -167,7 +163,7 @@ func (ts *TrapSender) SendLinkDown(ifIndex int, ifDescr string) error {
 		{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.OctetString, Value: ifDescr}, // ifDescr
 	}
 
-	return ts.sendTrap(OIDLinkDown, "linkDown", varbinds)
+	return ts.sendTrap(OIDLinkDown, TrapData{Trap: "linkDown", IfIndex: ifIndex, IfDescr: ifDescr}, varbinds)
 }
 
 // SendLinkUp sends a linkUp trap (interface came up)
@@ -183,7 +179,7 @@ func (ts *TrapSender) SendLinkUp(ifIndex int, ifDescr string) error {
 		{Name: ".1.3.6.1.2.1.2.2.1.2", Type: gosnmp.OctetString, Value: ifDescr}, // ifDescr
 	}
 
-	return ts.sendTrap(OIDLinkUp, "linkUp", varbinds)
+	return ts.sendTrap(OIDLinkUp, TrapData{Trap: "linkUp", IfIndex: ifIndex, IfDescr: ifDescr}, varbinds)
 }
 
 // SendAuthenticationFailure sends an authenticationFailure trap
@@ -196,7 +192,7 @@ func (ts *TrapSender) SendAuthenticationFailure() error {
 		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(time.Now().Unix())}, // sysUpTime
 	}
 
-	return ts.sendTrap(OIDAuthenticationFailure, "authenticationFailure", varbinds)
+	return ts.sendTrap(OIDAuthenticationFailure, TrapData{Trap: "authenticationFailure"}, varbinds)
 }
 
 // monitorCPU monitors CPU utilization and sends traps when threshold is exceeded
@@ -279,7 +275,7 @@ func (ts *TrapSender) SendHighCPU(cpuPercent int) error {
 		log.Printf("[%s] High CPU trap: %d%% (threshold: %d%%)", ts.deviceName, cpuPercent, ts.trapConfig.HighCPU.Threshold)
 	}
 
-	return ts.sendTrap(".1.3.6.1.4.1.9.9.109.0.1", "highCPU", varbinds)
+	return ts.sendTrap(".1.3.6.1.4.1.9.9.109.0.1", TrapData{Trap: "highCPU", Value: cpuPercent}, varbinds)
 }
 
 // SendHighMemory sends a trap for high memory utilization
@@ -293,7 +289,7 @@ func (ts *TrapSender) SendHighMemory(memPercent int) error {
 		log.Printf("[%s] High Memory trap: %d%% (threshold: %d%%)", ts.deviceName, memPercent, ts.trapConfig.HighMemory.Threshold)
 	}
 
-	return ts.sendTrap(".1.3.6.1.4.1.9.9.48.0.1", "highMemory", varbinds)
+	return ts.sendTrap(".1.3.6.1.4.1.9.9.48.0.1", TrapData{Trap: "highMemory", Value: memPercent}, varbinds)
 }
 
 // SendInterfaceErrors sends a trap for high interface error count
@@ -309,11 +305,15 @@ func (ts *TrapSender) SendInterfaceErrors(ifIndex int, ifDescr string, errorCoun
 		log.Printf("[%s] Interface Errors trap: %d errors (threshold: %d)", ts.deviceName, errorCount, ts.trapConfig.InterfaceErrors.Threshold)
 	}
 
-	return ts.sendTrap(".1.3.6.1.2.1.2.15", "interfaceErrors", varbinds)
+	return ts.sendTrap(".1.3.6.1.2.1.2.15", TrapData{Trap: "interfaceErrors", IfIndex: ifIndex, IfDescr: ifDescr, Value: errorCount}, varbinds)
 }
 
-// sendTrap sends an SNMPv2c trap to all configured receivers
-func (ts *TrapSender) sendTrap(trapOID string, trapName string, varbinds []gosnmp.SnmpPDU) error {
+// sendTrap sends an SNMPv2c trap to all configured receivers, followed by any
+// configured trap_varbinds rendered with data
+func (ts *TrapSender) sendTrap(trapOID string, data TrapData, varbinds []gosnmp.SnmpPDU) error {
+	trapName := data.Trap
+	data.Device = ts.deviceName
+
 	// Build trap PDU
 	trap := gosnmp.SnmpTrap{
 		Variables: []gosnmp.SnmpPDU{
@@ -332,17 +332,31 @@ func (ts *TrapSender) sendTrap(trapOID string, trapName string, varbinds []gosnm
 
 	// Add custom varbinds
 	trap.Variables = append(trap.Variables, varbinds...)
+	trap.Variables = append(trap.Variables, ts.extraVarbinds(data)...)
 
-	// Send to all receivers
+	// Send to all receivers with budget left in their rate limit window
 	sentCount := 0
+	suppressed := 0
 	var lastErr error
 
 	for _, receiver := range ts.receivers {
+		address := net.JoinHostPort(receiver.Target, strconv.Itoa(int(receiver.Port)))
+		report := func(window time.Duration, total int, detail string) {
+			ts.reportSuppressed(receiver, window, total, detail)
+		}
+		if !sharedTrapLimiter.allow(address, trapName, ts.trapConfig.RateLimit, ts.debugLevel, report) {
+			suppressed++
+			if ts.debugLevel >= 3 {
+				log.Printf("[%s] Suppressed %s trap to %s (rate limit)", ts.deviceName, trapName, address)
+			}
+			continue
+		}
+
 		err := receiver.Connect()
 		if err != nil {
 			if ts.debugLevel >= 2 {
-				log.Printf("[%s] Failed to connect to trap receiver %s:%d: %v",
-					ts.deviceName, receiver.Target, receiver.Port, err)
+				log.Printf("[%s] Failed to connect to trap receiver %s: %v",
+					ts.deviceName, address, err)
 			}
 			lastErr = err
 			continue
@@ -353,15 +367,15 @@ func (ts *TrapSender) sendTrap(trapOID string, trapName string, varbinds []gosnm
 
 		if err != nil {
 			if ts.debugLevel >= 2 {
-				log.Printf("[%s] Failed to send trap to %s:%d: %v",
-					ts.deviceName, receiver.Target, receiver.Port, err)
+				log.Printf("[%s] Failed to send trap to %s: %v",
+					ts.deviceName, address, err)
 			}
 			lastErr = err
 		} else {
 			sentCount++
 			if ts.debugLevel >= 3 {
-				log.Printf("[%s] Sent %s trap to %s:%d",
-					ts.deviceName, trapName, receiver.Target, receiver.Port)
+				log.Printf("[%s] Sent %s trap to %s",
+					ts.deviceName, trapName, address)
 			}
 		}
 	}
@@ -371,8 +385,8 @@ func (ts *TrapSender) sendTrap(trapOID string, trapName string, varbinds []gosnm
 	}
 
 	if ts.debugLevel >= 2 && sentCount > 0 {
-		log.Printf("[%s] Sent %s trap to %d/%d receivers",
-			ts.deviceName, trapName, sentCount, len(ts.receivers))
+		log.Printf("[%s] Sent %s trap to %d/%d receivers (%d rate limited)",
+			ts.deviceName, trapName, sentCount, len(ts.receivers), suppressed)
 	}
 
 	return nil
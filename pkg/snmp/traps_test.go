@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gosnmp/gosnmp"
 	"github.com/krisarmstrong/niac-go/pkg/config"
 )
 
@@ -144,30 +145,56 @@ func TestNewTrapSender_NoReceivers(t *testing.T) {
 	}
 }
 
-// TestParsePort tests port parsing function
-func TestParsePort(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected uint16
-	}{
-		{"Standard trap port", "162", 162},
-		{"Custom port", "1162", 1162},
-		{"High port", "10162", 10162},
-		{"Port 1", "1", 1},
-		{"Max port", "65535", 65535},
-		{"Invalid port 0", "0", 162},            // Should default to 162
-		{"Invalid port too high", "70000", 162}, // Should default to 162
-		{"Invalid string", "abc", 162},          // Should default to 162
+// TestTrapSender_ReceiversOnDifferentPorts tests that each receiver gets the
+// trap on its own port, over IPv4 and IPv6
+func TestTrapSender_ReceiversOnDifferentPorts(t *testing.T) {
+	var conns []net.PacketConn
+	var receivers []string
+	for _, listen := range []struct{ network, addr string }{
+		{"udp4", "127.0.0.1:0"},
+		{"udp4", "127.0.0.1:0"},
+		{"udp6", "[::1]:0"},
+	} {
+		conn, err := net.ListenPacket(listen.network, listen.addr)
+		if err != nil {
+			if listen.network == "udp6" {
+				continue // No IPv6 loopback in this environment
+			}
+			t.Skipf("cannot open UDP listener: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+		receivers = append(receivers, conn.LocalAddr().String())
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := parsePort(tt.input)
-			if result != tt.expected {
-				t.Errorf("parsePort(%s) = %d, expected %d", tt.input, result, tt.expected)
-			}
-		})
+	trapConfig := &config.TrapConfig{
+		Enabled:   true,
+		Receivers: receivers,
+		ColdStart: &config.TrapTriggerConfig{Enabled: true},
+	}
+	ts, err := NewTrapSender("edge-sw", net.ParseIP("127.0.0.1"), trapConfig, 0)
+	if err != nil {
+		t.Fatalf("NewTrapSender failed: %v", err)
+	}
+	if err := ts.SendColdStart(); err != nil {
+		t.Fatalf("SendColdStart failed: %v", err)
+	}
+
+	buf := make([]byte, 65535)
+	for i, conn := range conns {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("receiver %s got no trap: %v", receivers[i], err)
+		}
+		decoder := gosnmp.GoSNMP{Version: gosnmp.Version2c}
+		packet, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			t.Fatalf("receiver %s: decode trap: %v", receivers[i], err)
+		}
+		if len(packet.Variables) < 2 || packet.Variables[1].Value != OIDColdStart {
+			t.Errorf("receiver %s: expected coldStart trap, got %+v", receivers[i], packet.Variables)
+		}
 	}
 }
 
@@ -436,12 +463,151 @@ func BenchmarkNewTrapSender(b *testing.B) {
 	}
 }
 
-// BenchmarkParsePort benchmarks port parsing
-func BenchmarkParsePort(b *testing.B) {
-	ports := []string{"162", "1162", "10162", "invalid", "0", "70000"}
+// TestTrapSender_ExtraVarbinds tests that configured trap_varbinds are rendered
+// with runtime data and appended to the emitted trap PDU
+func TestTrapSender_ExtraVarbinds(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot open UDP listener: %v", err)
+	}
+	defer conn.Close()
+
+	trapConfig := &config.TrapConfig{
+		Enabled:   true,
+		Receivers: []string{conn.LocalAddr().String()},
+		LinkState: &config.LinkStateTrapConfig{Enabled: true, LinkDown: true},
+		Varbinds: []config.TrapVarbind{
+			{OID: ".1.3.6.1.4.1.9.2.2.1.1.20", Type: config.TrapVarbindString, Value: "{{.IfDescr}} (ifIndex {{.IfIndex}}) down on {{.Device}}"},
+			{OID: ".1.3.6.1.4.1.9.9.41.1.2.3.1.2", Type: config.TrapVarbindInteger, Value: "{{.IfIndex}}", Traps: []string{"linkDown"}},
+			{OID: ".1.3.6.1.4.1.9.9.109.1.1.1.1.7", Type: config.TrapVarbindGauge32, Value: "{{.Value}}", Traps: []string{"highCPU"}},
+		},
+	}
+	ts, err := NewTrapSender("edge-sw", net.ParseIP("127.0.0.1"), trapConfig, 0)
+	if err != nil {
+		t.Fatalf("NewTrapSender failed: %v", err)
+	}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		parsePort(ports[i%len(ports)])
+	if err := ts.SendLinkDown(3, "GigabitEthernet0/3"); err != nil {
+		t.Fatalf("SendLinkDown failed: %v", err)
+	}
+
+	buf := make([]byte, 65535)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no trap received: %v", err)
+	}
+
+	decoder := gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	packet, err := decoder.SnmpDecodePacket(buf[:n])
+	if err != nil {
+		t.Fatalf("decode trap: %v", err)
+	}
+
+	values := make(map[string]interface{})
+	for _, v := range packet.Variables {
+		values[v.Name] = v.Value
+	}
+	if got, ok := values[".1.3.6.1.4.1.9.2.2.1.1.20"].([]byte); !ok || string(got) != "GigabitEthernet0/3 (ifIndex 3) down on edge-sw" {
+		t.Errorf("unexpected templated string varbind: %v", values[".1.3.6.1.4.1.9.2.2.1.1.20"])
+	}
+	if got, ok := values[".1.3.6.1.4.1.9.9.41.1.2.3.1.2"].(int); !ok || got != 3 {
+		t.Errorf("unexpected integer varbind: %v", values[".1.3.6.1.4.1.9.9.41.1.2.3.1.2"])
+	}
+	if _, ok := values[".1.3.6.1.4.1.9.9.109.1.1.1.1.7"]; ok {
+		t.Error("highCPU-only varbind should not be appended to linkDown")
+	}
+}
+
+func TestTrapSender_RateLimitSharedAcrossDevices(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot open UDP listener: %v", err)
+	}
+	defer conn.Close()
+
+	receiver := conn.LocalAddr().String()
+	trapConfig := &config.TrapConfig{
+		Enabled:   true,
+		Receivers: []string{receiver},
+		HighCPU:   &config.ThresholdTrapConfig{Enabled: true, Threshold: 80, Interval: 60},
+		RateLimit: &config.TrapRateLimit{MaxTraps: 3, Window: 300 * time.Millisecond},
+	}
+
+	// Ten devices trip the CPU threshold at the same moment
+	for i := 0; i < 10; i++ {
+		ts, err := NewTrapSender(fmt.Sprintf("sw%d", i), net.ParseIP("127.0.0.1"), trapConfig, 0)
+		if err != nil {
+			t.Fatalf("NewTrapSender failed: %v", err)
+		}
+		if err := ts.SendHighCPU(95); err != nil {
+			t.Fatalf("SendHighCPU failed: %v", err)
+		}
+	}
+
+	if got := sharedTrapLimiter.suppressedCount(receiver); got != 7 {
+		t.Errorf("expected 7 suppressed traps, got %d", got)
+	}
+
+	// Three traps get through, then the summary arrives when the window closes
+	var trapOIDs []string
+	var summary *gosnmp.SnmpPacket
+	buf := make([]byte, 65535)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		packet, err := (&gosnmp.GoSNMP{Version: gosnmp.Version2c}).SnmpDecodePacket(buf[:n])
+		if err != nil || len(packet.Variables) < 2 {
+			t.Fatalf("undecodable trap: %v", err)
+		}
+		oid, _ := packet.Variables[1].Value.(string)
+		trapOIDs = append(trapOIDs, oid)
+		if oid == OIDTrapsSuppressed {
+			summary = packet
+			break
+		}
+	}
+	if len(trapOIDs) != 4 || summary == nil {
+		t.Fatalf("receiver got traps %v, want 3 highCPU traps and the rate limit summary", trapOIDs)
+	}
+	values := map[string]interface{}{}
+	for _, variable := range summary.Variables {
+		values[variable.Name] = variable.Value
+	}
+	if count, _ := values[OIDTrapsSuppressedCount].(uint); count != 7 {
+		t.Errorf("summary reports %v suppressed traps, want 7", values[OIDTrapsSuppressedCount])
+	}
+	if detail, _ := values[OIDTrapsSuppressedDetail].([]byte); string(detail) != "highCPU x7" {
+		t.Errorf("summary detail %q, want \"highCPU x7\"", detail)
+	}
+}
+
+func TestTrapLimiter_BudgetPerLimit(t *testing.T) {
+	limiter := &trapLimiter{receivers: make(map[budgetKey]*receiverBudget)}
+	receiver := "192.0.2.1:162"
+	strict := &config.TrapRateLimit{MaxTraps: 1, Window: time.Minute}
+	loose := &config.TrapRateLimit{MaxTraps: 3, Window: time.Minute}
+
+	if !limiter.allow(receiver, "highCPU", strict, 0, nil) {
+		t.Fatal("first trap under the strict limit was suppressed")
+	}
+	if limiter.allow(receiver, "highCPU", strict, 0, nil) {
+		t.Error("second trap under the strict limit was delivered")
+	}
+
+	// A device with a looser limit to the same receiver is held to its own limit
+	for i := 0; i < 3; i++ {
+		if !limiter.allow(receiver, "highCPU", loose, 0, nil) {
+			t.Errorf("trap %d under the loose limit was suppressed", i+1)
+		}
+	}
+	if limiter.allow(receiver, "highCPU", loose, 0, nil) {
+		t.Error("fourth trap under the loose limit was delivered")
+	}
+	if got := limiter.suppressedCount(receiver); got != 2 {
+		t.Errorf("expected 2 suppressed traps, got %d", got)
 	}
 }
@@ -0,0 +1,118 @@
+package snmp
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// TrapData is the runtime data available to trap_varbinds value templates
+type TrapData struct {
+	Device  string // Device name
+	Trap    string // Trap name (e.g., linkDown, highCPU)
+	IfIndex int    // Interface index for interface traps
+	IfDescr string // Interface description for interface traps
+	Value   int    // Current value for threshold traps (percent or error count)
+}
+
+// extraVarbind is a configured trap varbind with its parsed value template
+type extraVarbind struct {
+	config.TrapVarbind
+	value *template.Template
+}
+
+// compileTrapVarbinds parses the value templates of configured trap varbinds
+func compileTrapVarbinds(varbinds []config.TrapVarbind) ([]extraVarbind, error) {
+	compiled := make([]extraVarbind, 0, len(varbinds))
+	for _, vb := range varbinds {
+		tmpl, err := template.New(vb.OID).Parse(vb.Value)
+		if err != nil {
+			return nil, fmt.Errorf("trap varbind %s: %w", vb.OID, err)
+		}
+		compiled = append(compiled, extraVarbind{TrapVarbind: vb, value: tmpl})
+	}
+	return compiled, nil
+}
+
+// extraVarbinds renders the configured varbinds that apply to a trap. A
+// varbind whose rendered value does not fit its type is skipped.
+func (ts *TrapSender) extraVarbinds(data TrapData) []gosnmp.SnmpPDU {
+	var pdus []gosnmp.SnmpPDU
+	for _, vb := range ts.varbinds {
+		if !vb.AppliesTo(data.Trap) {
+			continue
+		}
+
+		var rendered strings.Builder
+		if err := vb.value.Execute(&rendered, data); err != nil {
+			if ts.debugLevel >= 1 {
+				log.Printf("[%s] Skipping trap varbind %s: %v", ts.deviceName, vb.OID, err)
+			}
+			continue
+		}
+
+		pdu, err := trapVarbindPDU(vb.OID, vb.Type, rendered.String())
+		if err != nil {
+			if ts.debugLevel >= 1 {
+				log.Printf("[%s] Skipping trap varbind %s: %v", ts.deviceName, vb.OID, err)
+			}
+			continue
+		}
+		pdus = append(pdus, pdu)
+	}
+	return pdus
+}
+
+// trapVarbindPDU converts a rendered value to a varbind of the configured type
+func trapVarbindPDU(oid, varType, value string) (gosnmp.SnmpPDU, error) {
+	value = strings.TrimSpace(value)
+	pdu := gosnmp.SnmpPDU{Name: oid}
+
+	switch varType {
+	case config.TrapVarbindInteger:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return pdu, fmt.Errorf("invalid integer %q", value)
+		}
+		pdu.Type, pdu.Value = gosnmp.Integer, n
+	case config.TrapVarbindCounter32, config.TrapVarbindGauge32, config.TrapVarbindTimeTicks:
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return pdu, fmt.Errorf("invalid %s %q", varType, value)
+		}
+		switch varType {
+		case config.TrapVarbindCounter32:
+			pdu.Type, pdu.Value = gosnmp.Counter32, uint(n)
+		case config.TrapVarbindGauge32:
+			pdu.Type, pdu.Value = gosnmp.Gauge32, uint(n)
+		default:
+			pdu.Type, pdu.Value = gosnmp.TimeTicks, uint32(n)
+		}
+	case config.TrapVarbindCounter64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return pdu, fmt.Errorf("invalid counter64 %q", value)
+		}
+		pdu.Type, pdu.Value = gosnmp.Counter64, n
+	case config.TrapVarbindIPAddress:
+		ip := net.ParseIP(value).To4()
+		if ip == nil {
+			return pdu, fmt.Errorf("invalid IPv4 address %q", value)
+		}
+		pdu.Type, pdu.Value = gosnmp.IPAddress, ip.String()
+	case config.TrapVarbindOID:
+		if value == "" {
+			return pdu, fmt.Errorf("empty OID value")
+		}
+		pdu.Type, pdu.Value = gosnmp.ObjectIdentifier, value
+	default:
+		pdu.Type, pdu.Value = gosnmp.OctetString, value
+	}
+	return pdu, nil
+}
@@ -0,0 +1,60 @@
+package snmp
+
+import (
+	"strings"
+
+	"github.com/krisarmstrong/niac-go/pkg/config"
+)
+
+// MIBView restricts which OID subtrees a community may access (RFC 3415 style).
+// The longest matching subtree decides visibility; when no subtrees are
+// included, every OID not excluded is visible.
+type MIBView struct {
+	included []string
+	excluded []string
+}
+
+// NewMIBView creates a view from configured subtrees. A nil config yields a
+// nil view, which grants full access.
+func NewMIBView(cfg *config.SNMPView) *MIBView {
+	if cfg == nil {
+		return nil
+	}
+	view := &MIBView{}
+	for _, oid := range cfg.Included {
+		view.included = append(view.included, strings.TrimPrefix(oid, "."))
+	}
+	for _, oid := range cfg.Excluded {
+		view.excluded = append(view.excluded, strings.TrimPrefix(oid, "."))
+	}
+	return view
+}
+
+// Contains reports whether oid is visible in the view.
+func (v *MIBView) Contains(oid string) bool {
+	if v == nil {
+		return true
+	}
+	oid = strings.TrimPrefix(oid, ".")
+
+	includeLen := longestSubtreeMatch(oid, v.included)
+	excludeLen := longestSubtreeMatch(oid, v.excluded)
+	if includeLen < 0 && excludeLen < 0 {
+		return len(v.included) == 0
+	}
+	return includeLen > excludeLen
+}
+
+// longestSubtreeMatch returns the length of the longest subtree containing oid,
+// or -1 if none does.
+func longestSubtreeMatch(oid string, subtrees []string) int {
+	best := -1
+	for _, subtree := range subtrees {
+		if oid == subtree || strings.HasPrefix(oid, subtree+".") {
+			if len(subtree) > best {
+				best = len(subtree)
+			}
+		}
+	}
+	return best
+}
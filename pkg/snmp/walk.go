@@ -2,13 +2,17 @@ package snmp
 
 import (
 	"bufio"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/gosnmp/gosnmp"
+	"github.com/krisarmstrong/niac-go/pkg/config"
 )
 
 // WalkEntry represents a single entry from an SNMP walk file
@@ -18,21 +22,75 @@ type WalkEntry struct {
 	Value interface{}
 }
 
-// ParseWalkFile parses an SNMP walk file
+// Default walk file limits, generous enough for a full capture of a large
+// chassis switch while keeping a runaway file from exhausting memory
+const (
+	DefaultMaxWalkFileSize = 256 << 20 // 256 MiB
+	DefaultMaxWalkOIDs     = 2000000
+)
+
+// WalkLimits caps the walk files an agent loads. Zero fields use the
+// defaults.
+type WalkLimits struct {
+	MaxFileSize int64 // Largest walk file in bytes
+	MaxOIDs     int   // Most OIDs parsed from one walk file
+}
+
+// NewWalkLimits returns the caps configured by walk_limits; nil means the
+// defaults
+func NewWalkLimits(cfg *config.WalkLimitsConfig) WalkLimits {
+	if cfg == nil {
+		return WalkLimits{}
+	}
+	return WalkLimits{MaxFileSize: cfg.MaxFileSize, MaxOIDs: cfg.MaxOIDs}
+}
+
+// fileSize returns the file size cap, or DefaultMaxWalkFileSize when unset
+func (l WalkLimits) fileSize() int64 {
+	if l.MaxFileSize > 0 {
+		return l.MaxFileSize
+	}
+	return DefaultMaxWalkFileSize
+}
+
+// oids returns the OID cap, or DefaultMaxWalkOIDs when unset
+func (l WalkLimits) oids() int {
+	if l.MaxOIDs > 0 {
+		return l.MaxOIDs
+	}
+	return DefaultMaxWalkOIDs
+}
+
+// ParseWalkFile parses an SNMP walk file within the default WalkLimits
 // Walk files are typically in the format:
 // OID = TYPE: VALUE
 // For example:
 // .1.3.6.1.2.1.1.1.0 = STRING: "Cisco IOS Software"
 // .1.3.6.1.2.1.1.3.0 = Timeticks: (12345) 0:02:03.45
 func ParseWalkFile(filename string) ([]WalkEntry, error) {
+	return ParseWalkFileWithLimits(filename, WalkLimits{})
+}
+
+// ParseWalkFileWithLimits parses an SNMP walk file, streaming it line by line
+// and aborting once the file is larger than limits.MaxFileSize bytes or holds
+// more than limits.MaxOIDs OIDs
+func ParseWalkFileWithLimits(filename string, limits WalkLimits) ([]WalkEntry, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open walk file: %v", err)
 	}
 	defer file.Close()
 
+	maxSize, maxOIDs := limits.fileSize(), limits.oids()
+	if info, err := file.Stat(); err == nil && info.Size() > maxSize {
+		return nil, fmt.Errorf("walk file %s is %d bytes, over the %d byte limit (walk_limits max_file_size)",
+			filename, info.Size(), maxSize)
+	}
+
 	var entries []WalkEntry
-	scanner := bufio.NewScanner(file)
+	// The size check above misses a file that grows while it is read
+	limited := &io.LimitedReader{R: file, N: maxSize + 1}
+	scanner := bufio.NewScanner(limited)
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -51,12 +109,19 @@ func ParseWalkFile(filename string) ([]WalkEntry, error) {
 			continue
 		}
 
+		if len(entries) == maxOIDs {
+			return nil, fmt.Errorf("walk file %s has more than %d OIDs (walk_limits max_oids), stopped at line %d",
+				filename, maxOIDs, lineNum)
+		}
 		entries = append(entries, *entry)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading walk file: %v", err)
 	}
+	if limited.N == 0 {
+		return nil, fmt.Errorf("walk file %s is over the %d byte limit (walk_limits max_file_size)", filename, maxSize)
+	}
 
 	return entries, nil
 }
@@ -110,7 +175,7 @@ func parseTypeAndValue(typeStr, valueStr string) (gosnmp.Asn1BER, interface{}, e
 		}
 		return gosnmp.Integer, int(value), nil
 
-	case "GAUGE", "GAUGE32":
+	case "GAUGE", "GAUGE32", "UNSIGNED32":
 		value, err := strconv.ParseUint(valueStr, 10, 32)
 		if err != nil {
 			return 0, nil, err
@@ -171,7 +236,7 @@ func parseTypeAndValue(typeStr, valueStr string) (gosnmp.Asn1BER, interface{}, e
 		return gosnmp.OctetString, value, nil
 
 	case "OPAQUE":
-		return gosnmp.Opaque, valueStr, nil
+		return parseOpaqueValue(valueStr)
 
 	case "NULL":
 		return gosnmp.Null, nil, nil
@@ -182,6 +247,31 @@ func parseTypeAndValue(typeStr, valueStr string) (gosnmp.Asn1BER, interface{}, e
 	}
 }
 
+// parseOpaqueValue parses an Opaque value as net-snmp writes it: "Float: 1.5",
+// "Double: 1.5", or the encoded bytes in hex ("9F 78 04 3F C0 00 00").
+// Anything else is kept as the text's bytes.
+func parseOpaqueValue(valueStr string) (gosnmp.Asn1BER, interface{}, error) {
+	kind, number, found := strings.Cut(valueStr, ":")
+	switch kind = strings.ToUpper(strings.TrimSpace(kind)); {
+	case found && kind == "FLOAT":
+		value, err := strconv.ParseFloat(strings.TrimSpace(number), 32)
+		if err != nil {
+			return 0, nil, err
+		}
+		return gosnmp.OpaqueFloat, float32(value), nil
+	case found && kind == "DOUBLE":
+		value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil {
+			return 0, nil, err
+		}
+		return gosnmp.OpaqueDouble, value, nil
+	}
+	if data, err := hex.DecodeString(strings.ReplaceAll(valueStr, " ", "")); err == nil && len(data) > 0 {
+		return gosnmp.Opaque, data, nil
+	}
+	return gosnmp.Opaque, []byte(valueStr), nil
+}
+
 // ExportToWalkFile exports MIB entries to a walk file format
 func ExportToWalkFile(filename string, mib *MIB) error {
 	file, err := os.Create(filename)
@@ -248,7 +338,7 @@ func formatTypeName(asnType gosnmp.Asn1BER) string {
 		return "OID"
 	case gosnmp.IPAddress:
 		return "IpAddress"
-	case gosnmp.Opaque:
+	case gosnmp.Opaque, gosnmp.OpaqueFloat, gosnmp.OpaqueDouble:
 		return "Opaque"
 	case gosnmp.Null:
 		return "NULL"
@@ -273,7 +363,19 @@ func formatValue(asnType gosnmp.Asn1BER, value interface{}) string {
 		}
 		return oid
 	case gosnmp.IPAddress:
+		if ip, ok := encodeValue(asnType, value).([]byte); ok {
+			return net.IP(ip).String()
+		}
+		return fmt.Sprintf("%v", value)
+	case gosnmp.Opaque:
+		if data, ok := encodeValue(asnType, value).([]byte); ok {
+			return fmt.Sprintf("% X", data)
+		}
 		return fmt.Sprintf("%v", value)
+	case gosnmp.OpaqueFloat:
+		return fmt.Sprintf("Float: %v", value)
+	case gosnmp.OpaqueDouble:
+		return fmt.Sprintf("Double: %v", value)
 	case gosnmp.Null:
 		return ""
 	default:
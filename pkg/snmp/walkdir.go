@@ -0,0 +1,105 @@
+package snmp
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+)
+
+// walkConflict is an OID that more than one merged walk file sets to
+// different values.
+type walkConflict struct {
+	oid        string
+	file       string // File whose value is served (the later one)
+	overridden string // File whose value was replaced
+}
+
+// mergeWalkFiles parses files within limits and merges their entries in
+// order. An OID set by several files keeps the value from the last one;
+// differing values are returned as conflicts.
+func mergeWalkFiles(files []string, limits WalkLimits) ([]WalkEntry, []walkConflict, error) {
+	var merged []WalkEntry
+	index := make(map[string]int)     // OID -> position in merged
+	source := make(map[string]string) // OID -> file providing it
+	var conflicts []walkConflict
+
+	for _, file := range files {
+		entries, err := ParseWalkFileWithLimits(file, limits)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse walk file %s: %v", file, err)
+		}
+		for _, entry := range entries {
+			i, seen := index[entry.OID]
+			if !seen {
+				index[entry.OID] = len(merged)
+				source[entry.OID] = file
+				merged = append(merged, entry)
+				continue
+			}
+			previous := merged[i]
+			if previous.Type != entry.Type || !reflect.DeepEqual(previous.Value, entry.Value) {
+				conflicts = append(conflicts, walkConflict{oid: entry.OID, file: file, overridden: source[entry.OID]})
+			}
+			merged[i] = entry
+			source[entry.OID] = file
+		}
+	}
+	return merged, conflicts, nil
+}
+
+// LoadWalkFiles merges several walk files, e.g. snmpwalk captures of
+// different subtrees, into the MIB. Files are applied in order, so when two
+// files set the same OID the later one wins; each conflicting value is
+// logged. The merged objects are applied over the walk file.
+func (a *Agent) LoadWalkFiles(files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no walk files specified")
+	}
+
+	entries, conflicts, err := mergeWalkFiles(files, a.walkLimits)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.walkFiles = files
+	a.setWalkEntries(entries)
+	a.walkOIDs += len(entries)
+	a.applyComputedOIDs(false)
+	a.applyInterfaceMTU()
+	a.applyInterfaceDescriptions()
+	a.initializeSysORTable()
+	a.mu.Unlock()
+
+	if a.debugLevel >= 1 {
+		for _, c := range conflicts {
+			log.Printf("SNMP walk merge for device %s: %s from %s overrides the value in %s",
+				a.device.Name, c.oid, c.file, c.overridden)
+		}
+		log.Printf("Merged %d OIDs from %d walk files for device %s (%d conflicts)",
+			len(entries), len(files), a.device.Name, len(conflicts))
+	}
+	return nil
+}
+
+// reloadWalkFiles re-applies the merged walk files after the MIB was rebuilt.
+// Callers must hold a.mu.
+func (a *Agent) reloadWalkFiles() error {
+	entries, _, err := mergeWalkFiles(a.walkFiles, a.walkLimits)
+	if err != nil {
+		return err
+	}
+	a.setWalkEntries(entries)
+	a.walkOIDs += len(entries)
+	return nil
+}
+
+// setWalkEntries adds walk entries to the MIB.
+func (a *Agent) setWalkEntries(entries []WalkEntry) {
+	for _, entry := range entries {
+		a.mib.Set(entry.OID, &OIDValue{
+			Type:  entry.Type,
+			Value: entry.Value,
+		})
+	}
+}
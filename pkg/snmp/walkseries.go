@@ -0,0 +1,115 @@
+package snmp
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// walkSeries replays a sequence of walk snapshots. The snapshot served is
+// chosen from the time elapsed since the series was loaded, so the agent
+// advances lazily when polled and needs no background goroutine.
+type walkSeries struct {
+	files     []string
+	snapshots [][]WalkEntry
+	interval  time.Duration
+	loop      bool
+	start     time.Time
+	current   int
+	installed map[string]bool // OIDs set by the current snapshot
+}
+
+// LoadWalkSeries parses every snapshot up front and serves the first one.
+// Each interval the agent moves to the next snapshot; at the end it holds the
+// last one, or starts over when loop is set. Snapshot values override the
+// walk file and are in turn overridden by computed objects.
+func (a *Agent) LoadWalkSeries(files []string, interval time.Duration, loop bool) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no walk series files specified")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("walk series interval must be positive: %v", interval)
+	}
+
+	series := &walkSeries{files: files, interval: interval, loop: loop}
+	for _, file := range files {
+		entries, err := ParseWalkFileWithLimits(file, a.walkLimits)
+		if err != nil {
+			return fmt.Errorf("failed to parse walk series file %s: %v", file, err)
+		}
+		series.snapshots = append(series.snapshots, entries)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	series.start = time.Now()
+	a.series = series
+	a.applyWalkSnapshot(0)
+	a.initializeSysORTable()
+
+	if a.debugLevel >= 1 {
+		log.Printf("Loaded walk series of %d snapshots (every %v) for device %s",
+			len(files), interval, a.device.Name)
+	}
+	return nil
+}
+
+// indexAt returns the snapshot due at t.
+func (s *walkSeries) indexAt(t time.Time) int {
+	step := int(t.Sub(s.start) / s.interval)
+	if s.loop {
+		return step % len(s.snapshots)
+	}
+	return min(step, len(s.snapshots)-1)
+}
+
+// advanceWalkSeries installs the snapshot due now if it is not already being
+// served. Called at the start of every request.
+func (a *Agent) advanceWalkSeries() {
+	a.mu.RLock()
+	series := a.series
+	due := series != nil && series.indexAt(time.Now()) != series.current
+	a.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if index := series.indexAt(time.Now()); index != series.current {
+		a.applyWalkSnapshot(index)
+	}
+}
+
+// applyWalkSnapshot replaces the current snapshot's objects with those of
+// snapshot index. Objects missing from the new snapshot are removed, so rows
+// that disappeared in the recording disappear from the agent.
+// Callers must hold a.mu or have exclusive access to the agent.
+func (a *Agent) applyWalkSnapshot(index int) {
+	series := a.series
+	entries := series.snapshots[index]
+
+	installed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		installed[entry.OID] = true
+	}
+	for oid := range series.installed {
+		if !installed[oid] {
+			a.mib.Delete(oid)
+		}
+	}
+	for _, entry := range entries {
+		a.mib.Set(entry.OID, &OIDValue{Type: entry.Type, Value: entry.Value})
+	}
+	series.installed = installed
+	series.current = index
+
+	a.applyComputedOIDs(false)
+	a.applyInterfaceMTU()
+	a.applyInterfaceDescriptions()
+
+	if a.debugLevel >= 2 {
+		log.Printf("SNMP walk series for device %s now at snapshot %d/%d (%s)",
+			a.device.Name, index+1, len(series.snapshots), series.files[index])
+	}
+}
@@ -0,0 +1,17 @@
+package stats
+
+// DeviceSummary holds the traffic counters of one simulated device
+type DeviceSummary struct {
+	Name            string            `json:"name"`
+	PacketsReceived uint64            `json:"packets_received"` // Unicast frames addressed to the device's MAC
+	PacketsSent     uint64            `json:"packets_sent"`     // Frames sent from the device
+	Errors          uint64            `json:"errors"`           // Frames from the device that failed to send
+	Sent            map[string]uint64 `json:"sent_by_protocol"` // Frames sent, by protocol (arp, icmp, dns, ...)
+}
+
+// SetDeviceSummaries records the per-device counters included in exports
+func (s *Statistics) SetDeviceSummaries(devices []DeviceSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Devices = devices
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
 )
@@ -39,12 +40,21 @@ type Statistics struct {
 	DHCPRequestCount int64 `json:"dhcp_request_count"`
 
 	// System stats
-	MemoryUsageMB  uint64 `json:"memory_usage_mb"`
-	GoroutineCount int    `json:"goroutine_count"`
-	CPUCount       int    `json:"cpu_count"`
+	MemoryUsageMB    uint64 `json:"memory_usage_mb"`
+	MemoryAllocBytes uint64 `json:"memory_alloc_bytes"`
+	MemorySysBytes   uint64 `json:"memory_sys_bytes"`
+	GCRuns           uint32 `json:"gc_runs"`
+	GoroutineCount   int    `json:"goroutine_count"`
+	CPUCount         int    `json:"cpu_count"`
 
 	// Protocol-specific stats
 	ProtocolStats map[string]ProtocolStat `json:"protocol_stats"`
+
+	// Protocol stack counters (as served on /metrics)
+	Stack StackCounters `json:"stack"`
+
+	// Per-device traffic counters
+	Devices []DeviceSummary `json:"devices"`
 }
 
 // ProtocolStat holds statistics for a specific protocol
@@ -81,12 +91,21 @@ type StatisticsSnapshot struct {
 	DHCPRequestCount int64 `json:"dhcp_request_count"`
 
 	// System stats
-	MemoryUsageMB  uint64 `json:"memory_usage_mb"`
-	GoroutineCount int    `json:"goroutine_count"`
-	CPUCount       int    `json:"cpu_count"`
+	MemoryUsageMB    uint64 `json:"memory_usage_mb"`
+	MemoryAllocBytes uint64 `json:"memory_alloc_bytes"`
+	MemorySysBytes   uint64 `json:"memory_sys_bytes"`
+	GCRuns           uint32 `json:"gc_runs"`
+	GoroutineCount   int    `json:"goroutine_count"`
+	CPUCount         int    `json:"cpu_count"`
 
 	// Protocol-specific stats
 	ProtocolStats map[string]ProtocolStat `json:"protocol_stats"`
+
+	// Protocol stack counters (as served on /metrics)
+	Stack StackCounters `json:"stack"`
+
+	// Per-device traffic counters
+	Devices []DeviceSummary `json:"devices"`
 }
 
 // NewStatistics creates a new Statistics instance
@@ -115,6 +134,9 @@ func (s *Statistics) Update() {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 	s.MemoryUsageMB = m.Alloc / 1024 / 1024
+	s.MemoryAllocBytes = m.Alloc
+	s.MemorySysBytes = m.Sys
+	s.GCRuns = m.NumGC
 }
 
 // IncrementPacketCount increments the packet count for a protocol
@@ -274,6 +296,21 @@ func (s *Statistics) ExportCSV(filename string) error {
 		writeRow(fmt.Sprintf("%s - Bytes Processed", protocol), fmt.Sprintf("%d", stat.BytesProcessed), "Protocol")
 	}
 
+	// Per-device stats
+	for _, device := range s.Devices {
+		writeRow(fmt.Sprintf("%s - Packets Received", device.Name), fmt.Sprintf("%d", device.PacketsReceived), "Device")
+		writeRow(fmt.Sprintf("%s - Packets Sent", device.Name), fmt.Sprintf("%d", device.PacketsSent), "Device")
+		writeRow(fmt.Sprintf("%s - Errors", device.Name), fmt.Sprintf("%d", device.Errors), "Device")
+		protocols := make([]string, 0, len(device.Sent))
+		for protocol := range device.Sent {
+			protocols = append(protocols, protocol)
+		}
+		sort.Strings(protocols)
+		for _, protocol := range protocols {
+			writeRow(fmt.Sprintf("%s - Sent (%s)", device.Name, protocol), fmt.Sprintf("%d", device.Sent[protocol]), "Device")
+		}
+	}
+
 	return nil
 }
 
@@ -293,11 +330,16 @@ func (s *Statistics) snapshot() StatisticsSnapshot {
 		DHCPLeaseCount:   s.DHCPLeaseCount,
 		DHCPRequestCount: s.DHCPRequestCount,
 		MemoryUsageMB:    s.MemoryUsageMB,
+		MemoryAllocBytes: s.MemoryAllocBytes,
+		MemorySysBytes:   s.MemorySysBytes,
+		GCRuns:           s.GCRuns,
 		GoroutineCount:   s.GoroutineCount,
 		CPUCount:         s.CPUCount,
 		PacketCounts:     make(map[string]int64),
 		ErrorCounts:      make(map[string]int64),
 		ProtocolStats:    make(map[string]ProtocolStat),
+		Stack:            s.Stack,
+		Devices:          make([]DeviceSummary, 0, len(s.Devices)),
 	}
 
 	// Deep copy maps
@@ -310,6 +352,14 @@ func (s *Statistics) snapshot() StatisticsSnapshot {
 	for protocol, stats := range s.ProtocolStats {
 		snapshot.ProtocolStats[protocol] = stats
 	}
+	for _, device := range s.Devices {
+		sent := make(map[string]uint64, len(device.Sent))
+		for protocol, count := range device.Sent {
+			sent[protocol] = count
+		}
+		device.Sent = sent
+		snapshot.Devices = append(snapshot.Devices, device)
+	}
 
 	return snapshot
 }
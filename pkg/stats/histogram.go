@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSNMPDurationBuckets are the upper bounds of the SNMP request
+// duration histogram: 1ms to 1s
+var DefaultSNMPDurationBuckets = []time.Duration{
+	1 * time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+}
+
+// Histogram counts durations into fixed buckets. Observe only uses atomic
+// adds, so it can sit on a request path without a lock.
+type Histogram struct {
+	bounds []time.Duration // Bucket upper bounds, ascending
+	counts []atomic.Uint64 // Per bucket (not cumulative); the last is +Inf
+	sum    atomic.Uint64   // Nanoseconds
+}
+
+// HistogramSnapshot is a point-in-time copy of a Histogram
+type HistogramSnapshot struct {
+	Bounds []time.Duration
+	Counts []uint64 // Cumulative count of each bound; the last is +Inf
+	Sum    time.Duration
+	Count  uint64 // Equal to the +Inf count
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds, which
+// must be positive and ascending
+func NewHistogram(bounds []time.Duration) (*Histogram, error) {
+	if err := ValidateBuckets(bounds); err != nil {
+		return nil, err
+	}
+	return &Histogram{
+		bounds: append([]time.Duration(nil), bounds...),
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}, nil
+}
+
+// ValidateBuckets checks histogram bucket upper bounds
+func ValidateBuckets(bounds []time.Duration) error {
+	if len(bounds) == 0 {
+		return fmt.Errorf("at least one bucket is required")
+	}
+	for i, bound := range bounds {
+		if bound <= 0 {
+			return fmt.Errorf("bucket %v must be positive", bound)
+		}
+		if i > 0 && bound <= bounds[i-1] {
+			return fmt.Errorf("buckets must be ascending: %v after %v", bound, bounds[i-1])
+		}
+	}
+	return nil
+}
+
+// Observe records one duration
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	if d > 0 {
+		h.sum.Add(uint64(d))
+	}
+}
+
+// Snapshot copies the histogram. An observation made during the copy may be
+// in some buckets or the sum but not others; it is complete in the next one.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snapshot := HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: make([]uint64, len(h.counts)),
+		Sum:    time.Duration(h.sum.Load()),
+	}
+	var cumulative uint64
+	for i := range h.counts {
+		cumulative += h.counts[i].Load()
+		snapshot.Counts[i] = cumulative
+	}
+	snapshot.Count = cumulative
+	return snapshot
+}
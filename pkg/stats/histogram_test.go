@@ -0,0 +1,38 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Observe(t *testing.T) {
+	h, err := NewHistogram([]time.Duration{time.Millisecond, 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewHistogram: %v", err)
+	}
+	for _, d := range []time.Duration{500 * time.Microsecond, time.Millisecond, 5 * time.Millisecond, time.Second} {
+		h.Observe(d)
+	}
+
+	snapshot := h.Snapshot()
+	if want := []uint64{2, 3, 4}; !reflect.DeepEqual(snapshot.Counts, want) {
+		t.Errorf("Cumulative counts %v, want %v (bounds are inclusive)", snapshot.Counts, want)
+	}
+	if snapshot.Count != 4 || snapshot.Sum != 1006500*time.Microsecond {
+		t.Errorf("Count %d, sum %v, want 4 and 1.0065s", snapshot.Count, snapshot.Sum)
+	}
+}
+
+func TestNewHistogram_InvalidBuckets(t *testing.T) {
+	for _, bounds := range [][]time.Duration{
+		nil,
+		{0, time.Millisecond},
+		{10 * time.Millisecond, time.Millisecond},
+		{time.Millisecond, time.Millisecond},
+	} {
+		if _, err := NewHistogram(bounds); err == nil {
+			t.Errorf("NewHistogram(%v) succeeded, want an error", bounds)
+		}
+	}
+}
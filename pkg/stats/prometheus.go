@@ -0,0 +1,127 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// StackCounters holds the protocol stack counters reported as Prometheus metrics
+type StackCounters struct {
+	PacketsSent           uint64        `json:"packets_sent"`
+	PacketsReceived       uint64        `json:"packets_received"`
+	SNMPQueries           uint64        `json:"snmp_queries"`
+	SNMPDenied            uint64        `json:"snmp_denied"`
+	Errors                uint64        `json:"errors"`
+	Neighbors             uint64        `json:"neighbors"`
+	NeighborsEvicted      uint64        `json:"neighbors_evicted"`
+	ARPRequests           uint64        `json:"arp_requests"`
+	ARPReplies            uint64        `json:"arp_replies"`
+	ICMPRequests          uint64        `json:"icmp_requests"`
+	ICMPReplies           uint64        `json:"icmp_replies"`
+	DNSQueries            uint64        `json:"dns_queries"`
+	DHCPRequests          uint64        `json:"dhcp_requests"`
+	DHCPRetransmits       uint64        `json:"dhcp_retransmits"`
+	DelayedResponses      uint64        `json:"delayed_responses"`
+	AddedLatency          time.Duration `json:"added_latency_ns"`
+	TCPConnectionsRefused uint64        `json:"tcp_connections_refused"`
+	MalformedFramesSent   uint64        `json:"malformed_frames_sent"`
+}
+
+// Metrics is one sample of every metric family served on /metrics
+type Metrics struct {
+	Stack       StackCounters
+	DeviceCount int
+	Uptime      time.Duration
+	Goroutines  int
+	MemoryAlloc uint64 // Bytes allocated and in use
+	MemorySys   uint64 // Bytes obtained from the OS
+	GCRuns      uint32
+
+	SNMPRequestDuration *HistogramSnapshot // Omitted when nil
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (m Metrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	family := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, kind)
+		fmt.Fprintf(bw, "%s %v\n", name, value)
+	}
+
+	family("niac_packets_sent_total", "counter", "Total packets sent", m.Stack.PacketsSent)
+	family("niac_packets_received_total", "counter", "Total packets received", m.Stack.PacketsReceived)
+	family("niac_snmp_queries_total", "counter", "Total SNMP queries processed", m.Stack.SNMPQueries)
+	family("niac_snmp_denied_total", "counter", "SNMP requests dropped by allowed_managers", m.Stack.SNMPDenied)
+	family("niac_errors_total", "counter", "Total errors", m.Stack.Errors)
+	family("niac_neighbors", "gauge", "Learned discovery neighbors currently in the table", m.Stack.Neighbors)
+	family("niac_neighbors_evicted_total", "counter", "Neighbors evicted to respect max_neighbors", m.Stack.NeighborsEvicted)
+	family("niac_devices_total", "gauge", "Number of simulated devices", m.DeviceCount)
+
+	// Protocol-specific metrics
+	family("niac_arp_requests_total", "counter", "Total ARP requests sent", m.Stack.ARPRequests)
+	family("niac_arp_replies_total", "counter", "Total ARP replies sent", m.Stack.ARPReplies)
+	family("niac_icmp_requests_total", "counter", "Total ICMP requests sent", m.Stack.ICMPRequests)
+	family("niac_icmp_replies_total", "counter", "Total ICMP replies sent", m.Stack.ICMPReplies)
+	family("niac_dns_queries_total", "counter", "Total DNS queries processed", m.Stack.DNSQueries)
+	family("niac_dhcp_requests_total", "counter", "Total DHCP requests processed", m.Stack.DHCPRequests)
+	family("niac_dhcp_retransmits_total", "counter", "DHCP DISCOVER retransmissions answered with the original Offer", m.Stack.DHCPRetransmits)
+	family("niac_delayed_responses_total", "counter", "Responses held back by a simulated latency model", m.Stack.DelayedResponses)
+	family("niac_added_latency_seconds_total", "counter", "Total simulated latency added to responses", fmt.Sprintf("%.6f", m.Stack.AddedLatency.Seconds()))
+	family("niac_tcp_connections_refused_total", "counter", "TCP connections refused by service connection limits", m.Stack.TCPConnectionsRefused)
+	family("niac_malformed_frames_total", "counter", "Deliberately malformed frames sent by malformed_frames", m.Stack.MalformedFramesSent)
+	if h := m.SNMPRequestDuration; h != nil {
+		name := "niac_snmp_request_duration_seconds"
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, "Time taken by SNMP agents to process requests")
+		fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+		for i, bound := range h.Bounds {
+			fmt.Fprintf(bw, "%s_bucket{le=\"%s\"} %d\n", name, formatSeconds(bound), h.Counts[i])
+		}
+		fmt.Fprintf(bw, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+		fmt.Fprintf(bw, "%s_sum %s\n", name, formatSeconds(h.Sum))
+		fmt.Fprintf(bw, "%s_count %d\n", name, h.Count)
+	}
+
+	// System performance metrics
+	family("niac_uptime_seconds", "gauge", "Server uptime in seconds", int64(m.Uptime.Seconds()))
+	family("niac_goroutines_total", "gauge", "Number of goroutines", m.Goroutines)
+	family("niac_memory_usage_bytes", "gauge", "Memory usage in bytes", m.MemoryAlloc)
+	family("niac_memory_sys_bytes", "gauge", "Total memory obtained from OS in bytes", m.MemorySys)
+	family("niac_gc_runs_total", "counter", "Total number of GC runs", m.GCRuns)
+
+	return bw.Flush()
+}
+
+// formatSeconds formats a duration as the shortest exact number of seconds
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
+
+// SetStackCounters records the protocol stack counters included in exports
+func (s *Statistics) SetStackCounters(counters StackCounters) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Stack = counters
+}
+
+// ExportPrometheus writes a snapshot of the statistics as the same metric
+// families served on /metrics, for ingestion after a short-lived run
+func (s *Statistics) ExportPrometheus(w io.Writer) error {
+	snapshot := s.GetSnapshot()
+	m := Metrics{
+		Stack:       snapshot.Stack,
+		DeviceCount: snapshot.DeviceCount,
+		Uptime:      snapshot.Uptime,
+		Goroutines:  snapshot.GoroutineCount,
+		MemoryAlloc: snapshot.MemoryAllocBytes,
+		MemorySys:   snapshot.MemorySysBytes,
+		GCRuns:      snapshot.GCRuns,
+	}
+	if err := m.WritePrometheus(w); err != nil {
+		return fmt.Errorf("failed to write Prometheus metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var promSampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*) (\S+)$`)
+
+// parsePrometheusText checks data against the Prometheus text exposition
+// format (HELP and TYPE before each family's sample, no family repeated) and
+// returns each sample's value by metric name
+func parsePrometheusText(t *testing.T, data []byte) map[string]float64 {
+	t.Helper()
+
+	types := make(map[string]string)
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "# HELP "):
+			if len(strings.Fields(text)) < 4 {
+				t.Fatalf("line %d: HELP without text: %q", line, text)
+			}
+		case strings.HasPrefix(text, "# TYPE "):
+			fields := strings.Fields(text)
+			if len(fields) != 4 || (fields[3] != "counter" && fields[3] != "gauge") {
+				t.Fatalf("line %d: malformed TYPE: %q", line, text)
+			}
+			if _, dup := types[fields[2]]; dup {
+				t.Fatalf("line %d: family %s declared twice", line, fields[2])
+			}
+			types[fields[2]] = fields[3]
+		default:
+			match := promSampleLine.FindStringSubmatch(text)
+			if match == nil {
+				t.Fatalf("line %d: malformed sample: %q", line, text)
+			}
+			if _, ok := types[match[1]]; !ok {
+				t.Fatalf("line %d: sample %s has no TYPE", line, match[1])
+			}
+			value, err := strconv.ParseFloat(match[2], 64)
+			if err != nil {
+				t.Fatalf("line %d: invalid value %q", line, match[2])
+			}
+			samples[match[1]] = value
+		}
+	}
+	return samples
+}
+
+func TestExportPrometheus(t *testing.T) {
+	stats := NewStatistics("en0", "config.yaml", "v1.19.0")
+	stats.SetDeviceCount(5)
+	stats.SetStackCounters(StackCounters{
+		PacketsSent:     42,
+		PacketsReceived: 40,
+		SNMPQueries:     7,
+		AddedLatency:    1500 * time.Millisecond,
+	})
+	stats.Update()
+
+	promFile := filepath.Join(t.TempDir(), "stats.prom")
+	file, err := os.Create(promFile)
+	if err != nil {
+		t.Fatalf("Failed to create metrics file: %v", err)
+	}
+	if err := stats.ExportPrometheus(file); err != nil {
+		t.Fatalf("Failed to export Prometheus metrics: %v", err)
+	}
+	file.Close()
+
+	data, err := os.ReadFile(promFile)
+	if err != nil {
+		t.Fatalf("Failed to read metrics file: %v", err)
+	}
+	samples := parsePrometheusText(t, data)
+
+	expected := map[string]float64{
+		"niac_packets_sent_total":          42,
+		"niac_packets_received_total":      40,
+		"niac_snmp_queries_total":          7,
+		"niac_devices_total":               5,
+		"niac_added_latency_seconds_total": 1.5,
+	}
+	for name, want := range expected {
+		if got, ok := samples[name]; !ok || got != want {
+			t.Errorf("%s = %v (present %v), expected %v", name, got, ok, want)
+		}
+	}
+	if samples["niac_memory_usage_bytes"] == 0 || samples["niac_goroutines_total"] == 0 {
+		t.Error("Expected system metrics from the captured snapshot")
+	}
+	if len(samples) != 24 {
+		t.Errorf("Expected the 24 metric families served on /metrics, got %d", len(samples))
+	}
+}